@@ -0,0 +1,165 @@
+package xlfill
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+)
+
+// Aggregates over a collection, optionally over a field of each item given as
+// a property path, as in ${sum(employees, "Salary")}. Nil values are skipped.
+// expr's sum, count, min and max builtins take these forms through the
+// aggregates patch; avg is a context function.
+
+// aggregateValues returns the non-nil values of items, or of field of each item.
+func aggregateValues(name string, items any, field []string) ([]any, error) {
+	if len(field) > 1 {
+		return nil, fmt.Errorf("%s: expected at most one field, got %d", name, len(field))
+	}
+	all, err := toSlice(items)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	values := make([]any, 0, len(all))
+	for _, item := range all {
+		if len(field) == 1 {
+			item = getField(item, field[0])
+		} else {
+			item = nullValue(item)
+		}
+		if item != nil {
+			values = append(values, item)
+		}
+	}
+	return values, nil
+}
+
+// sumOf adds up the values. The sum is an int when every value is an integer.
+func sumOf(items any, field ...string) (any, error) {
+	values, err := aggregateValues("sum", items, field)
+	if err != nil {
+		return nil, err
+	}
+	var sum float64
+	var intSum int
+	ints := true
+	for _, v := range values {
+		f, ok := toFloat64(v)
+		if !ok {
+			return nil, fmt.Errorf("sum: value %v (%T) is not a number", v, v)
+		}
+		sum += f
+		if i, ok := v.(int); ok {
+			intSum += i
+		} else {
+			ints = false
+		}
+	}
+	if ints {
+		return intSum, nil
+	}
+	return sum, nil
+}
+
+// avgOf returns the mean of the values as a float64, or nil when there are none.
+func avgOf(items any, field ...string) (any, error) {
+	values, err := aggregateValues("avg", items, field)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	var sum float64
+	for _, v := range values {
+		f, ok := toFloat64(v)
+		if !ok {
+			return nil, fmt.Errorf("avg: value %v (%T) is not a number", v, v)
+		}
+		sum += f
+	}
+	return sum / float64(len(values)), nil
+}
+
+// extremeOf returns the smallest value when sign is -1 or the largest when it
+// is 1, ordered like orderBy, or nil when there are none.
+func extremeOf(name string, sign int, items any, field []string) (any, error) {
+	values, err := aggregateValues(name, items, field)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	best := values[0]
+	for _, v := range values[1:] {
+		if compareValues(v, best)*sign > 0 {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+// countOf counts the items, or the items whose field holds a value, where nil,
+// "" and false are no value. Without a field, booleans count when true, as in
+// expr's count.
+func countOf(items any, field ...string) (any, error) {
+	values, err := aggregateValues("count", items, field)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, v := range values {
+		if v == false || (len(field) == 1 && v == "") {
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+// aggregateFunctions are the functions the aggregates patch calls.
+var aggregateFunctions = []expr.Option{
+	expr.Function("$sum", func(params ...any) (any, error) { return sumOf(params[0], fieldParam(params)...) }),
+	expr.Function("$count", func(params ...any) (any, error) { return countOf(params[0], fieldParam(params)...) }),
+	expr.Function("$min", func(params ...any) (any, error) { return extremeOf("min", -1, params[0], fieldParam(params)) }),
+	expr.Function("$max", func(params ...any) (any, error) { return extremeOf("max", 1, params[0], fieldParam(params)) }),
+}
+
+// fieldParam returns the field argument of an aggregate call, if any.
+func fieldParam(params []any) []string {
+	if len(params) < 2 {
+		return nil
+	}
+	return []string{fmt.Sprint(params[1])}
+}
+
+// aggregates rewrites calls of expr's builtins that are aggregates over a field,
+// sum(items, "Salary"), count(items, "Manager"), min(items, "Age") and
+// max(items, "Age"), and count(items) over any collection, into calls of
+// aggregateFunctions. Other forms, such as sum(items, #.Salary), are left to expr.
+type aggregates struct{}
+
+func (aggregates) Visit(node *ast.Node) {
+	b, ok := (*node).(*ast.BuiltinNode)
+	if !ok {
+		return
+	}
+	args := b.Arguments
+	switch {
+	case b.Name == "count" && len(args) == 1:
+	case (b.Name == "sum" || b.Name == "count") && len(args) == 2:
+		p, ok := args[1].(*ast.PredicateNode)
+		if !ok || !isStringNode(p.Node) {
+			return
+		}
+		args = []ast.Node{args[0], p.Node}
+	case (b.Name == "min" || b.Name == "max") && len(args) == 2:
+		if !isStringNode(args[1]) {
+			return
+		}
+	default:
+		return
+	}
+	ast.Patch(node, &ast.CallNode{Callee: &ast.IdentifierNode{Value: "$" + b.Name}, Arguments: args})
+}
+
+func isStringNode(node ast.Node) bool {
+	_, ok := node.(*ast.StringNode)
+	return ok
+}
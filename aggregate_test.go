@@ -0,0 +1,104 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func aggregateEnv() map[string]any {
+	return NewContext(map[string]any{
+		"employees": []testEmployee{
+			{Name: "Alice", Age: 30, Payment: 5000, Active: true, Address: &testAddress{City: "London"}},
+			{Name: "Bob", Age: 45, Payment: 4000.5},
+			{Name: "Carol", Age: 25, Payment: 6000, Active: true},
+		},
+		"rows":  []map[string]any{{"Qty": 2}, {"Qty": nil}, {"Qty": 3}, {}},
+		"flags": []bool{true, false, true},
+		"empty": []any{},
+	}).ToMap()
+}
+
+func TestAggregates(t *testing.T) {
+	ev := NewExpressionEvaluator()
+	env := aggregateEnv()
+
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{`sum(employees, "Payment")`, 15000.5},
+		{`sum(employees, "Age")`, 100},
+		{`sum(rows, "Qty")`, 5},
+		{`sum(empty, "Qty")`, 0},
+		{`employees | sum("Age")`, 100},
+		{`avg(employees, "Age")`, 100.0 / 3},
+		{`avg(rows, "Qty")`, 2.5},
+		{`avg([1, 2, 3, 6])`, 3.0},
+		{`avg(empty)`, nil},
+		{`min(employees, "Age")`, 25},
+		{`max(employees, "Payment")`, 6000.0},
+		{`max(employees, "Name")`, "Carol"},
+		{`min(employees, "Address.City")`, "London"},
+		{`max(empty, "Age")`, nil},
+		{`count(employees)`, 3},
+		{`count(rows)`, 4},
+		{`count(rows, "Qty")`, 2},
+		{`count(employees, "Active")`, 2},
+		{`count(flags)`, 2},
+		// expr's own forms are unchanged
+		{`sum(employees, #.Age * 2)`, 200},
+		{`sum([1, 2])`, 3},
+		{`count(employees, .Age > 28)`, 2},
+		{`max(1, 5, 3)`, 5},
+		{`min([4, 2])`, 2},
+	}
+	for _, tt := range tests {
+		got, err := ev.Evaluate(tt.expression, env)
+		require.NoError(t, err, tt.expression)
+		assert.Equal(t, tt.want, got, tt.expression)
+	}
+
+	_, err := ev.Evaluate(`sum(employees, "Name")`, env)
+	assert.ErrorContains(t, err, "sum: value Alice (string) is not a number")
+	_, err = ev.Evaluate(`avg(employees, "Age", "Payment")`, env)
+	assert.ErrorContains(t, err, "at most one field")
+}
+
+func TestAggregates_ValidateAndSandbox(t *testing.T) {
+	assert.NoError(t, compileSyntax(`sum(employees, "Salary") + count(employees)`))
+
+	ev := sandboxEvaluator(WithDeniedFunctions("sum"))
+	_, err := ev.Evaluate(`sum(employees, "Age")`, aggregateEnv())
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	got, err := ev.Evaluate(`max(employees, "Age")`, aggregateEnv())
+	require.NoError(t, err)
+	assert.Equal(t, 45, got)
+}
+
+func TestFill_AggregateHeader(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", `${count(employees)} employees`)
+	f.SetCellValue("Sheet1", "B1", `${sum(employees, "Salary")}`)
+	f.SetCellValue("Sheet1", "C1", `${avg(employees, "Salary")}`)
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Salary}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="B2")`})
+	tmpl := filepath.Join(testdataDir(t), "aggregate_template.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	out, err := FillBytes(tmpl, map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "Salary": 5000}, {"Name": "Bob", "Salary": 4000},
+	}})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	for cell, want := range map[string]string{"A1": "2 employees", "B1": "9000", "C1": "4500"} {
+		v, _ := res.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
@@ -0,0 +1,67 @@
+package xlfill
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AnchorCommand implements jx:anchor, which names the output cell of its first
+// template cell, e.g. a grand total below a jx:each whose row depends on the
+// data. After the fill the name is a workbook defined name, so a formula on any
+// sheet can use =grandTotal, and FillResult.AnchorRef and, in a pre-write
+// callback, ExcelizeTransformer.AnchorRef return the cell.
+//
+// An anchor inside jx:each is placed once per iteration; the name refers to
+// the first placement.
+type AnchorCommand struct {
+	Anchor string // the defined name, e.g. "grandTotal"
+	Area   *Area
+}
+
+func (c *AnchorCommand) Name() string { return "anchor" }
+func (c *AnchorCommand) Reset()       {}
+
+// anchorName matches the defined names Excel accepts for an anchor.
+var anchorName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// newAnchorCommandFromAttrs creates an AnchorCommand from parsed attributes.
+func newAnchorCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &AnchorCommand{Anchor: attrs["name"]}
+	if cmd.Anchor == "" {
+		return nil, fmt.Errorf("anchor command requires 'name' attribute")
+	}
+	if !anchorName.MatchString(cmd.Anchor) || bareCellRef.MatchString(strings.ToUpper(cmd.Anchor)) {
+		return nil, fmt.Errorf("anchor command: invalid name %q (expected a name such as grandTotal, not a cell reference)", cmd.Anchor)
+	}
+	return cmd, nil
+}
+
+// ApplyAt renders the area and records cellRef as a placement of the anchor.
+func (c *AnchorCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	size := Size{Width: 1, Height: 1}
+	if c.Area != nil {
+		var err error
+		size, err = c.Area.ApplyAt(cellRef, ctx)
+		if err != nil {
+			return ZeroSize, err
+		}
+	}
+	ctx.addAnchor(c.Anchor, cellRef)
+	return size, nil
+}
+
+// AnchorRef returns the output cell named by jx:anchor(name=...), or false when
+// no anchor with that name was placed.
+func (r *FillResult) AnchorRef(name string) (CellRef, bool) {
+	ref, ok := r.anchors[name]
+	return ref, ok
+}
+
+// AnchorRef returns the output cell named by jx:anchor(name=...), or false when
+// no anchor with that name was placed. It is set once all areas are filled, so
+// it can be used from a WithPreWrite callback.
+func (tx *ExcelizeTransformer) AnchorRef(name string) (CellRef, bool) {
+	ref, ok := tx.anchors[name]
+	return ref, ok
+}
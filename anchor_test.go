@@ -0,0 +1,117 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNewAnchorCommand(t *testing.T) {
+	cmd, err := newAnchorCommandFromAttrs(map[string]string{"name": "grand_total.2"})
+	require.NoError(t, err)
+	assert.Equal(t, "grand_total.2", cmd.(*AnchorCommand).Anchor)
+
+	_, err = newAnchorCommandFromAttrs(map[string]string{})
+	assert.ErrorContains(t, err, "requires 'name'")
+	for _, name := range []string{"grand total", "1st", "ab12", "$A$1"} {
+		_, err = newAnchorCommandFromAttrs(map[string]string{"name": name})
+		assert.ErrorContains(t, err, "invalid name", name)
+	}
+}
+
+// createAnchorTemplate creates a Data sheet listing lines with an anchored
+// total below them, and a Summary sheet whose formula reads the total.
+func createAnchorTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", "Summary")
+	f.SetCellValue("Summary", "A1", "Total")
+	f.SetCellFormula("Summary", "B1", "grandTotal*2")
+	f.AddComment("Summary", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B1")`})
+
+	f.NewSheet("Data")
+	f.SetCellValue("Data", "A1", "${e.Amount}")
+	f.SetCellFormula("Data", "A2", "SUM(A1)")
+	f.AddComment("Data", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A2")` + "\n" + `jx:each(items="lines" var="e" lastCell="A1")`})
+	f.AddComment("Data", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:anchor(name="grandTotal" lastCell="A2")`})
+
+	path := filepath.Join(testdataDir(t), "anchor_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestFill_Anchor(t *testing.T) {
+	data := map[string]any{"lines": []map[string]any{{"Amount": 1}, {"Amount": 2}, {"Amount": 3}}}
+
+	var preWrite CellRef
+	filler := NewFiller(WithTemplate(createAnchorTemplate(t)), WithPreWrite(func(tx Transformer) error {
+		preWrite, _ = tx.(*ExcelizeTransformer).AnchorRef("grandTotal")
+		return nil
+	}))
+	result, err := filler.FillWithResult(data)
+	require.NoError(t, err)
+
+	want := NewCellRef("Data", 3, 0)
+	ref, ok := result.AnchorRef("grandTotal")
+	require.True(t, ok)
+	assert.Equal(t, want, ref)
+	assert.Equal(t, want, preWrite)
+	_, ok = result.AnchorRef("missing")
+	assert.False(t, ok)
+
+	out := openOutput(t, result.Output)
+	formula, err := out.GetCellFormula("Data", "A4")
+	require.NoError(t, err)
+	assert.Equal(t, "SUM(A1:A3)", formula)
+	formula, err = out.GetCellFormula("Summary", "B1")
+	require.NoError(t, err)
+	assert.Equal(t, "grandTotal*2", formula)
+
+	var refersTo string
+	for _, dn := range out.GetDefinedName() {
+		if dn.Name == "grandTotal" {
+			refersTo = dn.RefersTo
+		}
+	}
+	assert.Equal(t, "Data!$A$4", refersTo)
+}
+
+func TestFill_AnchorInsideEach(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${e.Name}")
+	f.SetCellValue("Sheet1", "B1", "${e.Salary}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B1")` + "\n" + `jx:each(items="employees" var="e" lastCell="B1")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B1", Author: "xlfill", Text: `jx:anchor(name="firstSalary" lastCell="B1")`})
+	tmpl := filepath.Join(testdataDir(t), "anchor_each.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	employees := []map[string]any{{"Name": "Alice", "Salary": 1}, {"Name": "Bob", "Salary": 2}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		result, err := NewFiller(append([]Option{WithTemplate(tmpl)}, opts...)...).
+			FillWithResult(map[string]any{"employees": employees})
+		require.NoError(t, err)
+		ref, ok := result.AnchorRef("firstSalary")
+		require.True(t, ok)
+		assert.Equal(t, NewCellRef("Sheet1", 0, 1), ref)
+	}
+}
+
+func TestFill_AnchorNamedLikeArea(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${title}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1" name="report")` + "\n" + `jx:anchor(name="report" lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "anchor_area_name.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	_, err := FillBytes(tmpl, map[string]any{"title": "T"})
+	assert.ErrorContains(t, err, `anchor "report" has the name of an area`)
+}
@@ -1,12 +1,17 @@
 package xlfill
 
-import "fmt"
+import (
+	"fmt"
+	"log/slog"
+)
 
 // CommandBinding binds a Command to the area it operates on within a parent area.
 type CommandBinding struct {
 	Command  Command
-	StartRef CellRef // start cell of this command's area (relative to parent)
-	Size     Size    // size of this command's area
+	StartRef CellRef           // start cell of this command's area (relative to parent)
+	Size     Size              // size of this command's area
+	RenderIf string            // optional condition; the command is skipped when it is false
+	Attrs    map[string]string // attributes as written in the template comment (nil for programmatic commands)
 }
 
 // Area represents a rectangular region in a worksheet that can be processed.
@@ -16,6 +21,17 @@ type Area struct {
 	Bindings    []*CommandBinding
 	Transformer Transformer
 	Listeners   []AreaListener
+
+	// TemplateSheet is the disposition of this area's sheet after filling,
+	// from jx:area(templateSheet="keep|hide|delete").
+	TemplateSheet SheetDisposition
+
+	// Name identifies the area for FillArea, from jx:area(name="..."). The
+	// output range of a named area is saved as a workbook defined name.
+	Name string
+
+	formulaCells     []*CellData // formula cells inside the area, read on first apply
+	formulaCellsRead bool
 }
 
 // NewArea creates a new Area.
@@ -43,19 +59,94 @@ func (a *Area) ApplyAt(targetCell CellRef, ctx *Context) (Size, error) {
 		return ZeroSize, fmt.Errorf("area has no transformer")
 	}
 
-	// If no commands, just transform all cells (static area)
+	event := AreaEvent{Area: a, Source: a.SourceRef(), Target: targetCell}
+	for _, l := range a.Listeners {
+		if al, ok := l.(AreaApplyListener); ok {
+			al.BeforeApplyAtArea(event, ctx)
+		}
+	}
+	tracing := ctx.tracing()
+	if tracing {
+		ctx.trace(traceApply, 1, slog.String("src", event.Source.String()), slog.String("target", targetCell.String()))
+	}
+
+	var size Size
+	var err error
 	if len(a.Bindings) == 0 {
-		return a.transformStaticArea(targetCell, ctx)
+		// If no commands, just transform all cells (static area)
+		size, err = a.transformStaticArea(targetCell, ctx)
+	} else {
+		// Process with commands
+		size, err = a.processWithCommands(targetCell, ctx)
+	}
+	if tracing {
+		ctx.trace(traceApplyDone, -1, slog.String("src", event.Source.String()), slog.String("target", targetCell.String()), sizeAttr(size))
+	}
+	if err != nil {
+		return ZeroSize, err
+	}
+	ctx.run(func() error {
+		a.recordFormulaParentArea(targetCell, size)
+		return nil
+	})
+
+	event.Size = size
+	for _, l := range a.Listeners {
+		if al, ok := l.(AreaApplyListener); ok {
+			al.AfterApplyAtArea(event, ctx)
+		}
+	}
+	return size, nil
+}
+
+// SourceRef returns the template range covered by this area.
+func (a *Area) SourceRef() AreaRef {
+	last := NewCellRef(a.StartCell.Sheet, a.StartCell.Row+a.AreaSize.Height-1, a.StartCell.Col+a.AreaSize.Width-1)
+	return NewAreaRef(a.StartCell, last)
+}
+
+// recordFormulaParentArea records the output range of this application as the
+// parent area of formula targets written by it that have none yet. Inner areas
+// finish first, so each formula target is tied to the innermost area instance
+// containing it; the formula processor limits references to targets inside it.
+func (a *Area) recordFormulaParentArea(targetCell CellRef, size Size) {
+	if size.Width <= 0 || size.Height <= 0 {
+		return
+	}
+	if !a.formulaCellsRead {
+		for row := 0; row < a.AreaSize.Height; row++ {
+			for col := 0; col < a.AreaSize.Width; col++ {
+				cd := a.Transformer.GetCellData(NewCellRef(a.StartCell.Sheet, a.StartCell.Row+row, a.StartCell.Col+col))
+				if cd != nil && cd.IsFormulaCell() {
+					a.formulaCells = append(a.formulaCells, cd)
+				}
+			}
+		}
+		a.formulaCellsRead = true
+	}
+	if len(a.formulaCells) == 0 {
+		return
 	}
 
-	// Process with commands
-	return a.processWithCommands(targetCell, ctx)
+	parent := NewAreaRef(targetCell, NewCellRef(targetCell.Sheet, targetCell.Row+size.Height-1, targetCell.Col+size.Width-1))
+	for _, cd := range a.formulaCells {
+		for len(cd.TargetParentArea) < len(cd.TargetPositions) {
+			cd.TargetParentArea = append(cd.TargetParentArea, parent)
+		}
+	}
 }
 
 // transformStaticArea transforms all cells in the area without any command processing.
 func (a *Area) transformStaticArea(targetCell CellRef, ctx *Context) (Size, error) {
 	for row := 0; row < a.AreaSize.Height; row++ {
+		cells, sparse := a.rowCells(a.StartCell.Row+row, ctx)
+		if sparse && len(cells) == 0 {
+			continue
+		}
 		for col := 0; col < a.AreaSize.Width; col++ {
+			if sparse && cells[a.StartCell.Col+col] == nil {
+				continue
+			}
 			srcRef := NewCellRef(a.StartCell.Sheet, a.StartCell.Row+row, a.StartCell.Col+col)
 			dstRef := NewCellRef(targetCell.Sheet, targetCell.Row+row, targetCell.Col+col)
 			if err := a.transformCell(srcRef, dstRef, ctx); err != nil {
@@ -66,11 +157,34 @@ func (a *Area) transformStaticArea(targetCell CellRef, ctx *Context) (Size, erro
 	return a.AreaSize, nil
 }
 
+// templateRower is implemented by transformers that keep the template cells
+// by row, so areas can skip blank cells instead of transforming each of them.
+type templateRower interface {
+	templateRow(sheet string, row int) map[int]*CellData
+}
+
+// rowCells returns the template cells of row srcRow by column, and whether
+// cells missing from it may be skipped. Transforming such a cell writes
+// nothing; only area listeners and the trace, which see every cell, need
+// them visited.
+func (a *Area) rowCells(srcRow int, ctx *Context) (map[int]*CellData, bool) {
+	if len(a.Listeners) > 0 || ctx.tracing() {
+		return nil, false
+	}
+	tr, ok := unwrapTransformer(a.Transformer).(templateRower)
+	if !ok {
+		return nil, false
+	}
+	return tr.templateRow(a.StartCell.Sheet, srcRow), true
+}
+
 // transformCell transforms a single cell, firing listeners and injecting built-in variables.
 func (a *Area) transformCell(src, target CellRef, ctx *Context) error {
-	// Inject built-in position variables
-	ctx.setRunVar("_row", target.Row+1) // 1-based row number
-	ctx.setRunVar("_col", target.Col)   // 0-based column index
+	ctx.setPosition(target)
+	if ctx.state.usage != nil || ctx.state.errorPolicy == CollectAndContinue {
+		ctx.source = &src
+		defer func() { ctx.source = nil }()
+	}
 
 	// Fire before-transform listeners
 	for _, l := range a.Listeners {
@@ -83,6 +197,12 @@ func (a *Area) transformCell(src, target CellRef, ctx *Context) error {
 		}
 	}
 
+	if err := ctx.countCell(); err != nil {
+		return err
+	}
+	if ctx.tracing() {
+		ctx.trace(traceTransform, 0, slog.String("src", src.String()), slog.String("target", target.String()))
+	}
 	if err := a.Transformer.Transform(src, target, ctx, true); err != nil {
 		return err
 	}
@@ -122,17 +242,39 @@ func (a *Area) processWithCommands(targetCell CellRef, ctx *Context) (Size, erro
 		cmdColEnd := cmdColStart + binding.Size.Width
 		cmdRowCount := binding.Size.Height
 
-		if err := a.transformRows(binding.StartRef.Row, cmdRowCount, targetCell.Sheet, currentTargetRow, targetCell.Col, ctx, &colExclusion{start: cmdColStart, end: cmdColEnd}); err != nil {
+		// A command expanding to the right pushes the static cells after it, so
+		// those are transformed once the command's width is known.
+		expandsRight := isRightExpanding(binding.Command)
+		exclude := &colExclusion{start: cmdColStart, end: cmdColEnd}
+		if expandsRight {
+			exclude.end = a.AreaSize.Width
+		}
+		if err := a.transformRows(binding.StartRef.Row, cmdRowCount, targetCell.Sheet, currentTargetRow, targetCell.Col, ctx, exclude); err != nil {
 			return ZeroSize, err
 		}
 
 		// Execute command
 		cmdTarget := NewCellRef(targetCell.Sheet, currentTargetRow, targetCell.Col+cmdColStart)
-		cmdSize, err := binding.Command.ApplyAt(cmdTarget, ctx, a.Transformer)
+		cmdSize, err := a.applyCommand(binding, cmdTarget, ctx)
 		if err != nil {
 			return ZeroSize, fmt.Errorf("command %s (template %s) at target %s: %w", binding.Command.Name(), binding.StartRef, cmdTarget, err)
 		}
 
+		if expandsRight {
+			shift := cmdSize.Width - binding.Size.Width
+			if shift < 0 {
+				shift = 0
+			}
+			if cmdColEnd < a.AreaSize.Width {
+				if err := a.transformRows(binding.StartRef.Row, cmdRowCount, targetCell.Sheet, currentTargetRow, targetCell.Col+shift, ctx, &colExclusion{start: 0, end: cmdColEnd}); err != nil {
+					return ZeroSize, err
+				}
+			}
+			if a.AreaSize.Width+shift > maxWidth {
+				maxWidth = a.AreaSize.Width + shift
+			}
+		}
+
 		// Determine how many target rows this command band occupies.
 		// If the command spans the full area width, use command's actual height (allows contraction).
 		// If it's a partial-width command (static cells share the row), use at least source height.
@@ -162,6 +304,67 @@ func (a *Area) processWithCommands(targetCell CellRef, ctx *Context) (Size, erro
 	return Size{Width: maxWidth, Height: totalHeight}, nil
 }
 
+// applyCommand executes a bound command at the target cell, notifying command listeners.
+// A command whose renderIf condition is false is skipped and produces no output.
+func (a *Area) applyCommand(binding *CommandBinding, target CellRef, ctx *Context) (Size, error) {
+	// Command attributes see the position of the command's first output cell
+	ctx.setPosition(target)
+	if binding.RenderIf != "" {
+		render, err := ctx.IsConditionTrue(binding.RenderIf)
+		if err != nil {
+			return ZeroSize, fmt.Errorf("evaluate renderIf %q: %w", binding.RenderIf, err)
+		}
+		if !render {
+			return ZeroSize, nil
+		}
+	}
+
+	event := CommandEvent{
+		Name:    binding.Command.Name(),
+		Command: binding.Command,
+		Source: NewAreaRef(binding.StartRef, NewCellRef(binding.StartRef.Sheet,
+			binding.StartRef.Row+binding.Size.Height-1, binding.StartRef.Col+binding.Size.Width-1)),
+		Target: target,
+	}
+	for _, l := range a.Listeners {
+		if cl, ok := l.(CommandListener); ok {
+			cl.BeforeCommand(event, ctx)
+		}
+	}
+
+	tracing := ctx.tracing()
+	if tracing {
+		ctx.trace(traceCommand, 1, slog.String("command", event.Name), slog.String("src", event.Source.String()), slog.String("target", target.String()))
+	}
+	size, err := binding.Command.ApplyAt(target, ctx, transformerFor(a.Transformer, ctx))
+	if tracing {
+		ctx.trace(traceCommandDone, -1, slog.String("command", event.Name), slog.String("src", event.Source.String()), slog.String("target", target.String()), sizeAttr(size))
+	}
+	if err != nil {
+		return ZeroSize, err
+	}
+
+	event.Size = size
+	for _, l := range a.Listeners {
+		if cl, ok := l.(CommandListener); ok {
+			cl.AfterCommand(event, ctx)
+		}
+	}
+	return size, nil
+}
+
+// isRightExpanding reports whether a command grows to the right, shifting the
+// static cells that follow it on the same rows.
+func isRightExpanding(cmd Command) bool {
+	switch c := cmd.(type) {
+	case *EachCommand:
+		return (c.Direction == "RIGHT" || c.Direction == "DOWN_RIGHT") && c.MultiSheet == ""
+	case *GridCommand:
+		return c.Direction == "RIGHT"
+	}
+	return false
+}
+
 // colExclusion defines a column range to skip during row transformation.
 type colExclusion struct {
 	start int // inclusive, relative to area
@@ -172,10 +375,17 @@ type colExclusion struct {
 func (a *Area) transformRows(srcStartRow, rowCount int, targetSheet string, targetStartRow, targetStartCol int, ctx *Context, exclude *colExclusion) error {
 	for row := 0; row < rowCount; row++ {
 		srcRow := srcStartRow + row
+		cells, sparse := a.rowCells(srcRow, ctx)
+		if sparse && len(cells) == 0 {
+			continue
+		}
 		for col := 0; col < a.AreaSize.Width; col++ {
 			if exclude != nil && col >= exclude.start && col < exclude.end {
 				continue
 			}
+			if sparse && cells[a.StartCell.Col+col] == nil {
+				continue
+			}
 			srcRef := NewCellRef(a.StartCell.Sheet, srcRow, a.StartCell.Col+col)
 			dstRef := NewCellRef(targetSheet, targetStartRow+row, targetStartCol+col)
 			if err := a.transformCell(srcRef, dstRef, ctx); err != nil {
@@ -422,3 +422,104 @@ func TestArea_ApplyAt_TargetOffset(t *testing.T) {
 	v, _ = out.GetCellValue(sheet, "D5")
 	assert.Equal(t, "World", v)
 }
+
+func TestArea_ApplyAt_RenderIf(t *testing.T) {
+	// Template: A1 ${e.Name}, B1 ${e.Note} with jx:mergeCells(cols="2" renderIf="e.Wide")
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${e.Name}")
+	f.SetCellValue(sheet, "B1", "${e.Note}")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"C1\")\njx:each(items=\"items\" var=\"e\" lastCell=\"C1\")",
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "B1", Author: "xlfill",
+		Text: `jx:mergeCells(cols="2" renderIf="e.Wide" lastCell="B1")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	data := map[string]any{
+		"items": []map[string]any{
+			{"Name": "a", "Note": "wide", "Wide": true},
+			{"Name": "b", "Note": "narrow", "Wide": false},
+			{"Name": "c", "Note": "wide", "Wide": true},
+		},
+	}
+
+	outBytes, err := FillBytes(tmpPath, data)
+	require.NoError(t, err)
+
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	merges, err := out.GetMergeCells(sheet)
+	require.NoError(t, err)
+	var ranges []string
+	for _, m := range merges {
+		ranges = append(ranges, m.GetStartAxis()+":"+m.GetEndAxis())
+	}
+	assert.ElementsMatch(t, []string{"B1:C1", "B3:C3"}, ranges)
+
+	v, _ := out.GetCellValue(sheet, "A2")
+	assert.Equal(t, "b", v)
+}
+
+func TestArea_ApplyAt_RenderIfInvalid(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	area := NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 1}, tx)
+	area.AddCommand(&MergeCellsCommand{Cols: "2"}, NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 1})
+	area.Bindings[0].RenderIf = `"yes"`
+
+	_, err = area.ApplyAt(NewCellRef(sheet, 0, 0), NewContext(nil))
+	assert.ErrorContains(t, err, "renderIf")
+}
+
+func TestArea_ApplyAt_SparseRows(t *testing.T) {
+	// Only cells of the template are transformed; blank rows and columns of a
+	// wide area are skipped, while empty styled cells keep their style.
+	for _, concurrency := range []int{0, 4} {
+		f := excelize.NewFile()
+		sheet := "Sheet1"
+		f.SetCellValue(sheet, "A1", "${title}")
+		f.SetCellValue(sheet, "A3", "${e.Name}")
+		f.SetCellValue(sheet, "D3", "${e.Value}")
+		f.SetCellValue(sheet, "Z9", "end")
+		fill, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}})
+		require.NoError(t, err)
+		require.NoError(t, f.SetCellStyle(sheet, "B3", "B3", fill))
+		f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="Z9")`})
+		f.AddComment(sheet, excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="Z4")`})
+		var buf bytes.Buffer
+		require.NoError(t, f.Write(&buf))
+		f.Close()
+
+		data := map[string]any{"title": "Report", "items": []any{
+			map[string]any{"Name": "a", "Value": 1},
+			map[string]any{"Name": "b", "Value": 2},
+		}}
+		outBytes, err := NewFiller(WithTemplateReader(&buf), WithConcurrency(concurrency)).FillBytes(data)
+		require.NoError(t, err)
+		out := openOutput(t, outBytes)
+
+		for cell, want := range map[string]string{"A1": "Report", "A3": "a", "D3": "1", "A5": "b", "D5": "2", "Z11": "end"} {
+			v, _ := out.GetCellValue(sheet, cell)
+			assert.Equal(t, want, v, "cell %s, concurrency %d", cell, concurrency)
+		}
+		for _, cell := range []string{"B3", "B5"} {
+			id, _ := out.GetCellStyle(sheet, cell)
+			style, err := out.GetStyle(id)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"FFC7CE"}, style.Fill.Color, "cell %s, concurrency %d", cell, concurrency)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package xlfill
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// AreaDefinition declares one command outside the template, as an alternative
+// to a jx: cell comment. Cell is the cell that would hold the comment
+// (e.g. "Sheet1!A2"); Command is the command name without the "jx:" prefix
+// ("area", "each", "params", ...); Attrs are the command attributes.
+//
+// A JSON sidecar is an array of definitions:
+//
+//	[
+//	  {"cell": "Sheet1!A1", "command": "area", "attrs": {"lastCell": "C2"}},
+//	  {"cell": "Sheet1!A2", "command": "each", "attrs": {"items": "employees", "var": "e", "lastCell": "C2"}}
+//	]
+type AreaDefinition struct {
+	Cell    string            `json:"cell"`
+	Command string            `json:"command"`
+	Attrs   map[string]string `json:"attrs"`
+}
+
+// ParseAreaDefinitions decodes a JSON array of area definitions.
+func ParseAreaDefinitions(r io.Reader) ([]AreaDefinition, error) {
+	var defs []AreaDefinition
+	if err := json.NewDecoder(r).Decode(&defs); err != nil {
+		return nil, fmt.Errorf("decode area definitions: %w", err)
+	}
+	return defs, nil
+}
+
+// commandSource is a cell and the command text that applies to it, read from
+// a cell comment or rendered from area definitions with the markup prefix.
+type commandSource struct {
+	ref      CellRef
+	cellData *CellData // nil when the cell is empty in the template
+	comment  string
+}
+
+// commandSources returns the cells carrying commands: the template's cell
+// comments, or the configured area definitions when present. Comments of
+// authors other than WithMarkupAuthor's are left out.
+func (f *Filler) commandSources(tx Transformer) ([]commandSource, error) {
+	markup := f.opts.markup()
+	if f.opts.areaDefinitions == nil {
+		var sources []commandSource
+		for _, cd := range tx.GetCommentedCells() {
+			if !markup.reads(cd.CommentAuthor) {
+				continue
+			}
+			sources = append(sources, commandSource{ref: cd.Ref, cellData: cd, comment: cd.Comment})
+		}
+		if f.opts.inlineMarkers {
+			markers, err := inlineMarkerSources(tx, markup.prefix)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, markers...)
+		}
+		if f.opts.namedRangeAreas {
+			named, err := namedRangeSources(tx, markup.prefix)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, named...)
+		}
+		return sources, nil
+	}
+
+	defs, err := f.loadAreaDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group definitions by cell, keeping the order cells first appear in
+	lines := map[CellRef][]string{}
+	var refs []CellRef
+	for i, def := range defs {
+		ref, line, err := def.commentLine(markup.prefix)
+		if err != nil {
+			return nil, fmt.Errorf("area definition %d: %w", i, err)
+		}
+		if _, ok := lines[ref]; !ok {
+			refs = append(refs, ref)
+		}
+		lines[ref] = append(lines[ref], line)
+	}
+
+	sources := make([]commandSource, 0, len(refs))
+	for _, ref := range refs {
+		sources = append(sources, commandSource{
+			ref:      ref,
+			cellData: tx.GetCellData(ref),
+			comment:  strings.Join(lines[ref], "\n"),
+		})
+	}
+	return sources, nil
+}
+
+// prefixReader is implemented by transformers that can search the template
+// cells by their text.
+type prefixReader interface {
+	// GetCellsWithPrefix returns the template cells whose text starts with prefix.
+	GetCellsWithPrefix(prefix string) []*CellData
+}
+
+// cellsWithPrefix returns the template cells whose text starts with prefix.
+func cellsWithPrefix(tx Transformer, prefix string) ([]*CellData, error) {
+	pr, ok := unwrapTransformer(tx).(prefixReader)
+	if !ok {
+		return nil, fmt.Errorf("transformer %T cannot search cells by prefix", unwrapTransformer(tx))
+	}
+	return pr.GetCellsWithPrefix(prefix), nil
+}
+
+// inlineMarkerSources collects cells whose text is a command starting with
+// prefix and clears them, so markers never reach the output.
+func inlineMarkerSources(tx Transformer, prefix string) ([]commandSource, error) {
+	cells, err := cellsWithPrefix(tx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("inline markers: %w", err)
+	}
+	var sources []commandSource
+	for _, cd := range cells {
+		if cd.Ref.Sheet == configSheetName {
+			continue
+		}
+		sources = append(sources, commandSource{ref: cd.Ref, cellData: cd, comment: cd.Value.(string)})
+		cd.Value = nil
+		if err := tx.ClearCell(cd.Ref); err != nil {
+			return nil, fmt.Errorf("clear marker cell %s: %w", cd.Ref, err)
+		}
+	}
+	return sources, nil
+}
+
+// configSheetName is the sheet holding command definitions for named-range areas.
+// Column A holds the target cell (e.g. "Sheet1!A2"), column B the jx: command text.
+const configSheetName = "jx_config"
+
+// namedRangeAreaPrefix marks defined names that declare jx:area ranges, e.g. jxarea_1.
+const namedRangeAreaPrefix = "jxarea"
+
+// definedNameReader is implemented by transformers that know the workbook's
+// defined names.
+type definedNameReader interface {
+	// GetDefinedNames returns the workbook's defined names and what they refer to.
+	GetDefinedNames() map[string]string
+}
+
+// namedRangeSources turns jxarea* defined names into jx:area commands and reads
+// further commands, starting with prefix, from the jx_config sheet.
+func namedRangeSources(tx Transformer, prefix string) ([]commandSource, error) {
+	dr, ok := unwrapTransformer(tx).(definedNameReader)
+	if !ok {
+		return nil, fmt.Errorf("named range areas: transformer %T cannot read defined names", unwrapTransformer(tx))
+	}
+	defined := dr.GetDefinedNames()
+	names := make([]string, 0, len(defined))
+	for name := range defined {
+		if strings.HasPrefix(strings.ToLower(name), namedRangeAreaPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var sources []commandSource
+	for _, name := range names {
+		refersTo := strings.TrimPrefix(strings.TrimSpace(defined[name]), "=")
+		var area AreaRef
+		var err error
+		if strings.Contains(refersTo, ":") {
+			area, err = ParseAreaRef(refersTo)
+		} else {
+			var cell CellRef
+			cell, err = ParseCellRef(refersTo)
+			area = NewAreaRef(cell, cell)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("defined name %q: %w", name, err)
+		}
+		if area.First.Sheet == "" {
+			return nil, fmt.Errorf("defined name %q: reference %q must include a sheet name", name, refersTo)
+		}
+		sources = append(sources, commandSource{
+			ref:      area.First,
+			cellData: tx.GetCellData(area.First),
+			comment:  fmt.Sprintf(`%sarea(lastCell="%s")`, prefix, area.Last.CellName()),
+		})
+	}
+
+	cells, err := cellsWithPrefix(tx, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%s sheet: %w", configSheetName, err)
+	}
+	for _, cd := range cells {
+		if cd.Ref.Sheet != configSheetName || cd.Ref.Col != 1 {
+			continue
+		}
+		var target string
+		if td := tx.GetCellData(NewCellRef(configSheetName, cd.Ref.Row, 0)); td != nil {
+			target, _ = td.Value.(string)
+		}
+		ref, err := ParseCellRef(target)
+		if err != nil || ref.Sheet == "" {
+			return nil, fmt.Errorf("%s: command target %q must be a cell like Sheet1!A2", cd.Ref, target)
+		}
+		sources = append(sources, commandSource{ref: ref, cellData: tx.GetCellData(ref), comment: cd.Value.(string)})
+	}
+	return sources, nil
+}
+
+// loadAreaDefinitions reads the area definitions once; the reader cannot be re-read.
+func (f *Filler) loadAreaDefinitions() ([]AreaDefinition, error) {
+	if f.areaDefs == nil && f.areaDefsErr == nil {
+		f.areaDefs, f.areaDefsErr = ParseAreaDefinitions(f.opts.areaDefinitions)
+		if f.areaDefs == nil && f.areaDefsErr == nil {
+			f.areaDefs = []AreaDefinition{}
+		}
+	}
+	return f.areaDefs, f.areaDefsErr
+}
+
+// commentLine renders the definition as the equivalent comment line, with
+// command prefix.
+func (d AreaDefinition) commentLine(prefix string) (CellRef, string, error) {
+	ref, err := ParseCellRef(d.Cell)
+	if err != nil {
+		return CellRef{}, "", fmt.Errorf("invalid cell %q: %w", d.Cell, err)
+	}
+	if ref.Sheet == "" {
+		return CellRef{}, "", fmt.Errorf("cell %q must include a sheet name", d.Cell)
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(d.Command), commandPrefix), prefix)
+	if name == "" {
+		return CellRef{}, "", fmt.Errorf("missing command at %s", d.Cell)
+	}
+
+	keys := make([]string, 0, len(d.Attrs))
+	for k := range d.Attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(prefix + name + "(")
+	for i, k := range keys {
+		v := d.Attrs[k]
+		quote := `"`
+		if strings.Contains(v, `"`) {
+			if strings.Contains(v, "'") {
+				return CellRef{}, "", fmt.Errorf("attribute %s of %s at %s contains both quote characters", k, name, d.Cell)
+			}
+			quote = "'"
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k + "=" + quote + v + quote)
+	}
+	b.WriteByte(')')
+	return ref, b.String(), nil
+}
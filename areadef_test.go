@@ -0,0 +1,221 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createCommentlessTemplate creates a template with expressions but no comments.
+func createCommentlessTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Name")
+	f.SetCellValue(sheet, "B1", "City")
+	f.SetCellValue(sheet, "A2", "${e.Name}")
+	f.SetCellValue(sheet, "B2", "${e.City}")
+
+	path := filepath.Join(testdataDir(t), "commentless_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestFill_WithAreaDefinitions(t *testing.T) {
+	tmpl := createCommentlessTemplate(t)
+	defs := `[
+		{"cell": "Sheet1!A1", "command": "area", "attrs": {"lastCell": "B2"}},
+		{"cell": "Sheet1!A2", "command": "jx:each", "attrs": {"items": "employees", "var": "e", "select": "e.City != \"Paris\"", "lastCell": "B2"}}
+	]`
+	data := map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "City": "Berlin"},
+		{"Name": "Bob", "City": "Paris"},
+		{"Name": "Carol", "City": nil},
+	}}
+
+	out, err := FillBytes(tmpl, data, WithAreaDefinitions(strings.NewReader(defs)))
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, want := range map[string]string{
+		"A1": "Name", "A2": "Alice", "B2": "Berlin",
+		"A3": "Carol", "B3": "", "A4": "",
+	} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestBuildAreas_AreaDefinitionParams(t *testing.T) {
+	tx, err := OpenTemplate(createCommentlessTemplate(t))
+	require.NoError(t, err)
+	defer tx.Close()
+
+	defs := `[{"cell": "Sheet1!A1", "command": "area", "attrs": {"lastCell": "B2"}},
+		{"cell": "Sheet1!B2", "command": "params", "attrs": {"defaultValue": "0"}},
+		{"cell": "Sheet1!C9", "command": "params", "attrs": {"defaultValue": "0"}}]`
+	filler := NewFiller(WithAreaDefinitions(strings.NewReader(defs)))
+	_, err = filler.BuildAreas(tx)
+	require.NoError(t, err, "params on an empty cell are ignored")
+	assert.Equal(t, "0", tx.GetCellData(NewCellRef("Sheet1", 1, 1)).DefaultValue)
+
+	_, err = filler.BuildAreas(tx)
+	require.NoError(t, err, "definitions are cached after the reader is consumed")
+}
+
+func TestFill_WithoutAreaDefinitions_NoComments(t *testing.T) {
+	_, err := FillBytes(createCommentlessTemplate(t), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no commented cells")
+}
+
+func TestAreaDefinitions_Describe(t *testing.T) {
+	defs := `[{"cell": "Sheet1!A1", "command": "area", "attrs": {"lastCell": "B2"}},
+		{"cell": "Sheet1!A2", "command": "each", "attrs": {"items": "employees", "var": "e", "lastCell": "B2"}}]`
+	out, err := Describe(createCommentlessTemplate(t), WithAreaDefinitions(strings.NewReader(defs)))
+	require.NoError(t, err)
+	assert.Contains(t, out, `each (2x1) items="employees"`)
+}
+
+func TestAreaDefinitions_Errors(t *testing.T) {
+	tmpl := createCommentlessTemplate(t)
+	tests := []struct {
+		name string
+		defs string
+		want string
+	}{
+		{"bad json", `{`, "decode area definitions"},
+		{"bad cell", `[{"cell": "Sheet1!??", "command": "area", "attrs": {"lastCell": "B2"}}]`, "invalid cell"},
+		{"no sheet", `[{"cell": "A1", "command": "area", "attrs": {"lastCell": "B2"}}]`, "must include a sheet name"},
+		{"no command", `[{"cell": "Sheet1!A1", "attrs": {"lastCell": "B2"}}]`, "missing command"},
+		{"both quotes", `[{"cell": "Sheet1!A1", "command": "if", "attrs": {"condition": "a == \"x'\"", "lastCell": "B2"}}]`, "both quote characters"},
+		{"missing lastCell", `[{"cell": "Sheet1!A1", "command": "area", "attrs": {}}]`, "missing lastCell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FillBytes(tmpl, nil, WithAreaDefinitions(strings.NewReader(tt.defs)))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestAreaDefinition_CommentLine(t *testing.T) {
+	def := AreaDefinition{
+		Cell:    "Data!B3",
+		Command: "each",
+		Attrs:   map[string]string{"items": "rows", "var": "r", "select": `r.Kind == "A"`, "lastCell": "C3"},
+	}
+	ref, line, err := def.commentLine(commandPrefix)
+	require.NoError(t, err)
+	assert.Equal(t, NewCellRef("Data", 2, 1), ref)
+	assert.Equal(t, `jx:each(items="rows" lastCell="C3" select='r.Kind == "A"' var="r")`, line)
+
+	cmds, _, err := ParseComment(line, ref)
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, `r.Kind == "A"`, cmds[0].Attrs["select"])
+}
+
+func TestFill_WithInlineMarkers(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", `jx:area(lastCell="C2")`)
+	f.SetCellValue(sheet, "B1", "Name")
+	f.SetCellValue(sheet, "C1", "City")
+	f.SetCellValue(sheet, "A2", "jx:each(items=\"employees\" var=\"e\" lastCell=\"C2\")\njx:params(defaultValue=\"0\")")
+	f.SetCellValue(sheet, "B2", "${e.Name}")
+	f.SetCellValue(sheet, "C2", "${e.City}")
+	tmpl := filepath.Join(testdataDir(t), "inline_markers_template.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "City": "Berlin"},
+		{"Name": "Bob", "City": "Paris"},
+	}}
+	out, err := FillBytes(tmpl, data, WithInlineMarkers(true))
+	require.NoError(t, err)
+
+	res, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer res.Close()
+
+	for cell, want := range map[string]string{
+		"A1": "", "B1": "Name", "C1": "City",
+		"A2": "", "B2": "Alice", "C2": "Berlin",
+		"A3": "", "B3": "Bob", "C3": "Paris",
+	} {
+		v, _ := res.GetCellValue(sheet, cell)
+		assert.Equal(t, want, v, cell)
+	}
+
+	// Without the option the markers are plain text and there is no area
+	_, err = FillBytes(tmpl, data)
+	require.Error(t, err)
+}
+
+func TestFill_WithNamedRangeAreas(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Name")
+	f.SetCellValue(sheet, "B1", "City")
+	f.SetCellValue(sheet, "A2", "${e.Name}")
+	f.SetCellValue(sheet, "B2", "${e.City}")
+	require.NoError(t, f.SetDefinedName(&excelize.DefinedName{Name: "jxarea_1", RefersTo: "Sheet1!$A$1:$B$2"}))
+	_, err := f.NewSheet("jx_config")
+	require.NoError(t, err)
+	f.SetCellValue("jx_config", "A1", "Cell")
+	f.SetCellValue("jx_config", "B1", "Command")
+	f.SetCellValue("jx_config", "A2", "Sheet1!A2")
+	f.SetCellValue("jx_config", "B2", `jx:each(items="employees" var="e" lastCell="B2")`)
+	require.NoError(t, f.SetSheetVisible("jx_config", false))
+	tmpl := filepath.Join(testdataDir(t), "named_range_template.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "City": "Berlin"},
+		{"Name": "Bob", "City": "Paris"},
+	}}
+	out, err := FillBytes(tmpl, data, WithNamedRangeAreas(true))
+	require.NoError(t, err)
+
+	res, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer res.Close()
+
+	assert.Equal(t, []string{"Sheet1"}, res.GetSheetList(), "jx_config sheet removed")
+	for cell, want := range map[string]string{
+		"A1": "Name", "A2": "Alice", "B2": "Berlin", "A3": "Bob", "B3": "Paris",
+	} {
+		v, _ := res.GetCellValue(sheet, cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestNamedRangeAreas_InvalidTarget(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "x")
+	require.NoError(t, f.SetDefinedName(&excelize.DefinedName{Name: "jxarea_main", RefersTo: "Sheet1!$A$1:$B$2"}))
+	_, err := f.NewSheet("jx_config")
+	require.NoError(t, err)
+	f.SetCellValue("jx_config", "A1", "A2")
+	f.SetCellValue("jx_config", "B1", `jx:each(items="employees" var="e" lastCell="B2")`)
+	tmpl := filepath.Join(testdataDir(t), "named_range_invalid.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	_, err = FillBytes(tmpl, nil, WithNamedRangeAreas(true))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be a cell like Sheet1!A2")
+}
@@ -0,0 +1,192 @@
+package xlfill
+
+import (
+	"fmt"
+	"sort"
+)
+
+// areaLayout places root areas that share a sheet. Areas are processed top to
+// bottom; an area below (or to the right of) an area that grew is shifted so the
+// gap between them in the template is preserved in the output.
+type areaLayout struct {
+	placed   map[string][]placedArea // by sheet, in processing order
+	inserted map[string][]rowInsert  // rows inserted with WithShiftOutsideContent, by sheet
+	sheet    string                  // output sheet when the areas fill a copy of their sheet
+}
+
+// rowInsert records output rows inserted below a template row, which move the
+// template content below that row down by count rows.
+type rowInsert struct {
+	after int // template row
+	count int
+}
+
+// placedArea records a processed root area's template and output rectangles.
+type placedArea struct {
+	source AreaRef
+	output AreaRef
+}
+
+func newAreaLayout() *areaLayout {
+	return &areaLayout{placed: make(map[string][]placedArea), inserted: make(map[string][]rowInsert)}
+}
+
+// moved returns where the template cell ref is in the output workbook after
+// the rows inserted so far.
+func (l *areaLayout) moved(ref CellRef) CellRef {
+	for _, ins := range l.inserted[ref.Sheet] {
+		if ins.after < ref.Row {
+			ref.Row += ins.count
+		}
+	}
+	return ref
+}
+
+// output returns where the template cell ref is in the output: moved by the
+// inserted rows and, when the areas fill a copy of their sheet, on the copy.
+func (l *areaLayout) output(ref CellRef) CellRef {
+	ref = l.moved(ref)
+	if l.sheet != "" {
+		ref.Sheet = l.sheet
+	}
+	return ref
+}
+
+// insertRows makes room below the output of an area that grew by inserting
+// rows into the output workbook, so the content below keeps its distance to
+// the area's end. Rows already inserted below the same template row, by an
+// area beside this one, are reused.
+func (l *areaLayout) insertRows(ctx *Context, tx *ExcelizeTransformer, area *Area, target CellRef, size Size) error {
+	src := area.SourceRef()
+	free := 0
+	for _, ins := range l.inserted[src.First.Sheet] {
+		if ins.after == src.Last.Row {
+			free += ins.count
+		}
+	}
+	need := size.Height - src.Size().Height - free
+	if need <= 0 {
+		return nil
+	}
+	l.inserted[src.First.Sheet] = append(l.inserted[src.First.Sheet], rowInsert{after: src.Last.Row, count: need})
+	row := target.Row + src.Size().Height + free
+	return ctx.run(func() error { return tx.insertRows(target.Sheet, row, need) })
+}
+
+// target returns where area should be applied given the areas placed so far.
+func (l *areaLayout) target(area *Area) CellRef {
+	src := area.SourceRef()
+	start := l.output(src.First)
+	row, col := start.Row, start.Col
+	for _, p := range l.placed[start.Sheet] {
+		switch {
+		case p.source.Last.Row < src.First.Row && spansOverlap(p.source.First.Col, p.source.Last.Col, src.First.Col, src.Last.Col):
+			// p is above: keep the template gap below p's output
+			row = max(row, p.output.Last.Row+src.First.Row-p.source.Last.Row)
+		case p.source.Last.Col < src.First.Col && spansOverlap(p.source.First.Row, p.source.Last.Row, src.First.Row, src.Last.Row):
+			// p is to the left: keep the template gap right of p's output
+			col = max(col, p.output.Last.Col+src.First.Col-p.source.Last.Col)
+		}
+	}
+	return NewCellRef(start.Sheet, row, col)
+}
+
+// place records the output of an area and reports a collision with the output
+// of an area placed earlier on the same sheet.
+func (l *areaLayout) place(ctx *Context, area *Area, target CellRef, size Size) error {
+	src := area.SourceRef()
+	out := outputRef(target, size)
+
+	var collision error
+	if size.Width > 0 && size.Height > 0 {
+		for _, p := range l.placed[target.Sheet] {
+			if p.output.Last.Row < p.output.First.Row || p.output.Last.Col < p.output.First.Col {
+				continue // empty output
+			}
+			if spansOverlap(p.output.First.Row, p.output.Last.Row, out.First.Row, out.Last.Row) &&
+				spansOverlap(p.output.First.Col, p.output.Last.Col, out.First.Col, out.Last.Col) {
+				collision = fmt.Errorf("area %s (output %s) overlaps the output %s of area %s", src, out, p.output, p.source)
+				break
+			}
+		}
+	}
+
+	// An area never reserves less than its template footprint
+	if size.Height < src.Size().Height {
+		out.Last.Row = target.Row + src.Size().Height - 1
+	}
+	if size.Width < src.Size().Width {
+		out.Last.Col = target.Col + src.Size().Width - 1
+	}
+	l.placed[target.Sheet] = append(l.placed[target.Sheet], placedArea{source: src, output: out})
+
+	if target != l.output(area.StartCell) {
+		l.clearVacated(ctx, area)
+	}
+	return collision
+}
+
+// clearVacated clears template cells of a shifted area that no area's output covers,
+// so expressions do not linger in the gap the area moved away from. Rows inserted
+// above the area have already moved its template cells with them.
+func (l *areaLayout) clearVacated(ctx *Context, area *Area) {
+	if area.Transformer == nil {
+		return
+	}
+	src := area.SourceRef()
+	for row := src.First.Row; row <= src.Last.Row; row++ {
+	cells:
+		for col := src.First.Col; col <= src.Last.Col; col++ {
+			ref := NewCellRef(src.First.Sheet, row, col)
+			at := l.output(ref)
+			for _, p := range l.placed[at.Sheet] {
+				if p.output.Contains(at) {
+					continue cells
+				}
+			}
+			if area.Transformer.GetCellData(ref) != nil {
+				ctx.run(func() error { return area.Transformer.ClearCell(at) })
+			}
+		}
+	}
+}
+
+// outputs returns the output ranges of all placed areas.
+func (l *areaLayout) outputs() []AreaRef {
+	var refs []AreaRef
+	for _, placed := range l.placed {
+		for _, p := range placed {
+			refs = append(refs, p.output)
+		}
+	}
+	return refs
+}
+
+// outputRef returns the range of size cells starting at target.
+func outputRef(target CellRef, size Size) AreaRef {
+	return NewAreaRef(target, NewCellRef(target.Sheet, target.Row+size.Height-1, target.Col+size.Width-1))
+}
+
+// spansOverlap reports whether the inclusive ranges [a1,a2] and [b1,b2] intersect.
+func spansOverlap(a1, a2, b1, b2 int) bool {
+	return a1 <= b2 && b1 <= a2
+}
+
+// sortRootAreas orders areas by sheet position in the workbook, then top to
+// bottom and left to right.
+func sortRootAreas(areas []*Area, sheetNames []string) {
+	sheetIndex := make(map[string]int, len(sheetNames))
+	for i, name := range sheetNames {
+		sheetIndex[name] = i
+	}
+	sort.SliceStable(areas, func(i, j int) bool {
+		a, b := areas[i].StartCell, areas[j].StartCell
+		if a.Sheet != b.Sheet {
+			return sheetIndex[a.Sheet] < sheetIndex[b.Sheet]
+		}
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Col < b.Col
+	})
+}
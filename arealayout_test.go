@@ -0,0 +1,239 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createStackedAreasTemplate creates a sheet with an employee list (A1:B2) and,
+// after a blank row, a department list (A4:B5). Each area has a header row and
+// an each row.
+func createStackedAreasTemplate(t *testing.T, name string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Employees")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Age}")
+	f.SetCellValue("Sheet1", "A4", "Departments")
+	f.SetCellValue("Sheet1", "A5", "${d.Name}")
+	f.SetCellValue("Sheet1", "B5", "${d.Head}")
+	// Comments are added bottom area first; processing order must not depend on it
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A4", Author: "xlfill", Text: `jx:area(lastCell="B5")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A5", Author: "xlfill", Text: `jx:each(items="depts" var="d" lastCell="B5")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="emps" var="e" lastCell="B2")`})
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestAreaLayout_StackedAreasShiftDown(t *testing.T) {
+	tmpl := createStackedAreasTemplate(t, "layout_stacked.xlsx")
+	out, err := FillBytes(tmpl, map[string]any{
+		"emps":  []map[string]any{{"Name": "Alice", "Age": 30}, {"Name": "Bob", "Age": 25}, {"Name": "Carol", "Age": 41}},
+		"depts": []map[string]any{{"Name": "Sales", "Head": "Dan"}, {"Name": "Ops", "Head": "Eve"}},
+	})
+	require.NoError(t, err)
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	for len(rows) < 8 {
+		rows = append(rows, nil)
+	}
+	assert.Equal(t, []string{"Employees"}, rows[0])
+	assert.Equal(t, []string{"Alice", "30"}, rows[1])
+	assert.Equal(t, []string{"Carol", "41"}, rows[3])
+	assert.Empty(t, rows[4], "blank row between areas is preserved")
+	assert.Equal(t, []string{"Departments"}, rows[5])
+	assert.Equal(t, []string{"Sales", "Dan"}, rows[6])
+	assert.Equal(t, []string{"Ops", "Eve"}, rows[7])
+}
+
+func TestAreaLayout_SideBySideAreasShiftRight(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${m}")
+	f.SetCellValue("Sheet1", "C1", "Total")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:each(items=\"months\" var=\"m\" direction=\"RIGHT\" lastCell=\"A1\")"})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "C1", Author: "xlfill", Text: `jx:area(lastCell="C1")`})
+	path := filepath.Join(testdataDir(t), "layout_side.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	out, err := FillBytes(path, map[string]any{"months": []string{"Jan", "Feb", "Mar"}})
+	require.NoError(t, err)
+	res, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer res.Close()
+
+	rows, err := res.GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Jan", "Feb", "Mar", "", "Total"}, rows[0])
+}
+
+// createDiagonalAreasTemplate creates an area at A1 that grows down and right and
+// a static area at C3 that it runs into.
+func createDiagonalAreasTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${v}")
+	f.SetCellValue("Sheet1", "C3", "Notes")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:grid(headers=\"headers\" data=\"data\" lastCell=\"A1\")"})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "C3", Author: "xlfill", Text: `jx:area(lastCell="C3")`})
+	path := filepath.Join(testdataDir(t), "layout_diagonal.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestAreaLayout_CollisionCheck(t *testing.T) {
+	tmpl := createDiagonalAreasTemplate(t)
+	data := map[string]any{
+		"headers": []string{"A", "B", "C", "D"},
+		"data":    [][]any{{1, 2, 3, 4}, {5, 6, 7, 8}, {9, 10, 11, 12}},
+	}
+
+	_, err := FillBytes(tmpl, data)
+	require.NoError(t, err, "collisions are tolerated without the check")
+
+	_, err = FillBytes(tmpl, data, WithAreaCollisionCheck(true))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Sheet1!C3")
+	assert.Contains(t, err.Error(), "overlaps")
+
+	_, err = FillBytes(tmpl, map[string]any{"headers": []string{"A"}, "data": [][]any{{1}}}, WithAreaCollisionCheck(true))
+	assert.NoError(t, err, "no collision when the first area stays small")
+}
+
+func TestSortRootAreas(t *testing.T) {
+	areas := []*Area{
+		{StartCell: NewCellRef("Sheet1", 5, 0)},
+		{StartCell: NewCellRef("Summary", 0, 0)},
+		{StartCell: NewCellRef("Sheet1", 0, 3)},
+		{StartCell: NewCellRef("Sheet1", 0, 0)},
+	}
+	sortRootAreas(areas, []string{"Sheet1", "Summary"})
+	var got []string
+	for _, a := range areas {
+		got = append(got, a.StartCell.String())
+	}
+	assert.Equal(t, []string{"Sheet1!A1", "Sheet1!D1", "Sheet1!A6", "Summary!A1"}, got)
+}
+
+func TestValidate_OverlappingAreas(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "x")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B2", Author: "xlfill", Text: `jx:area(lastCell="C4")`})
+	path := filepath.Join(testdataDir(t), "layout_overlap.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	issues, err := Validate(path)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, SeverityWarning, issues[0].Severity)
+	assert.Equal(t, "Sheet1!B2", issues[0].CellRef.String())
+	assert.Contains(t, issues[0].Message, "overlaps")
+}
+
+func TestAreaLayout_ShiftOutsideContent(t *testing.T) {
+	// A list with a total, and below it a signature block outside every area
+	// followed by a second area
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	tmpl.SetCellValue("Sheet1", "A1", "Report")
+	tmpl.SetCellValue("Sheet1", "A2", "${e.Name}")
+	tmpl.SetCellValue("Sheet1", "B2", "${e.Value}")
+	tmpl.SetCellValue("Sheet1", "A3", "Total")
+	tmpl.SetCellFormula("Sheet1", "B3", "SUM(B2)")
+	tmpl.SetCellValue("Sheet1", "A5", "Signed:")
+	require.NoError(t, tmpl.MergeCell("Sheet1", "B5", "C5"))
+	tmpl.SetCellFormula("Sheet1", "A6", "B3*2")
+	tmpl.SetCellValue("Sheet1", "A8", "${title}")
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A5", Author: "xlfill", Text: "Sign here"})
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C3")`})
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="C2")`})
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A8", Author: "xlfill", Text: `jx:area(lastCell="B8")`})
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	data := map[string]any{"title": "Appendix", "items": []map[string]any{
+		{"Name": "a", "Value": 1}, {"Name": "b", "Value": 2}, {"Name": "c", "Value": 3}, {"Name": "d", "Value": 4},
+	}}
+	for _, concurrency := range []int{0, 4} {
+		out, err := NewFiller(WithTemplateReader(bytes.NewReader(buf.Bytes())), WithShiftOutsideContent(true),
+			WithProcessFormulasOutsideAreas(true), WithStripMarkupComments(true), WithConcurrency(concurrency)).FillBytes(data)
+		require.NoError(t, err)
+		f := openOutput(t, out)
+
+		// The list grew by three rows, so everything below moved down by three
+		for cell, want := range map[string]string{"A5": "d", "A6": "Total", "A7": "", "A8": "Signed:", "A11": "Appendix"} {
+			v, _ := f.GetCellValue("Sheet1", cell)
+			assert.Equal(t, want, v, "cell %s, concurrency %d", cell, concurrency)
+		}
+		formula, _ := f.GetCellFormula("Sheet1", "B6")
+		assert.Equal(t, "SUM(B2:B5)", formula)
+		formula, _ = f.GetCellFormula("Sheet1", "A9")
+		assert.Equal(t, "B6*2", formula)
+		merged, err := f.GetMergeCells("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, merged, 1)
+		assert.Equal(t, "B8", merged[0].GetStartAxis())
+		comments, err := f.GetComments("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+		assert.Equal(t, "A8", comments[0].Cell)
+	}
+
+	// Without the option the list overwrites the signature block
+	out, err := NewFiller(WithTemplateReader(bytes.NewReader(buf.Bytes()))).FillBytes(data)
+	require.NoError(t, err)
+	v, _ := openOutput(t, out).GetCellValue("Sheet1", "A5")
+	assert.Equal(t, "d", v)
+	v, _ = openOutput(t, out).GetCellValue("Sheet1", "A8")
+	assert.NotEqual(t, "Signed:", v)
+}
+
+func TestAreaLayout_ShiftOutsideContentBesideAreas(t *testing.T) {
+	// Two lists side by side share the rows inserted below them
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	tmpl.SetCellValue("Sheet1", "A1", "${e.Name}")
+	tmpl.SetCellValue("Sheet1", "D1", "${d.Name}")
+	tmpl.SetCellValue("Sheet1", "A3", "Note")
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B1")` + "\n" + `jx:each(items="emps" var="e" lastCell="B1")`})
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "D1", Author: "xlfill",
+		Text: `jx:area(lastCell="E1")` + "\n" + `jx:each(items="depts" var="d" lastCell="E1")`})
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	names := func(n int) []map[string]any {
+		var items []map[string]any
+		for i := range n {
+			items = append(items, map[string]any{"Name": string(rune('a' + i))})
+		}
+		return items
+	}
+	out, err := NewFiller(WithTemplateReader(&buf), WithShiftOutsideContent(true)).FillBytes(map[string]any{
+		"emps": names(3), "depts": names(4),
+	})
+	require.NoError(t, err)
+	f := openOutput(t, out)
+	cols, err := f.GetCols("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "", "", "Note"}, cols[0])
+	assert.Equal(t, []string{"a", "b", "c", "d"}, cols[3][:4])
+}
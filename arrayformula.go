@@ -0,0 +1,82 @@
+package xlfill
+
+import (
+	"github.com/xuri/excelize/v2"
+)
+
+// readArrayFormulas records the range of each array formula of a template
+// sheet on its formula cell. The other cells of the range only hold results
+// cached by Excel, so they are read as blank. Shared formulas need nothing
+// here: excelize reads each cell of a shared formula as its own formula, and
+// they are written as ordinary formulas, which Excel treats alike.
+func (tx *ExcelizeTransformer) readArrayFormulas(sheet string, sd *SheetData) {
+	ws, _, err := worksheetOf(tx.src, sheet)
+	if err != nil {
+		return
+	}
+	rows := ws.FieldByName("SheetData").FieldByName("Row")
+	for i := range rows.Len() {
+		cells := rows.Index(i).FieldByName("C")
+		for j := range cells.Len() {
+			f := cells.Index(j).FieldByName("F")
+			if f.IsNil() || f.Elem().FieldByName("T").String() != excelize.STCellFormulaTypeArray {
+				continue
+			}
+			anchor, err := ParseCellRef(sheet + "!" + cells.Index(j).FieldByName("R").String())
+			if err != nil {
+				continue
+			}
+			area := NewAreaRef(anchor, anchor)
+			if ref := f.Elem().FieldByName("Ref").String(); ref != "" {
+				if r, err := parseArrayRef(sheet, ref); err == nil {
+					area = r
+				}
+			}
+			if cd := sd.cell(anchor); cd != nil {
+				cd.ArraySize = area.Size()
+			}
+			for row := area.First.Row; row <= area.Last.Row; row++ {
+				for col := area.First.Col; col <= area.Last.Col; col++ {
+					if cd := sd.cell(NewCellRef(sheet, row, col)); cd != nil && cd.Ref != anchor {
+						cd.Value, cd.Type = nil, CellBlank
+					}
+				}
+			}
+		}
+	}
+}
+
+// parseArrayRef parses the range of an array formula, "C2" or "C2:D3".
+func parseArrayRef(sheet, ref string) (AreaRef, error) {
+	if area, err := ParseAreaRef(sheet + "!" + ref); err == nil {
+		return area, nil
+	}
+	cell, err := ParseCellRef(sheet + "!" + ref)
+	if err != nil {
+		return AreaRef{}, err
+	}
+	return NewAreaRef(cell, cell), nil
+}
+
+// cell returns the cell data at ref, nil if the sheet has none there.
+func (sd *SheetData) cell(ref CellRef) *CellData {
+	if rd, ok := sd.Rows[ref.Row]; ok {
+		return rd.Cells[ref.Col]
+	}
+	return nil
+}
+
+// setFormula writes formula at ref, as an array formula over a range of
+// arraySize when it is not zero.
+func (tx *ExcelizeTransformer) setFormula(ref CellRef, formula string, arraySize Size) error {
+	if arraySize.Width <= 0 || arraySize.Height <= 0 {
+		return tx.file.SetCellFormula(ref.Sheet, ref.CellName(), formula)
+	}
+	last := NewCellRef(ref.Sheet, ref.Row+arraySize.Height-1, ref.Col+arraySize.Width-1)
+	arrayRef := ref.CellName()
+	if last != ref {
+		arrayRef += ":" + last.CellName()
+	}
+	formulaType := excelize.STCellFormulaTypeArray
+	return tx.file.SetCellFormula(ref.Sheet, ref.CellName(), formula, excelize.FormulaOpts{Type: &formulaType, Ref: &arrayRef})
+}
@@ -0,0 +1,56 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestArrayAndSharedFormulas(t *testing.T) {
+	array, shared := excelize.STCellFormulaTypeArray, excelize.STCellFormulaTypeShared
+	ref := func(s string) *string { return &s }
+
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Qty")
+	f.SetCellValue("Sheet1", "A2", "${e.Qty}")
+	f.SetCellValue("Sheet1", "B2", "${e.Price}")
+	require.NoError(t, f.SetCellFormula("Sheet1", "C2", "A2:B2*10", excelize.FormulaOpts{Type: &array, Ref: ref("C2:D2")}))
+	f.SetCellValue("Sheet1", "D2", 20) // result cached by Excel
+	require.NoError(t, f.SetCellFormula("Sheet1", "A3", "SUM(A2)", excelize.FormulaOpts{Type: &shared, Ref: ref("A3:B3")}))
+	require.NoError(t, f.SetCellFormula("Sheet1", "C3", "SUM(A2:A2*B2:B2)", excelize.FormulaOpts{Type: &array, Ref: ref("C3")}))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="D3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="D2")`})
+	tmpl := filepath.Join(testdataDir(t), "array_formulas.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"items": []map[string]any{{"Qty": 1, "Price": 2}, {"Qty": 3, "Price": 4}}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		ws := sheetXML(t, out)
+
+		// Array formulas keep their type over the range at each target
+		assert.Contains(t, ws, `<c r="C2" t="str"><f t="array" ref="C2:D2">A2:B2*10</f></c>`)
+		assert.Contains(t, ws, `<c r="C3" t="str"><f t="array" ref="C3:D3">A3:B3*10</f></c>`)
+		assert.NotContains(t, ws, `<v>20</v>`, "cached results of the range are not copied")
+		assert.Contains(t, ws, `<f t="array" ref="C4">SUM(A2:A3*B2:B3)</f>`)
+
+		// Each cell of a shared formula is rewritten
+		o := openOutput(t, out)
+		for cell, want := range map[string]string{"A4": "SUM(A2:A3)", "B4": "SUM(B2:B3)"} {
+			formula, err := o.GetCellFormula("Sheet1", cell)
+			require.NoError(t, err)
+			assert.Equal(t, want, formula, cell)
+		}
+	}
+}
+
+func TestCollapseRepeatedRanges(t *testing.T) {
+	assert.Equal(t, "SUM(A2:A3*B2:B3)", collapseRepeatedRanges("SUM(A2:A3:A2:A3*B2:B3:B2:B3)"))
+	assert.Equal(t, "SUM(A1:A2:B1:B2)", collapseRepeatedRanges("SUM(A1:A2:B1:B2)"))
+	assert.Equal(t, "A1:A2+A1:A2", collapseRepeatedRanges("A1:A2+A1:A2"))
+}
@@ -1,18 +1,55 @@
 package xlfill
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultFontSize  = 11.0        // points, Excel's default font size
+	pointsPerLine    = 15.0 / 11.0 // line height per point of font size (15pt rows at 11pt)
+	maxRowHeight     = 409.0       // largest row height Excel accepts
+	rowHeightStep    = 0.75        // row heights are whole pixels (0.75pt at 96 DPI)
+	boldWidthPercent = 1.1         // bold text is about 10% wider
+)
+
 // AutoRowHeightCommand implements jx:autoRowHeight to auto-fit row heights after content is written.
+// Each output row is sized to the wrapped text of its cells, measured against the
+// column widths and font sizes of the output.
 type AutoRowHeightCommand struct {
-	Area *Area
+	LineHeight float64 // factor applied to the natural line height (default: 1)
+	MaxHeight  float64 // largest row height in points (default: 409)
+	Area       *Area
 }
 
 func (c *AutoRowHeightCommand) Name() string { return "autoRowHeight" }
 func (c *AutoRowHeightCommand) Reset()       {}
 
 func newAutoRowHeightCommandFromAttrs(attrs map[string]string) (Command, error) {
-	return &AutoRowHeightCommand{}, nil
+	cmd := &AutoRowHeightCommand{LineHeight: 1, MaxHeight: maxRowHeight}
+	for _, a := range []struct {
+		name string
+		dst  *float64
+	}{{"lineHeight", &cmd.LineHeight}, {"maxHeight", &cmd.MaxHeight}} {
+		s := attrs[a.name]
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("autoRowHeight command: invalid %s %q (expected a positive number)", a.name, s)
+		}
+		*a.dst = v
+	}
+	cmd.MaxHeight = min(cmd.MaxHeight, maxRowHeight)
+	return cmd, nil
 }
 
-// ApplyAt processes the area and then sets each row to auto-height.
+// ApplyAt processes the area and then fits the height of each output row.
 func (c *AutoRowHeightCommand) ApplyAt(cellRef CellRef, ctx *Context, tx Transformer) (Size, error) {
 	if c.Area == nil {
 		return ZeroSize, nil
@@ -22,11 +59,95 @@ func (c *AutoRowHeightCommand) ApplyAt(cellRef CellRef, ctx *Context, tx Transfo
 	if err != nil {
 		return ZeroSize, err
 	}
+	if size.Width <= 0 {
+		return size, nil
+	}
 
-	// Set each output row to auto-height by setting height to -1
+	fit := RowFit{LineHeight: c.LineHeight, MaxHeight: c.MaxHeight}
+	if fit.LineHeight == 0 {
+		fit.LineHeight = 1
+	}
+	if fit.MaxHeight == 0 {
+		fit.MaxHeight = maxRowHeight
+	}
+	lastCol := cellRef.Col + size.Width - 1
 	for row := 0; row < size.Height; row++ {
-		tx.SetRowHeight(cellRef.Sheet, cellRef.Row+row, -1)
+		if err := fitRowHeight(tx, cellRef.Sheet, cellRef.Row+row, cellRef.Col, lastCol, fit); err != nil {
+			return ZeroSize, fmt.Errorf("fit row %d height: %w", cellRef.Row+row+1, err)
+		}
 	}
 
 	return size, nil
 }
+
+// rowFitter is implemented by transformers that can size rows to their text.
+type rowFitter interface {
+	// FitRowHeight sizes a row to the wrapped text of columns firstCol to lastCol.
+	FitRowHeight(sheet string, row, firstCol, lastCol int, fit RowFit) error
+}
+
+// fitRowHeight sizes a row to the wrapped text of columns firstCol to lastCol.
+func fitRowHeight(tx Transformer, sheet string, row, firstCol, lastCol int, fit RowFit) error {
+	if _, ok := unwrapTransformer(tx).(rowFitter); !ok {
+		return fmt.Errorf("transformer %T cannot fit row heights", unwrapTransformer(tx))
+	}
+	return tx.(rowFitter).FitRowHeight(sheet, row, firstCol, lastCol, fit)
+}
+
+// RowFit configures how FitRowHeight sizes a row to its wrapped text.
+type RowFit struct {
+	LineHeight float64 // factor applied to the natural line height
+	MaxHeight  float64 // largest row height in points
+}
+
+// rowHeight returns the height in points of lines of text in a font of the given size.
+func (f RowFit) rowHeight(lines int, fontSize float64) float64 {
+	h := float64(lines) * fontSize * pointsPerLine * f.LineHeight
+	h = math.Ceil(h/rowHeightStep-1e-9) * rowHeightStep
+	return min(h, f.MaxHeight)
+}
+
+// wrappedLines estimates the lines text takes in a cell that fits width
+// characters of the default font. Words wrap greedily at spaces and words
+// longer than a line are broken; wide (East Asian) characters count double.
+func wrappedLines(text string, width float64) int {
+	if width <= 0 {
+		width = 1
+	}
+	lines := 0
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		lines++
+		used := 0.0
+		for i, word := range strings.Split(para, " ") {
+			w := textWidth(word)
+			if i > 0 {
+				if used+1+w <= width {
+					used += 1 + w
+					continue
+				}
+				lines++
+				used = 0
+			}
+			for w > width {
+				lines++
+				w -= width
+			}
+			used = w
+		}
+	}
+	return lines
+}
+
+// textWidth returns the width of s in characters of the default font.
+func textWidth(s string) float64 {
+	w := 0.0
+	for _, r := range s {
+		if r >= utf8.RuneSelf && (unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hangul, r) ||
+			unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || (r >= 0xFF01 && r <= 0xFF60)) {
+			w += 2
+		} else {
+			w++
+		}
+	}
+	return w
+}
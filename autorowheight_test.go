@@ -0,0 +1,88 @@
+package xlfill
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWrappedLines(t *testing.T) {
+	assert.Equal(t, 1, wrappedLines("", 10))
+	assert.Equal(t, 1, wrappedLines("short text", 10))
+	assert.Equal(t, 2, wrappedLines("short text!", 10))
+	assert.Equal(t, 3, wrappedLines("one\ntwo\r\nthree", 10))
+	assert.Equal(t, 3, wrappedLines(strings.Repeat("x", 25), 10))
+	assert.Equal(t, 2, wrappedLines("ab "+strings.Repeat("x", 10), 10))
+	assert.Equal(t, 2, wrappedLines("日本語のテキスト", 10))
+}
+
+// fillRowHeights fills a list under a header row whose rows have a wrapped
+// 20-wide column A and an unwrapped column B with the given font size, with
+// jx:autoRowHeight around the whole list, and returns the heights of the
+// output rows after the header.
+func fillRowHeights(t *testing.T, attrs string, fontSize float64, notes []string) []float64 {
+	t.Helper()
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	tmpl.SetCellValue("Sheet1", "A1", "Note")
+	tmpl.SetCellValue("Sheet1", "A2", "${e.Note}")
+	tmpl.SetCellValue("Sheet1", "B2", "${e.Code}")
+	require.NoError(t, tmpl.SetColWidth("Sheet1", "A", "A", 20))
+	wrap, err := tmpl.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{WrapText: true}})
+	require.NoError(t, err)
+	require.NoError(t, tmpl.SetCellStyle("Sheet1", "A2", "A2", wrap))
+	big, err := tmpl.NewStyle(&excelize.Style{Font: &excelize.Font{Size: fontSize}})
+	require.NoError(t, err)
+	require.NoError(t, tmpl.SetCellStyle("Sheet1", "B2", "B2", big))
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"B2\")\njx:autoRowHeight(lastCell=\"B2\"" + attrs + ")"})
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="rows" var="e" lastCell="B2")`})
+	var buf, out bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	var rows []map[string]any
+	for i, n := range notes {
+		rows = append(rows, map[string]any{"Note": n, "Code": i})
+	}
+	require.NoError(t, FillReader(&buf, &out, map[string]any{"rows": rows}))
+	f := openOutput(t, out.Bytes())
+
+	heights := make([]float64, len(notes))
+	for i := range notes {
+		heights[i], err = f.GetRowHeight("Sheet1", i+2)
+		require.NoError(t, err)
+	}
+	return heights
+}
+
+func TestAutoRowHeight_FitsWrappedText(t *testing.T) {
+	long := "The quick brown fox jumps over the lazy dog and keeps running"
+	heights := fillRowHeights(t, "", 11, []string{"short", long, "one\ntwo\nthree\nfour"})
+
+	// 61 characters in 20-character lines take 4 lines of 15pt
+	assert.Equal(t, []float64{15, 60, 60}, heights)
+}
+
+func TestAutoRowHeight_FontSizeAndOptions(t *testing.T) {
+	// The unwrapped 22pt column needs 30pt even for a single short line
+	assert.Equal(t, []float64{30, 60}, fillRowHeights(t, "", 22, []string{"a", strings.Repeat("word ", 16)}))
+
+	heights := fillRowHeights(t, ` lineHeight="1.2" maxHeight="50"`, 11, []string{"a", strings.Repeat("word ", 16)})
+	assert.Equal(t, []float64{18, 50}, heights)
+}
+
+func TestAutoRowHeight_InvalidAttributes(t *testing.T) {
+	_, err := newAutoRowHeightCommandFromAttrs(map[string]string{"lineHeight": "tall"})
+	assert.ErrorContains(t, err, `invalid lineHeight "tall"`)
+	_, err = newAutoRowHeightCommandFromAttrs(map[string]string{"maxHeight": "-5"})
+	assert.ErrorContains(t, err, `invalid maxHeight "-5"`)
+
+	cmd, err := newAutoRowHeightCommandFromAttrs(map[string]string{"maxHeight": "1000"})
+	require.NoError(t, err)
+	assert.Equal(t, 409.0, cmd.(*AutoRowHeightCommand).MaxHeight)
+}
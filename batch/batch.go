@@ -0,0 +1,244 @@
+// Package batch fills many templates from a declarative configuration.
+//
+// A config lists template → data file → output jobs plus options shared by all
+// jobs. Jobs can run in parallel on a worker pool; each job reports its own
+// result so one failing workbook does not stop the rest.
+//
+//	workers: 4
+//	options:
+//	  recalculateOnOpen: true
+//	jobs:
+//	  - name: north
+//	    template: templates/report.xlsx
+//	    data: data/north.json
+//	    output: out/north.xlsx
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javajack/xlfill"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a batch of fill jobs.
+type Config struct {
+	Workers int        `yaml:"workers" json:"workers"` // parallel jobs (default: 1)
+	Options JobOptions `yaml:"options" json:"options"` // options shared by all jobs
+	Jobs    []Job      `yaml:"jobs" json:"jobs"`
+
+	// BaseDir resolves relative job paths. LoadConfig sets it to the config file's directory.
+	BaseDir string `yaml:"-" json:"-"`
+}
+
+// JobOptions are the fill options that can be set from a config file.
+type JobOptions struct {
+	NotationBegin      string `yaml:"notationBegin" json:"notationBegin"`
+	NotationEnd        string `yaml:"notationEnd" json:"notationEnd"`
+	ClearTemplateCells *bool  `yaml:"clearTemplateCells" json:"clearTemplateCells"`
+	KeepTemplateSheet  bool   `yaml:"keepTemplateSheet" json:"keepTemplateSheet"`
+	HideTemplateSheet  bool   `yaml:"hideTemplateSheet" json:"hideTemplateSheet"`
+	RecalculateOnOpen  bool   `yaml:"recalculateOnOpen" json:"recalculateOnOpen"`
+	// TemplateSheets maps sheet names to "keep", "hide" or "delete".
+	TemplateSheets map[string]string `yaml:"templateSheets" json:"templateSheets"`
+}
+
+// Job is a single template → data → output triple.
+type Job struct {
+	Name     string `yaml:"name" json:"name"`
+	Template string `yaml:"template" json:"template"`
+	Data     string `yaml:"data" json:"data"` // JSON or YAML data file (optional)
+	Output   string `yaml:"output" json:"output"`
+}
+
+// Result is the outcome of a single job.
+type Result struct {
+	Job      Job
+	Err      error
+	Duration time.Duration
+}
+
+// LoadConfig reads a batch config from a YAML (.yaml, .yml) or JSON (.json) file.
+// Other formats, such as TOML, are rejected: YAML and JSON are parsed with the
+// packages xlfill already depends on.
+func LoadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %q: %w", path, err)
+	}
+
+	cfg := &Config{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(raw, cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, cfg)
+	default:
+		return nil, fmt.Errorf("config %q: unsupported format (use .yaml, .yml or .json)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %q: %w", path, err)
+	}
+	cfg.BaseDir = filepath.Dir(path)
+	return cfg, nil
+}
+
+// LoadData reads a data file into a map. JSON files are decoded with
+// xlfill.DecodeJSONData; YAML files (.yaml, .yml) must contain a mapping.
+func LoadData(path string) (map[string]any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read data %q: %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := xlfill.DecodeJSONData(raw)
+		if err != nil {
+			return nil, fmt.Errorf("data %q: %w", path, err)
+		}
+		return data, nil
+	case ".yaml", ".yml":
+		var data map[string]any
+		if err := yaml.Unmarshal(raw, &data); err != nil {
+			return nil, fmt.Errorf("parse data %q: %w", path, err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("data %q: unsupported format (use .json, .yaml or .yml)", path)
+	}
+}
+
+// Options converts the config options to xlfill options.
+func (o JobOptions) Options() []xlfill.Option {
+	var opts []xlfill.Option
+	if o.NotationBegin != "" || o.NotationEnd != "" {
+		begin, end := o.NotationBegin, o.NotationEnd
+		if begin == "" {
+			begin = "${"
+		}
+		if end == "" {
+			end = "}"
+		}
+		opts = append(opts, xlfill.WithExpressionNotation(begin, end))
+	}
+	if o.ClearTemplateCells != nil {
+		opts = append(opts, xlfill.WithClearTemplateCells(*o.ClearTemplateCells))
+	}
+	if o.KeepTemplateSheet {
+		opts = append(opts, xlfill.WithKeepTemplateSheet(true))
+	}
+	if o.HideTemplateSheet {
+		opts = append(opts, xlfill.WithHideTemplateSheet(true))
+	}
+	if o.RecalculateOnOpen {
+		opts = append(opts, xlfill.WithRecalculateOnOpen(true))
+	}
+	if len(o.TemplateSheets) > 0 {
+		sheets := make(map[string]xlfill.SheetDisposition, len(o.TemplateSheets))
+		for sheet, d := range o.TemplateSheets {
+			sheets[sheet] = xlfill.SheetDisposition(d)
+		}
+		opts = append(opts, xlfill.WithTemplateSheets(sheets))
+	}
+	return opts
+}
+
+// Run executes all jobs in cfg and returns one Result per job, in job order.
+// Extra opts are applied to every job after the config options, which lets Go
+// callers add listeners or custom commands. Jobs not started before ctx is
+// cancelled report ctx.Err(), and running jobs stop with it.
+func Run(ctx context.Context, cfg *Config, opts ...xlfill.Option) []Result {
+	results := make([]Result, len(cfg.Jobs))
+	jobOpts := append(cfg.Options.Options(), opts...)
+
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = cfg.runJob(ctx, cfg.Jobs[i], jobOpts)
+			}
+		}()
+	}
+
+	for i, job := range cfg.Jobs {
+		if err := ctx.Err(); err != nil {
+			results[i] = Result{Job: job, Err: err}
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// runJob loads the job's data and fills its template.
+func (c *Config) runJob(ctx context.Context, job Job, opts []xlfill.Option) Result {
+	start := time.Now()
+	err := c.fill(ctx, job, opts)
+	if err != nil && job.Name != "" {
+		err = fmt.Errorf("job %q: %w", job.Name, err)
+	}
+	return Result{Job: job, Err: err, Duration: time.Since(start)}
+}
+
+func (c *Config) fill(ctx context.Context, job Job, opts []xlfill.Option) error {
+	if job.Template == "" {
+		return fmt.Errorf("job requires a template")
+	}
+	if job.Output == "" {
+		return fmt.Errorf("job requires an output")
+	}
+
+	var data map[string]any
+	if job.Data != "" {
+		var err error
+		if data, err = LoadData(c.resolve(job.Data)); err != nil {
+			return err
+		}
+	}
+
+	output := c.resolve(job.Output)
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	opts = append(slices.Clip(opts), xlfill.WithCancel(ctx))
+	return xlfill.Fill(c.resolve(job.Template), output, data, opts...)
+}
+
+// resolve makes a relative job path relative to BaseDir.
+func (c *Config) resolve(path string) string {
+	if c.BaseDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.BaseDir, path)
+}
+
+// Failed returns the results whose job returned an error.
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
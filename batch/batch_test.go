@@ -0,0 +1,158 @@
+package batch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javajack/xlfill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTemplate creates a one-row each template reading ${e.Name}.
+func writeTemplate(t *testing.T, path string) {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${title}")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="A2")`})
+	require.NoError(t, f.SaveAs(path))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func cellValue(t *testing.T, path, cell string) string {
+	t.Helper()
+	f, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+	v, err := f.GetCellValue("Sheet1", cell)
+	require.NoError(t, err)
+	return v
+}
+
+func TestRun_YAMLConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "report.xlsx"))
+	writeFile(t, filepath.Join(dir, "north.json"), `{"title": "North", "employees": [{"Name": "Alice"}, {"Name": "Bob"}]}`)
+	writeFile(t, filepath.Join(dir, "south.yaml"), "title: South\nemployees:\n  - Name: Carol\n")
+	writeFile(t, filepath.Join(dir, "batch.yaml"), `
+workers: 2
+options:
+  recalculateOnOpen: true
+jobs:
+  - name: north
+    template: report.xlsx
+    data: north.json
+    output: out/north.xlsx
+  - name: south
+    template: report.xlsx
+    data: south.yaml
+    output: out/south.xlsx
+  - name: broken
+    template: report.xlsx
+    data: missing.json
+    output: out/broken.xlsx
+`)
+
+	cfg, err := LoadConfig(filepath.Join(dir, "batch.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.Workers)
+	assert.True(t, cfg.Options.RecalculateOnOpen)
+
+	results := Run(context.Background(), cfg)
+	require.Len(t, results, 3)
+	assert.Equal(t, "north", results[0].Job.Name, "results keep job order")
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+
+	failed := Failed(results)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "broken", failed[0].Job.Name)
+	assert.Contains(t, failed[0].Err.Error(), `job "broken"`)
+
+	north := filepath.Join(dir, "out", "north.xlsx")
+	assert.Equal(t, "North", cellValue(t, north, "A1"))
+	assert.Equal(t, "Alice", cellValue(t, north, "A2"))
+	assert.Equal(t, "Bob", cellValue(t, north, "A3"))
+	assert.Equal(t, "Carol", cellValue(t, filepath.Join(dir, "out", "south.xlsx"), "A2"))
+}
+
+func TestLoadConfig_JSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "batch.json")
+	writeFile(t, path, `{"options": {"notationBegin": "{{", "notationEnd": "}}", "clearTemplateCells": false,
+		"templateSheets": {"template": "hide"}},
+		"jobs": [{"template": "a.xlsx", "output": "b.xlsx"}]}`)
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, dir, cfg.BaseDir)
+	require.Len(t, cfg.Jobs, 1)
+	require.NotNil(t, cfg.Options.ClearTemplateCells)
+	assert.False(t, *cfg.Options.ClearTemplateCells)
+	assert.Equal(t, "hide", cfg.Options.TemplateSheets["template"])
+	assert.Len(t, cfg.Options.Options(), 3)
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadConfig(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "batch.toml")
+	writeFile(t, path, "workers = 1")
+	_, err = LoadConfig(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported format")
+}
+
+func TestRun_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := &Config{Jobs: []Job{{Template: "a.xlsx", Output: "b.xlsx"}}}
+	results := Run(ctx, cfg)
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+}
+
+func TestRun_MissingFields(t *testing.T) {
+	results := Run(context.Background(), &Config{Jobs: []Job{{Output: "x.xlsx"}, {Template: "t.xlsx"}}})
+	assert.Contains(t, results[0].Err.Error(), "requires a template")
+	assert.Contains(t, results[1].Err.Error(), "requires an output")
+}
+
+// cancelListener cancels the batch context when the first cell is transformed.
+type cancelListener struct{ cancel context.CancelFunc }
+
+func (l cancelListener) BeforeTransformCell(src, target xlfill.CellRef, ctx *xlfill.Context, tx xlfill.Transformer) bool {
+	l.cancel()
+	return true
+}
+
+func (l cancelListener) AfterTransformCell(src, target xlfill.CellRef, ctx *xlfill.Context, tx xlfill.Transformer) {
+}
+
+func TestRun_CancelRunningJob(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "report.xlsx"))
+	writeFile(t, filepath.Join(dir, "data.json"), `{"title": "T", "employees": [{"Name": "Alice"}, {"Name": "Bob"}]}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &Config{BaseDir: dir, Jobs: []Job{{Template: "report.xlsx", Data: "data.json", Output: "out.xlsx"}}}
+	results := Run(ctx, cfg, xlfill.WithAreaListener(cancelListener{cancel}))
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	assert.NoFileExists(t, filepath.Join(dir, "out.xlsx"))
+}
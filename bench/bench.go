@@ -0,0 +1,139 @@
+// Package bench builds reproducible templates and data for measuring fill
+// performance at scale: a flat list, nested groups and formula-heavy rows.
+// The same row count always gives the same template and data, so timings
+// can be compared across releases.
+//
+// Run the benchmarks, with CPU and memory profiles, with
+//
+//	make profile
+//
+// Scenarios of 1M rows only run when XLFILL_BENCH_1M=1 is set.
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Scenario is a template with the data to fill it with.
+type Scenario struct {
+	Name     string
+	Rows     int // data rows the fill generates
+	Template []byte
+	Data     map[string]any
+}
+
+// WriteTemplate saves the template as dir/<name>.xlsx and returns its path,
+// for Fill and FillBytes, which read templates from files.
+func (s *Scenario) WriteTemplate(dir string) (string, error) {
+	path := filepath.Join(dir, s.Name+".xlsx")
+	if err := os.WriteFile(path, s.Template, 0o644); err != nil {
+		return "", fmt.Errorf("write template: %w", err)
+	}
+	return path, nil
+}
+
+// Flat is a list of rows items under a header, with a total row.
+func Flat(rows int) (*Scenario, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	setRow(f, "A1", "ID", "Name", "Amount", "Date")
+	setRow(f, "A2", "${e.ID}", "${e.Name}", "${e.Amount}", "${e.Date}")
+	f.SetCellValue("Sheet1", "B3", "Total")
+	f.SetCellFormula("Sheet1", "C3", "SUM(C2)")
+	comment(f, "A1", `jx:area(lastCell="D3")`)
+	comment(f, "A2", `jx:each(items="items" var="e" lastCell="D2")`)
+	return newScenario(fmt.Sprintf("flat-%d", rows), rows, f, map[string]any{"items": items(0, rows)})
+}
+
+// Nested is groups of rowsPerGroup items each, rendered by a jx:each in a
+// jx:each, with a subtotal per group and a grand total.
+func Nested(groups, rowsPerGroup int) (*Scenario, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	setRow(f, "A1", "ID", "Name", "Amount", "Date")
+	f.SetCellValue("Sheet1", "A2", "${g.Name}")
+	setRow(f, "A3", "${e.ID}", "${e.Name}", "${e.Amount}", "${e.Date}")
+	f.SetCellValue("Sheet1", "B4", "Subtotal")
+	f.SetCellFormula("Sheet1", "C4", "SUM(C3)")
+	f.SetCellValue("Sheet1", "B5", "Total")
+	f.SetCellFormula("Sheet1", "C5", "SUM(C4)")
+	comment(f, "A1", `jx:area(lastCell="D5")`)
+	comment(f, "A2", `jx:each(items="groups" var="g" lastCell="D4")`)
+	comment(f, "A3", `jx:each(items="g.Items" var="e" lastCell="D3")`)
+
+	data := make([]any, groups)
+	for i := range data {
+		data[i] = map[string]any{
+			"Name":  fmt.Sprintf("Group %d", i+1),
+			"Items": items(i*rowsPerGroup, rowsPerGroup),
+		}
+	}
+	return newScenario(fmt.Sprintf("nested-%dx%d", groups, rowsPerGroup), groups*rowsPerGroup, f,
+		map[string]any{"groups": data})
+}
+
+// Formulas is a list of rows items with several formulas per row and totals
+// over each formula column, for the cost of the formula pass.
+func Formulas(rows int) (*Scenario, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	setRow(f, "A1", "Name", "Quantity", "Price", "Net", "Tax", "Gross", "Share")
+	setRow(f, "A2", "${e.Name}", "${e.Quantity}", "${e.Amount}")
+	f.SetCellFormula("Sheet1", "D2", "B2*C2")
+	f.SetCellFormula("Sheet1", "E2", "IF(D2>1000,D2*0.2,D2*0.1)")
+	f.SetCellFormula("Sheet1", "F2", "ROUND(D2+E2,2)")
+	f.SetCellFormula("Sheet1", "G2", "F2/$F$3")
+	f.SetCellValue("Sheet1", "A3", "Total")
+	f.SetCellFormula("Sheet1", "D3", "SUM(D2)")
+	f.SetCellFormula("Sheet1", "E3", "SUM(E2)")
+	f.SetCellFormula("Sheet1", "F3", "SUM(F2)")
+	f.SetCellFormula("Sheet1", "G3", "AVERAGE(G2)")
+	comment(f, "A1", `jx:area(lastCell="G3")`)
+	comment(f, "A2", `jx:each(items="items" var="e" lastCell="G2")`)
+	return newScenario(fmt.Sprintf("formulas-%d", rows), rows, f, map[string]any{"items": items(0, rows)})
+}
+
+// items returns n items numbered from first. Values only depend on the
+// number, so the data is the same on every run.
+func items(first, n int) []any {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	out := make([]any, n)
+	for i := range out {
+		id := first + i + 1
+		out[i] = map[string]any{
+			"ID":       id,
+			"Name":     fmt.Sprintf("Item %d", id),
+			"Quantity": id%17 + 1,
+			"Amount":   float64(id%1000) + 0.25,
+			"Date":     base.AddDate(0, 0, id%365),
+		}
+	}
+	return out
+}
+
+func newScenario(name string, rows int, f *excelize.File, data map[string]any) (*Scenario, error) {
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		return nil, fmt.Errorf("write template %s: %w", name, err)
+	}
+	return &Scenario{Name: name, Rows: rows, Template: buf.Bytes(), Data: data}, nil
+}
+
+// setRow sets values in consecutive cells from cell to the right.
+func setRow(f *excelize.File, cell string, values ...any) {
+	col, row, _ := excelize.CellNameToCoordinates(cell)
+	for i, v := range values {
+		name, _ := excelize.CoordinatesToCellName(col+i, row)
+		f.SetCellValue("Sheet1", name, v)
+	}
+}
+
+func comment(f *excelize.File, cell, text string) {
+	f.AddComment("Sheet1", excelize.Comment{Cell: cell, Author: "xlfill", Text: text})
+}
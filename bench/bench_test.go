@@ -0,0 +1,159 @@
+package bench
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javajack/xlfill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestScenarios(t *testing.T) {
+	tests := []struct {
+		build     func() (*Scenario, error)
+		lastRow   int
+		total     string
+		totalCell string
+	}{
+		// 1 header row, 20 items, a total
+		{func() (*Scenario, error) { return Flat(20) }, 22, "SUM(C2:C21)", "C22"},
+		// 1 header row, 3 groups of a heading, 5 items and a subtotal, a total
+		{func() (*Scenario, error) { return Nested(3, 5) }, 23, "SUM(C8,C15,C22)", "C23"},
+		{func() (*Scenario, error) { return Formulas(20) }, 22, "SUM(F2:F21)", "F22"},
+	}
+	for _, tt := range tests {
+		s, err := tt.build()
+		require.NoError(t, err)
+		t.Run(s.Name, func(t *testing.T) {
+			again, err := tt.build()
+			require.NoError(t, err)
+			assert.Equal(t, s.Data, again.Data, "data is reproducible")
+
+			path, err := s.WriteTemplate(t.TempDir())
+			require.NoError(t, err)
+			var stats xlfill.FillStats
+			out, err := xlfill.FillBytes(path, s.Data, xlfill.WithStats(&stats))
+			require.NoError(t, err)
+			assert.Positive(t, stats.Total)
+
+			f, err := excelize.OpenReader(bytes.NewReader(out))
+			require.NoError(t, err)
+			defer f.Close()
+			rows, err := f.GetRows("Sheet1")
+			require.NoError(t, err)
+			assert.Len(t, rows, tt.lastRow)
+			formula, err := f.GetCellFormula("Sheet1", tt.totalCell)
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, formula)
+		})
+	}
+}
+
+// benchmarkFillBytes fills s b.N times and reports the mean time of each
+// phase of the fill.
+func benchmarkFillBytes(b *testing.B, build func() (*Scenario, error)) {
+	s, err := build()
+	if err != nil {
+		b.Fatal(err)
+	}
+	path, err := s.WriteTemplate(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	var stats, sum xlfill.FillStats
+	filler := xlfill.NewFiller(xlfill.WithTemplate(path), xlfill.WithStats(&stats))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := filler.FillBytes(s.Data); err != nil {
+			b.Fatal(err)
+		}
+		sum.Open += stats.Open
+		sum.Parse += stats.Parse
+		sum.Apply += stats.Apply
+		sum.Formulas += stats.Formulas
+		sum.Write += stats.Write
+	}
+	n := float64(b.N)
+	b.ReportMetric(sum.Open.Seconds()*1000/n, "open-ms/op")
+	b.ReportMetric(sum.Parse.Seconds()*1000/n, "parse-ms/op")
+	b.ReportMetric(sum.Apply.Seconds()*1000/n, "apply-ms/op")
+	b.ReportMetric(sum.Formulas.Seconds()*1000/n, "formulas-ms/op")
+	b.ReportMetric(sum.Write.Seconds()*1000/n, "write-ms/op")
+	b.ReportMetric(float64(s.Rows)*n/b.Elapsed().Seconds(), "rows/s")
+}
+
+// benchmarkFill fills s into a file b.N times.
+func benchmarkFill(b *testing.B, build func() (*Scenario, error)) {
+	s, err := build()
+	if err != nil {
+		b.Fatal(err)
+	}
+	dir := b.TempDir()
+	path, err := s.WriteTemplate(dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	out := filepath.Join(dir, "output.xlsx")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := xlfill.Fill(path, out, s.Data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(s.Rows)*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+}
+
+// skipUnless1M skips scenarios of a million rows unless XLFILL_BENCH_1M=1.
+func skipUnless1M(b *testing.B) {
+	if os.Getenv("XLFILL_BENCH_1M") != "1" {
+		b.Skip("set XLFILL_BENCH_1M=1 to run 1M-row benchmarks")
+	}
+}
+
+func BenchmarkFillBytes_Flat10k(b *testing.B) {
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Flat(10_000) })
+}
+
+func BenchmarkFillBytes_Flat100k(b *testing.B) {
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Flat(100_000) })
+}
+
+func BenchmarkFillBytes_Flat1M(b *testing.B) {
+	skipUnless1M(b)
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Flat(1_000_000) })
+}
+
+func BenchmarkFillBytes_Nested100x100(b *testing.B) {
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Nested(100, 100) })
+}
+
+func BenchmarkFillBytes_Nested1000x100(b *testing.B) {
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Nested(1000, 100) })
+}
+
+func BenchmarkFillBytes_Formulas10k(b *testing.B) {
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Formulas(10_000) })
+}
+
+func BenchmarkFillBytes_Formulas100k(b *testing.B) {
+	benchmarkFillBytes(b, func() (*Scenario, error) { return Formulas(100_000) })
+}
+
+func BenchmarkFill_Flat10k(b *testing.B) {
+	benchmarkFill(b, func() (*Scenario, error) { return Flat(10_000) })
+}
+
+func BenchmarkFill_Flat100k(b *testing.B) {
+	benchmarkFill(b, func() (*Scenario, error) { return Flat(100_000) })
+}
+
+func BenchmarkFill_Flat1M(b *testing.B) {
+	skipUnless1M(b)
+	benchmarkFill(b, func() (*Scenario, error) { return Flat(1_000_000) })
+}
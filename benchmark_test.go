@@ -132,3 +132,31 @@ func BenchmarkParseComment(b *testing.B) {
 		ParseComment(comment, ref)
 	}
 }
+
+// BenchmarkFill_SparseArea fills a wide area with a few cells, repeated by a
+// jx:each whose rows are mostly blank.
+func BenchmarkFill_SparseArea(b *testing.B) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${title}")
+	f.SetCellValue("Sheet1", "Z500", "end")
+	f.SetCellValue("Sheet1", "A3", "${e.Name}")
+	f.SetCellValue("Sheet1", "M5", "${e.Value}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="Z500")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="Z7")`})
+	path := filepath.Join("testdata", "bench_sparse.xlsx")
+	require.NoError(b, f.SaveAs(path))
+	f.Close()
+
+	items := make([]any, 200)
+	for i := range items {
+		items[i] = map[string]any{"Name": fmt.Sprintf("Employee_%d", i), "Value": i}
+	}
+	data := map[string]any{"title": "Sparse", "items": items}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := FillBytes(path, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
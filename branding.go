@@ -0,0 +1,192 @@
+package xlfill
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Branding restyles the output workbook for a customer's brand, for
+// WithBranding. It rewrites the workbook's styles, so static and generated
+// cells change alike.
+type Branding struct {
+	// ReplaceColors maps template colors to brand colors, both as RGB hex such
+	// as "1F4E79", "#1F4E79" or "FF1F4E79". Fill, font and border colors,
+	// including those of conditional formats, are replaced; theme and indexed
+	// colors are kept.
+	ReplaceColors map[string]string
+	// FontFamily replaces the font of every cell style when set.
+	FontFamily string
+}
+
+// ApplyBranding rewrites the colors and fonts of the workbook's styles.
+// excelize has no API for editing existing styles; the style sheet is edited
+// in place through reflection. The fields used are checked first, so a
+// version of excelize that laid out its style sheet differently fails with
+// an error instead of a panic or a silently unbranded workbook.
+func (tx *ExcelizeTransformer) ApplyBranding(b Branding) error {
+	colors := make(map[string]string, len(b.ReplaceColors))
+	for from, to := range b.ReplaceColors {
+		src, ok := argbColor(from)
+		if !ok {
+			return fmt.Errorf("invalid color %q", from)
+		}
+		dst, ok := argbColor(to)
+		if !ok {
+			return fmt.Errorf("invalid color %q", to)
+		}
+		colors[src] = dst
+	}
+	if _, err := tx.file.GetStyle(0); err != nil { // loads the style sheet
+		return fmt.Errorf("read styles: %w", err)
+	}
+	if tx.file.Styles == nil {
+		return nil
+	}
+	styles := reflect.ValueOf(tx.file.Styles).Elem()
+	if len(colors) > 0 && !hasColorField(styles.Type(), map[reflect.Type]bool{}) {
+		return fmt.Errorf("style sheet of %T has no RGB colors to replace", tx.file.Styles)
+	}
+	var fonts []reflect.Value
+	if b.FontFamily != "" {
+		var err error
+		if fonts, err = styleFonts(styles); err != nil {
+			return fmt.Errorf("style sheet fonts: %w", err)
+		}
+	}
+	brandColors(styles, colors)
+	for _, font := range fonts {
+		setFontName(font, b.FontFamily)
+	}
+	return nil
+}
+
+// argbColor returns an RGB or ARGB hex color as upper-case ARGB.
+func argbColor(s string) (string, bool) {
+	s = strings.ToUpper(strings.TrimPrefix(s, "#"))
+	if len(s) == 6 {
+		s = "FF" + s
+	}
+	if _, err := hex.DecodeString(s); err != nil || len(s) != 8 {
+		return "", false
+	}
+	return s, true
+}
+
+// hasColorField reports whether values of type t can hold an RGB color that
+// brandColors replaces.
+func hasColorField(t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return hasColorField(t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Name == "RGB" && f.Type.Kind() == reflect.String || hasColorField(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// brandColors replaces the RGB colors found in v by colors.
+func brandColors(v reflect.Value, colors map[string]string) {
+	if len(colors) == 0 {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			brandColors(v.Elem(), colors)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			brandColors(v.Index(i), colors)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			f, fv := t.Field(i), v.Field(i)
+			switch {
+			case !f.IsExported():
+			case f.Name == "RGB" && fv.Kind() == reflect.String:
+				if rgb, ok := argbColor(fv.String()); ok && colors[rgb] != "" {
+					fv.SetString(colors[rgb])
+				}
+			default:
+				brandColors(fv, colors)
+			}
+		}
+	}
+}
+
+// styleFonts returns the cell fonts of a style sheet, checking each has the
+// Name and Scheme fields setFontName sets.
+func styleFonts(styles reflect.Value) ([]reflect.Value, error) {
+	fonts, err := structField(styles, "Fonts", reflect.Pointer)
+	if err != nil || fonts.IsNil() {
+		return nil, err
+	}
+	list, err := structField(fonts.Elem(), "Font", reflect.Slice)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]reflect.Value, 0, list.Len())
+	for i := range list.Len() {
+		font := list.Index(i)
+		if font.Kind() != reflect.Pointer || font.Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("font %d is a %s, not a pointer to a struct", i, font.Type())
+		}
+		if font.IsNil() {
+			continue
+		}
+		font = font.Elem()
+		name, err := structField(font, "Name", reflect.Pointer)
+		if err != nil {
+			return nil, err
+		}
+		if name.Type().Elem().Kind() != reflect.Struct {
+			return nil, fmt.Errorf("font name is a %s", name.Type())
+		}
+		val, ok := name.Type().Elem().FieldByName("Val")
+		if !ok || val.Type != reflect.TypeFor[*string]() {
+			return nil, fmt.Errorf("font name %s has no Val of type *string", name.Type().Elem())
+		}
+		if _, err := structField(font, "Scheme", reflect.Pointer); err != nil {
+			return nil, err
+		}
+		out = append(out, font)
+	}
+	return out, nil
+}
+
+// structField returns the exported field name of struct v, or an error when
+// v has no such field of the given kind.
+func structField(v reflect.Value, name string, kind reflect.Kind) (reflect.Value, error) {
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%s has no field %s", v.Type(), name)
+	}
+	if f.Kind() != kind || !f.CanSet() {
+		return reflect.Value{}, fmt.Errorf("field %s of %s is a %s, not a settable %s", name, v.Type(), f.Type(), kind)
+	}
+	return f, nil
+}
+
+// setFontName sets the name of a font checked by styleFonts, dropping the
+// theme font scheme that would override it.
+func setFontName(font reflect.Value, family string) {
+	name := reflect.New(font.FieldByName("Name").Type().Elem())
+	name.Elem().FieldByName("Val").Set(reflect.ValueOf(&family))
+	font.FieldByName("Name").Set(name)
+	font.FieldByName("Scheme").SetZero()
+}
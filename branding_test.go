@@ -0,0 +1,86 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWithBranding(t *testing.T) {
+	f := excelize.NewFile()
+	header, err := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true, Color: "FFFFFF", Family: "Calibri"},
+		Fill:   excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"1F4E79"}},
+		Border: []excelize.Border{{Type: "bottom", Color: "1F4E79", Style: 2}},
+	})
+	require.NoError(t, err)
+	f.SetCellValue("Sheet1", "A1", "Employees")
+	f.SetCellStyle("Sheet1", "A1", "A1", header)
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="B2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B2", Author: "xlfill", Text: `jx:highlight(condition="true" style="accent" lastCell="B2")`})
+	tmpl := filepath.Join(testdataDir(t), "branding.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"employees": []map[string]any{{"Name": "Alice"}, {"Name": "Bob"}}}
+	styles := WithStyles(map[string]*excelize.Style{
+		"accent": {Font: &excelize.Font{Color: "C00000"}},
+	})
+	out, err := FillBytes(tmpl, data, styles,
+		WithBranding(Branding{
+			ReplaceColors: map[string]string{"#1f4e79": "00A36C", "FFC00000": "#FF7F50"},
+			FontFamily:    "Arial",
+		}))
+	require.NoError(t, err)
+	o := openOutput(t, out)
+	argb := func(color string) string {
+		c, _ := argbColor(color)
+		return c
+	}
+
+	// Static cells
+	_, style := styleOf(t, o, "Sheet1", "A1")
+	require.Len(t, style.Fill.Color, 1)
+	assert.Equal(t, "FF00A36C", argb(style.Fill.Color[0]))
+	assert.Equal(t, "FF00A36C", argb(style.Border[0].Color))
+	assert.Equal(t, "FFFFFFFF", argb(style.Font.Color), "unmapped colors are kept")
+	assert.Equal(t, "Arial", style.Font.Family)
+	assert.True(t, style.Font.Bold)
+
+	// Generated cells, with a style added at fill time
+	_, style = styleOf(t, o, "Sheet1", "B3")
+	assert.Equal(t, "FFFF7F50", argb(style.Font.Color))
+	assert.Equal(t, "Arial", style.Font.Family)
+
+	_, err = FillBytes(tmpl, data, styles, WithBranding(Branding{ReplaceColors: map[string]string{"navy": "000080"}}))
+	assert.ErrorContains(t, err, `apply branding: invalid color "navy"`)
+}
+
+func TestBranding_StyleSheetLayout(t *testing.T) {
+	// A style sheet laid out differently from excelize's is reported, not
+	// edited blindly
+	type font struct{ Size int }
+	type sheet struct {
+		Fonts *struct{ Font []*font }
+	}
+	v := reflect.ValueOf(&sheet{Fonts: &struct{ Font []*font }{Font: []*font{{Size: 11}}}}).Elem()
+	_, err := styleFonts(v)
+	assert.ErrorContains(t, err, "has no field Name")
+	assert.False(t, hasColorField(v.Type(), map[reflect.Type]bool{}))
+
+	f := excelize.NewFile()
+	defer f.Close()
+	f.GetStyle(0)
+	styles := reflect.ValueOf(f.Styles).Elem()
+	fonts, err := styleFonts(styles)
+	require.NoError(t, err)
+	assert.NotEmpty(t, fonts)
+	assert.True(t, hasColorField(styles.Type(), map[reflect.Type]bool{}))
+}
@@ -51,17 +51,29 @@ type CellData struct {
 	Value           any             // cell value
 	Type            CellType        // value type
 	Comment         string          // cell comment/note text
+	CommentAuthor   string          // author of the comment
 	Formula         string          // Excel formula (without leading =)
 	EvalResult      any             // result of expression evaluation
 	TargetCellType  CellType        // type to use when writing to target
 	FormulaStrategy FormulaStrategy // formula expansion strategy (from jx:params)
 	DefaultValue    string          // default value for removed formula refs (from jx:params)
 
+	// Name of a custom formula strategy registered with WithFormulaStrategy (from jx:params)
+	FormulaStrategyName string
+
+	// Size of the range an array formula ({=...}) fills; zero for other cells
+	ArraySize Size
+
 	// Tracking for formula processing
 	TargetPositions  []CellRef  // where this cell was copied to during transformation
 	TargetParentArea []AreaRef  // parent area of each target position
 	EvalFormulas     []string   // evaluated formulas for each target position
 
+	evalSpans [][]formulaSpan // substituted ${...} values in each evaluated formula
+
+	targetIndex map[CellRef]int // first index of each target position, see targetPos
+	indexed     int             // target positions in targetIndex
+
 	// Style preservation
 	StyleID int // cached style ID for restoring after value write
 }
@@ -91,10 +103,61 @@ func (cd *CellData) IsFormulaCell() bool {
 	return cd.Type == CellFormula || cd.Formula != ""
 }
 
+// parentAreaAt returns the output range of the innermost area instance that
+// wrote the given target position.
+func (cd *CellData) parentAreaAt(target CellRef) (AreaRef, bool) {
+	if i := cd.targetPos(target); i >= 0 && i < len(cd.TargetParentArea) {
+		return cd.TargetParentArea[i], true
+	}
+	return AreaRef{}, false
+}
+
+// evalFormulaAt returns the formula written to the given target position,
+// with any ${...} parameters already resolved, and the spans of the substituted
+// values. Falls back to the template formula.
+func (cd *CellData) evalFormulaAt(target CellRef) (string, []formulaSpan) {
+	if i := cd.targetPos(target); i >= 0 && i < len(cd.EvalFormulas) {
+		var spans []formulaSpan
+		if i < len(cd.evalSpans) {
+			spans = cd.evalSpans[i]
+		}
+		return cd.EvalFormulas[i], spans
+	}
+	return cd.Formula, nil
+}
+
+// targetPos returns the first index of target in TargetPositions, or -1.
+// Cells copied many times are looked up in an index, so the formula pass over
+// all copies is not quadratic.
+func (cd *CellData) targetPos(target CellRef) int {
+	if len(cd.TargetPositions) <= smallTargetCount {
+		for i, t := range cd.TargetPositions {
+			if t == target {
+				return i
+			}
+		}
+		return -1
+	}
+	if cd.targetIndex == nil || cd.indexed > len(cd.TargetPositions) {
+		cd.targetIndex, cd.indexed = make(map[CellRef]int, len(cd.TargetPositions)), 0
+	}
+	for ; cd.indexed < len(cd.TargetPositions); cd.indexed++ {
+		if _, ok := cd.targetIndex[cd.TargetPositions[cd.indexed]]; !ok {
+			cd.targetIndex[cd.TargetPositions[cd.indexed]] = cd.indexed
+		}
+	}
+	if i, ok := cd.targetIndex[target]; ok {
+		return i
+	}
+	return -1
+}
+
 // Reset clears target tracking data for reuse.
 func (cd *CellData) Reset() {
 	cd.TargetPositions = cd.TargetPositions[:0]
 	cd.TargetParentArea = cd.TargetParentArea[:0]
 	cd.EvalFormulas = cd.EvalFormulas[:0]
+	cd.evalSpans = cd.evalSpans[:0]
+	cd.targetIndex, cd.indexed = nil, 0
 	cd.EvalResult = nil
 }
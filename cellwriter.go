@@ -0,0 +1,31 @@
+package xlfill
+
+// CellWrite is the value of a template cell about to be written to an output
+// cell, or a value a command such as jx:grid writes. Formulas are not written
+// through it.
+type CellWrite struct {
+	Target    CellRef  // output cell
+	Source    CellRef  // template cell; the output cell for values written by commands
+	Value     any      // value to write
+	Type      CellType // type of Value
+	Style     string   // named style laid over the cell's style, e.g. from styled()
+	Evaluated bool     // Value comes from an expression; otherwise it is the template's value
+}
+
+// CellWriteFunc writes a cell value. Middleware of WithCellWriter may change
+// the write before passing it on, or skip it by not calling next.
+type CellWriteFunc func(w CellWrite) error
+
+// setCellWriters wraps writeValue in the middleware of WithCellWriter, the
+// first one outermost. Writes are applied serially, also with WithConcurrency.
+func (tx *ExcelizeTransformer) setCellWriters(mws []func(next CellWriteFunc) CellWriteFunc) {
+	if len(mws) == 0 {
+		tx.cellWriter = nil
+		return
+	}
+	write := CellWriteFunc(tx.writeValue)
+	for i := len(mws) - 1; i >= 0; i-- {
+		write = mws[i](write)
+	}
+	tx.cellWriter = write
+}
@@ -0,0 +1,80 @@
+package xlfill
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWithCellWriter(t *testing.T) {
+	tmpl := createValueTemplate(t, "cell_writer.xlsx", "${r.Name}", "${r.SSN}", "${r.Salary}", "static")
+	data := map[string]any{"rows": []map[string]any{
+		{"Name": "Alice", "SSN": "123-45-6789", "Salary": 1500.456},
+		{"Name": "Bob", "SSN": "987-65-4321", "Salary": 2300.5},
+	}}
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		var audit []string
+		auditor := func(next CellWriteFunc) CellWriteFunc {
+			return func(w CellWrite) error {
+				audit = append(audit, w.Target.String())
+				return next(w)
+			}
+		}
+		mask := func(next CellWriteFunc) CellWriteFunc {
+			return func(w CellWrite) error {
+				if w.Source.Col == 1 {
+					w.Value = "***-**-" + w.Value.(string)[7:]
+				}
+				return next(w)
+			}
+		}
+		round := func(next CellWriteFunc) CellWriteFunc {
+			return func(w CellWrite) error {
+				if f, ok := w.Value.(float64); ok && w.Evaluated {
+					w.Value = math.Round(f)
+				}
+				return next(w)
+			}
+		}
+		out, err := FillBytes(tmpl, data, append(opts, WithCellWriter(auditor), WithCellWriter(mask), WithCellWriter(round))...)
+		require.NoError(t, err)
+
+		got, err := openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Alice", "***-**-6789", "1500", "static"},
+			{"Bob", "***-**-4321", "2301", "static"},
+		}, got)
+		assert.Equal(t, []string{
+			"Sheet1!A1", "Sheet1!B1", "Sheet1!C1", "Sheet1!D1",
+			"Sheet1!A2", "Sheet1!B2", "Sheet1!C2", "Sheet1!D2",
+		}, audit)
+		_, style := styleOf(t, openOutput(t, out), "Sheet1", "A1")
+		assert.True(t, style.Font.Bold, "the cell style is kept")
+	}
+
+	// Middleware can skip a write and set a named style
+	out, err := FillBytes(tmpl, data, WithStyles(map[string]*excelize.Style{"hidden": {Font: &excelize.Font{Color: "FFFFFF"}}}),
+		WithCellWriter(func(next CellWriteFunc) CellWriteFunc {
+			return func(w CellWrite) error {
+				if !w.Evaluated {
+					return nil
+				}
+				if w.Source.Col == 2 {
+					w.Style = "hidden"
+				}
+				return next(w)
+			}
+		}))
+	require.NoError(t, err)
+	o := openOutput(t, out)
+	v, err := o.GetCellValue("Sheet1", "D2")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+	_, style := styleOf(t, o, "Sheet1", "C2")
+	assert.Equal(t, "FFFFFF", style.Font.Color)
+}
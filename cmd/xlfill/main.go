@@ -0,0 +1,256 @@
+// Command xlfill fills Excel templates from JSON or YAML data files.
+//
+// Usage:
+//
+//	xlfill fill -t template.xlsx -d data.json -o out.xlsx [--notation '{{,}}']
+//	xlfill validate -t template.xlsx [--json]
+//	xlfill inspect -t template.xlsx [--json]
+//	xlfill batch config.yaml
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/javajack/xlfill"
+	"github.com/javajack/xlfill/batch"
+)
+
+const usage = `Usage: xlfill <command> [flags]
+
+Commands:
+  fill      Fill a template from a JSON or YAML data file
+  validate  Check a template for errors without data
+  inspect   Print the areas, commands and expressions of a template
+  batch     Run the jobs of a YAML or JSON batch config
+
+Run "xlfill <command> -h" for command flags.
+`
+
+// errIssues signals that validation found errors; the issues were already printed.
+var errIssues = errors.New("template has errors")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the CLI and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "fill":
+		err = runFill(args[1:], stdout, stderr)
+	case "validate":
+		err = runValidate(args[1:], stdout, stderr)
+	case "inspect":
+		err = runInspect(args[1:], stdout, stderr)
+	case "batch":
+		err = runBatch(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "xlfill: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errIssues):
+		return 1
+	default:
+		fmt.Fprintf(stderr, "xlfill: %v\n", err)
+		return 1
+	}
+}
+
+// templateFlags registers the flags shared by all template commands.
+type templateFlags struct {
+	template string
+	notation string
+}
+
+func (tf *templateFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&tf.template, "t", "", "template `file` (.xlsx)")
+	fs.StringVar(&tf.notation, "notation", "", "expression delimiters as `begin,end` (default \"${,}\")")
+}
+
+// options validates the flags and converts them to xlfill options.
+func (tf *templateFlags) options() ([]xlfill.Option, error) {
+	if tf.template == "" {
+		return nil, fmt.Errorf("missing template: use -t")
+	}
+	opts := []xlfill.Option{xlfill.WithTemplate(tf.template)}
+	if tf.notation != "" {
+		begin, end, ok := strings.Cut(tf.notation, ",")
+		if !ok || begin == "" || end == "" {
+			return nil, fmt.Errorf("invalid notation %q: expected begin,end", tf.notation)
+		}
+		opts = append(opts, xlfill.WithExpressionNotation(begin, end))
+	}
+	return opts, nil
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("xlfill "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func runFill(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("fill", stderr)
+	var tf templateFlags
+	tf.register(fs)
+	dataPath := fs.String("d", "", "data `file` (.json, .yaml or .yml)")
+	output := fs.String("o", "", "output `file` (.xlsx)")
+	recalc := fs.Bool("recalc", false, "tell Excel to recalculate formulas on open")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts, err := tf.options()
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("missing output: use -o")
+	}
+	if *recalc {
+		opts = append(opts, xlfill.WithRecalculateOnOpen(true))
+	}
+
+	var data map[string]any
+	if *dataPath != "" {
+		if data, err = batch.LoadData(*dataPath); err != nil {
+			return err
+		}
+	}
+
+	if err := xlfill.NewFiller(opts...).Fill(data, *output); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s\n", *output)
+	return nil
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", stderr)
+	var tf templateFlags
+	tf.register(fs)
+	asJSON := fs.Bool("json", false, "print issues as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts, err := tf.options()
+	if err != nil {
+		return err
+	}
+	issues, err := xlfill.NewFiller(opts...).Validate()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if issues == nil {
+			issues = []xlfill.ValidationIssue{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Fprintln(stdout, issue)
+		}
+		if len(issues) == 0 {
+			fmt.Fprintln(stdout, "OK")
+		}
+	}
+
+	for _, issue := range issues {
+		if issue.Severity == xlfill.SeverityError {
+			return errIssues
+		}
+	}
+	return nil
+}
+
+func runInspect(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("inspect", stderr)
+	var tf templateFlags
+	tf.register(fs)
+	asJSON := fs.Bool("json", false, "print the template model as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts, err := tf.options()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		model, err := xlfill.Inspect(tf.template, opts...)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(model)
+	}
+
+	desc, err := xlfill.NewFiller(opts...).Describe()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(stdout, desc)
+	return nil
+}
+
+func runBatch(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("batch", stderr)
+	workers := fs.Int("workers", 0, "override the number of parallel jobs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("batch requires exactly one config file")
+	}
+
+	cfg, err := batch.LoadConfig(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *workers > 0 {
+		cfg.Workers = *workers
+	}
+
+	results := batch.Run(context.Background(), cfg)
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(stdout, "FAIL %s: %v\n", r.Job.Output, r.Err)
+		} else {
+			fmt.Fprintf(stdout, "ok   %s (%s)\n", r.Job.Output, r.Duration.Round(time.Millisecond))
+		}
+	}
+	if failed := batch.Failed(results); len(failed) > 0 {
+		return fmt.Errorf("%d of %d jobs failed", len(failed), len(results))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTemplate creates a template using the given expression delimiters.
+func writeTemplate(t *testing.T, path, begin, end string) {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", begin+"title"+end)
+	f.SetCellValue("Sheet1", "A2", begin+"e.Name"+end)
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="A2")`})
+	require.NoError(t, f.SaveAs(path))
+}
+
+func TestRun_Fill(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "t.xlsx")
+	data := filepath.Join(dir, "d.yaml")
+	out := filepath.Join(dir, "out.xlsx")
+	writeTemplate(t, tmpl, "{{", "}}")
+	require.NoError(t, os.WriteFile(data, []byte("title: Staff\nemployees:\n  - Name: Alice\n  - Name: Bob\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"fill", "-t", tmpl, "-d", data, "-o", out, "--notation", "{{,}}"}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "wrote")
+
+	f, err := excelize.OpenFile(out)
+	require.NoError(t, err)
+	defer f.Close()
+	for cell, want := range map[string]string{"A1": "Staff", "A2": "Alice", "A3": "Bob"} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestRun_FillErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{"fill", "-o", "out.xlsx"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "missing template")
+
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"fill", "-t", "t.xlsx", "-o", "o.xlsx", "--notation", "{{"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "invalid notation")
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"bogus"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `unknown command "bogus"`)
+}
+
+func TestRun_ValidateJSON(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "bad.xlsx")
+
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${e.Name +}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A1")`})
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", "-t", tmpl, "--json"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+
+	var issues []map[string]string
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &issues))
+	require.NotEmpty(t, issues)
+	assert.Equal(t, "ERROR", issues[0]["severity"])
+	assert.Equal(t, "Sheet1!A1", issues[0]["cell"])
+}
+
+func TestRun_ValidateAndInspect(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "t.xlsx")
+	writeTemplate(t, tmpl, "${", "}")
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"validate", "-t", tmpl}, &stdout, &stderr), stderr.String())
+	assert.Equal(t, "OK\n", stdout.String())
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"inspect", "-t", tmpl}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), `each (1x1) items="employees"`)
+
+	stdout.Reset()
+	require.Equal(t, 0, run([]string{"inspect", "-t", tmpl, "--json"}, &stdout, &stderr), stderr.String())
+	var model struct {
+		Variables []string `json:"variables"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &model))
+	assert.Equal(t, []string{"employees", "title"}, model.Variables)
+}
+
+func TestRun_Batch(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, filepath.Join(dir, "t.xlsx"), "${", "}")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "d.json"), []byte(`{"title": "x", "employees": []}`), 0o644))
+	cfg := filepath.Join(dir, "batch.yaml")
+	require.NoError(t, os.WriteFile(cfg, []byte("jobs:\n  - template: t.xlsx\n    data: d.json\n    output: out.xlsx\n"), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"batch", cfg}, &stdout, &stderr), stderr.String())
+	assert.Contains(t, stdout.String(), "ok   out.xlsx")
+	assert.FileExists(t, filepath.Join(dir, "out.xlsx"))
+}
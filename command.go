@@ -27,6 +27,18 @@ func NewCommandRegistry() *CommandRegistry {
 	r.Register("mergeCells", newMergeCellsCommandFromAttrs)
 	r.Register("updateCell", newUpdateCellCommandFromAttrs)
 	r.Register("autoRowHeight", newAutoRowHeightCommandFromAttrs)
+	r.Register("sortState", newSortStateCommandFromAttrs)
+	r.Register("mask", newMaskCommandFromAttrs)
+	r.Register("pivot", newPivotCommandFromAttrs)
+	r.Register("toc", newTocCommandFromAttrs)
+	r.Register("highlight", newHighlightCommandFromAttrs)
+	r.Register("anchor", newAnchorCommandFromAttrs)
+	r.Register("raw", newRawCommandFromAttrs)
+	r.Register("sheetProps", newSheetPropsCommandFromAttrs)
+	r.Register("deleteRowIf", newDeleteRowIfCommandFromAttrs)
+	r.Register("include", newIncludeCommandFromAttrs)
+	r.Register("define", newDefineCommandFromAttrs)
+	r.Register("call", newCallCommandFromAttrs)
 	return r
 }
 
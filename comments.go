@@ -0,0 +1,203 @@
+package xlfill
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// note returns the lines of a comment that are not markup, and whether the
+// comment holds any markup.
+func (m markupSyntax) note(comment string) (string, bool) {
+	markup, note := m.split(comment)
+	return strings.TrimSpace(strings.Join(note, "\n")), len(markup) > 0
+}
+
+// placeComments moves the comments of template cells to the cells written from
+// them, so a comment on a row repeated by jx:each appears on every copy. A
+// comment with jx: markup stays on its template cell unless strip is set; then
+// the markup is removed and the rest of the comment is placed like any other.
+// Comments of cells that were not written stay where they are. m tells markup
+// from other comments, such as those of other authors, which are placed whole.
+func (tx *ExcelizeTransformer) placeComments(strip bool, m markupSyntax) error {
+	type placement struct {
+		author, text string
+		targets      []CellRef
+	}
+	var remove []CellRef
+	var add []placement
+	cells := tx.GetCommentedCells()
+	slices.SortFunc(cells, func(a, b *CellData) int {
+		return cmp.Or(strings.Compare(a.Ref.Sheet, b.Ref.Sheet), cmp.Compare(a.Ref.Row, b.Ref.Row), cmp.Compare(a.Ref.Col, b.Ref.Col))
+	})
+	for _, cd := range cells {
+		note, markup := strings.TrimSpace(cd.Comment), false
+		if m.reads(cd.CommentAuthor) {
+			note, markup = m.note(cd.Comment)
+		}
+		if markup && !strip {
+			continue
+		}
+		targets := tx.targetRefs[cd.Ref]
+		if !markup && len(targets) == 0 {
+			continue
+		}
+		// Copied sheets carry the template's comments to the targets too
+		remove = append(remove, targets...)
+		at := cd.Ref
+		if tx.moved != nil {
+			at = tx.moved(cd.Ref)
+		}
+		if !tx.crossFile() {
+			remove = append(remove, at)
+			for _, sheet := range tx.copies[cd.Ref.Sheet] {
+				remove = append(remove, NewCellRef(sheet, at.Row, at.Col))
+			}
+		}
+		if note == "" {
+			continue
+		}
+		if len(targets) == 0 {
+			targets = []CellRef{at}
+		}
+		add = append(add, placement{author: cd.CommentAuthor, text: note, targets: targets})
+	}
+
+	// Remove first, so a comment placed on another template cell's position is
+	// kept; a template sheet may have been deleted by now.
+	sheets := tx.file.GetSheetList()
+	for _, ref := range remove {
+		if !slices.Contains(sheets, ref.Sheet) {
+			continue
+		}
+		if err := tx.file.DeleteComment(ref.Sheet, ref.CellName()); err != nil {
+			return fmt.Errorf("remove comment %s: %w", ref, err)
+		}
+	}
+	for _, p := range add {
+		for _, target := range p.targets {
+			cell := target.CellName()
+			if err := tx.file.DeleteComment(target.Sheet, cell); err != nil {
+				return fmt.Errorf("replace comment %s: %w", target, err)
+			}
+			comment := excelize.Comment{Cell: cell, Author: p.author, Text: p.text}
+			if err := tx.file.AddComment(target.Sheet, comment); err != nil {
+				return fmt.Errorf("add comment %s: %w", target, err)
+			}
+		}
+	}
+	return nil
+}
+
+// copyComments gives the sheet dst, just copied from src by excelize, its own
+// copy of src's comments. excelize's CopySheet leaves the copy pointing at the
+// comments part of src, so comments added to or removed from either sheet
+// would show on both.
+func (tx *ExcelizeTransformer) copyComments(src, dst string) error {
+	comments, err := tx.file.GetComments(src)
+	if err != nil {
+		return fmt.Errorf("read comments of %q: %w", src, err)
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+	if err := tx.detachLegacyDrawing(dst); err != nil {
+		return fmt.Errorf("detach comments of %q: %w", dst, err)
+	}
+	for _, c := range comments {
+		if err := tx.file.AddComment(dst, c); err != nil {
+			return fmt.Errorf("copy comment %s!%s: %w", dst, c.Cell, err)
+		}
+	}
+	return nil
+}
+
+// worksheet returns the loaded worksheet struct of a sheet and the number of
+// its part, for the settings excelize has no API for.
+func (tx *ExcelizeTransformer) worksheet(sheet string) (reflect.Value, int, error) {
+	return worksheetOf(tx.file, sheet)
+}
+
+// worksheetOf returns the loaded worksheet struct of a sheet of f and the
+// number of its part.
+func worksheetOf(f *excelize.File, sheet string) (reflect.Value, int, error) {
+	id := 0
+	for sheetID, name := range f.GetSheetMap() {
+		if name == sheet {
+			id = sheetID
+		}
+	}
+	ws, ok := f.Sheet.Load(fmt.Sprintf("xl/worksheets/sheet%d.xml", id))
+	if !ok {
+		return reflect.Value{}, 0, fmt.Errorf("worksheet of sheet %q not loaded", sheet)
+	}
+	return reflect.ValueOf(ws).Elem(), id, nil
+}
+
+// detachLegacyDrawing removes the reference of a sheet to its VML drawing and
+// comments parts. excelize has no API for this; the worksheet and its
+// relationships are edited in place.
+func (tx *ExcelizeTransformer) detachLegacyDrawing(sheet string) error {
+	ws, id, err := tx.worksheet(sheet)
+	if err != nil {
+		return err
+	}
+	drawing := ws.FieldByName("LegacyDrawing")
+	if !drawing.IsValid() || drawing.IsNil() {
+		return nil
+	}
+	rID := drawing.Elem().FieldByName("RID").String()
+	drawing.SetZero()
+
+	drop := func(relID, relType string) bool {
+		return relID == rID || relType == excelize.SourceRelationshipComments
+	}
+	relsPath := fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", id)
+	if rels, ok := tx.file.Relationships.Load(relsPath); ok && rels != nil {
+		list := reflect.ValueOf(rels).Elem().FieldByName("Relationships")
+		kept := reflect.MakeSlice(list.Type(), 0, list.Len())
+		for i := range list.Len() {
+			r := list.Index(i)
+			if !drop(r.FieldByName("ID").String(), r.FieldByName("Type").String()) {
+				kept = reflect.Append(kept, r)
+			}
+		}
+		list.Set(kept)
+		return nil
+	}
+	raw, ok := tx.file.Pkg.Load(relsPath)
+	if !ok {
+		return nil
+	}
+	var rels sheetRelationships
+	if err := xml.Unmarshal(raw.([]byte), &rels); err != nil {
+		return err
+	}
+	rels.Relationships = slices.DeleteFunc(rels.Relationships, func(r sheetRelationship) bool {
+		return drop(r.ID, r.Type)
+	})
+	data, err := xml.Marshal(rels)
+	if err != nil {
+		return err
+	}
+	tx.file.Pkg.Store(relsPath, append([]byte(xml.Header), data...))
+	return nil
+}
+
+// sheetRelationships is the relationships part of a worksheet.
+type sheetRelationships struct {
+	XMLName       xml.Name            `xml:"http://schemas.openxmlformats.org/package/2006/relationships Relationships"`
+	Relationships []sheetRelationship `xml:"Relationship"`
+}
+
+type sheetRelationship struct {
+	ID         string `xml:"Id,attr"`
+	Target     string `xml:",attr"`
+	Type       string `xml:",attr"`
+	TargetMode string `xml:",attr,omitempty"`
+}
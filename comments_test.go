@@ -0,0 +1,142 @@
+package xlfill
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestCommentNote(t *testing.T) {
+	note, markup := defaultMarkup.note("jx:area(lastCell=\"B2\")\r\nCheck totals\njx:params(defaultValue=\"0\")")
+	assert.True(t, markup)
+	assert.Equal(t, "Check totals", note)
+
+	note, markup = defaultMarkup.note("Reviewed by finance")
+	assert.False(t, markup)
+	assert.Equal(t, "Reviewed by finance", note)
+}
+
+// fillComments fills a staff list with a footer and returns the output comments
+// of every sheet, keyed by "Sheet!Cell".
+//
+//	A1: "Staff"      [jx:area(lastCell="B3") + "Header note"]
+//	A2: "${e.Name}"  [jx:each(items="staff" var="e" lastCell="B2")]
+//	B2: "${e.Pay}"   ["gross pay"]
+//	A3: "End"        ["footer note"]
+//	Notes!A1: "x"    ["kept"] (no area)
+func fillComments(t *testing.T, opts ...Option) map[string]string {
+	t.Helper()
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	tmpl.SetCellValue("Sheet1", "A1", "Staff")
+	tmpl.SetCellValue("Sheet1", "A2", "${e.Name}")
+	tmpl.SetCellValue("Sheet1", "B2", "${e.Pay}")
+	tmpl.SetCellValue("Sheet1", "A3", "End")
+	for cell, text := range map[string]string{
+		"A1": "jx:area(lastCell=\"B3\")\nHeader note",
+		"A2": `jx:each(items="staff" var="e" lastCell="B2")`,
+		"B2": "gross pay",
+		"A3": "footer note",
+	} {
+		require.NoError(t, tmpl.AddComment("Sheet1", excelize.Comment{Cell: cell, Author: "xlfill", Text: text}))
+	}
+	_, err := tmpl.NewSheet("Notes")
+	require.NoError(t, err)
+	tmpl.SetCellValue("Notes", "A1", "x")
+	require.NoError(t, tmpl.AddComment("Notes", excelize.Comment{Cell: "A1", Author: "xlfill", Text: "kept"}))
+	var buf, out bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	data := map[string]any{"staff": []map[string]any{{"Name": "Ann", "Pay": 10}, {"Name": "Bob", "Pay": 20}, {"Name": "Cy", "Pay": 30}}}
+	require.NoError(t, FillReader(&buf, &out, data, opts...))
+	f := openOutput(t, out.Bytes())
+
+	comments := map[string]string{}
+	for _, sheet := range f.GetSheetList() {
+		list, err := f.GetComments(sheet)
+		require.NoError(t, err)
+		for _, c := range list {
+			comments[sheet+"!"+c.Cell] = c.Text
+		}
+	}
+	return comments
+}
+
+func TestComments_FollowRepeatedCells(t *testing.T) {
+	comments := fillComments(t)
+	assert.Equal(t, map[string]string{
+		"Sheet1!A1": "jx:area(lastCell=\"B3\")\nHeader note",
+		"Sheet1!A2": `jx:each(items="staff" var="e" lastCell="B2")`,
+		"Sheet1!B2": "gross pay",
+		"Sheet1!B3": "gross pay",
+		"Sheet1!B4": "gross pay",
+		"Sheet1!A5": "footer note",
+		"Notes!A1":  "kept",
+	}, comments)
+}
+
+func TestWithStripMarkupComments(t *testing.T) {
+	comments := fillComments(t, WithStripMarkupComments(true))
+	assert.Equal(t, map[string]string{
+		"Sheet1!A1": "Header note",
+		"Sheet1!B2": "gross pay",
+		"Sheet1!B3": "gross pay",
+		"Sheet1!B4": "gross pay",
+		"Sheet1!A5": "footer note",
+		"Notes!A1":  "kept",
+	}, comments)
+}
+
+func TestWithStripMarkupComments_MultiSheet(t *testing.T) {
+	tmpl := createConcurrencyTemplate(t, "comments_multisheet.xlsx")
+	for _, n := range []int{1, 4} {
+		out, err := FillBytes(tmpl, concurrencyData(3), WithStripMarkupComments(true), WithConcurrency(n))
+		require.NoError(t, err)
+		f := openOutput(t, out)
+		for _, sheet := range f.GetSheetList() {
+			comments, err := f.GetComments(sheet)
+			require.NoError(t, err)
+			assert.Empty(t, comments, "%s, concurrency %d", sheet, n)
+		}
+	}
+}
+
+func TestComments_MarkupPrefixAndAuthor(t *testing.T) {
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	tmpl.SetCellValue("Sheet1", "A1", "${e.Name}")
+	tmpl.SetCellValue("Sheet1", "B1", "${e.Pay}")
+	for _, c := range []excelize.Comment{
+		{Cell: "A1", Author: "xlfill", Text: "gox:area(lastCell=\"B1\")\ngox:each(items=\"staff\" var=\"e\" lastCell=\"B1\")"},
+		// A designer's comments: neither is a command
+		{Cell: "B1", Author: "Designer", Text: `gox:if(condition="false" lastCell="B1")`},
+		{Cell: "C1", Author: "xlfill", Text: `jx:each(items="missing" var="m" lastCell="C1")`},
+	} {
+		require.NoError(t, tmpl.AddComment("Sheet1", c))
+	}
+	var buf, out bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	data := map[string]any{"staff": []map[string]any{{"Name": "Ann", "Pay": 10}, {"Name": "Bob", "Pay": 20}}}
+	require.NoError(t, FillReader(&buf, &out, data,
+		WithMarkupPrefix("gox:"), WithMarkupAuthor("XLFill"), WithStripMarkupComments(true)))
+	f := openOutput(t, out.Bytes())
+
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Ann", "10"}, {"Bob", "20"}}, rows)
+	list, err := f.GetComments("Sheet1")
+	require.NoError(t, err)
+	comments := map[string]string{}
+	for _, c := range list {
+		comments[c.Cell] = c.Text
+	}
+	assert.Equal(t, map[string]string{
+		"B1": `gox:if(condition="false" lastCell="B1")`,
+		"B2": `gox:if(condition="false" lastCell="B1")`,
+		"C1": `jx:each(items="missing" var="m" lastCell="C1")`,
+	}, comments)
+}
@@ -0,0 +1,149 @@
+package xlfill
+
+import "sync"
+
+// writeLog queues transformer writes made while processing on a worker
+// goroutine. Logs are replayed in job order, so output does not depend on
+// scheduling.
+type writeLog struct {
+	ops []func() error
+}
+
+// runJobs runs jobs on up to workers goroutines. Each job gets a child of ctx
+// that evaluates expressions immediately but queues transformer writes; once
+// all jobs finish, the queued writes are applied (or queued on ctx, when ctx
+// itself defers writes) in job order. The first error in job order is returned.
+func runJobs(ctx *Context, workers int, jobs []func(*Context) error) error {
+	logs := make([]*writeLog, len(jobs))
+	errs := make([]error, len(jobs))
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		jobCtx := ctx.withWriteLog()
+		logs[i] = jobCtx.deferred
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = job(jobCtx)
+		}()
+	}
+	wg.Wait()
+
+	for i := range jobs {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		for _, op := range logs[i].ops {
+			if err := ctx.run(op); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deferredTransformer queues the writes of commands on a write log; reads go
+// to the wrapped transformer. Transform queues through its Context.
+type deferredTransformer struct {
+	Transformer
+	log *writeLog
+}
+
+// transformerFor returns the transformer commands should use under ctx.
+func transformerFor(tx Transformer, ctx *Context) Transformer {
+	if ctx.deferred == nil {
+		return tx
+	}
+	return &deferredTransformer{Transformer: unwrapTransformer(tx), log: ctx.deferred}
+}
+
+// unwrapTransformer returns the transformer that applies writes.
+func unwrapTransformer(tx Transformer) Transformer {
+	if d, ok := tx.(*deferredTransformer); ok {
+		return d.Transformer
+	}
+	return tx
+}
+
+func (d *deferredTransformer) queue(op func() error) error {
+	d.log.ops = append(d.log.ops, op)
+	return nil
+}
+
+func (d *deferredTransformer) ClearCell(ref CellRef) error {
+	return d.queue(func() error { return d.Transformer.ClearCell(ref) })
+}
+
+func (d *deferredTransformer) SetFormula(ref CellRef, formula string) error {
+	return d.queue(func() error { return d.Transformer.SetFormula(ref, formula) })
+}
+
+func (d *deferredTransformer) SetCellValue(ref CellRef, value any) error {
+	return d.queue(func() error { return d.Transformer.SetCellValue(ref, value) })
+}
+
+func (d *deferredTransformer) ApplyStyle(ref CellRef, name string) error {
+	return d.queue(func() error { return applyNamedStyle(d.Transformer, ref, name) })
+}
+
+func (d *deferredTransformer) SetRowHeight(sheet string, row int, height float64) error {
+	return d.queue(func() error { return d.Transformer.SetRowHeight(sheet, row, height) })
+}
+
+func (d *deferredTransformer) SetRowOutlineLevel(sheet string, row int, level uint8) error {
+	return d.queue(func() error { return setRowOutlineLevel(d.Transformer, sheet, row, level) })
+}
+
+func (d *deferredTransformer) FitRowHeight(sheet string, row, firstCol, lastCol int, fit RowFit) error {
+	return d.queue(func() error { return fitRowHeight(d.Transformer, sheet, row, firstCol, lastCol, fit) })
+}
+
+func (d *deferredTransformer) SetOutlineSummaryBelow(sheet string, below bool) error {
+	return d.queue(func() error { return setOutlineSummaryBelow(d.Transformer, sheet, below) })
+}
+
+func (d *deferredTransformer) SetAutoFilter(area AreaRef, sortCol int, descending bool) error {
+	return d.queue(func() error { return setAutoFilter(d.Transformer, area, sortCol, descending) })
+}
+
+func (d *deferredTransformer) DeleteSheet(name string) error {
+	return d.queue(func() error { return d.Transformer.DeleteSheet(name) })
+}
+
+func (d *deferredTransformer) SetHidden(name string, hidden bool) error {
+	return d.queue(func() error { return d.Transformer.SetHidden(name, hidden) })
+}
+
+func (d *deferredTransformer) CopySheet(src, dst string) error {
+	return d.queue(func() error { return d.Transformer.CopySheet(src, dst) })
+}
+
+func (d *deferredTransformer) MoveSheet(name, after string) error {
+	return d.queue(func() error { return d.Transformer.MoveSheet(name, after) })
+}
+
+func (d *deferredTransformer) SetSheetOrder(names []string) error {
+	return d.queue(func() error { return d.Transformer.SetSheetOrder(names) })
+}
+
+func (d *deferredTransformer) SetActiveSheet(name string) error {
+	return d.queue(func() error { return d.Transformer.SetActiveSheet(name) })
+}
+
+func (d *deferredTransformer) SetSheetProps(name string, props SheetProps) error {
+	return d.queue(func() error { return d.Transformer.SetSheetProps(name, props) })
+}
+
+func (d *deferredTransformer) AddImage(sheet string, cell string, imgBytes []byte, imgType string, scaleX, scaleY float64) error {
+	return d.queue(func() error { return d.Transformer.AddImage(sheet, cell, imgBytes, imgType, scaleX, scaleY) })
+}
+
+func (d *deferredTransformer) MergeCells(sheet, topLeft, bottomRight string) error {
+	return d.queue(func() error { return d.Transformer.MergeCells(sheet, topLeft, bottomRight) })
+}
+
+func (d *deferredTransformer) SetCellHyperLink(ref CellRef, url, display string) error {
+	return d.queue(func() error { return d.Transformer.SetCellHyperLink(ref, url, display) })
+}
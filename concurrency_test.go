@@ -0,0 +1,143 @@
+package xlfill
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createConcurrencyTemplate creates a multisheet "template" sheet with a
+// nested each and a SUM below it, and a "Lookup" sheet with its own area.
+//
+//	template!A1: "${d.Name}"  [jx:area(lastCell="B3"), jx:each(items="depts" var="d" multisheet="names" lastCell="B3")]
+//	template!A2: "${e.Name}"  [jx:each(items="d.Staff" var="e" lastCell="B2")]
+//	template!B2: "${e.Pay}"
+//	template!B3: =SUM(B2)
+//	Lookup!A1:   "${c.Code}"  [jx:area(lastCell="A1"), jx:each(items="codes" var="c" lastCell="A1")]
+func createConcurrencyTemplate(t *testing.T, name string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", "template")
+	f.SetCellValue("template", "A1", "${d.Name}")
+	f.SetCellValue("template", "A2", "${e.Name}")
+	f.SetCellValue("template", "B2", "${e.Pay}")
+	f.SetCellFormula("template", "B3", "SUM(B2)")
+	f.AddComment("template", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"B3\")\njx:each(items=\"depts\" var=\"d\" multisheet=\"names\" lastCell=\"B3\")"})
+	f.AddComment("template", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="d.Staff" var="e" lastCell="B2")`})
+
+	_, err := f.NewSheet("Lookup")
+	require.NoError(t, err)
+	f.SetCellValue("Lookup", "A1", "${c.Code}")
+	f.AddComment("Lookup", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:each(items=\"codes\" var=\"c\" lastCell=\"A1\")"})
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func concurrencyData(sheets int) map[string]any {
+	var depts []map[string]any
+	var names []string
+	for i := range sheets {
+		var staff []map[string]any
+		for j := range i%4 + 1 {
+			staff = append(staff, map[string]any{"Name": fmt.Sprintf("E%d-%d", i, j), "Pay": (i + 1) * (j + 1)})
+		}
+		depts = append(depts, map[string]any{"Name": fmt.Sprintf("Dept %d", i), "Staff": staff})
+		names = append(names, fmt.Sprintf("D%02d", i))
+	}
+	var codes []map[string]any
+	for i := range 5 {
+		codes = append(codes, map[string]any{"Code": fmt.Sprintf("C%d", i)})
+	}
+	return map[string]any{"depts": depts, "names": names, "codes": codes}
+}
+
+// workbookDump lists every sheet with its cell values and formulas.
+func workbookDump(t *testing.T, out []byte) []string {
+	t.Helper()
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var dump []string
+	for _, sheet := range f.GetSheetList() {
+		dump = append(dump, "sheet "+sheet)
+		rows, err := f.GetRows(sheet)
+		require.NoError(t, err)
+		for r, row := range rows {
+			for c, v := range row {
+				cell, _ := excelize.CoordinatesToCellName(c+1, r+1)
+				formula, _ := f.GetCellFormula(sheet, cell)
+				dump = append(dump, fmt.Sprintf("%s!%s=%q %q", sheet, cell, v, formula))
+			}
+		}
+	}
+	return dump
+}
+
+func TestWithConcurrency_MatchesSerialOutput(t *testing.T) {
+	tmpl := createConcurrencyTemplate(t, "concurrency.xlsx")
+	data := concurrencyData(12)
+
+	serial, err := FillBytes(tmpl, data)
+	require.NoError(t, err)
+	want := workbookDump(t, serial)
+	assert.Contains(t, want, `D05!B4="" "SUM(D05!B2:D05!B3)"`)
+
+	for _, n := range []int{2, 4, 16} {
+		parallel, err := FillBytes(tmpl, data, WithConcurrency(n))
+		require.NoError(t, err)
+		assert.Equal(t, want, workbookDump(t, parallel), "concurrency %d", n)
+	}
+}
+
+func TestWithConcurrency_TocListsSheetsInOrder(t *testing.T) {
+	tmpl := createTocTemplate(t, "concurrency_toc.xlsx", true)
+	data := map[string]any{
+		"depts": []map[string]any{{"Name": "Sales"}, {"Name": "R&D"}, {"Name": "Ops"}},
+		"names": []string{"Sales", "R&D", "Ops"},
+	}
+	out, err := FillBytes(tmpl, data, WithConcurrency(3))
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"Index", "Sales", "R&D", "Ops"}, f.GetSheetList())
+	for cell, want := range map[string]string{"A2": "Sales", "A3": "R&D", "A4": "Ops", "A5": "End"} {
+		v, _ := f.GetCellValue("Index", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestWithConcurrency_ReportsFirstErrorInOrder(t *testing.T) {
+	tmpl := createConcurrencyTemplate(t, "concurrency_err.xlsx")
+	data := concurrencyData(6)
+	depts := data["depts"].([]map[string]any)
+	depts[2]["Staff"] = 42
+	depts[4]["Staff"] = "x"
+
+	_, serialErr := FillBytes(tmpl, data)
+	require.Error(t, serialErr)
+	_, err := FillBytes(tmpl, data, WithConcurrency(4))
+	require.Error(t, err)
+	assert.Equal(t, serialErr.Error(), err.Error())
+}
+
+func TestWithConcurrency_ListenersRunSerially(t *testing.T) {
+	tmpl := createConcurrencyTemplate(t, "concurrency_listener.xlsx")
+	listener := &eventListener{}
+	_, err := FillBytes(tmpl, concurrencyData(8), WithConcurrency(4), WithAreaListener(listener))
+	require.NoError(t, err)
+	assert.NotEmpty(t, listener.commands)
+}
@@ -1,15 +1,25 @@
 package xlfill
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"maps"
 	"strings"
+	"sync"
 )
 
 // Context holds template data and provides expression evaluation.
 // It manages both user-provided data and loop iteration variables (runVars).
+//
+// Contexts form a chain of scopes: WithVar and WithVars return a child context
+// whose variables shadow the parent's, leaving the parent unchanged. Commands
+// bind loop variables this way, so sibling children can be used concurrently
+// as long as their common ancestors are not modified meanwhile.
 type Context struct {
 	data           map[string]any
-	runVars        map[string]any
+	parent         *Context       // enclosing scope, nil for the root
+	runVars        map[string]any // variables of this scope
 	evaluator      ExpressionEvaluator
 	notationBegin  string
 	notationEnd    string
@@ -19,6 +29,92 @@ type Context struct {
 	// Cached merged map for expression evaluation.
 	// Invalidated (set to nil) whenever runVars change.
 	cachedMap map[string]any
+
+	state    *fillState // shared by every scope of a fill
+	deferred *writeLog  // when set, transformer writes are queued here instead of applied
+
+	outlineLevel int // number of enclosing jx:each groups with outline="true"
+
+	raw   bool // inside jx:raw: cell values and formulas are copied unevaluated
+	blank bool // inside a jx:each without items and emptyAction="CLEAR": cells are written blank
+
+	masked map[string]bool // fields hidden by enclosing jx:mask commands, e.g. "e.SSN"
+
+	macroDepth int // number of enclosing jx:call commands
+
+	source *CellRef // template cell being transformed, set when usage is tracked or errors collected
+}
+
+// fillState holds per-fill bookkeeping shared by all scopes of a Context.
+type fillState struct {
+	mu sync.Mutex
+
+	// Sheets created by multisheet jx:each, in generation order.
+	generatedSheets []string
+
+	// First output cell of each jx:anchor name.
+	anchors map[string]CellRef
+
+	// Sheet dispositions set by the Filler. Multisheet template sheets without
+	// an entry use templateDisposition (default: delete).
+	sheetDispositions   map[string]SheetDisposition
+	templateDisposition SheetDisposition
+	disposedSheets      map[string]bool
+
+	// Workers for concurrent processing; 0 or 1 processes serially.
+	concurrency int
+
+	// Debug events go to logger when set; depth is the current nesting of
+	// areas and commands.
+	logger *slog.Logger
+	depth  int
+
+	// Output limits, with the cells written and sheets in the workbook so far.
+	limits outputLimits
+	cells  int
+	sheets int
+
+	// Names the sheets of multisheet jx:each; nil means SafeSheetNameBuilder{}.
+	sheetNames SheetNameBuilder
+
+	// Variables of individual output sheets, by sheet name.
+	sheetData map[string]map[string]any
+
+	// Stops the fill once done; nil means the fill runs to the end.
+	cancel context.Context
+
+	// Converts expression values before they are written; nil writes them as
+	// they are.
+	convert func(v any) (any, CellType, bool)
+
+	// Items of Iterators read ahead of their jx:each by header cells.
+	peeks iteratorPeeks
+
+	// Iterators a jx:each has read; an Iterator can be read once.
+	readIterators map[Iterator]bool
+
+	// Records the data read by expressions; nil when not reported.
+	usage *usageTracker
+
+	// Failing cell expressions are collected instead of stopping the fill
+	// under CollectAndContinue, with the placeholder written in their place.
+	errorPolicy      ErrorPolicy
+	errorPlaceholder string
+	exprErrors       []*ExpressionError
+
+	// Hides personal data in cell values; nil unless WithRedaction is used.
+	redaction *redaction
+
+	// select of jx:each with groupBy filters the groups, as with
+	// oldSelectBehavior="true"; set by WithSelectBeforeGroup(false).
+	selectAfterGroup bool
+
+	// Templates opened by jx:include; nil outside a Filler's fill.
+	includes *includes
+
+	// Output rows rendered by jx:deleteRowIf, deleted after the formula pass
+	// when their condition holds.
+	rowDeletions []rowDeletion
 }
 
 // ContextOption configures a Context.
@@ -66,6 +162,7 @@ func NewContext(data map[string]any, opts ...ContextOption) *Context {
 		notationEnd:    "}",
 		updateCellData: true,
 		clearCells:     true,
+		state:          &fillState{},
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -73,9 +170,54 @@ func NewContext(data map[string]any, opts ...ContextOption) *Context {
 	return c
 }
 
+// WithVar returns a child context in which name is bound to value.
+func (c *Context) WithVar(name string, value any) *Context {
+	return c.WithVars(map[string]any{name: value})
+}
+
+// WithVars returns a child context in which the given variables are bound,
+// shadowing variables of the same name in c. The map is copied.
+func (c *Context) WithVars(vars map[string]any) *Context {
+	child := *c
+	child.parent = c
+	child.runVars = make(map[string]any, len(vars))
+	for k, v := range vars {
+		child.runVars[k] = v
+	}
+	child.cachedMap = nil
+	return &child
+}
+
+// run applies a transformer write now, or queues it when c defers writes.
+func (c *Context) run(op func() error) error {
+	if c.deferred != nil {
+		c.deferred.ops = append(c.deferred.ops, op)
+		return nil
+	}
+	return op()
+}
+
+// withWriteLog returns a child context that queues transformer writes in a
+// new log, for processing on another goroutine.
+func (c *Context) withWriteLog() *Context {
+	child := c.WithVars(nil)
+	child.deferred = &writeLog{}
+	return child
+}
+
+// lookupRunVar finds a run variable in this scope or the nearest enclosing one.
+func (c *Context) lookupRunVar(name string) (any, bool) {
+	for s := c; s != nil; s = s.parent {
+		if v, ok := s.runVars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
 // GetVar returns a variable value. Checks runVars first, then data.
 func (c *Context) GetVar(name string) any {
-	if v, ok := c.runVars[name]; ok {
+	if v, ok := c.lookupRunVar(name); ok {
 		return v
 	}
 	return c.data[name]
@@ -95,7 +237,7 @@ func (c *Context) RemoveVar(name string) {
 
 // ContainsVar returns true if the variable exists in either runVars or data.
 func (c *Context) ContainsVar(name string) bool {
-	if _, ok := c.runVars[name]; ok {
+	if _, ok := c.lookupRunVar(name); ok {
 		return true
 	}
 	_, ok := c.data[name]
@@ -109,33 +251,195 @@ func (c *Context) ToMap() map[string]any {
 	if c.cachedMap != nil {
 		return c.cachedMap
 	}
+	var scopes []*Context
+	for s := c; s != nil; s = s.parent {
+		scopes = append(scopes, s)
+	}
 	m := make(map[string]any, len(c.data)+len(c.runVars)+2)
 	for k, v := range c.data {
 		m[k] = v
 	}
-	for k, v := range c.runVars {
-		m[k] = v
+	for i := len(scopes) - 1; i >= 0; i-- {
+		for k, v := range scopes[i].runVars {
+			m[k] = v
+		}
 	}
 	// Built-in functions
 	if _, ok := m["hyperlink"]; !ok {
 		m["hyperlink"] = Hyperlink
 	}
+	if _, ok := m["styled"]; !ok {
+		m["styled"] = Styled
+	}
+	if _, ok := m["text"]; !ok {
+		m["text"] = Text
+	}
+	if _, ok := m["currency"]; !ok {
+		m["currency"] = Currency
+	}
+	if _, ok := m["percent"]; !ok {
+		m["percent"] = Percent
+	}
+	if _, ok := m["avg"]; !ok {
+		m["avg"] = avgOf
+	}
+	m[peeksVar] = &c.state.peeks
 	c.cachedMap = m
 	return m
 }
 
+// GeneratedSheets returns the names of sheets created by multisheet jx:each
+// commands so far, in generation order.
+func (c *Context) GeneratedSheets() []string {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return append([]string(nil), c.state.generatedSheets...)
+}
+
+// addGeneratedSheet records a sheet created by a multisheet jx:each. Like the
+// sheet copy itself, the record is queued when c defers writes, which keeps
+// the order deterministic.
+func (c *Context) addGeneratedSheet(sheet string) {
+	c.run(func() error {
+		c.state.mu.Lock()
+		defer c.state.mu.Unlock()
+		c.state.generatedSheets = append(c.state.generatedSheets, sheet)
+		return nil
+	})
+}
+
+// addAnchor records ref as a placement of the anchor name; the first placement
+// is kept. Like addGeneratedSheet, the record is queued when c defers writes.
+func (c *Context) addAnchor(name string, ref CellRef) {
+	c.run(func() error {
+		c.state.mu.Lock()
+		defer c.state.mu.Unlock()
+		if c.state.anchors == nil {
+			c.state.anchors = map[string]CellRef{}
+		}
+		if _, ok := c.state.anchors[name]; !ok {
+			c.state.anchors[name] = ref
+		}
+		return nil
+	})
+}
+
+// sheetNameBuilder returns the builder for the names of generated sheets.
+func (c *Context) sheetNameBuilder() SheetNameBuilder {
+	if c.state.sheetNames == nil {
+		return SafeSheetNameBuilder{}
+	}
+	return c.state.sheetNames
+}
+
+// forSheet returns a child context with the variables set for sheet by
+// WithSheetData, or c when there are none.
+func (c *Context) forSheet(sheet string) *Context {
+	vars, ok := c.state.sheetData[sheet]
+	if !ok {
+		return c
+	}
+	return c.WithVars(vars)
+}
+
+// anchors returns the anchors placed so far by name.
+func (c *Context) anchors() map[string]CellRef {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return maps.Clone(c.state.anchors)
+}
+
+// setSheetDispositions sets per-sheet dispositions and the default for
+// multisheet template sheets.
+func (c *Context) setSheetDispositions(dispositions map[string]SheetDisposition, templateDefault SheetDisposition) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.sheetDispositions = dispositions
+	c.state.templateDisposition = templateDefault
+}
+
+// concurrency returns the number of workers for concurrent processing.
+func (c *Context) concurrency() int {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return c.state.concurrency
+}
+
+// setConcurrency sets the number of workers for concurrent processing.
+func (c *Context) setConcurrency(workers int) {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.concurrency = workers
+}
+
+// templateSheetDisposition returns the disposition for a multisheet template sheet.
+func (c *Context) templateSheetDisposition(sheet string) SheetDisposition {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if d, ok := c.state.sheetDispositions[sheet]; ok {
+		return d
+	}
+	if c.state.templateDisposition != "" {
+		return c.state.templateDisposition
+	}
+	return SheetDelete
+}
+
+// markDisposed records that a sheet's disposition has been applied. It reports
+// false if the sheet was already disposed.
+func (c *Context) markDisposed(sheet string) bool {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if c.state.disposedSheets[sheet] {
+		return false
+	}
+	if c.state.disposedSheets == nil {
+		c.state.disposedSheets = make(map[string]bool)
+	}
+	c.state.disposedSheets[sheet] = true
+	return true
+}
+
+// isDisposed reports whether a sheet's disposition has been applied.
+func (c *Context) isDisposed(sheet string) bool {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	return c.state.disposedSheets[sheet]
+}
+
 // invalidateCache clears the cached merged map.
 func (c *Context) invalidateCache() {
 	c.cachedMap = nil
 }
 
-// Evaluate evaluates an expression string using the merged data.
+// Evaluate evaluates an expression string using the merged data. An Iterator
+// whose items were read ahead, e.g. by ${first(rows).Date} in a header, is
+// returned as an Iterator over all of its items.
 func (c *Context) Evaluate(expression string) (any, error) {
-	return c.evaluator.Evaluate(expression, c.ToMap())
+	if c.state.usage != nil {
+		c.noteEvaluation(expression)
+	}
+	v, err := c.evaluator.Evaluate(expression, c.ToMap())
+	if it, ok := v.(Iterator); ok {
+		return c.state.peeks.replay(it), err
+	}
+	return v, err
+}
+
+// sandbox returns the sandbox of the context's evaluator, or nil when
+// expressions are unrestricted.
+func (c *Context) sandbox() *exprSandbox {
+	if ev, ok := c.evaluator.(*exprEvaluator); ok {
+		return ev.sandbox
+	}
+	return nil
 }
 
 // IsConditionTrue evaluates a boolean condition.
 func (c *Context) IsConditionTrue(condition string) (bool, error) {
+	if c.state.usage != nil {
+		c.noteEvaluation(condition)
+	}
 	return c.evaluator.IsConditionTrue(condition, c.ToMap())
 }
 
@@ -143,14 +447,28 @@ func (c *Context) IsConditionTrue(condition string) (bool, error) {
 // If the value is a single expression like "${e.Name}", the result is typed (number, bool, etc.).
 // If mixed content like "Name: ${e.Name}", the result is always a string.
 func (c *Context) EvaluateCellValue(value string) (any, CellType, error) {
+	if c.raw {
+		return value, CellString, nil
+	}
+	if c.blank {
+		return nil, CellBlank, nil
+	}
+
 	// Check if it's a single expression
 	exprStr, isSingle := ExtractSingleExpression(value, c.notationBegin, c.notationEnd)
 	if isSingle {
 		result, err := c.Evaluate(exprStr)
 		if err != nil {
-			return nil, CellBlank, fmt.Errorf("evaluate %q: %w", value, err)
+			return c.expressionError(fmt.Errorf("evaluate %q: %w", value, err))
+		}
+		if result == nil && c.state.usage != nil {
+			c.noteUnresolved(exprStr)
 		}
-		return result, inferCellType(result), nil
+		if result != nil && c.state.redaction != nil && c.redacts(exprStr) {
+			return c.state.redaction.replacement, CellString, nil
+		}
+		result, cellType := c.convertValue(result)
+		return result, cellType, nil
 	}
 
 	// Parse and evaluate all expressions in mixed content
@@ -168,7 +486,8 @@ func (c *Context) EvaluateCellValue(value string) (any, CellType, error) {
 		}
 	}
 	if !hasExpr {
-		return value, CellString, nil
+		// Only literal text, possibly with escaped delimiters
+		return segments[0].Text, CellString, nil
 	}
 
 	// Build result string
@@ -177,9 +496,15 @@ func (c *Context) EvaluateCellValue(value string) (any, CellType, error) {
 		if seg.IsExpression {
 			val, err := c.Evaluate(seg.Text)
 			if err != nil {
-				return nil, CellBlank, fmt.Errorf("evaluate expression %q in %q: %w", seg.Text, value, err)
+				return c.expressionError(fmt.Errorf("evaluate expression %q in %q: %w", seg.Text, value, err))
+			}
+			if val == nil && c.state.usage != nil {
+				c.noteUnresolved(seg.Text)
 			}
-			if val != nil {
+			if val != nil && c.state.redaction != nil && c.redacts(seg.Text) {
+				val = c.state.redaction.replacement
+			}
+			if val, _ = c.convertValue(val); val != nil {
 				fmt.Fprintf(&b, "%v", val)
 			}
 		} else {
@@ -189,6 +514,21 @@ func (c *Context) EvaluateCellValue(value string) (any, CellType, error) {
 	return b.String(), CellString, nil
 }
 
+// convertValue returns v as converted by the fill's value converter, with its
+// cell type. Values the converter leaves are unwrapped by nullValue.
+func (c *Context) convertValue(v any) (any, CellType) {
+	if c.state.convert != nil {
+		if cv, cellType, ok := c.state.convert(v); ok {
+			return cv, cellType
+		}
+	}
+	v = nullValue(v)
+	if n, ok := exactNumber(v); ok {
+		return n, CellNumber
+	}
+	return v, inferCellType(v)
+}
+
 // inferCellType determines the CellType from a Go value.
 func inferCellType(v any) CellType {
 	if v == nil {
@@ -199,7 +539,7 @@ func inferCellType(v any) CellType {
 		return CellBoolean
 	case int, int8, int16, int32, int64,
 		uint, uint8, uint16, uint32, uint64,
-		float32, float64:
+		float32, float64, numberFormatter, DecimalValue:
 		return CellNumber
 	case string:
 		return CellString
@@ -214,6 +554,16 @@ func (c *Context) setRunVar(name string, value any) {
 	c.invalidateCache()
 }
 
+// setPosition binds the built-in variables describing the output cell being
+// written: _row (1-based), _col (0-based), _sheet and _target.
+func (c *Context) setPosition(target CellRef) {
+	c.runVars["_row"] = target.Row + 1
+	c.runVars["_col"] = target.Col
+	c.runVars["_sheet"] = target.Sheet
+	c.runVars["_target"] = target
+	c.invalidateCache()
+}
+
 // removeRunVar removes a run variable.
 func (c *Context) removeRunVar(name string) {
 	delete(c.runVars, name)
@@ -222,6 +572,9 @@ func (c *Context) removeRunVar(name string) {
 
 // RunVar manages scoped loop variables with automatic save/restore.
 // Use with defer: rv := NewRunVar(ctx, "e"); defer rv.Close()
+//
+// RunVar mutates ctx in place and is kept for compatibility; prefer
+// ctx.WithVar, which leaves ctx unchanged.
 type RunVar struct {
 	ctx      *Context
 	varName  string
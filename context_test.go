@@ -1,6 +1,7 @@
 package xlfill
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -227,3 +228,58 @@ func TestContext_EvaluateCellValue_RunVarVisible(t *testing.T) {
 
 	rv.Close()
 }
+
+func TestContext_WithVar_ChildScope(t *testing.T) {
+	ctx := NewContext(map[string]any{"x": 10})
+	ctx.setRunVar("e", "outer")
+
+	child := ctx.WithVar("e", "inner")
+	assert.Equal(t, "inner", child.GetVar("e"))
+	assert.Equal(t, 10, child.GetVar("x"))
+	assert.Equal(t, "outer", ctx.GetVar("e"), "the parent is unchanged")
+
+	grandchild := child.WithVars(map[string]any{"idx": 2})
+	assert.Equal(t, "inner", grandchild.GetVar("e"))
+	assert.True(t, grandchild.ContainsVar("idx"))
+	assert.False(t, child.ContainsVar("idx"))
+	result, err := grandchild.Evaluate(`e + ":" + string(idx) + ":" + string(x)`)
+	require.NoError(t, err)
+	assert.Equal(t, "inner:2:10", result)
+
+	// RunVar on a child restores the enclosing scope's value on Close
+	rv := NewRunVar(grandchild, "e")
+	rv.Set("temp")
+	assert.Equal(t, "temp", grandchild.GetVar("e"))
+	rv.Close()
+	assert.Equal(t, "inner", grandchild.GetVar("e"))
+}
+
+func TestContext_WithVar_ConcurrentChildren(t *testing.T) {
+	ctx := NewContext(map[string]any{"base": 100})
+	results := make([]any, 50)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child := ctx.WithVar("i", i)
+			child.setRunVar("_row", i)
+			results[i], _ = child.Evaluate("base + i + _row")
+		}(i)
+	}
+	wg.Wait()
+	for i, r := range results {
+		assert.Equal(t, 100+2*i, r)
+	}
+}
+
+func TestContext_SharedFillState(t *testing.T) {
+	ctx := NewContext(nil)
+	child := ctx.WithVar("e", 1)
+	child.addGeneratedSheet("Sales")
+	assert.Equal(t, []string{"Sales"}, ctx.GeneratedSheets())
+
+	assert.True(t, child.markDisposed("template"))
+	assert.False(t, ctx.markDisposed("template"))
+	assert.True(t, ctx.isDisposed("template"))
+}
@@ -771,8 +771,33 @@ func TestClearTemplateCells_NoOp(t *testing.T) {
 	area := NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 1}, tx)
 	ctx := NewContext(nil)
 
-	// Should not panic
-	area.clearTemplateCells(ctx)
+	// An output covering the template clears nothing
+	require.NoError(t, area.clearTemplateCells(ctx, area.StartCell, area.AreaSize))
+	v, err := f.GetCellValue(sheet, "A1")
+	require.NoError(t, err)
+	assert.Equal(t, "${expr}", v)
+}
+
+// clearFailTransformer fails every ClearCell.
+type clearFailTransformer struct{ Transformer }
+
+func (clearFailTransformer) ClearCell(ref CellRef) error {
+	return fmt.Errorf("cannot clear %s", ref)
+}
+
+func TestClearTemplateCells_ClearError(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${a}")
+	f.SetCellValue(sheet, "A2", "${b}")
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	area := NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 2}, clearFailTransformer{tx})
+	err = area.clearTemplateCells(NewContext(nil), area.StartCell, Size{Width: 1, Height: 1})
+	assert.ErrorContains(t, err, "clear template cell Sheet1!A2: cannot clear Sheet1!A2")
 }
 
 // =============================================================================
@@ -1947,7 +1972,7 @@ func TestProcessFormula_NoRefs(t *testing.T) {
 	area := NewArea(NewCellRef(sheet, 0, 0), Size{Width: 5, Height: 5}, tx)
 	cd := &CellData{Ref: NewCellRef(sheet, 0, 0), Formula: "123+456"}
 
-	result := fp.processFormula("123+456", cd, NewCellRef(sheet, 0, 0), tx, area)
+	result := fp.processFormula("123+456", nil, cd, NewCellRef(sheet, 0, 0), tx, area, nil)
 	assert.Equal(t, "123+456", result) // no cell refs → unchanged
 }
 
@@ -0,0 +1,183 @@
+package xlfill
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// DeleteRowIfCommand implements jx:deleteRowIf. Placed on the full row of a
+// jx:each body, it drops the rows an iteration generated when the condition
+// is true, instead of wrapping every cell in jx:if:
+//
+//	jx:deleteRowIf(condition="e.Amount == 0" lastCell="D2")
+//	jx:deleteRowIf(condition="_cells.D < 0" lastCell="D2")
+//
+// The rows are rendered first and the condition is evaluated once formulas
+// are processed, so besides the variables of the iteration it can read the
+// output: _cells maps the column letters of the first output row to their
+// values, with formulas calculated. The rows below move up and formulas over
+// the generated rows, such as SUM(D2), only cover the rows that were kept.
+type DeleteRowIfCommand struct {
+	Condition string // boolean expression to evaluate (e.g., "e.Amount == 0")
+	Area      *Area
+}
+
+func (c *DeleteRowIfCommand) Name() string { return "deleteRowIf" }
+func (c *DeleteRowIfCommand) Reset()       {}
+
+// newDeleteRowIfCommandFromAttrs creates a DeleteRowIfCommand from parsed attributes.
+func newDeleteRowIfCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &DeleteRowIfCommand{Condition: attrs["condition"]}
+	if cmd.Condition == "" {
+		return nil, fmt.Errorf("deleteRowIf command requires 'condition' attribute")
+	}
+	return cmd, nil
+}
+
+// ApplyAt renders the area and records its output rows with the variables of
+// the iteration, for deleteRows to drop when the condition holds.
+func (c *DeleteRowIfCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	if c.Area == nil {
+		return ZeroSize, nil
+	}
+	size, err := c.Area.ApplyAt(cellRef, ctx)
+	if err != nil || size.Width <= 0 || size.Height <= 0 {
+		return size, err
+	}
+	if ctx.state.usage != nil {
+		ctx.noteEvaluation(c.Condition)
+	}
+	ctx.addRowDeletion(rowDeletion{
+		rows:      outputRef(cellRef, size),
+		condition: c.Condition,
+		vars:      maps.Clone(ctx.ToMap()),
+	})
+	return size, nil
+}
+
+// rowDeletion is the output of one jx:deleteRowIf iteration.
+type rowDeletion struct {
+	rows      AreaRef
+	condition string
+	vars      map[string]any // variables of the iteration
+}
+
+// addRowDeletion records rows of jx:deleteRowIf output. Like addAnchor, the
+// record is queued when c defers writes.
+func (c *Context) addRowDeletion(d rowDeletion) {
+	c.run(func() error {
+		c.state.mu.Lock()
+		defer c.state.mu.Unlock()
+		c.state.rowDeletions = append(c.state.rowDeletions, d)
+		return nil
+	})
+}
+
+// deleteRows removes the output rows of jx:deleteRowIf whose condition holds,
+// reading the output of tx once its formulas are processed, and returns the
+// removed rows by sheet.
+func (c *Context) deleteRows(tx *ExcelizeTransformer) (map[string][]int, error) {
+	remove := map[string]map[int]bool{}
+	for _, d := range c.state.rowDeletions {
+		sheet := d.rows.First.Sheet
+		if !slices.Contains(tx.GetSheetNames(), sheet) {
+			continue // deleted after it was filled
+		}
+		cells, err := outputRow(tx.file, d.rows)
+		if err != nil {
+			return nil, err
+		}
+		vars := maps.Clone(d.vars)
+		vars["_cells"] = cells
+		del, err := c.evaluator.IsConditionTrue(d.condition, vars)
+		if err != nil {
+			return nil, fmt.Errorf("deleteRowIf at %s: evaluate condition %q: %w", d.rows.First, d.condition, err)
+		}
+		if !del {
+			continue
+		}
+		if remove[sheet] == nil {
+			remove[sheet] = map[int]bool{}
+		}
+		for row := d.rows.First.Row; row <= d.rows.Last.Row; row++ {
+			remove[sheet][row] = true
+		}
+	}
+
+	removed := map[string][]int{}
+	for sheet, rows := range remove {
+		for row := range rows {
+			removed[sheet] = append(removed[sheet], row)
+		}
+		sort.Ints(removed[sheet])
+		if err := tx.removeRows(sheet, removed[sheet]); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// outputRow returns the values of the first row of ref in the output by
+// column letter, with formulas calculated and numbers as float64.
+func outputRow(f *excelize.File, ref AreaRef) (map[string]any, error) {
+	raw := excelize.Options{RawCellValue: true}
+	cells := make(map[string]any, ref.Last.Col-ref.First.Col+1)
+	for col := ref.First.Col; col <= ref.Last.Col; col++ {
+		cell := NewCellRef(ref.First.Sheet, ref.First.Row, col)
+		name := cell.CellName()
+		formula, err := f.GetCellFormula(cell.Sheet, name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", cell, err)
+		}
+		var value string
+		if formula != "" {
+			value, err = f.CalcCellValue(cell.Sheet, name, raw)
+		} else {
+			value, err = f.GetCellValue(cell.Sheet, name, raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", cell, err)
+		}
+		cells[ColToName(col)] = cellValue(f, cell, value, formula != "")
+	}
+	return cells, nil
+}
+
+// cellValue converts the raw text of an output cell to a number or bool when
+// the cell holds one.
+func cellValue(f *excelize.File, cell CellRef, value string, formula bool) any {
+	typ, _ := f.GetCellType(cell.Sheet, cell.CellName())
+	switch {
+	case typ == excelize.CellTypeBool:
+		return value == "1" || value == "TRUE"
+	case typ == excelize.CellTypeNumber, typ == excelize.CellTypeUnset, formula:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+// shiftRemovedRows moves ref up by the rows of removed above it.
+func shiftRemovedRows(ref CellRef, removed []int) CellRef {
+	ref.Row -= sort.SearchInts(removed, ref.Row)
+	return ref
+}
+
+// trimRemovedRows returns the result of an area after the rows of removed,
+// sorted, were deleted from its sheet.
+func trimRemovedRows(r AreaResult, removed []int) AreaResult {
+	out, ok := r.Ref()
+	if !ok || len(removed) == 0 {
+		return r
+	}
+	inside := sort.SearchInts(removed, out.Last.Row+1) - sort.SearchInts(removed, out.First.Row)
+	r.Target = shiftRemovedRows(r.Target, removed)
+	r.Size.Height -= inside
+	return r
+}
@@ -0,0 +1,102 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestDeleteRowIfCommand(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Item")
+	f.SetCellValue("Sheet1", "B1", "Amount")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Amount}")
+	f.SetCellValue("Sheet1", "A3", "Total")
+	f.SetCellFormula("Sheet1", "B3", "SUM(B2)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="B2")` + "\n" +
+		`jx:deleteRowIf(condition="e.Amount == 0" lastCell="B2")`})
+	tmpl := filepath.Join(testdataDir(t), "deleterowif.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"items": []map[string]any{
+		{"Name": "Apples", "Amount": 3},
+		{"Name": "Pears", "Amount": 0},
+		{"Name": "Plums", "Amount": 5},
+		{"Name": "Figs", "Amount": 0},
+	}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		b, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		rows, err := out.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Item", "Amount"}, {"Apples", "3"}, {"Plums", "5"}, {"Total", ""}}, rows)
+		formula, _ := out.GetCellFormula("Sheet1", "B4")
+		assert.Equal(t, "SUM(B2:B3)", formula)
+		out.Close()
+	}
+
+	_, err := newDeleteRowIfCommandFromAttrs(map[string]string{})
+	assert.ErrorContains(t, err, "requires 'condition'")
+}
+
+func TestDeleteRowIfCommand_OutputRow(t *testing.T) {
+	// The condition reads the calculated net amount of the rendered row
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Item")
+	f.SetCellValue("Sheet1", "D1", "Net")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Quantity}")
+	f.SetCellValue("Sheet1", "C2", "${e.Price}")
+	f.SetCellFormula("Sheet1", "D2", "B2*C2")
+	f.SetCellValue("Sheet1", "A3", "Total")
+	f.SetCellFormula("Sheet1", "D3", "SUM(D2)")
+	f.SetCellValue("Sheet1", "A9", "Checked")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="D3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="D2")` + "\n" +
+		`jx:deleteRowIf(condition="_cells.D == 0 || _cells.A == 'Skip'" lastCell="D2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A9", Author: "someone", Text: "note"})
+	tmpl := filepath.Join(testdataDir(t), "deleterowif_output.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"items": []map[string]any{
+		{"Name": "Apples", "Quantity": 3, "Price": 2},
+		{"Name": "Pears", "Quantity": 0, "Price": 4},
+		{"Name": "Skip", "Quantity": 1, "Price": 1},
+		{"Name": "Plums", "Quantity": 5, "Price": 1},
+	}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		result, err := NewFiller(append(opts, WithTemplate(tmpl))...).FillWithResult(data)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(bytes.NewReader(result.Output))
+		require.NoError(t, err)
+
+		rows, err := out.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Item", "", "", "Net"}, {"Apples", "3", "2", ""}, {"Plums", "5", "1", ""}, {"Total", "", "", ""},
+			nil, nil, {"Checked"}}, rows)
+		for cell, want := range map[string]string{"D2": "B2*C2", "D3": "B3*C3", "D4": "SUM(D2:D3)"} {
+			formula, _ := out.GetCellFormula("Sheet1", cell)
+			assert.Equal(t, want, formula, cell)
+		}
+		comments, err := out.GetComments("Sheet1")
+		require.NoError(t, err)
+		require.Len(t, comments, 3)
+		assert.Equal(t, "A7", comments[2].Cell, "comments below move up with their rows")
+
+		assert.Equal(t, Size{Width: 4, Height: 4}, result.Areas[0].Size)
+		assert.Equal(t, []CellRef{NewCellRef("Sheet1", 1, 0), NewCellRef("Sheet1", 2, 0)},
+			result.TargetsOf(NewCellRef("Sheet1", 1, 0)))
+		out.Close()
+	}
+}
@@ -30,9 +30,16 @@ func (f *Filler) Describe() (string, error) {
 
 	var b strings.Builder
 	b.WriteString("Template: ")
-	if f.opts.templatePath != "" {
+	switch {
+	case f.opts.templateReader != nil:
+		b.WriteString("<reader>")
+	case f.opts.templateSource != nil:
+		b.WriteString("<source>")
+	case f.opts.templateFS != nil:
+		b.WriteString(f.opts.templateName)
+	case f.opts.templatePath != "":
 		b.WriteString(f.opts.templatePath)
-	} else {
+	default:
 		b.WriteString("<reader>")
 	}
 	b.WriteByte('\n')
@@ -104,11 +111,14 @@ func (f *Filler) describeArea(b *strings.Builder, area *Area, tx Transformer, in
 		fmt.Fprintf(b, "%s  Commands:\n", prefix)
 		for _, bind := range area.Bindings {
 			attrs := describeCommandAttrs(bind.Command)
+			if bind.RenderIf != "" {
+				attrs += fmt.Sprintf(" renderIf=%q", bind.RenderIf)
+			}
 			fmt.Fprintf(b, "%s    %s %s %s%s\n", prefix, bind.StartRef, bind.Command.Name(), bind.Size, attrs)
 
-			// Recurse into child area
-			if childArea := getCommandArea(bind.Command); childArea != nil {
-				f.describeArea(b, childArea, tx, indent+3)
+			// Recurse into child areas
+			for _, child := range childAreas(bind.Command) {
+				f.describeArea(b, child, tx, indent+3)
 			}
 		}
 	}
@@ -135,31 +145,98 @@ func describeCommandAttrs(cmd Command) string {
 		if c.VarIndex != "" {
 			parts = append(parts, fmt.Sprintf("varIndex=%q", c.VarIndex))
 		}
+		if c.RowIndex != "" {
+			parts = append(parts, fmt.Sprintf("rowIndex=%q", c.RowIndex))
+		}
+		if c.VarStatus != "" {
+			parts = append(parts, fmt.Sprintf("varStatus=%q", c.VarStatus))
+		}
 		if c.Direction != "" && c.Direction != "DOWN" {
 			parts = append(parts, fmt.Sprintf("direction=%q", c.Direction))
 		}
 		if c.Select != "" {
 			parts = append(parts, fmt.Sprintf("select=%q", c.Select))
 		}
+		if c.Distinct != "" {
+			parts = append(parts, fmt.Sprintf("distinct=%q", c.Distinct))
+		}
 		if c.OrderBy != "" {
 			parts = append(parts, fmt.Sprintf("orderBy=%q", c.OrderBy))
 		}
 		if c.GroupBy != "" {
 			parts = append(parts, fmt.Sprintf("groupBy=%q", c.GroupBy))
 		}
+		if c.GroupVar != "" {
+			parts = append(parts, fmt.Sprintf("groupVar=%q", c.GroupVar))
+		}
+		if c.ItemVar != "" {
+			parts = append(parts, fmt.Sprintf("itemVar=%q", c.ItemVar))
+		}
 		if c.MultiSheet != "" {
 			parts = append(parts, fmt.Sprintf("multiSheet=%q", c.MultiSheet))
 		}
+		if c.OddStyle != "" {
+			parts = append(parts, fmt.Sprintf("oddStyle=%q", c.OddStyle))
+		}
+		if c.EvenStyle != "" {
+			parts = append(parts, fmt.Sprintf("evenStyle=%q", c.EvenStyle))
+		}
+		if c.MergeBy != "" {
+			parts = append(parts, fmt.Sprintf("mergeBy=%q", c.MergeBy))
+		}
+		if len(c.Let) > 0 {
+			lets := make([]string, len(c.Let))
+			for i, let := range c.Let {
+				lets[i] = let.Name + "=" + let.Expression
+			}
+			parts = append(parts, fmt.Sprintf("let=%q", strings.Join(lets, "; ")))
+		}
+		if c.Outline {
+			parts = append(parts, fmt.Sprintf("outline=%q summaryRow=%q", "true", c.SummaryRow))
+		}
+		if c.Footer != nil {
+			parts = append(parts, fmt.Sprintf("footerArea=%q", c.Footer.SourceRef().String()))
+		}
+		if c.Gap > 0 {
+			parts = append(parts, fmt.Sprintf("gap=\"%d\"", c.Gap))
+		}
+		if c.EmptyAction == "CLEAR" {
+			parts = append(parts, fmt.Sprintf("emptyAction=%q", c.EmptyAction))
+		}
+		if c.OldSelectBehavior {
+			parts = append(parts, fmt.Sprintf("oldSelectBehavior=%q", "true"))
+		}
 	case *IfCommand:
 		parts = append(parts, fmt.Sprintf("condition=%q", c.Condition))
+		if c.ElseAction == "CLEAR" {
+			parts = append(parts, fmt.Sprintf("elseAction=%q", c.ElseAction))
+		}
 	case *GridCommand:
 		parts = append(parts, fmt.Sprintf("headers=%q", c.Headers))
 		parts = append(parts, fmt.Sprintf("data=%q", c.Data))
 		if c.Props != "" {
 			parts = append(parts, fmt.Sprintf("props=%q", c.Props))
 		}
+		if c.Direction == "RIGHT" {
+			parts = append(parts, fmt.Sprintf("direction=%q", c.Direction))
+		}
+		if c.HeaderStyle != "" {
+			parts = append(parts, fmt.Sprintf("headerStyle=%q", c.HeaderStyle))
+		}
+		if c.DataStyle != "" {
+			parts = append(parts, fmt.Sprintf("dataStyle=%q", c.DataStyle))
+		}
+		if c.HeaderCells != "" {
+			parts = append(parts, fmt.Sprintf("headerArea=%q", c.HeaderCells))
+		}
+		if c.BodyCells != "" {
+			parts = append(parts, fmt.Sprintf("bodyArea=%q", c.BodyCells))
+		}
 	case *ImageCommand:
 		parts = append(parts, fmt.Sprintf("src=%q", c.Src))
+		if c.Placeholder != "" {
+			parts = append(parts, fmt.Sprintf("placeholder=%q", c.Placeholder))
+		}
 		if c.ImageType != "" {
 			parts = append(parts, fmt.Sprintf("imageType=%q", c.ImageType))
 		}
@@ -172,8 +249,60 @@ func describeCommandAttrs(cmd Command) string {
 		}
 	case *UpdateCellCommand:
 		parts = append(parts, fmt.Sprintf("updater=%q", c.Updater))
+	case *PivotCommand:
+		parts = append(parts, fmt.Sprintf("items=%q", c.Items))
+		parts = append(parts, fmt.Sprintf("rowKey=%q", c.RowKey))
+		parts = append(parts, fmt.Sprintf("colKey=%q", c.ColKey))
+		parts = append(parts, fmt.Sprintf("value=%q", c.Value))
+	case *TocCommand:
+		if c.IncludeHidden {
+			parts = append(parts, `includeHidden="true"`)
+		}
+	case *AnchorCommand:
+		parts = append(parts, fmt.Sprintf("name=%q", c.Anchor))
+	case *SheetPropsCommand:
+		if c.TabColor != "" {
+			parts = append(parts, fmt.Sprintf("tabColor=%q", c.TabColor))
+		}
+		if c.Hidden != "" {
+			parts = append(parts, fmt.Sprintf("hidden=%q", c.Hidden))
+		}
+	case *DefineCommand:
+		parts = append(parts, fmt.Sprintf("name=%q", c.MacroName))
+	case *CallCommand:
+		parts = append(parts, fmt.Sprintf("name=%q", c.MacroName))
+		if len(c.With) > 0 {
+			with := make([]string, len(c.With))
+			for i, w := range c.With {
+				with[i] = w.Name + "=" + w.Expression
+			}
+			parts = append(parts, fmt.Sprintf("with=%q", strings.Join(with, "; ")))
+		}
+	case *IncludeCommand:
+		parts = append(parts, fmt.Sprintf("template=%q", c.Template))
+		parts = append(parts, fmt.Sprintf("area=%q", c.Source))
+	case *DeleteRowIfCommand:
+		parts = append(parts, fmt.Sprintf("condition=%q", c.Condition))
+	case *HighlightCommand:
+		parts = append(parts, fmt.Sprintf("condition=%q", c.Condition))
+		parts = append(parts, fmt.Sprintf("style=%q", c.Style))
+		if c.ApplyTo != "CELLS" {
+			parts = append(parts, fmt.Sprintf("applyTo=%q", c.ApplyTo))
+		}
+	case *MaskCommand:
+		parts = append(parts, fmt.Sprintf("fields=%q", strings.Join(c.Fields, ",")))
+	case *SortStateCommand:
+		parts = append(parts, fmt.Sprintf("column=%q", c.Column))
+		if c.Descending {
+			parts = append(parts, `order="descending"`)
+		}
 	case *AutoRowHeightCommand:
-		// no extra attributes
+		if c.LineHeight != 0 && c.LineHeight != 1 {
+			parts = append(parts, fmt.Sprintf("lineHeight=%g", c.LineHeight))
+		}
+		if c.MaxHeight != 0 && c.MaxHeight != maxRowHeight {
+			parts = append(parts, fmt.Sprintf("maxHeight=%g", c.MaxHeight))
+		}
 	}
 	if len(parts) == 0 {
 		return ""
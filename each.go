@@ -3,7 +3,9 @@ package xlfill
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -12,15 +14,64 @@ type EachCommand struct {
 	Items     string // expression for collection (e.g., "employees")
 	Var       string // loop variable name (e.g., "e")
 	VarIndex  string // optional index variable name (e.g., "idx")
-	Direction string // "DOWN" (default) or "RIGHT"
+	VarStatus string // optional LoopStatus variable name (e.g., "status")
+	RowIndex  string // optional row index variable name for DOWN_RIGHT (e.g., "r")
+	Direction string // "DOWN" (default), "RIGHT" or "DOWN_RIGHT"
 	Area      *Area  // the template area to repeat for each item
 
 	// Advanced (Phase 10)
 	Select     string // filter expression
+	Distinct   string // key expression; only the first item with each key is kept
 	GroupBy    string // grouping property
 	GroupOrder string // "ASC" or "DESC"
 	OrderBy    string // sort specification
-	MultiSheet string // sheet names variable
+	MultiSheet string // sheet names variable, per-item name expression or @auto
+
+	// Names for groupBy: GroupVar binds each GroupData in the area (default:
+	// the loop variable) and ItemVar the group's first item, so that
+	// var="e" groupVar="g" itemVar="e" reads ${g.Key} and ${e.Name}
+	GroupVar string
+	ItemVar  string
+
+	// OldSelectBehavior applies select to the groups of groupBy, with the
+	// loop variable bound to each GroupData, as JXLS did before 2.12; by
+	// default select filters the items before they are grouped
+	OldSelectBehavior bool
+
+	// Alternate styles: a name registered with WithStyles or a template cell
+	// reference (e.g. "Styles!A1"; "E1" refers to the command's sheet)
+	OddStyle  string // style of the 1st, 3rd, ... iteration
+	EvenStyle string // style of the 2nd, 4th, ... iteration
+
+	// Row outline for groupBy: the rows of each group other than its summary
+	// row become collapsible detail rows, one level deeper per nested group
+	Outline    bool   // outline="true"
+	SummaryRow string // "ABOVE" (default, the group's first row) or "BELOW" (its last row)
+
+	// MergeBy is an expression (e.g. "e.Department"); the cells rendering it are
+	// merged down over consecutive iterations with the same value
+	MergeBy string
+
+	// Let holds values computed once per item and bound as variables
+	Let []LetBinding
+
+	// Gap is the number of blank rows (DOWN) or columns (RIGHT) left between
+	// the outputs of consecutive items
+	Gap int
+
+	// EmptyAction is what the command leaves without items: "REMOVE"
+	// (default) outputs nothing, so the cells below move up; "CLEAR" keeps
+	// the space of its area, blank but formatted
+	EmptyAction string
+
+	// Footer is rendered below the items of every group with groupBy, or once
+	// below all items otherwise (footerArea="A5:C5")
+	Footer *Area
+
+	// sheetAreas are the root areas of a multisheet template sheet, with Area
+	// in place of the one holding the command, filled on every generated
+	// sheet. Set by the Filler when the sheet has more than one root area.
+	sheetAreas []*Area
 }
 
 func (c *EachCommand) Name() string { return "each" }
@@ -32,12 +83,26 @@ func newEachCommandFromAttrs(attrs map[string]string) (Command, error) {
 		Items:      attrs["items"],
 		Var:        attrs["var"],
 		VarIndex:   attrs["varIndex"],
+		VarStatus:  attrs["varStatus"],
+		RowIndex:   attrs["rowIndex"],
 		Direction:  strings.ToUpper(attrs["direction"]),
 		Select:     attrs["select"],
+		Distinct:   attrs["distinct"],
 		GroupBy:    attrs["groupBy"],
 		GroupOrder: attrs["groupOrder"],
 		OrderBy:    attrs["orderBy"],
 		MultiSheet: attrs["multisheet"],
+		GroupVar:   attrs["groupVar"],
+		ItemVar:    attrs["itemVar"],
+		OddStyle:   attrs["oddStyle"],
+		EvenStyle:  attrs["evenStyle"],
+		Outline:    strings.EqualFold(attrs["outline"], "true"),
+		SummaryRow: strings.ToUpper(attrs["summaryRow"]),
+		MergeBy:    attrs["mergeBy"],
+
+		OldSelectBehavior: strings.EqualFold(attrs["oldSelectBehavior"], "true"),
+
+		EmptyAction: strings.ToUpper(attrs["emptyAction"]),
 	}
 	if cmd.Items == "" {
 		return nil, fmt.Errorf("each command requires 'items' attribute")
@@ -48,6 +113,55 @@ func newEachCommandFromAttrs(attrs map[string]string) (Command, error) {
 	if cmd.Direction == "" {
 		cmd.Direction = "DOWN"
 	}
+	if (cmd.GroupVar != "" || cmd.ItemVar != "") && cmd.GroupBy == "" {
+		return nil, fmt.Errorf("each command: groupVar and itemVar require 'groupBy' attribute")
+	}
+	if cmd.Outline {
+		if cmd.GroupBy == "" {
+			return nil, fmt.Errorf("each command: outline requires 'groupBy' attribute")
+		}
+		if cmd.Direction != "DOWN" || cmd.MultiSheet != "" {
+			return nil, fmt.Errorf("each command: outline requires direction DOWN without multisheet")
+		}
+		if cmd.SummaryRow == "" {
+			cmd.SummaryRow = "ABOVE"
+		}
+		if cmd.SummaryRow != "ABOVE" && cmd.SummaryRow != "BELOW" {
+			return nil, fmt.Errorf("each command: invalid summaryRow %q (expected ABOVE or BELOW)", attrs["summaryRow"])
+		}
+	}
+	switch cmd.EmptyAction {
+	case "":
+		cmd.EmptyAction = "REMOVE"
+	case "REMOVE":
+	case "CLEAR":
+		if cmd.MultiSheet != "" {
+			return nil, fmt.Errorf("each command: emptyAction CLEAR requires no multisheet")
+		}
+	default:
+		return nil, fmt.Errorf("each command: invalid emptyAction %q (expected REMOVE or CLEAR)", attrs["emptyAction"])
+	}
+	if cmd.MergeBy != "" && (cmd.Direction != "DOWN" || cmd.MultiSheet != "") {
+		return nil, fmt.Errorf("each command: mergeBy requires direction DOWN without multisheet")
+	}
+	if gap := attrs["gap"]; gap != "" {
+		n, err := strconv.Atoi(gap)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("each command: invalid gap %q (expected a number of rows or columns)", gap)
+		}
+		if n > 0 && ((cmd.Direction != "DOWN" && cmd.Direction != "RIGHT") || cmd.MultiSheet != "") {
+			return nil, fmt.Errorf("each command: gap requires direction DOWN or RIGHT without multisheet")
+		}
+		cmd.Gap = n
+	}
+	if attrs["footerArea"] != "" && (cmd.Direction != "DOWN" || cmd.MultiSheet != "" || cmd.Outline) {
+		return nil, fmt.Errorf("each command: footerArea requires direction DOWN without multisheet or outline")
+	}
+	lets, err := parseLets(attrs["let"])
+	if err != nil {
+		return nil, fmt.Errorf("each command: %w", err)
+	}
+	cmd.Let = lets
 	return cmd, nil
 }
 
@@ -59,6 +173,16 @@ func (c *EachCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transfo
 		return ZeroSize, fmt.Errorf("evaluate items %q: %w", c.Items, err)
 	}
 
+	// Stream iterators item by item when no option needs the whole collection
+	if it, ok := itemsVal.(Iterator); ok {
+		if err := ctx.readIterator(it); err != nil {
+			return ZeroSize, fmt.Errorf("items %q: %w", c.Items, err)
+		}
+		if c.canStream() {
+			return c.applyStream(cellRef, ctx, transformer, it)
+		}
+	}
+
 	// Convert to iterable slice
 	items, err := toSlice(itemsVal)
 	if err != nil {
@@ -66,17 +190,31 @@ func (c *EachCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transfo
 	}
 
 	if len(items) == 0 {
-		return ZeroSize, nil
+		return c.applyEmpty(cellRef, ctx)
+	}
+
+	selectFields, err := c.checkLoopPaths(ctx, items)
+	if err != nil {
+		return ZeroSize, err
 	}
 
-	// Apply select filter
-	if c.Select != "" {
-		items, err = c.filterItems(items, ctx)
+	// Apply select filter, to the items unless it selects groups
+	selectsGroups := c.selectsGroups(ctx)
+	if c.Select != "" && !selectsGroups {
+		items, err = c.filterItems(items, ctx, selectFields)
 		if err != nil {
 			return ZeroSize, err
 		}
 		if len(items) == 0 {
-			return ZeroSize, nil
+			return c.applyEmpty(cellRef, ctx)
+		}
+	}
+
+	// Apply distinct
+	if c.Distinct != "" {
+		items, err = c.distinctItems(items, ctx)
+		if err != nil {
+			return ZeroSize, err
 		}
 	}
 
@@ -84,6 +222,15 @@ func (c *EachCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transfo
 	if c.GroupBy != "" {
 		items = c.groupItems(items)
 	}
+	if c.Select != "" && selectsGroups {
+		items, err = c.filterItems(items, ctx, selectFields)
+		if err != nil {
+			return ZeroSize, err
+		}
+		if len(items) == 0 {
+			return c.applyEmpty(cellRef, ctx)
+		}
+	}
 
 	// Apply orderBy
 	if c.OrderBy != "" {
@@ -102,114 +249,681 @@ func (c *EachCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transfo
 		return c.applyMultiSheet(cellRef, ctx, transformer, items)
 	}
 
+	// Matrix mode: each item is a row of cells
+	if c.Direction == "DOWN_RIGHT" {
+		return c.applyMatrix(cellRef, ctx, transformer, items)
+	}
+
+	if c.Outline {
+		if err := setOutlineSummaryBelow(transformer, cellRef.Sheet, c.SummaryRow == "BELOW"); err != nil {
+			return ZeroSize, fmt.Errorf("set outline summary position: %w", err)
+		}
+	}
+
 	// Iterate
-	isRight := c.Direction == "RIGHT"
 	totalSize := ZeroSize
-
+	merge, err := c.newKeyMerge(ctx, transformer)
+	if err != nil {
+		return ZeroSize, err
+	}
+	if c.Footer != nil && c.GroupBy != "" {
+		if err := c.applyGroups(cellRef, ctx, transformer, items, &totalSize, merge); err != nil {
+			return ZeroSize, err
+		}
+		return totalSize, nil
+	}
 	for i, item := range items {
-		// Set loop variable
-		var rv *RunVar
-		if c.VarIndex != "" {
-			rv = NewRunVarWithIndex(ctx, c.Var, c.VarIndex)
-			rv.SetWithIndex(item, i)
-		} else {
-			rv = NewRunVar(ctx, c.Var)
-			rv.Set(item)
+		if err := c.applyItem(cellRef, ctx, transformer, item, i, len(items), c.Gap, &totalSize, merge); err != nil {
+			return ZeroSize, err
+		}
+	}
+	if err := merge.flush(); err != nil {
+		return ZeroSize, err
+	}
+	if c.Footer != nil {
+		if err := c.applyFooter(cellRef, ctx, 0, &totalSize); err != nil {
+			return ZeroSize, err
 		}
+	}
 
-		// Calculate target cell for this iteration
-		var iterTarget CellRef
-		if isRight {
-			iterTarget = NewCellRef(cellRef.Sheet, cellRef.Row, cellRef.Col+totalSize.Width)
-		} else {
-			iterTarget = NewCellRef(cellRef.Sheet, cellRef.Row+totalSize.Height, cellRef.Col)
+	return totalSize, nil
+}
+
+// applyItem applies the area for a single item, placing it gap rows or columns
+// after the output accumulated so far in totalSize and growing totalSize
+// accordingly. The gap is only left after earlier output.
+func (c *EachCommand) applyItem(cellRef CellRef, ctx *Context, transformer Transformer, item any, i, count, gap int, totalSize *Size, merge *keyMerge) error {
+	isRight := c.Direction == "RIGHT"
+
+	// Bind loop variables in a child scope
+	iterCtx, err := c.iterationContext(ctx, item, i, count)
+	if err != nil {
+		return fmt.Errorf("each iteration %d: %w", i, err)
+	}
+
+	// Calculate target cell for this iteration, leaving the gap after the
+	// output of the previous items
+	var iterTarget CellRef
+	if isRight {
+		if totalSize.Width == 0 {
+			gap = 0
 		}
+		iterTarget = NewCellRef(cellRef.Sheet, cellRef.Row, cellRef.Col+totalSize.Width+gap)
+	} else {
+		if totalSize.Height == 0 {
+			gap = 0
+		}
+		iterTarget = NewCellRef(cellRef.Sheet, cellRef.Row+totalSize.Height+gap, cellRef.Col)
+	}
 
-		// Apply area at target
-		iterSize, err := c.Area.ApplyAt(iterTarget, ctx)
-		rv.Close()
-		if err != nil {
-			return ZeroSize, fmt.Errorf("each iteration %d: %w", i, err)
+	if err := c.clearGap(transformer, iterTarget, gap); err != nil {
+		return fmt.Errorf("each iteration %d: %w", i, err)
+	}
+
+	// Apply area at target
+	iterSize, err := c.Area.ApplyAt(iterTarget, iterCtx)
+	if err != nil {
+		return fmt.Errorf("each iteration %d: %w", i, err)
+	}
+	if err := ctx.checkExtent(iterTarget, iterSize); err != nil {
+		return fmt.Errorf("each iteration %d: %w", i, err)
+	}
+	if err := c.applyStripe(transformer, iterTarget, iterSize, i); err != nil {
+		return err
+	}
+	if err := c.applyOutline(transformer, iterCtx, iterTarget, iterSize); err != nil {
+		return err
+	}
+	if err := merge.add(iterCtx, iterTarget, iterSize); err != nil {
+		return fmt.Errorf("each iteration %d: %w", i, err)
+	}
+
+	// Accumulate size; the gap counts only once an item follows it
+	if isRight {
+		if iterSize.Width > 0 {
+			totalSize.Width += gap + iterSize.Width
+		}
+		if iterSize.Height > totalSize.Height {
+			totalSize.Height = iterSize.Height
+		}
+	} else {
+		if iterSize.Height > 0 {
+			totalSize.Height += gap + iterSize.Height
+		}
+		if iterSize.Width > totalSize.Width {
+			totalSize.Width = iterSize.Width
 		}
+	}
+	return nil
+}
 
-		// Accumulate size
-		if isRight {
-			totalSize.Width += iterSize.Width
-			if iterSize.Height > totalSize.Height {
-				totalSize.Height = iterSize.Height
+// clearGap blanks the gap rows above target, or the gap columns left of it for
+// RIGHT, so that template cells the expansion moved away do not show through.
+func (c *EachCommand) clearGap(transformer Transformer, target CellRef, gap int) error {
+	if gap == 0 {
+		return nil
+	}
+	rows, cols := gap, c.Area.AreaSize.Width
+	first := NewCellRef(target.Sheet, target.Row-gap, target.Col)
+	if c.Direction == "RIGHT" {
+		rows, cols = c.Area.AreaSize.Height, gap
+		first = NewCellRef(target.Sheet, target.Row, target.Col-gap)
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			ref := NewCellRef(first.Sheet, first.Row+row, first.Col+col)
+			if err := transformer.ClearCell(ref); err != nil {
+				return fmt.Errorf("clear gap cell %s: %w", ref, err)
 			}
-		} else {
-			totalSize.Height += iterSize.Height
-			if iterSize.Width > totalSize.Width {
-				totalSize.Width = iterSize.Width
+		}
+	}
+	return nil
+}
+
+// applyOutline makes the rows of a group's output other than its summary row
+// detail rows at the group's outline level. Nested groups are applied first and
+// keep their deeper level.
+func (c *EachCommand) applyOutline(transformer Transformer, iterCtx *Context, target CellRef, size Size) error {
+	if !c.Outline || size.Height < 2 {
+		return nil
+	}
+	first, last := target.Row+1, target.Row+size.Height-1
+	if c.SummaryRow == "BELOW" {
+		first, last = target.Row, target.Row+size.Height-2
+	}
+	level := uint8(min(iterCtx.outlineLevel, 7)) // Excel supports 7 levels
+	for row := first; row <= last; row++ {
+		if err := setRowOutlineLevel(transformer, target.Sheet, row, level); err != nil {
+			return fmt.Errorf("set outline level of row %d: %w", row+1, err)
+		}
+	}
+	return nil
+}
+
+// outliner is implemented by transformers that can group rows into outlines.
+type outliner interface {
+	// SetRowOutlineLevel sets a row's outline (grouping) level.
+	SetRowOutlineLevel(sheet string, row int, level uint8) error
+	// SetOutlineSummaryBelow sets whether a sheet's outline summary rows are below their details.
+	SetOutlineSummaryBelow(sheet string, below bool) error
+}
+
+// setRowOutlineLevel sets a row's outline level.
+func setRowOutlineLevel(tx Transformer, sheet string, row int, level uint8) error {
+	if _, ok := unwrapTransformer(tx).(outliner); !ok {
+		return fmt.Errorf("transformer %T cannot group rows into outlines", unwrapTransformer(tx))
+	}
+	return tx.(outliner).SetRowOutlineLevel(sheet, row, level)
+}
+
+// setOutlineSummaryBelow sets whether a sheet's outline summary rows are below their details.
+func setOutlineSummaryBelow(tx Transformer, sheet string, below bool) error {
+	if _, ok := unwrapTransformer(tx).(outliner); !ok {
+		return fmt.Errorf("transformer %T cannot group rows into outlines", unwrapTransformer(tx))
+	}
+	return tx.(outliner).SetOutlineSummaryBelow(sheet, below)
+}
+
+// keyMerge merges the mergeBy key cells of consecutive iterations that render
+// the same key into one cell spanning their rows.
+type keyMerge struct {
+	cmd         *EachCommand
+	transformer Transformer
+	cells       []CellRef // key cells, relative to the area's start
+
+	key   string  // key of the current run
+	first CellRef // target of the run's first iteration
+	last  int     // last output row of the run
+	open  bool
+}
+
+// newKeyMerge returns the merger for the command, or nil without mergeBy. The
+// key cells are the cells of the area whose whole content is ${mergeBy}.
+func (c *EachCommand) newKeyMerge(ctx *Context, transformer Transformer) (*keyMerge, error) {
+	if c.MergeBy == "" || c.Area == nil || c.Area.Transformer == nil {
+		return nil, nil
+	}
+	m := &keyMerge{cmd: c, transformer: transformer}
+	start := c.Area.StartCell
+	for row := 0; row < c.Area.AreaSize.Height; row++ {
+		for col := 0; col < c.Area.AreaSize.Width; col++ {
+			cd := c.Area.Transformer.GetCellData(NewCellRef(start.Sheet, start.Row+row, start.Col+col))
+			if cd == nil {
+				continue
+			}
+			s, ok := cd.Value.(string)
+			if !ok {
+				continue
+			}
+			if e, ok := ExtractSingleExpression(s, ctx.notationBegin, ctx.notationEnd); ok && strings.TrimSpace(e) == strings.TrimSpace(c.MergeBy) {
+				m.cells = append(m.cells, NewCellRef("", row, col))
 			}
 		}
 	}
+	if len(m.cells) == 0 {
+		return nil, fmt.Errorf("each command: mergeBy %q matches no cell of the area", c.MergeBy)
+	}
+	return m, nil
+}
 
-	return totalSize, nil
+// add records an iteration's output, closing the current run when its key differs.
+func (m *keyMerge) add(iterCtx *Context, target CellRef, size Size) error {
+	if m == nil || size.Height <= 0 {
+		return nil
+	}
+	val, err := iterCtx.Evaluate(m.cmd.MergeBy)
+	if err != nil {
+		return fmt.Errorf("evaluate mergeBy %q: %w", m.cmd.MergeBy, err)
+	}
+	key := fmt.Sprintf("%v", val)
+	if m.open && key == m.key {
+		m.last = target.Row + size.Height - 1
+		return nil
+	}
+	if err := m.flush(); err != nil {
+		return err
+	}
+	m.key, m.first, m.last, m.open = key, target, target.Row+size.Height-1, true
+	return nil
 }
 
-// applyMultiSheet processes each item on a separate sheet.
-// The multisheet attribute holds the name of a context variable containing sheet names.
-func (c *EachCommand) applyMultiSheet(cellRef CellRef, ctx *Context, transformer Transformer, items []any) (Size, error) {
-	// Evaluate multisheet to get sheet names
-	sheetNamesVal, err := ctx.Evaluate(c.MultiSheet)
+// flush merges the key cells of the current run down to its last row. The
+// first copy keeps its value and style; the copies below it are cleared.
+func (m *keyMerge) flush() error {
+	if m == nil || !m.open {
+		return nil
+	}
+	m.open = false
+	for _, cell := range m.cells {
+		top := NewCellRef(m.first.Sheet, m.first.Row+cell.Row, m.first.Col+cell.Col)
+		if m.last <= top.Row {
+			continue
+		}
+		for row := top.Row + 1; row <= m.last; row++ {
+			if err := m.transformer.ClearCell(NewCellRef(top.Sheet, row, top.Col)); err != nil {
+				return err
+			}
+		}
+		bottom := NewCellRef(top.Sheet, m.last, top.Col)
+		if err := m.transformer.MergeCells(top.Sheet, top.CellName(), bottom.CellName()); err != nil {
+			return fmt.Errorf("merge cells %s:%s: %w", top.CellName(), bottom.CellName(), err)
+		}
+	}
+	return nil
+}
+
+// canStream reports whether items can be consumed one at a time. Filtering,
+// de-duplication, grouping, sorting, loop status and the multisheet/matrix modes need the whole collection.
+func (c *EachCommand) canStream() bool {
+	return c.Select == "" && c.Distinct == "" && c.GroupBy == "" && c.OrderBy == "" && c.VarStatus == "" &&
+		c.MultiSheet == "" && c.Direction != "DOWN_RIGHT"
+}
+
+// applyStream applies the area for each item produced by an Iterator without
+// materializing the whole collection.
+func (c *EachCommand) applyStream(cellRef CellRef, ctx *Context, transformer Transformer, it Iterator) (Size, error) {
+	if c.Area == nil {
+		return ZeroSize, fmt.Errorf("each command has no area")
+	}
+	totalSize := ZeroSize
+	merge, err := c.newKeyMerge(ctx, transformer)
 	if err != nil {
-		return ZeroSize, fmt.Errorf("evaluate multisheet %q: %w", c.MultiSheet, err)
+		return ZeroSize, err
+	}
+	n := 0
+	for ; it.Next(); n++ {
+		if err := c.applyItem(cellRef, ctx, transformer, it.Value(), n, -1, c.Gap, &totalSize, merge); err != nil {
+			return ZeroSize, err
+		}
 	}
-	sheetNames, err := toStringSlice(sheetNamesVal)
+	if err := it.Err(); err != nil {
+		return ZeroSize, fmt.Errorf("iterate items %q: %w", c.Items, err)
+	}
+	if err := merge.flush(); err != nil {
+		return ZeroSize, err
+	}
+	if n == 0 {
+		return c.applyEmpty(cellRef, ctx)
+	}
+	if c.Footer != nil {
+		if err := c.applyFooter(cellRef, ctx, 0, &totalSize); err != nil {
+			return ZeroSize, err
+		}
+	}
+	return totalSize, nil
+}
+
+// applyEmpty outputs the command without items: nothing or, with
+// emptyAction="CLEAR", its area once with every cell blank but formatted, so
+// the cells below stay in place and formulas over the area refer to blanks.
+func (c *EachCommand) applyEmpty(cellRef CellRef, ctx *Context) (Size, error) {
+	if c.EmptyAction != "CLEAR" || c.Area == nil {
+		return ZeroSize, nil
+	}
+	blankCtx := ctx.WithVars(nil)
+	blankCtx.blank = true
+	return c.Area.transformStaticArea(cellRef, blankCtx)
+}
+
+// applyMatrix expands the area in two dimensions: items is a slice of rows laid
+// out downwards, and the cells of each row are laid out to the right. The loop
+// variable holds the cell value, varIndex the column index and rowIndex the row index.
+// Alternate styles apply to whole rows.
+func (c *EachCommand) applyMatrix(cellRef CellRef, ctx *Context, transformer Transformer, rows []any) (Size, error) {
+	totalSize := ZeroSize
+	for r, row := range rows {
+		cells, err := toSlice(row)
+		if err != nil {
+			return ZeroSize, fmt.Errorf("matrix row %d is not iterable: %w", r, err)
+		}
+		rowCtx := ctx
+		if c.RowIndex != "" {
+			rowCtx = ctx.WithVar(c.RowIndex, r)
+		}
+
+		rowSize := ZeroSize
+		for i, cell := range cells {
+			iterCtx, err := c.iterationContext(rowCtx, cell, i, len(cells))
+			if err != nil {
+				return ZeroSize, fmt.Errorf("each matrix cell (%d,%d): %w", r, i, err)
+			}
+			iterTarget := NewCellRef(cellRef.Sheet, cellRef.Row+totalSize.Height, cellRef.Col+rowSize.Width)
+			iterSize, err := c.Area.ApplyAt(iterTarget, iterCtx)
+			if err != nil {
+				return ZeroSize, fmt.Errorf("each matrix cell (%d,%d): %w", r, i, err)
+			}
+			if err := ctx.checkExtent(iterTarget, iterSize); err != nil {
+				return ZeroSize, fmt.Errorf("each matrix cell (%d,%d): %w", r, i, err)
+			}
+			rowSize.Width += iterSize.Width
+			if iterSize.Height > rowSize.Height {
+				rowSize.Height = iterSize.Height
+			}
+		}
+		rowTarget := NewCellRef(cellRef.Sheet, cellRef.Row+totalSize.Height, cellRef.Col)
+		if err := c.applyStripe(transformer, rowTarget, rowSize, r); err != nil {
+			return ZeroSize, err
+		}
+
+		totalSize.Height += rowSize.Height
+		if rowSize.Width > totalSize.Width {
+			totalSize.Width = rowSize.Width
+		}
+	}
+	return totalSize, nil
+}
+
+// applyStripe lays the odd or even style over the output of iteration i.
+func (c *EachCommand) applyStripe(transformer Transformer, target CellRef, size Size, i int) error {
+	style := c.OddStyle
+	if i%2 == 1 {
+		style = c.EvenStyle
+	}
+	if style == "" {
+		return nil
+	}
+	style = qualifyStyleRef(style, c.Area.StartCell.Sheet)
+	for row := 0; row < size.Height; row++ {
+		for col := 0; col < size.Width; col++ {
+			ref := NewCellRef(target.Sheet, target.Row+row, target.Col+col)
+			if err := applyNamedStyle(transformer, ref, style); err != nil {
+				return fmt.Errorf("each iteration %d: style %s: %w", i, ref, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bareCellRef matches a cell reference without a sheet, such as "E1" or "$E$1".
+var bareCellRef = regexp.MustCompile(`^\$?[A-Z]{1,3}\$?[1-9][0-9]*$`)
+
+// qualifyStyleRef prefixes a style given as a bare cell reference such as "E1"
+// with sheet. Other style names are returned unchanged.
+func qualifyStyleRef(style, sheet string) string {
+	if !bareCellRef.MatchString(style) {
+		return style
+	}
+	return sheet + "!" + style
+}
+
+// LoopStatus describes the current iteration of a jx:each loop.
+// It is exposed under the varStatus name: ${status.Index}, ${status.Last}.
+type LoopStatus struct {
+	Index int  // 0-based iteration index
+	Count int  // total number of iterations
+	First bool // true on the first iteration
+	Last  bool // true on the last iteration
+}
+
+// sheetVar names the SheetInfo of the current sheet inside a multisheet jx:each.
+const sheetVar = "sheet"
+
+// SheetInfo describes the sheet a multisheet jx:each is filling. It is exposed
+// as ${sheet.Name}, ${sheet.Index} and ${sheet.Count}.
+type SheetInfo struct {
+	Name  string // the generated sheet's name
+	Index int    // 0-based position among the sheets of the jx:each
+	Count int    // number of sheets the jx:each generates
+}
+
+// iterationContext returns a child of ctx binding the loop variable, index,
+// status and let bindings for iteration i of count.
+func (c *EachCommand) iterationContext(ctx *Context, item any, i, count int) (*Context, error) {
+	vars := map[string]any{}
+	if group, ok := item.(GroupData); ok && c.GroupBy != "" {
+		if c.ItemVar != "" {
+			vars[c.ItemVar] = group.Item
+		}
+		vars[c.groupName()] = item
+	} else {
+		if c.ItemVar != "" {
+			vars[c.ItemVar] = item
+		}
+		vars[c.Var] = item
+	}
+	if c.VarIndex != "" {
+		vars[c.VarIndex] = i
+	}
+	if c.VarStatus != "" {
+		vars[c.VarStatus] = LoopStatus{Index: i, Count: count, First: i == 0, Last: i == count-1}
+	}
+	iterCtx := ctx.WithVars(vars)
+	if c.Outline {
+		iterCtx.outlineLevel = ctx.outlineLevel + 1
+	}
+	return c.bindLets(iterCtx)
+}
+
+// applyMultiSheet processes each item on a separate sheet, named as
+// sheetNames describes.
+func (c *EachCommand) applyMultiSheet(cellRef CellRef, ctx *Context, transformer Transformer, items []any) (Size, error) {
+	sheetNames, err := c.sheetNames(ctx, items)
 	if err != nil {
-		return ZeroSize, fmt.Errorf("multisheet %q must be a string slice: %w", c.MultiSheet, err)
+		return ZeroSize, err
 	}
 
 	templateSheet := cellRef.Sheet
-	lastSize := ZeroSize
+	sizes := make([]Size, len(items))
+	if err := ctx.reserveSheets(len(items)); err != nil {
+		return ZeroSize, fmt.Errorf("multisheet %q: %w", c.MultiSheet, err)
+	}
 
-	for i, item := range items {
-		// Determine sheet name
-		var sheetName string
+	// Name all sheets up front, so names do not depend on the order of concurrent copies
+	names := make([]string, len(items))
+	existing := transformer.GetSheetNames()
+	builder := ctx.sheetNameBuilder()
+	for i := range items {
+		requested := fmt.Sprintf("%s_%d", templateSheet, i+1)
 		if i < len(sheetNames) {
-			sheetName = sheetNames[i]
-		} else {
-			sheetName = fmt.Sprintf("%s_%d", templateSheet, i+1)
+			requested = sheetNames[i]
+		}
+		names[i], err = builder.BuildSheetName(requested, existing)
+		if err != nil {
+			return ZeroSize, fmt.Errorf("multisheet %q: sheet name for item %d: %w", c.MultiSheet, i, err)
 		}
-		sheetName = SafeSheetName(sheetName)
+		existing = append(existing, names[i])
+	}
+
+	// applySheet copies the template sheet for item i and applies the area to it
+	applySheet := func(ctx *Context, transformer Transformer, i int) error {
+		item, sheetName := items[i], names[i]
 
 		// Copy template sheet
 		if err := transformer.CopySheet(templateSheet, sheetName); err != nil {
-			return ZeroSize, fmt.Errorf("copy sheet for multisheet item %d: %w", i, err)
+			return fmt.Errorf("copy sheet for multisheet item %d: %w", i, err)
 		}
+		ctx.addGeneratedSheet(sheetName)
 
-		// Set loop variable
-		var rv *RunVar
-		if c.VarIndex != "" {
-			rv = NewRunVarWithIndex(ctx, c.Var, c.VarIndex)
-			rv.SetWithIndex(item, i)
-		} else {
-			rv = NewRunVar(ctx, c.Var)
-			rv.Set(item)
+		// Bind the sheet and loop variables in a child scope
+		sheetCtx := ctx.forSheet(sheetName).WithVar(sheetVar, SheetInfo{Name: sheetName, Index: i, Count: len(items)})
+		iterCtx, err := c.iterationContext(sheetCtx, item, i, len(items))
+		if err != nil {
+			return fmt.Errorf("multisheet iteration %d (sheet %s): %w", i, sheetName, err)
 		}
 
-		// Create a target on the new sheet at the same position
-		target := NewCellRef(sheetName, cellRef.Row, cellRef.Col)
-
-		// Process the area on the new sheet — we need to read cell data from the new sheet.
-		// Since the sheet was copied, the transformer already has the data.
-		// We use the template area's size but target the new sheet.
-		iterSize, err := c.Area.ApplyAt(target, ctx)
-		rv.Close()
+		// Fill the new sheet at the template's positions from the template's cell data
+		var iterSize Size
+		if len(c.sheetAreas) > 0 {
+			iterSize, err = c.applySheetAreas(sheetName, iterCtx)
+		} else {
+			target := NewCellRef(sheetName, cellRef.Row, cellRef.Col)
+			iterSize, err = c.Area.ApplyAt(target, iterCtx)
+			if err == nil {
+				c.Area.clearTemplateCells(iterCtx, target, iterSize)
+			}
+		}
 		if err != nil {
-			return ZeroSize, fmt.Errorf("multisheet iteration %d (sheet %s): %w", i, sheetName, err)
+			return fmt.Errorf("multisheet iteration %d (sheet %s): %w", i, sheetName, err)
 		}
-		lastSize = iterSize
+		sizes[i] = iterSize
+		return nil
 	}
 
-	// Delete the template sheet (it was the source for copies)
-	transformer.DeleteSheet(templateSheet)
+	if workers := ctx.concurrency(); workers > 1 && len(items) > 1 {
+		// Sheets are independent: fill them concurrently, writing in item order
+		jobs := make([]func(*Context) error, len(items))
+		for i := range items {
+			jobs[i] = func(jobCtx *Context) error {
+				return applySheet(jobCtx, transformerFor(transformer, jobCtx), i)
+			}
+		}
+		if err := runJobs(ctx, workers, jobs); err != nil {
+			return ZeroSize, err
+		}
+	} else {
+		for i := range items {
+			if err := applySheet(ctx, transformer, i); err != nil {
+				return ZeroSize, err
+			}
+		}
+	}
+	lastSize := ZeroSize
+	if len(sizes) > 0 {
+		lastSize = sizes[len(sizes)-1]
+	}
+
+	// Delete the template sheet (it was the source for copies) unless configured otherwise
+	if ctx.markDisposed(templateSheet) {
+		disposition := ctx.templateSheetDisposition(templateSheet)
+		if err := disposeSheet(transformer, templateSheet, disposition); err != nil {
+			return ZeroSize, fmt.Errorf("dispose template sheet %q: %w", templateSheet, err)
+		}
+		// The output opens on the first visible generated sheet instead, picked
+		// once the queued writes, which may hide sheets, are applied
+		if disposition == SheetDelete {
+			tx := unwrapTransformer(transformer)
+			if err := ctx.run(func() error { return activateFirstVisible(tx, names) }); err != nil {
+				return ZeroSize, fmt.Errorf("multisheet %q: %w", c.MultiSheet, err)
+			}
+		}
+	}
 
 	return lastSize, nil
 }
 
+// multisheetAuto is the multisheet value naming each sheet after its item.
+const multisheetAuto = "@auto"
+
+// sheetNames returns the requested name of each item's sheet; items past the
+// names get default names. The multisheet attribute is one of
+//   - a variable or expression holding the list of names, e.g. "sheetNames";
+//   - an expression over the loop variables evaluated for each item, e.g.
+//     "e.Name + ' ' + e.Year";
+//   - "@auto": the item's String method, its Name, or the item itself for a
+//     string or number; a groupBy group is named after its key.
+//
+// Names are made valid and unique by the SheetNameBuilder afterwards.
+func (c *EachCommand) sheetNames(ctx *Context, items []any) ([]string, error) {
+	if c.MultiSheet == multisheetAuto {
+		names := make([]string, len(items))
+		for i, item := range items {
+			name, err := autoSheetName(item)
+			if err != nil {
+				return nil, fmt.Errorf("multisheet %s: item %d: %w", multisheetAuto, i, err)
+			}
+			names[i] = name
+		}
+		return names, nil
+	}
+
+	if c.namesPerItem() {
+		names := make([]string, len(items))
+		for i, item := range items {
+			iterCtx, err := c.iterationContext(ctx, item, i, len(items))
+			if err != nil {
+				return nil, fmt.Errorf("multisheet %q: item %d: %w", c.MultiSheet, i, err)
+			}
+			val, err := iterCtx.Evaluate(c.MultiSheet)
+			if err != nil {
+				return nil, fmt.Errorf("evaluate multisheet %q for item %d: %w", c.MultiSheet, i, err)
+			}
+			if val = nullValue(val); val != nil {
+				names[i] = fmt.Sprintf("%v", val)
+			}
+		}
+		return names, nil
+	}
+
+	val, err := ctx.Evaluate(c.MultiSheet)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate multisheet %q: %w", c.MultiSheet, err)
+	}
+	names, err := toStringSlice(val)
+	if err != nil {
+		return nil, fmt.Errorf("multisheet %q must be a string slice: %w", c.MultiSheet, err)
+	}
+	return names, nil
+}
+
+// namesPerItem reports whether multisheet reads a variable bound for each
+// item, such as the loop variable, and so names one sheet per evaluation.
+func (c *EachCommand) namesPerItem() bool {
+	bound := map[string]bool{}
+	for _, name := range commandVariables(c) {
+		bound[name] = name != sheetVar
+	}
+	for _, name := range ExpressionVariables(c.MultiSheet) {
+		if bound[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// autoSheetName names the sheet of item for multisheet="@auto".
+func autoSheetName(item any) (string, error) {
+	if group, ok := item.(GroupData); ok {
+		item = group.Key
+	}
+	item = nullValue(item)
+	if s, ok := item.(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+	if name, ok := lookupField(item, "Name"); ok {
+		if name = nullValue(name); name != nil {
+			return fmt.Sprintf("%v", name), nil
+		}
+	}
+	switch reflect.ValueOf(item).Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return fmt.Sprintf("%v", item), nil
+	}
+	return "", fmt.Errorf("%T has no String method or Name to name its sheet after", item)
+}
+
+// activateFirstVisible makes the first of sheets that is not hidden active.
+func activateFirstVisible(tx Transformer, sheets []string) error {
+	for _, name := range sheets {
+		if !isHidden(tx, name) {
+			return tx.SetActiveSheet(name)
+		}
+	}
+	return nil
+}
+
+// applySheetAreas fills the root areas of the template sheet on the generated
+// sheet, top to bottom, shifting areas below ones that grew. It returns the
+// output size of the command's own area.
+func (c *EachCommand) applySheetAreas(sheet string, ctx *Context) (Size, error) {
+	layout := newAreaLayout()
+	layout.sheet = sheet
+	var size Size
+	for _, area := range c.sheetAreas {
+		target := layout.target(area)
+		areaSize, err := area.ApplyAt(target, ctx)
+		if err != nil {
+			return ZeroSize, fmt.Errorf("area %s: %w", area.SourceRef(), err)
+		}
+		layout.place(ctx, area, target, areaSize)
+		area.clearTemplateCells(ctx, target, areaSize)
+		if area == c.Area {
+			size = areaSize
+		}
+	}
+	return size, nil
+}
+
 // toStringSlice converts a value to []string.
 func toStringSlice(val any) ([]string, error) {
 	if val == nil {
@@ -226,14 +940,36 @@ func toStringSlice(val any) ([]string, error) {
 	return result, nil
 }
 
-// filterItems applies the select expression to filter items.
-func (c *EachCommand) filterItems(items []any, ctx *Context) ([]any, error) {
+// groupName returns the variable each GroupData is bound to: groupVar, or the
+// loop variable.
+func (c *EachCommand) groupName() string {
+	if c.GroupVar != "" {
+		return c.GroupVar
+	}
+	return c.Var
+}
+
+// selectsGroups reports whether select filters the groups of groupBy rather
+// than the items, with oldSelectBehavior="true" or WithSelectBeforeGroup(false).
+func (c *EachCommand) selectsGroups(ctx *Context) bool {
+	return c.GroupBy != "" && (c.OldSelectBehavior || ctx.state.selectAfterGroup)
+}
+
+// filterItems applies the select expression to filter items. The properties
+// in fields, which select names without the loop variable, are bound as
+// variables for each item.
+func (c *EachCommand) filterItems(items []any, ctx *Context, fields []string) ([]any, error) {
+	name := c.Var
+	if c.selectsGroups(ctx) {
+		name = c.groupName()
+	}
 	var filtered []any
 	for i, item := range items {
-		rv := NewRunVar(ctx, c.Var)
-		rv.Set(item)
-		ok, err := ctx.IsConditionTrue(c.Select)
-		rv.Close()
+		vars := map[string]any{name: item}
+		for _, field := range fields {
+			vars[field] = getField(item, field)
+		}
+		ok, err := ctx.WithVars(vars).IsConditionTrue(c.Select)
 		if err != nil {
 			return nil, fmt.Errorf("select filter %q at item %d: %w", c.Select, i, err)
 		}
@@ -244,10 +980,33 @@ func (c *EachCommand) filterItems(items []any, ctx *Context) ([]any, error) {
 	return filtered, nil
 }
 
-// sortItems sorts items by the orderBy specification.
+// distinctItems keeps the first item for each value of the distinct
+// expression, in order. Keys are compared like groupBy keys.
+func (c *EachCommand) distinctItems(items []any, ctx *Context) ([]any, error) {
+	seen := map[string]bool{}
+	var unique []any
+	for i, item := range items {
+		val, err := ctx.WithVars(map[string]any{c.Var: item}).Evaluate(c.Distinct)
+		if err != nil {
+			return nil, fmt.Errorf("distinct %q at item %d: %w", c.Distinct, i, err)
+		}
+		key := fmt.Sprintf("%v", val)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, item)
+		}
+	}
+	return unique, nil
+}
+
+// sortItems sorts items, or the groups of groupBy, by the orderBy specification.
 func (c *EachCommand) sortItems(items []any) ([]any, error) {
 	// Parse orderBy: "e.Name ASC, e.Payment DESC"
-	specs := parseOrderBy(c.OrderBy, c.Var)
+	varName := c.Var
+	if c.GroupBy != "" {
+		varName = c.groupName()
+	}
+	specs := parseOrderBy(c.OrderBy, varName)
 	if len(specs) == 0 {
 		return items, nil
 	}
@@ -256,20 +1015,17 @@ func (c *EachCommand) sortItems(items []any) ([]any, error) {
 }
 
 // GroupData represents a group of items sharing a common key value.
-// Used with groupBy: ${g.item.Department} accesses the key, ${g.items} iterates group members.
+// Used with groupBy: ${g.Key} is the key, ${g.Items} iterates group members.
 type GroupData struct {
+	Key   any   // the groupBy value shared by the items
 	Item  any   // the first item in the group (or representative)
 	Items []any // all items in this group
+	Count int   // len(Items)
 }
 
 // groupItems groups items by the groupBy property and returns []GroupData wrapped as []any.
 func (c *EachCommand) groupItems(items []any) []any {
-	field := c.GroupBy
-	// Strip var prefix (e.g., "e.Department" → "Department")
-	prefix := c.Var + "."
-	if strings.HasPrefix(field, prefix) {
-		field = field[len(prefix):]
-	}
+	field, _ := loopField(c.GroupBy, c.Var)
 
 	// Maintain insertion order
 	type groupEntry struct {
@@ -304,7 +1060,7 @@ func (c *EachCommand) groupItems(items []any) []any {
 	// Convert to []any of GroupData
 	result := make([]any, len(groups))
 	for i, g := range groups {
-		result[i] = GroupData{Item: g.items[0], Items: g.items}
+		result[i] = GroupData{Key: g.key, Item: g.items[0], Items: g.items, Count: len(g.items)}
 	}
 	return result
 }
@@ -342,18 +1098,13 @@ func parseOrderBy(spec string, varName string) []orderBySpec {
 	}
 	parts := strings.Split(spec, ",")
 	var specs []orderBySpec
-	prefix := varName + "."
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
 		if p == "" {
 			continue
 		}
 		tokens := strings.Fields(p)
-		field := tokens[0]
-		// Strip var prefix
-		if strings.HasPrefix(field, prefix) {
-			field = field[len(prefix):]
-		}
+		field, _ := loopField(tokens[0], varName)
 		desc := false
 		if len(tokens) > 1 && strings.EqualFold(tokens[1], "DESC") {
 			desc = true
@@ -389,29 +1140,6 @@ func compareBySpecs(a, b any, specs []orderBySpec) int {
 	return 0
 }
 
-// getField extracts a field value from a struct or map by name.
-func getField(item any, field string) any {
-	if item == nil {
-		return nil
-	}
-	// Try map first
-	if m, ok := item.(map[string]any); ok {
-		return m[field]
-	}
-	// Try struct via reflection
-	v := reflect.ValueOf(item)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-	if v.Kind() == reflect.Struct {
-		f := v.FieldByName(field)
-		if f.IsValid() {
-			return f.Interface()
-		}
-	}
-	return nil
-}
-
 // compareValues compares two values for ordering.
 func compareValues(a, b any) int {
 	if a == nil && b == nil {
@@ -474,6 +1202,14 @@ func toSlice(val any) ([]any, error) {
 		return nil, nil
 	}
 
+	if it, ok := val.(Iterator); ok {
+		var result []any
+		for it.Next() {
+			result = append(result, it.Value())
+		}
+		return result, it.Err()
+	}
+
 	v := reflect.ValueOf(val)
 	switch v.Kind() {
 	case reflect.Slice, reflect.Array:
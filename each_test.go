@@ -3,6 +3,8 @@ package xlfill
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -447,6 +449,113 @@ func TestEachCommand_SelectFilter(t *testing.T) {
 	assert.Equal(t, "Carol", v)
 }
 
+func TestEachCommand_Distinct(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${o.Customer}")
+	f.SetCellValue(sheet, "B1", "${o.ID}")
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	orders := []any{
+		map[string]any{"ID": 1, "Customer": "Zed", "CustomerID": 3, "Paid": true},
+		map[string]any{"ID": 2, "Customer": "Acme", "CustomerID": 1, "Paid": false},
+		map[string]any{"ID": 3, "Customer": "Zed", "CustomerID": 3, "Paid": true},
+		map[string]any{"ID": 4, "Customer": "Acme", "CustomerID": 1, "Paid": true},
+		map[string]any{"ID": 5, "Customer": "Bolt", "CustomerID": 2, "Paid": true},
+	}
+	ctx := NewContext(map[string]any{"orders": orders})
+
+	// distinct runs after select (order 2 is dropped) and before orderBy
+	cmd, err := newEachCommandFromAttrs(map[string]string{
+		"items": "orders", "var": "o", "select": "o.Paid", "distinct": "o.CustomerID", "orderBy": "o.Customer",
+	})
+	require.NoError(t, err)
+	each := cmd.(*EachCommand)
+	assert.Equal(t, "o.CustomerID", each.Distinct)
+	each.Area = NewArea(NewCellRef(sheet, 0, 0), Size{Width: 2, Height: 1}, tx)
+
+	size, err := each.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, size.Height)
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+	rows, err := out.GetRows(sheet)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Acme", "4"}, {"Bolt", "5"}, {"Zed", "1"}}, rows)
+}
+
+func TestEachCommand_DistinctError(t *testing.T) {
+	cmd := &EachCommand{Var: "e", Distinct: "e.Name +"}
+	_, err := cmd.distinctItems([]any{map[string]any{"Name": "A"}}, NewContext(nil))
+	assert.ErrorContains(t, err, `distinct "e.Name +" at item 0`)
+}
+
+func TestFill_EachGap(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${c.Name}")
+	f.SetCellValue("Sheet1", "A2", "${c.Total}")
+	f.SetCellFormula("Sheet1", "A3", "SUM(A2)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A3")` + "\n" + `jx:each(items="customers" var="c" gap="1" lastCell="A2")`})
+	f.NewSheet("Wide")
+	f.SetCellValue("Wide", "A1", "${c.Name}")
+	f.SetCellValue("Wide", "B1", "End")
+	f.AddComment("Wide", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B1")` + "\n" + `jx:each(items="customers" var="c" direction="RIGHT" gap="2" lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "each_gap.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	customers := []map[string]any{{"Name": "Acme", "Total": 1}, {"Name": "Bolt", "Total": 2}, {"Name": "Zed", "Total": 3}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, map[string]any{"customers": customers}, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		rows, err := res.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Acme"},
+			{"1"},
+			nil,
+			{"Bolt"},
+			{"2"},
+			nil,
+			{"Zed"},
+			{"3"},
+			{""},
+		}, rows)
+		formula, err := res.GetCellFormula("Sheet1", "A9")
+		require.NoError(t, err)
+		assert.Equal(t, "SUM(A2,A5,A8)", formula)
+
+		rows, err = res.GetRows("Wide")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Acme", "", "", "Bolt", "", "", "Zed", "End"}}, rows)
+	}
+}
+
+func TestNewEachCommand_Gap(t *testing.T) {
+	cmd, err := newEachCommandFromAttrs(map[string]string{"items": "xs", "var": "x", "gap": "2"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, cmd.(*EachCommand).Gap)
+
+	for _, gap := range []string{"-1", "one"} {
+		_, err := newEachCommandFromAttrs(map[string]string{"items": "xs", "var": "x", "gap": gap})
+		assert.ErrorContains(t, err, "invalid gap", gap)
+	}
+	_, err = newEachCommandFromAttrs(map[string]string{"items": "xs", "var": "x", "gap": "1", "multisheet": "names"})
+	assert.ErrorContains(t, err, "gap requires direction DOWN or RIGHT")
+	_, err = newEachCommandFromAttrs(map[string]string{"items": "xs", "var": "x", "gap": "1", "direction": "DOWN_RIGHT"})
+	assert.ErrorContains(t, err, "gap requires direction DOWN or RIGHT")
+}
+
 func TestEachCommand_OrderBy(t *testing.T) {
 	f := excelize.NewFile()
 	sheet := "Sheet1"
@@ -827,6 +936,54 @@ func TestEachCommand_GroupBy_WithSelect(t *testing.T) {
 	assert.Equal(t, 2, size.Height) // Eng and HR (Bob filtered out, so no Sales group)
 }
 
+func TestEachCommand_SelectBeforeOrAfterGroup(t *testing.T) {
+	template := func(name, attrs string) string {
+		f := excelize.NewFile()
+		f.SetCellValue("Sheet1", "A1", "${d.Item.Dept}")
+		f.SetCellValue("Sheet1", "B1", "${len(d.Items)}")
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B1")` + "\n" +
+			`jx:each(items="employees" var="d" groupBy="d.Dept" ` + attrs + ` lastCell="B1")`})
+		path := filepath.Join(testdataDir(t), name)
+		require.NoError(t, f.SaveAs(path))
+		f.Close()
+		return path
+	}
+	data := map[string]any{"employees": []any{
+		map[string]any{"Name": "Alice", "Dept": "Eng", "Salary": 3000},
+		map[string]any{"Name": "Bob", "Dept": "Sales", "Salary": 1500},
+		map[string]any{"Name": "Carol", "Dept": "Eng", "Salary": 1800},
+		map[string]any{"Name": "Dave", "Dept": "Sales", "Salary": 2500},
+		map[string]any{"Name": "Erin", "Dept": "HR", "Salary": 2100},
+	}}
+	rows := func(path string, opts ...Option) [][]string {
+		out, err := FillBytes(path, data, opts...)
+		require.NoError(t, err)
+		f, err := excelize.OpenReader(bytes.NewReader(out))
+		require.NoError(t, err)
+		defer f.Close()
+		got, err := f.GetRows("Sheet1")
+		require.NoError(t, err)
+		return got
+	}
+
+	items := template("select_items.xlsx", `select="d.Salary > 2000"`)
+	groups := template("select_groups.xlsx", `select="len(d.Items) > 1" oldSelectBehavior="true"`)
+	bare := template("select_groups_option.xlsx", `select="len(d.Items) > 1"`)
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		// By default select filters the employees, then they are grouped
+		assert.Equal(t, [][]string{{"Eng", "1"}, {"Sales", "1"}, {"HR", "1"}}, rows(items, opts...))
+		assert.Equal(t, [][]string{{"Eng", "1"}, {"Sales", "1"}, {"HR", "1"}}, rows(items, append(opts, WithSelectBeforeGroup(true))...))
+
+		// Old behavior: select filters the groups
+		assert.Equal(t, [][]string{{"Eng", "2"}, {"Sales", "2"}}, rows(groups, opts...))
+		assert.Equal(t, [][]string{{"Eng", "2"}, {"Sales", "2"}}, rows(bare, append(opts, WithSelectBeforeGroup(false))...))
+	}
+
+	// A select reading the items no longer matches once it selects groups
+	_, err := FillBytes(items, data, WithSelectBeforeGroup(false))
+	assert.ErrorContains(t, err, "d.Salary")
+}
+
 func TestEachCommand_GroupBy_GroupDataItems(t *testing.T) {
 	// Verify that GroupData.Items contains the correct members.
 	items := []any{
@@ -852,6 +1009,61 @@ func TestEachCommand_GroupBy_GroupDataItems(t *testing.T) {
 	assert.Len(t, g2.Items, 1) // Bob
 }
 
+func TestEachCommand_GroupKeyAndNames(t *testing.T) {
+	template := func(name, each string, cells ...string) string {
+		f := excelize.NewFile()
+		for i, cell := range cells {
+			f.SetCellValue("Sheet1", fmt.Sprintf("%c1", 'A'+i), cell)
+		}
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C2")` + "\n" + each})
+		path := filepath.Join(testdataDir(t), name)
+		require.NoError(t, f.SaveAs(path))
+		f.Close()
+		return path
+	}
+	data := map[string]any{"employees": []any{
+		map[string]any{"Name": "Alice", "Address": map[string]any{"City": "Oslo"}},
+		map[string]any{"Name": "Bob", "Address": map[string]any{"City": "Rome"}},
+		map[string]any{"Name": "Carol", "Address": map[string]any{"City": "Rome"}},
+	}}
+	rows := func(path string, opts ...Option) [][]string {
+		out, err := FillBytes(path, data, opts...)
+		require.NoError(t, err)
+		f, err := excelize.OpenReader(bytes.NewReader(out))
+		require.NoError(t, err)
+		defer f.Close()
+		got, err := f.GetRows("Sheet1")
+		require.NoError(t, err)
+		return got
+	}
+
+	byVar := template("group_key.xlsx", `jx:each(items="employees" var="e" groupBy="e.Address.City" lastCell="C1")`,
+		"${e.Key}", "${e.Count}", "${e.Item.Name}")
+	named := template("group_names.xlsx",
+		`jx:each(items="employees" var="e" groupBy="e.Address.City" groupVar="g" itemVar="e" orderBy="g.Count DESC" lastCell="C1")`,
+		"${g.Key}", "${g.Count}", "${e.Name}")
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		assert.Equal(t, [][]string{{"Oslo", "1", "Alice"}, {"Rome", "2", "Bob"}}, rows(byVar, opts...))
+		assert.Equal(t, [][]string{{"Rome", "2", "Bob"}, {"Oslo", "1", "Alice"}}, rows(named, opts...))
+	}
+
+	// With a footer the items see the group, and the footer its first item
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${g.Key}")
+	f.SetCellValue("Sheet1", "B1", "${e.Name}")
+	f.SetCellValue("Sheet1", "A2", "${first.Name}")
+	f.SetCellValue("Sheet1", "B2", "${g.Count}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")` + "\n" +
+		`jx:each(items="employees" var="e" groupBy="e.Address.City" groupVar="g" itemVar="first" lastCell="B1" footerArea="A2:B2")`})
+	footer := filepath.Join(testdataDir(t), "group_names_footer.xlsx")
+	require.NoError(t, f.SaveAs(footer))
+	f.Close()
+	assert.Equal(t, [][]string{{"Oslo", "Alice"}, {"Alice", "1"}, {"Rome", "Bob"}, {"Rome", "Carol"}, {"Bob", "2"}}, rows(footer))
+
+	_, err := newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "groupVar": "g"})
+	assert.ErrorContains(t, err, "require 'groupBy'")
+}
+
 func TestEachCommand_GroupBy_IgnoreCase(t *testing.T) {
 	items := []any{
 		map[string]any{"Dept": "engineering"},
@@ -869,3 +1081,606 @@ func TestEachCommand_GroupBy_IgnoreCase(t *testing.T) {
 	// But after sorting with ignore case, they should be ordered properly
 	require.True(t, len(grouped) >= 2)
 }
+
+func TestEachCommand_Right_VarIndexAndStatus(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${idx}")
+	f.SetCellValue(sheet, "A2", "${e.Name}")
+	f.SetCellValue(sheet, "A3", "${s.Index}/${s.Count} ${s.First} ${s.Last}")
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	items := []any{
+		map[string]any{"Name": "Alice"},
+		map[string]any{"Name": "Bob"},
+		map[string]any{"Name": "Carol"},
+	}
+	ctx := NewContext(map[string]any{"items": items})
+
+	cmd := &EachCommand{
+		Items: "items", Var: "e", VarIndex: "idx", VarStatus: "s", Direction: "RIGHT",
+		Area: NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 3}, tx),
+	}
+
+	size, err := cmd.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 3, Height: 3}, size)
+	assert.False(t, ctx.ContainsVar("s"), "status variable restored after loop")
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+
+	for i, col := range []string{"A", "B", "C"} {
+		v, _ := out.GetCellValue(sheet, col+"1")
+		assert.Equal(t, fmt.Sprint(i), v)
+		v, _ = out.GetCellValue(sheet, col+"2")
+		assert.Equal(t, items[i].(map[string]any)["Name"], v)
+	}
+	v, _ := out.GetCellValue(sheet, "A3")
+	assert.Equal(t, "0/3 true false", v)
+	v, _ = out.GetCellValue(sheet, "C3")
+	assert.Equal(t, "2/3 false true", v)
+}
+
+func TestEachCommand_Right_SelectAndGroupBy(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${g.Item.Dept}")
+	f.SetCellValue(sheet, "A2", "${len(g.Items)}")
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	items := []any{
+		map[string]any{"Name": "Alice", "Dept": "Eng", "Active": true},
+		map[string]any{"Name": "Bob", "Dept": "Sales", "Active": true},
+		map[string]any{"Name": "Carol", "Dept": "Eng", "Active": true},
+		map[string]any{"Name": "Dave", "Dept": "Ops", "Active": false},
+	}
+	ctx := NewContext(map[string]any{"items": items})
+
+	cmd := &EachCommand{
+		Items: "items", Var: "g", Direction: "RIGHT",
+		Select: "g.Active", GroupBy: "g.Dept", GroupOrder: "DESC",
+		Area: NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 2}, tx),
+	}
+
+	size, err := cmd.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 2, Height: 2}, size)
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+
+	v, _ := out.GetCellValue(sheet, "A1")
+	assert.Equal(t, "Sales", v)
+	v, _ = out.GetCellValue(sheet, "B1")
+	assert.Equal(t, "Eng", v)
+	v, _ = out.GetCellValue(sheet, "B2")
+	assert.Equal(t, "2", v)
+	v, _ = out.GetCellValue(sheet, "C1")
+	assert.Empty(t, v)
+}
+
+func TestEachCommand_Right_ShiftsStaticCellsAndFormulas(t *testing.T) {
+	// Template row: A1 "Label"  B1 ${m.Amount} (each RIGHT)  C1 =SUM(B1)
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Label")
+	f.SetCellValue(sheet, "B1", "${m.Amount}")
+	f.SetCellFormula(sheet, "C1", "SUM(B1)")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="C1")`,
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "B1", Author: "xlfill",
+		Text: `jx:each(items="months" var="m" direction="RIGHT" lastCell="B1")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	data := map[string]any{
+		"months": []map[string]any{{"Amount": 1}, {"Amount": 2}, {"Amount": 3}},
+	}
+	outBytes, err := FillBytes(tmpPath, data)
+	require.NoError(t, err)
+
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	v, _ := out.GetCellValue(sheet, "A1")
+	assert.Equal(t, "Label", v)
+	v, _ = out.GetCellValue(sheet, "D1")
+	assert.Equal(t, "3", v)
+
+	formula, _ := out.GetCellFormula(sheet, "E1")
+	assert.Equal(t, "SUM(B1:D1)", formula)
+}
+
+func TestEachCommand_DownRightMatrix(t *testing.T) {
+	// Template:
+	//   A1: "Sales"       B1: ${v}-${r}${c} (each DOWN_RIGHT)   C1: "Total"
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Sales")
+	f.SetCellValue(sheet, "B1", "${v}-${r}${c}")
+	f.SetCellValue(sheet, "C1", "Total")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="C1")`,
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "B1", Author: "xlfill",
+		Text: `jx:each(items="matrix" var="v" varIndex="c" rowIndex="r" direction="DOWN_RIGHT" lastCell="B1")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	data := map[string]any{
+		"matrix": [][]int{
+			{10, 20, 30},
+			{40, 50},
+		},
+	}
+	outBytes, err := FillBytes(tmpPath, data)
+	require.NoError(t, err)
+
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	expected := map[string]string{
+		"B1": "10-00", "C1": "20-01", "D1": "30-02",
+		"B2": "40-10", "C2": "50-11", "D2": "",
+		"A1": "Sales", "E1": "Total",
+	}
+	for cell, want := range expected {
+		v, _ := out.GetCellValue(sheet, cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestEachCommand_DownRightMatrix_RowNotIterable(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${v}")
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	cmd := &EachCommand{
+		Items: "matrix", Var: "v", Direction: "DOWN_RIGHT",
+		Area: NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 1}, tx),
+	}
+	ctx := NewContext(map[string]any{"matrix": []any{[]any{1}, 2}})
+
+	_, err = cmd.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+	assert.ErrorContains(t, err, "matrix row 1")
+}
+
+// createZebraTemplate creates a two-column list with alternate row styles and a
+// hidden "Styles" sheet holding a grey stripe cell.
+//
+//	Sheet1!A1: "${e.Name}"  [jx:area(lastCell="B1"), jx:each(items="emps" var="e" <attrs> lastCell="B1")]
+//	Sheet1!B1: "${e.Pay}" (bold)
+//	Styles!A1: grey fill
+func createZebraTemplate(t *testing.T, attrs string) *excelize.File {
+	t.Helper()
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${e.Name}")
+	f.SetCellValue("Sheet1", "B1", "${e.Pay}")
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Sheet1", "B1", "B1", bold))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"B1\")\njx:each(items=\"emps\" var=\"e\"" + attrs + " lastCell=\"B1\")"})
+
+	_, err = f.NewSheet("Styles")
+	require.NoError(t, err)
+	grey, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"EEEEEE"}}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Styles", "A1", "A1", grey))
+	require.NoError(t, f.SetCellStyle("Sheet1", "D1", "D1", grey))
+	return f
+}
+
+func fillZebra(t *testing.T, attrs string, opts ...Option) *excelize.File {
+	t.Helper()
+	tmpl := createZebraTemplate(t, attrs)
+	defer tmpl.Close()
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	var out bytes.Buffer
+	data := map[string]any{"emps": []map[string]any{
+		{"Name": "Ann", "Pay": 10}, {"Name": "Bob", "Pay": 20}, {"Name": "Cy", "Pay": 30}, {"Name": "Di", "Pay": 40},
+	}}
+	require.NoError(t, FillReader(&buf, &out, data, opts...))
+	f, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func fillColor(t *testing.T, f *excelize.File, cell string) string {
+	t.Helper()
+	id, err := f.GetCellStyle("Sheet1", cell)
+	require.NoError(t, err)
+	style, err := f.GetStyle(id)
+	require.NoError(t, err)
+	if len(style.Fill.Color) == 0 {
+		return ""
+	}
+	return style.Fill.Color[0]
+}
+
+func TestEachCommand_ZebraRegisteredStyles(t *testing.T) {
+	f := fillZebra(t, ` oddStyle="odd" evenStyle="even"`, WithStyles(map[string]*excelize.Style{
+		"odd":  {Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFFFFF"}}},
+		"even": {Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"DDEBF7"}}},
+	}))
+
+	for row, want := range []string{"FFFFFF", "DDEBF7", "FFFFFF", "DDEBF7"} {
+		for _, col := range []string{"A", "B"} {
+			cell := fmt.Sprintf("%s%d", col, row+1)
+			assert.Equal(t, want, fillColor(t, f, cell), cell)
+		}
+	}
+	assert.Empty(t, fillColor(t, f, "C2"))
+
+	id, _ := f.GetCellStyle("Sheet1", "B2")
+	style, err := f.GetStyle(id)
+	require.NoError(t, err)
+	assert.True(t, style.Font.Bold)
+}
+
+func TestEachCommand_ZebraTemplateCellStyle(t *testing.T) {
+	for _, ref := range []string{"Styles!A1", "D1"} {
+		f := fillZebra(t, ` evenStyle="`+ref+`"`)
+		for row, want := range []string{"", "EEEEEE", "", "EEEEEE"} {
+			cell := fmt.Sprintf("B%d", row+1)
+			assert.Equal(t, want, fillColor(t, f, cell), ref+" "+cell)
+		}
+
+		// Only the fill is taken from the stripe cell; the bold font stays
+		id, _ := f.GetCellStyle("Sheet1", "B2")
+		style, err := f.GetStyle(id)
+		require.NoError(t, err)
+		assert.True(t, style.Font.Bold, ref)
+	}
+}
+
+func TestEachCommand_ZebraUnknownStyle(t *testing.T) {
+	tmpl := createZebraTemplate(t, ` oddStyle="stripe"`)
+	defer tmpl.Close()
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	err := FillReader(&buf, &bytes.Buffer{}, map[string]any{"emps": []map[string]any{{"Name": "Ann"}}})
+	assert.ErrorContains(t, err, `unknown style "stripe"`)
+}
+
+// fillOutline fills a template whose cells are given per row (cells[i] on row
+// i+1, from column A) and whose comments are keyed by cell, and returns the
+// outline level of output rows 1..rows.
+func fillOutline(t *testing.T, cells [][]string, comments map[string]string, rows int) ([]uint8, *excelize.File) {
+	t.Helper()
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	for r, row := range cells {
+		for c, v := range row {
+			tmpl.SetCellValue("Sheet1", fmt.Sprintf("%s%d", ColToName(c), r+1), v)
+		}
+	}
+	for cell, text := range comments {
+		tmpl.AddComment("Sheet1", excelize.Comment{Cell: cell, Author: "xlfill", Text: text})
+	}
+	var buf, out bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	data := map[string]any{"staff": []map[string]any{
+		{"Dept": "Eng", "Team": "Core", "Name": "a"},
+		{"Dept": "Eng", "Team": "Core", "Name": "b"},
+		{"Dept": "Eng", "Team": "Web", "Name": "c"},
+		{"Dept": "Ops", "Team": "Infra", "Name": "d"},
+	}}
+	require.NoError(t, FillReader(&buf, &out, data))
+	f, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	levels := make([]uint8, rows)
+	for r := range levels {
+		levels[r], err = f.GetRowOutlineLevel("Sheet1", r+1)
+		require.NoError(t, err)
+	}
+	return levels, f
+}
+
+func TestEachCommand_OutlineNestedGroups(t *testing.T) {
+	levels, f := fillOutline(t,
+		[][]string{{"Report"}, {"${d.Item.Dept}"}, {"${g.Item.Team}"}, {"${e.Name}"}},
+		map[string]string{
+			"A1": `jx:area(lastCell="A4")`,
+			"A2": `jx:each(items="staff" var="d" groupBy="d.Dept" outline="true" lastCell="A4")`,
+			"A3": `jx:each(items="d.Items" var="g" groupBy="g.Team" outline="true" lastCell="A4")`,
+			"A4": `jx:each(items="g.Items" var="e" lastCell="A4")`,
+		}, 10)
+
+	// Eng, Core, a, b, Web, c, Ops, Infra, d
+	assert.Equal(t, []uint8{0, 0, 1, 2, 2, 1, 2, 0, 1, 2}, levels)
+	v, _ := f.GetCellValue("Sheet1", "A8")
+	assert.Equal(t, "Ops", v)
+
+	props, err := f.GetSheetProps("Sheet1")
+	require.NoError(t, err)
+	require.NotNil(t, props.OutlineSummaryBelow)
+	assert.False(t, *props.OutlineSummaryBelow)
+}
+
+func TestEachCommand_OutlineSummaryBelow(t *testing.T) {
+	levels, f := fillOutline(t,
+		[][]string{{"Report"}, {"${e.Name}"}, {"Total ${d.Item.Dept}"}},
+		map[string]string{
+			"A1": `jx:area(lastCell="A3")`,
+			"A2": "jx:each(items=\"staff\" var=\"d\" groupBy=\"d.Dept\" outline=\"true\" summaryRow=\"below\" lastCell=\"A3\")\njx:each(items=\"d.Items\" var=\"e\" lastCell=\"A2\")",
+		}, 7)
+
+	// a, b, c, Total Eng, d, Total Ops
+	assert.Equal(t, []uint8{0, 1, 1, 1, 0, 1, 0}, levels)
+	v, _ := f.GetCellValue("Sheet1", "A7")
+	assert.Equal(t, "Total Ops", v)
+
+	props, err := f.GetSheetProps("Sheet1")
+	require.NoError(t, err)
+	require.NotNil(t, props.OutlineSummaryBelow)
+	assert.True(t, *props.OutlineSummaryBelow)
+}
+
+func TestEachCommand_OutlineInvalidAttributes(t *testing.T) {
+	_, err := newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "outline": "true"})
+	assert.ErrorContains(t, err, "outline requires 'groupBy'")
+	_, err = newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "groupBy": "e.A", "outline": "true", "direction": "RIGHT"})
+	assert.ErrorContains(t, err, "outline requires direction DOWN")
+	_, err = newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "groupBy": "e.A", "outline": "true", "summaryRow": "LEFT"})
+	assert.ErrorContains(t, err, `invalid summaryRow "LEFT"`)
+}
+
+func TestEachCommand_MergeBy(t *testing.T) {
+	tmpl := excelize.NewFile()
+	defer tmpl.Close()
+	tmpl.SetCellValue("Sheet1", "A1", "${e.Dept}")
+	tmpl.SetCellValue("Sheet1", "B1", "${e.Name}")
+	bold, err := tmpl.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+	require.NoError(t, tmpl.SetCellStyle("Sheet1", "A1", "A1", bold))
+	tmpl.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"B1\")\njx:each(items=\"staff\" var=\"e\" mergeBy=\"e.Dept\" lastCell=\"B1\")"})
+	var buf, out bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+
+	data := map[string]any{"staff": []map[string]any{
+		{"Dept": "Eng", "Name": "a"},
+		{"Dept": "Eng", "Name": "b"},
+		{"Dept": "Eng", "Name": "c"},
+		{"Dept": "Ops", "Name": "d"},
+		{"Dept": "Sales", "Name": "e"},
+		{"Dept": "Sales", "Name": "f"},
+	}}
+	require.NoError(t, FillReader(&buf, &out, data))
+	f, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	defer f.Close()
+
+	merged, err := f.GetMergeCells("Sheet1")
+	require.NoError(t, err)
+	var ranges []string
+	for _, m := range merged {
+		ranges = append(ranges, m.GetStartAxis()+":"+m.GetEndAxis())
+	}
+	assert.ElementsMatch(t, []string{"A1:A3", "A5:A6"}, ranges)
+
+	for cell, want := range map[string]string{"A1": "Eng", "A4": "Ops", "A5": "Sales", "B3": "c", "B6": "f"} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+	_, style := styleOf(t, f, "Sheet1", "A1")
+	require.NotNil(t, style.Font)
+	assert.True(t, style.Font.Bold)
+}
+
+func TestEachCommand_MergeByGroupBlocks(t *testing.T) {
+	_, f := fillOutline(t,
+		[][]string{{"${d.Item.Dept}", "${e.Name}"}},
+		map[string]string{
+			"A1": "jx:area(lastCell=\"B1\")\njx:each(items=\"staff\" var=\"d\" groupBy=\"d.Dept\" mergeBy=\"d.Item.Dept\" lastCell=\"B1\")",
+			"B1": `jx:each(items="d.Items" var="e" lastCell="B1")`,
+		}, 0)
+
+	merged, err := f.GetMergeCells("Sheet1")
+	require.NoError(t, err)
+	require.Len(t, merged, 1)
+	assert.Equal(t, "A1", merged[0].GetStartAxis())
+	assert.Equal(t, "A3", merged[0].GetEndAxis())
+	v, _ := f.GetCellValue("Sheet1", "A4")
+	assert.Equal(t, "Ops", v)
+}
+
+func TestEachCommand_MergeByInvalidAttributes(t *testing.T) {
+	_, err := newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "mergeBy": "e.A", "direction": "RIGHT"})
+	assert.ErrorContains(t, err, "mergeBy requires direction DOWN")
+}
+
+func TestEachCommand_TemplateMerges(t *testing.T) {
+	items := []map[string]any{{"Name": "a", "Desc": "x"}, {"Name": "b", "Desc": "y"}, {"Name": "c", "Desc": "z"}}
+	depts := []map[string]any{
+		{"Name": "Eng", "Staff": items[:2]},
+		{"Name": "Ops", "Staff": items[2:]},
+	}
+	tests := []struct {
+		name     string
+		merges   []string
+		comments map[string]string
+		want     []string
+	}{
+		{
+			name:   "down",
+			merges: []string{"A1:D1", "B2:C2", "A3:B4"},
+			comments: map[string]string{
+				"A1": `jx:area(lastCell="D4")`,
+				"A2": `jx:each(items="items" var="e" lastCell="C2")`,
+			},
+			want: []string{"A1:D1", "B2:C2", "B3:C3", "B4:C4", "A5:B6"},
+		},
+		{
+			name:   "right",
+			merges: []string{"A1:D1", "B2:C2", "A3:B4"},
+			comments: map[string]string{
+				"A1": `jx:area(lastCell="D4")`,
+				"A2": `jx:each(items="items" var="e" direction="RIGHT" lastCell="C2")`,
+			},
+			want: []string{"A1:D1", "B2:C2", "E2:F2", "H2:I2", "A3:B4"},
+		},
+		{
+			name:   "nested",
+			merges: []string{"A1:D1", "B2:C2", "A3:B4"},
+			comments: map[string]string{
+				"A1": `jx:area(lastCell="D4")` + "\n" + `jx:each(items="depts" var="d" lastCell="D2")`,
+				"A2": `jx:each(items="d.Staff" var="e" lastCell="C2")`,
+			},
+			want: []string{"A1:D1", "B2:C2", "B3:C3", "A4:D4", "B5:C5", "A6:B7"},
+		},
+	}
+	for _, tt := range tests {
+		for _, concurrency := range []int{0, 4} {
+			t.Run(fmt.Sprintf("%s/concurrency=%d", tt.name, concurrency), func(t *testing.T) {
+				tmpl := excelize.NewFile()
+				defer tmpl.Close()
+				tmpl.SetCellValue("Sheet1", "A1", "${d.Name}")
+				tmpl.SetCellValue("Sheet1", "A2", "${e.Name}")
+				tmpl.SetCellValue("Sheet1", "B2", "${e.Desc}")
+				tmpl.SetCellValue("Sheet1", "A3", "Total")
+				for _, m := range tt.merges {
+					first, last, _ := strings.Cut(m, ":")
+					require.NoError(t, tmpl.MergeCell("Sheet1", first, last))
+				}
+				for cell, text := range tt.comments {
+					tmpl.AddComment("Sheet1", excelize.Comment{Cell: cell, Author: "xlfill", Text: text})
+				}
+				var buf bytes.Buffer
+				require.NoError(t, tmpl.Write(&buf))
+
+				data := map[string]any{"items": items, "depts": depts, "d": depts[0]}
+				out, err := NewFiller(WithTemplateReader(&buf), WithConcurrency(concurrency)).FillBytes(data)
+				require.NoError(t, err)
+				f := openOutput(t, out)
+
+				merged, err := f.GetMergeCells("Sheet1")
+				require.NoError(t, err)
+				var ranges []string
+				for _, m := range merged {
+					ranges = append(ranges, m.GetStartAxis()+":"+m.GetEndAxis())
+				}
+				assert.ElementsMatch(t, tt.want, ranges)
+			})
+		}
+	}
+}
+
+func TestEachCommand_EmptyAction(t *testing.T) {
+	f := excelize.NewFile()
+	amount, err := f.NewStyle(&excelize.Style{NumFmt: 4, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFF2CC"}}})
+	require.NoError(t, err)
+	f.SetCellValue("Sheet1", "A1", "Name")
+	f.SetCellValue("Sheet1", "B1", "Amount")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Amount}")
+	f.SetCellStyle("Sheet1", "B2", "B2", amount)
+	f.SetCellValue("Sheet1", "A3", "Total")
+	f.SetCellFormula("Sheet1", "B3", "SUM(B2)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B3", Author: "xlfill", Text: `jx:params(defaultValue="0")`})
+	each := `jx:each(items="rows" var="e" lastCell="B2")`
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: each})
+	removeTmpl := filepath.Join(testdataDir(t), "each_empty_remove.xlsx")
+	require.NoError(t, f.SaveAs(removeTmpl))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: strings.Replace(each, "lastCell", `emptyAction="clear" lastCell`, 1)})
+	clearTmpl := filepath.Join(testdataDir(t), "each_empty_clear.xlsx")
+	require.NoError(t, f.SaveAs(clearTmpl))
+	f.Close()
+
+	cell := func(f *excelize.File, ref string) (string, string) {
+		v, err := f.GetCellValue("Sheet1", ref)
+		require.NoError(t, err)
+		formula, err := f.GetCellFormula("Sheet1", ref)
+		require.NoError(t, err)
+		return v, formula
+	}
+	for _, rows := range []any{[]any{}, RowsItems(&fakeRows{columns: []string{"Name", "Amount"}})} {
+		for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+			// The footer moves up, its formula takes the default value and
+			// nothing of the template is left below it
+			out, err := FillBytes(removeTmpl, map[string]any{"rows": rows}, opts...)
+			require.NoError(t, err)
+			o := openOutput(t, out)
+			v, _ := cell(o, "A2")
+			assert.Equal(t, "Total", v)
+			v, formula := cell(o, "B2")
+			assert.Equal(t, []string{"", "SUM(0)"}, []string{v, formula}, "no value is left cached under the formula")
+			for _, ref := range []string{"A3", "B3"} {
+				v, formula = cell(o, ref)
+				assert.Empty(t, v+formula, ref)
+			}
+
+			// emptyAction="CLEAR" keeps a blank, formatted row for the formula
+			out, err = FillBytes(clearTmpl, map[string]any{"rows": []any{}}, opts...)
+			require.NoError(t, err)
+			o = openOutput(t, out)
+			for _, ref := range []string{"A2", "B2"} {
+				v, formula = cell(o, ref)
+				assert.Empty(t, v+formula, ref)
+			}
+			_, style := styleOf(t, o, "Sheet1", "B2")
+			assert.Equal(t, 4, style.NumFmt)
+			v, _ = cell(o, "A3")
+			assert.Equal(t, "Total", v)
+			_, formula = cell(o, "B3")
+			assert.Equal(t, "SUM(B2)", formula)
+		}
+	}
+
+	// Items fill the template as usual, nil values leaving blanks
+	out, err := FillBytes(clearTmpl, map[string]any{"rows": []map[string]any{{"Name": "Alice", "Amount": nil}, {"Name": "Bob", "Amount": 5}}})
+	require.NoError(t, err)
+	got, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Name", "Amount"}, {"Alice"}, {"Bob", "5.00"}, {"Total", ""}}, got)
+
+	// Without clearing template cells the collapsed rows keep the template
+	out, err = FillBytes(removeTmpl, map[string]any{"rows": []any{}}, WithClearTemplateCells(false))
+	require.NoError(t, err)
+	v, _ := cell(openOutput(t, out), "A3")
+	assert.Equal(t, "Total", v)
+
+	_, err = newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "emptyAction": "hide"})
+	assert.ErrorContains(t, err, `invalid emptyAction "hide"`)
+	_, err = newEachCommandFromAttrs(map[string]string{"items": "x", "var": "e", "emptyAction": "CLEAR", "multisheet": "names"})
+	assert.ErrorContains(t, err, "emptyAction CLEAR requires no multisheet")
+}
@@ -313,6 +313,44 @@ func TestRecalculateOnOpen(t *testing.T) {
 	assert.True(t, *props.FullCalcOnLoad)
 }
 
+func TestCalcProps(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${val}")
+	f.SetCellFormula("Sheet1", "B1", "A1+B1/2")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B1")`})
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	outBytes, err := FillBytes(tmpPath, map[string]any{"val": 42}, WithCalcProps(CalcProps{
+		FullCalcOnLoad: true, CalcMode: "manual", Iterative: true, MaxIterations: 50,
+	}))
+	require.NoError(t, err)
+	props, err := openOutput(t, outBytes).GetCalcProps()
+	require.NoError(t, err)
+	require.NotNil(t, props.FullCalcOnLoad)
+	assert.True(t, *props.FullCalcOnLoad)
+	require.NotNil(t, props.CalcMode)
+	assert.Equal(t, "manual", *props.CalcMode)
+	require.NotNil(t, props.Iterate)
+	assert.True(t, *props.Iterate)
+	require.NotNil(t, props.IterateCount)
+	assert.Equal(t, uint(50), *props.IterateCount)
+
+	// Zero fields keep the template's settings
+	outBytes, err = FillBytes(tmpPath, map[string]any{"val": 42}, WithRecalculateOnOpen(true), WithCalcProps(CalcProps{CalcMode: "autoNoTable"}))
+	require.NoError(t, err)
+	props, err = openOutput(t, outBytes).GetCalcProps()
+	require.NoError(t, err)
+	assert.Equal(t, "autoNoTable", *props.CalcMode)
+	assert.True(t, *props.FullCalcOnLoad)
+	assert.True(t, props.Iterate == nil || !*props.Iterate)
+
+	_, err = FillBytes(tmpPath, map[string]any{"val": 42}, WithCalcProps(CalcProps{CalcMode: "sometimes"}))
+	assert.ErrorContains(t, err, "set calculation properties")
+	_, err = FillBytes(tmpPath, map[string]any{"val": 42}, WithCalcProps(CalcProps{MaxIterations: -1}))
+	assert.ErrorContains(t, err, "invalid MaxIterations -1")
+}
+
 func TestRecalculateOnOpen_Default(t *testing.T) {
 	f := excelize.NewFile()
 	sheet := "Sheet1"
@@ -747,6 +785,60 @@ func TestBuiltinRowCol_InFormula(t *testing.T) {
 	assert.Equal(t, "Row 2: Bob", v)
 }
 
+func TestBuiltinPositionVariables_NestedAndFormulas(t *testing.T) {
+	// A1: header; A2:C3 an outer each whose rows hold an if and an inner each
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${_target.CellName()} on ${_sheet}")
+	f.SetCellValue(sheet, "A2", "${g.Name} ${_row}")
+	f.SetCellValue(sheet, "B2", "${_row}/${_col}")
+	f.SetCellFormula(sheet, "C2", "${_row}*10")
+	f.SetCellValue(sheet, "A3", "${m} ${_target}")
+	f.SetCellValue(sheet, "A5", "grid")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C5")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="groups" var="g" lastCell="C3")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "B2", Author: "xlfill", Text: `jx:if(condition="_row % 2 == 0" lastCell="B2")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:each(items="g.Members" var="m" lastCell="A3")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A5", Author: "xlfill", Text: `jx:grid(headers="headers" data="rows" props="Name, _row * 100 + _col" lastCell="A5")`})
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	data := map[string]any{
+		"groups": []map[string]any{
+			{"Name": "G1", "Members": []string{"x", "y"}},
+			{"Name": "G2", "Members": []string{"z"}},
+		},
+		"headers": []string{"Name", "Pos"},
+		"rows":    []map[string]any{{"Name": "r1"}, {"Name": "r2"}},
+	}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		outBytes, err := FillBytes(tmpPath, data, opts...)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+		require.NoError(t, err)
+
+		rows, err := out.GetRows(sheet)
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"A1 on Sheet1"},
+			{"G1 2", "2/1", ""},
+			{"x Sheet1!A3"},
+			{"y Sheet1!A4"},
+			{"G2 5", "", ""}, // _row is odd, so the if renders nothing
+			{"z Sheet1!A6"},
+			nil,
+			{"Name", "Pos"},
+			{"r1", "901"},
+			{"r2", "1001"},
+		}, rows)
+		formula, _ := out.GetCellFormula(sheet, "C2")
+		assert.Equal(t, "2*10", formula)
+		formula, _ = out.GetCellFormula(sheet, "C5")
+		assert.Equal(t, "5*10", formula)
+		out.Close()
+	}
+}
+
 // ============================================================
 // Enhancement: SetCellHyperLink on Transformer
 // ============================================================
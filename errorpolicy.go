@@ -0,0 +1,95 @@
+package xlfill
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorPolicy decides what a fill does when a cell expression fails.
+type ErrorPolicy int
+
+const (
+	// FailFast stops the fill at the first failing cell expression.
+	FailFast ErrorPolicy = iota
+	// CollectAndContinue writes a placeholder into each failing cell, fills
+	// the rest and returns every failure as ExpressionErrors.
+	CollectAndContinue
+)
+
+// defaultErrorPlaceholder is written into failing cells under
+// CollectAndContinue unless WithErrorPlaceholder sets another.
+const defaultErrorPlaceholder = "#ERR"
+
+// ExpressionError is a cell expression that failed under CollectAndContinue.
+type ExpressionError struct {
+	Cell   CellRef // output cell holding the placeholder
+	Source CellRef // template cell
+	Err    error
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s (template %s): %v", e.Cell, e.Source, e.Err)
+}
+
+func (e *ExpressionError) Unwrap() error { return e.Err }
+
+// ExpressionErrors is returned by a fill with CollectAndContinue when cell
+// expressions failed, ordered by output cell. The output is still written.
+type ExpressionErrors []*ExpressionError
+
+func (e ExpressionErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return fmt.Sprintf("%d cell expression(s) failed:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+func (e ExpressionErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// expressionError handles the failure of a cell expression: under FailFast it is
+// returned, under CollectAndContinue it is recorded and the placeholder is
+// the cell's value.
+func (c *Context) expressionError(err error) (any, CellType, error) {
+	if c.state.errorPolicy != CollectAndContinue {
+		return nil, CellBlank, err
+	}
+	ce := &ExpressionError{Err: err}
+	if v, ok := c.lookupRunVar("_target"); ok {
+		ce.Cell, _ = v.(CellRef)
+	}
+	if c.source != nil {
+		ce.Source = *c.source
+	}
+	c.state.mu.Lock()
+	c.state.exprErrors = append(c.state.exprErrors, ce)
+	c.state.mu.Unlock()
+	return c.state.errorPlaceholder, CellString, nil
+}
+
+// collectedErrors returns the cell errors recorded so far, nil if none.
+func (c *Context) collectedErrors() error {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if len(c.state.exprErrors) == 0 {
+		return nil
+	}
+	errs := ExpressionErrors(c.state.exprErrors)
+	sort.SliceStable(errs, func(i, j int) bool { return cellBefore(errs[i].Cell, errs[j].Cell) })
+	return errs
+}
+
+// isExpressionErrors reports whether err only reports failing cells, so the output
+// is complete and is still written.
+func isExpressionErrors(err error) bool {
+	var ce ExpressionErrors
+	return errors.As(err, &ce)
+}
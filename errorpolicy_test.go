@@ -0,0 +1,60 @@
+package xlfill
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWithErrorPolicy(t *testing.T) {
+	tmpl := createValueTemplate(t, "error_policy.xlsx", "${r.Name}", "${r.Tags[0]}", "Tag: ${r.Tags[0]}")
+	data := map[string]any{"rows": []map[string]any{
+		{"Name": "Alice", "Tags": []string{"a"}},
+		{"Name": "Bob", "Tags": []string{}},
+		{"Name": "Carol", "Tags": []string{"c"}},
+	}}
+
+	// Fail fast by default
+	_, err := FillBytes(tmpl, data)
+	require.Error(t, err)
+	assert.False(t, isExpressionErrors(err))
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, append(opts, WithErrorPolicy(CollectAndContinue))...)
+		var exprErrs ExpressionErrors
+		require.True(t, errors.As(err, &exprErrs))
+		require.Len(t, exprErrs, 2)
+		assert.Equal(t, NewCellRef("Sheet1", 1, 1), exprErrs[0].Cell)
+		assert.Equal(t, NewCellRef("Sheet1", 0, 1), exprErrs[0].Source)
+		assert.Equal(t, NewCellRef("Sheet1", 1, 2), exprErrs[1].Cell)
+		assert.Equal(t, NewCellRef("Sheet1", 0, 2), exprErrs[1].Source)
+		assert.Contains(t, err.Error(), "2 cell expression(s) failed")
+		assert.Contains(t, err.Error(), "Sheet1!B2 (template Sheet1!B1)")
+
+		got, err := openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Alice", "a", "Tag: a"},
+			{"Bob", "#ERR", "#ERR"},
+			{"Carol", "c", "Tag: c"},
+		}, got)
+	}
+
+	// Fill writes the output with a custom placeholder
+	path := filepath.Join(testdataDir(t), "error_policy_out.xlsx")
+	err = Fill(tmpl, path, data, WithErrorPolicy(CollectAndContinue), WithErrorPlaceholder("n/a"))
+	assert.True(t, isExpressionErrors(err))
+	_, statErr := os.Stat(path)
+	require.NoError(t, statErr)
+	o, err := excelize.OpenFile(path)
+	require.NoError(t, err)
+	defer o.Close()
+	v, err := o.GetCellValue("Sheet1", "B2")
+	require.NoError(t, err)
+	assert.Equal(t, "n/a", v)
+}
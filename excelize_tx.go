@@ -3,27 +3,68 @@ package xlfill
 import (
 	"fmt"
 	"io"
+	"reflect"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/javajack/xlfill/odsconv"
 	"github.com/xuri/excelize/v2"
 )
 
 // ExcelizeTransformer implements Transformer using excelize.
 type ExcelizeTransformer struct {
-	file       *excelize.File
+	file       *excelize.File        // output workbook
+	src        *excelize.File        // template workbook; same as file unless cross-file
 	sheets     map[string]*SheetData // in-memory sheet data read from template
 	styleCache map[string]int        // "Sheet!A1" → styleID for preservation
+	styleMap   map[int]int           // template styleID → output styleID (cross-file only)
 	targetRefs map[CellRef][]CellRef // source CellRef → list of target positions
+	anchors    map[string]CellRef    // jx:anchor name → output cell, set after the fill
+	mergeTops  map[CellRef]AreaRef   // template merged ranges by top-left cell
+	moved      func(CellRef) CellRef // output position of template cells moved by inserted rows, if any
+	copies     map[string][]string   // sheet → sheets copied from it by CopySheet
+
+	styles     map[string]*excelize.Style // named styles from WithStyles
+	styleRefs  map[string]string          // style name → template cell, from WithStyleFromCell
+	cellStyles map[string]*excelize.Style // styles read from template cells, by name or reference
+	overlays   map[overlayKey]int         // cell style with a named style or format laid over it → styleID
+	numFmts    map[int]bool               // output styleID → whether it sets a number format
+
+	cellWriter CellWriteFunc // middleware of WithCellWriter around writeValue, nil if none
+
+	ods bool // write the output as an OpenDocument spreadsheet
+}
+
+// overlayKey identifies a cell style with a named style or a number format laid over it.
+type overlayKey struct {
+	base   int
+	name   string // named style
+	format string // number format of a typed value such as text()
 }
 
 // NewExcelizeTransformer creates a Transformer from an excelize file.
 func NewExcelizeTransformer(f *excelize.File) (*ExcelizeTransformer, error) {
+	return NewCrossFileTransformer(f, f)
+}
+
+// NewCrossFileTransformer creates a Transformer that reads the template from src
+// and writes output to dst, so areas of one workbook can be applied into another.
+// Cell styles are copied into dst, reusing identical styles dst already has.
+// Target sheets missing from dst are created on first write. Close closes both files.
+func NewCrossFileTransformer(src, dst *excelize.File) (*ExcelizeTransformer, error) {
 	tx := &ExcelizeTransformer{
-		file:       f,
+		file:       dst,
+		src:        src,
 		sheets:     make(map[string]*SheetData),
 		styleCache: make(map[string]int),
+		styleMap:   make(map[int]int),
 		targetRefs: make(map[CellRef][]CellRef),
+		overlays:   make(map[overlayKey]int),
+		numFmts:    make(map[int]bool),
+		cellStyles: make(map[string]*excelize.Style),
+		mergeTops:  make(map[CellRef]AreaRef),
 	}
 	if err := tx.readAllCellData(); err != nil {
 		return nil, fmt.Errorf("read template data: %w", err)
@@ -31,6 +72,94 @@ func NewExcelizeTransformer(f *excelize.File) (*ExcelizeTransformer, error) {
 	return tx, nil
 }
 
+// crossFile reports whether the output workbook differs from the template.
+func (tx *ExcelizeTransformer) crossFile() bool {
+	return tx.src != tx.file
+}
+
+// outputStyle returns the output style ID for a template style ID, copying the
+// style into the output workbook when it is a different file.
+func (tx *ExcelizeTransformer) outputStyle(styleID int) (int, error) {
+	if !tx.crossFile() || styleID == 0 {
+		return styleID, nil
+	}
+	if id, ok := tx.styleMap[styleID]; ok {
+		return id, nil
+	}
+	id, err := copyStyle(tx.src, tx.file, styleID)
+	if err != nil {
+		return 0, err
+	}
+	tx.styleMap[styleID] = id
+	return id, nil
+}
+
+// copyStyle adds the style styleID of src to dst and returns its ID in dst.
+// excelize reuses an identical style dst already has.
+func copyStyle(src, dst *excelize.File, styleID int) (int, error) {
+	style, err := readStyle(src, styleID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := dst.NewStyle(style)
+	if err != nil {
+		return 0, fmt.Errorf("copy style %d: %w", styleID, err)
+	}
+	return id, nil
+}
+
+// readStyle returns the style styleID of f.
+func readStyle(f *excelize.File, styleID int) (*excelize.Style, error) {
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		return nil, fmt.Errorf("read style %d: %w", styleID, err)
+	}
+	// GetStyle reports "no fill" as an empty pattern fill, which would not match
+	// an equivalent style already in the workbook
+	if style.Fill.Type == "pattern" && style.Fill.Pattern == 0 && len(style.Fill.Color) == 0 {
+		style.Fill = excelize.Fill{}
+	}
+	return style, nil
+}
+
+// overlayStyle returns base with the parts set in over replacing its own.
+func overlayStyle(base, over *excelize.Style) *excelize.Style {
+	s := *base
+	if over.Fill.Type != "" {
+		s.Fill = over.Fill
+	}
+	if over.Font != nil {
+		s.Font = over.Font
+	}
+	if len(over.Border) > 0 {
+		s.Border = over.Border
+	}
+	if over.Alignment != nil {
+		s.Alignment = over.Alignment
+	}
+	if over.Protection != nil {
+		s.Protection = over.Protection
+	}
+	if over.NumFmt != 0 || over.CustomNumFmt != nil {
+		s.NumFmt, s.CustomNumFmt, s.DecimalPlaces = over.NumFmt, over.CustomNumFmt, over.DecimalPlaces
+	}
+	return &s
+}
+
+// ensureSheet creates a missing target sheet in a cross-file output workbook.
+func (tx *ExcelizeTransformer) ensureSheet(sheet string) error {
+	if !tx.crossFile() {
+		return nil
+	}
+	if idx, err := tx.file.GetSheetIndex(sheet); err == nil && idx >= 0 {
+		return nil
+	}
+	if _, err := tx.file.NewSheet(sheet); err != nil {
+		return fmt.Errorf("create sheet %q: %w", sheet, err)
+	}
+	return nil
+}
+
 // OpenTemplate opens an xlsx file and creates a Transformer.
 func OpenTemplate(path string) (*ExcelizeTransformer, error) {
 	f, err := excelize.OpenFile(path)
@@ -42,15 +171,15 @@ func OpenTemplate(path string) (*ExcelizeTransformer, error) {
 
 // readAllCellData reads all cell data from the template into memory.
 func (tx *ExcelizeTransformer) readAllCellData() error {
-	for _, sheet := range tx.file.GetSheetList() {
+	for _, sheet := range tx.src.GetSheetList() {
 		sd := &SheetData{
 			Name:         sheet,
 			ColumnWidths: make(map[int]float64),
 			Rows:         make(map[int]*RowData),
 		}
 
-		// Read all rows
-		rows, err := tx.file.GetRows(sheet)
+		// Read all rows, with numbers unformatted so they are copied as numbers
+		rows, err := tx.src.GetRows(sheet, excelize.Options{RawCellValue: true})
 		if err != nil {
 			return fmt.Errorf("read rows from sheet %q: %w", sheet, err)
 		}
@@ -64,7 +193,7 @@ func (tx *ExcelizeTransformer) readAllCellData() error {
 		}
 		// Read column widths only for columns that have data
 		for i := 0; i < maxCols; i++ {
-			w, err := tx.file.GetColWidth(sheet, ColToName(i))
+			w, err := tx.src.GetColWidth(sheet, ColToName(i))
 			if err == nil {
 				sd.ColumnWidths[i] = w
 			}
@@ -74,7 +203,7 @@ func (tx *ExcelizeTransformer) readAllCellData() error {
 			rd := &RowData{
 				Cells: make(map[int]*CellData),
 			}
-			h, err := tx.file.GetRowHeight(sheet, rowIdx+1)
+			h, err := tx.src.GetRowHeight(sheet, rowIdx+1)
 			if err == nil {
 				rd.Height = h
 			}
@@ -90,22 +219,23 @@ func (tx *ExcelizeTransformer) readAllCellData() error {
 				}
 
 				// Detect formula
-				formula, err := tx.file.GetCellFormula(sheet, cellName)
+				formula, err := tx.src.GetCellFormula(sheet, cellName)
 				if err == nil && formula != "" {
 					cd.Formula = formula
 					cd.Type = CellFormula
 				}
 
 				// Cache style
-				styleID, err := tx.file.GetCellStyle(sheet, cellName)
+				styleID, err := tx.src.GetCellStyle(sheet, cellName)
 				if err == nil {
 					cd.StyleID = styleID
 					tx.styleCache[ref.String()] = styleID
 				}
 
-				// Detect cell type from value if not formula
+				// Keep the type of static values: numbers and booleans stay
+				// numbers and booleans, numeric text such as "0123" stays text
 				if cd.Type != CellFormula {
-					cd.Type = detectCellType(cellVal)
+					cd.Value, cd.Type = tx.templateValue(sheet, cellName, cellVal)
 				}
 
 				rd.Cells[colIdx] = cd
@@ -113,9 +243,10 @@ func (tx *ExcelizeTransformer) readAllCellData() error {
 
 			sd.Rows[rowIdx] = rd
 		}
+		tx.readArrayFormulas(sheet, sd)
 
 		// Read comments
-		comments, err := tx.file.GetComments(sheet)
+		comments, err := tx.src.GetComments(sheet)
 		if err == nil {
 			for _, c := range comments {
 				ref, err := ParseCellRef(sheet + "!" + c.Cell)
@@ -133,7 +264,32 @@ func (tx *ExcelizeTransformer) readAllCellData() error {
 					cd = &CellData{Ref: ref, Type: CellBlank}
 					rd.Cells[ref.Col] = cd
 				}
-				cd.Comment = c.Text
+				cd.Comment, cd.CommentAuthor = c.Text, c.Author
+			}
+		}
+
+		// Read merged cells. The top-left cell of each merge gets cell data even
+		// when empty, so the merge is recreated wherever the cell is written.
+		merged, err := tx.src.GetMergeCells(sheet)
+		if err == nil {
+			for _, m := range merged {
+				area, err := ParseAreaRef(sheet + "!" + m.GetStartAxis() + ":" + m.GetEndAxis())
+				if err != nil {
+					continue
+				}
+				sd.MergedCells = append(sd.MergedCells, area)
+				tx.mergeTops[area.First] = area
+				rd, ok := sd.Rows[area.First.Row]
+				if !ok {
+					rd = &RowData{Cells: make(map[int]*CellData)}
+					sd.Rows[area.First.Row] = rd
+				}
+				if _, ok := rd.Cells[area.First.Col]; !ok {
+					rd.Cells[area.First.Col] = &CellData{Ref: area.First, Type: CellBlank}
+					if styleID, err := tx.src.GetCellStyle(sheet, area.First.CellName()); err == nil {
+						tx.styleCache[area.First.String()] = styleID
+					}
+				}
 			}
 		}
 
@@ -149,6 +305,30 @@ func detectCellType(val string) CellType {
 	return CellString
 }
 
+// templateValue returns the value of a template cell read as raw text, typed
+// as the cell is stored.
+func (tx *ExcelizeTransformer) templateValue(sheet, cell, raw string) (any, CellType) {
+	cellType := detectCellType(raw)
+	if cellType == CellBlank {
+		return raw, cellType
+	}
+	t, err := tx.src.GetCellType(sheet, cell)
+	if err != nil {
+		return raw, cellType
+	}
+	switch t {
+	case excelize.CellTypeUnset, excelize.CellTypeNumber:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n, CellNumber
+		}
+	case excelize.CellTypeBool:
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b, CellBoolean
+		}
+	}
+	return raw, cellType
+}
+
 // GetCellData returns the cached cell data for the given reference.
 func (tx *ExcelizeTransformer) GetCellData(ref CellRef) *CellData {
 	sd, ok := tx.sheets[ref.Sheet]
@@ -162,7 +342,136 @@ func (tx *ExcelizeTransformer) GetCellData(ref CellRef) *CellData {
 	return rd.Cells[ref.Col]
 }
 
-// GetCommentedCells returns all cells that have comments (for template parsing).
+// templateRow returns the template cells of a row by column, nil for a row
+// without cells.
+func (tx *ExcelizeTransformer) templateRow(sheet string, row int) map[int]*CellData {
+	sd, ok := tx.sheets[sheet]
+	if !ok {
+		return nil
+	}
+	rd, ok := sd.Rows[row]
+	if !ok {
+		return nil
+	}
+	return rd.Cells
+}
+
+// GetMergedRange returns the merged range of the template whose top-left cell is ref.
+func (tx *ExcelizeTransformer) GetMergedRange(ref CellRef) (AreaRef, bool) {
+	m, ok := tx.mergeTops[ref]
+	return m, ok
+}
+
+// insertRows inserts count empty rows before the 0-based row of sheet, moving
+// the rows below down along with the references, merges, pictures and
+// comments in them.
+func (tx *ExcelizeTransformer) insertRows(sheet string, row, count int) error {
+	if err := tx.file.InsertRows(sheet, row+1, count); err != nil {
+		return fmt.Errorf("insert %d rows at row %d of sheet %s: %w", count, row+1, sheet, err)
+	}
+
+	// excelize leaves comments where they were
+	comments, err := tx.file.GetComments(sheet)
+	if err != nil {
+		return fmt.Errorf("read comments of sheet %s: %w", sheet, err)
+	}
+	var moved []excelize.Comment
+	for _, c := range comments {
+		ref, err := ParseCellRef(c.Cell)
+		if err != nil || ref.Row < row {
+			continue
+		}
+		if err := tx.file.DeleteComment(sheet, c.Cell); err != nil {
+			return fmt.Errorf("move comment %s: %w", c.Cell, err)
+		}
+		c.Cell = NewCellRef("", ref.Row+count, ref.Col).CellName()
+		moved = append(moved, c)
+	}
+	for _, c := range moved {
+		if err := tx.file.AddComment(sheet, c); err != nil {
+			return fmt.Errorf("move comment to %s: %w", c.Cell, err)
+		}
+	}
+	return nil
+}
+
+// removeRows deletes the output rows of sheet, sorted, and moves the content
+// below them up. Formulas, merges and defined names are adjusted by excelize;
+// comments and the recorded targets of template cells are adjusted here.
+func (tx *ExcelizeTransformer) removeRows(sheet string, rows []int) error {
+	for i := len(rows) - 1; i >= 0; i-- {
+		if err := tx.file.RemoveRow(sheet, rows[i]+1); err != nil {
+			return fmt.Errorf("remove row %d of sheet %s: %w", rows[i]+1, sheet, err)
+		}
+	}
+
+	// excelize leaves comments where they were
+	comments, err := tx.file.GetComments(sheet)
+	if err != nil {
+		return fmt.Errorf("read comments of sheet %s: %w", sheet, err)
+	}
+	var moved []excelize.Comment
+	for _, c := range comments {
+		ref, err := ParseCellRef(c.Cell)
+		if err != nil || ref.Row < rows[0] {
+			continue
+		}
+		if err := tx.file.DeleteComment(sheet, c.Cell); err != nil {
+			return fmt.Errorf("move comment %s: %w", c.Cell, err)
+		}
+		if _, found := slices.BinarySearch(rows, ref.Row); found {
+			continue
+		}
+		c.Cell = shiftRemovedRows(ref, rows).CellName()
+		moved = append(moved, c)
+	}
+	for _, c := range moved {
+		if err := tx.file.AddComment(sheet, c); err != nil {
+			return fmt.Errorf("move comment to %s: %w", c.Cell, err)
+		}
+	}
+
+	for src, targets := range tx.targetRefs {
+		kept := targets[:0]
+		for _, t := range targets {
+			if t.Sheet != sheet {
+				kept = append(kept, t)
+				continue
+			}
+			if _, found := slices.BinarySearch(rows, t.Row); !found {
+				kept = append(kept, shiftRemovedRows(t, rows))
+			}
+		}
+		tx.targetRefs[src] = kept
+	}
+	return nil
+}
+
+// unmergeTemplate removes the template's merges with their top-left cell in
+// area from the output workbook. Writing the area recreates them at the
+// cells' targets, so merges follow rows moved or repeated by commands instead
+// of staying where the template had them.
+func (tx *ExcelizeTransformer) unmergeTemplate(area AreaRef) error {
+	if tx.crossFile() {
+		return nil
+	}
+	sd, ok := tx.sheets[area.First.Sheet]
+	if !ok {
+		return nil
+	}
+	for _, m := range sd.MergedCells {
+		if !area.Contains(m.First) {
+			continue
+		}
+		if err := tx.file.UnmergeCell(m.First.Sheet, m.First.CellName(), m.Last.CellName()); err != nil {
+			return fmt.Errorf("unmerge %s: %w", m, err)
+		}
+	}
+	return nil
+}
+
+// GetCommentedCells returns all cells that have comments (for template
+// parsing), ordered by sheet, row and column.
 func (tx *ExcelizeTransformer) GetCommentedCells() []*CellData {
 	var result []*CellData
 	for _, sd := range tx.sheets {
@@ -174,10 +483,37 @@ func (tx *ExcelizeTransformer) GetCommentedCells() []*CellData {
 			}
 		}
 	}
-	return result
+	return sortCellData(result)
+}
+
+// GetCellsWithPrefix returns all cells whose text value starts with prefix,
+// ignoring leading whitespace (for in-cell command markers), ordered by
+// sheet, row and column.
+func (tx *ExcelizeTransformer) GetCellsWithPrefix(prefix string) []*CellData {
+	var result []*CellData
+	for _, sd := range tx.sheets {
+		for _, rd := range sd.Rows {
+			for _, cd := range rd.Cells {
+				if s, ok := cd.Value.(string); ok && strings.HasPrefix(strings.TrimSpace(s), prefix) {
+					result = append(result, cd)
+				}
+			}
+		}
+	}
+	return sortCellData(result)
+}
+
+// GetDefinedNames returns the workbook's defined names mapped to the ranges they refer to.
+func (tx *ExcelizeTransformer) GetDefinedNames() map[string]string {
+	names := make(map[string]string)
+	for _, dn := range tx.src.GetDefinedName() {
+		names[dn.Name] = dn.RefersTo
+	}
+	return names
 }
 
-// GetFormulaCells returns all cells that contain formulas.
+// GetFormulaCells returns all cells that contain formulas, ordered by sheet,
+// row and column, so formulas are rewritten in the same order on every fill.
 func (tx *ExcelizeTransformer) GetFormulaCells() []*CellData {
 	var result []*CellData
 	for _, sd := range tx.sheets {
@@ -189,10 +525,26 @@ func (tx *ExcelizeTransformer) GetFormulaCells() []*CellData {
 			}
 		}
 	}
-	return result
+	return sortCellData(result)
+}
+
+// sortCellData orders cells by sheet, row and column.
+func sortCellData(cells []*CellData) []*CellData {
+	slices.SortFunc(cells, func(a, b *CellData) int {
+		switch {
+		case cellBefore(a.Ref, b.Ref):
+			return -1
+		case cellBefore(b.Ref, a.Ref):
+			return 1
+		}
+		return 0
+	})
+	return cells
 }
 
 // Transform copies a cell from source to target position, evaluating expressions.
+// Expressions are evaluated immediately; when ctx defers writes (concurrent
+// processing) the write itself is queued on ctx.
 func (tx *ExcelizeTransformer) Transform(src, target CellRef, ctx *Context, updateRowHeight bool) error {
 
 	srcData := tx.GetCellData(src)
@@ -200,14 +552,71 @@ func (tx *ExcelizeTransformer) Transform(src, target CellRef, ctx *Context, upda
 		return nil // nothing to transform
 	}
 
+	ev, err := evaluateCell(srcData, ctx)
+	if err != nil {
+		return fmt.Errorf("transform cell %s: %w", src, err)
+	}
+	return ctx.run(func() error {
+		return tx.writeCell(src, target, srcData, ev, updateRowHeight)
+	})
+}
+
+// cellEval is the evaluated content of a template cell for one target.
+type cellEval struct {
+	formula   string        // formula to write, with ${...} parameters resolved
+	spans     []formulaSpan // positions of the resolved parameters in formula
+	hasValue  bool          // value holds an evaluated expression
+	value     any           // evaluated expression value
+	valueType CellType      // type of value
+	style     string        // named style from styled(), laid over the cell style
+}
+
+// evaluateCell evaluates a template cell's expressions without writing anything.
+func evaluateCell(srcData *CellData, ctx *Context) (cellEval, error) {
+	if ctx.blank {
+		return cellEval{hasValue: true, valueType: CellBlank}, nil
+	}
+
+	// Formula cells: substitute ${...} parameters before references are processed
+	if srcData.IsFormulaCell() {
+		formula, spans := substituteFormulaParams(srcData.Formula, ctx)
+		return cellEval{formula: formula, spans: spans}, nil
+	}
+
+	// Expression cells
+	strVal, isStr := srcData.Value.(string)
+	if isStr && strings.Contains(strVal, ctx.notationBegin) {
+		val, cellType, err := ctx.EvaluateCellValue(strVal)
+		if err != nil {
+			return cellEval{}, err
+		}
+		if sv, ok := val.(StyledValue); ok {
+			v, cellType := ctx.convertValue(sv.Value)
+			return cellEval{hasValue: true, value: v, valueType: cellType, style: sv.Style}, nil
+		}
+		return cellEval{hasValue: true, value: val, valueType: cellType}, nil
+	}
+	return cellEval{}, nil
+}
+
+// writeCell writes an evaluated template cell to target, copying style, column
+// width and row height, and records the target for formula processing.
+func (tx *ExcelizeTransformer) writeCell(src, target CellRef, srcData *CellData, ev cellEval, updateRowHeight bool) error {
 	targetSheet := target.Sheet
 	if targetSheet == "" {
 		targetSheet = src.Sheet
 	}
 	targetCell := target.CellName()
+	if err := tx.ensureSheet(targetSheet); err != nil {
+		return err
+	}
 
 	// Copy style from source
 	if styleID, ok := tx.styleCache[src.String()]; ok {
+		styleID, err := tx.outputStyle(styleID)
+		if err != nil {
+			return err
+		}
 		tx.file.SetCellStyle(targetSheet, targetCell, targetCell, styleID)
 	}
 
@@ -215,7 +624,20 @@ func (tx *ExcelizeTransformer) Transform(src, target CellRef, ctx *Context, upda
 	sd, ok := tx.sheets[src.Sheet]
 	if ok {
 		if w, ok := sd.ColumnWidths[src.Col]; ok {
-			tx.file.SetColWidth(targetSheet, ColToName(target.Col), ColToName(target.Col), w)
+			// Rows of a jx:each repeat the same width; setting it again is costly
+			col := ColToName(target.Col)
+			if cur, err := tx.file.GetColWidth(targetSheet, col); err != nil || cur != w {
+				tx.file.SetColWidth(targetSheet, col, col, w)
+			}
+		}
+	}
+
+	// Recreate a merge the source cell starts at the target
+	if m, ok := tx.GetMergedRange(src); ok {
+		size := m.Size()
+		last := NewCellRef(targetSheet, target.Row+size.Height-1, target.Col+size.Width-1)
+		if err := tx.file.MergeCell(targetSheet, targetCell, last.CellName()); err != nil {
+			return fmt.Errorf("merge %s:%s: %w", targetCell, last.CellName(), err)
 		}
 	}
 
@@ -227,45 +649,36 @@ func (tx *ExcelizeTransformer) Transform(src, target CellRef, ctx *Context, upda
 	}
 
 	// Handle formula cells
-	if srcData.IsFormulaCell() {
-		formula := srcData.Formula
-		// Parameterized formulas: substitute ${...} expressions within formulas
-		if strings.Contains(formula, ctx.notationBegin) {
-			resolved, _, err := ctx.EvaluateCellValue(formula)
-			if err == nil && resolved != nil {
-				formula = fmt.Sprintf("%v", resolved)
-			}
+	if srcData.IsFormulaCell() && !ev.hasValue {
+		// Drop the value a template cell left at the target, which Excel would
+		// show as the formula's cached result
+		tx.file.SetCellValue(targetSheet, targetCell, nil)
+		if err := tx.setFormula(NewCellRef(targetSheet, target.Row, target.Col), ev.formula, srcData.ArraySize); err != nil {
+			return err
 		}
-		tx.file.SetCellFormula(targetSheet, targetCell, formula)
+		srcData.EvalFormulas = append(srcData.EvalFormulas, ev.formula)
+		srcData.evalSpans = append(srcData.evalSpans, ev.spans)
 		srcData.AddTargetPos(target)
 		tx.addTargetRef(src, target)
 		return nil
 	}
 
-	// Handle expression cells
-	strVal, isStr := srcData.Value.(string)
-	if isStr && strings.Contains(strVal, ctx.notationBegin) {
-		val, cellType, err := ctx.EvaluateCellValue(strVal)
-		if err != nil {
-			return fmt.Errorf("transform cell %s: %w", src, err)
-		}
-		srcData.EvalResult = val
-		srcData.TargetCellType = cellType
-
-		// Handle HyperlinkValue
-		if hv, ok := val.(HyperlinkValue); ok {
-			tx.file.SetCellValue(targetSheet, targetCell, hv.String())
-			linkType := "External"
-			if strings.HasPrefix(hv.URL, "#") || (!strings.Contains(hv.URL, "://") && !strings.HasPrefix(hv.URL, "mailto:") && strings.Contains(hv.URL, "!")) {
-				linkType = "Location"
-			}
-			tx.file.SetCellHyperLink(targetSheet, targetCell, hv.URL, linkType)
-		} else if err := tx.writeTypedValue(targetSheet, targetCell, val, cellType); err != nil {
-			return err
-		}
+	// Write the evaluated or copied value, through the WithCellWriter middleware
+	w := CellWrite{Target: NewCellRef(targetSheet, target.Row, target.Col), Source: src}
+	if ev.hasValue {
+		w.Value, w.Type, w.Style, w.Evaluated = ev.value, ev.valueType, ev.style, true
+		srcData.EvalResult = ev.value
+		srcData.TargetCellType = ev.valueType
 	} else {
 		// Copy value as-is
-		tx.file.SetCellValue(targetSheet, targetCell, srcData.Value)
+		w.Value, w.Type = srcData.Value, srcData.Type
+	}
+	write := tx.writeValue
+	if tx.cellWriter != nil {
+		write = tx.cellWriter
+	}
+	if err := write(w); err != nil {
+		return err
 	}
 
 	srcData.AddTargetPos(target)
@@ -273,10 +686,53 @@ func (tx *ExcelizeTransformer) Transform(src, target CellRef, ctx *Context, upda
 	return nil
 }
 
+// writeValue writes the value of a template cell to its output cell.
+func (tx *ExcelizeTransformer) writeValue(w CellWrite) error {
+	sheet, cell := w.Target.Sheet, w.Target.CellName()
+	if !w.Evaluated {
+		return tx.file.SetCellValue(sheet, cell, w.Value)
+	}
+
+	// Handle HyperlinkValue
+	if hv, ok := w.Value.(HyperlinkValue); ok {
+		tx.file.SetCellValue(sheet, cell, hv.String())
+		linkType := "External"
+		if strings.HasPrefix(hv.URL, "#") || (!strings.Contains(hv.URL, "://") && !strings.HasPrefix(hv.URL, "mailto:") && strings.Contains(hv.URL, "!")) {
+			linkType = "Location"
+		}
+		tx.file.SetCellHyperLink(sheet, cell, hv.URL, linkType)
+	} else if tv, ok := w.Value.(TextValue); ok {
+		if err := tx.file.SetCellStr(sheet, cell, tv.Text); err != nil {
+			return err
+		}
+		if err := tx.overlayCellStyle(w.Target, overlayKey{format: "@"}, &excelize.Style{NumFmt: 49}); err != nil {
+			return err
+		}
+	} else if nv, ok := w.Value.(numberFormatter); ok {
+		if err := tx.file.SetCellFloat(sheet, cell, nv.number(), -1, 64); err != nil {
+			return err
+		}
+		format := nv.numberFormat()
+		if err := tx.overlayCellStyle(w.Target, overlayKey{format: format}, &excelize.Style{CustomNumFmt: &format}); err != nil {
+			return err
+		}
+	} else if dv, ok := w.Value.(DecimalValue); ok {
+		if err := tx.writeDecimal(w.Target, dv); err != nil {
+			return err
+		}
+	} else if err := tx.writeTypedValue(sheet, cell, w.Value, w.Type); err != nil {
+		return err
+	}
+	if w.Style != "" {
+		return tx.ApplyStyle(w.Target, w.Style)
+	}
+	return nil
+}
+
 // writeTypedValue writes a value to a cell with the correct type.
 func (tx *ExcelizeTransformer) writeTypedValue(sheet, cell string, value any, cellType CellType) error {
 	if value == nil {
-		return nil // leave cell blank
+		return tx.file.SetCellValue(sheet, cell, nil) // blank, dropping what the template had there
 	}
 	switch cellType {
 	case CellFormula:
@@ -286,6 +742,37 @@ func (tx *ExcelizeTransformer) writeTypedValue(sheet, cell string, value any, ce
 	}
 }
 
+// writeDecimal writes d as a number cell with its exact digits. Cells whose
+// style has no number format are shown with d's decimals.
+func (tx *ExcelizeTransformer) writeDecimal(ref CellRef, d DecimalValue) error {
+	places, ok := d.places()
+	if !ok {
+		return fmt.Errorf("write %s: invalid decimal %q", ref, d.Text)
+	}
+	cell := ref.CellName()
+	if err := tx.file.SetCellDefault(ref.Sheet, cell, d.Text); err != nil {
+		return err
+	}
+	base, err := tx.file.GetCellStyle(ref.Sheet, cell)
+	if err != nil {
+		return fmt.Errorf("read style of %s: %w", ref, err)
+	}
+	formatted, ok := tx.numFmts[base]
+	if !ok && base != 0 {
+		style, err := readStyle(tx.file, base)
+		if err != nil {
+			return err
+		}
+		formatted = style.NumFmt != 0 || style.CustomNumFmt != nil
+		tx.numFmts[base] = formatted
+	}
+	if formatted {
+		return nil
+	}
+	format := decimalFormat(places)
+	return tx.overlayCellStyle(ref, overlayKey{format: format}, &excelize.Style{CustomNumFmt: &format})
+}
+
 // ClearCell clears a cell's content while preserving style.
 func (tx *ExcelizeTransformer) ClearCell(ref CellRef) error {
 
@@ -307,24 +794,148 @@ func (tx *ExcelizeTransformer) SetFormula(ref CellRef, formula string) error {
 	return tx.file.SetCellFormula(ref.Sheet, ref.CellName(), formula)
 }
 
-// SetCellValue sets a value on a cell, preserving style.
+// SetCellValue sets a value on a cell, preserving style. The value is written
+// through the WithCellWriter middleware, so redaction also covers the values
+// commands such as jx:grid and jx:pivot write.
 func (tx *ExcelizeTransformer) SetCellValue(ref CellRef, value any) error {
 
 	sheet := ref.Sheet
 	cell := ref.CellName()
 	styleID, _ := tx.file.GetCellStyle(sheet, cell)
-	tx.file.SetCellValue(sheet, cell, value)
+	write := tx.writeValue
+	if tx.cellWriter != nil {
+		write = tx.cellWriter
+	}
+	if err := write(CellWrite{Target: ref, Source: ref, Value: value, Type: inferCellType(value), Evaluated: true}); err != nil {
+		return err
+	}
 	if styleID > 0 {
 		tx.file.SetCellStyle(sheet, cell, cell, styleID)
 	}
 	return nil
 }
 
+// ApplyStyle lays a style over the current style of a cell. name is a style
+// registered with WithStyles or WithStyleFromCell, or a template cell reference
+// such as "Styles!A1". Each resulting style is added to the workbook once.
+func (tx *ExcelizeTransformer) ApplyStyle(ref CellRef, name string) error {
+	over, err := tx.namedStyle(name)
+	if err != nil {
+		return err
+	}
+	return tx.overlayCellStyle(ref, overlayKey{name: name}, over)
+}
+
+// overlayCellStyle lays over on the current style of a cell. The style for each
+// combination of cell style and key is created once.
+func (tx *ExcelizeTransformer) overlayCellStyle(ref CellRef, key overlayKey, over *excelize.Style) error {
+	if err := tx.ensureSheet(ref.Sheet); err != nil {
+		return err
+	}
+	cell := ref.CellName()
+	base, err := tx.file.GetCellStyle(ref.Sheet, cell)
+	if err != nil {
+		return fmt.Errorf("read style of %s: %w", ref, err)
+	}
+	key.base = base
+	id, ok := tx.overlays[key]
+	if !ok {
+		style := overlayStyle(&excelize.Style{}, over)
+		if base != 0 {
+			current, err := readStyle(tx.file, base)
+			if err != nil {
+				return err
+			}
+			style = overlayStyle(current, over)
+		}
+		if id, err = tx.file.NewStyle(style); err != nil {
+			return fmt.Errorf("create style for %s: %w", ref, err)
+		}
+		tx.overlays[key] = id
+	}
+	return tx.file.SetCellStyle(ref.Sheet, cell, cell, id)
+}
+
+// namedStyle resolves a registered style name or a template cell reference.
+// Styles of template cells are read once.
+func (tx *ExcelizeTransformer) namedStyle(name string) (*excelize.Style, error) {
+	if style, ok := tx.styles[name]; ok {
+		return style, nil
+	}
+	if style, ok := tx.cellStyles[name]; ok {
+		return style, nil
+	}
+	cellRef, registered := tx.styleRefs[name]
+	if !registered {
+		cellRef = name
+	}
+	ref, err := ParseCellRef(cellRef)
+	if err != nil || ref.Sheet == "" {
+		if registered {
+			return nil, fmt.Errorf("style %q: %q is not a cell reference with a sheet name", name, cellRef)
+		}
+		return nil, fmt.Errorf("unknown style %q", name)
+	}
+	id, err := tx.src.GetCellStyle(ref.Sheet, ref.CellName())
+	if err != nil {
+		return nil, fmt.Errorf("read style of %s: %w", cellRef, err)
+	}
+	style, err := cellStyleOverlay(tx.src, id)
+	if err != nil {
+		return nil, err
+	}
+	tx.cellStyles[name] = style
+	return style, nil
+}
+
+// cellStyleOverlay returns style styleID of f without the parts it shares with
+// the workbook's default style, so laying it over a cell changes only what the
+// style sets, e.g. the fill of a stripe cell.
+func cellStyleOverlay(f *excelize.File, styleID int) (*excelize.Style, error) {
+	style, err := readStyle(f, styleID)
+	if err != nil {
+		return nil, err
+	}
+	def, err := readStyle(f, 0)
+	if err != nil {
+		return nil, err
+	}
+	if reflect.DeepEqual(style.Fill, def.Fill) {
+		style.Fill = excelize.Fill{}
+	}
+	if reflect.DeepEqual(style.Font, def.Font) {
+		style.Font = nil
+	}
+	if reflect.DeepEqual(style.Border, def.Border) {
+		style.Border = nil
+	}
+	if reflect.DeepEqual(style.Alignment, def.Alignment) {
+		style.Alignment = nil
+	}
+	if reflect.DeepEqual(style.Protection, def.Protection) {
+		style.Protection = nil
+	}
+	if style.NumFmt == def.NumFmt && style.CustomNumFmt == nil {
+		style.NumFmt = 0
+	}
+	return style, nil
+}
+
 // GetTargetCellRef returns where a source cell was mapped to during transformation.
 func (tx *ExcelizeTransformer) GetTargetCellRef(src CellRef) []CellRef {
 	return tx.targetRefs[src]
 }
 
+// TargetMap returns a copy of where each source cell was mapped to, e.g. for
+// ProcessFormulas.
+func (tx *ExcelizeTransformer) TargetMap() TargetMap {
+	m := make(TargetMap, len(tx.targetRefs))
+	for src, targets := range tx.targetRefs {
+		m[src] = slices.Clone(targets)
+	}
+	return m
+}
+
 // ResetTargetCellRefs clears all source→target mappings.
 func (tx *ExcelizeTransformer) ResetTargetCellRefs() {
 	tx.targetRefs = make(map[CellRef][]CellRef)
@@ -362,6 +973,136 @@ func (tx *ExcelizeTransformer) SetRowHeight(sheet string, row int, height float6
 	return tx.file.SetRowHeight(sheet, row+1, height)
 }
 
+// FitRowHeight sets the height of a row (0-based index) to fit the text of its
+// cells from firstCol to lastCol. Wrapped cells take as many lines as their text
+// needs at their column width (the merged width for merged cells) and font
+// size; other cells take one line. Cells merged over several rows are ignored,
+// as in Excel, and a row without text keeps its height.
+func (tx *ExcelizeTransformer) FitRowHeight(sheet string, row, firstCol, lastCol int, fit RowFit) error {
+	merges, err := tx.file.GetMergeCells(sheet)
+	if err != nil {
+		return err
+	}
+	height := 0.0
+	for col := firstCol; col <= lastCol; col++ {
+		cell := CellRef{Row: row, Col: col}.CellName()
+		text, err := tx.file.GetCellValue(sheet, cell)
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			continue
+		}
+		width := tx.GetColumnWidth(sheet, col)
+		if span, ok := mergeSpan(merges, row, col); ok {
+			if span.First.Row != span.Last.Row || span.First.Col != col {
+				continue
+			}
+			for c := col + 1; c <= span.Last.Col; c++ {
+				width += tx.GetColumnWidth(sheet, c)
+			}
+		}
+
+		size, lines := defaultFontSize, 1
+		styleID, _ := tx.file.GetCellStyle(sheet, cell)
+		style, _ := tx.file.GetStyle(styleID)
+		if style != nil && style.Font != nil && style.Font.Size > 0 {
+			size = style.Font.Size
+		}
+		if style != nil && style.Alignment != nil && style.Alignment.WrapText {
+			chars := width * defaultFontSize / size
+			if style.Font != nil && style.Font.Bold {
+				chars /= boldWidthPercent
+			}
+			lines = wrappedLines(text, chars)
+		}
+		height = max(height, fit.rowHeight(lines, size))
+	}
+	if height == 0 {
+		return nil
+	}
+	return tx.file.SetRowHeight(sheet, row+1, height)
+}
+
+// mergeSpan returns the merged range containing a cell (0-based indexes).
+func mergeSpan(merges []excelize.MergeCell, row, col int) (AreaRef, bool) {
+	for _, m := range merges {
+		ref, err := ParseAreaRef(m.GetStartAxis() + ":" + m.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		if row >= ref.First.Row && row <= ref.Last.Row && col >= ref.First.Col && col <= ref.Last.Col {
+			return ref, true
+		}
+	}
+	return AreaRef{}, false
+}
+
+// SetRowOutlineLevel sets the outline level of a row (0-based row index). A
+// deeper level already set, e.g. by a nested group, is kept.
+func (tx *ExcelizeTransformer) SetRowOutlineLevel(sheet string, row int, level uint8) error {
+	if cur, err := tx.file.GetRowOutlineLevel(sheet, row+1); err == nil && cur >= level {
+		return nil
+	}
+	return tx.file.SetRowOutlineLevel(sheet, row+1, level)
+}
+
+// SetOutlineSummaryBelow sets whether the summary rows of a sheet's outline
+// are below their detail rows (Excel's default) or above them.
+func (tx *ExcelizeTransformer) SetOutlineSummaryBelow(sheet string, below bool) error {
+	return tx.file.SetSheetProps(sheet, &excelize.SheetPropsOptions{OutlineSummaryBelow: &below})
+}
+
+// SetAutoFilter adds an AutoFilter over area, whose first row holds the
+// column headers, and records a sort of its data rows by sortCol (0-based) in
+// the sheet's sortState, so Excel marks that column's filter button as sorted.
+// The rows themselves are not reordered.
+func (tx *ExcelizeTransformer) SetAutoFilter(area AreaRef, sortCol int, descending bool) error {
+	sheet := area.First.Sheet
+	if sortCol < area.First.Col || sortCol > area.Last.Col {
+		return fmt.Errorf("sort column %s is outside %s", ColToName(sortCol), area)
+	}
+	if _, err := tx.file.GetSheetProps(sheet); err != nil {
+		return err
+	}
+	ws, _, err := tx.worksheet(sheet)
+	if err != nil {
+		return err
+	}
+	// AutoFilter replaces the sheet properties and marks the sheet filtered;
+	// keep the properties already set
+	props := ws.FieldByName("SheetPr")
+	saved, filtered := props.Elem(), false
+	if !props.IsNil() {
+		filtered = saved.FieldByName("FilterMode").Bool()
+	}
+	if err := tx.file.AutoFilter(sheet, area.First.CellName()+":"+area.Last.CellName(), nil); err != nil {
+		return err
+	}
+	if saved.IsValid() {
+		saved.FieldByName("FilterMode").SetBool(filtered)
+		props.Set(saved.Addr())
+	} else {
+		props.SetZero()
+	}
+	if area.Last.Row == area.First.Row {
+		return nil
+	}
+
+	first := CellRef{Row: area.First.Row + 1, Col: area.First.Col}
+	key := CellRef{Row: first.Row, Col: sortCol}.CellName() + ":" + CellRef{Row: area.Last.Row, Col: sortCol}.CellName()
+	order := ""
+	if descending {
+		order = ` descending="1"`
+	}
+	state := ws.FieldByName("SortState")
+	v := reflect.New(state.Type().Elem())
+	v.Elem().FieldByName("Ref").SetString(first.CellName() + ":" + area.Last.CellName())
+	v.Elem().FieldByName("Content").SetString(fmt.Sprintf(`<sortCondition%s ref="%s"/>`, order, key))
+	state.Set(v)
+	return nil
+}
+
 // DeleteSheet removes a sheet from the workbook.
 func (tx *ExcelizeTransformer) DeleteSheet(name string) error {
 	return tx.file.DeleteSheet(name)
@@ -370,12 +1111,30 @@ func (tx *ExcelizeTransformer) DeleteSheet(name string) error {
 // SetHidden hides or unhides a sheet.
 func (tx *ExcelizeTransformer) SetHidden(name string, hidden bool) error {
 	if hidden {
+		// Excel cannot hide the selected sheet; select another visible sheet first
+		if idx, err := tx.file.GetSheetIndex(name); err == nil && idx == tx.file.GetActiveSheetIndex() {
+			for i, other := range tx.file.GetSheetList() {
+				if other != name && !tx.IsHidden(other) {
+					tx.file.SetActiveSheet(i)
+					break
+				}
+			}
+		}
 		return tx.file.SetSheetVisible(name, false)
 	}
 	return tx.file.SetSheetVisible(name, true)
 }
 
-// CopySheet copies a sheet to a new name.
+// IsHidden reports whether a sheet is hidden.
+func (tx *ExcelizeTransformer) IsHidden(name string) bool {
+	visible, err := tx.file.GetSheetVisible(name)
+	return err == nil && !visible
+}
+
+// CopySheet copies a sheet to a new name. Besides cells and sheet-level settings
+// that excelize copies (column widths, panes, margins, header/footer, conditional
+// formats), it carries over the page setup and sheet-scoped defined names such as
+// the print area and print titles.
 func (tx *ExcelizeTransformer) CopySheet(src, dst string) error {
 	srcIdx, err := tx.file.GetSheetIndex(src)
 	if err != nil {
@@ -385,9 +1144,194 @@ func (tx *ExcelizeTransformer) CopySheet(src, dst string) error {
 	if err != nil {
 		return fmt.Errorf("create sheet %q: %w", dst, err)
 	}
-	_ = srcIdx
-	_ = newIdx
-	return tx.file.CopySheet(srcIdx, newIdx)
+	if err := tx.file.CopySheet(srcIdx, newIdx); err != nil {
+		return err
+	}
+	if err := tx.copyComments(src, dst); err != nil {
+		return err
+	}
+	if tx.copies == nil {
+		tx.copies = make(map[string][]string)
+	}
+	tx.copies[src] = append(tx.copies[src], dst)
+
+	// excelize drops the page setup when copying
+	layout, err := tx.file.GetPageLayout(src)
+	if err != nil {
+		return fmt.Errorf("read page layout of %q: %w", src, err)
+	}
+	if err := tx.file.SetPageLayout(dst, &layout); err != nil {
+		return fmt.Errorf("copy page layout to %q: %w", dst, err)
+	}
+
+	return tx.copySheetDefinedNames(src, dst)
+}
+
+// MoveSheet moves a sheet directly after sheet after, or first when after is "".
+func (tx *ExcelizeTransformer) MoveSheet(name, after string) error {
+	sheets := tx.file.GetSheetList()
+	if !slices.Contains(sheets, name) {
+		return fmt.Errorf("sheet %q not found", name)
+	}
+	order := slices.DeleteFunc(slices.Clone(sheets), func(s string) bool { return s == name })
+	at := 0
+	if after != "" {
+		i := slices.Index(order, after)
+		if i < 0 {
+			return fmt.Errorf("sheet %q not found", after)
+		}
+		at = i + 1
+	}
+	return tx.reorderSheets(slices.Insert(order, at, name))
+}
+
+// SetSheetOrder moves the named sheets first, in that order; the others follow
+// in their current order.
+func (tx *ExcelizeTransformer) SetSheetOrder(names []string) error {
+	sheets := tx.file.GetSheetList()
+	order := make([]string, 0, len(sheets))
+	for _, name := range names {
+		if !slices.Contains(sheets, name) {
+			return fmt.Errorf("sheet %q not found", name)
+		}
+		if slices.Contains(order, name) {
+			return fmt.Errorf("sheet %q listed twice", name)
+		}
+		order = append(order, name)
+	}
+	for _, name := range sheets {
+		if !slices.Contains(order, name) {
+			order = append(order, name)
+		}
+	}
+	return tx.reorderSheets(order)
+}
+
+// reorderSheets puts the sheets of the workbook in order. excelize ties
+// sheet-scoped defined names, such as print areas, to sheet positions, so they
+// are set again for the sheets' new positions.
+func (tx *ExcelizeTransformer) reorderSheets(order []string) error {
+	if slices.Equal(order, tx.file.GetSheetList()) {
+		return nil
+	}
+	var scoped []excelize.DefinedName
+	for _, dn := range tx.file.GetDefinedName() {
+		if dn.Scope != "" && dn.Scope != "Workbook" {
+			if err := tx.file.DeleteDefinedName(&excelize.DefinedName{Name: dn.Name, Scope: dn.Scope}); err != nil {
+				return fmt.Errorf("move defined name %q of %q: %w", dn.Name, dn.Scope, err)
+			}
+			scoped = append(scoped, dn)
+		}
+	}
+	for i, name := range order {
+		if current := tx.file.GetSheetList()[i]; current != name {
+			if err := tx.file.MoveSheet(name, current); err != nil {
+				return fmt.Errorf("move sheet %q: %w", name, err)
+			}
+		}
+	}
+	for _, dn := range scoped {
+		if err := tx.file.SetDefinedName(&dn); err != nil {
+			return fmt.Errorf("move defined name %q of %q: %w", dn.Name, dn.Scope, err)
+		}
+	}
+	return nil
+}
+
+// SetActiveSheet selects the sheet shown when the output is opened. Excel
+// cannot show a hidden sheet first.
+func (tx *ExcelizeTransformer) SetActiveSheet(name string) error {
+	idx, err := tx.file.GetSheetIndex(name)
+	if err != nil || idx < 0 {
+		return fmt.Errorf("sheet %q not found", name)
+	}
+	if tx.IsHidden(name) {
+		return fmt.Errorf("sheet %q is hidden", name)
+	}
+	tx.file.SetActiveSheet(idx)
+	return nil
+}
+
+// SetSheetProps sets the tab color and visibility of a sheet.
+func (tx *ExcelizeTransformer) SetSheetProps(name string, props SheetProps) error {
+	if props.TabColor != nil {
+		color, ok := argbColor(*props.TabColor)
+		if !ok {
+			return fmt.Errorf("invalid tab color %q", *props.TabColor)
+		}
+		if err := tx.file.SetSheetProps(name, &excelize.SheetPropsOptions{TabColorRGB: &color}); err != nil {
+			return fmt.Errorf("set tab color of %q: %w", name, err)
+		}
+	}
+	if props.Hidden != nil {
+		return tx.SetHidden(name, *props.Hidden)
+	}
+	return nil
+}
+
+// copySheetDefinedNames duplicates names scoped to src (e.g. _xlnm.Print_Area)
+// for dst, rewriting references to src so they point at dst.
+func (tx *ExcelizeTransformer) copySheetDefinedNames(src, dst string) error {
+	for _, dn := range tx.file.GetDefinedName() {
+		if dn.Scope != src {
+			continue
+		}
+		refersTo := strings.ReplaceAll(dn.RefersTo, quoteSheetName(src)+"!", quoteSheetName(dst)+"!")
+		if err := tx.file.SetDefinedName(&excelize.DefinedName{
+			Name:     dn.Name,
+			Comment:  dn.Comment,
+			RefersTo: refersTo,
+			Scope:    dst,
+		}); err != nil {
+			return fmt.Errorf("copy defined name %q to %q: %w", dn.Name, dst, err)
+		}
+	}
+	return nil
+}
+
+// quoteSheetName returns the sheet name as written in a reference. Like Excel,
+// it quotes names with characters other than letters, digits, '_' and '.',
+// names starting with a digit or '.', and names that read as a cell reference,
+// such as "A1", "R1C1" or "RC".
+func quoteSheetName(name string) string {
+	quote := name == "" || (name[0] >= '0' && name[0] <= '9') || name[0] == '.' ||
+		a1NameRe.MatchString(name) || r1c1NameRe.MatchString(name)
+	for _, r := range name {
+		if !(r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			quote = true
+		}
+	}
+	if quote {
+		return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	return name
+}
+
+var (
+	a1NameRe   = regexp.MustCompile(`^[A-Za-z]{1,3}[0-9]+$`)
+	r1c1NameRe = regexp.MustCompile(`^([Rr][0-9]*)?([Cc][0-9]*)?$`)
+)
+
+// setAreaName saves ref as the workbook-scoped defined name name, replacing an
+// existing definition.
+func (tx *ExcelizeTransformer) setAreaName(name string, ref AreaRef) error {
+	tx.file.DeleteDefinedName(&excelize.DefinedName{Name: name})
+	refersTo := fmt.Sprintf("%s!$%s$%d:$%s$%d", quoteSheetName(ref.First.Sheet),
+		ColToName(ref.First.Col), ref.First.Row+1, ColToName(ref.Last.Col), ref.Last.Row+1)
+	if err := tx.file.SetDefinedName(&excelize.DefinedName{Name: name, RefersTo: refersTo}); err != nil {
+		return fmt.Errorf("set defined name %q: %w", name, err)
+	}
+	return nil
+}
+
+// setCellName sets a workbook-scoped defined name referring to a single cell.
+func (tx *ExcelizeTransformer) setCellName(name string, ref CellRef) error {
+	tx.file.DeleteDefinedName(&excelize.DefinedName{Name: name})
+	refersTo := fmt.Sprintf("%s!$%s$%d", quoteSheetName(ref.Sheet), ColToName(ref.Col), ref.Row+1)
+	if err := tx.file.SetDefinedName(&excelize.DefinedName{Name: name, RefersTo: refersTo}); err != nil {
+		return fmt.Errorf("set defined name %q: %w", name, err)
+	}
+	return nil
 }
 
 // AddImage inserts an image into a sheet.
@@ -428,7 +1372,7 @@ func (tx *ExcelizeTransformer) SetCellHyperLink(ref CellRef, url, display string
 		return err
 	}
 	if display != "" {
-		return tx.file.SetCellValue(ref.Sheet, cell, display)
+		return tx.SetCellValue(ref, display)
 	}
 	return nil
 }
@@ -443,16 +1387,68 @@ func (tx *ExcelizeTransformer) SetRecalculateOnOpen(recalc bool) error {
 	})
 }
 
+// SetCalcProps sets the calculation properties of the workbook. Zero fields
+// of props leave the workbook's setting unchanged.
+func (tx *ExcelizeTransformer) SetCalcProps(props CalcProps) error {
+	opts := &excelize.CalcPropsOptions{}
+	if props.FullCalcOnLoad {
+		opts.FullCalcOnLoad = &props.FullCalcOnLoad
+	}
+	if props.CalcMode != "" {
+		opts.CalcMode = &props.CalcMode
+	}
+	if props.Iterative {
+		opts.Iterate = &props.Iterative
+	}
+	if props.MaxIterations != 0 {
+		if props.MaxIterations < 0 {
+			return fmt.Errorf("invalid MaxIterations %d", props.MaxIterations)
+		}
+		n := uint(props.MaxIterations)
+		opts.IterateCount = &n
+	}
+	current, err := tx.file.GetCalcProps()
+	if err != nil {
+		return err
+	}
+	opts.ConcurrentCalc = current.ConcurrentCalc // SetCalcProps resets it otherwise
+	return tx.file.SetCalcProps(opts)
+}
+
 // Write writes the workbook to the given writer.
 func (tx *ExcelizeTransformer) Write(w io.Writer) error {
+	if tx.ods {
+		return odsconv.Write(w, tx.file)
+	}
 	return tx.file.Write(w)
 }
 
-// Close closes the underlying excelize file.
+// Close closes the underlying excelize file, and the template file when it differs.
 func (tx *ExcelizeTransformer) Close() error {
+	if tx.crossFile() {
+		if err := tx.src.Close(); err != nil {
+			return err
+		}
+	}
 	return tx.file.Close()
 }
 
+// includeTemplate opens the template data with a transformer that writes to
+// the output of tx, with its named styles and cell writers, for jx:include.
+func (tx *ExcelizeTransformer) includeTemplate(data []byte) (*includedTemplate, error) {
+	file, err := openTemplateData(data)
+	if err != nil {
+		return nil, err
+	}
+	in, err := NewCrossFileTransformer(file, tx.file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	in.styles, in.styleRefs, in.cellWriter = tx.styles, tx.styleRefs, tx.cellWriter
+	return &includedTemplate{tx: in, sheet: file.GetSheetName(0), close: file.Close}, nil
+}
+
 // File returns the underlying excelize file for advanced operations.
 func (tx *ExcelizeTransformer) File() *excelize.File {
 	return tx.file
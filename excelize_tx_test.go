@@ -3,6 +3,7 @@ package xlfill
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -104,6 +105,48 @@ func TestTransformer_Transform_StringValue(t *testing.T) {
 	assert.Equal(t, "Name", val)
 }
 
+func TestTransformer_Transform_KeepsStaticValueTypes(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellStr("Sheet1", "A1", "0123")
+	f.SetCellValue("Sheet1", "B1", 123)
+	f.SetCellValue("Sheet1", "C1", 1234.5)
+	thousands, err := f.NewStyle(&excelize.Style{NumFmt: 4})
+	require.NoError(t, err)
+	f.SetCellStyle("Sheet1", "C1", "C1", thousands)
+	f.SetCellValue("Sheet1", "D1", true)
+	f.SetCellStr("Sheet1", "E1", "1e5")
+	path := filepath.Join(testdataDir(t), "static_types.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	tx, err := OpenTemplate(path)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	ctx := NewContext(map[string]any{})
+	for col := range 5 {
+		require.NoError(t, tx.Transform(NewCellRef("Sheet1", 0, col), NewCellRef("Sheet1", 2, col), ctx, true))
+	}
+	for cell, want := range map[string]excelize.CellType{
+		"A3": excelize.CellTypeSharedString,
+		"B3": excelize.CellTypeUnset, // number
+		"C3": excelize.CellTypeUnset,
+		"D3": excelize.CellTypeBool,
+		"E3": excelize.CellTypeSharedString,
+	} {
+		got, err := tx.file.GetCellType("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, cell)
+	}
+	for cell, want := range map[string]string{"A3": "0123", "B3": "123", "C3": "1,234.50", "D3": "TRUE", "E3": "1e5"} {
+		got, err := tx.file.GetCellValue("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, cell)
+	}
+	assert.Equal(t, 1234.5, tx.GetCellData(NewCellRef("Sheet1", 0, 2)).Value)
+	assert.Equal(t, CellNumber, tx.GetCellData(NewCellRef("Sheet1", 0, 2)).Type)
+}
+
 func TestTransformer_Transform_PreservesStyle(t *testing.T) {
 	path := createStyledTemplate(t)
 	defer os.Remove(path)
@@ -402,3 +445,159 @@ func TestTransformer_SetCellValue(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "NewValue", val)
 }
+
+// createSheetSettingsTemplate creates a multisheet template with sheet-level
+// settings that generated sheets must inherit.
+func createSheetSettingsTemplate(t *testing.T) *excelize.File {
+	t.Helper()
+	f := excelize.NewFile()
+	s := "template"
+	f.SetSheetName("Sheet1", s)
+	f.SetCellValue(s, "A1", "Header")
+	f.SetCellValue(s, "A2", "${e.Name}")
+	require.NoError(t, f.SetColWidth(s, "A", "B", 33))
+	require.NoError(t, f.SetPanes(s, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}))
+	top := 1.5
+	require.NoError(t, f.SetPageMargins(s, &excelize.PageLayoutMarginsOptions{Top: &top}))
+	orientation, size := "landscape", 9
+	require.NoError(t, f.SetPageLayout(s, &excelize.PageLayoutOptions{Orientation: &orientation, Size: &size}))
+	require.NoError(t, f.SetHeaderFooter(s, &excelize.HeaderFooterOptions{OddHeader: "&CReport"}))
+	styleID, err := f.NewConditionalStyle(&excelize.Style{Font: &excelize.Font{Color: "9A0511"}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetConditionalFormat(s, "A2:A10", []excelize.ConditionalFormatOptions{
+		{Type: "cell", Criteria: ">", Format: &styleID, Value: "6"},
+	}))
+	require.NoError(t, f.SetDefinedName(&excelize.DefinedName{Name: "_xlnm.Print_Area", RefersTo: "template!$A$1:$B$5", Scope: s}))
+	require.NoError(t, f.SetDefinedName(&excelize.DefinedName{Name: "_xlnm.Print_Titles", RefersTo: "template!$1:$1", Scope: s}))
+	f.AddComment(s, excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A2\")\njx:each(items=\"depts\" var=\"e\" multisheet=\"names\" lastCell=\"A2\")"})
+	return f
+}
+
+func TestTransformer_CopySheet_PreservesSheetSettings(t *testing.T) {
+	tmpl := createSheetSettingsTemplate(t)
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Write(&buf))
+	tmpl.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, FillReader(&buf, &out, map[string]any{
+		"depts": []map[string]any{{"Name": "Sales"}, {"Name": "Ops"}},
+		"names": []string{"Sales", "Q1 Ops"},
+	}))
+
+	f, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	defer f.Close()
+	require.Equal(t, []string{"Sales", "Q1 Ops"}, f.GetSheetList())
+
+	printAreas := map[string]string{}
+	printTitles := map[string]string{}
+	for _, dn := range f.GetDefinedName() {
+		switch dn.Name {
+		case "_xlnm.Print_Area":
+			printAreas[dn.Scope] = dn.RefersTo
+		case "_xlnm.Print_Titles":
+			printTitles[dn.Scope] = dn.RefersTo
+		}
+	}
+
+	for _, sheet := range f.GetSheetList() {
+		w, err := f.GetColWidth(sheet, "B")
+		require.NoError(t, err)
+		assert.Equal(t, 33.0, w, sheet)
+
+		panes, err := f.GetPanes(sheet)
+		require.NoError(t, err)
+		assert.True(t, panes.Freeze, sheet)
+		assert.Equal(t, "A2", panes.TopLeftCell, sheet)
+
+		margins, err := f.GetPageMargins(sheet)
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, *margins.Top, sheet)
+
+		layout, err := f.GetPageLayout(sheet)
+		require.NoError(t, err)
+		assert.Equal(t, "landscape", *layout.Orientation, sheet)
+		assert.Equal(t, 9, *layout.Size, sheet)
+
+		hf, err := f.GetHeaderFooter(sheet)
+		require.NoError(t, err)
+		assert.Equal(t, "&CReport", hf.OddHeader, sheet)
+
+		cf, err := f.GetConditionalFormats(sheet)
+		require.NoError(t, err)
+		assert.Contains(t, cf, "A2:A10", sheet)
+	}
+
+	assert.Equal(t, "Sales!$A$1:$B$5", printAreas["Sales"])
+	assert.Equal(t, "'Q1 Ops'!$A$1:$B$5", printAreas["Q1 Ops"])
+	assert.Equal(t, "'Q1 Ops'!$1:$1", printTitles["Q1 Ops"])
+}
+
+func TestQuoteSheetName(t *testing.T) {
+	assert.Equal(t, "Sheet1", quoteSheetName("Sheet1"))
+	assert.Equal(t, "'Q1 Ops'", quoteSheetName("Q1 Ops"))
+	assert.Equal(t, "'O''Neil'", quoteSheetName("O'Neil"))
+	assert.Equal(t, "'2024'", quoteSheetName("2024"))
+	assert.Equal(t, "'A1'", quoteSheetName("A1"))
+	assert.Equal(t, "'xfd1048576'", quoteSheetName("xfd1048576"))
+	assert.Equal(t, "'R1C1'", quoteSheetName("R1C1"))
+	assert.Equal(t, "'RC'", quoteSheetName("RC"))
+	assert.Equal(t, "'C'", quoteSheetName("C"))
+	assert.Equal(t, "Sales2024", quoteSheetName("Sales2024"))
+	assert.Equal(t, "Report", quoteSheetName("Report"))
+}
+
+func TestCrossFileTransformer_ApplyAreaIntoOtherWorkbook(t *testing.T) {
+	src, err := excelize.OpenFile(createBasicTemplate(t))
+	require.NoError(t, err)
+
+	dst := excelize.NewFile()
+	_, err = dst.NewSheet("Report")
+	require.NoError(t, err)
+	dst.SetCellValue("Report", "A1", "Staff")
+	bold, err := dst.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+
+	tx, err := NewCrossFileTransformer(src, dst)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	areas, err := NewFiller().BuildAreas(tx)
+	require.NoError(t, err)
+	require.Len(t, areas, 1)
+
+	ctx := NewContext(map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "Age": 30, "Salary": 5000},
+		{"Name": "Bob", "Age": 25, "Salary": 4000},
+	}})
+	size, err := areas[0].ApplyAt(NewCellRef("Report", 2, 0), ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 3, Height: 3}, size)
+	_, err = areas[0].ApplyAt(NewCellRef("Copy", 0, 0), ctx)
+	require.NoError(t, err, "missing target sheets are created")
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+
+	rows, err := out.GetRows("Report")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Staff"}, nil, {"Name", "Age", "Salary"}, {"Alice", "30", "5000"}, {"Bob", "25", "4000"}}, rows)
+	assert.Contains(t, out.GetSheetList(), "Copy")
+
+	styleID, err := out.GetCellStyle("Report", "A3")
+	require.NoError(t, err)
+	assert.Equal(t, bold, styleID, "identical style in the destination is reused")
+	copyStyleID, err := out.GetCellStyle("Copy", "C1")
+	require.NoError(t, err)
+	assert.Equal(t, bold, copyStyleID)
+
+	// The template workbook is untouched
+	v, err := src.GetCellValue("Sheet1", "A2")
+	require.NoError(t, err)
+	assert.Equal(t, "${e.Name}", v)
+}
@@ -1,7 +1,9 @@
 package xlfill
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -17,7 +19,8 @@ type ExpressionEvaluator interface {
 
 // exprEvaluator implements ExpressionEvaluator using expr-lang/expr.
 type exprEvaluator struct {
-	cache sync.Map // expression string → compiled *vm.Program
+	cache   sync.Map     // expression string → compiled *vm.Program
+	sandbox *exprSandbox // nil when expressions are unrestricted
 }
 
 // NewExpressionEvaluator creates a new expression evaluator backed by expr-lang/expr.
@@ -29,17 +32,49 @@ func (e *exprEvaluator) Evaluate(expression string, data map[string]any) (any, e
 	if expression == "" {
 		return nil, nil
 	}
+	if alternatives := fallbacks(expression); len(alternatives) > 1 {
+		return e.evaluateFallbacks(expression, alternatives, data)
+	}
 	program, err := e.compile(expression, data)
 	if err != nil {
 		return nil, fmt.Errorf("compile expression %q: %w", expression, err)
 	}
-	result, err := expr.Run(program, data)
+	result, err := e.run(program, data)
 	if err != nil {
 		return nil, fmt.Errorf("evaluate expression %q: %w", expression, err)
 	}
 	return result, nil
 }
 
+// evaluateFallbacks returns the value of the first alternative that evaluates
+// to a value other than nil or "". Evaluation errors of all but the last
+// alternative, such as reading a field of a missing object, move on to the
+// next one; syntax errors and expression limits fail.
+func (e *exprEvaluator) evaluateFallbacks(expression string, alternatives []string, data map[string]any) (any, error) {
+	programs := make([]*vm.Program, len(alternatives))
+	for i, alt := range alternatives {
+		program, err := e.compile(alt, data)
+		if err != nil {
+			return nil, fmt.Errorf("compile expression %q: %w", expression, err)
+		}
+		programs[i] = program
+	}
+	for i, program := range programs {
+		last := i == len(programs)-1
+		result, err := e.run(program, data)
+		if err != nil {
+			if last || errors.Is(err, ErrExpressionLimit) {
+				return nil, fmt.Errorf("evaluate expression %q: %w", expression, err)
+			}
+			continue
+		}
+		if last || (result != nil && result != "") {
+			return result, nil
+		}
+	}
+	return nil, nil
+}
+
 func (e *exprEvaluator) IsConditionTrue(condition string, data map[string]any) (bool, error) {
 	result, err := e.Evaluate(condition, data)
 	if err != nil {
@@ -59,7 +94,13 @@ func (e *exprEvaluator) compile(expression string, env map[string]any) (*vm.Prog
 	if cached, ok := e.cache.Load(expression); ok {
 		return cached.(*vm.Program), nil
 	}
-	program, err := expr.Compile(expression, expr.Env(env), expr.AllowUndefinedVariables())
+	var program *vm.Program
+	var err error
+	if e.sandbox != nil {
+		program, err = e.sandbox.compile(expression, env)
+	} else {
+		program, err = expr.Compile(expression, compileOptions(env)...)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +108,87 @@ func (e *exprEvaluator) compile(expression string, env map[string]any) (*vm.Prog
 	return program, nil
 }
 
+func (e *exprEvaluator) run(program *vm.Program, data map[string]any) (any, error) {
+	if e.sandbox != nil {
+		return e.sandbox.run(program, data)
+	}
+	return expr.Run(program, data)
+}
+
+// compileOptions returns the options template expressions are compiled with.
+// env is nil when only the syntax is checked.
+func compileOptions(env map[string]any) []expr.Option {
+	var opts []expr.Option
+	if env != nil {
+		opts = append(opts, expr.Env(env))
+	}
+	opts = append(opts, expr.AllowUndefinedVariables(), expr.Patch(aggregates{}), expr.Patch(itemAccess{}))
+	opts = append(opts, aggregateFunctions...)
+	return append(opts, itemFunctions...)
+}
+
+// pipeTarget matches the start of a function call, the right side of expr's
+// pipe operator.
+var pipeTarget = regexp.MustCompile(`^\s*[A-Za-z_$][A-Za-z0-9_$]*\s*\(`)
+
+// fallbacks splits an expression at its top-level fallback operators, e.g.
+// "e.Qty | 0" → [e.Qty 0]. A | followed by a function call is expr's pipe
+// operator, as in "e.Name | upper()", and is kept. An expression without a
+// fallback is returned as the only alternative.
+func fallbacks(expression string) []string {
+	parts := splitTopLevel(expression, '|', true)
+	var alternatives []string
+	for i, part := range parts {
+		if i > 0 && pipeTarget.MatchString(part) {
+			alternatives[len(alternatives)-1] += "|" + part
+			continue
+		}
+		alternatives = append(alternatives, part)
+	}
+	if len(alternatives) == 1 {
+		return []string{expression}
+	}
+	for i := range alternatives {
+		alternatives[i] = strings.TrimSpace(alternatives[i])
+	}
+	return alternatives
+}
+
+// splitTopLevel splits s at the sep characters outside quotes, parentheses,
+// brackets and braces. As in expr, a backslash escapes the next character in
+// a quoted string, except in backquotes. With keepDoubled, a doubled sep,
+// such as the || operator, does not split. The parts are not trimmed.
+func splitTopLevel(s string, sep byte, keepDoubled bool) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote != '`' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'' || ch == '`':
+			quote = ch
+		case ch == '(' || ch == '[' || ch == '{':
+			depth++
+		case ch == ')' || ch == ']' || ch == '}':
+			depth--
+		case ch == sep && depth == 0:
+			if keepDoubled && i+1 < len(s) && s[i+1] == sep {
+				i++
+				continue
+			}
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
 // ExpressionSegment represents a part of a cell value: either literal text or an expression.
 type ExpressionSegment struct {
 	IsExpression bool
@@ -75,6 +197,8 @@ type ExpressionSegment struct {
 
 // ParseExpressions splits a cell value into segments of literal text and expressions.
 // For example, "Name: ${e.Name}" → [{false, "Name: "}, {true, "e.Name"}]
+// A begin delimiter preceded by a backslash is literal text without the
+// backslash: "\${name}" → [{false, "${name}"}].
 func ParseExpressions(value string, begin, end string) []ExpressionSegment {
 	if begin == "" || end == "" {
 		begin = "${"
@@ -82,6 +206,7 @@ func ParseExpressions(value string, begin, end string) []ExpressionSegment {
 	}
 
 	var segments []ExpressionSegment
+	var literal strings.Builder
 	remaining := value
 
 	for {
@@ -89,6 +214,12 @@ func ParseExpressions(value string, begin, end string) []ExpressionSegment {
 		if startIdx < 0 {
 			break
 		}
+		if startIdx > 0 && remaining[startIdx-1] == '\\' {
+			literal.WriteString(remaining[:startIdx-1])
+			literal.WriteString(begin)
+			remaining = remaining[startIdx+len(begin):]
+			continue
+		}
 
 		// Find matching end delimiter, accounting for nested braces
 		searchFrom := startIdx + len(begin)
@@ -99,11 +230,13 @@ func ParseExpressions(value string, begin, end string) []ExpressionSegment {
 		endIdx += searchFrom
 
 		// Add literal text before expression
-		if startIdx > 0 {
+		literal.WriteString(remaining[:startIdx])
+		if literal.Len() > 0 {
 			segments = append(segments, ExpressionSegment{
 				IsExpression: false,
-				Text:         remaining[:startIdx],
+				Text:         literal.String(),
 			})
+			literal.Reset()
 		}
 
 		// Add expression
@@ -117,10 +250,11 @@ func ParseExpressions(value string, begin, end string) []ExpressionSegment {
 	}
 
 	// Add remaining literal text
-	if remaining != "" {
+	literal.WriteString(remaining)
+	if literal.Len() > 0 {
 		segments = append(segments, ExpressionSegment{
 			IsExpression: false,
-			Text:         remaining,
+			Text:         literal.String(),
 		})
 	}
 
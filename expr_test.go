@@ -220,6 +220,18 @@ func TestParseExpressions_MixedContent(t *testing.T) {
 	assert.Equal(t, "e.Age", segs[3].Text)
 }
 
+func TestParseExpressions_Escaped(t *testing.T) {
+	segs := ParseExpressions(`Use \${name} for ${label}, \${x}`, "${", "}")
+	assert.Equal(t, []ExpressionSegment{
+		{Text: "Use ${name} for "},
+		{IsExpression: true, Text: "label"},
+		{Text: ", ${x}"},
+	}, segs)
+
+	segs = ParseExpressions(`\{{e.Name}}`, "{{", "}}")
+	assert.Equal(t, []ExpressionSegment{{Text: "{{e.Name}}"}}, segs)
+}
+
 func TestParseExpressions_CustomNotation(t *testing.T) {
 	segs := ParseExpressions("{{e.Name}}", "{{", "}}")
 	require.Len(t, segs, 1)
@@ -278,3 +290,53 @@ func TestExtractSingleExpression_NoExpression(t *testing.T) {
 	_, ok := ExtractSingleExpression("Hello", "${", "}")
 	assert.False(t, ok)
 }
+
+func TestFallbacks(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []string
+	}{
+		{"e.Qty", []string{"e.Qty"}},
+		{"e.Qty | 0", []string{"e.Qty", "0"}},
+		{"a | b | 'n/a'", []string{"a", "b", "'n/a'"}},
+		{"a || b", []string{"a || b"}},
+		{"e.Name | upper()", []string{"e.Name | upper()"}},
+		{"e.Name | upper() | '-'", []string{"e.Name | upper()", "'-'"}},
+		{`"a|b" | x`, []string{`"a|b"`, "x"}},
+		{"f(a | b)", []string{"f(a | b)"}},
+		{`"say \"a|b\"" | x`, []string{`"say \"a|b\""`, "x"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, fallbacks(tt.expression), tt.expression)
+	}
+}
+
+func TestExpr_Fallback(t *testing.T) {
+	ev := NewExpressionEvaluator()
+	env := newTestEvalEnv()
+	env["m"] = map[string]any{"Blank": "", "Qty": 3}
+
+	tests := []struct {
+		expression string
+		want       any
+	}{
+		{"m.Qty | 0", 3},
+		{"m.Missing | 0", 0},
+		{"m.Blank | 'n/a'", "n/a"},
+		{"missing.Deep.Field | 0", 0},
+		{"m.Missing | m.Qty | 0", 3},
+		{"e.Name | upper() | 'x'", "ALICE"},
+		{"m.Missing | nil", nil},
+		{"missing || true", true},
+	}
+	for _, tt := range tests {
+		got, err := ev.Evaluate(tt.expression, env)
+		require.NoError(t, err, tt.expression)
+		assert.Equal(t, tt.want, got, tt.expression)
+	}
+
+	_, err := ev.Evaluate("m.Missing | )", env)
+	assert.ErrorContains(t, err, "compile expression")
+	_, err = ev.Evaluate("m.Missing | missing.Deep", env)
+	assert.ErrorContains(t, err, "evaluate expression")
+}
@@ -0,0 +1,179 @@
+package xlfill
+
+import (
+	"reflect"
+	"strings"
+)
+
+// getField extracts a property value from an item by name, as used by groupBy,
+// orderBy and grid props. The name may be a dot path ("Address.City"); each part
+// is looked up in a map key, an exported struct field, a field tagged
+// `xlfill:"name"` or a zero-argument getter method ("Name" or "Name()"), falling
+// back to a case-insensitive match. Returns nil when any part is not found.
+// Nullable database values and pointers are unwrapped by nullValue.
+func getField(item any, field string) any {
+	if field == "" {
+		return item // the loop variable itself
+	}
+	for _, name := range strings.Split(field, ".") {
+		if item == nil {
+			return nil
+		}
+		item = fieldValue(item, strings.TrimSuffix(strings.TrimSpace(name), "()"))
+	}
+	return nullValue(item)
+}
+
+// fieldValue looks up a single property of item.
+func fieldValue(item any, name string) any {
+	v, _ := lookupField(item, name)
+	return v
+}
+
+// hasField reports whether item has a property name, which may be nil.
+func hasField(item any, name string) bool {
+	_, ok := lookupField(item, name)
+	return ok
+}
+
+// lookupField looks up a single property of item and reports whether it exists.
+func lookupField(item any, name string) (any, bool) {
+	v, _, ok := resolveField(item, name)
+	return v, ok
+}
+
+// fieldMembers returns the names of the map keys, struct fields and getter
+// methods a property path reads from item. A struct tag or a case-insensitive
+// match can make them differ from the names in the path.
+func fieldMembers(item any, path string) []string {
+	var members []string
+	for _, name := range strings.Split(path, ".") {
+		if item == nil {
+			break
+		}
+		v, member, ok := resolveField(item, strings.TrimSuffix(strings.TrimSpace(name), "()"))
+		if !ok {
+			break
+		}
+		members = append(members, member)
+		item = nullValue(v)
+	}
+	return members
+}
+
+// resolveField looks up a single property of item, returning its value, the
+// name of the map key, field or method it was found in, and whether it exists.
+func resolveField(item any, name string) (any, string, bool) {
+	if m, ok := item.(map[string]any); ok {
+		if v, ok := m[name]; ok {
+			return v, name, true
+		}
+		return mapValueFold(reflect.ValueOf(m), name)
+	}
+
+	v := reflect.ValueOf(item)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, "", false
+		}
+		if m, ok := getterMethod(v, name); ok {
+			return m.Call(nil)[0].Interface(), name, true
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, "", false
+		}
+		if mv := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); mv.IsValid() {
+			return mv.Interface(), name, true
+		}
+		return mapValueFold(v, name)
+	case reflect.Struct:
+		if f, ok := v.Type().FieldByName(name); ok && f.IsExported() {
+			return fieldInterface(v, f.Index), f.Name, true
+		}
+		fields := reflect.VisibleFields(v.Type())
+		for _, f := range fields {
+			if f.IsExported() && tagName(f) == name {
+				return fieldInterface(v, f.Index), f.Name, true
+			}
+		}
+		// Pointer-receiver getters need an addressable copy of the struct
+		addr := reflect.New(v.Type())
+		addr.Elem().Set(v)
+		if m, ok := getterMethod(addr, name); ok {
+			return m.Call(nil)[0].Interface(), name, true
+		}
+		for _, f := range fields {
+			if f.IsExported() && !f.Anonymous && (strings.EqualFold(f.Name, name) || strings.EqualFold(tagName(f), name)) {
+				return fieldInterface(v, f.Index), f.Name, true
+			}
+		}
+		if m, name, ok := getterMethodFold(addr, name); ok {
+			return m.Call(nil)[0].Interface(), name, true
+		}
+	}
+	return nil, "", false
+}
+
+// fieldInterface returns the value of a struct field, or nil when it is
+// promoted through a nil embedded pointer.
+func fieldInterface(v reflect.Value, index []int) any {
+	f, err := v.FieldByIndexErr(index)
+	if err != nil {
+		return nil
+	}
+	return f.Interface()
+}
+
+// tagName returns the name given by a field's xlfill struct tag.
+func tagName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("xlfill"), ",")
+	return name
+}
+
+// mapValueFold returns the value and the first key, in sorted order, that
+// matches name case-insensitively, and whether there is one.
+func mapValueFold(m reflect.Value, name string) (any, string, bool) {
+	var found reflect.Value
+	var foundKey string
+	for _, k := range m.MapKeys() {
+		key := k.String()
+		if strings.EqualFold(key, name) && (!found.IsValid() || key < foundKey) {
+			found, foundKey = m.MapIndex(k), key
+		}
+	}
+	if !found.IsValid() {
+		return nil, "", false
+	}
+	return found.Interface(), foundKey, true
+}
+
+// getterMethod returns the method of v with the given name if it is a getter:
+// no arguments and a single result.
+func getterMethod(v reflect.Value, name string) (reflect.Value, bool) {
+	if !v.IsValid() || name == "" {
+		return reflect.Value{}, false
+	}
+	m := v.MethodByName(name)
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return reflect.Value{}, false
+	}
+	return m, true
+}
+
+// getterMethodFold is getterMethod with a case-insensitive name; it also
+// returns the method's name.
+func getterMethodFold(v reflect.Value, name string) (reflect.Value, string, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		if strings.EqualFold(t.Method(i).Name, name) {
+			m, ok := getterMethod(v, t.Method(i).Name)
+			return m, t.Method(i).Name, ok
+		}
+	}
+	return reflect.Value{}, "", false
+}
@@ -0,0 +1,119 @@
+package xlfill
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+type fieldAddress struct {
+	City string `xlfill:"city"`
+	Zip  string `xlfill:"postcode,omitempty"`
+}
+
+type fieldPerson struct {
+	First   string
+	Last    string
+	Address *fieldAddress
+	Tags    map[string]string
+	secret  string
+}
+
+func (p fieldPerson) FullName() string { return p.First + " " + p.Last }
+
+func (p *fieldPerson) Initials() string { return p.First[:1] + p.Last[:1] }
+
+func (p fieldPerson) Greet(name string) string { return "hi " + name }
+
+type fieldManager struct {
+	fieldPerson
+	Reports int `xlfill:"team"`
+}
+
+func TestGetField_PathsTagsAndGetters(t *testing.T) {
+	p := fieldPerson{First: "Ada", Last: "Lovelace", Address: &fieldAddress{City: "London", Zip: "W1"},
+		Tags: map[string]string{"Role": "analyst"}, secret: "x"}
+
+	for _, item := range []any{p, &p} {
+		assert.Equal(t, "Ada", getField(item, "First"))
+		assert.Equal(t, "London", getField(item, "Address.City"))
+		assert.Equal(t, "W1", getField(item, "Address.postcode"))
+		assert.Equal(t, "London", getField(item, "address.city"))
+		assert.Equal(t, "analyst", getField(item, "Tags.Role"))
+		assert.Equal(t, "analyst", getField(item, "Tags.role"))
+		assert.Equal(t, "Ada Lovelace", getField(item, "FullName"))
+		assert.Equal(t, "Ada Lovelace", getField(item, "fullName()"))
+		assert.Equal(t, "AL", getField(item, "Initials()"))
+	}
+
+	// Methods with arguments and unexported fields are not properties
+	assert.Nil(t, getField(p, "Greet"))
+	assert.Nil(t, getField(p, "secret"))
+	assert.Nil(t, getField(p, "Address.Street"))
+	assert.Nil(t, getField(fieldPerson{}, "Address.City"))
+	assert.Nil(t, getField((*fieldPerson)(nil), "FullName"))
+
+	m := fieldManager{fieldPerson: p, Reports: 4}
+	assert.Equal(t, 4, getField(m, "team"))
+	assert.Equal(t, "London", getField(m, "Address.city"))
+	assert.Equal(t, "Ada Lovelace", getField(m, "FullName"))
+
+	nested := map[string]any{"Customer": map[string]any{"Name": "Acme"}}
+	assert.Equal(t, "Acme", getField(nested, "Customer.Name"))
+	assert.Equal(t, "Acme", getField(nested, "customer.name"))
+}
+
+func TestHasField(t *testing.T) {
+	p := fieldPerson{First: "Ada"}
+	assert.True(t, hasField(p, "First"))
+	assert.True(t, hasField(&p, "fullName"))
+	assert.True(t, hasField(p, "Address"), "nil values are still properties")
+	assert.False(t, hasField(p, "Address.City"))
+	assert.False(t, hasField(p, "secret"))
+	assert.True(t, hasField(map[string]any{"Qty": nil}, "qty"))
+	assert.False(t, hasField(map[string]any{}, "Qty"))
+	assert.False(t, hasField(42, "Qty"))
+}
+
+func TestGetField_OrderAndGroupByNestedPaths(t *testing.T) {
+	people := []any{
+		fieldPerson{First: "Cy", Last: "B", Address: &fieldAddress{City: "Paris"}},
+		fieldPerson{First: "Al", Last: "A", Address: &fieldAddress{City: "Berlin"}},
+		fieldPerson{First: "Bo", Last: "C", Address: &fieldAddress{City: "Paris"}},
+	}
+
+	sorted := append([]any(nil), people...)
+	sortByFields(sorted, parseOrderBy("e.Address.City ASC, e.FullName() DESC", "e"))
+	var names []any
+	for _, p := range sorted {
+		names = append(names, getField(p, "First"))
+	}
+	assert.Equal(t, []any{"Al", "Cy", "Bo"}, names)
+
+	cmd := &EachCommand{Var: "e", GroupBy: "e.Address.city"}
+	groups := cmd.groupItems(people)
+	require.Len(t, groups, 2)
+	assert.Len(t, groups[0].(GroupData).Items, 2)
+	assert.Equal(t, "Paris", getField(groups[0].(GroupData).Item, "Address.City"))
+}
+
+func TestGetField_GridProps(t *testing.T) {
+	f := excelize.NewFile()
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:grid(headers=\"headers\" data=\"people\" props=\"FullName, Address.City\" lastCell=\"A1\")"})
+	path := t.TempDir() + "/grid_props.xlsx"
+	require.NoError(t, f.SaveAs(path))
+
+	out, err := FillBytes(path, map[string]any{
+		"headers": []string{"Name", "City"},
+		"people":  []fieldPerson{{First: "Ada", Last: "Lovelace", Address: &fieldAddress{City: "London"}}},
+	})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	for cell, want := range map[string]string{"A1": "Name", "A2": "Ada Lovelace", "B2": "London"} {
+		v, _ := res.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
@@ -0,0 +1,184 @@
+package xlfill
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FillArea refreshes a single area of a workbook produced earlier from
+// templatePath, leaving the rest of the workbook, including user edits, untouched.
+// See Filler.FillArea.
+func FillArea(templatePath, existingPath, area string, data map[string]any, opts ...Option) error {
+	allOpts := append([]Option{WithTemplate(templatePath)}, opts...)
+	filler := NewFiller(allOpts...)
+	return filler.FillArea(existingPath, area, data)
+}
+
+// FillArea re-applies one area of the template to the workbook at existingPath
+// and saves it in place. area is either the name given with
+// jx:area(name="...") or the area's start cell, e.g. "Sheet1!A1".
+//
+// A full fill saves the output range of each named area as a defined name of the
+// same name. FillArea applies a named area at the first cell of that range,
+// where the area ended up after the areas above it grew, and clears the range
+// before writing, so an area that shrank leaves no stale rows; then it updates
+// the name. An area that grew past the range into cells holding content is an
+// error and the workbook is left unchanged; refresh it with a full fill
+// instead. Areas selected by start cell, or named areas without a saved range,
+// are applied at their template position and overwrite the cells the new
+// output covers.
+func (f *Filler) FillArea(existingPath, area string, data map[string]any) error {
+	src, err := f.openTemplateFile()
+	if err != nil {
+		return err
+	}
+	dst, err := excelize.OpenFile(existingPath)
+	if err != nil {
+		src.Close()
+		return fmt.Errorf("open workbook %q: %w", existingPath, err)
+	}
+	tx, err := NewCrossFileTransformer(src, dst)
+	if err != nil {
+		src.Close()
+		dst.Close()
+		return err
+	}
+	defer tx.Close()
+	tx.styles, tx.styleRefs = f.opts.styles, f.opts.styleCells
+	tx.setCellWriters(f.opts.cellWriters)
+
+	ctx, err := f.newContext(data, tx)
+	if err != nil {
+		return err
+	}
+	defer ctx.state.includes.close()
+	areas, err := f.BuildAreas(tx)
+	if err != nil {
+		return err
+	}
+	target, err := findArea(areas, area)
+	if err != nil {
+		return err
+	}
+
+	start := target.StartCell
+	var saved *AreaRef
+	if target.Name != "" {
+		if saved, err = namedRange(tx, target.Name); err != nil {
+			return err
+		}
+	}
+	if saved != nil {
+		start = saved.First
+		clearRange(tx, *saved)
+	}
+
+	size, err := target.ApplyAt(start, ctx.forSheet(start.Sheet))
+	if err != nil {
+		return fmt.Errorf("process area at %s: %w", start, err)
+	}
+	if saved != nil {
+		if err := checkGrowth(existingPath, target.Name, *saved, outputRef(start, size)); err != nil {
+			return err
+		}
+	}
+	if f.opts.usageReport != nil {
+		*f.opts.usageReport = ctx.usageReport()
+	}
+	fp := NewFormulaProcessor()
+	fp.logger = f.opts.logger
+	fp.ProcessAreaFormulas(tx, target)
+	ctx.state.includes.processFormulas(fp)
+	if err := tx.placeComments(f.opts.stripMarkup, f.opts.markup()); err != nil {
+		return err
+	}
+	removed, err := ctx.deleteRows(tx)
+	if err != nil {
+		return err
+	}
+	size = trimRemovedRows(AreaResult{Target: start, Size: size}, removed[start.Sheet]).Size
+
+	if target.Name != "" && size.Width > 0 && size.Height > 0 {
+		if err := tx.setAreaName(target.Name, outputRef(start, size)); err != nil {
+			return err
+		}
+	}
+	if err := dst.Save(); err != nil {
+		return fmt.Errorf("save workbook %q: %w", existingPath, err)
+	}
+	return nil
+}
+
+// findArea returns the root area with the given name or start cell.
+func findArea(areas []*Area, area string) (*Area, error) {
+	for _, a := range areas {
+		if a.Name != "" && a.Name == area {
+			return a, nil
+		}
+	}
+	if ref, err := ParseCellRef(area); err == nil && ref.Sheet != "" {
+		for _, a := range areas {
+			if a.StartCell == ref {
+				return a, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no area named or starting at %q in template", area)
+}
+
+// namedRange returns the range of the workbook defined name, or nil if the
+// workbook has none.
+func namedRange(tx *ExcelizeTransformer, name string) (*AreaRef, error) {
+	for _, dn := range tx.file.GetDefinedName() {
+		if dn.Name != name || dn.Scope != "Workbook" {
+			continue
+		}
+		ref, err := ParseAreaRef(strings.ReplaceAll(dn.RefersTo, "$", ""))
+		if err != nil {
+			return nil, fmt.Errorf("defined name %q: %w", name, err)
+		}
+		return &ref, nil
+	}
+	return nil, nil
+}
+
+// clearRange clears the cells of ref.
+func clearRange(tx *ExcelizeTransformer, ref AreaRef) {
+	for row := ref.First.Row; row <= ref.Last.Row; row++ {
+		for col := ref.First.Col; col <= ref.Last.Col; col++ {
+			tx.ClearCell(NewCellRef(ref.First.Sheet, row, col))
+		}
+	}
+}
+
+// checkGrowth returns an error if output, the new output range of the named
+// area, covers cells outside saved, its previous range, that hold content in
+// the workbook at path. Those cells belong to other areas or to the user.
+func checkGrowth(path, name string, saved, output AreaRef) error {
+	if output.Last.Row <= saved.Last.Row && output.Last.Col <= saved.Last.Col {
+		return nil
+	}
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return fmt.Errorf("open workbook %q: %w", path, err)
+	}
+	defer f.Close()
+	for row := output.First.Row; row <= output.Last.Row; row++ {
+		for col := output.First.Col; col <= output.Last.Col; col++ {
+			ref := NewCellRef(output.First.Sheet, row, col)
+			if saved.Contains(ref) {
+				continue
+			}
+			cell := ref.CellName()
+			value, _ := f.GetCellValue(ref.Sheet, cell)
+			formula, _ := f.GetCellFormula(ref.Sheet, cell)
+			if value != "" || formula != "" {
+				return fmt.Errorf("area %q grew from %s to %s over content at %s; run a full fill instead",
+					name, saved, output, ref)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createNamedAreaTemplate creates a template with a named "today" area (A1:B2)
+// listing numbers and a static title area at D1.
+func createNamedAreaTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Today")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Amount}")
+	f.SetCellValue("Sheet1", "D1", "${title}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2" name="today")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="B2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "D1", Author: "xlfill", Text: `jx:area(lastCell="D1")`})
+
+	path := filepath.Join(testdataDir(t), "named_area_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestFillArea_RefreshesNamedArea(t *testing.T) {
+	tmpl := createNamedAreaTemplate(t)
+	out := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, Fill(tmpl, out, map[string]any{
+		"title": "Daily",
+		"items": []map[string]any{{"Name": "a", "Amount": 1}, {"Name": "b", "Amount": 2}, {"Name": "c", "Amount": 3}},
+	}))
+
+	// The output range of the named area is saved as a defined name
+	f, err := excelize.OpenFile(out)
+	require.NoError(t, err)
+	var refersTo string
+	for _, dn := range f.GetDefinedName() {
+		if dn.Name == "today" {
+			refersTo = dn.RefersTo
+		}
+	}
+	assert.Equal(t, "Sheet1!$A$1:$B$4", refersTo)
+
+	// A user edit outside the area
+	f.SetCellValue("Sheet1", "F10", "note")
+	require.NoError(t, f.Save())
+	f.Close()
+
+	require.NoError(t, FillArea(tmpl, out, "today", map[string]any{
+		"items": []map[string]any{{"Name": "x", "Amount": 9}},
+	}))
+
+	f, err = excelize.OpenFile(out)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Today", "", "", "Daily"}, rows[0])
+	assert.Equal(t, []string{"x", "9"}, rows[1])
+	assert.Empty(t, rows[2], "rows from the previous, longer output are cleared")
+	assert.Empty(t, rows[3])
+	note, _ := f.GetCellValue("Sheet1", "F10")
+	assert.Equal(t, "note", note)
+
+	for _, dn := range f.GetDefinedName() {
+		if dn.Name == "today" {
+			assert.Equal(t, "Sheet1!$A$1:$B$2", dn.RefersTo)
+		}
+	}
+}
+
+func TestFillArea_ByStartCell(t *testing.T) {
+	tmpl := createNamedAreaTemplate(t)
+	out := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, Fill(tmpl, out, map[string]any{"title": "Daily", "items": []map[string]any{}}))
+
+	require.NoError(t, FillArea(tmpl, out, "Sheet1!D1", map[string]any{"title": "Weekly"}))
+
+	f, err := excelize.OpenFile(out)
+	require.NoError(t, err)
+	defer f.Close()
+	v, _ := f.GetCellValue("Sheet1", "D1")
+	assert.Equal(t, "Weekly", v)
+}
+
+func TestFillArea_UnknownArea(t *testing.T) {
+	tmpl := createNamedAreaTemplate(t)
+	out := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, Fill(tmpl, out, map[string]any{"title": "Daily", "items": []map[string]any{}}))
+
+	err := FillArea(tmpl, out, "tomorrow", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"tomorrow"`)
+}
+
+// createStackedAreaTemplate creates a template with a list area at A1:A2 above
+// a named "today" area at A4:B5, so the named area moves down in the output
+// when the list grows.
+func createStackedAreaTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Notes")
+	f.SetCellValue("Sheet1", "A2", "${n}")
+	f.SetCellValue("Sheet1", "A4", "Today")
+	f.SetCellValue("Sheet1", "A5", "${e.Name}")
+	f.SetCellValue("Sheet1", "B5", "${e.Amount}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="notes" var="n" lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A4", Author: "xlfill", Text: `jx:area(lastCell="B5" name="today")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A5", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="B5")`})
+
+	path := filepath.Join(testdataDir(t), "stacked_area_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestFillArea_AtSavedPosition(t *testing.T) {
+	tmpl := createStackedAreaTemplate(t)
+	out := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, Fill(tmpl, out, map[string]any{
+		"notes": []string{"n1", "n2", "n3"},
+		"items": []map[string]any{{"Name": "a", "Amount": 1}, {"Name": "b", "Amount": 2}},
+	}))
+
+	require.NoError(t, FillArea(tmpl, out, "today", map[string]any{
+		"items": []map[string]any{{"Name": "x", "Amount": 9}},
+	}))
+
+	f, err := excelize.OpenFile(out)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	// The named area is refreshed where the full fill put it, below the
+	// grown list, not at its template position A4
+	assert.Equal(t, [][]string{{"Notes"}, {"n1"}, {"n2"}, {"n3"}, nil, {"Today"}, {"x", "9"}}, rows)
+	for _, dn := range f.GetDefinedName() {
+		if dn.Name == "today" {
+			assert.Equal(t, "Sheet1!$A$6:$B$7", dn.RefersTo)
+		}
+	}
+}
+
+func TestFillArea_GrowsOverContent(t *testing.T) {
+	tmpl := createNamedAreaTemplate(t)
+	out := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, Fill(tmpl, out, map[string]any{
+		"title": "Daily",
+		"items": []map[string]any{{"Name": "a", "Amount": 1}},
+	}))
+	f, err := excelize.OpenFile(out)
+	require.NoError(t, err)
+	f.SetCellValue("Sheet1", "A4", "note")
+	require.NoError(t, f.Save())
+	f.Close()
+
+	err = FillArea(tmpl, out, "today", map[string]any{
+		"items": []map[string]any{{"Name": "x", "Amount": 9}, {"Name": "y", "Amount": 8}, {"Name": "z", "Amount": 7}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Sheet1!A4")
+
+	// The workbook is left as it was
+	f, err = excelize.OpenFile(out)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Today", "", "", "Daily"}, {"a", "1"}, nil, {"note"}}, rows)
+
+	// Growing into empty cells is fine
+	require.NoError(t, FillArea(tmpl, out, "today", map[string]any{
+		"items": []map[string]any{{"Name": "x", "Amount": 9}, {"Name": "y", "Amount": 8}},
+	}))
+}
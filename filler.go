@@ -1,6 +1,7 @@
 package xlfill
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -10,6 +11,9 @@ import (
 type Filler struct {
 	opts     *Options
 	registry *CommandRegistry
+
+	areaDefs    []AreaDefinition // decoded WithAreaDefinitions input, read on first use
+	areaDefsErr error
 }
 
 // NewFiller creates a Filler with the given options.
@@ -27,35 +31,71 @@ func NewFiller(opts ...Option) *Filler {
 
 // BuildAreas parses all commented cells in the transformer and builds the Area/Command hierarchy.
 // It finds jx:area commands as root areas, then nests other commands within their containing area.
+// A lastCell above or left of its command's cell and a command reaching outside
+// its jx:area are errors, naming the comment cell and a lastCell that fits.
 func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
-	commented := tx.GetCommentedCells()
-	if len(commented) == 0 {
-		return nil, fmt.Errorf("no commented cells found in template")
+	areas, problems, err := f.buildAreas(tx)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		errs := make([]error, len(problems))
+		for i, p := range problems {
+			errs[i] = fmt.Errorf("%s: %s", p.CellRef, p.Message)
+		}
+		return nil, errors.Join(errs...)
+	}
+	return areas, nil
+}
+
+// buildAreas builds the areas like BuildAreas, returning the lastCell problems
+// it finds as issues. Commands with an inverted range are left out.
+func (f *Filler) buildAreas(tx Transformer) ([]*Area, []ValidationIssue, error) {
+	sources, err := f.commandSources(tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sources) == 0 {
+		return nil, nil, fmt.Errorf("no commented cells found in template")
 	}
 
 	type parsedCell struct {
-		cellData *CellData
+		ref      CellRef
+		cellData *CellData // nil for empty cells named by area definitions
 		commands []ParsedCommand
 		params   *ParamsData
 	}
 
 	var parsed []parsedCell
-	for _, cd := range commented {
-		cmds, params, _ := ParseComment(cd.Comment, cd.Ref)
+	for _, src := range sources {
+		cmds, params, err := f.opts.markup().parse(src.comment, src.ref)
+		if err != nil && f.opts.areaDefinitions != nil {
+			return nil, nil, err
+		}
 		if len(cmds) > 0 || params != nil {
-			parsed = append(parsed, parsedCell{cellData: cd, commands: cmds, params: params})
+			parsed = append(parsed, parsedCell{ref: src.ref, cellData: src.cellData, commands: cmds, params: params})
 		}
 	}
 
+	var problems []ValidationIssue
+
 	// Apply params to cell data
 	for _, p := range parsed {
-		if p.params != nil {
+		if p.params != nil && p.params.FormulaStrategyName != "" {
+			if issue := f.unknownFormulaStrategy(p.ref, p.params.FormulaStrategyName); issue != nil {
+				problems = append(problems, *issue)
+			}
+		}
+		if p.params != nil && p.cellData != nil {
 			if p.params.DefaultValue != "" {
 				p.cellData.DefaultValue = p.params.DefaultValue
 			}
 			if p.params.FormulaStrategy != FormulaDefault {
 				p.cellData.FormulaStrategy = p.params.FormulaStrategy
 			}
+			if p.params.FormulaStrategyName != "" {
+				p.cellData.FormulaStrategyName = p.params.FormulaStrategyName
+			}
 		}
 	}
 
@@ -72,10 +112,14 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 				continue
 			}
 
-			startRef := p.cellData.Ref
+			startRef := p.ref
 			endRef, err := resolveLastCell(startRef, lastCell)
 			if err != nil {
-				return nil, fmt.Errorf("parse area lastCell %q: %w", lastCell, err)
+				return nil, nil, fmt.Errorf("parse area lastCell %q: %w", lastCell, err)
+			}
+			if issue := invertedRange("area", startRef, endRef, lastCell); issue != nil {
+				problems = append(problems, *issue)
+				continue
 			}
 
 			areaSize := Size{
@@ -84,19 +128,31 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 			}
 
 			area := NewArea(startRef, areaSize, tx)
+			area.Name = cmd.Attrs["name"]
+			area.TemplateSheet, err = parseSheetDisposition(cmd.Attrs["templateSheet"])
+			if err != nil {
+				return nil, nil, fmt.Errorf("area at %s: %w", startRef, err)
+			}
 			rootAreas = append(rootAreas, area)
 		}
 	}
 
+	if len(rootAreas) == 0 && len(problems) > 0 {
+		return nil, problems, nil
+	}
 	if len(rootAreas) == 0 {
-		return nil, fmt.Errorf("no jx:area commands found in template")
+		return nil, nil, fmt.Errorf("no jx:area commands found in template")
 	}
+	sortRootAreas(rootAreas, tx.GetSheetNames())
 
 	// Collect all non-area commands with their parsed info
 	type commandInfo struct {
-		command  Command
-		startRef CellRef
-		size     Size
+		command   Command
+		startRef  CellRef
+		size      Size // template range the command covers, e.g. with its else or footer area
+		rangeSize Size // range from the command's cell to its lastCell
+		renderIf  string
+		attrs     map[string]string
 	}
 	var allCommands []commandInfo
 
@@ -108,7 +164,7 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 
 			command, err := f.registry.Create(cmd.Name, cmd.Attrs)
 			if err != nil {
-				return nil, fmt.Errorf("create command %q at %s: %w", cmd.Name, p.cellData.Ref, err)
+				return nil, nil, fmt.Errorf("create command %q at %s: %w", cmd.Name, p.ref, err)
 			}
 			if command == nil {
 				continue // unknown command, silently ignored
@@ -120,10 +176,14 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 				continue
 			}
 
-			cmdStartRef := p.cellData.Ref
+			cmdStartRef := p.ref
 			cmdEndRef, err := resolveLastCell(cmdStartRef, lastCell)
 			if err != nil {
-				return nil, fmt.Errorf("parse command lastCell %q: %w", lastCell, err)
+				return nil, nil, fmt.Errorf("parse command lastCell %q: %w", lastCell, err)
+			}
+			if issue := invertedRange(cmd.Name, cmdStartRef, cmdEndRef, lastCell); issue != nil {
+				problems = append(problems, *issue)
+				continue
 			}
 
 			cmdSize := Size{
@@ -135,24 +195,32 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 			innerArea := NewArea(cmdStartRef, cmdSize, tx)
 			attachArea(command, innerArea)
 
-			// Handle if command else area (from "areas" attribute)
+			// Handle if command areas (from "areas", "ifArea" and "elseArea" attributes)
 			if ifCmd, ok := command.(*IfCommand); ok {
-				// Use parsed Areas field if available (from areas=[...] syntax)
-				if len(cmd.Areas) >= 2 {
-					elseAreaRef := cmd.Areas[1]
-					elseSize := elseAreaRef.Size()
-					ifCmd.ElseArea = NewArea(elseAreaRef.First, elseSize, tx)
-				} else if areasAttr := cmd.Attrs["areas"]; areasAttr != "" {
-					if err := f.buildIfElseArea(ifCmd, areasAttr, cmdStartRef, tx); err != nil {
-						return nil, err
-					}
+				if cmdSize, err = f.buildIfAreas(ifCmd, cmd, cmdStartRef, cmdEndRef, tx); err != nil {
+					return nil, nil, err
+				}
+			}
+
+			// Handle each command footer area; the command covers it as well
+			if each, ok := command.(*EachCommand); ok && cmd.Attrs["footerArea"] != "" {
+				footerRef, err := buildEachFooterArea(each, cmd.Attrs["footerArea"], cmdStartRef, cmdEndRef, tx)
+				if err != nil {
+					return nil, nil, err
+				}
+				cmdSize = Size{
+					Width:  max(cmdEndRef.Col, footerRef.Last.Col) - cmdStartRef.Col + 1,
+					Height: footerRef.Last.Row - cmdStartRef.Row + 1,
 				}
 			}
 
 			allCommands = append(allCommands, commandInfo{
-				command:  command,
-				startRef: cmdStartRef,
-				size:     cmdSize,
+				command:   command,
+				startRef:  cmdStartRef,
+				size:      cmdSize,
+				rangeSize: innerArea.AreaSize,
+				renderIf:  cmd.RenderIf,
+				attrs:     cmd.Attrs,
 			})
 		}
 	}
@@ -166,9 +234,12 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 
 	// Build command tree: each command goes into the smallest strictly-larger
 	// containing command's area, or into the root area if no parent command contains it.
-	// Commands with equal area size are siblings, not parent-child.
+	// Commands with equal area size are siblings, not parent-child, except a
+	// jx:deleteRowIf, which nests in the command sharing its rows. A command's
+	// own range is compared, so a jx:if whose else area makes it as large as an
+	// enclosing jx:each is still nested in it.
 	for i, ci := range allCommands {
-		ciArea := ci.size.Width * ci.size.Height
+		ciArea := ci.rangeSize.Width * ci.rangeSize.Height
 		placed := false
 
 		// Find the tightest parent: the smallest command whose area strictly contains ci
@@ -181,14 +252,10 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 			}
 			cjArea := cj.size.Width * cj.size.Height
 			// Parent must be strictly larger
-			if cjArea <= ciArea {
+			if cjArea < ciArea || cjArea == ciArea && !nestsInEqual(ci.command, cj.command) {
 				continue
 			}
-			parentArea := getCommandArea(cj.command)
-			if parentArea == nil {
-				continue
-			}
-			if !parentArea.containsRef(ci.startRef) {
+			if commandAreaAt(cj.command, ci.startRef) == nil {
 				continue
 			}
 			// Is this the tightest (smallest valid parent)?
@@ -199,8 +266,10 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 		}
 
 		if bestParentIdx >= 0 {
-			parentArea := getCommandArea(allCommands[bestParentIdx].command)
+			parentArea := commandAreaAt(allCommands[bestParentIdx].command, ci.startRef)
 			parentArea.AddCommand(ci.command, ci.startRef, ci.size)
+			binding := parentArea.Bindings[len(parentArea.Bindings)-1]
+			binding.RenderIf, binding.Attrs = ci.renderIf, ci.attrs
 			placed = true
 		}
 
@@ -209,18 +278,35 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 			for _, rootArea := range rootAreas {
 				if rootArea.containsRef(ci.startRef) {
 					rootArea.AddCommand(ci.command, ci.startRef, ci.size)
+					binding := rootArea.Bindings[len(rootArea.Bindings)-1]
+					binding.RenderIf, binding.Attrs = ci.renderIf, ci.attrs
+					if issue := outsideArea(binding, rootArea); issue != nil {
+						problems = append(problems, *issue)
+					}
+					placed = true
 					break
 				}
 			}
 		}
+		if !placed {
+			problems = append(problems, ValidationIssue{
+				Severity: SeverityError,
+				CellRef:  ci.startRef,
+				Message: fmt.Sprintf("jx:%s is outside every jx:area; move the comment into an area or extend the area's lastCell to include %s",
+					ci.command.Name(), ci.startRef.CellName()),
+			})
+		}
 	}
 
 	// Sort each area's bindings by row then column for deterministic processing
 	sortAreaBindings(rootAreas)
 	for _, ci := range allCommands {
-		if area := getCommandArea(ci.command); area != nil && len(area.Bindings) > 0 {
-			sortAreaBindings([]*Area{area})
-		}
+		sortAreaBindings(childAreas(ci.command))
+	}
+	bindHighlightRows(rootAreas)
+	problems = append(problems, bindMacros(rootAreas)...)
+	if f.opts.logger != nil {
+		traceAreas(f.opts.logger, rootAreas)
 	}
 
 	// Propagate listeners to all areas (root + command inner areas)
@@ -230,54 +316,141 @@ func (f *Filler) BuildAreas(tx Transformer) ([]*Area, error) {
 		}
 	}
 
-	return rootAreas, nil
+	return rootAreas, problems, nil
+}
+
+// invertedRange reports a lastCell above or left of the command cell start.
+func invertedRange(name string, start, end CellRef, lastCell string) *ValidationIssue {
+	if end.Sheet != start.Sheet || (end.Row >= start.Row && end.Col >= start.Col) {
+		return nil
+	}
+	fix := NewCellRef("", max(start.Row, end.Row), max(start.Col, end.Col))
+	return &ValidationIssue{
+		Severity: SeverityError,
+		CellRef:  start,
+		Message: fmt.Sprintf("jx:%s lastCell %q is above or left of its cell %s; lastCell names the bottom-right cell of the range, e.g. lastCell=%q",
+			name, lastCell, start.CellName(), fix.CellName()),
+	}
+}
+
+// outsideArea reports a command binding that reaches past the bottom or right
+// edge of its jx:area.
+func outsideArea(b *CommandBinding, area *Area) *ValidationIssue {
+	ref := area.SourceRef()
+	last := NewCellRef(b.StartRef.Sheet, b.StartRef.Row+b.Size.Height-1, b.StartRef.Col+b.Size.Width-1)
+	if ref.Contains(last) {
+		return nil
+	}
+	fit := NewCellRef("", min(last.Row, ref.Last.Row), min(last.Col, ref.Last.Col))
+	grown := NewCellRef("", max(last.Row, ref.Last.Row), max(last.Col, ref.Last.Col))
+	return &ValidationIssue{
+		Severity: SeverityError,
+		CellRef:  b.StartRef,
+		Message: fmt.Sprintf("jx:%s range %s reaches outside its jx:area %s; use lastCell=%q or extend the area to lastCell=%q",
+			b.Command.Name(), NewAreaRef(b.StartRef, last), ref, fit.CellName(), grown.CellName()),
+	}
 }
 
 // propagateListeners sets listeners on an area and all its child command areas recursively.
 func (f *Filler) propagateListeners(area *Area) {
 	area.Listeners = f.opts.areaListeners
 	for _, b := range area.Bindings {
-		switch c := b.Command.(type) {
-		case *EachCommand:
-			if c.Area != nil {
-				f.propagateListeners(c.Area)
-			}
-		case *IfCommand:
-			if c.IfArea != nil {
-				f.propagateListeners(c.IfArea)
-			}
-			if c.ElseArea != nil {
-				f.propagateListeners(c.ElseArea)
-			}
-		case *GridCommand:
-			if c.BodyArea != nil {
-				f.propagateListeners(c.BodyArea)
-			}
-		case *UpdateCellCommand:
-			if c.Area != nil {
-				f.propagateListeners(c.Area)
-			}
-		case *AutoRowHeightCommand:
-			if c.Area != nil {
-				f.propagateListeners(c.Area)
-			}
+		for _, child := range childAreas(b.Command) {
+			f.propagateListeners(child)
 		}
 	}
 }
 
-// getCommandArea returns the inner area of a command, or nil if the command type has no area.
-func getCommandArea(cmd Command) *Area {
+// areaField returns the field holding the inner area of a command, or nil if
+// the command type has no area.
+func areaField(cmd Command) **Area {
 	switch c := cmd.(type) {
 	case *EachCommand:
-		return c.Area
+		return &c.Area
 	case *IfCommand:
-		return c.IfArea
+		return &c.IfArea
 	case *UpdateCellCommand:
-		return c.Area
+		return &c.Area
 	case *GridCommand:
-		return c.BodyArea
+		return &c.BodyArea
 	case *AutoRowHeightCommand:
-		return c.Area
+		return &c.Area
+	case *SortStateCommand:
+		return &c.Area
+	case *MaskCommand:
+		return &c.Area
+	case *HighlightCommand:
+		return &c.Area
+	case *AnchorCommand:
+		return &c.Area
+	case *RawCommand:
+		return &c.Area
+	case *SheetPropsCommand:
+		return &c.Area
+	case *DeleteRowIfCommand:
+		return &c.Area
+	case *IncludeCommand:
+		return &c.Area
+	case *DefineCommand:
+		return &c.Area
+	case *CallCommand:
+		return &c.Area
+	case *ImageCommand:
+		return &c.Area
+	}
+	return nil
+}
+
+// getCommandArea returns the inner area of a command, or nil if the command
+// type has no area. The cells of a jx:image are covered by the picture and
+// not rendered, so its area is not returned.
+func getCommandArea(cmd Command) *Area {
+	if _, ok := cmd.(*ImageCommand); ok {
+		return nil
+	}
+	if field := areaField(cmd); field != nil {
+		return *field
+	}
+	return nil
+}
+
+// childAreas returns the areas a command renders: its inner area and the
+// footer area of a jx:each or the else area of a jx:if. Code walking the
+// area tree descends through it.
+func childAreas(cmd Command) []*Area {
+	var areas []*Area
+	if area := getCommandArea(cmd); area != nil {
+		areas = append(areas, area)
+	}
+	switch c := cmd.(type) {
+	case *EachCommand:
+		if c.Footer != nil {
+			areas = append(areas, c.Footer)
+		}
+	case *IfCommand:
+		if c.ElseArea != nil {
+			areas = append(areas, c.ElseArea)
+		}
+	}
+	return areas
+}
+
+// nestsInEqual reports whether child nests in a parent command of the same
+// size: a jx:deleteRowIf applies to the rows of the command it is written with.
+func nestsInEqual(child, parent Command) bool {
+	_, isDelete := child.(*DeleteRowIfCommand)
+	_, parentDelete := parent.(*DeleteRowIfCommand)
+	return isDelete && !parentDelete
+}
+
+// commandAreaAt returns the area of cmd containing ref: its inner area, the
+// footer area of a jx:each or the else area of a jx:if. It returns nil when
+// none contains ref.
+func commandAreaAt(cmd Command, ref CellRef) *Area {
+	for _, area := range childAreas(cmd) {
+		if area.containsRef(ref) {
+			return area
+		}
 	}
 	return nil
 }
@@ -295,6 +468,70 @@ func sortAreaBindings(areas []*Area) {
 	}
 }
 
+// buildEachFooterArea parses the "footerArea" attribute of an each command. The
+// footer must start on the row below the command's lastCell, at or right of its
+// first column.
+func buildEachFooterArea(each *EachCommand, footerAttr string, cmdStart, cmdEnd CellRef, tx Transformer) (AreaRef, error) {
+	footerRef, err := ParseAreaRef(footerAttr)
+	if err != nil {
+		return AreaRef{}, fmt.Errorf("parse each footerArea %q: %w", footerAttr, err)
+	}
+	if footerRef.First.Sheet == "" {
+		footerRef.First.Sheet, footerRef.Last.Sheet = cmdStart.Sheet, cmdStart.Sheet
+	}
+	if footerRef.First.Sheet != cmdStart.Sheet || footerRef.First.Row != cmdEnd.Row+1 || footerRef.First.Col < cmdStart.Col {
+		return AreaRef{}, fmt.Errorf("each at %s: footerArea %q must start on the row below lastCell, at or right of column %s",
+			cmdStart, footerAttr, ColToName(cmdStart.Col))
+	}
+	each.Footer = NewArea(footerRef.First, footerRef.Size(), tx)
+	return footerRef, nil
+}
+
+// buildIfAreas sets up the if and else areas of an IfCommand and returns the
+// template size the command covers. The areas come from areas=["A2:C2",
+// "A3:C3"] (if area first) or from the ifArea and elseArea attributes; the if
+// area defaults to the command's own range. Either may be on another sheet. An
+// else area starting directly below or right of the command's range is covered
+// by the command, so its template cells are not also rendered as static cells.
+func (f *Filler) buildIfAreas(ifCmd *IfCommand, cmd ParsedCommand, cmdStart, cmdEnd CellRef, tx Transformer) (Size, error) {
+	if len(cmd.Areas) > 0 {
+		ifCmd.IfArea = NewArea(cmd.Areas[0].First, cmd.Areas[0].Size(), tx)
+	}
+	if len(cmd.Areas) > 1 {
+		ifCmd.ElseArea = NewArea(cmd.Areas[1].First, cmd.Areas[1].Size(), tx)
+	} else if areasAttr := cmd.Attrs["areas"]; areasAttr != "" {
+		if err := f.buildIfElseArea(ifCmd, areasAttr, cmdStart, tx); err != nil {
+			return ZeroSize, err
+		}
+	}
+	for _, attr := range []string{"ifArea", "elseArea"} {
+		value := cmd.Attrs[attr]
+		if value == "" {
+			continue
+		}
+		ref, err := parseCommandAreaRef(value, cmdStart.Sheet)
+		if err != nil {
+			return ZeroSize, fmt.Errorf("parse if %s %q: %w", attr, value, err)
+		}
+		if attr == "ifArea" {
+			ifCmd.IfArea = NewArea(ref.First, ref.Size(), tx)
+		} else {
+			ifCmd.ElseArea = NewArea(ref.First, ref.Size(), tx)
+		}
+	}
+
+	cover := NewAreaRef(cmdStart, cmdEnd)
+	if e := ifCmd.ElseArea; e != nil && e.StartCell.Sheet == cmdStart.Sheet {
+		below := e.StartCell.Row == cmdEnd.Row+1 && e.StartCell.Col >= cmdStart.Col
+		beside := e.StartCell.Col == cmdEnd.Col+1 && e.StartCell.Row >= cmdStart.Row
+		if below || beside {
+			last := e.SourceRef().Last
+			cover.Last = NewCellRef(cmdStart.Sheet, max(cmdEnd.Row, last.Row), max(cmdEnd.Col, last.Col))
+		}
+	}
+	return cover.Size(), nil
+}
+
 // buildIfElseArea parses the "areas" attribute to set up the else area for an IfCommand.
 // Format: areas=["A2:C2", "A3:C3"] — first is if area (already set), second is else area.
 func (f *Filler) buildIfElseArea(ifCmd *IfCommand, areasAttr string, cmdStart CellRef, tx Transformer) error {
@@ -310,38 +547,32 @@ func (f *Filler) buildIfElseArea(ifCmd *IfCommand, areasAttr string, cmdStart Ce
 		return nil
 	}
 
-	// Parse the else area reference
-	areaRef, err := ParseAreaRef(cmdStart.Sheet + "!" + elseRef)
+	areaRef, err := parseCommandAreaRef(elseRef, cmdStart.Sheet)
 	if err != nil {
-		// Try without sheet
-		areaRef, err = ParseAreaRef(elseRef)
-		if err != nil {
-			return fmt.Errorf("parse if else area %q: %w", elseRef, err)
-		}
-		if areaRef.First.Sheet == "" {
-			areaRef.First.Sheet = cmdStart.Sheet
-			areaRef.Last.Sheet = cmdStart.Sheet
-		}
+		return fmt.Errorf("parse if else area %q: %w", elseRef, err)
 	}
-
-	elseSize := areaRef.Size()
-	ifCmd.ElseArea = NewArea(areaRef.First, elseSize, tx)
+	ifCmd.ElseArea = NewArea(areaRef.First, areaRef.Size(), tx)
 	return nil
 }
 
-// attachArea attaches an inner area to a command based on its type.
+// parseCommandAreaRef parses an area reference given in a command attribute,
+// such as "A3:C3" or "'Else Sheet'!A1:C1". A reference without a sheet is on
+// sheet.
+func parseCommandAreaRef(s, sheet string) (AreaRef, error) {
+	ref, err := ParseAreaRef(strings.Trim(strings.TrimSpace(s), `"`))
+	if err != nil {
+		return AreaRef{}, err
+	}
+	if ref.First.Sheet == "" {
+		ref.First.Sheet, ref.Last.Sheet = sheet, sheet
+	}
+	return ref, nil
+}
+
+// attachArea sets the inner area of a command.
 func attachArea(cmd Command, area *Area) {
-	switch c := cmd.(type) {
-	case *EachCommand:
-		c.Area = area
-	case *IfCommand:
-		c.IfArea = area
-	case *UpdateCellCommand:
-		c.Area = area
-	case *GridCommand:
-		c.BodyArea = area
-	case *AutoRowHeightCommand:
-		c.Area = area
+	if field := areaField(cmd); field != nil {
+		*field = area
 	}
 }
 
@@ -369,3 +600,22 @@ func resolveLastCell(start CellRef, lastCell string) (CellRef, error) {
 	ref.Sheet = start.Sheet
 	return ref, nil
 }
+
+// unknownFormulaStrategy reports a jx:params formulaStrategy that is neither
+// built in nor registered with WithFormulaStrategy, listing the accepted names.
+func (f *Filler) unknownFormulaStrategy(ref CellRef, name string) *ValidationIssue {
+	var registered []string
+	for r := range f.opts.formulaStrategies {
+		if strings.EqualFold(r, name) {
+			return nil
+		}
+		registered = append(registered, strings.ToUpper(r))
+	}
+	sort.Strings(registered)
+	accepted := append([]string{"DEFAULT", "BY_COLUMN", "BY_ROW"}, registered...)
+	return &ValidationIssue{
+		Severity: SeverityError,
+		CellRef:  ref,
+		Message:  fmt.Sprintf("unknown formulaStrategy %q (accepted: %s)", name, strings.Join(accepted, ", ")),
+	}
+}
@@ -2,9 +2,11 @@ package xlfill
 
 import (
 	"bytes"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -162,6 +164,49 @@ func TestFill_OutputToBytes(t *testing.T) {
 	assert.Equal(t, "Bob", v)
 }
 
+func TestFillFile(t *testing.T) {
+	tmpl := createIntegrationTemplate(t)
+	data := map[string]any{
+		"employees": []any{
+			map[string]any{"Name": "Alice", "Age": 30, "Salary": 5000.0},
+			map[string]any{"Name": "Bob", "Age": 40, "Salary": 8000.0},
+		},
+	}
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		f, err := excelize.OpenFile(tmpl)
+		require.NoError(t, err)
+		filled, err := FillFile(f, data, opts...)
+		require.NoError(t, err)
+		assert.Same(t, f, filled)
+		assert.Equal(t, tmpl, f.Path, "the path is kept for SaveAs")
+
+		// The file stays usable for further changes
+		require.NoError(t, f.SetCellValue("Sheet1", "D1", "added"))
+		v, _ := f.GetCellValue("Sheet1", "A3")
+		assert.Equal(t, "Bob", v)
+		buf, err := f.WriteToBuffer()
+		require.NoError(t, err)
+		res := openOutput(t, buf.Bytes())
+		v, _ = res.GetCellValue("Sheet1", "D1")
+		assert.Equal(t, "added", v)
+		v, _ = res.GetCellValue("Sheet1", "C3")
+		assert.Equal(t, "8000", v)
+		require.NoError(t, f.Close())
+	}
+
+	f, err := excelize.OpenFile(tmpl)
+	require.NoError(t, err)
+	defer f.Close()
+	result, err := NewFiller().Apply(f, data)
+	require.NoError(t, err)
+	assert.Nil(t, result.Output)
+	assert.Equal(t, []CellRef{NewCellRef("Sheet1", 1, 0), NewCellRef("Sheet1", 2, 0)}, result.TargetsOf(NewCellRef("Sheet1", 1, 0)))
+
+	_, err = FillFile(excelize.NewFile(), data)
+	assert.ErrorContains(t, err, "no commented cells")
+}
+
 func TestFill_PreservesFormatting(t *testing.T) {
 	tmpl := createIntegrationTemplate(t)
 
@@ -214,6 +259,31 @@ func TestFill_TemplateFromReader(t *testing.T) {
 	assert.Equal(t, "ReaderTest", v)
 }
 
+func TestFill_TemplateFromFS(t *testing.T) {
+	tmplBytes, err := os.ReadFile(createIntegrationTemplate(t))
+	require.NoError(t, err)
+	fsys := fstest.MapFS{"templates/report.xlsx": {Data: tmplBytes}}
+	data := map[string]any{"employees": []any{map[string]any{"Name": "Alice", "Age": 30, "Salary": 5000.0}}}
+
+	var buf bytes.Buffer
+	require.NoError(t, FillFS(fsys, "templates/report.xlsx", &buf, data))
+	v, _ := openOutput(t, buf.Bytes()).GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "Alice", v)
+
+	out, err := NewFiller(WithTemplateFS(fsys, "templates/report.xlsx")).FillBytes(data)
+	require.NoError(t, err)
+	v, _ = openOutput(t, out).GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "Alice", v)
+
+	desc, err := NewFiller(WithTemplateFS(fsys, "templates/report.xlsx")).Describe()
+	require.NoError(t, err)
+	assert.Contains(t, desc, "Template: templates/report.xlsx\n")
+
+	err = FillFS(fsys, "templates/missing.xlsx", &buf, data)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	assert.ErrorContains(t, err, `open template "templates/missing.xlsx"`)
+}
+
 func TestFill_MapData(t *testing.T) {
 	tmpl := createIntegrationTemplate(t)
 
@@ -236,6 +306,57 @@ func TestFill_MapData(t *testing.T) {
 	assert.Equal(t, "9999", v)
 }
 
+func TestFill_Defaults(t *testing.T) {
+	tmpl := createIntegrationTemplate(t)
+
+	data := map[string]any{"employees": nil}
+	out, err := FillBytes(tmpl, data, WithDefaults(map[string]any{
+		"employees": []any{map[string]any{"Name": "Default", "Age": 1, "Salary": 2.0}},
+	}))
+	require.NoError(t, err)
+	assert.Nil(t, data["employees"], "the data map is not modified")
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+	v, _ := f.GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "Default", v)
+
+	// Data wins over defaults
+	out, err = FillBytes(tmpl, map[string]any{"employees": []any{map[string]any{"Name": "Given"}}},
+		WithDefaults(map[string]any{"employees": []any{map[string]any{"Name": "Default"}}}))
+	require.NoError(t, err)
+	f2, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f2.Close()
+	v, _ = f2.GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "Given", v)
+}
+
+func TestFill_ExpressionFallback(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${e.Qty | 0}")
+	f.SetCellValue("Sheet1", "B1", "${title | 'Report'}")
+	f.SetCellFormula("Sheet1", "A2", "SUM(A1)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B2")` + "\n" + `jx:each(items="rows" var="e" lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "fallback_template.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	out, err := FillBytes(tmpl, map[string]any{"rows": []any{
+		map[string]any{"Qty": 5}, map[string]any{}, map[string]any{"Qty": nil},
+	}})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	for cell, want := range map[string]string{"A1": "5", "A2": "0", "A3": "0", "B1": "Report"} {
+		v, _ := res.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+	formula, _ := res.GetCellFormula("Sheet1", "A4")
+	assert.Equal(t, "SUM(A1:A3)", formula)
+}
+
 func TestFill_StructData(t *testing.T) {
 	type Employee struct {
 		Name   string
@@ -370,3 +491,38 @@ func TestBuildAreas_NoComments(t *testing.T) {
 	_, err = filler.BuildAreas(tx)
 	assert.Error(t, err)
 }
+
+func TestBuildAreas_LastCellChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments map[string]string
+		want     string
+	}{
+		{"inverted command", map[string]string{
+			"A1": `jx:area(lastCell="C5")`, "B3": `jx:each(items="e" var="e" lastCell="A4")`,
+		}, `Sheet1!B3: jx:each lastCell "A4" is above or left of its cell B3; lastCell names the bottom-right cell of the range, e.g. lastCell="B4"`},
+		{"inverted area", map[string]string{
+			"C3": `jx:area(lastCell="A1")`,
+		}, `Sheet1!C3: jx:area lastCell "A1" is above or left of its cell C3`},
+		{"outside area", map[string]string{
+			"A1": `jx:area(lastCell="B2")`, "A2": `jx:each(items="e" var="e" lastCell="C3")`,
+		}, `Sheet1!A2: jx:each range Sheet1!A2:C3 reaches outside its jx:area Sheet1!A1:B2; use lastCell="B2" or extend the area to lastCell="C3"`},
+		{"no area", map[string]string{
+			"A1": `jx:area(lastCell="B2")`, "D4": `jx:if(condition="true" lastCell="D4")`,
+		}, `Sheet1!D4: jx:if is outside every jx:area`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := excelize.NewFile()
+			for cell, text := range tt.comments {
+				require.NoError(t, f.AddComment("Sheet1", excelize.Comment{Cell: cell, Author: "xlfill", Text: text}))
+			}
+			tx, err := NewExcelizeTransformer(f)
+			require.NoError(t, err)
+			defer tx.Close()
+
+			_, err = NewFiller().BuildAreas(tx)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package xlfill
+
+import "fmt"
+
+// applyGroups lists the items of each group one after another and renders the
+// footer below the items of every group, with the group variable bound to the
+// group's GroupData; with groupVar the items see it too. The gap is left
+// between groups rather than between items.
+func (c *EachCommand) applyGroups(cellRef CellRef, ctx *Context, transformer Transformer, groups []any, totalSize *Size, merge *keyMerge) error {
+	count := 0
+	for _, g := range groups {
+		count += len(g.(GroupData).Items)
+	}
+	i := 0
+	for gi, g := range groups {
+		group := g.(GroupData)
+		groupCtx := ctx
+		if c.groupName() != c.Var {
+			groupCtx = ctx.WithVar(c.groupName(), group)
+		}
+		start := totalSize.Height
+		for j, item := range group.Items {
+			gap := 0
+			if j == 0 {
+				gap = c.Gap
+			}
+			if err := c.applyItem(cellRef, groupCtx, transformer, item, i, count, gap, totalSize, merge); err != nil {
+				return err
+			}
+			i++
+		}
+		if err := merge.flush(); err != nil {
+			return err
+		}
+		if start > 0 && totalSize.Height > start {
+			start += c.Gap
+		}
+		footerVars := map[string]any{c.groupName(): group}
+		if c.ItemVar != "" && c.ItemVar != c.groupName() {
+			footerVars[c.ItemVar] = group.Item
+		}
+		if err := c.applyFooter(cellRef, ctx.WithVars(footerVars), start, totalSize); err != nil {
+			return fmt.Errorf("each group %d: %w", gi, err)
+		}
+	}
+	return nil
+}
+
+// applyFooter renders the footer area below the output in totalSize and grows
+// totalSize by it. Formulas in the footer refer to the cells written from row
+// start of the output on, i.e. to the block the footer closes.
+func (c *EachCommand) applyFooter(cellRef CellRef, footerCtx *Context, start int, totalSize *Size) error {
+	offset := c.Footer.StartCell.Col - c.Area.StartCell.Col
+	target := NewCellRef(cellRef.Sheet, cellRef.Row+totalSize.Height, cellRef.Col+offset)
+	size, err := c.Footer.ApplyAt(target, footerCtx)
+	if err != nil {
+		return fmt.Errorf("footer: %w", err)
+	}
+	if err := footerCtx.checkExtent(target, size); err != nil {
+		return fmt.Errorf("footer: %w", err)
+	}
+	if size.Width <= 0 || size.Height <= 0 {
+		return nil
+	}
+	totalSize.Height += size.Height
+	totalSize.Width = max(totalSize.Width, offset+size.Width)
+
+	footer := NewAreaRef(target, NewCellRef(target.Sheet, target.Row+size.Height-1, target.Col+size.Width-1))
+	block := NewAreaRef(NewCellRef(cellRef.Sheet, cellRef.Row+start, cellRef.Col),
+		NewCellRef(cellRef.Sheet, cellRef.Row+totalSize.Height-1, cellRef.Col+totalSize.Width-1))
+	footerCtx.run(func() error {
+		c.Footer.widenFormulaParentArea(footer, block)
+		return nil
+	})
+	return nil
+}
+
+// widenFormulaParentArea ties the formula targets this area recorded with
+// parent from to the larger output range to instead.
+func (a *Area) widenFormulaParentArea(from, to AreaRef) {
+	for _, cd := range a.formulaCells {
+		for i, parent := range cd.TargetParentArea {
+			if parent == from {
+				cd.TargetParentArea[i] = to
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createFooterTemplate creates a template listing sales with the footer in
+// row 2 and a grand total below the each.
+func createFooterTemplate(t *testing.T, each string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${e.Dept}")
+	f.SetCellValue("Sheet1", "B1", "${e.Amount}")
+	f.SetCellValue("Sheet1", "A2", "Subtotal")
+	f.SetCellFormula("Sheet1", "B2", "SUM(B1)")
+	f.SetCellValue("Sheet1", "A3", "Total")
+	f.SetCellFormula("Sheet1", "B3", "SUM(B1)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B3")` + "\n" + each})
+	path := filepath.Join(testdataDir(t), "footer_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+var footerSales = []map[string]any{
+	{"Dept": "Sales", "Amount": 10},
+	{"Dept": "Ops", "Amount": 5},
+	{"Dept": "Sales", "Amount": 20},
+}
+
+func footerFormulas(t *testing.T, res *excelize.File, cells ...string) []string {
+	t.Helper()
+	formulas := make([]string, len(cells))
+	for i, cell := range cells {
+		var err error
+		formulas[i], err = res.GetCellFormula("Sheet1", cell)
+		require.NoError(t, err)
+	}
+	return formulas
+}
+
+func TestFill_EachGroupFooter(t *testing.T) {
+	tmpl := createFooterTemplate(t, `jx:each(items="sales" var="e" groupBy="e.Dept" gap="1" lastCell="B1" footerArea="A2:B2")`)
+	f, err := excelize.OpenFile(tmpl)
+	require.NoError(t, err)
+	f.SetCellValue("Sheet1", "A2", "${e.Item.Dept} subtotal")
+	require.NoError(t, f.Save())
+	f.Close()
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, map[string]any{"sales": footerSales}, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		rows, err := res.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Sales", "10"},
+			{"Sales", "20"},
+			{"Sales subtotal", ""},
+			nil,
+			{"Ops", "5"},
+			{"Ops subtotal", ""},
+			{"Total", ""},
+		}, rows)
+		assert.Equal(t, []string{"SUM(B1:B2)", "SUM(B5)", "SUM(B1,B2,B5)"}, footerFormulas(t, res, "B3", "B6", "B7"))
+	}
+}
+
+func TestFill_EachFooter(t *testing.T) {
+	tmpl := createFooterTemplate(t, `jx:each(items="sales" var="e" lastCell="B1" footerArea="A2:B2")`)
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, map[string]any{"sales": footerSales}, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		rows, err := res.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Sales", "10"},
+			{"Ops", "5"},
+			{"Sales", "20"},
+			{"Subtotal", ""},
+			{"Total", ""},
+		}, rows)
+		assert.Equal(t, []string{"SUM(B1:B3)", "SUM(B1:B3)"}, footerFormulas(t, res, "B4", "B5"))
+	}
+}
+
+func TestFill_EachFooterErrors(t *testing.T) {
+	for _, tt := range []struct {
+		each string
+		want string
+	}{
+		{`jx:each(items="sales" var="e" lastCell="B1" footerArea="A3:B3")`, `footerArea "A3:B3" must start on the row below lastCell`},
+		{`jx:each(items="sales" var="e" lastCell="B1" footerArea="A2")`, `parse each footerArea "A2"`},
+		{`jx:each(items="sales" var="e" direction="RIGHT" lastCell="B1" footerArea="A2:B2")`, "footerArea requires direction DOWN"},
+	} {
+		_, err := FillBytes(createFooterTemplate(t, tt.each), map[string]any{"sales": footerSales})
+		assert.ErrorContains(t, err, tt.want, tt.each)
+	}
+}
+
+func TestInspect_EachFooter(t *testing.T) {
+	tmpl := createFooterTemplate(t, `jx:each(items="sales" var="e" groupBy="e.Dept" lastCell="B1" footerArea="A2:B2")`)
+	f, err := excelize.OpenFile(tmpl)
+	require.NoError(t, err)
+	f.SetCellValue("Sheet1", "A2", "${e.Item.Dept} ${label}")
+	require.NoError(t, f.Save())
+	f.Close()
+
+	model, err := Inspect(tmpl)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"label", "sales"}, model.Variables)
+
+	desc, err := Describe(tmpl)
+	require.NoError(t, err)
+	assert.Contains(t, desc, `each (2x2) items="sales" var="e" groupBy="e.Dept" footerArea="Sheet1!A2:B2"`)
+	assert.Contains(t, desc, "A2: ${e.Item.Dept} ${label}")
+}
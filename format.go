@@ -0,0 +1,119 @@
+package xlfill
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/javajack/xlfill/odsconv"
+	"github.com/xuri/excelize/v2"
+)
+
+// workbookFormats maps the workbook file extensions to the content type of
+// their workbook part.
+var workbookFormats = map[string]string{
+	".xlsx": excelize.ContentTypeSheetML,
+	".xlsm": excelize.ContentTypeMacro,
+	".xltx": excelize.ContentTypeTemplate,
+	".xltm": excelize.ContentTypeTemplateMacro,
+}
+
+// templateFormats maps Excel template extensions to the workbook formats filled
+// from them.
+var templateFormats = map[string]string{
+	".xltx": ".xlsx",
+	".xltm": ".xlsm",
+}
+
+// odsFormat is the extension of OpenDocument spreadsheets, which are converted
+// to and from excelize workbooks by package odsconv, losing what it cannot
+// convert.
+const odsFormat = ".ods"
+
+// openODS reads an OpenDocument spreadsheet into a workbook that
+// workbookFormat reports as ".ods".
+func openODS(r io.Reader) (*excelize.File, error) {
+	file, err := odsconv.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	file.Path = "template" + odsFormat
+	return file, nil
+}
+
+// workbookFormat returns the extension matching the workbook content type of
+// a freshly opened file, or ".xlsx" when it cannot be told.
+func workbookFormat(f *excelize.File) string {
+	if strings.EqualFold(filepath.Ext(f.Path), odsFormat) {
+		return odsFormat
+	}
+	raw, _ := f.Pkg.Load("[Content_Types].xml")
+	data, _ := raw.([]byte)
+	return contentTypesFormat(data)
+}
+
+// contentTypesFormat returns the extension matching the workbook content type
+// in a [Content_Types].xml part, or ".xlsx" when it cannot be told.
+func contentTypesFormat(data []byte) string {
+	var types struct {
+		Overrides []struct {
+			PartName    string `xml:"PartName,attr"`
+			ContentType string `xml:"ContentType,attr"`
+		} `xml:"Override"`
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&types); err != nil {
+		return ".xlsx"
+	}
+	for _, o := range types.Overrides {
+		if o.PartName != "/xl/workbook.xml" {
+			continue
+		}
+		for ext, contentType := range workbookFormats {
+			if o.ContentType == contentType {
+				return ext
+			}
+		}
+	}
+	return ".xlsx"
+}
+
+// outputFormat returns the format to write the output in: that of the output
+// path when it names a workbook format, otherwise the template's, with Excel
+// templates (.xltx, .xltm) producing the matching workbooks.
+func outputFormat(template, outputPath string) string {
+	if ext := strings.ToLower(filepath.Ext(outputPath)); workbookFormats[ext] != "" || ext == odsFormat {
+		return ext
+	}
+	if ext, ok := templateFormats[template]; ok {
+		return ext
+	}
+	return template
+}
+
+// fixedDocTime replaces the creation and modification times of deterministic output.
+const fixedDocTime = "2000-01-01T00:00:00Z"
+
+// normalizeDocProps replaces the document properties that change whenever a
+// workbook is saved with fixed values.
+func (tx *ExcelizeTransformer) normalizeDocProps() error {
+	if tx.ods {
+		return nil // the OpenDocument writer saves no document properties
+	}
+	return tx.file.SetDocProps(&excelize.DocProperties{
+		Created:        fixedDocTime,
+		Modified:       fixedDocTime,
+		LastModifiedBy: "xlfill",
+	})
+}
+
+// setFormat makes Write produce a file of the given workbook format. excelize
+// sets the workbook content type from the file's path when writing.
+func (tx *ExcelizeTransformer) setFormat(ext string) {
+	tx.ods = ext == odsFormat
+	if tx.ods {
+		ext = ".xlsx"
+	}
+	tx.file.Path = "output" + ext
+}
@@ -0,0 +1,263 @@
+package xlfill
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/javajack/xlfill/odsconv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createFormatTemplate saves a one-cell list template with the given extension.
+func createFormatTemplate(t *testing.T, name string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${e}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:each(items=\"items\" var=\"e\" lastCell=\"A1\")"})
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+// workbookContentType returns the content type of the workbook part of an xlsx package.
+func workbookContentType(t *testing.T, out []byte) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	rc, err := zr.Open("[Content_Types].xml")
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return workbookFormats[contentTypesFormat(data)]
+}
+
+var formatData = map[string]any{"items": []any{"a", "b"}}
+
+func TestFill_TemplateFileProducesWorkbook(t *testing.T) {
+	tmpl := createFormatTemplate(t, "format_template.xltx")
+	src, err := os.ReadFile(tmpl)
+	require.NoError(t, err)
+	require.Equal(t, excelize.ContentTypeTemplate, workbookContentType(t, src))
+
+	out, err := FillBytes(tmpl, formatData)
+	require.NoError(t, err)
+	assert.Equal(t, excelize.ContentTypeSheetML, workbookContentType(t, out))
+
+	var buf bytes.Buffer
+	require.NoError(t, FillReader(bytes.NewReader(src), &buf, formatData))
+	assert.Equal(t, excelize.ContentTypeSheetML, workbookContentType(t, buf.Bytes()))
+
+	f := openOutput(t, out)
+	v, _ := f.GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "b", v)
+}
+
+func TestFill_OutputPathSetsFormat(t *testing.T) {
+	tmpl := createFormatTemplate(t, "format_path.xltx")
+	for ext, want := range map[string]string{
+		".xlsx": excelize.ContentTypeSheetML,
+		".xltx": excelize.ContentTypeTemplate,
+		".out":  excelize.ContentTypeSheetML,
+	} {
+		outPath := filepath.Join(t.TempDir(), "report"+ext)
+		require.NoError(t, Fill(tmpl, outPath, formatData))
+		out, err := os.ReadFile(outPath)
+		require.NoError(t, err)
+		assert.Equal(t, want, workbookContentType(t, out), ext)
+	}
+
+	// An ordinary workbook template written to an .xltx path becomes an Excel template
+	outPath := filepath.Join(t.TempDir(), "report.xltx")
+	require.NoError(t, Fill(createFormatTemplate(t, "format_path.xlsx"), outPath, formatData))
+	out, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, excelize.ContentTypeTemplate, workbookContentType(t, out))
+}
+
+func TestOutputFormat(t *testing.T) {
+	assert.Equal(t, ".xlsx", outputFormat(".xltx", ""))
+	assert.Equal(t, ".xlsm", outputFormat(".xltm", ""))
+	assert.Equal(t, ".xlsm", outputFormat(".xlsm", "out.bin"))
+	assert.Equal(t, ".xltx", outputFormat(".xlsx", "out.XLTX"))
+	assert.Equal(t, ".ods", outputFormat(".xlsx", "out.ods"))
+	assert.Equal(t, ".ods", outputFormat(".ods", ""))
+}
+
+// createODSTemplate saves the format template as an OpenDocument spreadsheet.
+func createODSTemplate(t *testing.T) string {
+	t.Helper()
+	f, err := excelize.OpenFile(createFormatTemplate(t, "format_ods.xlsx"))
+	require.NoError(t, err)
+	defer f.Close()
+	f.SetCellValue("Sheet1", "B1", "total")
+	f.SetCellFormula("Sheet1", "C1", "COUNTA(A1:A1)")
+	var buf bytes.Buffer
+	require.NoError(t, odsconv.Write(&buf, f))
+	path := filepath.Join(testdataDir(t), "format_template.ods")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
+
+func TestFill_ODSTemplate(t *testing.T) {
+	tmpl := createODSTemplate(t)
+
+	// An .ods template produces an .ods file unless the output path says otherwise
+	out, err := FillBytes(tmpl, formatData)
+	require.NoError(t, err)
+	require.True(t, odsconv.IsODS(out))
+	f, err := odsconv.Read(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+	for cell, want := range map[string]string{"A1": "a", "A2": "b", "B1": "total"} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+	formula, _ := f.GetCellFormula("Sheet1", "C1")
+	assert.Equal(t, "COUNTA(A1:A1)", formula)
+	comments, _ := f.GetComments("Sheet1")
+	require.Len(t, comments, 1)
+	assert.Equal(t, "A1", comments[0].Cell)
+
+	outPath := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, Fill(tmpl, outPath, formatData))
+	xlsx, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, excelize.ContentTypeSheetML, workbookContentType(t, xlsx))
+
+	// Templates read from a reader are recognized by content
+	src, err := os.ReadFile(tmpl)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, FillReader(bytes.NewReader(src), &buf, formatData))
+	assert.True(t, odsconv.IsODS(buf.Bytes()))
+}
+
+func TestFill_ODSOutputPath(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "report.ods")
+	require.NoError(t, Fill(createFormatTemplate(t, "format_to_ods.xlsx"), outPath, formatData))
+	out, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	require.True(t, odsconv.IsODS(out))
+	f, err := odsconv.Read(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+	v, _ := f.GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "b", v)
+}
+
+func TestFill_DeterministicOutput(t *testing.T) {
+	template := func(name, modified string) string {
+		f, err := excelize.OpenFile(createFormatTemplate(t, name))
+		require.NoError(t, err)
+		defer f.Close()
+		require.NoError(t, f.SetDocProps(&excelize.DocProperties{Created: modified, Modified: modified, LastModifiedBy: "someone"}))
+		require.NoError(t, f.Save())
+		return f.Path
+	}
+	first := template("format_det1.xlsx", "2024-03-01T10:00:00Z")
+	second := template("format_det2.xlsx", "2025-07-15T08:30:00Z")
+
+	out1, err := FillBytes(first, formatData, WithDeterministicOutput(true))
+	require.NoError(t, err)
+	out2, err := FillBytes(second, formatData, WithDeterministicOutput(true))
+	require.NoError(t, err)
+	assert.Equal(t, out1, out2, "outputs differ only in their templates' save times")
+
+	props, err := openOutput(t, out1).GetDocProps()
+	require.NoError(t, err)
+	assert.Equal(t, fixedDocTime, props.Created)
+	assert.Equal(t, fixedDocTime, props.Modified)
+	assert.Equal(t, "xlfill", props.LastModifiedBy)
+
+	plain, err := FillBytes(first, formatData)
+	require.NoError(t, err)
+	props, err = openOutput(t, plain).GetDocProps()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T10:00:00Z", props.Modified)
+}
+
+func TestFill_DeterministicOutputIgnoresTemplatePackaging(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "dept")
+	f.SetCellValue("dept", "A1", "${d.Name}")
+	f.SetCellValue("dept", "A2", "${e}")
+	f.SetCellValue("dept", "B2", "${hyperlink('https://example.com/' + e, e)}")
+	f.SetCellFormula("dept", "B3", "COUNTA(A2)")
+	f.AddComment("dept", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3")` + "\n" +
+		`jx:each(items="depts" var="d" multisheet="names" lastCell="B3")`})
+	f.AddComment("dept", excelize.Comment{Cell: "A2", Author: "xlfill", Text: "Staff\n" + `jx:each(items="d.Staff" var="e" lastCell="B2")`})
+	f.AddComment("dept", excelize.Comment{Cell: "B1", Author: "ann", Text: "Reviewed"})
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	f.Close()
+
+	// The same workbook packaged with its entries reversed and stamped with other times
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	var repacked bytes.Buffer
+	zw := zip.NewWriter(&repacked)
+	for i := len(zr.File) - 1; i >= 0; i-- {
+		zf := zr.File[i]
+		hdr := zf.FileHeader
+		hdr.Modified = hdr.Modified.AddDate(3, 0, i)
+		w, err := zw.CreateHeader(&hdr)
+		require.NoError(t, err)
+		r, err := zf.Open()
+		require.NoError(t, err)
+		_, err = io.Copy(w, r)
+		r.Close()
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	staff := []string{"ann", "bob", "cid", "dan", "eve"}
+	data := map[string]any{"names": []string{"A", "B", "C"}, "depts": []map[string]any{
+		{"Name": "A", "Staff": staff}, {"Name": "B", "Staff": staff[:2]}, {"Name": "C", "Staff": staff[3:]}}}
+	opts := []Option{WithDeterministicOutput(true), WithStripMarkupComments(true), WithConcurrency(4)}
+	var want []byte
+	for i, template := range [][]byte{buf.Bytes(), repacked.Bytes(), buf.Bytes(), repacked.Bytes()} {
+		var out bytes.Buffer
+		require.NoError(t, FillReader(bytes.NewReader(template), &out, data, opts...))
+		if want == nil {
+			want = out.Bytes()
+			continue
+		}
+		assert.Equal(t, want, out.Bytes(), "fill %d", i)
+	}
+
+	// Entries carry no timestamps
+	zr, err = zip.NewReader(bytes.NewReader(want), int64(len(want)))
+	require.NoError(t, err)
+	for _, zf := range zr.File {
+		assert.True(t, zf.Modified.IsZero() || zf.Modified.Year() <= 1980, zf.Name)
+	}
+}
+
+func TestFill_NamedAreasAreByteStable(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	for _, col := range []string{"A", "C", "E", "G"} {
+		f.SetCellValue("Sheet1", col+"1", "${e}")
+		f.AddComment("Sheet1", excelize.Comment{Cell: col + "1", Author: "xlfill",
+			Text: `jx:area(lastCell="` + col + `1" name="area` + col + `")` + "\n" + `jx:each(items="items" var="e" lastCell="` + col + `1")`})
+	}
+	path := filepath.Join(testdataDir(t), "format_named.xlsx")
+	require.NoError(t, f.SaveAs(path))
+
+	want, err := FillBytes(path, formatData)
+	require.NoError(t, err)
+	for range 10 {
+		out, err := FillBytes(path, formatData)
+		require.NoError(t, err)
+		require.Equal(t, want, out)
+	}
+}
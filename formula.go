@@ -2,8 +2,12 @@ package xlfill
 
 import (
 	"fmt"
+	"log/slog"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/xuri/excelize/v2"
 )
 
 // FormulaProcessor updates formula cell references after template expansion.
@@ -11,15 +15,41 @@ type FormulaProcessor interface {
 	ProcessAreaFormulas(transformer Transformer, area *Area)
 }
 
+// FormulaStrategyFunc selects which targets of a referenced cell a formula copy
+// should point to. It receives all targets of the referenced cell and the
+// position the formula is being written to.
+type FormulaStrategyFunc func(targets []CellRef, formulaTarget CellRef) []CellRef
+
 // StandardFormulaProcessor implements the standard formula processing algorithm.
 // It maps source cell references in formulas to their expanded target positions.
-type StandardFormulaProcessor struct{}
+type StandardFormulaProcessor struct {
+	strategies map[string]FormulaStrategyFunc // custom strategies by upper-case name
+	logger     *slog.Logger                   // rewrites are logged here when set
+	moved      func(CellRef) CellRef          // output position of template cells moved by inserted rows, if any
+}
+
+// position returns where the template cell ref is in the output.
+func (fp *StandardFormulaProcessor) position(ref CellRef) CellRef {
+	if fp.moved == nil {
+		return ref
+	}
+	return fp.moved(ref)
+}
 
 // NewFormulaProcessor creates a new StandardFormulaProcessor.
 func NewFormulaProcessor() *StandardFormulaProcessor {
 	return &StandardFormulaProcessor{}
 }
 
+// RegisterStrategy registers a custom formula strategy, selectable from a template
+// with jx:params(formulaStrategy="NAME"). Names are case-insensitive.
+func (fp *StandardFormulaProcessor) RegisterStrategy(name string, fn FormulaStrategyFunc) {
+	if fp.strategies == nil {
+		fp.strategies = make(map[string]FormulaStrategyFunc)
+	}
+	fp.strategies[strings.ToUpper(name)] = fn
+}
+
 // cellRefRegex matches cell references in formulas (e.g., A1, $A$1, Sheet1!A1, A1:B5).
 var cellRefRegex = regexp.MustCompile(`(?:('?[^'!]+?'?)!)?\$?([A-Z]{1,3})\$?(\d+)`)
 
@@ -29,6 +59,7 @@ var rangeRefRegex = regexp.MustCompile(`(?:('?[^'!]+?'?)!)?\$?([A-Z]{1,3})\$?(\d
 // ProcessAreaFormulas processes all formula cells in the area, updating references.
 func (fp *StandardFormulaProcessor) ProcessAreaFormulas(transformer Transformer, area *Area) {
 	formulaCells := transformer.GetFormulaCells()
+	pass := newFormulaPass()
 
 	for _, cd := range formulaCells {
 		if !area.containsRef(cd.Ref) {
@@ -41,76 +72,403 @@ func (fp *StandardFormulaProcessor) ProcessAreaFormulas(transformer Transformer,
 		}
 
 		for _, targetPos := range targetPositions {
-			newFormula := fp.processFormula(cd.Formula, cd, targetPos, transformer, area)
+			formula, fixed := cd.evalFormulaAt(targetPos)
+			newFormula := fp.processFormula(formula, fixed, cd, targetPos, transformer, area, pass)
 			if newFormula != "" {
 				transformer.SetFormula(targetPos, newFormula)
+				fp.traceRewrite(targetPos, cd.Formula, newFormula)
+			}
+		}
+	}
+}
+
+// TargetMap maps template cells to the output cells they were written to,
+// in the order they were written.
+type TargetMap map[CellRef][]CellRef
+
+// GetTargetCellRef returns the output cells of the template cell src.
+func (m TargetMap) GetTargetCellRef(src CellRef) []CellRef {
+	return m[src]
+}
+
+// ProcessFormulas rewrites the formulas of a workbook filled without Fill,
+// e.g. by applying areas with an ExcelizeTransformer directly, as Fill does
+// after applying its areas. areas are the template areas that were applied and
+// mapping their cells' output cells, e.g. ExcelizeTransformer.TargetMap. The
+// formula written to each output cell of a template cell inside areas is read
+// from f and its references are pointed at the output cells of the template
+// cells they name. Formulas must not have been processed already.
+func ProcessFormulas(f *excelize.File, areas []AreaRef, mapping TargetMap) error {
+	sources := make([]CellRef, 0, len(mapping))
+	for src := range mapping {
+		sources = append(sources, src)
+	}
+	sort.Slice(sources, func(i, j int) bool { return cellBefore(sources[i], sources[j]) })
+
+	fp := NewFormulaProcessor()
+	pass := newFormulaPass()
+	for _, ref := range areas {
+		area := &Area{StartCell: ref.First, AreaSize: ref.Size()}
+		for _, src := range sources {
+			if !area.containsRef(src) {
+				continue
+			}
+			cd := &CellData{Ref: src}
+			for _, target := range mapping[src] {
+				formula, err := f.GetCellFormula(target.Sheet, target.CellName())
+				if err != nil {
+					return fmt.Errorf("read formula at %s: %w", target, err)
+				}
+				if formula == "" {
+					continue
+				}
+				cd.Formula = formula
+				if newFormula := fp.processFormula(formula, nil, cd, target, mapping, area, pass); newFormula != formula {
+					if err := f.SetCellFormula(target.Sheet, target.CellName(), newFormula); err != nil {
+						return fmt.Errorf("write formula at %s: %w", target, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// processOutsideFormulas rewrites formulas in cells outside every area, e.g. a
+// total below an area, so references to expanded cells cover their targets.
+// The formulas stay in place; references to cells that were not expanded are
+// kept. Formula cells overwritten by an area's output (outputs) are skipped.
+func (fp *StandardFormulaProcessor) processOutsideFormulas(transformer Transformer, areas []*Area, outputs []AreaRef) {
+	pass := newFormulaPass()
+	sheets := make(map[string]bool)
+	for _, name := range transformer.GetSheetNames() {
+		sheets[name] = true
+	}
+
+cells:
+	for _, cd := range transformer.GetFormulaCells() {
+		if !sheets[cd.Ref.Sheet] || len(transformer.GetTargetCellRef(cd.Ref)) > 0 {
+			continue
+		}
+		for _, area := range areas {
+			if area.containsRef(cd.Ref) {
+				continue cells
 			}
 		}
+		at := fp.position(cd.Ref)
+		for _, out := range outputs {
+			if out.Contains(at) {
+				continue cells
+			}
+		}
+
+		// An empty area on the formula's sheet: only expanded references change
+		scope := &Area{StartCell: NewCellRef(cd.Ref.Sheet, 0, 0)}
+		newFormula := fp.processFormula(cd.Formula, nil, cd, at, transformer, scope, pass)
+		if newFormula != cd.Formula {
+			transformer.SetFormula(at, newFormula)
+			fp.traceRewrite(at, cd.Formula, newFormula)
+		}
 	}
 }
 
+// traceRewrite logs a formula written at cell.
+func (fp *StandardFormulaProcessor) traceRewrite(cell CellRef, before, after string) {
+	if fp.logger != nil {
+		logTrace(fp.logger, traceFormula, 0, slog.String("cell", cell.String()),
+			slog.String("before", before), slog.String("after", after))
+	}
+}
+
+// targetLookup finds where template cells were written: a Transformer, or
+// the TargetMap given to ProcessFormulas.
+type targetLookup interface {
+	GetTargetCellRef(src CellRef) []CellRef
+}
+
 // processFormula processes a single formula, replacing source refs with target refs.
+// References overlapping a fixed span were produced by ${...} substitution and
+// already point at output cells, so they are kept as written. pass caches the
+// lookups repeated for the copies of formulas; nil caches nothing.
 func (fp *StandardFormulaProcessor) processFormula(
 	formula string,
+	fixed []formulaSpan,
 	formulaCell *CellData,
 	targetPos CellRef,
-	transformer Transformer,
+	targets targetLookup,
 	area *Area,
+	pass *formulaPass,
 ) string {
 	result := formula
 
-	// Find all cell reference matches in the formula
-	matches := cellRefRegex.FindAllStringSubmatchIndex(formula, -1)
-	if len(matches) == 0 {
+	// Find all cell references in the formula. Formulas without substituted
+	// values are the same text in every copy, so they are parsed once.
+	var refs []formulaRef
+	if len(fixed) == 0 {
+		refs = pass.parse(formula, area.StartCell.Sheet)
+	} else {
+		refs = parseFormulaRefs(formula, area.StartCell.Sheet)
+	}
+	if !fp.rewrites(refs, fixed, targets, area) {
 		return formula
 	}
 
-	// Process matches in reverse order to preserve indices
-	for i := len(matches) - 1; i >= 0; i-- {
-		match := matches[i]
-		fullMatch := formula[match[0]:match[1]]
+	// Position of this formula copy among all copies of the formula cell,
+	// used to pair relative refs with the target produced in the same iteration.
+	formulaTargets := targets.GetTargetCellRef(formulaCell.Ref)
+	iteration := pass.iteration(formulaCell, formulaTargets, targetPos)
 
-		// Parse the referenced cell
-		ref, err := parseCellRefFromFormula(fullMatch, area.StartCell.Sheet)
-		if err != nil {
+	// Process matches in reverse order to preserve indices
+	for i := len(refs) - 1; i >= 0; i-- {
+		fr := refs[i]
+		if overlapsSpan(fixed, fr.start, fr.end) {
 			continue
 		}
+		ref, anchor := fr.ref, fr.anchor
 
 		// Look up where this source cell was mapped to
-		targetRefs := transformer.GetTargetCellRef(ref)
+		targetRefs := targets.GetTargetCellRef(ref)
 		if len(targetRefs) == 0 {
 			// External reference — check if it's outside the area
 			if !area.containsRef(ref) {
-				continue // keep external ref as-is
+				// Keep external refs, following cells moved by inserted rows
+				if at := fp.position(ref); at != ref {
+					replacement := fp.buildReplacement([]CellRef{at}, ref.Sheet, area.StartCell.Sheet)
+					result = result[:fr.start] + anchor.apply(replacement) + result[fr.end:]
+				}
+				continue
 			}
 			// Internal ref with no target — use default value
 			defaultVal := formulaCell.DefaultValue
 			if defaultVal == "" {
 				defaultVal = "0"
 			}
-			result = result[:match[0]] + defaultVal + result[match[1]:]
+			result = result[:fr.start] + defaultVal + result[fr.end:]
 			continue
 		}
 
+		// A formula repeated by a command refers to the copy of each referenced cell
+		// made in the same iteration, unless the reference is fully anchored ($A$1),
+		// in which case every copy keeps pointing at the first target.
+		if iteration >= 0 && len(targetRefs) == len(formulaTargets) {
+			if anchor.isAbsolute() {
+				targetRefs = targetRefs[:1]
+			} else {
+				targetRefs = targetRefs[iteration : iteration+1]
+			}
+		} else if parent, ok := formulaCell.parentAreaAt(targetPos); ok {
+			// Joint cells: a formula inside a repeated block, e.g. a subtotal below
+			// an inner each, refers only to the targets written in its own block
+			targetRefs = targetsWithin(targetRefs, parent)
+		}
+
 		// Apply formula strategy filtering
-		filtered := fp.filterByStrategy(targetRefs, targetPos, formulaCell.FormulaStrategy)
+		filtered := fp.applyStrategy(targetRefs, targetPos, formulaCell)
 		if len(filtered) == 0 {
 			defaultVal := formulaCell.DefaultValue
 			if defaultVal == "" {
 				defaultVal = "0"
 			}
-			result = result[:match[0]] + defaultVal + result[match[1]:]
+			result = result[:fr.start] + defaultVal + result[fr.end:]
 			continue
 		}
 
 		// Replace the reference
 		replacement := fp.buildReplacement(filtered, ref.Sheet, area.StartCell.Sheet)
-		result = result[:match[0]] + replacement + result[match[1]:]
+		result = result[:fr.start] + anchor.apply(replacement) + result[fr.end:]
 	}
 
+	if result != formula {
+		result = collapseRepeatedRanges(result)
+	}
 	return result
 }
 
+// rewrites reports whether processFormula changes a reference of refs: one
+// to a cell that was written, is inside area or moved. Other formulas, e.g.
+// over cells outside every area, are kept without further lookups.
+func (fp *StandardFormulaProcessor) rewrites(refs []formulaRef, fixed []formulaSpan, targets targetLookup, area *Area) bool {
+	for _, fr := range refs {
+		if overlapsSpan(fixed, fr.start, fr.end) {
+			continue
+		}
+		if len(targets.GetTargetCellRef(fr.ref)) > 0 || area.containsRef(fr.ref) || fp.position(fr.ref) != fr.ref {
+			return true
+		}
+	}
+	return false
+}
+
+// formulaRef is a cell reference in a formula.
+type formulaRef struct {
+	start, end int // byte range in the formula
+	ref        CellRef
+	anchor     refAnchor
+}
+
+// parseFormulaRefs returns the cell references of formula in order. References
+// without a sheet are on sheet.
+func parseFormulaRefs(formula, sheet string) []formulaRef {
+	matches := cellRefRegex.FindAllStringIndex(formula, -1)
+	refs := make([]formulaRef, 0, len(matches))
+	for _, m := range matches {
+		text := formula[m[0]:m[1]]
+		ref, err := parseCellRefFromFormula(text, sheet)
+		if err != nil {
+			continue
+		}
+		refs = append(refs, formulaRef{start: m[0], end: m[1], ref: ref, anchor: parseRefAnchor(text)})
+	}
+	return refs
+}
+
+// formulaPass caches what rewriting the formulas of a workbook looks up for
+// every copy of a formula: the references of each formula text, and which
+// iteration wrote each copy of the formula cell being rewritten.
+type formulaPass struct {
+	refs   map[[2]string][]formulaRef // by sheet and formula
+	cell   *CellData                  // the cell copies indexes
+	copies map[CellRef]int            // first index of each output cell of cell
+}
+
+func newFormulaPass() *formulaPass {
+	return &formulaPass{refs: make(map[[2]string][]formulaRef)}
+}
+
+// parse returns the references of formula, parsed once per sheet.
+func (p *formulaPass) parse(formula, sheet string) []formulaRef {
+	if p == nil {
+		return parseFormulaRefs(formula, sheet)
+	}
+	key := [2]string{sheet, formula}
+	refs, ok := p.refs[key]
+	if !ok {
+		refs = parseFormulaRefs(formula, sheet)
+		p.refs[key] = refs
+	}
+	return refs
+}
+
+// iteration returns the index of target among the output cells of cell, or
+// -1 when cell was written once. Many copies are looked up in an index built
+// once per cell, so rewriting all copies is not quadratic.
+func (p *formulaPass) iteration(cell *CellData, copies []CellRef, target CellRef) int {
+	if len(copies) <= 1 {
+		return -1
+	}
+	if p == nil || len(copies) <= smallTargetCount {
+		for i, t := range copies {
+			if t == target {
+				return i
+			}
+		}
+		return -1
+	}
+	if p.cell != cell {
+		p.cell, p.copies = cell, make(map[CellRef]int, len(copies))
+		for i, t := range copies {
+			if _, ok := p.copies[t]; !ok {
+				p.copies[t] = i
+			}
+		}
+	}
+	if i, ok := p.copies[target]; ok {
+		return i
+	}
+	return -1
+}
+
+// smallTargetCount is the most output cells of a template cell searched one
+// by one rather than through an index.
+const smallTargetCount = 8
+
+// collapseRepeatedRanges joins a range chained to itself, e.g. A2:A3:A2:A3
+// from the single-cell range A2:A2 of an array formula, into A2:A3.
+func collapseRepeatedRanges(formula string) string {
+	// A chained range has at least three colons
+	if strings.Count(formula, ":") < 3 {
+		return formula
+	}
+	matches := rangeRefRegex.FindAllStringIndex(formula, -1)
+	for i := len(matches) - 1; i > 0; i-- {
+		prev, cur := matches[i-1], matches[i]
+		if cur[0] == prev[1]+1 && formula[prev[1]] == ':' && formula[prev[0]:prev[1]] == formula[cur[0]:cur[1]] {
+			formula = formula[:prev[1]] + formula[cur[1]:]
+		}
+	}
+	return formula
+}
+
+// formulaSpan is the byte range of a value substituted into a formula.
+type formulaSpan struct {
+	start, end int
+}
+
+// substituteFormulaParams resolves the ${...} parameters of a formula before its
+// references are processed, returning the formula and where each value was put.
+// A formula whose parameters fail to evaluate is returned unchanged.
+func substituteFormulaParams(formula string, ctx *Context) (string, []formulaSpan) {
+	if ctx.raw || !strings.Contains(formula, ctx.notationBegin) {
+		return formula, nil
+	}
+	var b strings.Builder
+	var spans []formulaSpan
+	for _, seg := range ParseExpressions(formula, ctx.notationBegin, ctx.notationEnd) {
+		if !seg.IsExpression {
+			b.WriteString(seg.Text)
+			continue
+		}
+		val, err := ctx.Evaluate(seg.Text)
+		if err != nil {
+			return formula, nil
+		}
+		start := b.Len()
+		if val != nil {
+			fmt.Fprintf(&b, "%v", val)
+		}
+		spans = append(spans, formulaSpan{start: start, end: b.Len()})
+	}
+	return b.String(), spans
+}
+
+// overlapsSpan reports whether the range [start, end) overlaps any span.
+func overlapsSpan(spans []formulaSpan, start, end int) bool {
+	for _, s := range spans {
+		if s.start < end && s.end > start {
+			return true
+		}
+	}
+	return false
+}
+
+// targetsWithin returns the targets inside area, or all targets when none are,
+// e.g. for a reference to a cell outside the formula's repeated block.
+func targetsWithin(targets []CellRef, area AreaRef) []CellRef {
+	var inside []CellRef
+	for _, t := range targets {
+		if area.Contains(t) {
+			inside = append(inside, t)
+		}
+	}
+	if len(inside) == 0 {
+		return targets
+	}
+	return inside
+}
+
+// applyStrategy filters target refs using the formula cell's custom strategy if one
+// is registered under its name, or the built-in FormulaStrategy otherwise.
+func (fp *StandardFormulaProcessor) applyStrategy(
+	targets []CellRef, formulaTarget CellRef, formulaCell *CellData,
+) []CellRef {
+	if formulaCell.FormulaStrategyName != "" {
+		if fn, ok := fp.strategies[strings.ToUpper(formulaCell.FormulaStrategyName)]; ok {
+			return fn(targets, formulaTarget)
+		}
+	}
+	return fp.filterByStrategy(targets, formulaTarget, formulaCell.FormulaStrategy)
+}
+
 // filterByStrategy filters target refs based on FormulaStrategy.
 func (fp *StandardFormulaProcessor) filterByStrategy(
 	targets []CellRef, formulaTarget CellRef, strategy FormulaStrategy,
@@ -233,6 +591,55 @@ func (fp *StandardFormulaProcessor) formatRef(ref CellRef, origRefSheet, areaShe
 	return cellName
 }
 
+// refAnchor records which parts of a formula cell reference carry a "$" anchor.
+type refAnchor struct {
+	col bool // $A1
+	row bool // A$1
+}
+
+// parseRefAnchor extracts the "$" anchors from a reference as written in a formula,
+// e.g. "$A1", "A$1", "$A$1" or "Sheet1!$A$1".
+func parseRefAnchor(match string) refAnchor {
+	cell := match
+	if idx := strings.LastIndex(match, "!"); idx >= 0 {
+		cell = match[idx+1:]
+	}
+	return refAnchor{
+		col: strings.HasPrefix(cell, "$"),
+		row: strings.Contains(strings.TrimPrefix(cell, "$"), "$"),
+	}
+}
+
+// isAbsolute reports whether both the column and the row are anchored ($A$1).
+func (a refAnchor) isAbsolute() bool {
+	return a.col && a.row
+}
+
+// apply re-inserts the anchors into every cell reference of a rewritten
+// reference string ("A5", "A2:A5", "A2,A4" or "Sheet2!A5").
+func (a refAnchor) apply(s string) string {
+	if !a.col && !a.row {
+		return s
+	}
+	return cellRefRegex.ReplaceAllStringFunc(s, func(m string) string {
+		parts := cellRefRegex.FindStringSubmatch(m)
+		var b strings.Builder
+		if parts[1] != "" {
+			b.WriteString(parts[1])
+			b.WriteByte('!')
+		}
+		if a.col {
+			b.WriteByte('$')
+		}
+		b.WriteString(parts[2])
+		if a.row {
+			b.WriteByte('$')
+		}
+		b.WriteString(parts[3])
+		return b.String()
+	})
+}
+
 // parseCellRefFromFormula parses a cell reference from a formula match.
 func parseCellRefFromFormula(match string, defaultSheet string) (CellRef, error) {
 	// Remove $ signs for parsing
@@ -314,6 +721,7 @@ func (fp *StandardFormulaProcessor) ProcessFormulasForRange(
 
 		newStart := NewCellRef(sheet, minRow, minCol)
 		newEnd := NewCellRef(sheet, maxRow, maxCol)
-		return newStart.CellName() + ":" + newEnd.CellName()
+		startStr, endStr, _ := strings.Cut(match, ":")
+		return parseRefAnchor(startStr).apply(newStart.CellName()) + ":" + parseRefAnchor(endStr).apply(newEnd.CellName())
 	})
 }
@@ -280,3 +280,422 @@ func TestFormulaProcessor_VerticalRange(t *testing.T) {
 	assert.Contains(t, formula, "A2")
 	assert.Contains(t, formula, "A4")
 }
+
+func TestParseRefAnchor(t *testing.T) {
+	assert.Equal(t, refAnchor{}, parseRefAnchor("A1"))
+	assert.Equal(t, refAnchor{col: true}, parseRefAnchor("$A1"))
+	assert.Equal(t, refAnchor{row: true}, parseRefAnchor("A$1"))
+	assert.Equal(t, refAnchor{col: true, row: true}, parseRefAnchor("$A$1"))
+	assert.Equal(t, refAnchor{col: true, row: true}, parseRefAnchor("Sheet1!$A$1"))
+
+	assert.Equal(t, "$B$5", refAnchor{col: true, row: true}.apply("B5"))
+	assert.Equal(t, "$B2:$B5", refAnchor{col: true}.apply("B2:B5"))
+	assert.Equal(t, "Sheet2!B$5", refAnchor{row: true}.apply("Sheet2!B5"))
+	assert.Equal(t, "B5", refAnchor{}.apply("B5"))
+}
+
+func TestFormulaProcessor_AbsoluteRefsInEachRow(t *testing.T) {
+	// Template:
+	//   A1: "Rate"   B1: 2
+	//   A2: ${e.V}   B2: =$A2*$B$1   C2: =A2+A$1   D2: =$A$2
+	//   A3: =SUM($A$2:$A$2)
+	// Each row keeps its $ anchors; relative rows follow the iteration,
+	// fully absolute refs stay on the first target.
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+
+	f.SetCellValue(sheet, "A1", "Rate")
+	f.SetCellValue(sheet, "B1", 2)
+	f.SetCellValue(sheet, "A2", "${e.V}")
+	f.SetCellFormula(sheet, "B2", "$A2*$B$1")
+	f.SetCellFormula(sheet, "C2", "A2+A$1")
+	f.SetCellFormula(sheet, "D2", "$A$2")
+	f.SetCellFormula(sheet, "A3", "SUM($A$2:$A$2)")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="D3")`,
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="items" var="e" lastCell="D2")`,
+	})
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	items := []any{
+		map[string]any{"V": 10},
+		map[string]any{"V": 20},
+		map[string]any{"V": 30},
+	}
+	ctx := NewContext(map[string]any{"items": items})
+
+	filler := NewFiller()
+	areas, err := filler.BuildAreas(tx)
+	require.NoError(t, err)
+
+	fp := NewFormulaProcessor()
+	for _, area := range areas {
+		_, err := area.ApplyAt(area.StartCell, ctx)
+		require.NoError(t, err)
+		fp.ProcessAreaFormulas(tx, area)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+
+	for i, row := range []string{"2", "3", "4"} {
+		formula, _ := out.GetCellFormula(sheet, "B"+row)
+		assert.Equal(t, "$A"+row+"*$B$1", formula, "iteration %d", i)
+		formula, _ = out.GetCellFormula(sheet, "C"+row)
+		assert.Equal(t, "A"+row+"+A$1", formula, "iteration %d", i)
+		formula, _ = out.GetCellFormula(sheet, "D"+row)
+		assert.Equal(t, "$A$2", formula, "iteration %d", i)
+	}
+
+	formula, _ := out.GetCellFormula(sheet, "A5")
+	assert.Contains(t, formula, "$A$2")
+	assert.Contains(t, formula, "$A$4")
+	assert.NotContains(t, formula, "$A$2:$A$2")
+}
+
+func TestFormulaProcessor_CustomStrategy(t *testing.T) {
+	fp := NewFormulaProcessor()
+	fp.RegisterStrategy("first_only", func(targets []CellRef, _ CellRef) []CellRef {
+		return targets[:1]
+	})
+
+	targets := []CellRef{NewCellRef("S", 1, 0), NewCellRef("S", 2, 0), NewCellRef("S", 3, 0)}
+	cd := &CellData{FormulaStrategyName: "FIRST_ONLY"}
+	assert.Equal(t, targets[:1], fp.applyStrategy(targets, NewCellRef("S", 5, 0), cd))
+
+	// Unregistered names fall back to the built-in strategy
+	cd = &CellData{FormulaStrategyName: "MISSING", FormulaStrategy: FormulaByRow}
+	assert.Empty(t, fp.applyStrategy(targets, NewCellRef("S", 5, 0), cd))
+}
+
+func TestFill_WithFormulaStrategyByGroup(t *testing.T) {
+	// Template:
+	//   A1: ${d.Name}                       (each departments, A1:A3)
+	//   A2: ${e.Amount}                     (each d.Items, A2:A2)
+	//   A3: =SUM(A2)  jx:params(formulaStrategy="BY_GROUP")
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${d.Name}")
+	f.SetCellValue(sheet, "A2", "${e.Amount}")
+	f.SetCellFormula(sheet, "A3", "SUM(A2)")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A3\")\njx:each(items=\"departments\" var=\"d\" lastCell=\"A3\")",
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="d.Items" var="e" lastCell="A2")`,
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A3", Author: "xlfill",
+		Text: `jx:params(formulaStrategy="BY_GROUP")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	// Pick the contiguous block of targets directly above the subtotal row
+	byGroup := func(targets []CellRef, formulaTarget CellRef) []CellRef {
+		rows := make(map[int]CellRef)
+		for _, t := range targets {
+			if t.Col == formulaTarget.Col {
+				rows[t.Row] = t
+			}
+		}
+		var picked []CellRef
+		for r := formulaTarget.Row - 1; ; r-- {
+			t, ok := rows[r]
+			if !ok {
+				break
+			}
+			picked = append([]CellRef{t}, picked...)
+		}
+		return picked
+	}
+
+	data := map[string]any{
+		"departments": []map[string]any{
+			{"Name": "Eng", "Items": []map[string]any{{"Amount": 1}, {"Amount": 2}}},
+			{"Name": "Ops", "Items": []map[string]any{{"Amount": 3}, {"Amount": 4}, {"Amount": 5}}},
+		},
+	}
+
+	outBytes, err := FillBytes(tmpPath, data, WithFormulaStrategy("BY_GROUP", byGroup))
+	require.NoError(t, err)
+
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	formula, _ := out.GetCellFormula(sheet, "A4")
+	assert.Equal(t, "SUM(A2:A3)", formula)
+	formula, _ = out.GetCellFormula(sheet, "A9")
+	assert.Equal(t, "SUM(A6:A8)", formula)
+
+	// Without the registration the name is rejected rather than ignored
+	_, err = FillBytes(tmpPath, data, WithFormulaStrategy("BY_TOTAL", byGroup))
+	assert.ErrorContains(t, err, `Sheet1!A3: unknown formulaStrategy "BY_GROUP" (accepted: DEFAULT, BY_COLUMN, BY_ROW, BY_TOTAL)`)
+	issues, err := NewFiller(WithTemplate(tmpPath)).Validate()
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, SeverityError, issues[0].Severity)
+	assert.Equal(t, `unknown formulaStrategy "BY_GROUP" (accepted: DEFAULT, BY_COLUMN, BY_ROW)`, issues[0].Message)
+}
+
+func TestFill_NestedEachSubtotalsScopedToGroup(t *testing.T) {
+	// Template:
+	//   A1: ${d.Name}        (each departments, A1:A3)
+	//   A2: ${e.Amount}      (each d.Items, A2:A2)
+	//   A3: =SUM(A2)         subtotal, inside the outer each only
+	//   A4: =SUM(A2)         grand total, outside both eaches
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${d.Name}")
+	f.SetCellValue(sheet, "A2", "${e.Amount}")
+	f.SetCellFormula(sheet, "A3", "SUM(A2)")
+	f.SetCellFormula(sheet, "A4", "SUM(A2)")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A4\")\njx:each(items=\"departments\" var=\"d\" lastCell=\"A3\")",
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="d.Items" var="e" lastCell="A2")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	data := map[string]any{
+		"departments": []map[string]any{
+			{"Name": "Eng", "Items": []map[string]any{{"Amount": 1}, {"Amount": 2}}},
+			{"Name": "Ops", "Items": []map[string]any{{"Amount": 3}, {"Amount": 4}, {"Amount": 5}}},
+		},
+	}
+	outBytes, err := FillBytes(tmpPath, data)
+	require.NoError(t, err)
+
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	formula, _ := out.GetCellFormula(sheet, "A4")
+	assert.Equal(t, "SUM(A2:A3)", formula)
+	formula, _ = out.GetCellFormula(sheet, "A9")
+	assert.Equal(t, "SUM(A6:A8)", formula)
+	formula, _ = out.GetCellFormula(sheet, "A10")
+	assert.Equal(t, "SUM(A2,A3,A6,A7,A8)", formula, "the grand total still covers every group")
+}
+
+func TestTargetsWithin(t *testing.T) {
+	targets := []CellRef{NewCellRef("S", 1, 0), NewCellRef("S", 2, 0), NewCellRef("S", 5, 0)}
+	block := NewAreaRef(NewCellRef("S", 0, 0), NewCellRef("S", 3, 1))
+	assert.Equal(t, targets[:2], targetsWithin(targets, block))
+
+	elsewhere := NewAreaRef(NewCellRef("S", 10, 0), NewCellRef("S", 12, 1))
+	assert.Equal(t, targets, targetsWithin(targets, elsewhere), "references outside the block keep all targets")
+}
+
+func TestFill_ProcessFormulasOutsideAreas(t *testing.T) {
+	// Template:
+	//   A1:B2 area with an each over rows 2
+	//   D1: =SUM(B2)        beside the area
+	//   A6: =SUM(B2)*2      below the area
+	//   A3: =B2             below the area, overwritten by its output
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Name")
+	f.SetCellValue(sheet, "A2", "${e.Name}")
+	f.SetCellValue(sheet, "B2", "${e.Amount}")
+	f.SetCellFormula(sheet, "D1", "SUM(B2)")
+	f.SetCellFormula(sheet, "A6", "SUM(B2)*2")
+	f.SetCellFormula(sheet, "A3", "B2")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="B2")`})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+	data := map[string]any{"items": []map[string]any{
+		{"Name": "a", "Amount": 1}, {"Name": "b", "Amount": 2}, {"Name": "c", "Amount": 3},
+	}}
+
+	formulas := func(opts ...Option) (string, string, string) {
+		outBytes, err := FillBytes(tmpPath, data, opts...)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+		require.NoError(t, err)
+		defer out.Close()
+		d1, _ := out.GetCellFormula(sheet, "D1")
+		a6, _ := out.GetCellFormula(sheet, "A6")
+		a3, _ := out.GetCellFormula(sheet, "A3")
+		return d1, a6, a3
+	}
+
+	d1, a6, _ := formulas()
+	assert.Equal(t, "SUM(B2)", d1, "outside formulas are left alone by default")
+	assert.Equal(t, "SUM(B2)*2", a6)
+
+	d1, a6, a3 := formulas(WithProcessFormulasOutsideAreas(true))
+	assert.Equal(t, "SUM(B2:B4)", d1)
+	assert.Equal(t, "SUM(B2:B4)*2", a6)
+	assert.Empty(t, a3, "a formula cell overwritten by area output is not restored")
+}
+
+func TestSubstituteFormulaParams(t *testing.T) {
+	ctx := NewContext(map[string]any{"rate": 0.2, "col": "B"})
+
+	formula, spans := substituteFormulaParams("A1*${rate}+${col}7", ctx)
+	assert.Equal(t, "A1*0.2+B7", formula)
+	assert.Equal(t, []formulaSpan{{3, 6}, {7, 8}}, spans)
+
+	formula, spans = substituteFormulaParams("SUM(A1:A3)", ctx)
+	assert.Equal(t, "SUM(A1:A3)", formula)
+	assert.Nil(t, spans)
+
+	// A parameter that fails to evaluate leaves the formula unchanged
+	formula, spans = substituteFormulaParams("A1*${rate +}", ctx)
+	assert.Equal(t, "A1*${rate +}", formula)
+	assert.Nil(t, spans)
+}
+
+func TestFill_FormulaRowParameters(t *testing.T) {
+	// Template:
+	//   A1: "Qty"  (area A1:D4)
+	//   A3: ${e.Qty}  B3: ${e.Price}  C3: =A${_row}*B${_row}  D3: =C3*${rate}+A${_row}
+	//   (each over row 3)
+	//   A4: "Total"
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Qty")
+	f.SetCellValue(sheet, "A3", "${e.Qty}")
+	f.SetCellValue(sheet, "B3", "${e.Price}")
+	f.SetCellFormula(sheet, "C3", "A${_row}*B${_row}")
+	f.SetCellFormula(sheet, "D3", "C3*${rate}+A${_row}")
+	f.SetCellValue(sheet, "A4", "Total")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="D4")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:each(items="items" var="e" lastCell="D3")`})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+	data := map[string]any{"rate": 0.1, "items": []map[string]any{
+		{"Qty": 1, "Price": 10}, {"Qty": 2, "Price": 20}, {"Qty": 3, "Price": 30},
+	}}
+
+	outBytes, err := FillBytes(tmpPath, data)
+	require.NoError(t, err)
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	// Substituted references are kept; template references still follow the copy
+	for cell, want := range map[string]string{
+		"C3": "A3*B3", "C4": "A4*B4", "C5": "A5*B5",
+		"D3": "C3*0.1+A3", "D4": "C4*0.1+A4", "D5": "C5*0.1+A5",
+	} {
+		formula, _ := out.GetCellFormula(sheet, cell)
+		assert.Equal(t, want, formula, cell)
+	}
+}
+
+func TestProcessFormulas(t *testing.T) {
+	// Areas applied without Fill, then only the formulas processed
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${e.Amount}")
+	f.SetCellFormula(sheet, "B1", "A1*2")
+	f.SetCellFormula(sheet, "A2", "SUM(A1)")
+	f.SetCellFormula(sheet, "B2", "SUM(B1)+D9")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"B2\")\njx:each(items=\"items\" var=\"e\" lastCell=\"B1\")"})
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+	areas, err := NewFiller().BuildAreas(tx)
+	require.NoError(t, err)
+	ctx := NewContext(map[string]any{"items": []any{
+		map[string]any{"Amount": 100}, map[string]any{"Amount": 200}, map[string]any{"Amount": 300},
+	}})
+	var refs []AreaRef
+	for _, area := range areas {
+		_, err := area.ApplyAt(area.StartCell, ctx)
+		require.NoError(t, err)
+		refs = append(refs, area.SourceRef())
+	}
+
+	require.NoError(t, ProcessFormulas(f, refs, tx.TargetMap()))
+	for cell, want := range map[string]string{
+		"B1": "A1*2", "B2": "A2*2", "B3": "A3*2",
+		"A4": "SUM(A1:A3)", "B4": "SUM(B1:B3)+D9",
+	} {
+		formula, err := f.GetCellFormula(sheet, cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, formula, cell)
+	}
+
+	// A copy of the mapping, unaffected by later changes to the transformer
+	targets := tx.TargetMap()
+	tx.ResetTargetCellRefs()
+	assert.Equal(t, []CellRef{NewCellRef(sheet, 3, 0)}, targets.GetTargetCellRef(NewCellRef(sheet, 1, 0)))
+}
+
+func TestFill_FormulasOfManyCopies(t *testing.T) {
+	// More copies than are searched one by one: formulas of each copy still
+	// pair with the cells of their own iteration and group
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${g.Name}")
+	f.SetCellValue(sheet, "A2", "${e}")
+	f.SetCellFormula(sheet, "B2", "A2*2+$A$2")
+	f.SetCellFormula(sheet, "C2", "Z1*2")
+	f.SetCellFormula(sheet, "A3", "SUM(A2)")
+	f.SetCellFormula(sheet, "A4", "SUM(A3)")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"C4\")\njx:each(items=\"groups\" var=\"g\" lastCell=\"C3\")"})
+	f.AddComment(sheet, excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="g.Items" var="e" lastCell="C2")`})
+	var tmpl bytes.Buffer
+	require.NoError(t, f.Write(&tmpl))
+	f.Close()
+
+	groups := make([]any, 10)
+	for i := range groups {
+		items := make([]any, 10)
+		for j := range items {
+			items[j] = i*10 + j
+		}
+		groups[i] = map[string]any{"Name": i, "Items": items}
+	}
+	var out bytes.Buffer
+	require.NoError(t, FillReader(bytes.NewReader(tmpl.Bytes()), &out, map[string]any{"groups": groups}))
+
+	res, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	defer res.Close()
+	// Each group is a heading row, 10 item rows and a subtotal: 12 rows
+	for cell, want := range map[string]string{
+		"B2":   "A2*2+$A$2",
+		"B11":  "A11*2+$A$2",
+		"B111": "A111*2+$A$2",
+		"C50":  "Z1*2",
+		"A12":  "SUM(A2:A11)",
+		"A120": "SUM(A110:A119)",
+		"A121": "SUM(A12,A24,A36,A48,A60,A72,A84,A96,A108,A120)",
+	} {
+		formula, err := res.GetCellFormula(sheet, cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, formula, cell)
+	}
+}
@@ -3,20 +3,34 @@ package xlfill
 import (
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 )
 
 // GridCommand implements the jx:grid command for dynamic grid rendering.
-// It renders headers horizontally and data rows below.
+// It renders headers horizontally and data rows below or, with direction
+// "RIGHT", headers down the first column and one record per column.
 type GridCommand struct {
 	Headers    string // expression for header values ([]any)
 	Data       string // expression for data rows ([]any)
-	Props      string // comma-separated property names for object data
+	Props      string // comma-separated property names or expressions for object data
+	Direction  string // "DOWN" (default) or "RIGHT"
 	FormatCells string // type-to-format mapping (unused for now)
+	HeaderStyle string // style for header cells (registered name or cell reference)
+	DataStyle   string // style for data cells (registered name or cell reference)
+	HeaderCells string // template cells whose styles are copied to header columns (headerArea)
+	BodyCells   string // template cells whose styles are copied to data columns (bodyArea)
 	HeaderArea *Area
 	BodyArea   *Area
+
+	headerRef AreaRef // parsed HeaderCells
+	bodyRef   AreaRef // parsed BodyCells
 }
 
+// propertyPath matches a props entry read with getField rather than evaluated,
+// e.g. "Name", "Address.City" or "FullName()".
+var propertyPath = regexp.MustCompile(`^[A-Za-z_]\w*(\(\))?(\.[A-Za-z_]\w*(\(\))?)*$`)
+
 func (c *GridCommand) Name() string { return "grid" }
 func (c *GridCommand) Reset()       {}
 
@@ -27,6 +41,11 @@ func newGridCommandFromAttrs(attrs map[string]string) (Command, error) {
 		Data:       attrs["data"],
 		Props:      attrs["props"],
 		FormatCells: attrs["formatCells"],
+		HeaderStyle: attrs["headerStyle"],
+		DataStyle:   attrs["dataStyle"],
+		HeaderCells: attrs["headerArea"],
+		BodyCells:   attrs["bodyArea"],
+		Direction:   strings.ToUpper(attrs["direction"]),
 	}
 	if cmd.Headers == "" {
 		return nil, fmt.Errorf("grid command requires 'headers' attribute")
@@ -34,9 +53,46 @@ func newGridCommandFromAttrs(attrs map[string]string) (Command, error) {
 	if cmd.Data == "" {
 		return nil, fmt.Errorf("grid command requires 'data' attribute")
 	}
+	if cmd.Direction == "" {
+		cmd.Direction = "DOWN"
+	}
+	if cmd.Direction != "DOWN" && cmd.Direction != "RIGHT" {
+		return nil, fmt.Errorf("grid command: invalid direction %q (expected DOWN or RIGHT)", attrs["direction"])
+	}
+	var err error
+	if cmd.headerRef, err = parseGridCells(cmd.HeaderCells); err != nil {
+		return nil, fmt.Errorf("grid command: invalid headerArea: %w", err)
+	}
+	if cmd.bodyRef, err = parseGridCells(cmd.BodyCells); err != nil {
+		return nil, fmt.Errorf("grid command: invalid bodyArea: %w", err)
+	}
 	return cmd, nil
 }
 
+// parseGridCells parses a headerArea or bodyArea attribute: a row or column of
+// template cells such as "A5:C5" or a single cell.
+func parseGridCells(s string) (AreaRef, error) {
+	if s == "" {
+		return AreaRef{}, nil
+	}
+	if !strings.Contains(s, ":") {
+		ref, err := ParseCellRef(s)
+		if err != nil {
+			return AreaRef{}, err
+		}
+		return NewAreaRef(ref, ref), nil
+	}
+	area, err := ParseAreaRef(s)
+	if err != nil {
+		return AreaRef{}, err
+	}
+	size := area.Size()
+	if size.Width < 1 || size.Height < 1 || (size.Width > 1 && size.Height > 1) {
+		return AreaRef{}, fmt.Errorf("%q is not a single row or column of cells", s)
+	}
+	return area, nil
+}
+
 // ApplyAt renders the grid at the given target cell.
 func (c *GridCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
 	// Evaluate headers
@@ -63,38 +119,272 @@ func (c *GridCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transfo
 		return ZeroSize, nil
 	}
 
-	totalWidth := len(headers)
-	totalHeight := 0
+	layout := c.layout(cellRef, transformer)
 
-	// Render headers (one per column)
-	for col, header := range headers {
-		target := NewCellRef(cellRef.Sheet, cellRef.Row, cellRef.Col+col)
-		transformer.SetCellValue(target, header)
+	// Render headers (one per column, or one per row block when transposed)
+	for i, header := range headers {
+		target := layout.header(i)
+		if err := transformer.SetCellValue(target, header); err != nil {
+			return ZeroSize, fmt.Errorf("grid cell %s: %w", target, err)
+		}
+		if err := layout.merge(transformer, target, layout.headerSpan); err != nil {
+			return ZeroSize, err
+		}
+		if err := c.applyStyle(transformer, target, gridCellStyle(c.HeaderCells, c.headerRef, i)); err != nil {
+			return ZeroSize, err
+		}
+		if err := c.applyStyle(transformer, target, c.HeaderStyle); err != nil {
+			return ZeroSize, err
+		}
 	}
-	totalHeight++ // header row
 
 	// Parse props if provided
-	var propNames []string
-	if c.Props != "" {
-		for _, p := range strings.Split(c.Props, ",") {
-			propNames = append(propNames, strings.TrimSpace(p))
-		}
+	propNames := splitProps(c.Props)
+	if err := c.checkProps(ctx.sandbox(), propNames, dataRows); err != nil {
+		return ZeroSize, err
 	}
 
-	// Render data rows
+	// Render data rows (records across columns when transposed)
 	for rowIdx, row := range dataRows {
 		rowSlice, err := extractRowData(row, propNames)
 		if err != nil {
 			return ZeroSize, fmt.Errorf("extract row %d data: %w", rowIdx, err)
 		}
-		for col := 0; col < totalWidth && col < len(rowSlice); col++ {
-			target := NewCellRef(cellRef.Sheet, cellRef.Row+1+rowIdx, cellRef.Col+col)
-			transformer.SetCellValue(target, rowSlice[col])
+		at := func(i int) CellRef { return layout.data(rowIdx, i) }
+		if err := evaluateRowProps(ctx, row, propNames, rowSlice, at); err != nil {
+			return ZeroSize, fmt.Errorf("grid row %d: %w", rowIdx, err)
+		}
+		ctx.redactProps(propNames, rowSlice)
+		for i := 0; i < len(headers) && i < len(rowSlice); i++ {
+			target := layout.data(rowIdx, i)
+			if err := transformer.SetCellValue(target, rowSlice[i]); err != nil {
+				return ZeroSize, fmt.Errorf("grid cell %s: %w", target, err)
+			}
+			if err := layout.merge(transformer, target, layout.dataSpan); err != nil {
+				return ZeroSize, err
+			}
+			if err := c.applyStyle(transformer, target, gridCellStyle(c.BodyCells, c.bodyRef, i)); err != nil {
+				return ZeroSize, err
+			}
+			if err := c.applyStyle(transformer, target, c.DataStyle); err != nil {
+				return ZeroSize, err
+			}
+		}
+	}
+
+	return layout.size(len(headers), len(dataRows)), nil
+}
+
+// gridLayout maps header and data positions of a grid to output cells.
+type gridLayout struct {
+	origin     CellRef
+	transposed bool
+	headerSpan Size // cells covered by each header (a merged template cell when transposed)
+	dataSpan   Size // cells covered by each data value
+}
+
+// mergeReader is implemented by transformers that know the merged ranges of
+// the template.
+type mergeReader interface {
+	// GetMergedRange returns the merged range of the template whose top-left
+	// cell is ref, and false when no merge starts there.
+	GetMergedRange(ref CellRef) (AreaRef, bool)
+}
+
+// layout returns the grid layout at target. A transposed grid whose template
+// cell is merged, on a transformer that is a mergeReader, gives every header a
+// block of the merged size; the records start right of it and each value spans
+// the block's height.
+func (c *GridCommand) layout(target CellRef, transformer Transformer) gridLayout {
+	l := gridLayout{origin: target, headerSpan: Size{Width: 1, Height: 1}, dataSpan: Size{Width: 1, Height: 1}}
+	if c.Direction != "RIGHT" {
+		return l
+	}
+	l.transposed = true
+	if mr, ok := unwrapTransformer(transformer).(mergeReader); ok && c.BodyArea != nil {
+		if merged, ok := mr.GetMergedRange(c.BodyArea.StartCell); ok {
+			l.headerSpan = merged.Size()
+			l.dataSpan.Height = l.headerSpan.Height
+		}
+	}
+	return l
+}
+
+// header returns the cell of the i-th header.
+func (l gridLayout) header(i int) CellRef {
+	if l.transposed {
+		return NewCellRef(l.origin.Sheet, l.origin.Row+i*l.headerSpan.Height, l.origin.Col)
+	}
+	return NewCellRef(l.origin.Sheet, l.origin.Row, l.origin.Col+i)
+}
+
+// data returns the cell of the i-th value of a data row.
+func (l gridLayout) data(row, i int) CellRef {
+	if l.transposed {
+		return NewCellRef(l.origin.Sheet, l.origin.Row+i*l.headerSpan.Height, l.origin.Col+l.headerSpan.Width+row)
+	}
+	return NewCellRef(l.origin.Sheet, l.origin.Row+1+row, l.origin.Col+i)
+}
+
+// size returns the output size of a grid with the given headers and rows.
+func (l gridLayout) size(headers, rows int) Size {
+	if l.transposed {
+		return Size{Width: l.headerSpan.Width + rows, Height: headers * l.headerSpan.Height}
+	}
+	return Size{Width: headers, Height: 1 + rows}
+}
+
+// merge merges the cells a value spans, when it spans more than one.
+func (l gridLayout) merge(transformer Transformer, target CellRef, span Size) error {
+	if span.Width <= 1 && span.Height <= 1 {
+		return nil
+	}
+	last := NewCellRef(target.Sheet, target.Row+span.Height-1, target.Col+span.Width-1)
+	if err := transformer.MergeCells(target.Sheet, target.CellName(), last.CellName()); err != nil {
+		return fmt.Errorf("grid merge %s:%s: %w", target, last.CellName(), err)
+	}
+	return nil
+}
+
+// applyStyle lays a header or data style over a grid cell.
+func (c *GridCommand) applyStyle(transformer Transformer, target CellRef, style string) error {
+	if style == "" {
+		return nil
+	}
+	sheet := target.Sheet
+	if c.BodyArea != nil {
+		sheet = c.BodyArea.StartCell.Sheet
+	}
+	if err := applyNamedStyle(transformer, target, qualifyStyleRef(style, sheet)); err != nil {
+		return fmt.Errorf("grid cell %s: %w", target, err)
+	}
+	return nil
+}
+
+// gridCellStyle returns the template cell whose style is copied to the n-th grid
+// column (or row, when transposed): the n-th cell of a headerArea or bodyArea,
+// the last one beyond its end, or "" when attr is not set.
+func gridCellStyle(attr string, cells AreaRef, n int) string {
+	if attr == "" {
+		return ""
+	}
+	ref := cells.First
+	if size := cells.Size(); size.Height > 1 {
+		ref.Row += min(n, size.Height-1)
+	} else {
+		ref.Col += min(n, size.Width-1)
+	}
+	if ref.Sheet != "" {
+		return ref.String()
+	}
+	return ref.CellName()
+}
+
+// splitProps splits a props attribute at the commas outside parentheses,
+// brackets and quotes, so expressions such as "max(a, b)" stay whole.
+func splitProps(props string) []string {
+	if strings.TrimSpace(props) == "" {
+		return nil
+	}
+	parts := splitTopLevel(props, ',', false)
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// evaluateRowProps evaluates the props that are expressions rather than
+// property paths, e.g. "Amount*rate", with the row's properties in scope and
+// the position variables, such as _row, of the cell at(i) the value goes to.
+func evaluateRowProps(ctx *Context, row any, props []string, values []any, at func(i int) CellRef) error {
+	if row == nil || len(values) != len(props) {
+		return nil
+	}
+	var rowCtx *Context
+	for i, prop := range props {
+		if propertyPath.MatchString(prop) {
+			continue
+		}
+		if rowCtx == nil {
+			rowCtx = ctx.WithVars(rowProperties(row))
+		}
+		rowCtx.setPosition(at(i))
+		val, err := rowCtx.Evaluate(prop)
+		if err != nil {
+			return fmt.Errorf("evaluate prop %q: %w", prop, err)
+		}
+		values[i] = val
+	}
+	return nil
+}
+
+// rowProperties returns the top-level properties of a map or struct row by name.
+func rowProperties(row any) map[string]any {
+	vars := map[string]any{}
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			for _, k := range v.MapKeys() {
+				vars[k.String()] = v.MapIndex(k).Interface()
+			}
+		}
+	case reflect.Struct:
+		for _, f := range reflect.VisibleFields(v.Type()) {
+			if !f.IsExported() || f.Anonymous {
+				continue
+			}
+			val := fieldInterface(v, f.Index)
+			vars[f.Name] = val
+			if tag := tagName(f); tag != "" {
+				vars[tag] = val
+			}
 		}
-		totalHeight++
 	}
+	return vars
+}
 
-	return Size{Width: totalWidth, Height: totalHeight}, nil
+// checkProps checks the row properties the grid reads against the sandbox:
+// those props names, directly or as variables of an expression, and without
+// props every key of map rows.
+func (c *GridCommand) checkProps(sandbox *exprSandbox, props []string, rows []any) error {
+	if sandbox == nil || !sandbox.restrictsProperties() {
+		return nil
+	}
+	for _, prop := range props {
+		paths := []string{prop}
+		if !propertyPath.MatchString(prop) {
+			paths = nil
+			for _, name := range ExpressionVariables(prop) {
+				if anyHasField(rows, name) {
+					paths = append(paths, name)
+				}
+			}
+		}
+		for _, path := range paths {
+			if err := sandbox.checkPath(path, rows); err != nil {
+				return fmt.Errorf("grid props %q: %w", c.Props, err)
+			}
+		}
+	}
+	if len(props) > 0 {
+		return nil
+	}
+	for _, row := range rows {
+		m := reflect.ValueOf(row)
+		if m.Kind() != reflect.Map || m.Type().Key().Kind() != reflect.String {
+			continue
+		}
+		for _, key := range m.MapKeys() {
+			if err := sandbox.checkProperty(key.String()); err != nil {
+				return fmt.Errorf("grid data %q: %w", c.Data, err)
+			}
+		}
+	}
+	return nil
 }
 
 // extractRowData extracts values from a data row.
@@ -2,6 +2,7 @@ package xlfill
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -58,6 +59,17 @@ func TestGridCommand_NilHeaders(t *testing.T) {
 	assert.Equal(t, ZeroSize, size)
 }
 
+func TestGridCommand_WriteError(t *testing.T) {
+	tx, err := NewExcelizeTransformer(excelize.NewFile())
+	require.NoError(t, err)
+	defer tx.Close()
+
+	ctx := NewContext(map[string]any{"headers": []any{"Name"}, "data": []any{[]any{"Alice"}}})
+	cmd := &GridCommand{Headers: "headers", Data: "data"}
+	_, err = cmd.ApplyAt(NewCellRef("Missing", 0, 0), ctx, tx)
+	assert.ErrorContains(t, err, "grid cell Missing!A1")
+}
+
 func TestGridCommand_NilData(t *testing.T) {
 	f := excelize.NewFile()
 	tx, err := NewExcelizeTransformer(f)
@@ -126,3 +138,215 @@ func TestNewGridCommandFromAttrs_MissingData(t *testing.T) {
 	_, err := newGridCommandFromAttrs(map[string]string{"headers": "h"})
 	assert.Error(t, err)
 }
+
+func TestGridCommand_HeaderAndDataStyles(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:grid(headers=\"headers\" data=\"rows\" headerStyle=\"head\" dataStyle=\"Styles!A1\" lastCell=\"A1\")"})
+	_, err := f.NewSheet("Styles")
+	require.NoError(t, err)
+	grey, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"EEEEEE"}}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Styles", "A1", "A1", grey))
+	tmpl := filepath.Join(testdataDir(t), "grid_styles.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+
+	out, err := FillBytes(tmpl, map[string]any{
+		"headers": []string{"Name", "Qty"},
+		"rows":    [][]any{{"Bolt", 3}, {"Nut", 9}},
+	}, WithStyles(map[string]*excelize.Style{"head": {Font: &excelize.Font{Bold: true}}}))
+	require.NoError(t, err)
+	res := openOutput(t, out)
+
+	_, head := styleOf(t, res, "Sheet1", "B1")
+	require.NotNil(t, head.Font)
+	assert.True(t, head.Font.Bold)
+	assert.Empty(t, head.Fill.Color)
+
+	_, data := styleOf(t, res, "Sheet1", "B3")
+	assert.Equal(t, []string{"EEEEEE"}, data.Fill.Color)
+}
+
+func TestGridCommand_HeaderAndBodyAreas(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:grid(headers=\"headers\" data=\"orders\" props=\"Customer, Amount, Amount*rate\" headerArea=\"Styles!A1\" bodyArea=\"Styles!A2:B2\" lastCell=\"A1\")"})
+	_, err := f.NewSheet("Styles")
+	require.NoError(t, err)
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+	money, err := f.NewStyle(&excelize.Style{NumFmt: 4})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Styles", "A1", "A1", bold))
+	require.NoError(t, f.SetCellStyle("Styles", "B2", "B2", money))
+	tmpl := filepath.Join(testdataDir(t), "grid_areas.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+
+	out, err := FillBytes(tmpl, map[string]any{
+		"headers": []string{"Customer", "Amount", "Tax"},
+		"rate":    0.25,
+		"orders": []map[string]any{
+			{"Customer": "Acme", "Amount": 100},
+			{"Customer": "Globex", "Amount": 40},
+		},
+	})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+
+	for cell, want := range map[string]string{"A2": "Acme", "B2": "100", "C2": "25", "C3": "10"} {
+		v, _ := res.GetCellValue("Sheet1", cell, excelize.Options{RawCellValue: true})
+		assert.Equal(t, want, v, cell)
+	}
+
+	// Every header column repeats the single header cell
+	for _, cell := range []string{"A1", "B1", "C1"} {
+		_, style := styleOf(t, res, "Sheet1", cell)
+		require.NotNil(t, style.Font, cell)
+		assert.True(t, style.Font.Bold, cell)
+	}
+	// Body columns follow the body row; the tax column repeats its last cell
+	_, customer := styleOf(t, res, "Sheet1", "A2")
+	assert.Equal(t, 0, customer.NumFmt)
+	for _, cell := range []string{"B2", "C2", "B3", "C3"} {
+		_, style := styleOf(t, res, "Sheet1", cell)
+		assert.Equal(t, 4, style.NumFmt, cell)
+	}
+}
+
+func TestGridCommand_PropsExpressions(t *testing.T) {
+	type line struct {
+		Item  string
+		Qty   int
+		Price float64 `xlfill:"unit"`
+	}
+	f := excelize.NewFile()
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	ctx := NewContext(map[string]any{
+		"headers":  []any{"Item", "Total", "Label"},
+		"lines":    []line{{"Bolt", 3, 0.5}, {"Nut", 10, 0.2}},
+		"discount": 1.0,
+	})
+	cmd := &GridCommand{Headers: "headers", Data: "lines",
+		Props: `Item, Qty*unit - discount, join([Item, string(Qty)], "x")`}
+	_, err = cmd.ApplyAt(NewCellRef("Sheet1", 0, 0), ctx, tx)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+	for cell, want := range map[string]string{"A2": "Bolt", "B2": "0.5", "C2": "Boltx3", "B3": "1", "C3": "Nutx10"} {
+		v, _ := out.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+
+	// The row's fields shadow context data only within the props
+	assert.Nil(t, ctx.GetVar("Qty"))
+
+	cmd.Props = "Item, Qty +"
+	_, err = cmd.ApplyAt(NewCellRef("Sheet1", 5, 0), ctx, tx)
+	assert.ErrorContains(t, err, `evaluate prop "Qty +"`)
+}
+
+func TestGridCommand_SplitPropsAndAreaAttrs(t *testing.T) {
+	assert.Equal(t, []string{"A", "max(a, b)", `"x,y"`, "B.C"}, splitProps(`A, max(a, b), "x,y", B.C`))
+	assert.Nil(t, splitProps(" "))
+	assert.Equal(t, []string{`A ?? "say \"x,y\""`, "B"}, splitProps(`A ?? "say \"x,y\"", B`))
+
+	cmd, err := newGridCommandFromAttrs(map[string]string{"headers": "h", "data": "d", "headerArea": "A5:C5", "bodyArea": "D6"})
+	require.NoError(t, err)
+	g := cmd.(*GridCommand)
+	assert.Equal(t, "B5", gridCellStyle(g.HeaderCells, g.headerRef, 1))
+	assert.Equal(t, "C5", gridCellStyle(g.HeaderCells, g.headerRef, 7))
+	assert.Equal(t, "D6", gridCellStyle(g.BodyCells, g.bodyRef, 2))
+
+	_, err = newGridCommandFromAttrs(map[string]string{"headers": "h", "data": "d", "bodyArea": "A1:B2"})
+	assert.ErrorContains(t, err, "invalid bodyArea")
+	_, err = newGridCommandFromAttrs(map[string]string{"headers": "h", "data": "d", "headerArea": "nope"})
+	assert.ErrorContains(t, err, "invalid headerArea")
+}
+
+func TestGridCommand_DirectionRight(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "label")
+	require.NoError(t, f.MergeCell("Sheet1", "A1", "B1"))
+	f.SetCellValue("Sheet1", "C1", "Note")
+	f.SetCellValue("Sheet1", "A2", "Checked")
+	f.SetCellFormula("Sheet1", "B2", "C1")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"C2\")\njx:grid(headers=\"fields\" data=\"accounts\" props=\"Name, Balance\" headerArea=\"Styles!A1:A2\" direction=\"right\" lastCell=\"B1\")"})
+	_, err := f.NewSheet("Styles")
+	require.NoError(t, err)
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Styles", "A2", "A2", bold))
+	tmpl := filepath.Join(testdataDir(t), "grid_right.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+
+	out, err := FillBytes(tmpl, map[string]any{
+		"fields":   []string{"Account", "Balance"},
+		"accounts": []map[string]any{{"Name": "Cash", "Balance": 120}, {"Name": "Bank", "Balance": 75}},
+	})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+
+	for cell, want := range map[string]string{
+		"A1": "Account", "C1": "Cash", "D1": "Bank",
+		"A2": "Balance", "C2": "120", "D2": "75",
+		"E1": "Note", "A3": "Checked",
+	} {
+		v, _ := res.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+
+	// Static cells right of the grid moved, and the formula follows them
+	formula, _ := res.GetCellFormula("Sheet1", "B3")
+	assert.Equal(t, "E1", formula)
+
+	// Headers take the size of the merged template cell
+	merged, err := res.GetMergeCells("Sheet1")
+	require.NoError(t, err)
+	var ranges []string
+	for _, m := range merged {
+		ranges = append(ranges, m.GetStartAxis()+":"+m.GetEndAxis())
+	}
+	assert.ElementsMatch(t, []string{"A1:B1", "A2:B2"}, ranges)
+
+	// A column headerArea styles the header rows in turn
+	_, first := styleOf(t, res, "Sheet1", "A1")
+	assert.True(t, first.Font == nil || !first.Font.Bold)
+	_, second := styleOf(t, res, "Sheet1", "A2")
+	require.NotNil(t, second.Font)
+	assert.True(t, second.Font.Bold)
+}
+
+func TestGridCommand_DirectionRightSize(t *testing.T) {
+	f := excelize.NewFile()
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	ctx := NewContext(map[string]any{
+		"headers": []any{"Name", "Qty", "Price"},
+		"rows":    [][]any{{"Bolt", 3, 0.5}, {"Nut", 9, 0.2}},
+	})
+	cmd := &GridCommand{Headers: "headers", Data: "rows", Direction: "RIGHT"}
+	size, err := cmd.ApplyAt(NewCellRef("Sheet1", 1, 1), ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 3, Height: 3}, size)
+
+	for cell, want := range map[string]string{"B2": "Name", "B4": "Price", "C2": "Bolt", "D3": "9", "D4": "0.2"} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+
+	_, err = newGridCommandFromAttrs(map[string]string{"headers": "h", "data": "d", "direction": "UP"})
+	assert.ErrorContains(t, err, `invalid direction "UP"`)
+}
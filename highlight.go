@@ -0,0 +1,109 @@
+package xlfill
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HighlightCommand implements the jx:highlight command. It renders its area and,
+// when the condition is true, applies a style registered with WithStyles to the
+// output cells or, with applyTo="ROW", to the full width of the enclosing area.
+type HighlightCommand struct {
+	Condition string // boolean expression to evaluate (e.g., "e.Overdue")
+	Style     string // name of a registered style
+	ApplyTo   string // "CELLS" (default) or "ROW"
+	Area      *Area
+
+	rowOffset int // columns of the enclosing area before the command
+	rowWidth  int // width of the enclosing area (0 when unknown)
+}
+
+func (c *HighlightCommand) Name() string { return "highlight" }
+func (c *HighlightCommand) Reset()       {}
+
+// newHighlightCommandFromAttrs creates a HighlightCommand from parsed attributes.
+func newHighlightCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &HighlightCommand{
+		Condition: attrs["condition"],
+		Style:     attrs["style"],
+		ApplyTo:   strings.ToUpper(attrs["applyTo"]),
+	}
+	if cmd.Condition == "" {
+		return nil, fmt.Errorf("highlight command requires 'condition' attribute")
+	}
+	if cmd.Style == "" {
+		return nil, fmt.Errorf("highlight command requires 'style' attribute")
+	}
+	if cmd.ApplyTo == "" {
+		cmd.ApplyTo = "CELLS"
+	}
+	if cmd.ApplyTo != "CELLS" && cmd.ApplyTo != "ROW" {
+		return nil, fmt.Errorf("highlight command: invalid applyTo %q (expected CELLS or ROW)", attrs["applyTo"])
+	}
+	return cmd, nil
+}
+
+// ApplyAt renders the area and styles its output when the condition is true.
+func (c *HighlightCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	if c.Area == nil {
+		return ZeroSize, nil
+	}
+
+	highlight, err := ctx.IsConditionTrue(c.Condition)
+	if err != nil {
+		return ZeroSize, fmt.Errorf("evaluate condition %q: %w", c.Condition, err)
+	}
+	size, err := c.Area.ApplyAt(cellRef, ctx)
+	if err != nil {
+		return ZeroSize, err
+	}
+	if !highlight || size.Width <= 0 || size.Height <= 0 {
+		return size, nil
+	}
+
+	// Cells of the enclosing area on the same rows are written before the
+	// command, so the whole row can be styled now
+	startCol, width := cellRef.Col, size.Width
+	if c.ApplyTo == "ROW" && c.rowWidth > 0 {
+		startCol = cellRef.Col - c.rowOffset
+		width = max(c.rowWidth, c.rowOffset+size.Width)
+	}
+	for row := 0; row < size.Height; row++ {
+		for col := 0; col < width; col++ {
+			ref := NewCellRef(cellRef.Sheet, cellRef.Row+row, startCol+col)
+			if err := applyNamedStyle(transformer, ref, c.Style); err != nil {
+				return ZeroSize, fmt.Errorf("highlight %s: %w", ref, err)
+			}
+		}
+	}
+	return size, nil
+}
+
+// bindHighlightRows tells each jx:highlight the columns of the area it sits in,
+// so applyTo="ROW" can style the full width of that area.
+func bindHighlightRows(areas []*Area) {
+	for _, area := range areas {
+		for _, b := range area.Bindings {
+			if h, ok := b.Command.(*HighlightCommand); ok {
+				h.rowOffset = b.StartRef.Col - area.StartCell.Col
+				h.rowWidth = area.AreaSize.Width
+			}
+			bindHighlightRows(childAreas(b.Command))
+		}
+	}
+}
+
+// styler is implemented by transformers that can lay named styles over output
+// cells.
+type styler interface {
+	// ApplyStyle lays a named style (see WithStyles) over an output cell's style.
+	ApplyStyle(ref CellRef, name string) error
+}
+
+// applyNamedStyle lays a named style over an output cell's style.
+func applyNamedStyle(tx Transformer, ref CellRef, name string) error {
+	if _, ok := unwrapTransformer(tx).(styler); !ok {
+		return fmt.Errorf("transformer %T cannot apply styles", unwrapTransformer(tx))
+	}
+	return tx.(styler).ApplyStyle(ref, name)
+}
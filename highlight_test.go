@@ -0,0 +1,141 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createHighlightTemplate creates an invoice list with a jx:highlight on B2.
+//
+//	A1: "Invoice"  [jx:area(lastCell="C2")]
+//	A2: "${e.No}"  [jx:each(items="invoices" var="e" lastCell="C2")]
+//	B2: "${e.Due}" [jx:highlight(condition="e.Overdue" style="overdue" <attrs> lastCell="B2")]
+//	C2: "${e.Amount}" (bold, number format 4)
+func createHighlightTemplate(t *testing.T, name, attrs string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Invoice")
+	f.SetCellValue("Sheet1", "A2", "${e.No}")
+	f.SetCellValue("Sheet1", "B2", "${e.Due}")
+	f.SetCellValue("Sheet1", "C2", "${e.Amount}")
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}, NumFmt: 4})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Sheet1", "C2", "C2", bold))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="invoices" var="e" lastCell="C2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B2", Author: "xlfill",
+		Text: `jx:highlight(condition="e.Overdue" style="overdue"` + attrs + ` lastCell="B2")`})
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+var overdueStyle = map[string]*excelize.Style{
+	"overdue": {Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}},
+}
+
+func highlightData() map[string]any {
+	return map[string]any{"invoices": []map[string]any{
+		{"No": "INV-1", "Due": "2026-01-10", "Amount": 100, "Overdue": false},
+		{"No": "INV-2", "Due": "2025-12-01", "Amount": 250, "Overdue": true},
+		{"No": "INV-3", "Due": "2026-02-15", "Amount": 75, "Overdue": false},
+	}}
+}
+
+func fillHighlight(t *testing.T, tmpl string, opts ...Option) *excelize.File {
+	t.Helper()
+	out, err := FillBytes(tmpl, highlightData(), opts...)
+	require.NoError(t, err)
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func cellFill(t *testing.T, f *excelize.File, cell string) []string {
+	t.Helper()
+	id, err := f.GetCellStyle("Sheet1", cell)
+	require.NoError(t, err)
+	style, err := f.GetStyle(id)
+	require.NoError(t, err)
+	return style.Fill.Color
+}
+
+func TestHighlight_Cells(t *testing.T) {
+	f := fillHighlight(t, createHighlightTemplate(t, "highlight_cells.xlsx", ""), WithStyles(overdueStyle))
+
+	assert.Equal(t, []string{"FFC7CE"}, cellFill(t, f, "B3"))
+	for _, cell := range []string{"A3", "C3", "B2", "B4"} {
+		assert.Empty(t, cellFill(t, f, cell), cell)
+	}
+	v, _ := f.GetCellValue("Sheet1", "B3")
+	assert.Equal(t, "2025-12-01", v)
+}
+
+func TestHighlight_RowKeepsCellStyle(t *testing.T) {
+	f := fillHighlight(t, createHighlightTemplate(t, "highlight_row.xlsx", ` applyTo="row"`), WithStyles(overdueStyle))
+
+	for _, cell := range []string{"A3", "B3", "C3"} {
+		assert.Equal(t, []string{"FFC7CE"}, cellFill(t, f, cell), cell)
+	}
+	for _, cell := range []string{"A2", "C2", "A4", "C4", "D3"} {
+		assert.Empty(t, cellFill(t, f, cell), cell)
+	}
+
+	// The amount keeps its bold font and number format under the fill
+	id, err := f.GetCellStyle("Sheet1", "C3")
+	require.NoError(t, err)
+	style, err := f.GetStyle(id)
+	require.NoError(t, err)
+	require.NotNil(t, style.Font)
+	assert.True(t, style.Font.Bold)
+	assert.Equal(t, 4, style.NumFmt)
+}
+
+func TestHighlight_StyleCreatedOncePerBaseStyle(t *testing.T) {
+	tmpl := createHighlightTemplate(t, "highlight_reuse.xlsx", ` applyTo="ROW"`)
+	data := highlightData()
+	data["invoices"] = append(data["invoices"].([]map[string]any),
+		map[string]any{"No": "INV-4", "Due": "2025-11-01", "Amount": 30, "Overdue": true})
+	out, err := FillBytes(tmpl, data, WithStyles(overdueStyle))
+	require.NoError(t, err)
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	a3, _ := f.GetCellStyle("Sheet1", "A3")
+	a5, _ := f.GetCellStyle("Sheet1", "A5")
+	c3, _ := f.GetCellStyle("Sheet1", "C3")
+	c5, _ := f.GetCellStyle("Sheet1", "C5")
+	assert.Equal(t, a3, a5)
+	assert.Equal(t, c3, c5)
+	assert.NotEqual(t, a3, c3)
+}
+
+func TestHighlight_UnknownStyle(t *testing.T) {
+	tmpl := createHighlightTemplate(t, "highlight_unknown.xlsx", "")
+	_, err := FillBytes(tmpl, highlightData())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown style "overdue"`)
+
+	result, err := Validate(tmpl)
+	require.NoError(t, err)
+	require.NotEmpty(t, result)
+	assert.Contains(t, result[0].Message, `unregistered style "overdue"`)
+}
+
+func TestHighlight_InvalidAttributes(t *testing.T) {
+	_, err := newHighlightCommandFromAttrs(map[string]string{"style": "x"})
+	assert.ErrorContains(t, err, "requires 'condition'")
+	_, err = newHighlightCommandFromAttrs(map[string]string{"condition": "true"})
+	assert.ErrorContains(t, err, "requires 'style'")
+	_, err = newHighlightCommandFromAttrs(map[string]string{"condition": "true", "style": "x", "applyTo": "COLUMN"})
+	assert.ErrorContains(t, err, `invalid applyTo "COLUMN"`)
+}
@@ -1,12 +1,20 @@
 package xlfill
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // IfCommand implements the jx:if command for conditional rendering.
 type IfCommand struct {
 	Condition string // boolean expression to evaluate
 	IfArea    *Area  // area to render when condition is true
 	ElseArea  *Area  // area to render when condition is false (optional)
+
+	// ElseAction is what a false condition without an else area leaves:
+	// "REMOVE" (default) removes the area, so the cells below move up;
+	// "CLEAR" keeps its space, blank but formatted.
+	ElseAction string
 }
 
 func (c *IfCommand) Name() string { return "if" }
@@ -15,11 +23,18 @@ func (c *IfCommand) Reset()       {}
 // newIfCommandFromAttrs creates an IfCommand from parsed attributes.
 func newIfCommandFromAttrs(attrs map[string]string) (Command, error) {
 	cmd := &IfCommand{
-		Condition: attrs["condition"],
+		Condition:  attrs["condition"],
+		ElseAction: strings.ToUpper(attrs["elseAction"]),
 	}
 	if cmd.Condition == "" {
 		return nil, fmt.Errorf("if command requires 'condition' attribute")
 	}
+	if cmd.ElseAction == "" {
+		cmd.ElseAction = "REMOVE"
+	}
+	if cmd.ElseAction != "REMOVE" && cmd.ElseAction != "CLEAR" {
+		return nil, fmt.Errorf("if command: invalid elseAction %q (expected REMOVE or CLEAR)", attrs["elseAction"])
+	}
 	return cmd, nil
 }
 
@@ -38,7 +53,25 @@ func (c *IfCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transform
 		if c.ElseArea != nil {
 			return c.ElseArea.ApplyAt(cellRef, ctx)
 		}
+		if c.ElseAction == "CLEAR" && c.IfArea != nil {
+			return c.clear(cellRef, transformer)
+		}
 	}
 
 	return ZeroSize, nil
 }
+
+// clear blanks the cells the if area would have filled at cellRef and returns
+// its size, so the cells after it stay in place.
+func (c *IfCommand) clear(cellRef CellRef, transformer Transformer) (Size, error) {
+	size := c.IfArea.AreaSize
+	for row := 0; row < size.Height; row++ {
+		for col := 0; col < size.Width; col++ {
+			ref := NewCellRef(cellRef.Sheet, cellRef.Row+row, cellRef.Col+col)
+			if err := transformer.ClearCell(ref); err != nil {
+				return ZeroSize, fmt.Errorf("clear %s: %w", ref, err)
+			}
+		}
+	}
+	return size, nil
+}
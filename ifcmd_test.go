@@ -2,6 +2,7 @@ package xlfill
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -258,3 +259,92 @@ func TestIfCommand_WithElseExpression(t *testing.T) {
 	v, _ := out.GetCellValue(sheet, "A5")
 	assert.Equal(t, "Alice: Standard", v)
 }
+
+func TestNewIfCommand_ElseAction(t *testing.T) {
+	cmd, err := newIfCommandFromAttrs(map[string]string{"condition": "show"})
+	require.NoError(t, err)
+	assert.Equal(t, "REMOVE", cmd.(*IfCommand).ElseAction)
+
+	cmd, err = newIfCommandFromAttrs(map[string]string{"condition": "show", "elseAction": "clear"})
+	require.NoError(t, err)
+	assert.Equal(t, "CLEAR", cmd.(*IfCommand).ElseAction)
+
+	_, err = newIfCommandFromAttrs(map[string]string{"condition": "show", "elseAction": "hide"})
+	assert.ErrorContains(t, err, `invalid elseAction "hide"`)
+}
+
+// fillIfTemplate fills a template listing employees in rows 2-3 between a
+// header and a footer, with the given jx:if comment on A2.
+func fillIfTemplate(t *testing.T, ifComment string, setup func(f *excelize.File), opts ...Option) [][]string {
+	t.Helper()
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Header")
+	f.SetCellValue("Sheet1", "A2", "VIP: ${e.Name}")
+	f.SetCellValue("Sheet1", "A3", "Regular: ${e.Name}")
+	f.SetCellValue("Sheet1", "A4", "End")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A4")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="employees" var="e" lastCell="A3")` + "\n" + ifComment})
+	if setup != nil {
+		setup(f)
+	}
+	tmpl := filepath.Join(testdataDir(t), "if_areas.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	employees := []map[string]any{{"Name": "Alice", "VIP": true}, {"Name": "Bob", "VIP": false}}
+	out, err := FillBytes(tmpl, map[string]any{"employees": employees}, opts...)
+	require.NoError(t, err)
+	rows, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	return rows
+}
+
+func TestFill_IfElseAreas(t *testing.T) {
+	want := [][]string{{"Header"}, {"VIP: Alice"}, {"Regular: Bob"}, {"End"}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		assert.Equal(t, want, fillIfTemplate(t, `jx:if(condition="e.VIP" lastCell="A2" areas=["A2:A2","A3:A3"])`, nil, opts...))
+	}
+	assert.Equal(t, want, fillIfTemplate(t, `jx:if(condition="e.VIP" lastCell="A2" elseArea="A3:A3")`, nil))
+}
+
+func TestFill_IfElseAreaOnOtherSheet(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "VIP: ${e.Name}")
+	f.SetCellValue("Sheet1", "B1", "${e.Name}")
+	f.SetCellValue("Sheet1", "A2", "End")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")`})
+	f.NewSheet("Else Parts")
+	f.SetCellValue("Else Parts", "B2", "Other: ${e.Name}")
+	tmpl := filepath.Join(testdataDir(t), "if_else_sheet.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	employees := []map[string]any{{"Name": "Alice", "VIP": true}, {"Name": "Bob", "VIP": false}}
+	for _, ifComment := range []string{
+		`jx:if(condition="e.VIP" lastCell="A1" areas=["A1:A1","'Else Parts'!B2:B2"])`,
+		`jx:if(condition="e.VIP" lastCell="A1" elseArea="'Else Parts'!B2:B2")`,
+	} {
+		f, err := excelize.OpenFile(tmpl)
+		require.NoError(t, err)
+		require.NoError(t, f.DeleteComment("Sheet1", "A1"))
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+			Text: `jx:area(lastCell="B2")` + "\n" + `jx:each(items="employees" var="e" lastCell="B1")` + "\n" + ifComment})
+		require.NoError(t, f.Save())
+		f.Close()
+
+		out, err := FillBytes(tmpl, map[string]any{"employees": employees})
+		require.NoError(t, err, ifComment)
+		rows, err := openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"VIP: Alice", "Alice"}, {"Other: Bob", "Bob"}, {"End"}}, rows, ifComment)
+	}
+}
+
+func TestFill_IfElseActionClear(t *testing.T) {
+	rows := fillIfTemplate(t, `jx:if(condition="e.VIP" lastCell="A2" elseAction="CLEAR")`, nil)
+	assert.Equal(t, [][]string{{"Header"}, {"VIP: Alice"}, {"Regular: Alice"}, nil, {"Regular: Bob"}, {"End"}}, rows)
+
+	rows = fillIfTemplate(t, `jx:if(condition="e.VIP" lastCell="A2")`, nil)
+	assert.Equal(t, [][]string{{"Header"}, {"VIP: Alice"}, {"Regular: Alice"}, {"Regular: Bob"}, {"End"}}, rows)
+}
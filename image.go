@@ -6,11 +6,15 @@ import (
 )
 
 // ImageCommand implements the jx:image command for embedding images.
+// The image is anchored at the top-left cell of the command's output, so inside
+// a jx:each each item gets its own image.
 type ImageCommand struct {
-	Src       string  // expression returning []byte
-	ImageType string  // PNG, JPEG, etc. (default: PNG)
-	ScaleX    float64 // width scale (default: 1.0)
-	ScaleY    float64 // height scale (default: 1.0)
+	Src         string  // expression returning []byte
+	Placeholder string  // expression for the image used when src is empty (optional)
+	ImageType   string  // PNG, JPEG, etc. (default: PNG)
+	ScaleX      float64 // width scale (default: 1.0)
+	ScaleY      float64 // height scale (default: 1.0)
+	Area        *Area   // cells covered by the image; their template content is not rendered
 }
 
 func (c *ImageCommand) Name() string { return "image" }
@@ -19,10 +23,11 @@ func (c *ImageCommand) Reset()       {}
 // newImageCommandFromAttrs creates an ImageCommand from parsed attributes.
 func newImageCommandFromAttrs(attrs map[string]string) (Command, error) {
 	cmd := &ImageCommand{
-		Src:       attrs["src"],
-		ImageType: strings.ToUpper(attrs["imageType"]),
-		ScaleX:    1.0,
-		ScaleY:    1.0,
+		Src:         attrs["src"],
+		Placeholder: attrs["placeholder"],
+		ImageType:   strings.ToUpper(attrs["imageType"]),
+		ScaleX:      1.0,
+		ScaleY:      1.0,
 	}
 	if cmd.Src == "" {
 		return nil, fmt.Errorf("image command requires 'src' attribute")
@@ -40,27 +45,61 @@ func newImageCommandFromAttrs(attrs map[string]string) (Command, error) {
 	return cmd, nil
 }
 
-// ApplyAt inserts the image at the given target cell.
+// ApplyAt inserts the image at the given target cell. An item without an
+// image gets the placeholder, or no image at all.
 func (c *ImageCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
-	// Evaluate src expression to get image bytes
-	val, err := ctx.Evaluate(c.Src)
-	if err != nil {
-		return ZeroSize, fmt.Errorf("evaluate image src %q: %w", c.Src, err)
+	size := Size{Width: 1, Height: 1}
+	if c.Area != nil {
+		size = c.Area.AreaSize
 	}
 
-	if val == nil {
-		return Size{Width: 1, Height: 1}, nil // skip gracefully
+	imgBytes, err := c.imageBytes(ctx, "src", c.Src)
+	if err != nil {
+		return ZeroSize, err
+	}
+	if len(imgBytes) == 0 && c.Placeholder != "" {
+		if imgBytes, err = c.imageBytes(ctx, "placeholder", c.Placeholder); err != nil {
+			return ZeroSize, err
+		}
 	}
 
-	imgBytes, ok := val.([]byte)
-	if !ok {
-		return ZeroSize, fmt.Errorf("image src must be []byte, got %T", val)
+	// The covered cells hold no output of their own; clear what the template
+	// left at this target
+	for row := 0; row < size.Height; row++ {
+		for col := 0; col < size.Width; col++ {
+			if err := transformer.ClearCell(NewCellRef(cellRef.Sheet, cellRef.Row+row, cellRef.Col+col)); err != nil {
+				return ZeroSize, err
+			}
+		}
 	}
 
+	if len(imgBytes) == 0 {
+		return size, nil // skip gracefully
+	}
 	cellName := cellRef.CellName()
 	if err := transformer.AddImage(cellRef.Sheet, cellName, imgBytes, c.ImageType, c.ScaleX, c.ScaleY); err != nil {
 		return ZeroSize, fmt.Errorf("add image at %s: %w", cellRef, err)
 	}
 
-	return Size{Width: 1, Height: 1}, nil
+	return size, nil
+}
+
+// imageBytes evaluates an image expression. A nil result yields no bytes.
+func (c *ImageCommand) imageBytes(ctx *Context, attr, expression string) ([]byte, error) {
+	val, err := ctx.Evaluate(expression)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate image %s %q: %w", attr, expression, err)
+	}
+	switch v := val.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case *[]byte:
+		if v == nil {
+			return nil, nil
+		}
+		return *v, nil
+	}
+	return nil, fmt.Errorf("image %s must be []byte, got %T", attr, val)
 }
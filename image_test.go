@@ -5,6 +5,7 @@ import (
 	"image/color"
 	"image/png"
 	"bytes"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -101,3 +102,75 @@ func TestNewImageCommandFromAttrs_Defaults(t *testing.T) {
 	assert.Equal(t, 1.0, img.ScaleX)
 	assert.Equal(t, 1.0, img.ScaleY)
 }
+
+// createImageEachTemplate creates a list with a 2-row photo area per item.
+//
+//	A1: "Staff"      [jx:area(lastCell="B4")]
+//	A2: "photo"      [jx:each(items="staff" var="e" lastCell="B3"), jx:image(src="e.Photo" <attrs> lastCell="A3")]
+//	B2: "${e.Name}"
+//	A4: "End"
+func createImageEachTemplate(t *testing.T, name, attrs string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Staff")
+	f.SetCellValue("Sheet1", "A2", "photo")
+	f.SetCellValue("Sheet1", "B2", "${e.Name}")
+	f.SetCellValue("Sheet1", "A4", "End")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B4")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: "jx:each(items=\"staff\" var=\"e\" lastCell=\"B3\")\njx:image(src=\"e.Photo\"" + attrs + " lastCell=\"A3\")"})
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func pictureCells(t *testing.T, f *excelize.File, cells ...string) []string {
+	t.Helper()
+	var found []string
+	for _, cell := range cells {
+		pics, err := f.GetPictures("Sheet1", cell)
+		require.NoError(t, err)
+		if len(pics) > 0 {
+			found = append(found, cell)
+		}
+	}
+	return found
+}
+
+func TestImageCommand_InsideEach(t *testing.T) {
+	png := createTestPNG(t)
+	tmpl := createImageEachTemplate(t, "image_each.xlsx", "")
+	for _, opts := range [][]Option{nil, {WithConcurrency(2)}} {
+		out, err := FillBytes(tmpl, map[string]any{"staff": []map[string]any{
+			{"Name": "Ann", "Photo": png}, {"Name": "Ben"}, {"Name": "Cal", "Photo": []byte{}}, {"Name": "Dee", "Photo": png},
+		}}, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+
+		// One image per item with a photo, anchored at the item's own rows
+		assert.Equal(t, []string{"A2", "A8"}, pictureCells(t, res, "A2", "A3", "A4", "A5", "A6", "A7", "A8", "A9", "A10"))
+		for cell, want := range map[string]string{"B2": "Ann", "B4": "Ben", "B8": "Dee", "A2": "", "A4": "", "A6": "", "A10": "End"} {
+			v, _ := res.GetCellValue("Sheet1", cell)
+			assert.Equal(t, want, v, cell)
+		}
+	}
+}
+
+func TestImageCommand_Placeholder(t *testing.T) {
+	png := createTestPNG(t)
+	tmpl := createImageEachTemplate(t, "image_placeholder.xlsx", ` placeholder="noPhoto"`)
+	out, err := FillBytes(tmpl, map[string]any{
+		"noPhoto": png,
+		"staff":   []map[string]any{{"Name": "Ann", "Photo": png}, {"Name": "Ben"}},
+	})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	assert.Equal(t, []string{"A2", "A4"}, pictureCells(t, res, "A2", "A3", "A4", "A5"))
+
+	_, err = FillBytes(tmpl, map[string]any{
+		"noPhoto": "missing.png",
+		"staff":   []map[string]any{{"Name": "Ben"}},
+	})
+	assert.ErrorContains(t, err, "image placeholder must be []byte, got string")
+}
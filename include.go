@@ -0,0 +1,183 @@
+package xlfill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// IncludeSource is implemented by a TemplateSource that also provides the
+// templates named by jx:include, e.g. relative to its own location.
+type IncludeSource interface {
+	// OpenInclude returns the content of the template name. The caller closes it.
+	OpenInclude(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// IncludeCommand implements jx:include. It renders an area of another
+// template file at its position, with the current context, so blocks such as
+// a letterhead are kept in one workbook shared by many templates:
+//
+//	jx:include(template="header.xlsx" area="Sheet1!A1:F3" lastCell="F3")
+//
+// The template is resolved like the including one: relative to the directory
+// of WithTemplate or WithTemplateFS, or by a TemplateSource that implements
+// IncludeSource. area is a range, rendered cell by cell, or the name or start
+// cell of a jx:area of the included template, rendered with its commands.
+// Styles of the included cells are copied into the output.
+type IncludeCommand struct {
+	Template string // template file, e.g. "header.xlsx"
+	Source   string // area of the template, e.g. "Sheet1!A1:F3" or "letterhead"
+	Area     *Area
+}
+
+func (c *IncludeCommand) Name() string { return "include" }
+func (c *IncludeCommand) Reset()       {}
+
+// newIncludeCommandFromAttrs creates an IncludeCommand from parsed attributes.
+func newIncludeCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &IncludeCommand{Template: attrs["template"], Source: attrs["area"]}
+	if cmd.Template == "" {
+		return nil, fmt.Errorf("include command requires 'template' attribute")
+	}
+	if cmd.Source == "" {
+		return nil, fmt.Errorf("include command requires 'area' attribute")
+	}
+	return cmd, nil
+}
+
+// ApplyAt renders the included area at cellRef and returns its size.
+func (c *IncludeCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	out, ok := unwrapTransformer(transformer).(templateIncluder)
+	if !ok || ctx.state.includes == nil {
+		return ZeroSize, fmt.Errorf("include command: transformer %T cannot render other templates", unwrapTransformer(transformer))
+	}
+	area, err := ctx.state.includes.area(c.Template, c.Source, out)
+	if err != nil {
+		return ZeroSize, fmt.Errorf("include %q: %w", c.Template, err)
+	}
+	return area.ApplyAt(cellRef, ctx)
+}
+
+// includes are the templates opened by jx:include during a fill. Each is read
+// once and written into the output workbook by its own transformer.
+type includes struct {
+	mu        sync.Mutex
+	filler    *Filler
+	templates map[string]*includedTemplate // by name
+	order     []*includedTemplate
+}
+
+// templateIncluder is implemented by transformers that can render the cells
+// of another template into their output.
+type templateIncluder interface {
+	// includeTemplate opens the template data with a transformer that reads
+	// it and writes to the output of this one.
+	includeTemplate(data []byte) (*includedTemplate, error)
+}
+
+type includedTemplate struct {
+	tx      Transformer
+	sheet   string           // first sheet of the template
+	close   func() error     // releases the template, not the output
+	roots   []*Area          // jx:area areas, parsed on first use
+	areas   map[string]*Area // by area attribute
+	applied []*Area
+}
+
+// area returns the area source of the template name, writing to out.
+func (in *includes) area(name, source string, out templateIncluder) (*Area, error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	t, ok := in.templates[name]
+	if !ok {
+		data, err := in.filler.readInclude(name)
+		if err != nil {
+			return nil, err
+		}
+		if t, err = out.includeTemplate(data); err != nil {
+			return nil, err
+		}
+		t.areas = map[string]*Area{}
+		in.templates[name] = t
+		in.order = append(in.order, t)
+	}
+	if area, ok := t.areas[source]; ok {
+		return area, nil
+	}
+
+	var area *Area
+	if ref, err := ParseAreaRef(source); err == nil {
+		// A range without a sheet is on the first sheet
+		if ref.First.Sheet == "" {
+			ref.First.Sheet, ref.Last.Sheet = t.sheet, t.sheet
+		}
+		area = NewArea(ref.First, ref.Size(), t.tx)
+	} else {
+		if t.roots == nil {
+			// Area definitions name cells of the including template
+			opts := *in.filler.opts
+			opts.areaDefinitions = nil
+			filler := Filler{opts: &opts, registry: in.filler.registry}
+			if t.roots, err = filler.BuildAreas(t.tx); err != nil {
+				return nil, err
+			}
+		}
+		if area, err = findArea(t.roots, source); err != nil {
+			return nil, err
+		}
+	}
+	t.areas[source] = area
+	t.applied = append(t.applied, area)
+	return area, nil
+}
+
+// processFormulas rewrites the formulas of the included areas for their output cells.
+func (in *includes) processFormulas(fp *StandardFormulaProcessor) {
+	for _, t := range in.order {
+		for _, area := range t.applied {
+			fp.ProcessAreaFormulas(t.tx, area)
+		}
+	}
+}
+
+// close closes the included template files. The output workbook stays open.
+func (in *includes) close() {
+	for _, t := range in.order {
+		t.close()
+	}
+}
+
+// readInclude reads the template name of a jx:include, resolved like the
+// template of f.
+func (f *Filler) readInclude(name string) ([]byte, error) {
+	switch {
+	case f.opts.templateSource != nil:
+		src, ok := f.opts.templateSource.(IncludeSource)
+		if !ok {
+			return nil, fmt.Errorf("template source %T does not implement IncludeSource", f.opts.templateSource)
+		}
+		ctx := f.opts.cancel
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		r, err := src.OpenInclude(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case f.opts.templateFS != nil:
+		return fs.ReadFile(f.opts.templateFS, path.Join(path.Dir(f.opts.templateName), name))
+	case f.opts.templatePath != "":
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(filepath.Dir(f.opts.templatePath), name)
+		}
+		return os.ReadFile(name)
+	}
+	return nil, fmt.Errorf("included templates are resolved relative to the template: use WithTemplate, WithTemplateFS or WithTemplateSource")
+}
@@ -0,0 +1,86 @@
+package xlfill
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestIncludeCommand(t *testing.T) {
+	dir := testdataDir(t)
+	inc := excelize.NewFile()
+	inc.SetCellValue("Sheet1", "A1", "${title}")
+	inc.SetCellValue("Sheet1", "B1", "Printed")
+	inc.SetCellValue("Sheet1", "A2", 5)
+	inc.SetCellFormula("Sheet1", "B2", "A2*2")
+	bold, err := inc.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+	inc.SetCellStyle("Sheet1", "A1", "A1", bold)
+	inc.SetCellValue("Sheet1", "A5", "${p}")
+	inc.SetCellValue("Sheet1", "A6", "Signed")
+	inc.AddComment("Sheet1", excelize.Comment{Cell: "A5", Author: "xlfill", Text: `jx:area(name="sign" lastCell="A6")` + "\n" +
+		`jx:each(items="people" var="p" lastCell="A5")`})
+	require.NoError(t, inc.SaveAs(filepath.Join(dir, "include_header.xlsx")))
+	inc.Close()
+
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Report")
+	f.SetCellValue("Sheet1", "A4", "Body")
+	f.SetCellValue("Sheet1", "A6", "End")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B6")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:include(template="include_header.xlsx" area="Sheet1!A1:B2" lastCell="B3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A5", Author: "xlfill",
+		Text: `jx:include(template="include_header.xlsx" area="sign" lastCell="A5")`})
+	tmpl := filepath.Join(dir, "include.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"title": "Quarterly", "people": []string{"Ann", "Bob"}}
+	check := func(b []byte) {
+		out, err := excelize.OpenReader(bytes.NewReader(b))
+		require.NoError(t, err)
+		defer out.Close()
+		rows, err := out.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Report"}, {"Quarterly", "Printed"}, {"5", ""}, {"Body"}, {"Ann"}, {"Bob"}, {"Signed"}, {"End"}}, rows)
+		formula, _ := out.GetCellFormula("Sheet1", "B3")
+		assert.Equal(t, "A3*2", formula)
+		styleID, _ := out.GetCellStyle("Sheet1", "A2")
+		style, err := out.GetStyle(styleID)
+		require.NoError(t, err)
+		require.NotNil(t, style.Font)
+		assert.True(t, style.Font.Bold)
+	}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		b, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		check(b)
+	}
+
+	// Templates in a file system are resolved next to the including one
+	read := func(name string) []byte {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return b
+	}
+	fsys := fstest.MapFS{
+		"reports/include.xlsx":        {Data: read("include.xlsx")},
+		"reports/include_header.xlsx": {Data: read("include_header.xlsx")},
+	}
+	b, err := NewFiller(WithTemplateFS(fsys, "reports/include.xlsx")).FillBytes(data)
+	require.NoError(t, err)
+	check(b)
+
+	delete(fsys, "reports/include_header.xlsx")
+	_, err = NewFiller(WithTemplateFS(fsys, "reports/include.xlsx")).FillBytes(data)
+	assert.ErrorContains(t, err, `include "include_header.xlsx"`)
+	_, err = newIncludeCommandFromAttrs(map[string]string{"template": "header.xlsx"})
+	assert.ErrorContains(t, err, "requires 'area'")
+}
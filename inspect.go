@@ -0,0 +1,302 @@
+package xlfill
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+)
+
+// TemplateModel is a structured, JSON-serializable description of a template:
+// its areas, commands and expressions, and the context variables it reads.
+type TemplateModel struct {
+	Areas []*AreaModel `json:"areas"`
+	// Variables lists the top-level data keys the template reads, sorted.
+	// Loop variables and built-ins such as _row are excluded.
+	Variables []string `json:"variables"`
+}
+
+// AreaModel describes an area and the commands and expressions directly inside it.
+type AreaModel struct {
+	Ref         string             `json:"ref"`            // e.g. "Sheet1!A1:C2"
+	Name        string             `json:"name,omitempty"` // from jx:area(name="...")
+	Expressions []*ExpressionModel `json:"expressions,omitempty"`
+	Commands    []*CommandModel    `json:"commands,omitempty"`
+}
+
+// CommandModel describes a command, its template attributes and its inner areas.
+type CommandModel struct {
+	Name  string            `json:"name"`
+	Ref   string            `json:"ref"` // the command's area, e.g. "Sheet1!A2:C2"
+	Attrs map[string]string `json:"attrs,omitempty"`
+	// Defines lists the variables the command binds for its inner area (var, varIndex, ...).
+	Defines []string     `json:"defines,omitempty"`
+	Areas   []*AreaModel `json:"areas,omitempty"` // inner area, plus the else area for jx:if
+}
+
+// ExpressionModel describes a cell value or formula that contains expressions.
+type ExpressionModel struct {
+	Cell        string   `json:"cell"`              // e.g. "Sheet1!A2"
+	Text        string   `json:"text"`              // raw cell value, or "=formula"
+	Formula     bool     `json:"formula,omitempty"` // true for parameterized formulas
+	Expressions []string `json:"expressions"`       // expression bodies without delimiters
+	Variables   []string `json:"variables,omitempty"`
+}
+
+// builtinVariables are provided by the engine and never required from data.
+var builtinVariables = map[string]bool{"_row": true, "_col": true, "_sheet": true, "_target": true}
+
+// expressionAttrs lists, per command, the attributes that hold expressions.
+// renderIf is an expression on every command.
+var expressionAttrs = map[string][]string{
+	"each":        {"items", "select", "distinct", "multisheet", "mergeBy"},
+	"if":          {"condition"},
+	"grid":        {"headers", "data"},
+	"image":       {"src", "placeholder"},
+	"mergeCells":  {"cols", "rows"},
+	"pivot":       {"items", "rowKey", "colKey", "value"},
+	"highlight":   {"condition"},
+	"deleteRowIf": {"condition"},
+}
+
+// Inspect parses a template and returns its structured model.
+func Inspect(templatePath string, opts ...Option) (*TemplateModel, error) {
+	allOpts := append([]Option{WithTemplate(templatePath)}, opts...)
+	filler := NewFiller(allOpts...)
+	tx, err := filler.openTemplate()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Close()
+	return filler.Inspect(tx)
+}
+
+// Inspect builds the areas of an opened template and returns its structured model.
+func (f *Filler) Inspect(tx Transformer) (*TemplateModel, error) {
+	areas, err := f.BuildAreas(tx)
+	if err != nil {
+		return nil, fmt.Errorf("build areas: %w", err)
+	}
+
+	ins := &inspector{filler: f, tx: tx, required: map[string]bool{}}
+	model := &TemplateModel{Variables: []string{}}
+	for _, area := range areas {
+		model.Areas = append(model.Areas, ins.area(area, nil))
+	}
+	for name := range ins.required {
+		model.Variables = append(model.Variables, name)
+	}
+	sort.Strings(model.Variables)
+	return model, nil
+}
+
+// inspector walks the area tree, tracking variables bound by enclosing commands.
+type inspector struct {
+	filler   *Filler
+	tx       Transformer
+	required map[string]bool
+}
+
+// use records variables referenced in scope that are not bound locally.
+func (ins *inspector) use(vars []string, scope map[string]bool) {
+	for _, v := range vars {
+		if !scope[v] && !builtinVariables[v] {
+			ins.required[v] = true
+		}
+	}
+}
+
+func (ins *inspector) area(area *Area, scope map[string]bool) *AreaModel {
+	lastCell := NewCellRef(
+		area.StartCell.Sheet,
+		area.StartCell.Row+area.AreaSize.Height-1,
+		area.StartCell.Col+area.AreaSize.Width-1,
+	)
+	model := &AreaModel{Ref: NewAreaRef(area.StartCell, lastCell).String(), Name: area.Name}
+
+	childRanges := make([][4]int, 0, len(area.Bindings))
+	for _, bind := range area.Bindings {
+		childRanges = append(childRanges, [4]int{
+			bind.StartRef.Row,
+			bind.StartRef.Col,
+			bind.StartRef.Row + bind.Size.Height - 1,
+			bind.StartRef.Col + bind.Size.Width - 1,
+		})
+	}
+
+	begin := ins.filler.opts.notationBegin
+	for row := 0; row < area.AreaSize.Height; row++ {
+		for col := 0; col < area.AreaSize.Width; col++ {
+			absRow := area.StartCell.Row + row
+			absCol := area.StartCell.Col + col
+			if inChildRange(absRow, absCol, childRanges) {
+				continue
+			}
+			ref := NewCellRef(area.StartCell.Sheet, absRow, absCol)
+			cd := ins.tx.GetCellData(ref)
+			if cd == nil {
+				continue
+			}
+			if strVal, ok := cd.Value.(string); ok && strings.Contains(strVal, begin) {
+				model.Expressions = append(model.Expressions, ins.expression(ref, strVal, strVal, false, scope))
+			}
+			if cd.Formula != "" && strings.Contains(cd.Formula, begin) {
+				model.Expressions = append(model.Expressions, ins.expression(ref, "="+cd.Formula, cd.Formula, true, scope))
+			}
+		}
+	}
+
+	for _, bind := range area.Bindings {
+		model.Commands = append(model.Commands, ins.command(bind, scope))
+	}
+	return model
+}
+
+func (ins *inspector) expression(ref CellRef, text, value string, formula bool, scope map[string]bool) *ExpressionModel {
+	model := &ExpressionModel{Cell: ref.String(), Text: text, Formula: formula, Expressions: []string{}}
+	seen := map[string]bool{}
+	begin, end := ins.filler.opts.notationBegin, ins.filler.opts.notationEnd
+	for _, seg := range ParseExpressions(value, begin, end) {
+		if !seg.IsExpression {
+			continue
+		}
+		model.Expressions = append(model.Expressions, seg.Text)
+		for _, v := range ExpressionVariables(seg.Text) {
+			if !seen[v] {
+				seen[v] = true
+				model.Variables = append(model.Variables, v)
+			}
+		}
+	}
+	sort.Strings(model.Variables)
+	ins.use(model.Variables, scope)
+	return model
+}
+
+func (ins *inspector) command(bind *CommandBinding, scope map[string]bool) *CommandModel {
+	cmd := bind.Command
+	lastCell := NewCellRef(bind.StartRef.Sheet, bind.StartRef.Row+bind.Size.Height-1, bind.StartRef.Col+bind.Size.Width-1)
+	model := &CommandModel{
+		Name:    cmd.Name(),
+		Ref:     NewAreaRef(bind.StartRef, lastCell).String(),
+		Attrs:   bind.Attrs,
+		Defines: commandVariables(cmd),
+	}
+
+	// Command attributes are evaluated in the command's own scope
+	inner := make(map[string]bool, len(scope)+len(model.Defines))
+	for v := range scope {
+		inner[v] = true
+	}
+	for _, v := range model.Defines {
+		inner[v] = true
+	}
+	if bind.RenderIf != "" {
+		ins.use(ExpressionVariables(bind.RenderIf), scope)
+	}
+	for _, attr := range expressionAttrs[cmd.Name()] {
+		if expression := bind.Attrs[attr]; expression != "" {
+			ins.use(ExpressionVariables(expression), inner)
+		}
+	}
+	if each, ok := cmd.(*EachCommand); ok {
+		for _, let := range each.Let {
+			ins.use(ExpressionVariables(let.Expression), inner)
+		}
+	}
+
+	// The cells of a jx:raw area are not evaluated, so they use no variables.
+	if _, raw := cmd.(*RawCommand); raw {
+		return model
+	}
+	for _, child := range childAreas(cmd) {
+		areaScope := inner
+		if each, ok := cmd.(*EachCommand); ok && child == each.Footer {
+			// A group footer sees the group as the loop variable; otherwise the
+			// footer is rendered outside the loop
+			areaScope = scope
+			if each.GroupBy != "" {
+				areaScope = map[string]bool{each.groupName(): true}
+				if each.ItemVar != "" {
+					areaScope[each.ItemVar] = true
+				}
+				maps.Copy(areaScope, scope)
+			}
+		}
+		model.Areas = append(model.Areas, ins.area(child, areaScope))
+	}
+	return model
+}
+
+// commandVariables returns the variables a built-in command binds while it runs.
+func commandVariables(cmd Command) []string {
+	var names []string
+	switch c := cmd.(type) {
+	case *EachCommand:
+		names = []string{c.Var, c.VarIndex, c.VarStatus, c.RowIndex, c.GroupVar, c.ItemVar}
+		for _, let := range c.Let {
+			names = append(names, let.Name)
+		}
+		if c.MultiSheet != "" {
+			names = append(names, sheetVar)
+		}
+	case *PivotCommand:
+		names = []string{c.Var}
+	}
+	var vars []string
+	for _, n := range names {
+		if n != "" {
+			vars = append(vars, n)
+		}
+	}
+	return vars
+}
+
+// ExpressionVariables returns the sorted root variable names an expression reads,
+// e.g. "e.Price * qty" → [e qty], including those of fallbacks such as
+// "e.Qty | defaultQty". Function names are not included. An expression that
+// does not parse yields nil.
+func ExpressionVariables(expression string) []string {
+	v := &variableCollector{callees: map[ast.Node]bool{}, declared: map[string]bool{}}
+	for _, alt := range fallbacks(expression) {
+		tree, err := parser.Parse(alt)
+		if err != nil {
+			return nil
+		}
+		ast.Walk(&tree.Node, v)
+	}
+
+	seen := map[string]bool{}
+	var vars []string
+	for _, ident := range v.idents {
+		if v.callees[ident] || v.declared[ident.Value] || seen[ident.Value] {
+			continue
+		}
+		seen[ident.Value] = true
+		vars = append(vars, ident.Value)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// variableCollector gathers identifiers, function callees and let-bound names.
+// ast.Walk visits children before parents, so filtering happens after the walk.
+type variableCollector struct {
+	idents   []*ast.IdentifierNode
+	callees  map[ast.Node]bool
+	declared map[string]bool
+}
+
+func (v *variableCollector) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.CallNode:
+		v.callees[n.Callee] = true
+	case *ast.VariableDeclaratorNode:
+		v.declared[n.Name] = true
+	case *ast.IdentifierNode:
+		v.idents = append(v.idents, n)
+	}
+}
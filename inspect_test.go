@@ -0,0 +1,108 @@
+package xlfill
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createInspectTemplate creates a template exercising nested scopes.
+// Layout:
+//
+//	A1: "Report ${title}"                    [jx:area(lastCell="C3")]
+//	A2: "${i}: ${e.Name}"  B2: "${upper(e.Dept)}"  C2: "${hyperlink(e.Url, e.Name)}"
+//	    [jx:each(items="employees" var="e" varIndex="i" select="e.Age > minAge" lastCell="C2")]
+//	A3: "${total}"                           [jx:if(condition="showTotal" lastCell="A3")]
+func createInspectTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Report ${title}")
+	f.SetCellValue(sheet, "A2", "${i}: ${e.Name}")
+	f.SetCellValue(sheet, "B2", "${upper(e.Dept)}")
+	f.SetCellValue(sheet, "C2", "${hyperlink(e.Url, e.Name)}")
+	f.SetCellValue(sheet, "A3", "${total}")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C3")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="employees" var="e" varIndex="i" select="e.Age > minAge" lastCell="C2")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:if(condition="showTotal" lastCell="A3")`})
+
+	path := filepath.Join(testdataDir(t), "inspect_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestInspect(t *testing.T) {
+	model, err := Inspect(createInspectTemplate(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"employees", "minAge", "showTotal", "title", "total"}, model.Variables)
+
+	require.Len(t, model.Areas, 1)
+	root := model.Areas[0]
+	assert.Equal(t, "Sheet1!A1:C3", root.Ref)
+	require.Len(t, root.Expressions, 1)
+	assert.Equal(t, "Sheet1!A1", root.Expressions[0].Cell)
+	assert.Equal(t, []string{"title"}, root.Expressions[0].Expressions)
+
+	require.Len(t, root.Commands, 2)
+	each := root.Commands[0]
+	assert.Equal(t, "each", each.Name)
+	assert.Equal(t, "Sheet1!A2:C2", each.Ref)
+	assert.Equal(t, "employees", each.Attrs["items"])
+	assert.Equal(t, "C2", each.Attrs["lastCell"])
+	assert.Equal(t, []string{"e", "i"}, each.Defines)
+
+	require.Len(t, each.Areas, 1)
+	exprs := each.Areas[0].Expressions
+	require.Len(t, exprs, 3)
+	assert.Equal(t, []string{"i", "e.Name"}, exprs[0].Expressions)
+	assert.Equal(t, []string{"e", "i"}, exprs[0].Variables)
+	assert.Equal(t, []string{"e"}, exprs[2].Variables, "function names are not variables")
+
+	ifCmd := root.Commands[1]
+	assert.Equal(t, "if", ifCmd.Name)
+	assert.Equal(t, "showTotal", ifCmd.Attrs["condition"])
+}
+
+func TestInspect_JSON(t *testing.T) {
+	model, err := Inspect(createInspectTemplate(t))
+	require.NoError(t, err)
+
+	b, err := json.Marshal(model)
+	require.NoError(t, err)
+
+	var decoded TemplateModel
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.Equal(t, model.Variables, decoded.Variables)
+	assert.Equal(t, "each", decoded.Areas[0].Commands[0].Name)
+}
+
+func TestInspect_BadTemplatePath(t *testing.T) {
+	_, err := Inspect("/nonexistent/template.xlsx")
+	assert.Error(t, err)
+}
+
+func TestExpressionVariables(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       []string
+	}{
+		{"e.Name", []string{"e"}},
+		{"e.Price * qty + e.Tax", []string{"e", "qty"}},
+		{"hyperlink(url, e.Name)", []string{"e", "url"}},
+		{"filter(items, .Age > min)", []string{"items", "min"}},
+		{"let x = a + 1; x * b", []string{"a", "b"}},
+		{"e.Qty | defaultQty | 0", []string{"defaultQty", "e"}},
+		{"'literal'", nil},
+		{"e.Name +", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ExpressionVariables(tt.expression), tt.expression)
+	}
+}
@@ -0,0 +1,115 @@
+package xlfill
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Iterator is a lazily produced collection that can be used as jx:each items.
+// Plain loops consume it item by item; options that need the whole collection
+// (select, groupBy, orderBy, varStatus, multisheet) read it fully first.
+//
+// An Iterator is read once: a fill fails when a second jx:each uses an
+// Iterator, such as one from RowsItems, that another jx:each has read. Pass
+// items that more than one jx:each shows as a slice.
+type Iterator interface {
+	// Next advances to the next item. It returns false when there are no more
+	// items or an error occurred.
+	Next() bool
+	// Value returns the current item.
+	Value() any
+	// Err returns the error that stopped the iteration, if any.
+	Err() error
+}
+
+// readIterator records that a jx:each reads it, failing when another jx:each
+// already did. Iterators of a type that cannot be compared are not tracked.
+func (c *Context) readIterator(it Iterator) error {
+	if !reflect.TypeOf(it).Comparable() {
+		return nil
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if c.state.readIterators[it] {
+		return fmt.Errorf("the Iterator was already read by another jx:each; an Iterator can be read once")
+	}
+	if c.state.readIterators == nil {
+		c.state.readIterators = make(map[Iterator]bool)
+	}
+	c.state.readIterators[it] = true
+	return nil
+}
+
+// SQLRows is the subset of *sql.Rows used by RowsItems. Result sets from other
+// drivers (e.g. pgx) can be adapted by implementing these four methods.
+type SQLRows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// RowsItems wraps a database result set as jx:each items. Each row is scanned
+// lazily into a map keyed by column name, so ${e.name} reads the "name" column.
+// []byte column values are converted to strings. The caller still owns rows
+// and must close it after filling.
+func RowsItems(rows SQLRows) Iterator {
+	return &rowsIterator{rows: rows}
+}
+
+// rowsIterator adapts SQLRows to Iterator.
+type rowsIterator struct {
+	rows    SQLRows
+	columns []string
+	current map[string]any
+	err     error
+}
+
+func (r *rowsIterator) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.columns == nil {
+		cols, err := r.rows.Columns()
+		if err != nil {
+			r.err = fmt.Errorf("read columns: %w", err)
+			return false
+		}
+		r.columns = cols
+	}
+	if !r.rows.Next() {
+		return false
+	}
+
+	values := make([]any, len(r.columns))
+	dest := make([]any, len(r.columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		r.err = fmt.Errorf("scan row: %w", err)
+		return false
+	}
+
+	row := make(map[string]any, len(r.columns))
+	for i, col := range r.columns {
+		if b, ok := values[i].([]byte); ok {
+			row[col] = string(b)
+		} else {
+			row[col] = values[i]
+		}
+	}
+	r.current = row
+	return true
+}
+
+func (r *rowsIterator) Value() any {
+	return r.current
+}
+
+func (r *rowsIterator) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.rows.Err()
+}
@@ -0,0 +1,159 @@
+package xlfill
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// fakeRows is an in-memory SQLRows used to test RowsItems without a database.
+type fakeRows struct {
+	columns []string
+	data    [][]any
+	pos     int
+	scanErr error
+	scanned int
+}
+
+func (r *fakeRows) Columns() ([]string, error) { return r.columns, nil }
+func (r *fakeRows) Err() error                 { return nil }
+
+func (r *fakeRows) Next() bool {
+	if r.pos >= len(r.data) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if r.scanErr != nil {
+		return r.scanErr
+	}
+	r.scanned++
+	for i, v := range r.data[r.pos-1] {
+		*dest[i].(*any) = v
+	}
+	return nil
+}
+
+func employeeRows() *fakeRows {
+	return &fakeRows{
+		columns: []string{"Name", "Age", "Salary"},
+		data: [][]any{
+			{[]byte("Alice"), int64(30), 5000.0},
+			{[]byte("Bob"), int64(25), 4000.0},
+			{"Carol", int64(35), nil},
+		},
+	}
+}
+
+func TestRowsItems(t *testing.T) {
+	it := RowsItems(employeeRows())
+
+	var rows []map[string]any
+	for it.Next() {
+		rows = append(rows, it.Value().(map[string]any))
+	}
+	require.NoError(t, it.Err())
+	require.Len(t, rows, 3)
+	assert.Equal(t, "Alice", rows[0]["Name"], "[]byte converted to string")
+	assert.Equal(t, int64(25), rows[1]["Age"])
+	assert.Nil(t, rows[2]["Salary"])
+}
+
+func TestRowsItems_ScanError(t *testing.T) {
+	rows := employeeRows()
+	rows.scanErr = errors.New("bad column type")
+	it := RowsItems(rows)
+
+	assert.False(t, it.Next())
+	require.Error(t, it.Err())
+	assert.Contains(t, it.Err().Error(), "bad column type")
+}
+
+func TestFill_RowsItems(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+	rows := employeeRows()
+
+	out, err := FillBytes(tmpl, map[string]any{"employees": RowsItems(rows)})
+	require.NoError(t, err)
+	assert.Equal(t, 3, rows.scanned)
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, want := range map[string]string{
+		"A2": "Alice", "B2": "30", "C2": "5000",
+		"A3": "Bob", "B3": "25",
+		"A4": "Carol", "C4": "",
+	} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestEachCommand_IteratorWithOrderBy(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${e.Name}")
+
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	ctx := NewContext(map[string]any{"employees": RowsItems(employeeRows())})
+	cmd := &EachCommand{
+		Items: "employees", Var: "e", OrderBy: "e.Age DESC",
+		Area: NewArea(NewCellRef(sheet, 0, 0), Size{Width: 1, Height: 1}, tx),
+	}
+
+	size, err := cmd.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 1, Height: 3}, size)
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+
+	for cell, want := range map[string]string{"A1": "Carol", "A2": "Alice", "A3": "Bob"} {
+		v, _ := out.GetCellValue(sheet, cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestFill_IteratorReadOnce(t *testing.T) {
+	// Two lists of the same items side by side, below a header
+	template := func(t *testing.T, header string) string {
+		f := excelize.NewFile()
+		defer f.Close()
+		f.SetCellValue("Sheet1", "A1", header)
+		f.SetCellValue("Sheet1", "A2", "${e.Name}")
+		f.SetCellValue("Sheet1", "B2", "${e.Age}")
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="A2")`})
+		f.AddComment("Sheet1", excelize.Comment{Cell: "B2", Author: "xlfill",
+			Text: `jx:area(lastCell="B2")` + "\n" + `jx:each(items="employees" var="e" lastCell="B2")`})
+		path := filepath.Join(t.TempDir(), "template.xlsx")
+		require.NoError(t, f.SaveAs(path))
+		return path
+	}
+
+	_, err := FillBytes(template(t, "Staff"), map[string]any{"employees": RowsItems(employeeRows())})
+	assert.ErrorContains(t, err, `items "employees": the Iterator was already read by another jx:each`)
+
+	// Items read ahead by the header are buffered, so both lists see them
+	out, err := FillBytes(template(t, "${first(employees).Name}"), map[string]any{"employees": RowsItems(employeeRows())})
+	require.NoError(t, err)
+	rows, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Alice"}, {"Alice", "30"}, {"Bob", "25"}, {"Carol", "35"}}, rows)
+}
@@ -0,0 +1,88 @@
+package xlfill
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FillJSON processes a template file with data decoded from a JSON document and
+// writes the populated output to outputPath. The top-level JSON value must be an
+// object; its keys become context variables, so items="invoice.lines" resolves
+// through the nested structure.
+func FillJSON(templatePath, outputPath string, jsonData []byte, opts ...Option) error {
+	allOpts := append([]Option{WithTemplate(templatePath)}, opts...)
+	allOpts = append(allOpts, WithJSONData(jsonData))
+	filler := NewFiller(allOpts...)
+	return filler.Fill(nil, outputPath)
+}
+
+// DecodeJSONData decodes a JSON object into a data map suitable for filling.
+// Numbers are decoded with json.Number and converted to int64 when they are
+// integers that fit, to float64 otherwise. Integers too large for int64 are kept
+// as their decimal string so no digits are lost.
+func DecodeJSONData(jsonData []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode JSON data: %w", err)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("decode JSON data: top-level value must be an object, got %T", v)
+	}
+	return convertJSONNumbers(m).(map[string]any), nil
+}
+
+// convertJSONNumbers recursively replaces json.Number values with Go numbers.
+func convertJSONNumbers(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = convertJSONNumbers(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = convertJSONNumbers(item)
+		}
+		return val
+	case json.Number:
+		return convertJSONNumber(val)
+	default:
+		return v
+	}
+}
+
+// convertJSONNumber converts a single json.Number to int64, float64 or, for
+// integers outside the int64 range, the original decimal string.
+func convertJSONNumber(n json.Number) any {
+	s := n.String()
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if !strings.ContainsAny(s, ".eE") {
+		return s
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// mergeJSONData decodes jsonData and merges it with data. Keys in data take
+// precedence over keys from the JSON document.
+func mergeJSONData(jsonData []byte, data map[string]any) (map[string]any, error) {
+	merged, err := DecodeJSONData(jsonData)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range data {
+		merged[k] = v
+	}
+	return merged, nil
+}
@@ -0,0 +1,97 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestDecodeJSONData_Numbers(t *testing.T) {
+	data, err := DecodeJSONData([]byte(`{
+		"id": 9007199254740993,
+		"huge": 123456789012345678901234567890,
+		"price": 12.5,
+		"exp": 1e3,
+		"lines": [{"qty": 2}]
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(9007199254740993), data["id"], "int64 keeps precision beyond float64")
+	assert.Equal(t, "123456789012345678901234567890", data["huge"])
+	assert.Equal(t, 12.5, data["price"])
+	assert.Equal(t, 1000.0, data["exp"])
+	lines := data["lines"].([]any)
+	assert.Equal(t, int64(2), lines[0].(map[string]any)["qty"])
+}
+
+func TestDecodeJSONData_Errors(t *testing.T) {
+	_, err := DecodeJSONData([]byte(`[1, 2]`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be an object")
+
+	_, err = DecodeJSONData([]byte(`{"a":`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "decode JSON data")
+}
+
+func TestFillJSON_NestedItems(t *testing.T) {
+	dir := testdataDir(t)
+	tmpl := filepath.Join(dir, "json_invoice_template.xlsx")
+	out := filepath.Join(dir, "json_invoice_output.xlsx")
+
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "${invoice.number}")
+	f.SetCellValue(sheet, "A2", "${l.sku}")
+	f.SetCellValue(sheet, "B2", "${l.qty * l.price}")
+	f.AddComment(sheet, excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")`})
+	f.AddComment(sheet, excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="invoice.lines" var="l" lastCell="B2")`})
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	doc := []byte(`{"invoice": {"number": 10042, "lines": [
+		{"sku": "A-1", "qty": 2, "price": 1.5},
+		{"sku": "B-2", "qty": 3, "price": 10}
+	]}}`)
+	require.NoError(t, FillJSON(tmpl, out, doc))
+
+	res, err := excelize.OpenFile(out)
+	require.NoError(t, err)
+	defer res.Close()
+
+	for cell, want := range map[string]string{
+		"A1": "10042",
+		"A2": "A-1", "B2": "3",
+		"A3": "B-2", "B3": "30",
+	} {
+		v, _ := res.GetCellValue(sheet, cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestFill_WithJSONData_DataTakesPrecedence(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+	doc := []byte(`{"employees": [{"Name": "FromJSON", "Age": 1, "Salary": 1}]}`)
+
+	out, err := FillBytes(tmpl, map[string]any{
+		"employees": []map[string]any{{"Name": "FromData", "Age": 2, "Salary": 2}},
+	}, WithJSONData(doc))
+	require.NoError(t, err)
+	res, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	v, _ := res.GetCellValue("Sheet1", "A2")
+	res.Close()
+	assert.Equal(t, "FromData", v)
+
+	out, err = FillBytes(tmpl, nil, WithJSONData(doc))
+	require.NoError(t, err)
+	res, err = excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	v, _ = res.GetCellValue("Sheet1", "A2")
+	res.Close()
+	assert.Equal(t, "FromJSON", v)
+}
@@ -0,0 +1,49 @@
+package xlfill
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LetBinding is a value a jx:each computes once per item, given in the let
+// attribute as name=expression: let="total=e.Qty*e.Price; vat=total*0.19".
+type LetBinding struct {
+	Name       string
+	Expression string
+}
+
+// letName matches the name of a let binding.
+var letName = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+
+// parseLets parses a let attribute: name=expression bindings separated by
+// semicolons outside quotes, parentheses and brackets.
+func parseLets(spec string) ([]LetBinding, error) {
+	var lets []LetBinding
+	for _, part := range splitTopLevel(spec, ';', false) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, expression, ok := strings.Cut(part, "=")
+		name, expression = strings.TrimSpace(name), strings.TrimSpace(expression)
+		if !ok || !letName.MatchString(name) || expression == "" || strings.HasPrefix(expression, "=") {
+			return nil, fmt.Errorf("invalid let binding %q (expected name=expression)", part)
+		}
+		lets = append(lets, LetBinding{Name: name, Expression: expression})
+	}
+	return lets, nil
+}
+
+// bindLets evaluates the let bindings in order in iterCtx, each seeing the
+// ones before it, and returns a context binding them all.
+func (c *EachCommand) bindLets(iterCtx *Context) (*Context, error) {
+	for _, let := range c.Let {
+		val, err := iterCtx.Evaluate(let.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("let %s=%s: %w", let.Name, let.Expression, err)
+		}
+		iterCtx = iterCtx.WithVar(let.Name, val)
+	}
+	return iterCtx, nil
+}
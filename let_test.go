@@ -0,0 +1,94 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseLets(t *testing.T) {
+	lets, err := parseLets(`total = e.Qty*e.Price; vat=total*0.19;; label=join(["a;b", e.Name], ";") `)
+	require.NoError(t, err)
+	assert.Equal(t, []LetBinding{
+		{Name: "total", Expression: "e.Qty*e.Price"},
+		{Name: "vat", Expression: "total*0.19"},
+		{Name: "label", Expression: `join(["a;b", e.Name], ";")`},
+	}, lets)
+
+	lets, err = parseLets("")
+	require.NoError(t, err)
+	assert.Empty(t, lets)
+
+	for _, spec := range []string{"total", "=e.Qty", "1x=2", "a==b", "total="} {
+		_, err := parseLets(spec)
+		assert.ErrorContains(t, err, "expected name=expression", spec)
+	}
+}
+
+func createLetTemplate(t *testing.T, let string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${e.Item}")
+	f.SetCellValue("Sheet1", "B1", "${total}")
+	f.SetCellValue("Sheet1", "C1", "${vat}")
+	f.SetCellValue("Sheet1", "D1", "${total + vat}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="D1")` + "\n" + `jx:each(items="lines" var="e" let="` + let + `" lastCell="D1")`})
+	path := filepath.Join(testdataDir(t), "let_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestFill_EachLet(t *testing.T) {
+	tmpl := createLetTemplate(t, "total=e.Qty*e.Price; vat=total*rate")
+	out, err := FillBytes(tmpl, map[string]any{
+		"rate": 0.5,
+		"lines": []map[string]any{
+			{"Item": "Pen", "Qty": 2, "Price": 3},
+			{"Item": "Ink", "Qty": 1, "Price": 10},
+		},
+	})
+	require.NoError(t, err)
+	rows, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Pen", "6", "3", "9"}, {"Ink", "10", "5", "15"}}, rows)
+}
+
+func TestParseLets_EscapedQuotes(t *testing.T) {
+	lets, err := parseLets(`label="a\";b"; n=len(label)`)
+	require.NoError(t, err)
+	assert.Equal(t, []LetBinding{{"label", `"a\";b"`}, {"n", "len(label)"}}, lets)
+}
+
+func TestFill_EachLetErrors(t *testing.T) {
+	_, err := FillBytes(createLetTemplate(t, "total e.Qty"), map[string]any{"lines": []any{}})
+	assert.ErrorContains(t, err, `invalid let binding "total e.Qty"`)
+
+	_, err = FillBytes(createLetTemplate(t, "total=e.Qty / missing()"), map[string]any{
+		"lines": []map[string]any{{"Qty": 1}},
+	})
+	assert.ErrorContains(t, err, "each iteration 0: let total=e.Qty / missing()")
+}
+
+func TestInspect_EachLet(t *testing.T) {
+	model, err := Inspect(createLetTemplate(t, "total=e.Qty*e.Price; vat=total*rate"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lines", "rate"}, model.Variables)
+	each := model.Areas[0].Commands[0]
+	assert.Equal(t, []string{"e", "total", "vat"}, each.Defines)
+
+	desc, err := Describe(createLetTemplate(t, "total=e.Qty*e.Price"))
+	require.NoError(t, err)
+	assert.Contains(t, desc, `let="total=e.Qty*e.Price"`)
+}
+
+func TestValidate_EachLet(t *testing.T) {
+	issues, err := Validate(createLetTemplate(t, "total=e.Qty *; vat=total"))
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Contains(t, issues[0].Message, `invalid let total expression "e.Qty *"`)
+}
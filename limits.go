@@ -0,0 +1,66 @@
+package xlfill
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOutputLimit is returned, wrapped, when a fill would produce more rows,
+// columns, sheets or cells than allowed with WithOutputLimits.
+var ErrOutputLimit = errors.New("output limit exceeded")
+
+// outputLimits caps the output of a fill. Zero fields are unlimited.
+type outputLimits struct {
+	maxRows   int
+	maxCols   int
+	maxSheets int
+	maxCells  int
+}
+
+// checkExtent fails when output at target of the given size reaches past the
+// row or column limit.
+func (c *Context) checkExtent(target CellRef, size Size) error {
+	limits := c.state.limits
+	if last := target.Row + size.Height; limits.maxRows > 0 && last > limits.maxRows {
+		return fmt.Errorf("%w: output reaches row %d of sheet %s, over %d rows", ErrOutputLimit, last, target.Sheet, limits.maxRows)
+	}
+	if last := target.Col + size.Width; limits.maxCols > 0 && last > limits.maxCols {
+		return fmt.Errorf("%w: output reaches column %s of sheet %s, over %d columns", ErrOutputLimit, ColToName(last-1), target.Sheet, limits.maxCols)
+	}
+	return nil
+}
+
+// countCell counts a cell written by the fill against the cell limit. It also
+// stops the fill once the WithCancel context is done.
+func (c *Context) countCell() error {
+	if c.state.cancel != nil {
+		if err := c.state.cancel.Err(); err != nil {
+			return fmt.Errorf("fill cancelled: %w", err)
+		}
+	}
+	if c.state.limits.maxCells <= 0 {
+		return nil
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	c.state.cells++
+	if c.state.cells > c.state.limits.maxCells {
+		return fmt.Errorf("%w: more than %d cells", ErrOutputLimit, c.state.limits.maxCells)
+	}
+	return nil
+}
+
+// reserveSheets counts n sheets about to be added to the workbook against the
+// sheet limit.
+func (c *Context) reserveSheets(n int) error {
+	if c.state.limits.maxSheets <= 0 {
+		return nil
+	}
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	if c.state.sheets+n > c.state.limits.maxSheets {
+		return fmt.Errorf("%w: %d sheets, over %d", ErrOutputLimit, c.state.sheets+n, c.state.limits.maxSheets)
+	}
+	c.state.sheets += n
+	return nil
+}
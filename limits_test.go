@@ -0,0 +1,89 @@
+package xlfill
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func limitEmployees(n int) map[string]any {
+	employees := make([]map[string]any, n)
+	for i := range employees {
+		employees[i] = map[string]any{"Name": fmt.Sprintf("E%d", i), "Age": 30, "Salary": 1000}
+	}
+	return map[string]any{"employees": employees}
+}
+
+func TestOutputLimits_Rows(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+
+	out, err := FillBytes(tmpl, limitEmployees(49), WithOutputLimits(50, 0, 0, 0))
+	require.NoError(t, err)
+	rows, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Len(t, rows, 50)
+
+	_, err = FillBytes(tmpl, limitEmployees(100000), WithOutputLimits(50, 0, 0, 0))
+	assert.ErrorIs(t, err, ErrOutputLimit)
+	assert.ErrorContains(t, err, "each iteration 49: output limit exceeded: output reaches row 51 of sheet Sheet1, over 50 rows")
+}
+
+func TestOutputLimits_Cols(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+
+	_, err := FillBytes(tmpl, limitEmployees(2), WithOutputLimits(0, 3, 0, 0))
+	require.NoError(t, err)
+	_, err = FillBytes(tmpl, limitEmployees(2), WithOutputLimits(0, 2, 0, 0))
+	assert.ErrorIs(t, err, ErrOutputLimit)
+	assert.ErrorContains(t, err, "reaches column C")
+}
+
+func TestOutputLimits_Cells(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+
+	// 3 header cells and 3 cells per employee
+	_, err := FillBytes(tmpl, limitEmployees(9), WithOutputLimits(0, 0, 0, 30))
+	require.NoError(t, err)
+	_, err = FillBytes(tmpl, limitEmployees(10), WithOutputLimits(0, 0, 0, 30))
+	assert.ErrorIs(t, err, ErrOutputLimit)
+	_, err = FillBytes(tmpl, limitEmployees(10), WithOutputLimits(0, 0, 0, 30), WithConcurrency(4))
+	assert.ErrorIs(t, err, ErrOutputLimit)
+}
+
+func TestOutputLimits_Sheets(t *testing.T) {
+	tmpl := createSheetLifecycleTemplate(t, "limits_sheets.xlsx", "")
+
+	// The template's three sheets and two copies
+	f := fillSheetLifecycle(t, tmpl, WithOutputLimits(0, 0, 5, 0))
+	assert.Equal(t, []string{"Static", "Lookup", "Sales", "Ops"}, f.GetSheetList())
+
+	_, err := FillBytes(tmpl, map[string]any{
+		"depts": []map[string]any{{"Name": "Sales"}, {"Name": "Ops"}},
+		"names": []string{"Sales", "Ops"},
+	}, WithOutputLimits(0, 0, 4, 0))
+	assert.ErrorIs(t, err, ErrOutputLimit)
+	assert.ErrorContains(t, err, "5 sheets, over 4")
+}
+
+func TestOutputLimits_UnlimitedByDefault(t *testing.T) {
+	_, err := FillBytes(createBasicTemplate(t), limitEmployees(2000))
+	require.NoError(t, err)
+}
+
+func TestFill_Cancel(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+
+	_, err := FillBytes(tmpl, limitEmployees(2), WithCancel(context.Background()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, opts := range [][]Option{{WithCancel(ctx)}, {WithCancel(ctx), WithConcurrency(4)}} {
+		_, err = FillBytes(tmpl, limitEmployees(2), opts...)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorContains(t, err, "fill cancelled")
+	}
+}
@@ -11,3 +11,48 @@ type AreaListener interface {
 	// AfterTransformCell is called after a cell has been transformed.
 	AfterTransformCell(src, target CellRef, ctx *Context, tx Transformer)
 }
+
+// AreaEvent describes an area being applied at a target position.
+type AreaEvent struct {
+	Area   *Area
+	Source AreaRef // template range of the area
+	Target CellRef // top-left output cell
+	Size   Size    // resulting output size (zero in BeforeApplyAtArea)
+}
+
+// CommandEvent describes a command being executed within its parent area.
+type CommandEvent struct {
+	Name    string // command name (e.g., "each", "if")
+	Command Command
+	Source  AreaRef // template range covered by the command
+	Target  CellRef // top-left output cell
+	Size    Size    // resulting output size (zero in BeforeCommand)
+}
+
+// AreaApplyListener is an optional extension of AreaListener. Listeners that
+// also implement it are notified before and after every area is applied,
+// including the inner areas of commands.
+type AreaApplyListener interface {
+	BeforeApplyAtArea(event AreaEvent, ctx *Context)
+	AfterApplyAtArea(event AreaEvent, ctx *Context)
+}
+
+// CommandListener is an optional extension of AreaListener. Listeners that
+// also implement it are notified before and after every command executes.
+type CommandListener interface {
+	BeforeCommand(event CommandEvent, ctx *Context)
+	AfterCommand(event CommandEvent, ctx *Context)
+}
+
+// BaseAreaListener implements AreaListener, AreaApplyListener and CommandListener
+// with no-op methods. Embed it to override only the callbacks you need.
+type BaseAreaListener struct{}
+
+func (BaseAreaListener) BeforeTransformCell(src, target CellRef, ctx *Context, tx Transformer) bool {
+	return true
+}
+func (BaseAreaListener) AfterTransformCell(src, target CellRef, ctx *Context, tx Transformer) {}
+func (BaseAreaListener) BeforeApplyAtArea(event AreaEvent, ctx *Context)                      {}
+func (BaseAreaListener) AfterApplyAtArea(event AreaEvent, ctx *Context)                       {}
+func (BaseAreaListener) BeforeCommand(event CommandEvent, ctx *Context)                       {}
+func (BaseAreaListener) AfterCommand(event CommandEvent, ctx *Context)                        {}
@@ -0,0 +1,90 @@
+package xlfill
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// eventListener records area and command events; cell callbacks come from BaseAreaListener.
+type eventListener struct {
+	BaseAreaListener
+	events   []string
+	commands []CommandEvent
+}
+
+func (l *eventListener) BeforeApplyAtArea(event AreaEvent, ctx *Context) {
+	l.events = append(l.events, "before area "+event.Source.String())
+}
+
+func (l *eventListener) AfterApplyAtArea(event AreaEvent, ctx *Context) {
+	l.events = append(l.events, "after area "+event.Source.String()+" "+event.Size.String())
+}
+
+func (l *eventListener) BeforeCommand(event CommandEvent, ctx *Context) {
+	l.events = append(l.events, "before "+event.Name)
+}
+
+func (l *eventListener) AfterCommand(event CommandEvent, ctx *Context) {
+	l.events = append(l.events, "after "+event.Name+" "+event.Size.String())
+	l.commands = append(l.commands, event)
+}
+
+func TestAreaListener_AreaAndCommandEvents(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Name")
+	f.SetCellValue(sheet, "A2", "${e.Name}")
+	f.SetCellValue(sheet, "B2", "${e.Age}")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B2")`,
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="people" var="e" lastCell="B2")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	listener := &eventListener{}
+	data := map[string]any{
+		"people": []map[string]any{
+			{"Name": "Alice", "Age": 30},
+			{"Name": "Bob", "Age": 25},
+		},
+	}
+
+	_, err := FillBytes(tmpPath, data, WithAreaListener(listener))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"before area Sheet1!A1:B2",
+		"before each",
+		"before area Sheet1!A2:B2",
+		"after area Sheet1!A2:B2 (2x1)",
+		"before area Sheet1!A2:B2",
+		"after area Sheet1!A2:B2 (2x1)",
+		"after each (2x2)",
+		"after area Sheet1!A1:B2 (2x3)",
+	}, listener.events)
+
+	require.Len(t, listener.commands, 1)
+	ev := listener.commands[0]
+	assert.Equal(t, "Sheet1!A2:B2", ev.Source.String())
+	assert.Equal(t, NewCellRef(sheet, 1, 0), ev.Target)
+	assert.IsType(t, &EachCommand{}, ev.Command)
+}
+
+func TestBaseAreaListener_DoesNotSkipCells(t *testing.T) {
+	var l AreaListener = BaseAreaListener{}
+	assert.True(t, l.BeforeTransformCell(CellRef{}, CellRef{}, nil, nil))
+	_, ok := l.(AreaApplyListener)
+	assert.True(t, ok)
+	_, ok = l.(CommandListener)
+	assert.True(t, ok)
+}
@@ -0,0 +1,105 @@
+package xlfill
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The select, orderBy and groupBy attributes of jx:each read the loop
+// variable, as in select="e.Salary >= 6000", orderBy="e.Name ASC" and
+// groupBy="e.Department". As a shorthand, a property of the items may be named
+// without the variable: select="Salary >= 6000", orderBy="Name",
+// groupBy="Department". After groupBy, orderBy sorts the groups, so it reads
+// GroupData fields: orderBy="g.Key"; so does select when it filters the groups
+// (oldSelectBehavior="true"). Both then name the groupVar when it is set.
+
+// loopField returns the property path of an item that path names for the loop
+// variable varName: "e.Address.City" → "Address.City". A path without the
+// variable, "Address.City", names the property directly and bare reports it;
+// the variable alone names the item itself: "e" → "".
+func loopField(path, varName string) (field string, bare bool) {
+	path = strings.TrimSpace(path)
+	if path == varName {
+		return "", false
+	}
+	if rest, ok := strings.CutPrefix(path, varName+"."); ok {
+		return rest, false
+	}
+	return path, true
+}
+
+// checkLoopPaths checks that the select, groupBy and orderBy attributes read
+// the loop variable or properties of items, and returns the properties select
+// names without the variable. In select, context variables take precedence over
+// properties of the items.
+func (c *EachCommand) checkLoopPaths(ctx *Context, items []any) (selectFields []string, err error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	selected, selectVar := items, c.Var
+	if c.selectsGroups(ctx) {
+		selected, selectVar = []any{GroupData{}}, c.groupName()
+	}
+	for _, name := range ExpressionVariables(c.Select) {
+		switch {
+		case name == selectVar || strings.HasPrefix(name, "$") || ctx.ContainsVar(name):
+		case anyHasField(selected, name):
+			selectFields = append(selectFields, name)
+		default:
+			return nil, fmt.Errorf("select %q: %s is not the loop variable %s, a property of the items or a context variable", c.Select, name, selectVar)
+		}
+	}
+	for _, name := range selectFields {
+		if err := ctx.sandbox().checkPath(name, selected); err != nil {
+			return nil, fmt.Errorf("select %q: %w", c.Select, err)
+		}
+	}
+	if c.GroupBy != "" {
+		if err := c.checkLoopPath(ctx, "groupBy", c.GroupBy, c.Var, items); err != nil {
+			return nil, err
+		}
+	}
+	sorted, sortVar := items, c.Var
+	if c.GroupBy != "" {
+		sorted, sortVar = []any{GroupData{}}, c.groupName()
+	}
+	for _, spec := range strings.Split(c.OrderBy, ",") {
+		if fields := strings.Fields(spec); len(fields) > 0 {
+			if err := c.checkLoopPath(ctx, "orderBy", fields[0], sortVar, sorted); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return selectFields, nil
+}
+
+// checkLoopPath checks that path, the value of attribute attr, reads the loop
+// variable varName or a property of items that the sandbox allows.
+func (c *EachCommand) checkLoopPath(ctx *Context, attr, path, varName string, items []any) error {
+	field, bare := loopField(path, varName)
+	if err := ctx.sandbox().checkPath(field, items); err != nil {
+		return fmt.Errorf("%s %q: %w", attr, path, err)
+	}
+	if !bare {
+		return nil
+	}
+	first, _, _ := strings.Cut(field, ".")
+	first = strings.TrimSuffix(strings.TrimSpace(first), "()")
+	switch {
+	case anyHasField(items, first):
+		return nil
+	case ctx.ContainsVar(first):
+		return fmt.Errorf("%s %q reads %s, not the loop variable %s", attr, path, first, varName)
+	}
+	return fmt.Errorf("%s %q: %s is not the loop variable %s or a property of the items", attr, path, first, varName)
+}
+
+// anyHasField reports whether any of items has a property name.
+func anyHasField(items []any, name string) bool {
+	for _, item := range items {
+		if hasField(item, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLoopField(t *testing.T) {
+	tests := []struct {
+		path, field string
+		bare        bool
+	}{
+		{"e.Name", "Name", false},
+		{"e.Address.City", "Address.City", false},
+		{" e ", "", false},
+		{"Name", "Name", true},
+		{"employee.Name", "employee.Name", true},
+		{"x.Name", "x.Name", true},
+	}
+	for _, tt := range tests {
+		field, bare := loopField(tt.path, "e")
+		assert.Equal(t, tt.field, field, tt.path)
+		assert.Equal(t, tt.bare, bare, tt.path)
+	}
+}
+
+func loopPathItems() []any {
+	return []any{
+		map[string]any{"Name": "Alice", "Dept": "Eng", "Salary": 7000},
+		map[string]any{"Name": "Bob", "Dept": "Ops", "Salary": 5000},
+		map[string]any{"Name": "Carol", "Dept": "Eng", "Salary": 6500},
+	}
+}
+
+func TestEachCommand_CheckLoopPaths(t *testing.T) {
+	ctx := NewContext(map[string]any{"minSalary": 6000, "d": map[string]any{"Name": "outer"}})
+
+	tests := []struct {
+		name    string
+		cmd     EachCommand
+		wantErr string
+	}{
+		{"prefixed", EachCommand{Var: "e", Select: "e.Salary >= minSalary", OrderBy: "e.Name DESC", GroupBy: "e.Dept"}, ""},
+		{"bare", EachCommand{Var: "e", Select: "Salary >= minSalary", OrderBy: "Name DESC, salary"}, ""},
+		{"bare groupBy", EachCommand{Var: "e", GroupBy: "Dept", OrderBy: "Item.Name"}, ""},
+		{"orderBy after groupBy", EachCommand{Var: "g", GroupBy: "g.Dept", OrderBy: "g.Item.Name, Items"}, ""},
+		{"scalar var", EachCommand{Var: "e", OrderBy: "e DESC"}, ""},
+		{"other variable", EachCommand{Var: "emp", OrderBy: "e.Name"},
+			`orderBy "e.Name": e is not the loop variable emp or a property of the items`},
+		{"outer loop variable", EachCommand{Var: "e", GroupBy: "d.Name"},
+			`groupBy "d.Name" reads d, not the loop variable e`},
+		{"group field after groupBy", EachCommand{Var: "g", GroupBy: "Dept", OrderBy: "Dept"},
+			`orderBy "Dept": Dept is not the loop variable g or a property of the items`},
+		{"unknown in select", EachCommand{Var: "emp", Select: "e.Salary > 1"},
+			`select "e.Salary > 1": e is not the loop variable emp, a property of the items or a context variable`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cmd.checkLoopPaths(ctx, loopPathItems())
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := (&EachCommand{Var: "e", OrderBy: "x.Name"}).checkLoopPaths(ctx, nil)
+	assert.NoError(t, err, "nothing to check without items")
+}
+
+func TestEachCommand_SelectBareFields(t *testing.T) {
+	// Context variables take precedence over item properties of the same name
+	ctx := NewContext(map[string]any{"dept": "Eng"})
+	cmd := &EachCommand{Var: "e", Select: "Salary > 6000 || e.Dept != dept"}
+	fields, err := cmd.checkLoopPaths(ctx, loopPathItems())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Salary"}, fields)
+
+	filtered, err := cmd.filterItems(loopPathItems(), ctx, fields)
+	require.NoError(t, err)
+	var names []any
+	for _, item := range filtered {
+		names = append(names, getField(item, "Name"))
+	}
+	assert.Equal(t, []any{"Alice", "Bob", "Carol"}, names)
+}
+
+func TestFill_EachBareAttributes(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(items="employees" var="e" select="Salary >= 6000" orderBy="Name DESC" lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "bare_attrs.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	out, err := FillBytes(tmpl, map[string]any{"employees": loopPathItems()})
+	require.NoError(t, err)
+	rows, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Carol"}, {"Alice"}}, rows)
+
+	f = excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${emp.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(items="employees" var="emp" orderBy="e.Name" lastCell="A1")`})
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+	_, err = FillBytes(tmpl, map[string]any{"employees": loopPathItems()})
+	assert.ErrorContains(t, err, `orderBy "e.Name": e is not the loop variable emp`)
+}
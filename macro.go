@@ -0,0 +1,131 @@
+package xlfill
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxMacroDepth limits jx:call nesting, so a macro calling itself fails
+// instead of recursing forever.
+const maxMacroDepth = 32
+
+// DefineCommand implements jx:define. Its area is a macro: a block rendered
+// by jx:call wherever it is invoked, and not where it is defined. Definitions
+// are usually kept on a sheet deleted after filling:
+//
+//	jx:area(lastCell="C10" templateSheet="delete")
+//	jx:define(name="addressBlock" lastCell="C3")
+type DefineCommand struct {
+	MacroName string // name jx:call invokes it by
+	Area      *Area
+}
+
+func (c *DefineCommand) Name() string { return "define" }
+func (c *DefineCommand) Reset()       {}
+
+// newDefineCommandFromAttrs creates a DefineCommand from parsed attributes.
+func newDefineCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &DefineCommand{MacroName: strings.TrimSpace(attrs["name"])}
+	if cmd.MacroName == "" {
+		return nil, fmt.Errorf("define command requires 'name' attribute")
+	}
+	return cmd, nil
+}
+
+// ApplyAt renders nothing: a definition is only rendered by jx:call.
+func (c *DefineCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	return ZeroSize, nil
+}
+
+// CallCommand implements jx:call. It renders the area of the jx:define of the
+// same name at its position, on any sheet, with variables of its own given
+// as name=expression bindings separated by semicolons:
+//
+//	jx:call(name="addressBlock" with="customer=order.Billing; title='Bill to'" lastCell="C3")
+//
+// The bindings are evaluated where the call is; the macro also sees the
+// variables around the call.
+type CallCommand struct {
+	MacroName string
+	With      []LetBinding
+	Area      *Area
+
+	define *DefineCommand // bound when the areas are built
+}
+
+func (c *CallCommand) Name() string { return "call" }
+func (c *CallCommand) Reset()       {}
+
+// newCallCommandFromAttrs creates a CallCommand from parsed attributes.
+func newCallCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &CallCommand{MacroName: strings.TrimSpace(attrs["name"])}
+	if cmd.MacroName == "" {
+		return nil, fmt.Errorf("call command requires 'name' attribute")
+	}
+	with, err := parseLets(attrs["with"])
+	if err != nil {
+		return nil, fmt.Errorf("call command: %w", err)
+	}
+	cmd.With = with
+	return cmd, nil
+}
+
+// ApplyAt renders the macro at cellRef with the call's variables and returns
+// the macro's size.
+func (c *CallCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	if c.define == nil || c.define.Area == nil {
+		return ZeroSize, fmt.Errorf("call command: no jx:define named %q", c.MacroName)
+	}
+	if ctx.macroDepth >= maxMacroDepth {
+		return ZeroSize, fmt.Errorf("call command: macro %q nested more than %d levels deep", c.MacroName, maxMacroDepth)
+	}
+	vars := make(map[string]any, len(c.With))
+	for _, w := range c.With {
+		val, err := ctx.Evaluate(w.Expression)
+		if err != nil {
+			return ZeroSize, fmt.Errorf("evaluate with %s=%s: %w", w.Name, w.Expression, err)
+		}
+		vars[w.Name] = val
+	}
+	callCtx := ctx.WithVars(vars)
+	callCtx.macroDepth++
+	return c.define.Area.ApplyAt(cellRef, callCtx)
+}
+
+// bindMacros binds each jx:call in areas to the jx:define of its name. Calls
+// without a definition and names defined twice are reported.
+func bindMacros(areas []*Area) []ValidationIssue {
+	defines := map[string]*DefineCommand{}
+	var calls []*CommandBinding
+	var issues []ValidationIssue
+	var walk func(area *Area)
+	walk = func(area *Area) {
+		for _, b := range area.Bindings {
+			switch c := b.Command.(type) {
+			case *DefineCommand:
+				if _, ok := defines[c.MacroName]; ok {
+					issues = append(issues, ValidationIssue{Severity: SeverityError, CellRef: b.StartRef,
+						Message: fmt.Sprintf("jx:define %q is defined more than once", c.MacroName)})
+				}
+				defines[c.MacroName] = c
+			case *CallCommand:
+				calls = append(calls, b)
+			}
+			for _, child := range childAreas(b.Command) {
+				walk(child)
+			}
+		}
+	}
+	for _, area := range areas {
+		walk(area)
+	}
+	for _, b := range calls {
+		call := b.Command.(*CallCommand)
+		call.define = defines[call.MacroName]
+		if call.define == nil {
+			issues = append(issues, ValidationIssue{Severity: SeverityError, CellRef: b.StartRef,
+				Message: fmt.Sprintf("jx:call names %q, which no jx:define defines", call.MacroName)})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,74 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestDefineAndCallCommands(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Order")
+	f.SetCellValue("Sheet1", "A4", "End")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B4")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:call(name="address" with="who=order.Billing; title='Bill to'" lastCell="B2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A3", Author: "xlfill",
+		Text: `jx:call(name="address" with="who=order.Shipping; title='Ship to'" lastCell="B3")`})
+	f.NewSheet("Macros")
+	f.SetSheetVisible("Macros", false)
+	f.SetCellValue("Macros", "A1", "${title}")
+	f.SetCellValue("Macros", "B1", "${who.Name}")
+	f.SetCellValue("Macros", "A2", "${who.City}")
+	f.SetCellFormula("Macros", "B2", "LEN(A2)")
+	f.AddComment("Macros", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3" templateSheet="delete")` + "\n" +
+		`jx:define(name="address" lastCell="B2")`})
+	tmpl := filepath.Join(testdataDir(t), "macro.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"order": map[string]any{
+		"Billing":  map[string]any{"Name": "Ann", "City": "Paris"},
+		"Shipping": map[string]any{"Name": "Bob", "City": "Rome"},
+	}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		b, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Sheet1"}, out.GetSheetList())
+		rows, err := out.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Order"}, {"Bill to", "Ann"}, {"Paris", ""}, {"Ship to", "Bob"}, {"Rome", ""}, {"End"}}, rows)
+		for cell, want := range map[string]string{"B3": "LEN(Sheet1!A3)", "B5": "LEN(Sheet1!A5)"} {
+			formula, _ := out.GetCellFormula("Sheet1", cell)
+			assert.Equal(t, want, formula, cell)
+		}
+		out.Close()
+	}
+
+	issues, err := Validate(tmpl)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+	desc, err := Describe(tmpl)
+	require.NoError(t, err)
+	assert.Contains(t, desc, `call (2x1) name="address" with="who=order.Billing; title='Bill to'"`)
+
+	// A call without a definition fails when the areas are built
+	f, err = excelize.OpenFile(tmpl)
+	require.NoError(t, err)
+	f.DeleteSheet("Macros")
+	missing := filepath.Join(testdataDir(t), "macro_missing.xlsx")
+	require.NoError(t, f.SaveAs(missing))
+	f.Close()
+	_, err = FillBytes(missing, data)
+	assert.ErrorContains(t, err, `jx:call names "address", which no jx:define defines`)
+
+	_, err = newCallCommandFromAttrs(map[string]string{"name": "address", "with": "who"})
+	assert.ErrorContains(t, err, "invalid let binding")
+}
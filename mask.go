@@ -0,0 +1,201 @@
+package xlfill
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+)
+
+// defaultRedaction replaces redacted values unless RedactionRules.Replacement
+// is set.
+const defaultRedaction = "***"
+
+// RedactionRules select the personal data WithRedaction hides in the output,
+// in addition to the fields the template marks with jx:mask.
+type RedactionRules struct {
+	// Columns are field names, such as "SSN", whose values are replaced in
+	// every cell expression that reads them, e.g. ${e.SSN}. Names match
+	// case-insensitively.
+	Columns []string
+	// Pattern replaces its matches in every text written, e.g. email
+	// addresses. A hyperlink whose target matches is written as its redacted
+	// text, without the link.
+	Pattern *regexp.Regexp
+	// Replacement is written in place of redacted values (default: "***").
+	Replacement string
+}
+
+// redaction is the compiled RedactionRules of a fill.
+type redaction struct {
+	columns     map[string]bool // lower-cased field names
+	replacement string
+	paths       map[string][]string // member paths of each expression seen, e.g. "e.SSN"
+}
+
+func newRedaction(rules RedactionRules) *redaction {
+	r := &redaction{columns: map[string]bool{}, replacement: rules.Replacement, paths: map[string][]string{}}
+	for _, col := range rules.Columns {
+		r.columns[strings.ToLower(col)] = true
+	}
+	if r.replacement == "" {
+		r.replacement = defaultRedaction
+	}
+	return r
+}
+
+// patternWriter is the WithCellWriter middleware replacing the matches of
+// pattern in the text values written.
+func patternWriter(pattern *regexp.Regexp, replacement string) func(next CellWriteFunc) CellWriteFunc {
+	return func(next CellWriteFunc) CellWriteFunc {
+		return func(w CellWrite) error {
+			switch v := w.Value.(type) {
+			case string:
+				w.Value = pattern.ReplaceAllString(v, replacement)
+			case TextValue:
+				w.Value = Text(pattern.ReplaceAllString(v.Text, replacement))
+			case HyperlinkValue:
+				// A link to a matching target, such as mailto:bob@example.com,
+				// is written as its redacted text
+				display := pattern.ReplaceAllString(v.String(), replacement)
+				if pattern.MatchString(v.URL) {
+					w.Value = display
+				} else {
+					v.Display = display
+					w.Value = v
+				}
+			}
+			return next(w)
+		}
+	}
+}
+
+// redacts reports whether the value of expression is hidden, because it reads
+// a field of RedactionRules.Columns or of an enclosing jx:mask.
+func (c *Context) redacts(expression string) bool {
+	r := c.state.redaction
+	c.state.mu.Lock()
+	paths, ok := r.paths[expression]
+	if !ok {
+		paths = memberPaths(expression)
+		r.paths[expression] = paths
+	}
+	c.state.mu.Unlock()
+
+	for _, path := range paths {
+		if c.masked[path] {
+			return true
+		}
+		if r.columns[strings.ToLower(path[strings.LastIndex(path, ".")+1:])] {
+			return true
+		}
+	}
+	return false
+}
+
+// redactProps replaces the values of props, such as the props of a jx:grid,
+// that read a redacted field.
+func (c *Context) redactProps(props []string, values []any) {
+	if c.state.redaction == nil {
+		return
+	}
+	for i, prop := range props {
+		if i < len(values) && values[i] != nil && c.redacts(prop) {
+			values[i] = c.state.redaction.replacement
+		}
+	}
+}
+
+// memberPaths returns the variable paths an expression reads, such as "e",
+// "e.SSN" and "e.Contact.Email" for "e.SSN + e.Contact.Email".
+func memberPaths(expression string) []string {
+	var v memberCollector
+	for _, alt := range fallbacks(expression) {
+		tree, err := parser.Parse(alt)
+		if err != nil {
+			return nil
+		}
+		ast.Walk(&tree.Node, &v)
+	}
+	return v.paths
+}
+
+type memberCollector struct {
+	paths []string
+}
+
+func (v *memberCollector) Visit(node *ast.Node) {
+	switch n := (*node).(type) {
+	case *ast.IdentifierNode, *ast.MemberNode:
+		if path, ok := memberPath(n); ok {
+			v.paths = append(v.paths, path)
+		}
+	}
+}
+
+// memberPath returns the dotted path of a member access on a variable.
+func memberPath(n ast.Node) (string, bool) {
+	switch n := n.(type) {
+	case *ast.IdentifierNode:
+		return n.Value, true
+	case *ast.MemberNode:
+		prop, ok := n.Property.(*ast.StringNode)
+		if !ok {
+			return "", false
+		}
+		parent, ok := memberPath(n.Node)
+		if !ok {
+			return "", false
+		}
+		return parent + "." + prop.Value, true
+	case *ast.ChainNode:
+		return memberPath(n.Node)
+	}
+	return "", false
+}
+
+// MaskCommand implements jx:mask. It marks fields, such as e.SSN, as personal
+// data: with WithRedaction their values are replaced in the cells of its
+// area, so one template produces both full and redacted reports. Without
+// WithRedaction the area is rendered as it is.
+type MaskCommand struct {
+	Fields []string // variable paths, e.g. "e.SSN"
+	Area   *Area
+}
+
+func (c *MaskCommand) Name() string { return "mask" }
+func (c *MaskCommand) Reset()       {}
+
+// newMaskCommandFromAttrs creates a MaskCommand from parsed attributes.
+func newMaskCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &MaskCommand{}
+	for field := range strings.SplitSeq(attrs["fields"], ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			cmd.Fields = append(cmd.Fields, field)
+		}
+	}
+	if len(cmd.Fields) == 0 {
+		return nil, fmt.Errorf("mask command requires 'fields' attribute")
+	}
+	return cmd, nil
+}
+
+// ApplyAt renders the area with the fields masked.
+func (c *MaskCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	if c.Area == nil {
+		return ZeroSize, nil
+	}
+	if ctx.state.redaction == nil {
+		return c.Area.ApplyAt(cellRef, ctx)
+	}
+	maskCtx := ctx.WithVars(nil)
+	maskCtx.masked = make(map[string]bool, len(ctx.masked)+len(c.Fields))
+	maps.Copy(maskCtx.masked, ctx.masked)
+	for _, field := range c.Fields {
+		maskCtx.masked[field] = true
+	}
+	return c.Area.ApplyAt(cellRef, maskCtx)
+}
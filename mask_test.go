@@ -0,0 +1,151 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func createMaskTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Name")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "SSN ${e.SSN}")
+	f.SetCellValue("Sheet1", "C2", "${e.Email}")
+	f.SetCellValue("Sheet1", "D2", "${e.Note}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"D2\")\njx:mask(fields=\"e.SSN, e.Email\" lastCell=\"D2\")"})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="employees" var="e" lastCell="D2")`})
+	tmpl := filepath.Join(testdataDir(t), "mask.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+	return tmpl
+}
+
+func TestMaskCommand(t *testing.T) {
+	tmpl := createMaskTemplate(t)
+	data := map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "SSN": "123-45-6789", "Email": "alice@example.com", "Note": "mail bob@example.com"},
+		{"Name": "Bob", "SSN": "987-65-4321", "Email": "bob@example.com"},
+	}}
+
+	// Full report
+	out, err := FillBytes(tmpl, data)
+	require.NoError(t, err)
+	got, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "SSN 123-45-6789", "alice@example.com", "mail bob@example.com"}, got[1])
+
+	// Redacted report
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, append(opts, WithRedaction(RedactionRules{}))...)
+		require.NoError(t, err)
+		got, err := openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Name"},
+			{"Alice", "SSN ***", "***", "mail bob@example.com"},
+			{"Bob", "SSN ***", "***"},
+		}, got)
+	}
+}
+
+func TestWithRedaction(t *testing.T) {
+	tmpl := createValueTemplate(t, "redaction.xlsx", "${r.Name}", "${r.ssn}", "${r.Note}", "${upper(r.Name)}")
+	data := map[string]any{"rows": []map[string]any{
+		{"Name": "Alice", "ssn": "123-45-6789", "Note": "mail alice@example.com or bob@example.com"},
+	}}
+	out, err := FillBytes(tmpl, data, WithRedaction(RedactionRules{
+		Columns:     []string{"SSN", "Name"},
+		Pattern:     regexp.MustCompile(`[\w.]+@[\w.]+`),
+		Replacement: "[redacted]",
+	}))
+	require.NoError(t, err)
+	got, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"[redacted]", "[redacted]", "mail [redacted] or [redacted]", "[redacted]"}, got[0])
+}
+
+func TestWithRedaction_HyperlinkTarget(t *testing.T) {
+	tmpl := createValueTemplate(t, "redaction_links.xlsx",
+		"${hyperlink('mailto:' + r.Email, r.Email)}", "${hyperlink('https://example.org/' + r.Name, 'mail ' + r.Email)}")
+	data := map[string]any{"rows": []map[string]any{{"Name": "bob", "Email": "bob@example.com"}}}
+	out, err := FillBytes(tmpl, data, WithRedaction(RedactionRules{Pattern: regexp.MustCompile(`[\w.]+@[\w.]+`)}))
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	got, err := res.GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"***", "mail ***"}, got[0])
+
+	ok, target, err := res.GetCellHyperLink("Sheet1", "A1")
+	require.NoError(t, err)
+	assert.False(t, ok, "link to %s", target)
+	ok, target, err = res.GetCellHyperLink("Sheet1", "B1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.org/bob", target)
+}
+
+func TestWithRedaction_CommandWrites(t *testing.T) {
+	// Values written by commands rather than cell expressions: a grid by
+	// props, a grid of plain rows and a table of contents link
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "grid")
+	f.SetCellValue("Sheet1", "A4", "rows")
+	f.SetCellValue("Sheet1", "A7", "toc")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: "jx:area(lastCell=\"C7\")\n" +
+		`jx:grid(headers="headers" data="people" props="Name,SSN,Email" lastCell="C1")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A4", Author: "xlfill",
+		Text: `jx:grid(headers="headers" data="rows" lastCell="C4")`})
+	f.NewSheet("bob@example.com")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A7", Author: "xlfill", Text: `jx:toc(lastCell="A7")`})
+	tmpl := filepath.Join(testdataDir(t), "redaction_commands.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{
+		"headers": []string{"Name", "SSN", "Email"},
+		"people":  []map[string]any{{"Name": "Alice", "SSN": "123-45-6789", "Email": "alice@example.com"}},
+		"rows":    [][]any{{"Bob", "987-65-4321", "bob@example.com"}},
+	}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, append(opts, WithRedaction(RedactionRules{
+			Columns: []string{"SSN"},
+			Pattern: regexp.MustCompile(`[\w.]+@[\w.]+|\d{3}-\d{2}-\d{4}`),
+		}))...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		got, err := res.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Name", "SSN", "Email"},
+			{"Alice", "***", "***"},
+			nil,
+			{"rows"},
+			{"Name", "SSN", "Email"},
+			{"Bob", "***", "***"},
+			{"toc"},
+			nil,
+			{"***"},
+		}, got)
+	}
+}
+
+func TestMaskCommand_Attributes(t *testing.T) {
+	cmd, err := newMaskCommandFromAttrs(map[string]string{"fields": " e.SSN ,e.Email,"})
+	require.NoError(t, err)
+	assert.Equal(t, &MaskCommand{Fields: []string{"e.SSN", "e.Email"}}, cmd)
+	assert.Equal(t, "mask", cmd.Name())
+
+	_, err = newMaskCommandFromAttrs(map[string]string{"fields": " , "})
+	assert.ErrorContains(t, err, "requires 'fields'")
+
+	assert.Equal(t, []string{"e", "e.SSN", "e", "e.Contact", "e.Contact.Email"},
+		memberPaths("upper(e.SSN) + e.Contact?.Email"))
+}
@@ -0,0 +1,171 @@
+package xlfill
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FilledResult is a filled workbook to combine with MergeOutputs.
+type FilledResult struct {
+	Name string // sheet name in the merged workbook (e.g. a subsidiary); prefixes sheet names when the workbook has several
+	Data []byte // xlsx content, e.g. from FillBytes
+}
+
+// MergeOutputs concatenates the sheets of several filled workbooks into one
+// workbook written to w, e.g. a monthly pack with one sheet per subsidiary.
+// A workbook with a single sheet contributes it under its Name; with several
+// sheets each is named "Name Sheet". Without a Name the original sheet names are
+// used. Names are made valid and unique by SafeSheetNameBuilder, which appends
+// " (2)", " (3)", ...; a name Excel reserves, such as "History", is an error.
+//
+// Cell values, formulas, styles, column widths, row heights and merged cells
+// are copied. Formulas referring to other sheets by name are not rewritten.
+func MergeOutputs(w io.Writer, outputs ...FilledResult) error {
+	if len(outputs) == 0 {
+		return fmt.Errorf("merge outputs: nothing to merge")
+	}
+	dst := excelize.NewFile()
+	defer dst.Close()
+	defaultSheet := dst.GetSheetList()[0]
+
+	var used []string
+	for i, out := range outputs {
+		src, err := excelize.OpenReader(bytes.NewReader(out.Data))
+		if err != nil {
+			return fmt.Errorf("merge output %d (%s): %w", i, out.Name, err)
+		}
+		err = mergeWorkbook(src, dst, out.Name, &used)
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("merge output %d (%s): %w", i, out.Name, err)
+		}
+	}
+
+	if !slices.ContainsFunc(used, func(s string) bool { return strings.EqualFold(s, defaultSheet) }) {
+		if err := dst.DeleteSheet(defaultSheet); err != nil {
+			return fmt.Errorf("delete sheet %q: %w", defaultSheet, err)
+		}
+	}
+	dst.SetActiveSheet(0)
+	return dst.Write(w)
+}
+
+// mergeWorkbook copies every sheet of src into dst under a unique name.
+func mergeWorkbook(src, dst *excelize.File, name string, used *[]string) error {
+	sheets := src.GetSheetList()
+	styles := map[int]int{}
+	for _, sheet := range sheets {
+		dstSheet := sheet
+		switch {
+		case name != "" && len(sheets) == 1:
+			dstSheet = name
+		case name != "":
+			dstSheet = name + " " + sheet
+		}
+		dstSheet, err := uniqueSheetName(dstSheet, used)
+		if err != nil {
+			return err
+		}
+		if _, err := dst.NewSheet(dstSheet); err != nil {
+			return fmt.Errorf("create sheet %q: %w", dstSheet, err)
+		}
+		if err := copySheetContent(src, dst, sheet, dstSheet, styles); err != nil {
+			return fmt.Errorf("copy sheet %q: %w", sheet, err)
+		}
+	}
+	return nil
+}
+
+// uniqueSheetName returns a name SafeSheetNameBuilder builds from name that
+// is not yet in used, and records it. A blank name becomes "Sheet".
+func uniqueSheetName(name string, used *[]string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		name = "Sheet"
+	}
+	unique, err := SafeSheetNameBuilder{}.BuildSheetName(name, *used)
+	if err != nil {
+		return "", err
+	}
+	*used = append(*used, unique)
+	return unique, nil
+}
+
+// copySheetContent copies cells, styles, column widths, row heights and merged
+// cells of a sheet in src to a sheet in dst. styles caches src → dst style IDs.
+func copySheetContent(src, dst *excelize.File, srcSheet, dstSheet string, styles map[int]int) error {
+	rows, err := src.GetRows(srcSheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+	maxCols := 0
+	for r, row := range rows {
+		maxCols = max(maxCols, len(row))
+		if h, err := src.GetRowHeight(srcSheet, r+1); err == nil {
+			dst.SetRowHeight(dstSheet, r+1, h)
+		}
+		for c, raw := range row {
+			cell := ColToName(c) + strconv.Itoa(r+1)
+			if err := copyCell(src, dst, srcSheet, dstSheet, cell, raw, styles); err != nil {
+				return fmt.Errorf("cell %s: %w", cell, err)
+			}
+		}
+	}
+	for c := 0; c < maxCols; c++ {
+		col := ColToName(c)
+		if w, err := src.GetColWidth(srcSheet, col); err == nil {
+			dst.SetColWidth(dstSheet, col, col, w)
+		}
+	}
+
+	merged, err := src.GetMergeCells(srcSheet)
+	if err != nil {
+		return err
+	}
+	for _, m := range merged {
+		if err := dst.MergeCell(dstSheet, m.GetStartAxis(), m.GetEndAxis()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyCell copies one cell's value or formula and style, keeping numbers and
+// booleans typed.
+func copyCell(src, dst *excelize.File, srcSheet, dstSheet, cell, raw string, styles map[int]int) error {
+	if styleID, err := src.GetCellStyle(srcSheet, cell); err == nil && styleID != 0 {
+		id, ok := styles[styleID]
+		if !ok {
+			if id, err = copyStyle(src, dst, styleID); err != nil {
+				return err
+			}
+			styles[styleID] = id
+		}
+		dst.SetCellStyle(dstSheet, cell, cell, id)
+	}
+
+	if formula, err := src.GetCellFormula(srcSheet, cell); err == nil && formula != "" {
+		return dst.SetCellFormula(dstSheet, cell, formula)
+	}
+	if raw == "" {
+		return nil
+	}
+	cellType, err := src.GetCellType(srcSheet, cell)
+	if err != nil {
+		return err
+	}
+	switch cellType {
+	case excelize.CellTypeBool:
+		return dst.SetCellBool(dstSheet, cell, raw == "1")
+	case excelize.CellTypeNumber, excelize.CellTypeUnset:
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return dst.SetCellFloat(dstSheet, cell, f, -1, 64)
+		}
+	}
+	return dst.SetCellStr(dstSheet, cell, raw)
+}
@@ -0,0 +1,100 @@
+package xlfill
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestMergeOutputs(t *testing.T) {
+	tmpl := createBasicTemplate(t)
+	fill := func(name string, salary int) FilledResult {
+		out, err := FillBytes(tmpl, map[string]any{"employees": []map[string]any{
+			{"Name": name, "Age": 30, "Salary": salary},
+		}})
+		require.NoError(t, err)
+		return FilledResult{Name: name, Data: out}
+	}
+
+	twoSheets := excelize.NewFile()
+	twoSheets.SetCellValue("Sheet1", "A1", "first")
+	_, err := twoSheets.NewSheet("Notes")
+	require.NoError(t, err)
+	twoSheets.SetCellValue("Notes", "A1", "second")
+	twoSheets.SetCellFormula("Notes", "B1", "1+1")
+	twoSheets.MergeCell("Notes", "C1", "D2")
+	var twoBuf bytes.Buffer
+	require.NoError(t, twoSheets.Write(&twoBuf))
+	twoSheets.Close()
+
+	var buf bytes.Buffer
+	err = MergeOutputs(&buf,
+		fill("ACME", 5000),
+		fill("Globex", 4000),
+		fill("acme", 3000),
+		FilledResult{Name: "Misc", Data: twoBuf.Bytes()},
+	)
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"ACME", "Globex", "acme (2)", "Misc Sheet1", "Misc Notes"}, f.GetSheetList())
+
+	rows, err := f.GetRows("Globex")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Name", "Age", "Salary"}, {"Globex", "30", "4000"}}, rows)
+
+	cellType, err := f.GetCellType("ACME", "C2")
+	require.NoError(t, err)
+	assert.NotEqual(t, excelize.CellTypeSharedString, cellType, "numbers stay numeric")
+
+	styleID, err := f.GetCellStyle("acme (2)", "A1")
+	require.NoError(t, err)
+	style, err := f.GetStyle(styleID)
+	require.NoError(t, err)
+	require.NotNil(t, style.Font)
+	assert.True(t, style.Font.Bold)
+
+	formula, err := f.GetCellFormula("Misc Notes", "B1")
+	require.NoError(t, err)
+	assert.Equal(t, "1+1", formula)
+	merged, err := f.GetMergeCells("Misc Notes")
+	require.NoError(t, err)
+	require.Len(t, merged, 1)
+	assert.Equal(t, "C1", merged[0].GetStartAxis())
+}
+
+func TestMergeOutputs_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, MergeOutputs(&buf))
+
+	err := MergeOutputs(&buf, FilledResult{Name: "bad", Data: []byte("not a workbook")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "merge output 0 (bad)")
+}
+
+func TestUniqueSheetName(t *testing.T) {
+	var used []string
+	long := strings.Repeat("x", 40)
+	for _, tt := range []struct{ name, want string }{
+		{"Sales", "Sales"},
+		{"SALES", "SALES (2)"},
+		{"Sales", "Sales (3)"},
+		{"a/b", "a_b"},
+		{long, strings.Repeat("x", 31)},
+		{long, strings.Repeat("x", 27) + " (2)"},
+		{"", "Sheet"},
+	} {
+		got, err := uniqueSheetName(tt.name, &used)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := uniqueSheetName("history", &used)
+	assert.ErrorIs(t, err, ErrInvalidSheetName)
+}
@@ -0,0 +1,79 @@
+package xlfill
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+var (
+	valuerType = reflect.TypeFor[driver.Valuer]()
+	timeType   = reflect.TypeFor[time.Time]()
+)
+
+// nullValue unwraps the nullable values database scans produce. Nil pointers
+// and invalid nullable values, such as sql.NullString, sql.Null[T],
+// null.String or pgtype.Text with Valid false, become nil; valid ones become
+// the value they hold. Pointers to numbers, strings, times and nullable values
+// are dereferenced; pointers to other structs are kept.
+func nullValue(v any) any {
+	switch v.(type) {
+	case nil, string, int, int64, float64, bool:
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		if elem := rv.Type().Elem(); elem.Kind() == reflect.Struct && elem != timeType && !isNullable(elem) {
+			return rv.Interface()
+		}
+		rv = rv.Elem()
+	}
+	for isNullable(rv.Type()) {
+		valid, value := nullableFields(rv.Type())
+		if !rv.FieldByIndex(valid).Bool() {
+			return nil
+		}
+		rv = rv.FieldByIndex(value)
+	}
+	return rv.Interface()
+}
+
+// isNullable reports whether t is a struct of a value and a Valid bool, or
+// embeds one, implementing driver.Valuer.
+func isNullable(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || !t.Implements(valuerType) {
+		return false
+	}
+	valid, value := nullableFields(t)
+	return valid != nil && value != nil
+}
+
+// nullableFields returns the indexes of the Valid and value fields of a
+// nullable struct type.
+func nullableFields(t reflect.Type) (valid, value []int) {
+	if t.NumField() == 1 && t.Field(0).Anonymous && t.Field(0).Type.Kind() == reflect.Struct {
+		valid, value = nullableFields(t.Field(0).Type)
+		if valid == nil || value == nil {
+			return nil, nil
+		}
+		return append([]int{0}, valid...), append([]int{0}, value...)
+	}
+	if t.NumField() != 2 {
+		return nil, nil
+	}
+	for i := range 2 {
+		f := t.Field(i)
+		switch {
+		case !f.IsExported():
+			return nil, nil
+		case f.Name == "Valid" && f.Type.Kind() == reflect.Bool:
+			valid = f.Index
+		default:
+			value = f.Index
+		}
+	}
+	return valid, value
+}
@@ -0,0 +1,98 @@
+package xlfill
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// wrappedString embeds sql.NullString like null.String of guregu/null does.
+type wrappedString struct {
+	sql.NullString
+}
+
+func TestNullValue(t *testing.T) {
+	name, age := "Alice", 30
+	var noName *string
+	when := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	person := &struct{ Name string }{"Bob"}
+
+	for _, tc := range []struct {
+		in, want any
+	}{
+		{"x", "x"},
+		{nil, nil},
+		{&name, "Alice"},
+		{&age, 30},
+		{noName, nil},
+		{&when, when},
+		{sql.NullString{String: "abc", Valid: true}, "abc"},
+		{sql.NullString{String: "abc"}, nil},
+		{sql.NullFloat64{Float64: 1.5, Valid: true}, 1.5},
+		{sql.NullInt32{Int32: 7, Valid: true}, int32(7)},
+		{sql.NullTime{Time: when, Valid: true}, when},
+		{sql.NullBool{}, nil},
+		{sql.Null[int]{V: 3, Valid: true}, 3},
+		{&sql.NullString{String: "ptr", Valid: true}, "ptr"},
+		{(*sql.NullString)(nil), nil},
+		{wrappedString{sql.NullString{String: "wrapped", Valid: true}}, "wrapped"},
+		{wrappedString{}, nil},
+		{struct{ Name string }{"kept"}, struct{ Name string }{"kept"}},
+	} {
+		assert.Equal(t, tc.want, nullValue(tc.in), "%#v", tc.in)
+	}
+	assert.Same(t, person, nullValue(person), "pointers to other structs are kept")
+}
+
+// dbEmployee is a row as scanned from a database.
+type dbEmployee struct {
+	Name   sql.NullString
+	Dept   *string
+	Salary sql.NullFloat64
+	Bonus  *float64
+	Hired  sql.NullTime
+}
+
+func TestFill_NullableValues(t *testing.T) {
+	sales, ops := "Sales", "Ops"
+	bonus := 250.0
+	hired := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+	rows := []any{
+		dbEmployee{Name: sql.NullString{String: "Alice", Valid: true}, Dept: &sales, Salary: sql.NullFloat64{Float64: 5000, Valid: true}, Bonus: &bonus, Hired: sql.NullTime{Time: hired, Valid: true}},
+		dbEmployee{Name: sql.NullString{String: "Bob", Valid: true}, Dept: &ops},
+		dbEmployee{Name: sql.NullString{String: "Carol", Valid: true}, Dept: &sales, Salary: sql.NullFloat64{Float64: 4000, Valid: true}},
+	}
+	tmpl := createValueTemplate(t, "nullable.xlsx", "${r.Name}", "${r.Dept}", "${r.Salary}", "${r.Bonus}", "${r.Hired}", "${r.Name}: ${r.Salary}")
+	out, err := FillBytes(tmpl, map[string]any{"rows": rows})
+	require.NoError(t, err)
+	f := openOutput(t, out)
+	got, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, []string{"Alice", "Sales", "5000", "250"}, got[0][:4])
+	assert.Equal(t, "Alice: 5000", got[0][5])
+	assert.Equal(t, []string{"Bob", "Ops", "", "", "", "Bob: "}, got[1])
+	assert.Equal(t, "Carol", got[2][0])
+	hiredCell, err := f.GetCellValue("Sheet1", "E1", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	assert.Equal(t, "45418", hiredCell, "a valid sql.NullTime is written as a date")
+
+	// Aggregates, orderBy and groupBy read the values inside
+	ctx := NewContext(map[string]any{"rows": rows})
+	total, err := ctx.Evaluate(`sum(rows, "Salary")`)
+	require.NoError(t, err)
+	assert.Equal(t, 9000.0, total)
+	n, err := ctx.Evaluate(`count(rows, "Salary")`)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	sorted := append([]any(nil), rows...)
+	sortByFields(sorted, parseOrderBy("Salary DESC", ""))
+	assert.Equal(t, "Alice", sorted[0].(dbEmployee).Name.String)
+	assert.Equal(t, "Bob", sorted[2].(dbEmployee).Name.String)
+	assert.Equal(t, "Sales", getField(rows[2], "Dept"))
+}
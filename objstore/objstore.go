@@ -0,0 +1,241 @@
+// Package objstore reads templates from and writes outputs to object storage,
+// such as S3 or Google Cloud Storage, addressed by URL.
+//
+// The package does not depend on any cloud SDK. Register a Bucket adapter for
+// each URL scheme once at startup; a URL then works as an xlfill.TemplateSource
+// and as an xlfill.OutputSink:
+//
+//	objstore.Register("s3", func(ctx context.Context, bucket string) (objstore.Bucket, error) {
+//		return s3Bucket{client: client, bucket: bucket}, nil
+//	})
+//
+//	filler := xlfill.NewFiller(xlfill.WithTemplateSource(objstore.URL("s3://templates/sales.xlsx")))
+//	err := filler.FillTo(ctx, objstore.URL("s3://reports/2026/sales.xlsx"), data)
+//
+// An S3 adapter with the AWS SDK for Go v2 streams uploads through a pipe:
+//
+//	type s3Bucket struct {
+//		client *s3.Client
+//		bucket string
+//	}
+//
+//	func (b s3Bucket) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+//		out, err := b.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &b.bucket, Key: &key})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Body, nil
+//	}
+//
+//	func (b s3Bucket) NewWriter(ctx context.Context, key string) (io.WriteCloser, error) {
+//		return objstore.PipeWriter(func(r io.Reader) error {
+//			_, err := manager.NewUploader(b.client).Upload(ctx, &s3.PutObjectInput{Bucket: &b.bucket, Key: &key, Body: r})
+//			return err
+//		}), nil
+//	}
+//
+// For Google Cloud Storage, the client's readers and writers fit directly:
+//
+//	func (b gcsBucket) NewReader(ctx context.Context, key string) (io.ReadCloser, error) {
+//		return b.client.Bucket(b.bucket).Object(key).NewReader(ctx)
+//	}
+//
+//	func (b gcsBucket) NewWriter(ctx context.Context, key string) (io.WriteCloser, error) {
+//		return b.client.Bucket(b.bucket).Object(key).NewWriter(ctx), nil
+//	}
+//
+// A GCS writer is abandoned by cancelling ctx; xlfill's FillTo creates the
+// output only after the template has been filled, so failures before writing
+// leave no object behind.
+//
+// The "file" scheme is registered by default: file:///var/reports/sales.xlsx.
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Bucket is the object storage API the adapters need.
+type Bucket interface {
+	// NewReader returns the content of the object key. The caller closes it.
+	NewReader(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// NewWriter returns a writer creating the object key. The object is
+	// complete once Close returns nil; when the writer also has a
+	// CloseWithError(error) error method, a failed write is reported through it.
+	NewWriter(ctx context.Context, key string) (io.WriteCloser, error)
+}
+
+// Opener returns the bucket named by the host of a URL.
+type Opener func(ctx context.Context, bucket string) (Bucket, error)
+
+var (
+	mu      sync.RWMutex
+	openers = map[string]Opener{
+		"file": func(context.Context, string) (Bucket, error) { return Dir(""), nil },
+	}
+)
+
+// Register sets the opener for URLs with the given scheme, replacing any
+// earlier one.
+func Register(scheme string, open Opener) {
+	mu.Lock()
+	defer mu.Unlock()
+	openers[strings.ToLower(scheme)] = open
+}
+
+// URL addresses an object as "scheme://bucket/key", e.g.
+// "s3://reports/2026/sales.xlsx". It is an xlfill.TemplateSource and an
+// xlfill.OutputSink.
+type URL string
+
+// OpenTemplate implements xlfill.TemplateSource.
+func (u URL) OpenTemplate(ctx context.Context) (io.ReadCloser, error) {
+	b, key, err := u.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := b.NewReader(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	return r, nil
+}
+
+// OpenInclude implements xlfill.IncludeSource. name is resolved relative to
+// u, so "header.xlsx" next to "s3://templates/sales.xlsx" is
+// "s3://templates/header.xlsx".
+func (u URL) OpenInclude(ctx context.Context, name string) (io.ReadCloser, error) {
+	base, err := url.Parse(string(u))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object URL %q: %w", string(u), err)
+	}
+	ref, err := url.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include %q: %w", name, err)
+	}
+	return URL(base.ResolveReference(ref).String()).OpenTemplate(ctx)
+}
+
+// CreateOutput implements xlfill.OutputSink.
+func (u URL) CreateOutput(ctx context.Context) (io.WriteCloser, error) {
+	b, key, err := u.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w, err := b.NewWriter(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("write %s: %w", u, err)
+	}
+	return w, nil
+}
+
+// open returns the bucket and key u addresses.
+func (u URL) open(ctx context.Context) (Bucket, string, error) {
+	parsed, err := url.Parse(string(u))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid object URL %q: %w", string(u), err)
+	}
+	mu.RLock()
+	open, ok := openers[strings.ToLower(parsed.Scheme)]
+	mu.RUnlock()
+	if !ok {
+		return nil, "", fmt.Errorf("object URL %q: no bucket registered for scheme %q", string(u), parsed.Scheme)
+	}
+	key := strings.TrimPrefix(parsed.Path, "/")
+	if parsed.Scheme == "file" {
+		key = parsed.Path
+	}
+	if key == "" {
+		return nil, "", fmt.Errorf("object URL %q has no key", string(u))
+	}
+	b, err := open(ctx, parsed.Host)
+	if err != nil {
+		return nil, "", fmt.Errorf("open bucket %q: %w", parsed.Host, err)
+	}
+	return b, key, nil
+}
+
+// Dir is a Bucket of files below a local directory, for development and
+// tests. Objects are written to a temporary file that replaces the object on
+// Close, so readers never see a partial output.
+type Dir string
+
+// NewReader implements Bucket.
+func (d Dir) NewReader(_ context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(d.path(key))
+}
+
+// NewWriter implements Bucket.
+func (d Dir) NewWriter(_ context.Context, key string) (io.WriteCloser, error) {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &fileWriter{File: f, path: path}, nil
+}
+
+func (d Dir) path(key string) string {
+	return filepath.Join(string(d), filepath.FromSlash(key))
+}
+
+// fileWriter moves its temporary file to path on Close.
+type fileWriter struct {
+	*os.File
+	path string
+}
+
+func (w *fileWriter) Close() error {
+	if err := w.File.Close(); err != nil {
+		os.Remove(w.Name())
+		return err
+	}
+	return os.Rename(w.Name(), w.path)
+}
+
+// CloseWithError discards the temporary file.
+func (w *fileWriter) CloseWithError(error) error {
+	w.File.Close()
+	return os.Remove(w.Name())
+}
+
+// PipeWriter returns a writer whose output upload reads on another goroutine,
+// for storage APIs that take an io.Reader. Close waits for upload to finish and
+// returns its error; CloseWithError makes upload's reads fail with the error.
+func PipeWriter(upload func(io.Reader) error) io.WriteCloser {
+	r, w := io.Pipe()
+	pw := &pipeWriter{PipeWriter: w, done: make(chan error, 1)}
+	go func() {
+		err := upload(r)
+		r.CloseWithError(err)
+		pw.done <- err
+	}()
+	return pw
+}
+
+type pipeWriter struct {
+	*io.PipeWriter
+	done chan error
+}
+
+func (w *pipeWriter) Close() error {
+	w.PipeWriter.Close()
+	return <-w.done
+}
+
+func (w *pipeWriter) CloseWithError(err error) error {
+	w.PipeWriter.CloseWithError(err)
+	<-w.done
+	return nil
+}
@@ -0,0 +1,168 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/javajack/xlfill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// memBucket keeps objects in memory and uploads them through PipeWriter, like
+// an SDK uploader taking an io.Reader.
+type memBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *memBucket) NewReader(_ context.Context, key string) (io.ReadCloser, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *memBucket) NewWriter(_ context.Context, key string) (io.WriteCloser, error) {
+	return PipeWriter(func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.objects[key] = data
+		return nil
+	}), nil
+}
+
+// templateBytes returns a one-row each template reading ${e.Name}.
+func templateBytes(t *testing.T) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(items="employees" var="e" lastCell="A1")`})
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+var employees = map[string]any{"employees": []map[string]any{{"Name": "Alice"}, {"Name": "Bob"}}}
+
+func cellValue(t *testing.T, data []byte, cell string) string {
+	t.Helper()
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer f.Close()
+	v, err := f.GetCellValue("Sheet1", cell)
+	require.NoError(t, err)
+	return v
+}
+
+func TestURL_FillTo(t *testing.T) {
+	templates := &memBucket{objects: map[string][]byte{"sales/report.xlsx": templateBytes(t)}}
+	reports := &memBucket{objects: map[string][]byte{}}
+	Register("mem", func(_ context.Context, bucket string) (Bucket, error) {
+		switch bucket {
+		case "templates":
+			return templates, nil
+		case "reports":
+			return reports, nil
+		}
+		return nil, errors.New("no such bucket")
+	})
+
+	filler := xlfill.NewFiller(xlfill.WithTemplateSource(URL("mem://templates/sales/report.xlsx")))
+	require.NoError(t, filler.FillTo(context.Background(), URL("mem://reports/2026/sales.xlsx"), employees))
+	out := reports.objects["2026/sales.xlsx"]
+	require.NotEmpty(t, out)
+	assert.Equal(t, "Bob", cellValue(t, out, "A2"))
+
+	err := filler.FillTo(context.Background(), URL("mem://archive/sales.xlsx"), employees)
+	assert.ErrorContains(t, err, `open bucket "archive": no such bucket`)
+	_, err = xlfill.NewFiller(xlfill.WithTemplateSource(URL("mem://templates/missing.xlsx"))).FillBytes(employees)
+	assert.ErrorContains(t, err, "read mem://templates/missing.xlsx: no such key")
+	_, err = xlfill.NewFiller(xlfill.WithTemplateSource(URL("gs://templates/report.xlsx"))).FillBytes(employees)
+	assert.ErrorContains(t, err, `no bucket registered for scheme "gs"`)
+	_, err = URL("mem://templates").OpenTemplate(context.Background())
+	assert.ErrorContains(t, err, "has no key")
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.xlsx"), templateBytes(t), 0o644))
+
+	// The file scheme reads and writes local paths
+	out := filepath.Join(dir, "out", "sales.xlsx")
+	filler := xlfill.NewFiller(xlfill.WithTemplateSource(URL("file://" + filepath.ToSlash(filepath.Join(dir, "report.xlsx")))))
+	require.NoError(t, filler.FillTo(context.Background(), URL("file://"+filepath.ToSlash(out)), employees))
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", cellValue(t, data, "A1"))
+
+	// A failed write leaves neither the object nor a temporary file
+	w, err := Dir(dir).NewWriter(context.Background(), "failed/sales.xlsx")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("partial"))
+	require.NoError(t, err)
+	require.NoError(t, w.(interface{ CloseWithError(error) error }).CloseWithError(errors.New("boom")))
+	entries, err := os.ReadDir(filepath.Join(dir, "failed"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPipeWriter(t *testing.T) {
+	var got []byte
+	w := PipeWriter(func(r io.Reader) error {
+		var err error
+		got, err = io.ReadAll(r)
+		return err
+	})
+	_, err := w.Write([]byte("workbook"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "workbook", string(got))
+
+	// A failed upload fails the writes and Close
+	w = PipeWriter(func(io.Reader) error { return errors.New("access denied") })
+	_, err = w.Write([]byte("workbook"))
+	assert.ErrorContains(t, err, "access denied")
+	assert.ErrorContains(t, w.Close(), "access denied")
+
+	// CloseWithError makes the upload's reads fail
+	var uploadErr error
+	w = PipeWriter(func(r io.Reader) error {
+		_, uploadErr = io.ReadAll(r)
+		return uploadErr
+	})
+	require.NoError(t, w.(interface{ CloseWithError(error) error }).CloseWithError(errors.New("fill failed")))
+	assert.ErrorContains(t, uploadErr, "fill failed")
+}
+
+func TestURL_OpenInclude(t *testing.T) {
+	bucket := &memBucket{objects: map[string][]byte{"common/header.xlsx": []byte("header")}}
+	Register("mem", func(context.Context, string) (Bucket, error) { return bucket, nil })
+
+	for _, name := range []string{"../common/header.xlsx", "/common/header.xlsx", "mem://templates/common/header.xlsx"} {
+		r, err := URL("mem://templates/sales/report.xlsx").OpenInclude(context.Background(), name)
+		require.NoError(t, err, name)
+		data, err := io.ReadAll(r)
+		r.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "header", string(data), name)
+	}
+	_, err := URL("mem://templates/sales/report.xlsx").OpenInclude(context.Background(), "header.xlsx")
+	assert.ErrorContains(t, err, "read mem://templates/sales/header.xlsx: no such key")
+}
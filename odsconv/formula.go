@@ -0,0 +1,139 @@
+package odsconv
+
+import (
+	"regexp"
+	"strings"
+)
+
+// excelRef matches an Excel cell or range reference with an optional sheet,
+// e.g. A1, $B$2:C3, Sheet2!A1 or 'My Sheet'!A1:B2.
+var excelRef = regexp.MustCompile(`^(?:('(?:[^']|'')+'|[A-Za-z_][A-Za-z0-9_.]*)!)?(\$?[A-Za-z]{1,3}\$?[0-9]+)(?::(\$?[A-Za-z]{1,3}\$?[0-9]+))?`)
+
+// odfRef matches an ODF reference in brackets, e.g. [.A1], [.A1:.B2] or
+// [$Sheet2.A1:.B2].
+var odfRef = regexp.MustCompile(`^\[\$?((?:'(?:[^']|'')+'|[^.\[\]:]*))\.(\$?[A-Za-z]{1,3}\$?[0-9]+)(?::\$?(?:'(?:[^']|'')+'|[^.\[\]:]*)\.(\$?[A-Za-z]{1,3}\$?[0-9]+))?\]`)
+
+// toODF converts an Excel formula (without the leading "=") to an OpenFormula
+// attribute value: references are bracketed and arguments are separated by
+// semicolons.
+func toODF(formula string) string {
+	var b strings.Builder
+	b.WriteString("of:=")
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		switch {
+		case c == '"':
+			end := stringEnd(formula, i)
+			b.WriteString(formula[i:end])
+			i = end
+			continue
+		case c == ',':
+			b.WriteByte(';')
+			i++
+			continue
+		}
+		if i == 0 || !isNamePart(formula[i-1]) {
+			if m := excelRef.FindStringSubmatch(formula[i:]); m != nil && !followedByName(formula, i+len(m[0])) {
+				b.WriteString("[")
+				if m[1] != "" {
+					b.WriteString(m[1])
+				}
+				b.WriteString("." + m[2])
+				if m[3] != "" {
+					b.WriteString(":." + m[3])
+				}
+				b.WriteString("]")
+				i += len(m[0])
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// fromODF converts an OpenFormula attribute value to an Excel formula without
+// the leading "=".
+func fromODF(formula string) string {
+	for _, prefix := range []string{"of:", "oooc:", "msoxl:"} {
+		if rest, ok := strings.CutPrefix(formula, prefix); ok {
+			formula = rest
+			break
+		}
+	}
+	formula = strings.TrimPrefix(formula, "=")
+
+	var b strings.Builder
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		switch c {
+		case '"':
+			end := stringEnd(formula, i)
+			b.WriteString(formula[i:end])
+			i = end
+			continue
+		case ';':
+			b.WriteByte(',')
+			i++
+			continue
+		case '~':
+			b.WriteByte(',')
+			i++
+			continue
+		case '[':
+			if m := odfRef.FindStringSubmatch(formula[i:]); m != nil {
+				if m[1] != "" {
+					b.WriteString(excelSheetName(m[1]) + "!")
+				}
+				b.WriteString(m[2])
+				if m[3] != "" {
+					b.WriteString(":" + m[3])
+				}
+				i += len(m[0])
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// excelSheetName returns a sheet name as written in an Excel reference,
+// quoted when it is not a plain name.
+func excelSheetName(name string) string {
+	if strings.HasPrefix(name, "'") {
+		return name
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNamePart(name[i]) {
+			return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+		}
+	}
+	return name
+}
+
+// stringEnd returns the index after the string literal starting at i.
+func stringEnd(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] == '"' {
+			if j+1 < len(s) && s[j+1] == '"' {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// followedByName reports whether s continues a name at i, e.g. the "(" of
+// LOG10( or the digits of a longer name, so a match there is not a reference.
+func followedByName(s string, i int) bool {
+	return i < len(s) && (isNamePart(s[i]) || s[i] == '(')
+}
+
+func isNamePart(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
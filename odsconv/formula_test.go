@@ -0,0 +1,31 @@
+package odsconv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToODF(t *testing.T) {
+	for formula, want := range map[string]string{
+		"SUM(A1:B2)":                     "of:=SUM([.A1:.B2])",
+		"IF($A$1>0,Sheet2!B3,\"a,b\")":   `of:=IF([.$A$1]>0;[Sheet2.B3];"a,b")`,
+		"'My Sheet'!A1*LOG10(C3)":        "of:=['My Sheet'.A1]*LOG10([.C3])",
+		`CONCATENATE("A1",A1)`:           `of:=CONCATENATE("A1";[.A1])`,
+		"SUM(Data!A2:A10)/COUNT(A2:A10)": "of:=SUM([Data.A2:.A10])/COUNT([.A2:.A10])",
+	} {
+		assert.Equal(t, want, toODF(formula), formula)
+	}
+}
+
+func TestFromODF(t *testing.T) {
+	for formula, want := range map[string]string{
+		"of:=SUM([.A1:.B2])":                   "SUM(A1:B2)",
+		`of:=IF([.$A$1]>0;[$Sheet2.B3];"a;b")`: `IF($A$1>0,Sheet2!B3,"a;b")`,
+		"of:=['My Sheet'.A1]*2":                "'My Sheet'!A1*2",
+		"of:=SUM([My Sheet.A1:My Sheet.A3])":   "SUM('My Sheet'!A1:A3)",
+		"oooc:=[.A1]+1":                        "A1+1",
+	} {
+		assert.Equal(t, want, fromODF(formula), formula)
+	}
+}
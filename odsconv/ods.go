@@ -0,0 +1,88 @@
+// Package odsconv converts between OpenDocument spreadsheets (.ods) and
+// excelize workbooks. It is not an ODS implementation of the fill engine: an
+// .ods template is converted into an excelize workbook, filled like an .xlsx
+// template, and the result converted back, so templates authored in
+// LibreOffice can be filled.
+//
+// The conversion is lossy. It keeps:
+//
+//   - cell values, with dates, times and percentages
+//   - formulas, translated between the ODF and Excel syntax
+//   - comments, merged cells and images (written at their natural size)
+//   - column widths and row heights, and hidden sheets when writing .ods
+//
+// Everything else is dropped in both directions, including fonts, fills,
+// borders, alignment, number formats other than dates, times and percentages,
+// conditional formats, data validations, hyperlinks, defined names, charts,
+// frozen panes, print settings and sheet protection. Templates that depend on
+// those should be .xlsx.
+package odsconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MimeType is the media type of an OpenDocument spreadsheet.
+const MimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+// IsODS reports whether data is an OpenDocument spreadsheet package.
+func IsODS(data []byte) bool {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, zf := range zr.File {
+		if zf.Name != "mimetype" {
+			continue
+		}
+		content, err := readZipFile(zf)
+		return err == nil && strings.TrimSpace(string(content)) == MimeType
+	}
+	return false
+}
+
+// readZipFile returns the content of a file in a zip archive.
+func readZipFile(zf *zip.File) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// lengthPoints converts an ODF length such as "2.5cm" or "12pt" to points.
+func lengthPoints(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	for _, u := range []struct {
+		unit   string
+		points float64
+	}{{"cm", 72 / 2.54}, {"mm", 72 / 25.4}, {"in", 72}, {"pt", 1}, {"pc", 12}, {"px", 0.75}} {
+		if num, ok := strings.CutSuffix(s, u.unit); ok {
+			v, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid length %q", s)
+			}
+			return v * u.points, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid length %q", s)
+}
+
+// Excel column widths are in characters of the default font: 7 pixels each
+// plus 5 pixels of padding, at 96 pixels per inch.
+
+// widthPoints converts an Excel column width to points.
+func widthPoints(chars float64) float64 {
+	return (chars*7 + 5) * 0.75
+}
+
+// widthChars converts a column width in points to an Excel column width.
+func widthChars(points float64) float64 {
+	return max(points/0.75-5, 0) / 7
+}
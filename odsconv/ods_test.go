@@ -0,0 +1,194 @@
+package odsconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// roundTrip writes f as .ods and reads it back.
+func roundTrip(t *testing.T, f *excelize.File) *excelize.File {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, f))
+	require.True(t, IsODS(buf.Bytes()))
+	out, err := Read(&buf)
+	require.NoError(t, err)
+	t.Cleanup(func() { out.Close() })
+	return out
+}
+
+func TestRoundTrip_Values(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	require.NoError(t, f.SetSheetName("Sheet1", "Data & More"))
+	sheet := "Data & More"
+	f.SetCellValue(sheet, "A1", "${e.Name}")
+	f.SetCellValue(sheet, "B1", 42.5)
+	f.SetCellValue(sheet, "C1", true)
+	f.SetCellValue(sheet, "D1", "two\nlines  with\tspace ")
+	f.SetCellValue(sheet, "A2", time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC))
+	f.SetCellFormula(sheet, "B3", "SUM(B1:B2)")
+	f.SetCellValue(sheet, "E5", "<&>")
+	_, err := f.NewSheet("Hidden")
+	require.NoError(t, err)
+	f.SetCellValue("Hidden", "A1", 1)
+	require.NoError(t, f.SetSheetVisible("Hidden", false))
+
+	out := roundTrip(t, f)
+	assert.Equal(t, []string{sheet, "Hidden"}, out.GetSheetList())
+	for cell, want := range map[string]string{"A1": "${e.Name}", "B1": "42.5", "C1": "TRUE", "D1": "two\nlines  with\tspace ", "E5": "<&>"} {
+		v, err := out.GetCellValue(sheet, cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, v, cell)
+	}
+	typ, _ := out.GetCellType(sheet, "C1")
+	assert.Equal(t, excelize.CellTypeBool, typ)
+
+	v, _ := out.GetCellValue(sheet, "A2", excelize.Options{RawCellValue: true})
+	date, err := excelize.ExcelDateToTime(mustFloat(t, v), false)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-14", date.Format("2006-01-02"))
+
+	formula, _ := out.GetCellFormula(sheet, "B3")
+	assert.Equal(t, "SUM(B1:B2)", formula)
+
+	visible, err := out.GetSheetVisible("Hidden")
+	require.NoError(t, err)
+	assert.True(t, visible, "sheet visibility is not converted back")
+}
+
+func TestRoundTrip_CommentsMergesImagesAndSizes(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Title")
+	require.NoError(t, f.MergeCell("Sheet1", "A1", "C2"))
+	require.NoError(t, f.AddComment("Sheet1", excelize.Comment{Cell: "A3", Author: "ann", Text: "jx:area(lastCell=\"C3\")\nnote"}))
+	require.NoError(t, f.AddPictureFromBytes("Sheet1", "B4", &excelize.Picture{
+		Extension: ".png", File: testPNG(t, 40, 20), Format: &excelize.GraphicOptions{ScaleX: 2, ScaleY: 1},
+	}))
+	require.NoError(t, f.SetColWidth("Sheet1", "B", "B", 30))
+	require.NoError(t, f.SetRowHeight("Sheet1", 4, 42))
+
+	out := roundTrip(t, f)
+	merges, err := out.GetMergeCells("Sheet1")
+	require.NoError(t, err)
+	require.Len(t, merges, 1)
+	assert.Equal(t, "A1", merges[0].GetStartAxis())
+	assert.Equal(t, "C2", merges[0].GetEndAxis())
+
+	comments, err := out.GetComments("Sheet1")
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "A3", comments[0].Cell)
+	assert.Equal(t, "ann", comments[0].Author)
+	assert.Equal(t, "jx:area(lastCell=\"C3\")\nnote", comments[0].Text)
+
+	pics, err := out.GetPictures("Sheet1", "B4")
+	require.NoError(t, err)
+	require.Len(t, pics, 1)
+	assert.Equal(t, ".png", pics[0].Extension)
+
+	w, _ := out.GetColWidth("Sheet1", "B")
+	assert.InDelta(t, 30, w, 0.01)
+	h, _ := out.GetRowHeight("Sheet1", 4)
+	assert.InDelta(t, 42, h, 0.01)
+}
+
+func TestRead_LibreOfficeMarkup(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content ` + namespaces + ` xmlns:calcext="urn:org:documentfoundation:names:experimental:calc:xmlns:calcext:1.0" office:version="1.2">
+<office:automatic-styles>
+ <style:style style:name="co1" style:family="table-column"><style:table-column-properties style:column-width="2.258cm"/></style:style>
+</office:automatic-styles>
+<office:body><office:spreadsheet>
+ <table:table table:name="Report">
+  <table:table-column table:style-name="co1" table:number-columns-repeated="2"/>
+  <table:table-row>
+   <table:table-cell office:value-type="string" calcext:value-type="string"><text:p>Hello <text:span>big</text:span><text:s text:c="2"/>world</text:p></table:table-cell>
+   <table:table-cell office:value-type="percentage" office:value="0.25"><text:p>25%</text:p></table:table-cell>
+   <table:table-cell table:number-columns-repeated="2" office:value-type="float" office:value="7"><text:p>7</text:p></table:table-cell>
+  </table:table-row>
+  <table:table-row table:number-rows-repeated="2">
+   <table:table-cell table:formula="of:=[.B1]*2" office:value-type="float" office:value="0.5"/>
+   <table:table-cell office:value-type="time" office:time-value="PT12H00M00S"/>
+  </table:table-row>
+  <table:table-row table:number-rows-repeated="1048572"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+ </table:table>
+</office:spreadsheet></office:body>
+</office:document-content>`
+	var buf bytes.Buffer
+	zw := newZip(t, &buf, map[string]string{"content.xml": content})
+	require.NoError(t, zw.Close())
+
+	f, err := Read(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"Report"}, f.GetSheetList())
+	for cell, want := range map[string]string{"A1": "Hello big  world", "C1": "7", "D1": "7", "B3": "0.5"} {
+		v, _ := f.GetCellValue("Report", cell, excelize.Options{RawCellValue: true})
+		assert.Equal(t, want, v, cell)
+	}
+	v, _ := f.GetCellValue("Report", "B1")
+	assert.Equal(t, "25%", v)
+	formula, _ := f.GetCellFormula("Report", "A3")
+	assert.Equal(t, "B1*2", formula)
+	w, _ := f.GetColWidth("Report", "B")
+	assert.InDelta(t, widthChars(2.258*72/2.54), w, 0.01)
+	rows, err := f.GetRows("Report")
+	require.NoError(t, err)
+	assert.Len(t, rows, 3)
+}
+
+func TestRead_NotODS(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	assert.False(t, IsODS(buf.Bytes()))
+	_, err := Read(&buf)
+	assert.ErrorContains(t, err, "not an OpenDocument spreadsheet")
+}
+
+func mustFloat(t *testing.T, s string) float64 {
+	t.Helper()
+	v, err := strconv.ParseFloat(s, 64)
+	require.NoError(t, err)
+	return v
+}
+
+// newZip writes an .ods package with the given parts after its mimetype.
+func newZip(t *testing.T, w io.Writer, parts map[string]string) *zip.Writer {
+	t.Helper()
+	zw := zip.NewWriter(w)
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	require.NoError(t, err)
+	_, err = io.WriteString(mt, MimeType)
+	require.NoError(t, err)
+	for name, content := range parts {
+		pw, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = io.WriteString(pw, content)
+		require.NoError(t, err)
+	}
+	return zw
+}
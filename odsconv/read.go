@@ -0,0 +1,465 @@
+package odsconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"  // decode GIF sizes
+	_ "image/jpeg" // decode JPEG sizes
+	_ "image/png"  // decode PNG sizes
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	maxRows = excelize.TotalRows
+	maxCols = excelize.MaxColumns
+)
+
+// odsStyle is an automatic style of content.xml; only the column width and
+// row height are read.
+type odsStyle struct {
+	Name   string `xml:"name,attr"`
+	Column struct {
+		Width string `xml:"column-width,attr"`
+	} `xml:"table-column-properties"`
+	Row struct {
+		Height string `xml:"row-height,attr"`
+	} `xml:"table-row-properties"`
+}
+
+type odsColumn struct {
+	Repeated int    `xml:"number-columns-repeated,attr"`
+	Style    string `xml:"style-name,attr"`
+}
+
+type odsRow struct {
+	Repeated int       `xml:"number-rows-repeated,attr"`
+	Style    string    `xml:"style-name,attr"`
+	Cells    []odsCell `xml:",any"`
+}
+
+// odsCell is a table:table-cell or table:covered-table-cell.
+type odsCell struct {
+	XMLName     xml.Name
+	Repeated    int            `xml:"number-columns-repeated,attr"`
+	ColSpan     int            `xml:"number-columns-spanned,attr"`
+	RowSpan     int            `xml:"number-rows-spanned,attr"`
+	ValueType   string         `xml:"value-type,attr"`
+	Value       string         `xml:"value,attr"`
+	DateValue   string         `xml:"date-value,attr"`
+	TimeValue   string         `xml:"time-value,attr"`
+	BoolValue   string         `xml:"boolean-value,attr"`
+	StringValue string         `xml:"string-value,attr"`
+	Formula     string         `xml:"formula,attr"`
+	Paragraphs  []odsText      `xml:"p"`
+	Annotation  *odsAnnotation `xml:"annotation"`
+	Frames      []odsFrame     `xml:"frame"`
+}
+
+type odsText struct {
+	Inner string `xml:",innerxml"`
+}
+
+type odsAnnotation struct {
+	Creator    string    `xml:"creator"`
+	Paragraphs []odsText `xml:"p"`
+}
+
+type odsFrame struct {
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+	Image  struct {
+		Href string `xml:"href,attr"`
+	} `xml:"image"`
+}
+
+// empty reports whether the cell has no content to convert.
+func (c *odsCell) empty() bool {
+	return c.XMLName.Local == "covered-table-cell" || c.ValueType == "" && c.Formula == "" &&
+		len(c.Paragraphs) == 0 && c.Annotation == nil && len(c.Frames) == 0 && c.ColSpan <= 1 && c.RowSpan <= 1
+}
+
+// reader converts the content of an .ods package into an excelize workbook.
+type reader struct {
+	zip     *zip.Reader
+	file    *excelize.File
+	styles  map[string]odsStyle
+	percent int // style ID of converted percentages, once created
+	time    int // style ID of converted times, once created
+
+	sheet    string
+	sheets   int
+	row, col int
+}
+
+// Read reads an OpenDocument spreadsheet into a new excelize workbook.
+func Read(r io.Reader) (*excelize.File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !IsODS(data) {
+		return nil, fmt.Errorf("not an OpenDocument spreadsheet")
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	for _, zf := range zr.File {
+		if zf.Name == "content.xml" {
+			if content, err = readZipFile(zf); err != nil {
+				return nil, fmt.Errorf("read content.xml: %w", err)
+			}
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("missing content.xml")
+	}
+
+	rd := &reader{zip: zr, file: excelize.NewFile(), styles: map[string]odsStyle{}}
+	if err := rd.readContent(content); err != nil {
+		rd.file.Close()
+		return nil, fmt.Errorf("read content.xml: %w", err)
+	}
+	return rd.file, nil
+}
+
+func (rd *reader) readContent(content []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "style":
+			var s odsStyle
+			if err := dec.DecodeElement(&s, &se); err != nil {
+				return err
+			}
+			rd.styles[s.Name] = s
+		case "table":
+			if err := rd.startSheet(attr(se, "name")); err != nil {
+				return err
+			}
+		case "table-column":
+			var c odsColumn
+			if err := dec.DecodeElement(&c, &se); err != nil {
+				return err
+			}
+			if err := rd.readColumn(c); err != nil {
+				return err
+			}
+		case "table-row":
+			var r odsRow
+			if err := dec.DecodeElement(&r, &se); err != nil {
+				return err
+			}
+			if err := rd.readRow(r); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func attr(se xml.StartElement, name string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// startSheet makes name the current sheet; the first one renames the new
+// workbook's default sheet.
+func (rd *reader) startSheet(name string) error {
+	rd.sheets++
+	if rd.sheets == 1 {
+		if err := rd.file.SetSheetName(rd.file.GetSheetName(0), name); err != nil {
+			return err
+		}
+	} else if _, err := rd.file.NewSheet(name); err != nil {
+		return err
+	}
+	rd.sheet, rd.row, rd.col = name, 0, 0
+	return nil
+}
+
+func (rd *reader) readColumn(c odsColumn) error {
+	n := max(c.Repeated, 1)
+	first := rd.col
+	rd.col = min(rd.col+n, maxCols)
+	width := rd.styles[c.Style].Column.Width
+	if width == "" || first >= maxCols {
+		return nil
+	}
+	pt, err := lengthPoints(width)
+	if err != nil {
+		return err
+	}
+	start, _ := excelize.ColumnNumberToName(first + 1)
+	end, _ := excelize.ColumnNumberToName(rd.col)
+	return rd.file.SetColWidth(rd.sheet, start, end, widthChars(pt))
+}
+
+func (rd *reader) readRow(r odsRow) error {
+	n := max(r.Repeated, 1)
+	blank := true
+	for i := range r.Cells {
+		if !r.Cells[i].empty() {
+			blank = false
+		}
+	}
+	height := rd.styles[r.Style].Row.Height
+	for i := 0; i < n && rd.row < maxRows; i++ {
+		// Trailing blank rows are usually repeated to the end of the sheet
+		if blank && (height == "" || n > 1) {
+			rd.row = min(rd.row+n-i, maxRows)
+			break
+		}
+		if height != "" {
+			pt, err := lengthPoints(height)
+			if err != nil {
+				return err
+			}
+			if err := rd.file.SetRowHeight(rd.sheet, rd.row+1, pt); err != nil {
+				return err
+			}
+		}
+		col := 0
+		for j := range r.Cells {
+			cell := &r.Cells[j]
+			repeat := max(cell.Repeated, 1)
+			if cell.empty() {
+				col += repeat
+				continue
+			}
+			for k := 0; k < repeat && col < maxCols; k++ {
+				if err := rd.readCell(cell, rd.row, col); err != nil {
+					return err
+				}
+				col++
+			}
+		}
+		rd.row++
+	}
+	return nil
+}
+
+func (rd *reader) readCell(c *odsCell, row, col int) error {
+	f, sheet := rd.file, rd.sheet
+	cell, err := excelize.CoordinatesToCellName(col+1, row+1)
+	if err != nil {
+		return err
+	}
+
+	text := paragraphsText(c.Paragraphs)
+	switch c.ValueType {
+	case "float", "currency", "percentage":
+		v, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return fmt.Errorf("cell %s!%s: invalid number %q", sheet, cell, c.Value)
+		}
+		if err := f.SetCellFloat(sheet, cell, v, -1, 64); err != nil {
+			return err
+		}
+		if c.ValueType == "percentage" {
+			if err := rd.setStyle(cell, &rd.percent, &excelize.Style{NumFmt: 9}); err != nil {
+				return err
+			}
+		}
+	case "date":
+		t, err := parseDate(c.DateValue)
+		if err != nil {
+			return fmt.Errorf("cell %s!%s: %w", sheet, cell, err)
+		}
+		if err := f.SetCellValue(sheet, cell, t); err != nil {
+			return err
+		}
+	case "time":
+		d, err := parseDuration(c.TimeValue)
+		if err != nil {
+			return fmt.Errorf("cell %s!%s: %w", sheet, cell, err)
+		}
+		if err := f.SetCellFloat(sheet, cell, d.Hours()/24, -1, 64); err != nil {
+			return err
+		}
+		if err := rd.setStyle(cell, &rd.time, &excelize.Style{NumFmt: 21}); err != nil {
+			return err
+		}
+	case "boolean":
+		if err := f.SetCellBool(sheet, cell, c.BoolValue == "true"); err != nil {
+			return err
+		}
+	default:
+		if c.StringValue != "" {
+			text = c.StringValue
+		}
+		if text != "" {
+			if err := f.SetCellStr(sheet, cell, text); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Formula != "" {
+		if err := f.SetCellFormula(sheet, cell, fromODF(c.Formula)); err != nil {
+			return err
+		}
+	}
+
+	if c.ColSpan > 1 || c.RowSpan > 1 {
+		end, err := excelize.CoordinatesToCellName(col+max(c.ColSpan, 1), row+max(c.RowSpan, 1))
+		if err != nil {
+			return err
+		}
+		if err := f.MergeCell(sheet, cell, end); err != nil {
+			return err
+		}
+	}
+	if c.Annotation != nil {
+		comment := excelize.Comment{Cell: cell, Author: c.Annotation.Creator, Text: paragraphsText(c.Annotation.Paragraphs)}
+		if err := f.AddComment(sheet, comment); err != nil {
+			return err
+		}
+	}
+	for _, frame := range c.Frames {
+		if err := rd.readImage(cell, frame); err != nil {
+			return fmt.Errorf("cell %s!%s: %w", sheet, cell, err)
+		}
+	}
+	return nil
+}
+
+// setStyle applies a style created once per workbook to a cell.
+func (rd *reader) setStyle(cell string, id *int, style *excelize.Style) error {
+	if *id == 0 {
+		var err error
+		if *id, err = rd.file.NewStyle(style); err != nil {
+			return err
+		}
+	}
+	return rd.file.SetCellStyle(rd.sheet, cell, cell, *id)
+}
+
+// readImage adds an image embedded in the package at a cell, scaled to the
+// frame's size.
+func (rd *reader) readImage(cell string, frame odsFrame) error {
+	name := strings.TrimPrefix(frame.Image.Href, "./")
+	var data []byte
+	for _, zf := range rd.zip.File {
+		if zf.Name == name {
+			var err error
+			if data, err = readZipFile(zf); err != nil {
+				return err
+			}
+		}
+	}
+	if data == nil {
+		return nil // linked, not embedded
+	}
+	opts := &excelize.GraphicOptions{ScaleX: 1, ScaleY: 1}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && cfg.Width > 0 && cfg.Height > 0 {
+		if w, err := lengthPoints(frame.Width); err == nil {
+			opts.ScaleX = w / 0.75 / float64(cfg.Width)
+		}
+		if h, err := lengthPoints(frame.Height); err == nil {
+			opts.ScaleY = h / 0.75 / float64(cfg.Height)
+		}
+	}
+	return rd.file.AddPictureFromBytes(rd.sheet, cell, &excelize.Picture{
+		Extension: strings.ToLower(path.Ext(name)),
+		File:      data,
+		Format:    opts,
+	})
+}
+
+// paragraphsText returns the text of text:p elements, one line each.
+func paragraphsText(paragraphs []odsText) string {
+	lines := make([]string, len(paragraphs))
+	for i, p := range paragraphs {
+		lines[i] = inlineText(p.Inner)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineText returns the text of a paragraph's content, expanding text:s
+// (spaces), text:tab and text:line-break and skipping annotations.
+func inlineText(inner string) string {
+	var b strings.Builder
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	dec.Strict = false
+	skip := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return b.String()
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 || t.Name.Local == "annotation" {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "s":
+				n, err := strconv.Atoi(attr(t, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				b.WriteString(strings.Repeat(" ", n))
+			case "tab":
+				b.WriteByte('\t')
+			case "line-break":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+			}
+		case xml.CharData:
+			if skip == 0 {
+				b.Write(t)
+			}
+		}
+	}
+}
+
+// parseDate parses an ODF date value: a date with an optional time.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseDuration parses an ODF time value such as "PT10H30M00S".
+func parseDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(s, "PT")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	d, err := time.ParseDuration(strings.ToLower(rest))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return d, nil
+}
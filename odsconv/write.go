@@ -0,0 +1,392 @@
+package odsconv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	manifestHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="` + MimeType + `"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+ <manifest:file-entry manifest:full-path="styles.xml" manifest:media-type="text/xml"/>
+`
+	namespaces = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+		` xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0"` +
+		` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+		` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+		` xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"` +
+		` xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"` +
+		` xmlns:xlink="http://www.w3.org/1999/xlink"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/"` +
+		` xmlns:svg="urn:oasis:names:tc:opendocument:xmlns:svg-compatible:1.0"` +
+		` xmlns:of="urn:oasis:names:tc:opendocument:xmlns:of:1.2"`
+	stylesXML = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-styles ` + namespaces + ` office:version="1.2"/>
+`
+	defaultRowHeight = 15.0 // points, excelize's default
+)
+
+// writer converts an excelize workbook into the parts of an .ods package.
+type writer struct {
+	file     *excelize.File
+	styles   strings.Builder // automatic styles
+	styleIDs map[string]string
+	body     strings.Builder
+	images   []odsImage
+}
+
+type odsImage struct {
+	name string
+	data []byte
+}
+
+// Write writes an excelize workbook as an OpenDocument spreadsheet.
+func Write(w io.Writer, f *excelize.File) error {
+	wr := &writer{file: f, styleIDs: map[string]string{}}
+	for _, sheet := range f.GetSheetList() {
+		if err := wr.writeSheet(sheet); err != nil {
+			return fmt.Errorf("sheet %q: %w", sheet, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// The mimetype comes first and uncompressed so the type can be sniffed
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mt, MimeType); err != nil {
+		return err
+	}
+
+	manifest := manifestHeader
+	for _, img := range wr.images {
+		manifest += ` <manifest:file-entry manifest:full-path="` + img.name + `" manifest:media-type="` + imageMediaType(img.name) + `"/>` + "\n"
+	}
+	manifest += "</manifest:manifest>\n"
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content ` + namespaces + ` office:version="1.2">
+<office:automatic-styles>` + wr.styles.String() + `</office:automatic-styles>
+<office:body><office:spreadsheet>` + wr.body.String() + `</office:spreadsheet></office:body>
+</office:document-content>
+`
+	parts := []odsImage{{"META-INF/manifest.xml", []byte(manifest)}, {"content.xml", []byte(content)}, {"styles.xml", []byte(stylesXML)}}
+	for _, part := range append(parts, wr.images...) {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := pw.Write(part.data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// style returns the name of an automatic style with the given family and
+// properties, adding it on first use.
+func (wr *writer) style(prefix, family, props string) string {
+	key := family + props
+	if name, ok := wr.styleIDs[key]; ok {
+		return name
+	}
+	name := prefix + strconv.Itoa(len(wr.styleIDs)+1)
+	wr.styleIDs[key] = name
+	fmt.Fprintf(&wr.styles, `<style:style style:name="%s" style:family="%s">%s</style:style>`, name, family, props)
+	return name
+}
+
+// sheetCell is what the writer knows about one cell of a sheet.
+type sheetCell struct {
+	comment *excelize.Comment
+	span    [2]int // columns and rows of a merge starting at the cell
+	covered bool   // covered by a merge starting elsewhere
+	picture bool
+}
+
+func (wr *writer) writeSheet(sheet string) error {
+	f := wr.file
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+	height, width := len(rows), 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	cells := map[[2]int]*sheetCell{}
+	at := func(row, col int) *sheetCell {
+		height, width = max(height, row+1), max(width, col+1)
+		c := cells[[2]int{row, col}]
+		if c == nil {
+			c = &sheetCell{}
+			cells[[2]int{row, col}] = c
+		}
+		return c
+	}
+	merges, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return err
+	}
+	for _, m := range merges {
+		c1, r1, err := excelize.CellNameToCoordinates(m.GetStartAxis())
+		if err != nil {
+			return err
+		}
+		c2, r2, err := excelize.CellNameToCoordinates(m.GetEndAxis())
+		if err != nil {
+			return err
+		}
+		for r := r1; r <= r2; r++ {
+			for c := c1; c <= c2; c++ {
+				at(r-1, c-1).covered = r != r1 || c != c1
+			}
+		}
+		at(r1-1, c1-1).span = [2]int{c2 - c1 + 1, r2 - r1 + 1}
+	}
+	comments, err := f.GetComments(sheet)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		c, r, err := excelize.CellNameToCoordinates(comments[i].Cell)
+		if err != nil {
+			return err
+		}
+		at(r-1, c-1).comment = &comments[i]
+	}
+	pictures, err := f.GetPictureCells(sheet)
+	if err != nil {
+		return err
+	}
+	for _, cell := range pictures {
+		c, r, err := excelize.CellNameToCoordinates(cell)
+		if err != nil {
+			return err
+		}
+		at(r-1, c-1).picture = true
+	}
+
+	b := &wr.body
+	tableStyle := wr.style("ta", "table", `<style:table-properties table:display="true"/>`)
+	if visible, err := f.GetSheetVisible(sheet); err == nil && !visible {
+		tableStyle = wr.style("ta", "table", `<style:table-properties table:display="false"/>`)
+	}
+	fmt.Fprintf(b, `<table:table table:name="%s" table:style-name="%s">`, escape(sheet), tableStyle)
+	for col := 0; col < max(width, 1); col++ {
+		name, _ := excelize.ColumnNumberToName(col + 1)
+		w, err := f.GetColWidth(sheet, name)
+		if err != nil {
+			return err
+		}
+		style := wr.style("co", "table-column", fmt.Sprintf(`<style:table-column-properties style:column-width="%.3fpt"/>`, widthPoints(w)))
+		fmt.Fprintf(b, `<table:table-column table:style-name="%s"/>`, style)
+	}
+	// A table has at least one row of one cell
+	height, width = max(height, 1), max(width, 1)
+	for row := 0; row < height; row++ {
+		h, err := f.GetRowHeight(sheet, row+1)
+		if err != nil {
+			return err
+		}
+		if h != defaultRowHeight {
+			style := wr.style("ro", "table-row", fmt.Sprintf(`<style:table-row-properties style:row-height="%.2fpt" style:use-optimal-row-height="false"/>`, h))
+			fmt.Fprintf(b, `<table:table-row table:style-name="%s">`, style)
+		} else {
+			b.WriteString(`<table:table-row>`)
+		}
+		for col := 0; col < width; col++ {
+			value := ""
+			if row < len(rows) && col < len(rows[row]) {
+				value = rows[row][col]
+			}
+			if err := wr.writeCell(sheet, row, col, value, cells[[2]int{row, col}]); err != nil {
+				return err
+			}
+		}
+		b.WriteString(`</table:table-row>`)
+	}
+	b.WriteString(`</table:table>`)
+	return nil
+}
+
+func (wr *writer) writeCell(sheet string, row, col int, raw string, info *sheetCell) error {
+	f, b := wr.file, &wr.body
+	if info != nil && info.covered {
+		b.WriteString(`<table:covered-table-cell/>`)
+		return nil
+	}
+	cell, err := excelize.CoordinatesToCellName(col+1, row+1)
+	if err != nil {
+		return err
+	}
+	formula, err := f.GetCellFormula(sheet, cell)
+	if err != nil {
+		return err
+	}
+
+	b.WriteString(`<table:table-cell`)
+	if formula != "" {
+		fmt.Fprintf(b, ` table:formula="%s"`, escape(toODF(formula)))
+	}
+	if info != nil && info.span != [2]int{} {
+		fmt.Fprintf(b, ` table:number-columns-spanned="%d" table:number-rows-spanned="%d"`, info.span[0], info.span[1])
+	}
+	text := raw
+	if raw != "" {
+		cellType, err := f.GetCellType(sheet, cell)
+		if err != nil {
+			return err
+		}
+		number, numErr := strconv.ParseFloat(raw, 64)
+		switch {
+		case cellType == excelize.CellTypeBool:
+			bv := "false"
+			if raw == "1" || strings.EqualFold(raw, "true") {
+				bv = "true"
+			}
+			text = strings.ToUpper(bv)
+			fmt.Fprintf(b, ` office:value-type="boolean" office:boolean-value="%s"`, bv)
+		case numErr == nil && (cellType == excelize.CellTypeNumber || cellType == excelize.CellTypeUnset ||
+			cellType == excelize.CellTypeFormula):
+			if text, err = f.GetCellValue(sheet, cell); err != nil {
+				return err
+			}
+			if t, ok := wr.dateValue(sheet, cell, number); ok {
+				fmt.Fprintf(b, ` office:value-type="date" office:date-value="%s"`, t)
+			} else {
+				fmt.Fprintf(b, ` office:value-type="float" office:value="%s"`, raw)
+			}
+		default:
+			b.WriteString(` office:value-type="string"`)
+		}
+	}
+	b.WriteString(`>`)
+
+	if info != nil && info.picture {
+		if err := wr.writePictures(sheet, cell); err != nil {
+			return err
+		}
+	}
+	if info != nil && info.comment != nil {
+		fmt.Fprintf(b, `<office:annotation><dc:creator>%s</dc:creator>`, escape(info.comment.Author))
+		writeParagraphs(b, info.comment.Text)
+		b.WriteString(`</office:annotation>`)
+	}
+	if text != "" {
+		writeParagraphs(b, text)
+	}
+	b.WriteString(`</table:table-cell>`)
+	return nil
+}
+
+// dateValue returns the ODF date value of a number shown with a date format.
+func (wr *writer) dateValue(sheet, cell string, number float64) (string, bool) {
+	styleID, err := wr.file.GetCellStyle(sheet, cell)
+	if err != nil || styleID == 0 {
+		return "", false
+	}
+	style, err := wr.file.GetStyle(styleID)
+	if err != nil || !isDateFormat(style) {
+		return "", false
+	}
+	t, err := excelize.ExcelDateToTime(number, false)
+	if err != nil {
+		return "", false
+	}
+	return t.Format("2006-01-02T15:04:05"), true
+}
+
+// isDateFormat reports whether a style's number format shows a date.
+func isDateFormat(style *excelize.Style) bool {
+	if style.CustomNumFmt != nil {
+		format := strings.ToLower(*style.CustomNumFmt)
+		return strings.Contains(format, "yy") || strings.Contains(format, "dd") || strings.Contains(format, "mmm")
+	}
+	return style.NumFmt >= 14 && style.NumFmt <= 17 || style.NumFmt == 22
+}
+
+// writePictures embeds the pictures of a cell in frames anchored to it.
+func (wr *writer) writePictures(sheet, cell string) error {
+	pics, err := wr.file.GetPictures(sheet, cell)
+	if err != nil {
+		return err
+	}
+	for _, pic := range pics {
+		name := fmt.Sprintf("Pictures/image%d%s", len(wr.images)+1, pic.Extension)
+		wr.images = append(wr.images, odsImage{name: name, data: pic.File})
+		// excelize does not report the size a picture is shown at
+		width, height := 2.54, 2.54 // cm
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(pic.File)); err == nil {
+			width = float64(cfg.Width) / 96 * 2.54
+			height = float64(cfg.Height) / 96 * 2.54
+		}
+		fmt.Fprintf(&wr.body, `<draw:frame svg:width="%.3fcm" svg:height="%.3fcm" svg:x="0cm" svg:y="0cm">`+
+			`<draw:image xlink:href="%s" xlink:type="simple" xlink:show="embed" xlink:actuate="onLoad"/></draw:frame>`,
+			width, height, name)
+	}
+	return nil
+}
+
+// writeParagraphs writes text as text:p elements, one per line. Tabs and runs
+// of spaces, which ODF readers would collapse, are written as elements.
+func writeParagraphs(b *strings.Builder, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(`<text:p>`)
+		runes := []rune(line)
+		for i := 0; i < len(runes); i++ {
+			switch runes[i] {
+			case ' ':
+				n := 1
+				for i+n < len(runes) && runes[i+n] == ' ' {
+					n++
+				}
+				if n == 1 && i > 0 && i+1 < len(runes) {
+					b.WriteByte(' ')
+				} else {
+					fmt.Fprintf(b, `<text:s text:c="%d"/>`, n)
+				}
+				i += n - 1
+			case '\t':
+				b.WriteString(`<text:tab/>`)
+			default:
+				b.WriteString(escape(string(runes[i])))
+			}
+		}
+		b.WriteString(`</text:p>`)
+	}
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func imageMediaType(name string) string {
+	switch strings.ToLower(name[strings.LastIndex(name, ".")+1:]) {
+	case "jpg", "jpeg":
+		return "image/jpeg"
+	case "gif":
+		return "image/gif"
+	case "svg":
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
@@ -1,11 +1,25 @@
 package xlfill
 
-import "io"
+import (
+	"context"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
 
 // Options holds configuration for the Filler.
 type Options struct {
 	templatePath        string
 	templateReader      io.Reader
+	templateFS          fs.FS
+	templateSource      TemplateSource
+	templateName        string
 	notationBegin       string
 	notationEnd         string
 	customCommands      map[string]CommandFactory
@@ -13,8 +27,44 @@ type Options struct {
 	keepTemplateSheet   bool
 	hideTemplateSheet   bool
 	recalculateOnOpen   bool
+	calcProps           *CalcProps
+	branding            *Branding
 	areaListeners       []AreaListener
 	preWrite            func(Transformer) error
+	formulaStrategies   map[string]FormulaStrategyFunc
+	jsonData            []byte
+	areaDefinitions     io.Reader
+	inlineMarkers       bool
+	namedRangeAreas     bool
+	templateSheets      map[string]SheetDisposition
+	areaCollisionCheck  bool
+	outsideFormulas     bool
+	shiftOutside        bool
+	concurrency         int
+	styles              map[string]*excelize.Style
+	styleCells          map[string]string
+	stripMarkup         bool
+	logger              *slog.Logger
+	deterministic       bool
+	sandbox             *exprSandbox
+	outputLimits        outputLimits
+	defaults            map[string]any
+	valueConverter      func(v any) (any, CellType, bool)
+	sheetNameBuilder    SheetNameBuilder
+	sheetData           map[string]map[string]any
+	cancel              context.Context
+	usageReport         *UsageReport
+	errorPolicy         ErrorPolicy
+	errorPlaceholder    string
+	cellWriters         []func(next CellWriteFunc) CellWriteFunc
+	redaction           *RedactionRules
+	selectAfterGroup    bool
+	sheetOrder          []string
+	activeSheet         string
+	outputFilters       []OutputFilter
+	stats               *FillStats
+	markupPrefix        string
+	markupAuthor        string
 }
 
 func defaultOptions() *Options {
@@ -22,6 +72,7 @@ func defaultOptions() *Options {
 		notationBegin:      "${",
 		notationEnd:        "}",
 		clearTemplateCells: true,
+		errorPlaceholder:   defaultErrorPlaceholder,
 	}
 }
 
@@ -38,6 +89,12 @@ func WithTemplateReader(r io.Reader) Option {
 	return func(o *Options) { o.templateReader = r }
 }
 
+// WithTemplateFS sets the template as the file name in fsys, e.g. a template
+// compiled into the binary with go:embed.
+func WithTemplateFS(fsys fs.FS, name string) Option {
+	return func(o *Options) { o.templateFS, o.templateName = fsys, name }
+}
+
 // WithExpressionNotation sets the expression delimiters (default: "${", "}").
 func WithExpressionNotation(begin, end string) Option {
 	return func(o *Options) {
@@ -61,16 +118,58 @@ func WithClearTemplateCells(clear bool) Option {
 	return func(o *Options) { o.clearTemplateCells = clear }
 }
 
+// WithStripMarkupComments removes the jx: command lines from the template's cell
+// comments in the output. Comments left empty are deleted; other lines, such as
+// notes for the report's readers, are kept and follow the cell like ordinary
+// comments do.
+func WithStripMarkupComments(strip bool) Option {
+	return func(o *Options) { o.stripMarkup = strip }
+}
+
 // WithKeepTemplateSheet keeps the original template sheet in the output.
 func WithKeepTemplateSheet(keep bool) Option {
 	return func(o *Options) { o.keepTemplateSheet = keep }
 }
 
+// WithSheetOrder moves the named sheets of the output first, in that order,
+// e.g. a summary sheet before the sheets of a multisheet jx:each. The other
+// sheets follow in their order. Naming a sheet missing from the output fails
+// the fill.
+func WithSheetOrder(names []string) Option {
+	return func(o *Options) { o.sheetOrder = slices.Clone(names) }
+}
+
+// WithActiveSheet selects the sheet shown when the output is opened.
+func WithActiveSheet(name string) Option {
+	return func(o *Options) { o.activeSheet = name }
+}
+
 // WithHideTemplateSheet hides the template sheet instead of deleting it.
 func WithHideTemplateSheet(hide bool) Option {
 	return func(o *Options) { o.hideTemplateSheet = hide }
 }
 
+// CalcProps are the calculation settings of the output workbook, for
+// WithCalcProps. Zero fields keep the template's setting.
+type CalcProps struct {
+	FullCalcOnLoad bool   // recalculate all formulas when the file is opened
+	CalcMode       string // "auto", "autoNoTable" or "manual"
+	Iterative      bool   // resolve circular references by iteration
+	MaxIterations  int    // iterations for circular references (Excel's default: 100)
+}
+
+// WithCalcProps sets how Excel calculates the output workbook, e.g. manual
+// calculation or iterative formulas with circular references.
+func WithCalcProps(props CalcProps) Option {
+	return func(o *Options) { o.calcProps = &props }
+}
+
+// WithBranding recolors and refonts the styles of the output workbook, so one
+// template can follow each customer's brand palette.
+func WithBranding(b Branding) Option {
+	return func(o *Options) { o.branding = &b }
+}
+
 // WithRecalculateOnOpen tells Excel to recalculate all formulas when the file is opened.
 func WithRecalculateOnOpen(recalc bool) Option {
 	return func(o *Options) { o.recalculateOnOpen = recalc }
@@ -85,3 +184,316 @@ func WithAreaListener(listener AreaListener) Option {
 func WithPreWrite(fn func(Transformer) error) Option {
 	return func(o *Options) { o.preWrite = fn }
 }
+
+// WithJSONData fills the template from a JSON document. The top-level object's keys
+// become context variables; keys in the data map passed to Fill take precedence.
+func WithJSONData(data []byte) Option {
+	return func(o *Options) { o.jsonData = data }
+}
+
+// WithDefaults sets values for context variables the data leaves missing or
+// nil, such as a report title. Calling it again adds to the defaults. For a
+// fallback inside an expression, write ${e.Qty | 0}.
+func WithDefaults(defaults map[string]any) Option {
+	return func(o *Options) {
+		if o.defaults == nil {
+			o.defaults = make(map[string]any, len(defaults))
+		}
+		maps.Copy(o.defaults, defaults)
+	}
+}
+
+// WithAreaDefinitions reads the template's commands from a JSON sidecar instead of
+// cell comments, for templates produced by tools that strip comments. The reader
+// holds a JSON array of AreaDefinition values; comments in the template are ignored.
+func WithAreaDefinitions(r io.Reader) Option {
+	return func(o *Options) { o.areaDefinitions = r }
+}
+
+// WithInlineMarkers treats cells whose text is a jx: command, e.g.
+// jx:each(items="emps" var="e" lastCell="C2"), as command markers in addition
+// to comments. Marker cells are cleared from the output.
+func WithInlineMarkers(enabled bool) Option {
+	return func(o *Options) { o.inlineMarkers = enabled }
+}
+
+// WithNamedRangeAreas discovers areas from workbook defined names starting with
+// "jxarea" (e.g. jxarea_1 = Sheet1!$A$1:$C$3) and reads other commands from a
+// "jx_config" sheet: column A names the cell, column B holds the jx: command.
+// The jx_config sheet is removed from the output.
+func WithNamedRangeAreas(enabled bool) Option {
+	return func(o *Options) { o.namedRangeAreas = enabled }
+}
+
+// WithMarkupPrefix sets the prefix of command lines in comments (default:
+// "jx:"), e.g. "gox:" for gox:each(...). Comment lines without the prefix are
+// notes, so comments other tools add are not read as commands. The prefix
+// also applies to inline markers and the jx_config sheet.
+func WithMarkupPrefix(prefix string) Option {
+	return func(o *Options) { o.markupPrefix = prefix }
+}
+
+// WithMarkupAuthor reads commands only from comments whose author is author,
+// compared case-insensitively, e.g. the account that authored the template's
+// markup. Comments of other authors are ignored and kept in the output like
+// any other comment.
+func WithMarkupAuthor(author string) Option {
+	return func(o *Options) { o.markupAuthor = strings.TrimSpace(author) }
+}
+
+// markup returns how commands are told apart from other comment text.
+func (o *Options) markup() markupSyntax {
+	m := markupSyntax{prefix: o.markupPrefix, author: o.markupAuthor}
+	if m.prefix == "" {
+		m.prefix = commandPrefix
+	}
+	return m
+}
+
+// WithTemplateSheets sets what happens to individual sheets after filling, e.g.
+// delete a reusable template sheet while keeping static sheets. Entries override
+// jx:area(templateSheet=...) and the global WithKeepTemplateSheet/WithHideTemplateSheet.
+func WithTemplateSheets(dispositions map[string]SheetDisposition) Option {
+	return func(o *Options) {
+		if o.templateSheets == nil {
+			o.templateSheets = make(map[string]SheetDisposition)
+		}
+		for sheet, d := range dispositions {
+			o.templateSheets[sheet] = d
+		}
+	}
+}
+
+// WithAreaCollisionCheck makes filling fail when the output of one area overlaps
+// the output of another area on the same sheet, e.g. when an area that grows both
+// down and right runs into an area placed diagonally from it. Without the check
+// the later area overwrites the earlier one's cells.
+func WithAreaCollisionCheck(enabled bool) Option {
+	return func(o *Options) { o.areaCollisionCheck = enabled }
+}
+
+// WithProcessFormulasOutsideAreas also rewrites formulas in cells outside every
+// jx:area, e.g. a grand total below an area, so references to cells inside an
+// area cover all of their expanded copies.
+func WithProcessFormulasOutsideAreas(enabled bool) Option {
+	return func(o *Options) { o.outsideFormulas = enabled }
+}
+
+// WithShiftOutsideContent inserts rows into the output for the rows an area
+// grows by, so content below the area that is not part of any area, such as
+// notes or a signature block, moves down instead of being overwritten. The rows
+// span the whole sheet, like inserting rows in Excel, so content beside the
+// area moves down too; formulas, merges and pictures below follow.
+func WithShiftOutsideContent(enabled bool) Option {
+	return func(o *Options) { o.shiftOutside = enabled }
+}
+
+// WithStyles registers named styles for jx:highlight, jx:each stripes, jx:grid
+// and the styled() expression function. A named style is laid over the style a
+// cell already has: fill, font, border, alignment, protection and number format
+// replace the cell's own when set.
+func WithStyles(styles map[string]*excelize.Style) Option {
+	return func(o *Options) {
+		if o.styles == nil {
+			o.styles = make(map[string]*excelize.Style)
+		}
+		for name, style := range styles {
+			o.styles[name] = style
+		}
+	}
+}
+
+// WithStyleFromCell registers the style of a template cell, e.g. "Styles!A1" on a
+// hidden sheet, under name. Only the parts that differ from the workbook's default
+// style are laid over cells, so a cell with just a fill changes only the fill.
+func WithStyleFromCell(name, cell string) Option {
+	return func(o *Options) {
+		if o.styleCells == nil {
+			o.styleCells = make(map[string]string)
+		}
+		o.styleCells[name] = cell
+	}
+}
+
+// WithConcurrency processes independent work on up to n goroutines: areas on
+// different sheets and the sheets of a multisheet jx:each. Expressions are
+// evaluated concurrently while writes to the workbook are applied in the same
+// order as serial processing, so the output does not change. Processing is
+// serial when area listeners are registered. Custom functions and commands must
+// be safe for concurrent use.
+func WithConcurrency(n int) Option {
+	return func(o *Options) { o.concurrency = n }
+}
+
+// WithValueConverter converts the values of template expressions before they
+// are written, for types the application uses throughout its data such as
+// decimal.Decimal, uuid.UUID or nullable wrappers. convert returns the value to
+// write and its cell type, or false to leave v as it is. A nil value with
+// CellBlank leaves the cell blank. Values inside text like "Total: ${x}" are
+// converted before they are formatted.
+func WithValueConverter(convert func(v any) (any, CellType, bool)) Option {
+	return func(o *Options) { o.valueConverter = convert }
+}
+
+// WithLogger logs debug events of area processing to logger: areas
+// discovered, commands bound, areas and commands applied (source, target and
+// size), cells transformed and formulas rewritten. Use a Trace as the handler
+// to render the events as a tree.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Options) { o.logger = logger }
+}
+
+// WithDeterministicOutput makes the output byte-stable for golden-file tests,
+// caching and content-addressed storage: the creation and modification times
+// and the last author in the workbook's document properties are replaced with
+// fixed values, so the output depends only on the template's content and the
+// data. Package entries are always written in a fixed order without
+// timestamps, and generated parts such as comments, styles and names are
+// numbered in template order, also with WithConcurrency.
+func WithDeterministicOutput(enabled bool) Option {
+	return func(o *Options) { o.deterministic = enabled }
+}
+
+// WithOutputLimits aborts a fill that would produce output past row maxRows or
+// column maxCols of a sheet, more than maxSheets sheets in the workbook or more
+// than maxCells cells written, such as a jx:each over an unexpectedly large
+// collection. Zero leaves a limit off. The fill fails with an error wrapping
+// ErrOutputLimit as soon as a limit is passed; multisheet jx:each checks the
+// sheet limit before copying any sheet.
+func WithOutputLimits(maxRows, maxCols, maxSheets, maxCells int) Option {
+	return func(o *Options) { o.outputLimits = outputLimits{maxRows, maxCols, maxSheets, maxCells} }
+}
+
+// WithExpressionLimits bounds the work of each template expression, for
+// templates from untrusted sources. maxOps caps the iterations of map, filter,
+// reduce and the other builtins taking a predicate; maxDepth caps the nesting of
+// the expression's syntax tree, where a + b * c is 3 deep; timeout caps the time
+// spent iterating. Zero leaves a limit off. An expression over a limit fails the
+// fill with an error wrapping ErrExpressionLimit. Limits also cap the length of
+// strings built by iterations or repeat at 1 MB.
+func WithExpressionLimits(maxOps, maxDepth int, timeout time.Duration) Option {
+	return func(o *Options) {
+		s := o.sandboxOpts()
+		s.maxOps, s.maxDepth, s.timeout = maxOps, maxDepth, timeout
+	}
+}
+
+// WithAllowedFunctions lets template expressions call only the named functions:
+// expr builtins such as len or filter, the functions xlfill adds such as
+// hyperlink, and functions in the data.
+func WithAllowedFunctions(names ...string) Option {
+	return func(o *Options) { addNames(&o.sandboxOpts().allowFuncs, names) }
+}
+
+// WithDeniedFunctions keeps template expressions from calling the named
+// functions.
+func WithDeniedFunctions(names ...string) Option {
+	return func(o *Options) { addNames(&o.sandboxOpts().denyFuncs, names) }
+}
+
+// WithAllowedProperties lets template expressions read only the named fields,
+// map keys and methods, as in e.Name or e["Name"]. Member access by a computed
+// name, such as e[key], and the builtins get, keys, values, toPairs and toJSON
+// are rejected while property access is restricted. The property paths of
+// groupBy, orderBy and select, grid props and the field of sum(items, "Field")
+// and the other aggregates are checked too, by name and by the field or method
+// they read, and a grid without props may not read a map with a restricted key.
+func WithAllowedProperties(names ...string) Option {
+	return func(o *Options) { addNames(&o.sandboxOpts().allowProps, names) }
+}
+
+// WithDeniedProperties keeps template expressions from reading the named
+// fields, map keys and methods, with the same restrictions as
+// WithAllowedProperties.
+func WithDeniedProperties(names ...string) Option {
+	return func(o *Options) { addNames(&o.sandboxOpts().denyProps, names) }
+}
+
+// WithSheetNameBuilder sets how the sheets of a multisheet jx:each are named
+// from the requested names, e.g. to truncate differently or number duplicates
+// as "Sales-2". The default is SafeSheetNameBuilder{}.
+func WithSheetNameBuilder(b SheetNameBuilder) Option {
+	return func(o *Options) { o.sheetNameBuilder = b }
+}
+
+// WithSheetData sets variables for individual output sheets, by sheet name.
+// While a sheet is filled, its variables are merged over the data, e.g. the
+// period of each monthly tab generated by a multisheet jx:each. Calling it
+// again adds to the sheets.
+func WithSheetData(data map[string]map[string]any) Option {
+	return func(o *Options) {
+		if o.sheetData == nil {
+			o.sheetData = make(map[string]map[string]any, len(data))
+		}
+		maps.Copy(o.sheetData, data)
+	}
+}
+
+// WithCancel stops the fill with ctx.Err() once ctx is done, e.g. when the
+// HTTP request the workbook is built for goes away.
+func WithCancel(ctx context.Context) Option {
+	return func(o *Options) { o.cancel = ctx }
+}
+
+// WithUsageReport sets report, once the fill is done, to the data keys the
+// template read and left unused and the cell expressions that evaluated to nil,
+// e.g. to fail a CI check when a template and its data drift apart.
+func WithUsageReport(report *UsageReport) Option {
+	return func(o *Options) { o.usageReport = report }
+}
+
+// WithErrorPolicy sets what a fill does when a cell expression fails: stop
+// (FailFast, the default) or write a placeholder into the cell, fill the rest
+// and return every failure as ExpressionErrors (CollectAndContinue).
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(o *Options) { o.errorPolicy = policy }
+}
+
+// WithCellWriter adds middleware around the write of each cell value, e.g. to
+// audit the output, mask personal data or round numbers. The middleware added
+// first is outermost. See CellWrite.
+func WithCellWriter(mw func(next CellWriteFunc) CellWriteFunc) Option {
+	return func(o *Options) { o.cellWriters = append(o.cellWriters, mw) }
+}
+
+// WithRedaction hides personal data in the output: the fields of rules.Columns
+// and of jx:mask commands in the template, and the matches of rules.Pattern.
+// Filling without it produces the full report from the same template.
+func WithRedaction(rules RedactionRules) Option {
+	return func(o *Options) {
+		o.redaction = &rules
+		if rules.Pattern != nil {
+			replacement := rules.Replacement
+			if replacement == "" {
+				replacement = defaultRedaction
+			}
+			o.cellWriters = append(o.cellWriters, patternWriter(rules.Pattern, replacement))
+		}
+	}
+}
+
+// WithSelectBeforeGroup sets whether the select of a jx:each with groupBy
+// filters the items before they are grouped (true, the default, as JXLS 2.12
+// and later) or the groups, with the loop variable bound to each GroupData
+// (false, as older JXLS), for every jx:each of the template. A jx:each with
+// oldSelectBehavior="true" always filters the groups.
+func WithSelectBeforeGroup(before bool) Option {
+	return func(o *Options) { o.selectAfterGroup = !before }
+}
+
+// WithErrorPlaceholder sets the text written into failing cells under
+// CollectAndContinue (default: "#ERR").
+func WithErrorPlaceholder(text string) Option {
+	return func(o *Options) { o.errorPlaceholder = text }
+}
+
+// WithFormulaStrategy registers a custom formula strategy that templates can select
+// with jx:params(formulaStrategy="NAME"), e.g. "BY_GROUP" for per-group subtotals.
+func WithFormulaStrategy(name string, fn FormulaStrategyFunc) Option {
+	return func(o *Options) {
+		if o.formulaStrategies == nil {
+			o.formulaStrategies = make(map[string]FormulaStrategyFunc)
+		}
+		o.formulaStrategies[name] = fn
+	}
+}
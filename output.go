@@ -0,0 +1,163 @@
+package xlfill
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// OutputFilter runs once a fill has written its output, e.g. to render it
+// into another format. Set filters with WithOutputFilters.
+type OutputFilter func(ctx context.Context, out *Output) error
+
+// Output is the workbook a fill wrote, as passed to output filters.
+type Output struct {
+	Data   []byte // the workbook as written
+	Format string // its extension, e.g. ".xlsx" or ".ods"
+
+	dir  string // temporary directory, created on first use
+	path string // Data saved in dir, written on first use
+}
+
+// TempDir returns a temporary directory for the filter's files. It is
+// removed once every filter ran.
+func (o *Output) TempDir() (string, error) {
+	if o.dir == "" {
+		dir, err := os.MkdirTemp("", "xlfill-output-")
+		if err != nil {
+			return "", fmt.Errorf("create temporary directory: %w", err)
+		}
+		o.dir = dir
+	}
+	return o.dir, nil
+}
+
+// Path returns a temporary file holding Data, for renderers that read
+// files. It is removed once every filter ran.
+func (o *Output) Path() (string, error) {
+	if o.path != "" {
+		return o.path, nil
+	}
+	dir, err := o.TempDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "output"+o.Format)
+	if err := os.WriteFile(path, o.Data, 0o600); err != nil {
+		return "", fmt.Errorf("write output: %w", err)
+	}
+	o.path = path
+	return path, nil
+}
+
+// WithOutputFilters runs filters in order after the output is written, e.g.
+// PDF(w, renderer) to also produce a PDF in the same fill. A failing filter
+// fails the fill.
+func WithOutputFilters(filters ...OutputFilter) Option {
+	return func(o *Options) { o.outputFilters = append(o.outputFilters, filters...) }
+}
+
+// Renderer converts the workbook file src into dst, in the format of dst's
+// extension, e.g. "output.pdf". LibreOffice is a Renderer; a client of a
+// conversion service such as Gotenberg is another.
+type Renderer interface {
+	Render(ctx context.Context, src, dst string) error
+}
+
+// RendererFunc adapts a function to a Renderer.
+type RendererFunc func(ctx context.Context, src, dst string) error
+
+func (f RendererFunc) Render(ctx context.Context, src, dst string) error { return f(ctx, src, dst) }
+
+// Convert returns an output filter that renders the output into format, such
+// as "pdf" or "csv", with r and writes the result to w. Temporary files are
+// removed afterwards.
+func Convert(format string, w io.Writer, r Renderer) OutputFilter {
+	format = strings.TrimPrefix(format, ".")
+	return func(ctx context.Context, out *Output) error {
+		src, err := out.Path()
+		if err != nil {
+			return err
+		}
+		dst := strings.TrimSuffix(src, filepath.Ext(src)) + "." + format
+		if err := r.Render(ctx, src, dst); err != nil {
+			return fmt.Errorf("render %s: %w", format, err)
+		}
+		f, err := os.Open(dst)
+		if err != nil {
+			return fmt.Errorf("render %s: %w", format, err)
+		}
+		defer f.Close()
+		if _, err := io.Copy(w, f); err != nil {
+			return fmt.Errorf("write %s: %w", format, err)
+		}
+		return nil
+	}
+}
+
+// PDF returns an output filter writing the output rendered as PDF by r to w.
+func PDF(w io.Writer, r Renderer) OutputFilter {
+	return Convert("pdf", w, r)
+}
+
+// LibreOffice renders workbooks with a local LibreOffice installation,
+// running it headless with a user profile of its own for each conversion, so
+// conversions can run side by side.
+type LibreOffice struct {
+	Binary string // path of soffice (default: "soffice" from PATH)
+}
+
+// Render implements Renderer.
+func (l LibreOffice) Render(ctx context.Context, src, dst string) error {
+	binary := l.Binary
+	if binary == "" {
+		binary = "soffice"
+	}
+	outDir, err := os.MkdirTemp(filepath.Dir(dst), "soffice-")
+	if err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+	profile := url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(outDir, "profile"))}
+
+	format := strings.TrimPrefix(filepath.Ext(dst), ".")
+	cmd := exec.CommandContext(ctx, binary, "-env:UserInstallation="+profile.String(),
+		"--headless", "--convert-to", format, "--outdir", outDir, src)
+	var stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stderr, &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", binary, err, strings.TrimSpace(stderr.String()))
+	}
+	// soffice names the result after its input
+	name := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src)) + "." + format
+	if err := os.Rename(filepath.Join(outDir, name), dst); err != nil {
+		return fmt.Errorf("%s produced no %s: %s", binary, format, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// filterOutput runs the output filters of f on the written output.
+func (f *Filler) filterOutput(data []byte, format string) error {
+	ctx := f.opts.cancel
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := &Output{Data: data, Format: format}
+	defer func() {
+		if out.dir != "" {
+			os.RemoveAll(out.dir)
+		}
+	}()
+	for _, filter := range f.opts.outputFilters {
+		if err := filter(ctx, out); err != nil {
+			return fmt.Errorf("output filter: %w", err)
+		}
+	}
+	return nil
+}
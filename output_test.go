@@ -0,0 +1,94 @@
+package xlfill
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWithOutputFilters(t *testing.T) {
+	tmpl := filepath.Join(testdataDir(t), "output_filters.xlsx")
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${title}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A1")`})
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	// The renderer reads the workbook from a temporary file, removed afterwards
+	var srcPath string
+	renderer := RendererFunc(func(_ context.Context, src, dst string) error {
+		srcPath = src
+		wb, err := excelize.OpenFile(src)
+		if err != nil {
+			return err
+		}
+		defer wb.Close()
+		title, _ := wb.GetCellValue("Sheet1", "A1")
+		return os.WriteFile(dst, []byte("PDF "+title+" "+filepath.Ext(dst)), 0o600)
+	})
+	var pdf bytes.Buffer
+	var format string
+	out := filepath.Join(testdataDir(t), "output_filters_out.xlsx")
+	err := Fill(tmpl, out, map[string]any{"title": "Sales"}, WithOutputFilters(PDF(&pdf, renderer),
+		func(_ context.Context, o *Output) error {
+			format = o.Format
+			return nil
+		}))
+	require.NoError(t, err)
+	assert.Equal(t, "PDF Sales .pdf", pdf.String())
+	assert.Equal(t, ".xlsx", format)
+	assert.Equal(t, "output.xlsx", filepath.Base(srcPath))
+	assert.NoDirExists(t, filepath.Dir(srcPath))
+	assert.FileExists(t, out)
+
+	// A failing filter fails the fill
+	failing := RendererFunc(func(context.Context, string, string) error { return errors.New("renderer down") })
+	_, err = FillBytes(tmpl, map[string]any{"title": "Sales"}, WithOutputFilters(Convert("csv", &pdf, failing)))
+	assert.EqualError(t, err, "output filter: render csv: renderer down")
+}
+
+func TestLibreOffice(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of soffice")
+	}
+	// A stand-in for soffice writing <outdir>/<input name>.<format> and logging its arguments
+	dir := t.TempDir()
+	script := filepath.Join(dir, "soffice")
+	args := filepath.Join(dir, "args")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+args+`
+while [ $# -gt 1 ]; do
+	case "$1" in
+	--convert-to) format=$2 ;;
+	--outdir) outdir=$2 ;;
+	esac
+	shift
+done
+name=$(basename "$1")
+cp "$1" "$outdir/${name%.*}.$format"
+`), 0o755))
+	src := filepath.Join(dir, "report.xlsx")
+	require.NoError(t, os.WriteFile(src, []byte("workbook"), 0o600))
+
+	dst := filepath.Join(dir, "report.pdf")
+	require.NoError(t, LibreOffice{Binary: script}.Render(context.Background(), src, dst))
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "workbook", string(data))
+	logged, err := os.ReadFile(args)
+	require.NoError(t, err)
+	assert.Contains(t, string(logged), "--headless --convert-to pdf --outdir ")
+	assert.True(t, strings.HasPrefix(string(logged), "-env:UserInstallation=file://"))
+
+	err = LibreOffice{Binary: filepath.Join(dir, "missing")}.Render(context.Background(), src, dst)
+	assert.Error(t, err)
+}
@@ -4,11 +4,28 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 const commandPrefix = "jx:"
 const paramsPrefix = "jx:params"
 
+// markupSyntax tells commands apart from other comment text: command lines
+// start with prefix, and when author is set only comments of that author hold
+// commands. Set with WithMarkupPrefix and WithMarkupAuthor.
+type markupSyntax struct {
+	prefix string
+	author string
+}
+
+// defaultMarkup reads jx: commands from comments of any author.
+var defaultMarkup = markupSyntax{prefix: commandPrefix}
+
+// reads reports whether comments of author may hold commands.
+func (m markupSyntax) reads(author string) bool {
+	return m.author == "" || strings.EqualFold(strings.TrimSpace(author), m.author)
+}
+
 // ParsedCommand represents a parsed jx: command from a cell comment.
 type ParsedCommand struct {
 	Name     string            // command name (e.g., "each", "if", "area")
@@ -16,36 +33,34 @@ type ParsedCommand struct {
 	LastCell CellRef           // parsed lastCell attribute
 	Areas    []AreaRef         // parsed areas attribute (optional)
 	CellRef  CellRef           // cell containing this comment
+	RenderIf string            // universal renderIf condition (optional)
 }
 
-// attrKeyPattern matches the key= part of an attribute to find the start of each attribute.
-var attrKeyPattern = regexp.MustCompile(`(\w+)\s*=\s*`)
-
-// areasPattern matches the areas=[...] attribute.
-var areasPattern = regexp.MustCompile(`areas\s*=\s*\[([^\]]*)\]`)
-
-// areaRefPattern matches cell range references like "A1:C5" or "Sheet1!A1:C5".
-var areaRefPattern = regexp.MustCompile(`[A-Za-z0-9_!'.]+:[A-Za-z0-9_!'.]+`)
+// areaRefPattern matches cell range references like "A1:C5", "Sheet1!A1:C5" or
+// "'Else Sheet'!A1:C5".
+var areaRefPattern = regexp.MustCompile(`(?:'[^']+'!|[A-Za-z0-9_.]+!)?\$?[A-Za-z]{1,3}\$?[0-9]+:\$?[A-Za-z]{1,3}\$?[0-9]+`)
 
 // ParseComment parses all jx: commands from a cell comment.
-// A comment may contain multiple commands (one per line).
+// A comment may contain multiple commands, each starting on its own line. A
+// command continues over the following lines until its parentheses close, so
+// attribute values may span lines.
 func ParseComment(comment string, cellRef CellRef) ([]ParsedCommand, *ParamsData, error) {
+	return defaultMarkup.parse(comment, cellRef)
+}
+
+// parse parses the commands of a comment, like ParseComment, with the prefix of m.
+func (m markupSyntax) parse(comment string, cellRef CellRef) ([]ParsedCommand, *ParamsData, error) {
 	if comment == "" {
 		return nil, nil, nil
 	}
 
-	lines := splitCommentLines(comment)
+	markup, _ := m.split(comment)
 	var commands []ParsedCommand
 	var params *ParamsData
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		if IsParams(line) {
-			p, err := ParseParams(line)
+	for _, text := range markup {
+		if strings.HasPrefix(text, m.prefix+"params") {
+			p, err := ParseParams(text)
 			if err != nil {
 				return nil, nil, fmt.Errorf("parse params at %s: %w", cellRef, err)
 			}
@@ -53,11 +68,7 @@ func ParseComment(comment string, cellRef CellRef) ([]ParsedCommand, *ParamsData
 			continue
 		}
 
-		if !IsCommand(line) {
-			continue
-		}
-
-		cmd, err := parseCommandLine(line, cellRef)
+		cmd, err := parseCommandLine(text, m.prefix, cellRef)
 		if err != nil {
 			return nil, nil, fmt.Errorf("parse command at %s: %w", cellRef, err)
 		}
@@ -74,6 +85,31 @@ func splitCommentLines(comment string) []string {
 	return strings.Split(comment, "\n")
 }
 
+// split separates the commands of a comment, each with its continuation
+// lines, from the lines of plain note text.
+func (m markupSyntax) split(comment string) (markup, note []string) {
+	var current []string
+	for _, line := range splitCommentLines(comment) {
+		if current == nil {
+			trimmed := strings.TrimSpace(line)
+			if !strings.HasPrefix(trimmed, m.prefix) {
+				note = append(note, line)
+				continue
+			}
+			line = trimmed
+		}
+		current = append(current, line)
+		if text := strings.Join(current, "\n"); commandEnd(text) >= 0 {
+			markup = append(markup, text)
+			current = nil
+		}
+	}
+	if current != nil {
+		markup = append(markup, strings.Join(current, "\n"))
+	}
+	return markup, note
+}
+
 // IsCommand returns true if the line starts with "jx:" and is not "jx:params".
 func IsCommand(line string) bool {
 	trimmed := strings.TrimSpace(line)
@@ -85,26 +121,62 @@ func IsParams(line string) bool {
 	return strings.HasPrefix(strings.TrimSpace(line), paramsPrefix)
 }
 
-// parseCommandLine parses a single command line like:
+// commandEnd returns the index of the ')' closing the first '(' of a command,
+// skipping quoted values, or -1 when the parentheses are not closed.
+func commandEnd(text string) int {
+	depth := 0
+	var quote rune
+	escaped := false
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if r == '\\' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case isQuote(r):
+			quote = matchingCloseQuote(r)
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseCommandLine parses a single command starting with prefix, like:
 // jx:each(items="employees" var="e" lastCell="C2")
-func parseCommandLine(line string, cellRef CellRef) (ParsedCommand, error) {
+func parseCommandLine(line, prefix string, cellRef CellRef) (ParsedCommand, error) {
 	// Extract command name
-	nameStart := len(commandPrefix)
+	nameStart := len(prefix)
 	parenIdx := strings.Index(line, "(")
-	if parenIdx < 0 {
+	if parenIdx < nameStart {
 		return ParsedCommand{}, fmt.Errorf("missing '(' in command: %q", line)
 	}
 	name := strings.TrimSpace(line[nameStart:parenIdx])
 
-	// Extract attributes string between ( and )
-	closeIdx := strings.LastIndex(line, ")")
+	// Extract attributes string between ( and the matching )
+	closeIdx := commandEnd(line)
 	if closeIdx < 0 {
 		return ParsedCommand{}, fmt.Errorf("missing ')' in command: %q", line)
 	}
+	if rest := strings.TrimSpace(line[closeIdx+1:]); rest != "" {
+		return ParsedCommand{}, fmt.Errorf("unexpected %q after %s command (start each command on its own line): %q", rest, name, line)
+	}
 	attrStr := line[parenIdx+1 : closeIdx]
 
 	// Parse attributes
-	attrs := parseAttributes(attrStr)
+	attrs, err := parseAttributes(attrStr)
+	if err != nil {
+		return ParsedCommand{}, fmt.Errorf("%s command: %w", name, err)
+	}
 
 	// Extract lastCell
 	lastCellStr, hasLastCell := attrs["lastCell"]
@@ -127,10 +199,8 @@ func parseCommandLine(line string, cellRef CellRef) (ParsedCommand, error) {
 
 	// Extract areas attribute
 	var areas []AreaRef
-	areasMatch := areasPattern.FindStringSubmatch(attrStr)
-	if len(areasMatch) > 1 {
-		areaRefs := areaRefPattern.FindAllString(areasMatch[1], -1)
-		for _, ar := range areaRefs {
+	if list := attrs["areas"]; strings.HasPrefix(list, "[") {
+		for _, ar := range areaRefPattern.FindAllString(list, -1) {
 			areaRef, err := ParseAreaRef(ar)
 			if err != nil {
 				return ParsedCommand{}, fmt.Errorf("invalid area ref %q: %w", ar, err)
@@ -150,6 +220,7 @@ func parseCommandLine(line string, cellRef CellRef) (ParsedCommand, error) {
 		LastCell: lastCell,
 		Areas:    areas,
 		CellRef:  cellRef,
+		RenderIf: attrs["renderIf"],
 	}, nil
 }
 
@@ -175,49 +246,97 @@ func matchingCloseQuote(open rune) rune {
 }
 
 // parseAttributes extracts key="value" pairs from an attribute string.
-// Values are delimited by matching quotes: the closing quote must be the same type
-// as the opening quote. This allows single quotes inside double-quoted values
-// (e.g., select="e.city == 'Geldern'").
-func parseAttributes(attrStr string) map[string]string {
+// Attributes are separated by whitespace, newlines or commas. A value is
+// either quoted, a [...] list such as areas=["A2:C2","A3:C3"], kept as written,
+// or a bare word. In a quoted value the closing quote must be the same type as
+// the opening one, so single quotes can be used inside double-quoted values
+// (e.g., select="e.city == 'Geldern'"), and a backslash escapes the closing
+// quote or a backslash: select="e.Name == \"O'Brien (Sales)\"".
+func parseAttributes(attrStr string) (map[string]string, error) {
 	attrs := make(map[string]string)
 	runes := []rune(attrStr)
 	i := 0
-	for i < len(runes) {
-		// Find key=
-		loc := attrKeyPattern.FindStringIndex(string(runes[i:]))
-		if loc == nil {
-			break
-		}
-		m := attrKeyPattern.FindStringSubmatch(string(runes[i:]))
-		key := m[1]
-		i += loc[1] // advance past "key="
-
-		// Expect an opening quote
-		if i >= len(runes) || !isQuote(runes[i]) {
-			continue
+	for {
+		for i < len(runes) && (unicode.IsSpace(runes[i]) || runes[i] == ',') {
+			i++
+		}
+		if i >= len(runes) {
+			return attrs, nil
 		}
-		openQuote := runes[i]
-		closeQuote := matchingCloseQuote(openQuote)
-		i++ // skip opening quote
 
-		// Read until matching close quote
+		// Key, then =
 		start := i
-		for i < len(runes) && runes[i] != closeQuote {
+		for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+			i++
+		}
+		key := string(runes[start:i])
+		if key == "" {
+			return nil, fmt.Errorf("unexpected %q in attributes at offset %d", runes[i], start)
+		}
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		if i >= len(runes) || runes[i] != '=' {
+			return nil, fmt.Errorf("attribute %q has no value", key)
+		}
+		i++
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
 			i++
 		}
-		value := string(runes[start:i])
-		if i < len(runes) {
+		if i >= len(runes) {
+			return nil, fmt.Errorf("attribute %q has no value", key)
+		}
+
+		// Value
+		var value string
+		switch {
+		case isQuote(runes[i]):
+			closeQuote := matchingCloseQuote(runes[i])
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != closeQuote; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && (runes[i+1] == closeQuote || runes[i+1] == '\\') {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("attribute %q: missing closing %c", key, closeQuote)
+			}
 			i++ // skip closing quote
+			value = sb.String()
+		case runes[i] == '[':
+			start = i
+			var quote rune
+			for ; i < len(runes) && (quote != 0 || runes[i] != ']'); i++ {
+				switch {
+				case quote != 0 && runes[i] == quote:
+					quote = 0
+				case quote == 0 && isQuote(runes[i]):
+					quote = matchingCloseQuote(runes[i])
+				}
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("attribute %q: missing closing ]", key)
+			}
+			i++
+			value = string(runes[start:i])
+		default:
+			start = i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != ',' {
+				i++
+			}
+			value = string(runes[start:i])
 		}
 		attrs[key] = value
 	}
-	return attrs
 }
 
 // ParamsData holds parsed jx:params attributes.
 type ParamsData struct {
-	FormulaStrategy FormulaStrategy
-	DefaultValue    string
+	FormulaStrategy     FormulaStrategy
+	FormulaStrategyName string // custom strategy name when not a built-in one
+	DefaultValue        string
 }
 
 // ParseParams parses a jx:params line.
@@ -226,12 +345,18 @@ func ParseParams(line string) (*ParamsData, error) {
 	if parenIdx < 0 {
 		return &ParamsData{}, nil
 	}
-	closeIdx := strings.LastIndex(line, ")")
+	closeIdx := commandEnd(line)
 	if closeIdx < 0 {
 		return nil, fmt.Errorf("missing ')' in params: %q", line)
 	}
+	if rest := strings.TrimSpace(line[closeIdx+1:]); rest != "" {
+		return nil, fmt.Errorf("unexpected %q after params (start each command on its own line): %q", rest, line)
+	}
 	attrStr := line[parenIdx+1 : closeIdx]
-	attrs := parseAttributes(attrStr)
+	attrs, err := parseAttributes(attrStr)
+	if err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
 
 	pd := &ParamsData{}
 
@@ -245,8 +370,11 @@ func ParseParams(line string) (*ParamsData, error) {
 			pd.FormulaStrategy = FormulaByColumn
 		case "BY_ROW":
 			pd.FormulaStrategy = FormulaByRow
+		case "", "DEFAULT":
+			pd.FormulaStrategy = FormulaDefault
 		default:
 			pd.FormulaStrategy = FormulaDefault
+			pd.FormulaStrategyName = strings.ToUpper(fs)
 		}
 	}
 
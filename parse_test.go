@@ -231,6 +231,18 @@ func TestParseParams_Both(t *testing.T) {
 	assert.Equal(t, FormulaByRow, params.FormulaStrategy)
 }
 
+func TestParseParams_CustomFormulaStrategy(t *testing.T) {
+	_, params, err := ParseComment(`jx:params(formulaStrategy="by_group")`, cell("S", 0, 0))
+	require.NoError(t, err)
+	require.NotNil(t, params)
+	assert.Equal(t, FormulaDefault, params.FormulaStrategy)
+	assert.Equal(t, "BY_GROUP", params.FormulaStrategyName)
+
+	_, params, err = ParseComment(`jx:params(formulaStrategy="BY_ROW")`, cell("S", 0, 0))
+	require.NoError(t, err)
+	assert.Empty(t, params.FormulaStrategyName)
+}
+
 func TestParseComment_CommandAndParams(t *testing.T) {
 	comment := "jx:each(items=\"list\" var=\"e\" lastCell=\"C2\")\njx:params(defaultValue=\"1\")"
 	cmds, params, err := ParseComment(comment, cell("S", 0, 0))
@@ -257,3 +269,86 @@ func TestParseComment_MultiLine(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, cmds, 2)
 }
+
+func TestParseComment_RenderIf(t *testing.T) {
+	cmds, _, err := ParseComment(`jx:image(src="e.Logo" renderIf="e.Logo != nil" lastCell="A1")`, cell("S", 0, 0))
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, "e.Logo != nil", cmds[0].RenderIf)
+}
+
+func TestParseComment_EscapedQuotes(t *testing.T) {
+	cmds, _, err := ParseComment(
+		`jx:each(items="employees" var="e" select="e.Name == \"O'Brien (Sales)\" || e.Path == 'C:\\tmp'" lastCell="C2")`,
+		cell("S", 0, 0),
+	)
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, `e.Name == "O'Brien (Sales)" || e.Path == 'C:\tmp'`, cmds[0].Attrs["select"])
+	assert.Equal(t, "C2", cmds[0].Attrs["lastCell"])
+
+	cmds, _, err = ParseComment(`jx:if(condition='e.Note != \'n/a\'' lastCell="A1")`, cell("S", 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, `e.Note != 'n/a'`, cmds[0].Attrs["condition"])
+}
+
+func TestParseComment_MultiLineValues(t *testing.T) {
+	comment := "Totals per region\n" +
+		"jx:area(lastCell=\"C5\")\n" +
+		"jx:each(items=\"employees\" var=\"e\"\n" +
+		"  select=\"e.Active &&\n    e.Salary > 100\"\n" +
+		"  lastCell=\"C2\")\n" +
+		"jx:params(defaultValue=\"-\")"
+	cmds, params, err := ParseComment(comment, cell("S", 0, 0))
+	require.NoError(t, err)
+	require.Len(t, cmds, 2)
+	assert.Equal(t, "each", cmds[1].Name)
+	assert.Equal(t, "e.Active &&\n    e.Salary > 100", cmds[1].Attrs["select"])
+	assert.Equal(t, 1, cmds[1].LastCell.Row)
+	require.NotNil(t, params)
+	assert.Equal(t, "-", params.DefaultValue)
+
+	note, markup := defaultMarkup.note(comment)
+	assert.True(t, markup)
+	assert.Equal(t, "Totals per region", note)
+}
+
+func TestParseComment_SyntaxErrors(t *testing.T) {
+	for _, comment := range []string{
+		`jx:each(items="employees var="e" lastCell="C2")`,
+		`jx:each(items="employees" var="e" lastCell="C2"`,
+		`jx:if(condition="x" areas=["A1:A1" lastCell="A1")`,
+		`jx:each(items lastCell="C2")`,
+		`jx:each(items="a" ! lastCell="C2")`,
+	} {
+		_, _, err := ParseComment(comment, cell("S", 0, 0))
+		assert.Error(t, err, comment)
+	}
+}
+
+func TestParseComment_TextAfterCommand(t *testing.T) {
+	_, _, err := ParseComment(`jx:if(condition="x" lastCell="C2") jx:each(items="e" var="e" lastCell="C2")`, cell("S", 0, 0))
+	assert.ErrorContains(t, err, `unexpected "jx:each(items=\"e\" var=\"e\" lastCell=\"C2\")" after if command`)
+
+	_, _, err = ParseComment(`jx:params(defaultValue="0") x`, cell("S", 0, 0))
+	assert.ErrorContains(t, err, `unexpected "x" after params`)
+
+	cmds, _, err := ParseComment("jx:area(lastCell=\"C2\")  \n", cell("S", 0, 0))
+	require.NoError(t, err)
+	assert.Len(t, cmds, 1)
+}
+
+func TestMarkupSyntax_Parse(t *testing.T) {
+	m := markupSyntax{prefix: "gox:"}
+	cmds, params, err := m.parse("Check totals\ngox:each(items=\"e\" var=\"x\" lastCell=\"B2\")\njx:if(condition=\"x\" lastCell=\"B2\")\ngox:params(defaultValue=\"1\")", cell("S", 0, 0))
+	require.NoError(t, err)
+	require.Len(t, cmds, 1)
+	assert.Equal(t, "each", cmds[0].Name)
+	require.NotNil(t, params)
+	assert.Equal(t, "1", params.DefaultValue)
+
+	assert.True(t, m.reads("anyone"))
+	m.author = "xlfill"
+	assert.True(t, m.reads(" XLFill "))
+	assert.False(t, m.reads("Designer"))
+}
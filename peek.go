@@ -0,0 +1,198 @@
+package xlfill
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm/runtime"
+)
+
+// Header cells often show an item of the list a jx:each below them repeats,
+// as in ${employees[0].Name}, ${first(employees).Name} or
+// ${last(employees).Date}. expr indexes slices and arrays itself; the
+// itemAccess patch routes these forms through itemFunctions so that they read
+// lazy Iterators too. The items of an Iterator read ahead of its jx:each are
+// buffered and replayed, so the jx:each still sees every item.
+
+// peeksVar names the fill's iterator buffers in the expression environment.
+const peeksVar = "$peeks"
+
+// itemFunctions are the functions the itemAccess patch calls.
+var itemFunctions = []expr.Option{
+	expr.Function("$first", func(params ...any) (any, error) { return edgeItem("first", params[0], params[1], 0) }),
+	expr.Function("$last", func(params ...any) (any, error) { return edgeItem("last", params[0], params[1], -1) }),
+	expr.Function("$index", func(params ...any) (any, error) { return indexItem(params[0], params[1], params[2]) }),
+}
+
+// itemAccess rewrites first(items), last(items) and items[i] into calls of
+// itemFunctions. Property access (items.Name, items["Name"]) and optional
+// indexing (items?.[0]) are left to expr.
+type itemAccess struct{}
+
+func (itemAccess) Visit(node *ast.Node) {
+	peeks := &ast.IdentifierNode{Value: peeksVar}
+	switch n := (*node).(type) {
+	case *ast.BuiltinNode:
+		if (n.Name == "first" || n.Name == "last") && len(n.Arguments) == 1 {
+			ast.Patch(node, &ast.CallNode{Callee: &ast.IdentifierNode{Value: "$" + n.Name}, Arguments: []ast.Node{peeks, n.Arguments[0]}})
+		}
+	case *ast.MemberNode:
+		if _, ok := n.Property.(*ast.StringNode); ok || n.Optional {
+			return
+		}
+		ast.Patch(node, &ast.CallNode{Callee: &ast.IdentifierNode{Value: "$index"}, Arguments: []ast.Node{peeks, n.Node, n.Property}})
+	}
+}
+
+// edgeItem returns the item of items at i, 0 or -1, or nil when there are none.
+func edgeItem(name string, peeks, items any, i int) (any, error) {
+	if it, ok := items.(Iterator); ok {
+		v, _, err := peekIterator(peeks, it, i)
+		return v, err
+	}
+	if items == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%s: cannot take an item of %T", name, items)
+	}
+	if v.Len() == 0 {
+		return nil, nil
+	}
+	if i < 0 {
+		i += v.Len()
+	}
+	return v.Index(i).Interface(), nil
+}
+
+// indexItem returns items[index] like expr does, reading Iterators through
+// the fill's buffers.
+func indexItem(peeks, items, index any) (any, error) {
+	it, ok := items.(Iterator)
+	if !ok {
+		return runtime.Fetch(items, index), nil
+	}
+	i, ok := index.(int)
+	if !ok {
+		return nil, fmt.Errorf("cannot index an Iterator with %v (%T)", index, index)
+	}
+	v, found, err := peekIterator(peeks, it, i)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("index out of range: %d", i)
+	}
+	return v, nil
+}
+
+// peekIterator returns item i of it, counting from the end when negative.
+func peekIterator(peeks any, it Iterator, i int) (any, bool, error) {
+	p, ok := peeks.(*iteratorPeeks)
+	if !ok || !reflect.TypeOf(it).Comparable() {
+		return nil, false, fmt.Errorf("cannot read ahead in %T", it)
+	}
+	b := p.buffer(it)
+	if i >= 0 {
+		return b.at(i)
+	}
+	if _, _, err := b.at(math.MaxInt); err != nil {
+		return nil, false, err
+	}
+	return b.at(b.len() + i)
+}
+
+// iteratorPeeks buffers the items of Iterators read ahead of their jx:each.
+type iteratorPeeks struct {
+	mu   sync.Mutex
+	bufs map[Iterator]*peekBuffer
+}
+
+// buffer returns the buffer of it, creating it on first use.
+func (p *iteratorPeeks) buffer(it Iterator) *peekBuffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.bufs == nil {
+		p.bufs = make(map[Iterator]*peekBuffer)
+	}
+	b, ok := p.bufs[it]
+	if !ok {
+		b = &peekBuffer{src: it}
+		p.bufs[it] = b
+	}
+	return b
+}
+
+// replay returns an Iterator over all items of it from the first one when
+// items of it were read ahead, or it itself.
+func (p *iteratorPeeks) replay(it Iterator) Iterator {
+	if !reflect.TypeOf(it).Comparable() {
+		return it
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b, ok := p.bufs[it]
+	if !ok {
+		return it
+	}
+	return &replayIterator{buf: b, pos: -1}
+}
+
+// peekBuffer holds the items read from src so far.
+type peekBuffer struct {
+	mu    sync.Mutex
+	src   Iterator
+	items []any
+	done  bool
+}
+
+// at returns item i, reading src up to it; found is false past the last item.
+func (b *peekBuffer) at(i int) (item any, found bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i < 0 {
+		return nil, false, nil
+	}
+	for len(b.items) <= i && !b.done {
+		if !b.src.Next() {
+			b.done = true
+			break
+		}
+		b.items = append(b.items, b.src.Value())
+	}
+	if i < len(b.items) {
+		return b.items[i], true, nil
+	}
+	return nil, false, b.src.Err()
+}
+
+// len returns the number of items read so far.
+func (b *peekBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.items)
+}
+
+// replayIterator iterates a peekBuffer from its first item.
+type replayIterator struct {
+	buf *peekBuffer
+	pos int
+	cur any
+	err error
+}
+
+func (r *replayIterator) Next() bool {
+	r.pos++
+	v, ok, err := r.buf.at(r.pos)
+	r.cur, r.err = v, err
+	return ok
+}
+
+func (r *replayIterator) Value() any { return r.cur }
+
+func (r *replayIterator) Err() error { return r.err }
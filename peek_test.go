@@ -0,0 +1,92 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestItemAccess_HeaderCells(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${first(employees).Name}")
+	f.SetCellValue("Sheet1", "B1", "${employees[1].Name}")
+	f.SetCellValue("Sheet1", "C1", "${last(employees).Name}")
+	f.SetCellValue("Sheet1", "D1", "${employees[-1].Age}")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="D2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="employees" var="e" lastCell="A2")`})
+	tmpl := filepath.Join(testdataDir(t), "item_access.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	want := [][]string{{"Alice", "Bob", "Carol", "35"}, {"Alice"}, {"Bob"}, {"Carol"}}
+	slice := []map[string]any{{"Name": "Alice", "Age": 30}, {"Name": "Bob", "Age": 25}, {"Name": "Carol", "Age": 35}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		// Items of an Iterator read by the header are replayed for the jx:each
+		rows := employeeRows()
+		out, err := FillBytes(tmpl, map[string]any{"employees": RowsItems(rows)}, opts...)
+		require.NoError(t, err)
+		assert.Equal(t, 3, rows.scanned)
+		got, err := openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		out, err = FillBytes(tmpl, map[string]any{"employees": slice}, opts...)
+		require.NoError(t, err)
+		got, err = openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestItemAccess_Expressions(t *testing.T) {
+	ctx := NewContext(map[string]any{
+		"empty": []any{},
+		"nums":  []int{1, 2, 3},
+		"m":     map[string]any{"k": "v"},
+		"rows":  RowsItems(employeeRows()),
+		"none":  RowsItems(&fakeRows{columns: []string{"Name"}}),
+	})
+	for expr, want := range map[string]any{
+		"first(empty)":      nil,
+		"last(empty)":       nil,
+		"first(nums)":       1,
+		"last(nums)":        3,
+		"nums[-2]":          2,
+		`m["k"]`:            "v",
+		"first(none)":       nil,
+		"last(rows).Name":   "Carol",
+		"rows[0].Name":      "Alice",
+		"first(rows).Name":  "Alice",
+		"rows[-3].Age":      int64(30),
+		"len(nums[1:])":     2,
+		`first(["a", "b"])`: "a",
+	} {
+		got, err := ctx.Evaluate(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, want, got, expr)
+	}
+
+	_, err := ctx.Evaluate("rows[3]")
+	assert.ErrorContains(t, err, "index out of range: 3")
+	_, err = ctx.Evaluate(`rows["Name"]`)
+	assert.Error(t, err)
+	_, err = ctx.Evaluate("first(m)")
+	assert.ErrorContains(t, err, "cannot take an item of map[string]interface {}")
+
+	// The buffered items are replayed from the first one
+	v, err := ctx.Evaluate("rows")
+	require.NoError(t, err)
+	it := v.(Iterator)
+	var names []any
+	for it.Next() {
+		names = append(names, it.Value().(map[string]any)["Name"])
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, []any{"Alice", "Bob", "Carol"}, names)
+}
@@ -0,0 +1,266 @@
+package xlfill
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PivotCommand implements the jx:pivot command for rendering a crosstab.
+// It groups a flat collection by a row key and a column key and renders the
+// row keys down the first column, the column keys across the first row and
+// the aggregated values in the grid between them.
+type PivotCommand struct {
+	Items    string // expression for the collection (e.g., "sales")
+	Var      string // loop variable name (default: prefix of rowKey, e.g. "e")
+	RowKey   string // expression for the row key (e.g., "e.Region")
+	ColKey   string // expression for the column key (e.g., "e.Month")
+	Value    string // aggregate expression: sum(...), avg(...), min(...), max(...), count(...)
+	Corner   string // optional text for the top-left header cell
+	RowOrder string // "ASC" or "DESC" to sort row keys (default: first-seen order)
+	ColOrder string // "ASC" or "DESC" to sort column keys (default: first-seen order)
+
+	aggFunc  string // parsed aggregate function name
+	aggValue string // parsed aggregate argument expression
+}
+
+func (c *PivotCommand) Name() string { return "pivot" }
+func (c *PivotCommand) Reset()       {}
+
+// pivotAggregates lists the supported aggregate functions for the value attribute.
+var pivotAggregates = map[string]bool{"sum": true, "avg": true, "min": true, "max": true, "count": true}
+
+// newPivotCommandFromAttrs creates a PivotCommand from parsed attributes.
+func newPivotCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &PivotCommand{
+		Items:    attrs["items"],
+		Var:      attrs["var"],
+		RowKey:   attrs["rowKey"],
+		ColKey:   attrs["colKey"],
+		Value:    attrs["value"],
+		Corner:   attrs["corner"],
+		RowOrder: attrs["rowOrder"],
+		ColOrder: attrs["colOrder"],
+	}
+	if cmd.Items == "" {
+		return nil, fmt.Errorf("pivot command requires 'items' attribute")
+	}
+	if cmd.RowKey == "" {
+		return nil, fmt.Errorf("pivot command requires 'rowKey' attribute")
+	}
+	if cmd.ColKey == "" {
+		return nil, fmt.Errorf("pivot command requires 'colKey' attribute")
+	}
+	if cmd.Value == "" {
+		return nil, fmt.Errorf("pivot command requires 'value' attribute")
+	}
+	if cmd.Var == "" {
+		if idx := strings.Index(cmd.RowKey, "."); idx > 0 {
+			cmd.Var = cmd.RowKey[:idx]
+		} else {
+			return nil, fmt.Errorf("pivot command requires 'var' attribute")
+		}
+	}
+	fn, arg, err := parsePivotValue(cmd.Value)
+	if err != nil {
+		return nil, err
+	}
+	cmd.aggFunc, cmd.aggValue = fn, arg
+	return cmd, nil
+}
+
+// parsePivotValue splits "sum(e.Amount)" into ("sum", "e.Amount").
+// A bare expression is summed.
+func parsePivotValue(value string) (string, string, error) {
+	value = strings.TrimSpace(value)
+	open := strings.Index(value, "(")
+	if open > 0 && strings.HasSuffix(value, ")") {
+		fn := strings.ToLower(strings.TrimSpace(value[:open]))
+		if pivotAggregates[fn] {
+			return fn, strings.TrimSpace(value[open+1 : len(value)-1]), nil
+		}
+		if isIdentifier(fn) {
+			return "", "", fmt.Errorf("pivot value: unsupported aggregate function %q", fn)
+		}
+	}
+	return "sum", value, nil
+}
+
+// isIdentifier reports whether s is a plain identifier like "sum" or "myFunc".
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// pivotCell accumulates the values of one row/column intersection.
+type pivotCell struct {
+	sum   float64
+	min   float64
+	max   float64
+	count int
+}
+
+func (p *pivotCell) add(v float64) {
+	if p.count == 0 || v < p.min {
+		p.min = v
+	}
+	if p.count == 0 || v > p.max {
+		p.max = v
+	}
+	p.sum += v
+	p.count++
+}
+
+func (p *pivotCell) result(fn string) any {
+	switch fn {
+	case "count":
+		return p.count
+	case "avg":
+		return p.sum / float64(p.count)
+	case "min":
+		return p.min
+	case "max":
+		return p.max
+	default:
+		return p.sum
+	}
+}
+
+// ApplyAt computes the crosstab and renders it at the given target cell.
+func (c *PivotCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	if c.aggFunc == "" {
+		fn, arg, err := parsePivotValue(c.Value)
+		if err != nil {
+			return ZeroSize, err
+		}
+		c.aggFunc, c.aggValue = fn, arg
+	}
+
+	itemsVal, err := ctx.Evaluate(c.Items)
+	if err != nil {
+		return ZeroSize, fmt.Errorf("evaluate items %q: %w", c.Items, err)
+	}
+	items, err := toSlice(itemsVal)
+	if err != nil {
+		return ZeroSize, fmt.Errorf("items %q is not iterable: %w", c.Items, err)
+	}
+	if len(items) == 0 {
+		return ZeroSize, nil
+	}
+
+	var rowKeys, colKeys []any
+	rowIndex := map[string]int{}
+	colIndex := map[string]int{}
+	cells := map[[2]string]*pivotCell{}
+
+	for i, item := range items {
+		rowKey, colKey, val, err := c.evaluateItem(ctx.WithVar(c.Var, item))
+		if err != nil {
+			return ZeroSize, fmt.Errorf("pivot item %d: %w", i, err)
+		}
+
+		rk, ck := fmt.Sprintf("%v", rowKey), fmt.Sprintf("%v", colKey)
+		if _, ok := rowIndex[rk]; !ok {
+			rowIndex[rk] = len(rowKeys)
+			rowKeys = append(rowKeys, rowKey)
+		}
+		if _, ok := colIndex[ck]; !ok {
+			colIndex[ck] = len(colKeys)
+			colKeys = append(colKeys, colKey)
+		}
+
+		cell, ok := cells[[2]string{rk, ck}]
+		if !ok {
+			cell = &pivotCell{}
+			cells[[2]string{rk, ck}] = cell
+		}
+		cell.add(val)
+	}
+
+	sortPivotKeys(rowKeys, c.RowOrder)
+	sortPivotKeys(colKeys, c.ColOrder)
+
+	set := func(ref CellRef, value any) error {
+		if err := transformer.SetCellValue(ref, value); err != nil {
+			return fmt.Errorf("pivot cell %s: %w", ref, err)
+		}
+		return nil
+	}
+
+	// Header row
+	if c.Corner != "" {
+		if err := set(cellRef, c.Corner); err != nil {
+			return ZeroSize, err
+		}
+	}
+	for j, ck := range colKeys {
+		if err := set(NewCellRef(cellRef.Sheet, cellRef.Row, cellRef.Col+1+j), ck); err != nil {
+			return ZeroSize, err
+		}
+	}
+
+	// Row headers and values
+	for i, rk := range rowKeys {
+		row := cellRef.Row + 1 + i
+		if err := set(NewCellRef(cellRef.Sheet, row, cellRef.Col), rk); err != nil {
+			return ZeroSize, err
+		}
+		for j, ck := range colKeys {
+			cell, ok := cells[[2]string{fmt.Sprintf("%v", rk), fmt.Sprintf("%v", ck)}]
+			if !ok {
+				continue
+			}
+			if err := set(NewCellRef(cellRef.Sheet, row, cellRef.Col+1+j), cell.result(c.aggFunc)); err != nil {
+				return ZeroSize, err
+			}
+		}
+	}
+
+	return Size{Width: len(colKeys) + 1, Height: len(rowKeys) + 1}, nil
+}
+
+// evaluateItem evaluates the row key, column key and numeric value for the current item.
+func (c *PivotCommand) evaluateItem(ctx *Context) (any, any, float64, error) {
+	rowKey, err := ctx.Evaluate(c.RowKey)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("evaluate rowKey %q: %w", c.RowKey, err)
+	}
+	colKey, err := ctx.Evaluate(c.ColKey)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("evaluate colKey %q: %w", c.ColKey, err)
+	}
+	if c.aggFunc == "count" {
+		return rowKey, colKey, 0, nil
+	}
+	val, err := ctx.Evaluate(c.aggValue)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("evaluate value %q: %w", c.aggValue, err)
+	}
+	if val == nil {
+		return rowKey, colKey, 0, nil
+	}
+	f, ok := toFloat64(val)
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("value %q evaluated to %T, expected a number", c.aggValue, val)
+	}
+	return rowKey, colKey, f, nil
+}
+
+// sortPivotKeys sorts keys in place when order is "ASC" or "DESC".
+func sortPivotKeys(keys []any, order string) {
+	if order == "" {
+		return
+	}
+	desc := strings.EqualFold(order, "DESC")
+	sort.SliceStable(keys, func(i, j int) bool {
+		return compareGroupKeys(keys[i], keys[j], desc, false) < 0
+	})
+}
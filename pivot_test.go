@@ -0,0 +1,156 @@
+package xlfill
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func pivotSales() []any {
+	return []any{
+		map[string]any{"Region": "North", "Month": "Jan", "Amount": 100},
+		map[string]any{"Region": "South", "Month": "Jan", "Amount": 50},
+		map[string]any{"Region": "North", "Month": "Feb", "Amount": 70},
+		map[string]any{"Region": "North", "Month": "Jan", "Amount": 30},
+		map[string]any{"Region": "East", "Month": "Feb", "Amount": 20},
+	}
+}
+
+func TestPivotCommand_Sum(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	cmd, err := newPivotCommandFromAttrs(map[string]string{
+		"items": "sales", "rowKey": "e.Region", "colKey": "e.Month",
+		"value": "sum(e.Amount)", "corner": "Region",
+	})
+	require.NoError(t, err)
+
+	ctx := NewContext(map[string]any{"sales": pivotSales()})
+	size, err := cmd.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 3, Height: 4}, size)
+
+	var buf bytes.Buffer
+	require.NoError(t, tx.Write(&buf))
+	out, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer out.Close()
+
+	expected := map[string]string{
+		"A1": "Region", "B1": "Jan", "C1": "Feb",
+		"A2": "North", "B2": "130", "C2": "70",
+		"A3": "South", "B3": "50", "C3": "",
+		"A4": "East", "B4": "", "C4": "20",
+	}
+	for cell, want := range expected {
+		v, _ := out.GetCellValue(sheet, cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestPivotCommand_AggregatesAndOrder(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	tx, err := NewExcelizeTransformer(f)
+	require.NoError(t, err)
+	defer tx.Close()
+
+	ctx := NewContext(map[string]any{"sales": pivotSales()})
+
+	cases := []struct {
+		value string
+		north string // North/Jan
+	}{
+		{"count(e)", "2"},
+		{"avg(e.Amount)", "65"},
+		{"min(e.Amount)", "30"},
+		{"max(e.Amount)", "100"},
+		{"e.Amount", "130"},
+	}
+	for _, tc := range cases {
+		cmd := &PivotCommand{
+			Items: "sales", Var: "e", RowKey: "e.Region", ColKey: "e.Month",
+			Value: tc.value, RowOrder: "ASC", ColOrder: "DESC",
+		}
+		_, err := cmd.ApplyAt(NewCellRef(sheet, 0, 0), ctx, tx)
+		require.NoError(t, err, tc.value)
+
+		// Rows sorted ASC: East, North, South; columns DESC: Jan, Feb
+		v, _ := tx.File().GetCellValue(sheet, "A3")
+		assert.Equal(t, "North", v)
+		v, _ = tx.File().GetCellValue(sheet, "B1")
+		assert.Equal(t, "Jan", v)
+		v, _ = tx.File().GetCellValue(sheet, "B3")
+		assert.Equal(t, tc.north, v, tc.value)
+	}
+}
+
+func TestNewPivotCommandFromAttrs_Errors(t *testing.T) {
+	_, err := newPivotCommandFromAttrs(map[string]string{"rowKey": "e.R", "colKey": "e.C", "value": "e.V"})
+	assert.ErrorContains(t, err, "items")
+
+	_, err = newPivotCommandFromAttrs(map[string]string{"items": "x", "rowKey": "R", "colKey": "C", "value": "V"})
+	assert.ErrorContains(t, err, "var")
+
+	_, err = newPivotCommandFromAttrs(map[string]string{"items": "x", "rowKey": "e.R", "colKey": "e.C", "value": "median(e.V)"})
+	assert.ErrorContains(t, err, "median")
+
+	cmd, err := newPivotCommandFromAttrs(map[string]string{"items": "x", "rowKey": "e.R", "colKey": "e.C", "value": "e.V"})
+	require.NoError(t, err)
+	assert.Equal(t, "e", cmd.(*PivotCommand).Var)
+}
+
+func TestPivotCommand_WriteError(t *testing.T) {
+	tx, err := NewExcelizeTransformer(excelize.NewFile())
+	require.NoError(t, err)
+	defer tx.Close()
+
+	cmd, err := newPivotCommandFromAttrs(map[string]string{
+		"items": "sales", "rowKey": "e.Region", "colKey": "e.Month", "value": "sum(e.Amount)",
+	})
+	require.NoError(t, err)
+
+	ctx := NewContext(map[string]any{"sales": pivotSales()})
+	_, err = cmd.ApplyAt(NewCellRef("Missing", 0, 0), ctx, tx)
+	assert.ErrorContains(t, err, "pivot cell Missing!B1")
+}
+
+func TestPivotCommand_InTemplate(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	f.SetCellValue(sheet, "A1", "Sales report")
+	f.SetCellValue(sheet, "A3", "End")
+
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A3")`,
+	})
+	f.AddComment(sheet, excelize.Comment{
+		Cell: "A2", Author: "xlfill",
+		Text: `jx:pivot(items="sales" rowKey="e.Region" colKey="e.Month" value="sum(e.Amount)" lastCell="A2")`,
+	})
+
+	tmpPath := t.TempDir() + "/tmpl.xlsx"
+	require.NoError(t, f.SaveAs(tmpPath))
+
+	outBytes, err := FillBytes(tmpPath, map[string]any{"sales": pivotSales()})
+	require.NoError(t, err)
+
+	out, err := excelize.OpenReader(bytes.NewReader(outBytes))
+	require.NoError(t, err)
+	defer out.Close()
+
+	v, _ := out.GetCellValue(sheet, "B2")
+	assert.Equal(t, "Jan", v)
+	v, _ = out.GetCellValue(sheet, "A5")
+	assert.Equal(t, "East", v)
+	v, _ = out.GetCellValue(sheet, "A6")
+	assert.Equal(t, "End", v)
+}
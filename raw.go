@@ -0,0 +1,43 @@
+package xlfill
+
+// RawCommand implements jx:raw, which copies its cells as they are: ${...}
+// stays literal text, formula parameters are not substituted and commands
+// inside it are not run. For a single literal expression in a cell, escape it
+// instead: \${name}.
+type RawCommand struct {
+	Area *Area
+}
+
+func (c *RawCommand) Name() string { return "raw" }
+func (c *RawCommand) Reset()       {}
+
+// newRawCommandFromAttrs creates a RawCommand from parsed attributes.
+func newRawCommandFromAttrs(attrs map[string]string) (Command, error) {
+	return &RawCommand{}, nil
+}
+
+// ApplyAt copies the area's cells to cellRef without evaluating them.
+func (c *RawCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	if c.Area == nil {
+		return ZeroSize, nil
+	}
+	rawCtx := ctx.WithVars(nil)
+	rawCtx.raw = true
+	return c.Area.transformStaticArea(cellRef, rawCtx)
+}
+
+// rawRanges returns the template ranges of the jx:raw commands in areas.
+func rawRanges(areas []*Area) []AreaRef {
+	var ranges []AreaRef
+	for _, area := range areas {
+		for _, b := range area.Bindings {
+			if _, ok := b.Command.(*RawCommand); ok {
+				last := NewCellRef(b.StartRef.Sheet, b.StartRef.Row+b.Size.Height-1, b.StartRef.Col+b.Size.Width-1)
+				ranges = append(ranges, NewAreaRef(b.StartRef, last))
+				continue
+			}
+			ranges = append(ranges, rawRanges(childAreas(b.Command))...)
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,71 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createRawTemplate creates a template whose rows 2-3 are a jx:raw block
+// documenting the template syntax, below an evaluated title.
+func createRawTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", `${title}: write \${name}`)
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", `\${escaped}`)
+	f.SetCellFormula("Sheet1", "C2", "${rate}*2")
+	f.SetCellValue("Sheet1", "A3", "${e.Age}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:raw(lastCell="C3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="A3")`})
+	path := filepath.Join(testdataDir(t), "raw_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestFill_Raw(t *testing.T) {
+	tmpl := createRawTemplate(t)
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, map[string]any{
+			"title":     "Syntax",
+			"rate":      3,
+			"employees": []map[string]any{{"Age": 30}, {"Age": 40}},
+		}, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		rows, err := res.GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Syntax: write ${name}"},
+			{"${e.Name}", `\${escaped}`, ""},
+			{"${e.Age}"},
+		}, rows)
+		formula, err := res.GetCellFormula("Sheet1", "C2")
+		require.NoError(t, err)
+		assert.Equal(t, "${rate}*2", formula)
+	}
+}
+
+func TestValidate_SkipsRaw(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${ok}")
+	f.SetCellValue("Sheet1", "A2", "${not valid(}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:raw(lastCell="A2")`})
+	path := filepath.Join(testdataDir(t), "raw_validate.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	f.Close()
+
+	issues, err := Validate(path)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	model, err := Inspect(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ok"}, model.Variables)
+}
@@ -0,0 +1,54 @@
+package xlfill
+
+import "bytes"
+
+// FillResult describes where a fill placed each area and template cell, for
+// code that post-processes the output, e.g. to attach charts, comments or
+// protection to the generated rows.
+type FillResult struct {
+	Output []byte       // the filled workbook (nil for Filler.Apply)
+	Areas  []AreaResult // root areas in processing order
+
+	targets map[CellRef][]CellRef
+	anchors map[string]CellRef
+}
+
+// AreaResult is the placement of one root area in the output.
+type AreaResult struct {
+	Name   string  // from jx:area(name="..."), if any
+	Source AreaRef // template range
+	Target CellRef // top-left output cell (areas move when areas above them grow)
+	Size   Size    // final output size
+}
+
+// Ref returns the output range of the area, or false when it produced no cells.
+func (a AreaResult) Ref() (AreaRef, bool) {
+	if a.Size.Width <= 0 || a.Size.Height <= 0 {
+		return AreaRef{}, false
+	}
+	return outputRef(a.Target, a.Size), true
+}
+
+// TargetsOf returns the output cells a template cell was written to, in the
+// order they were written, e.g. one per iteration of an enclosing jx:each.
+func (r *FillResult) TargetsOf(src CellRef) []CellRef {
+	targets := r.targets[src]
+	if len(targets) == 0 {
+		return nil
+	}
+	out := make([]CellRef, len(targets))
+	copy(out, targets)
+	return out
+}
+
+// FillWithResult processes the template with data and returns the output
+// together with the mapping from template cells to output cells.
+func (f *Filler) FillWithResult(data map[string]any) (*FillResult, error) {
+	var buf bytes.Buffer
+	result, err := f.fill(data, writerOutput(&buf), "")
+	if err != nil && !isExpressionErrors(err) {
+		return nil, err
+	}
+	result.Output = buf.Bytes()
+	return result, err
+}
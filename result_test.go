@@ -0,0 +1,38 @@
+package xlfill
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestFillWithResult(t *testing.T) {
+	tmpl := createStackedAreasTemplate(t, "result_stacked.xlsx")
+	filler := NewFiller(WithTemplate(tmpl))
+	result, err := filler.FillWithResult(map[string]any{
+		"emps":  []map[string]any{{"Name": "Alice", "Age": 30}, {"Name": "Bob", "Age": 25}},
+		"depts": []map[string]any{{"Name": "Sales", "Head": "Dan"}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, result.Areas, 2)
+	assert.Equal(t, "Sheet1!A1:B2", result.Areas[0].Source.String())
+	assert.Equal(t, Size{Width: 2, Height: 3}, result.Areas[0].Size)
+	assert.Equal(t, "Sheet1!A5", result.Areas[1].Target.String(), "the second area moved down")
+	ref, ok := result.Areas[1].Ref()
+	require.True(t, ok)
+	assert.Equal(t, "Sheet1!A5:B6", ref.String())
+
+	assert.Equal(t, []CellRef{NewCellRef("Sheet1", 1, 0), NewCellRef("Sheet1", 2, 0)}, result.TargetsOf(NewCellRef("Sheet1", 1, 0)))
+	assert.Equal(t, []CellRef{NewCellRef("Sheet1", 5, 1)}, result.TargetsOf(NewCellRef("Sheet1", 4, 1)))
+	assert.Nil(t, result.TargetsOf(NewCellRef("Sheet1", 20, 0)))
+
+	out, err := excelize.OpenReader(bytes.NewReader(result.Output))
+	require.NoError(t, err)
+	defer out.Close()
+	v, _ := out.GetCellValue("Sheet1", "A3")
+	assert.Equal(t, "Bob", v)
+}
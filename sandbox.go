@@ -0,0 +1,334 @@
+package xlfill
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+	"github.com/expr-lang/expr/vm"
+)
+
+// ErrExpressionLimit is returned, wrapped, when an expression exceeds a limit
+// set with WithExpressionLimits or uses a function or property the template may
+// not use.
+var ErrExpressionLimit = errors.New("expression limit exceeded")
+
+// Names injected into sandboxed programs. Templates may not use them.
+const (
+	opFunc    = "$op"
+	budgetVar = "$budget"
+)
+
+// maxStringLen is the longest string an iteration or repeat may produce, the
+// same as expr's limit on the repeat count.
+const maxStringLen = 1_000_000
+
+// memberFunctions read members by a computed name or expose all members, so
+// they are denied when property access is restricted.
+var memberFunctions = []string{"get", "keys", "values", "toPairs", "toJSON"}
+
+// exprSandbox restricts what template expressions may do.
+type exprSandbox struct {
+	maxOps     int
+	maxDepth   int
+	timeout    time.Duration
+	allowFuncs map[string]bool // nil allows every function
+	denyFuncs  map[string]bool
+	allowProps map[string]bool // nil allows every property
+	denyProps  map[string]bool
+}
+
+func (o *Options) sandboxOpts() *exprSandbox {
+	if o.sandbox == nil {
+		o.sandbox = &exprSandbox{}
+	}
+	return o.sandbox
+}
+
+func addNames(set *map[string]bool, names []string) {
+	if *set == nil {
+		*set = make(map[string]bool, len(names))
+	}
+	for _, name := range names {
+		(*set)[name] = true
+	}
+}
+
+// restrictsProperties reports whether property access is checked.
+func (s *exprSandbox) restrictsProperties() bool {
+	return s.allowProps != nil || len(s.denyProps) > 0
+}
+
+// counting reports whether programs count their operations.
+func (s *exprSandbox) counting() bool {
+	return s.maxOps > 0 || s.timeout > 0
+}
+
+// compile checks an expression against the sandbox and compiles it so that
+// every iteration of map, filter, reduce and the other builtins taking a
+// predicate calls opFunc.
+func (s *exprSandbox) compile(expression string, env map[string]any) (*vm.Program, error) {
+	tree, err := parser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.check(tree.Node); err != nil {
+		return nil, err
+	}
+	opts := append(compileOptions(env), expr.DisableBuiltin("repeat"), expr.Function("repeat", repeat, strings.Repeat))
+	if s.counting() {
+		opts = append(opts, expr.Patch(countOps{}), expr.Function(opFunc, countOp))
+	}
+	if s.restrictsProperties() {
+		opts = append(opts, s.aggregateFunctions()...)
+	}
+	return expr.Compile(expression, opts...)
+}
+
+// check reports the first part of an expression the sandbox does not allow.
+func (s *exprSandbox) check(root ast.Node) error {
+	if s.maxDepth > 0 {
+		if depth := nodeDepth(root); depth > s.maxDepth {
+			return fmt.Errorf("%w: nesting depth %d is over %d", ErrExpressionLimit, depth, s.maxDepth)
+		}
+	}
+	var err error
+	ast.Walk(&root, visitFunc(func(node *ast.Node) {
+		if err != nil {
+			return
+		}
+		switch n := (*node).(type) {
+		case *ast.IdentifierNode:
+			if n.Value == opFunc || n.Value == budgetVar {
+				err = fmt.Errorf("%w: %s is reserved", ErrExpressionLimit, n.Value)
+			}
+		case *ast.CallNode:
+			if id, ok := n.Callee.(*ast.IdentifierNode); ok {
+				err = s.checkFunction(id.Value)
+			}
+		case *ast.BuiltinNode:
+			err = s.checkFunction(n.Name)
+		case *ast.MemberNode:
+			err = s.checkMember(n)
+		}
+	}))
+	return err
+}
+
+func (s *exprSandbox) checkFunction(name string) error {
+	if s.denyFuncs[name] || (s.allowFuncs != nil && !s.allowFuncs[name]) {
+		return fmt.Errorf("%w: function %s is not allowed", ErrExpressionLimit, name)
+	}
+	for _, f := range memberFunctions {
+		if name == f && s.restrictsProperties() {
+			return fmt.Errorf("%w: function %s is not allowed when property access is restricted", ErrExpressionLimit, name)
+		}
+	}
+	return nil
+}
+
+func (s *exprSandbox) checkMember(n *ast.MemberNode) error {
+	if !s.restrictsProperties() {
+		return nil
+	}
+	switch p := n.Property.(type) {
+	case *ast.StringNode:
+		return s.checkProperty(p.Value)
+	case *ast.IntegerNode:
+		return nil
+	}
+	return fmt.Errorf("%w: computed property access is not allowed", ErrExpressionLimit)
+}
+
+func (s *exprSandbox) checkProperty(name string) error {
+	if s.denyProps[name] || (s.allowProps != nil && !s.allowProps[name]) {
+		return fmt.Errorf("%w: property %s is not allowed", ErrExpressionLimit, name)
+	}
+	return nil
+}
+
+// checkPath checks a property path such as "Address.City", which groupBy,
+// orderBy, select, grid props and the aggregates read from items without
+// evaluating an expression: each name of the path, and each member it reads
+// from any of items, must be allowed. A nil sandbox allows every path.
+func (s *exprSandbox) checkPath(path string, items []any) error {
+	if s == nil || !s.restrictsProperties() || path == "" {
+		return nil
+	}
+	for _, name := range strings.Split(path, ".") {
+		if err := s.checkProperty(strings.TrimSuffix(strings.TrimSpace(name), "()")); err != nil {
+			return err
+		}
+	}
+	for _, item := range items {
+		for _, member := range fieldMembers(item, path) {
+			if err := s.checkProperty(member); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkAggregate checks the field an aggregate reads, as in
+// sum(employees, "Salary"), with checkPath.
+func (s *exprSandbox) checkAggregate(items any, field []string) error {
+	if len(field) == 0 {
+		return nil
+	}
+	all, err := toSlice(items)
+	if err != nil {
+		return nil // the aggregate reports it
+	}
+	return s.checkPath(field[0], all)
+}
+
+// aggregateFunctions replaces the functions of the aggregates patch with ones
+// that check their field first.
+func (s *exprSandbox) aggregateFunctions() []expr.Option {
+	checked := func(name string, fn func(items any, field []string) (any, error)) expr.Option {
+		return expr.Function(name, func(params ...any) (any, error) {
+			field := fieldParam(params)
+			if err := s.checkAggregate(params[0], field); err != nil {
+				return nil, fmt.Errorf("%s: %w", name[1:], err)
+			}
+			return fn(params[0], field)
+		})
+	}
+	return []expr.Option{
+		checked("$sum", func(items any, field []string) (any, error) { return sumOf(items, field...) }),
+		checked("$count", func(items any, field []string) (any, error) { return countOf(items, field...) }),
+		checked("$min", func(items any, field []string) (any, error) { return extremeOf("min", -1, items, field) }),
+		checked("$max", func(items any, field []string) (any, error) { return extremeOf("max", 1, items, field) }),
+	}
+}
+
+// run runs a program with a fresh operation budget. When property access is
+// restricted, the avg context function checks its field first.
+func (s *exprSandbox) run(program *vm.Program, data map[string]any) (any, error) {
+	avg, checkAvg := data["avg"].(func(any, ...string) (any, error))
+	checkAvg = checkAvg && s.restrictsProperties()
+	if !s.counting() && !checkAvg {
+		return expr.Run(program, data)
+	}
+	env := make(map[string]any, len(data)+1)
+	maps.Copy(env, data)
+	if s.counting() {
+		budget := &opBudget{limit: s.maxOps}
+		if s.timeout > 0 {
+			budget.deadline = time.Now().Add(s.timeout)
+		}
+		env[budgetVar] = budget
+	}
+	if checkAvg {
+		env["avg"] = func(items any, field ...string) (any, error) {
+			if err := s.checkAggregate(items, field); err != nil {
+				return nil, fmt.Errorf("avg: %w", err)
+			}
+			return avg(items, field...)
+		}
+	}
+	return vm.Run(program, env)
+}
+
+// opBudget counts the operations of one evaluation.
+type opBudget struct {
+	ops      int
+	limit    int
+	deadline time.Time
+}
+
+func (b *opBudget) spend() error {
+	b.ops++
+	if b.limit > 0 && b.ops > b.limit {
+		return fmt.Errorf("%w: more than %d operations", ErrExpressionLimit, b.limit)
+	}
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		return fmt.Errorf("%w: evaluation timed out", ErrExpressionLimit)
+	}
+	return nil
+}
+
+// countOp is opFunc: it spends one operation of the budget and passes the
+// iteration's value through.
+func countOp(params ...any) (any, error) {
+	budget, ok := params[0].(*opBudget)
+	if !ok {
+		return nil, fmt.Errorf("%w: operation budget missing", ErrExpressionLimit)
+	}
+	if err := budget.spend(); err != nil {
+		return nil, err
+	}
+	if s, ok := params[1].(string); ok && len(s) > maxStringLen {
+		return nil, fmt.Errorf("%w: string longer than %d bytes", ErrExpressionLimit, maxStringLen)
+	}
+	return params[1], nil
+}
+
+// repeat replaces expr's repeat, which limits the count but not the length of
+// the result.
+func repeat(params ...any) (any, error) {
+	s, ok := params[0].(string)
+	n, ok2 := params[1].(int)
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("repeat expects a string and an int, got %T and %T", params[0], params[1])
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid argument for repeat (expected positive integer, got %d)", n)
+	}
+	if len(s) > 0 && n > maxStringLen/len(s) {
+		return nil, fmt.Errorf("%w: string longer than %d bytes", ErrExpressionLimit, maxStringLen)
+	}
+	return strings.Repeat(s, n), nil
+}
+
+// countOps wraps the body of every predicate in a call to opFunc.
+type countOps struct{}
+
+func (countOps) Visit(node *ast.Node) {
+	if c, ok := (*node).(*ast.PredicateNode); ok {
+		c.Node = &ast.CallNode{
+			Callee:    &ast.IdentifierNode{Value: opFunc},
+			Arguments: []ast.Node{&ast.IdentifierNode{Value: budgetVar}, c.Node},
+		}
+	}
+}
+
+type visitFunc func(node *ast.Node)
+
+func (f visitFunc) Visit(node *ast.Node) { f(node) }
+
+var nodeType = reflect.TypeFor[ast.Node]()
+
+// nodeDepth returns the depth of a syntax tree: a + b * c is 3 deep.
+func nodeDepth(node ast.Node) int {
+	v := reflect.ValueOf(node)
+	if !v.IsValid() || v.IsNil() {
+		return 0
+	}
+	v = v.Elem()
+	deepest := 0
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanInterface() {
+			continue
+		}
+		switch {
+		case f.Type() == nodeType:
+			if !f.IsNil() {
+				deepest = max(deepest, nodeDepth(f.Interface().(ast.Node)))
+			}
+		case f.Kind() == reflect.Slice && f.Type().Elem() == nodeType:
+			for j := 0; j < f.Len(); j++ {
+				deepest = max(deepest, nodeDepth(f.Index(j).Interface().(ast.Node)))
+			}
+		}
+	}
+	return deepest + 1
+}
@@ -0,0 +1,230 @@
+package xlfill
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func sandboxEvaluator(opts ...Option) ExpressionEvaluator {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &exprEvaluator{sandbox: o.sandbox}
+}
+
+func TestSandbox_MaxOps(t *testing.T) {
+	ev := sandboxEvaluator(WithExpressionLimits(100, 0, 0))
+	data := newTestEvalEnv()
+
+	got, err := ev.Evaluate("count(1..50, # % 2 == 0)", data)
+	require.NoError(t, err)
+	assert.Equal(t, 25, got)
+
+	_, err = ev.Evaluate("count(1..101, # > 0)", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	_, err = ev.Evaluate("map(1..10, map(1..10, # * 2))", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit, "nested iterations count too")
+
+	// Each evaluation starts with a fresh budget
+	for range 3 {
+		_, err = ev.Evaluate("sum(1..60, #)", data)
+		require.NoError(t, err)
+	}
+}
+
+func TestSandbox_MaxDepth(t *testing.T) {
+	ev := sandboxEvaluator(WithExpressionLimits(0, 3, 0))
+	data := newTestEvalEnv()
+
+	got, err := ev.Evaluate("e.Age + 1", data)
+	require.NoError(t, err)
+	assert.Equal(t, 31, got)
+	_, err = ev.Evaluate("e.Age + (1 + (2 + 3))", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+}
+
+func TestSandbox_Timeout(t *testing.T) {
+	ev := sandboxEvaluator(WithExpressionLimits(0, 0, 20*time.Millisecond))
+	data := map[string]any{"slow": func() int { time.Sleep(5 * time.Millisecond); return 1 }}
+
+	got, err := ev.Evaluate("slow()", data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got)
+
+	start := time.Now()
+	_, err = ev.Evaluate("sum(1..1000, slow())", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestSandbox_StringLength(t *testing.T) {
+	ev := sandboxEvaluator(WithExpressionLimits(1000, 0, 0))
+	data := newTestEvalEnv()
+
+	got, err := ev.Evaluate(`repeat("ab", 3)`, data)
+	require.NoError(t, err)
+	assert.Equal(t, "ababab", got)
+	_, err = ev.Evaluate(`repeat("ab", 600000)`, data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	_, err = ev.Evaluate(`reduce(1..25, #acc + #acc, "xx")`, data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+}
+
+func TestSandbox_Functions(t *testing.T) {
+	data := newTestEvalEnv()
+
+	ev := sandboxEvaluator(WithDeniedFunctions("upper", "now"))
+	_, err := ev.Evaluate("upper(e.Name)", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	_, err = ev.Evaluate("now()", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	got, err := ev.Evaluate("lower(e.Name)", data)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got)
+
+	ev = sandboxEvaluator(WithAllowedFunctions("len", "hyperlink"))
+	got, err = ev.Evaluate("len(list)", data)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got)
+	_, err = ev.Evaluate("filter(list, # > 1)", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+	_, err = ev.Evaluate("list | map(# * 2)", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+}
+
+func TestSandbox_Properties(t *testing.T) {
+	data := newTestEvalEnv()
+	data["m"] = map[string]any{"secret": "s3cr3t", "public": "ok"}
+
+	ev := sandboxEvaluator(WithDeniedProperties("Payment", "secret"))
+	got, err := ev.Evaluate("e.Name + m.public + m['public']", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Aliceokok", got)
+	got, err = ev.Evaluate("list[0]", data)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got)
+	for _, expression := range []string{
+		"e.Payment",
+		"m['secret']",
+		"$env.m.secret",
+		"m?.secret",
+		"m[e.Name]",
+		"get(m, 'secret')",
+		"toJSON(m)",
+	} {
+		_, err := ev.Evaluate(expression, data)
+		assert.ErrorIs(t, err, ErrExpressionLimit, expression)
+	}
+
+	ev = sandboxEvaluator(WithAllowedProperties("Address", "City", "m"))
+	got, err = ev.Evaluate("e.Address.City", data)
+	require.NoError(t, err)
+	assert.Equal(t, "London", got)
+	_, err = ev.Evaluate("e.Name", data)
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+}
+
+func TestSandbox_ReservedNames(t *testing.T) {
+	ev := sandboxEvaluator(WithExpressionLimits(10, 0, 0))
+	for _, expression := range []string{"$budget", "$op($budget, 1)"} {
+		_, err := ev.Evaluate(expression, newTestEvalEnv())
+		assert.ErrorIs(t, err, ErrExpressionLimit, expression)
+	}
+}
+
+func TestSandbox_UnrestrictedByDefault(t *testing.T) {
+	got, err := NewExpressionEvaluator().Evaluate("count(1..5000, # > 0)", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 5000, got)
+}
+
+func TestFill_ExpressionLimits(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${count(rows, # > 1)}")
+	f.SetCellValue("Sheet1", "A2", "${e}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="rows" var="e" select="e % 2 == 1" lastCell="A2")`})
+	tmpl := filepath.Join(testdataDir(t), "sandbox_template.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	rows := make([]any, 200)
+	for i := range rows {
+		rows[i] = i
+	}
+	data := map[string]any{"rows": rows[:5]}
+
+	out, err := FillBytes(tmpl, data, WithExpressionLimits(100, 10, time.Second), WithDeniedFunctions("now"))
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	v, _ := res.GetCellValue("Sheet1", "A1")
+	assert.Equal(t, "3", v)
+	v, _ = res.GetCellValue("Sheet1", "A3")
+	assert.Equal(t, "3", v)
+
+	_, err = FillBytes(tmpl, map[string]any{"rows": rows}, WithExpressionLimits(100, 10, time.Second))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrExpressionLimit), err.Error())
+
+	_, err = FillBytes(tmpl, data, WithDeniedFunctions("count"))
+	assert.ErrorIs(t, err, ErrExpressionLimit)
+}
+
+type sandboxUser struct {
+	Name     string
+	Password string `xlfill:"pw"`
+	Salary   int
+}
+
+func TestFill_DeniedPropertyPaths(t *testing.T) {
+	template := func(t *testing.T, value, command string) string {
+		f := excelize.NewFile()
+		defer f.Close()
+		f.SetCellValue("Sheet1", "A2", value)
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+		f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: command})
+		path := filepath.Join(t.TempDir(), "template.xlsx")
+		require.NoError(t, f.SaveAs(path))
+		return path
+	}
+	data := map[string]any{
+		"headers": []any{"Name", "Password"},
+		"users":   []sandboxUser{{"bob", "hunter2", 10}, {"amy", "letmein", 20}},
+		"rows":    []map[string]any{{"Name": "bob", "Password": "hunter2"}},
+	}
+	deny := WithDeniedProperties("Password", "Salary")
+
+	for _, tt := range []struct{ value, command string }{
+		{"${e.Key}", `jx:each(items="users" var="e" groupBy="e.Password" lastCell="A2")`},
+		{"${e.Key}", `jx:each(items="users" var="e" groupBy="pw" lastCell="A2")`},
+		{"${e.Name}", `jx:each(items="users" var="e" orderBy="e.password DESC" lastCell="A2")`},
+		{"${e.Name}", `jx:each(items="users" var="e" select="Password == 'hunter2'" lastCell="A2")`},
+		{"", `jx:grid(headers="headers" data="users" props="Name, Password" lastCell="A2")`},
+		{"", `jx:grid(headers="headers" data="users" props="Name, pw + '!'" lastCell="A2")`},
+		{"", `jx:grid(headers="headers" data="rows" lastCell="A2")`},
+		{`${count(users, "password")}`, `jx:area(lastCell="A2")`},
+		{`${max(users, "Salary")}`, `jx:area(lastCell="A2")`},
+		{`${avg(users, "salary")}`, `jx:area(lastCell="A2")`},
+	} {
+		tmpl := template(t, tt.value, tt.command)
+		_, err := FillBytes(tmpl, data)
+		require.NoError(t, err, tt.command+" "+tt.value)
+		_, err = FillBytes(tmpl, data, deny)
+		assert.ErrorIs(t, err, ErrExpressionLimit, tt.command+" "+tt.value)
+	}
+
+	// Paths the deny list does not name still work
+	out, err := FillBytes(template(t, "${e.Name}", `jx:each(items="users" var="e" orderBy="e.Name" lastCell="A2")`), data, deny)
+	require.NoError(t, err)
+	rows, err := openOutput(t, out).GetRows("Sheet1")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{nil, {"amy"}, {"bob"}}, rows)
+}
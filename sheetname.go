@@ -0,0 +1,80 @@
+package xlfill
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSheetName is returned when no sheet can be created for a name,
+// such as an empty name or a name Excel reserves.
+var ErrInvalidSheetName = errors.New("invalid sheet name")
+
+// SheetNameBuilder names the sheets xlfill creates, such as the copies a
+// multisheet jx:each makes of its template sheet. Set it with
+// WithSheetNameBuilder; the default is SafeSheetNameBuilder{}.
+type SheetNameBuilder interface {
+	// BuildSheetName returns a valid name based on requested that is not one
+	// of existing, or an error when there is none.
+	BuildSheetName(requested string, existing []string) (string, error)
+}
+
+// SafeSheetNameBuilder replaces the characters Excel forbids in sheet names,
+// truncates the name and, when a sheet already has it, appends a counter:
+// "Sales", "Sales (2)", "Sales (3)". Names are compared case-insensitively, as
+// Excel does. Empty names and the reserved name "History" are rejected.
+type SafeSheetNameBuilder struct {
+	MaxLength   int    // longest name in characters; 0 means Excel's limit of 31
+	Replacement string // replaces each forbidden character; empty means "_"
+	Suffix      string // format of the counter added on a collision; empty means " (%d)"
+}
+
+// BuildSheetName implements SheetNameBuilder.
+func (b SafeSheetNameBuilder) BuildSheetName(requested string, existing []string) (string, error) {
+	maxLen, replacement, suffix := b.MaxLength, b.Replacement, b.Suffix
+	if maxLen <= 0 || maxLen > 31 {
+		maxLen = 31
+	}
+	if replacement == "" {
+		replacement = "_"
+	}
+	if suffix == "" {
+		suffix = " (%d)"
+	}
+
+	var sb strings.Builder
+	for _, r := range requested {
+		if strings.ContainsRune(`/\:*?[]`, r) {
+			sb.WriteString(replacement)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	name := truncateRunes(strings.TrimSpace(sb.String()), maxLen)
+	if name == "" {
+		return "", fmt.Errorf("%w: %q is empty", ErrInvalidSheetName, requested)
+	}
+	if strings.EqualFold(name, "History") {
+		return "", fmt.Errorf("%w: %q is reserved by Excel", ErrInvalidSheetName, requested)
+	}
+
+	taken := make(map[string]bool, len(existing))
+	for _, s := range existing {
+		taken[strings.ToLower(s)] = true
+	}
+	candidate := name
+	for n := 2; taken[strings.ToLower(candidate)]; n++ {
+		counter := fmt.Sprintf(suffix, n)
+		candidate = truncateRunes(name, maxLen-len([]rune(counter))) + counter
+	}
+	return candidate, nil
+}
+
+// truncateRunes returns s cut to at most n characters.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:max(n, 0)])
+}
@@ -0,0 +1,202 @@
+package xlfill
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSafeSheetNameBuilder(t *testing.T) {
+	existing := []string{"Template", "Sales", "sales (2)"}
+	tests := []struct {
+		builder   SafeSheetNameBuilder
+		requested string
+		want      string
+	}{
+		{SafeSheetNameBuilder{}, "Ops", "Ops"},
+		{SafeSheetNameBuilder{}, "SALES", "SALES (3)"},
+		{SafeSheetNameBuilder{}, "Q1/Q2: [draft]?", "Q1_Q2_ _draft__"},
+		{SafeSheetNameBuilder{}, strings.Repeat("x", 40), strings.Repeat("x", 31)},
+		{SafeSheetNameBuilder{}, "  Ops  ", "Ops"},
+		{SafeSheetNameBuilder{MaxLength: 5, Replacement: "-", Suffix: "-%d"}, "a/b/c/d", "a-b-c"},
+		{SafeSheetNameBuilder{MaxLength: 5, Suffix: "-%d"}, "Sales", "Sal-3"},
+	}
+	for _, tt := range tests {
+		got, err := tt.builder.BuildSheetName(tt.requested, append(existing, "Sal-2"))
+		require.NoError(t, err, tt.requested)
+		assert.Equal(t, tt.want, got, tt.requested)
+	}
+
+	for _, name := range []string{"", "   ", "history", "History"} {
+		_, err := SafeSheetNameBuilder{}.BuildSheetName(name, nil)
+		assert.ErrorIs(t, err, ErrInvalidSheetName, name)
+	}
+}
+
+func createMultisheetTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", "Template")
+	f.SetCellValue("Template", "A1", "${d.Name}")
+	f.AddComment("Template", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(items="depts" var="d" multisheet="names" lastCell="A1")`})
+	path := filepath.Join(testdataDir(t), "multisheet_names.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func multisheetData(names ...string) map[string]any {
+	depts := make([]map[string]any, len(names))
+	for i, name := range names {
+		depts[i] = map[string]any{"Name": name}
+	}
+	return map[string]any{"depts": depts, "names": names}
+}
+
+// dashBuilder numbers duplicate sheet names "Sales-2", "Sales-3", ...
+type dashBuilder struct{}
+
+func (dashBuilder) BuildSheetName(requested string, existing []string) (string, error) {
+	name := requested
+	for n := 2; containsFold(existing, name); n++ {
+		name = fmt.Sprintf("%s-%d", requested, n)
+	}
+	return name, nil
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFill_MultisheetSheetNames(t *testing.T) {
+	tmpl := createMultisheetTemplate(t)
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, multisheetData("Sales", "Ops", "sales", "Template", "a/b"), opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		assert.Equal(t, []string{"Sales", "Ops", "sales (2)", "Template (2)", "a_b"}, res.GetSheetList())
+		v, _ := res.GetCellValue("sales (2)", "A1")
+		assert.Equal(t, "sales", v)
+	}
+
+	out, err := FillBytes(tmpl, multisheetData("Sales", "Sales"), WithSheetNameBuilder(dashBuilder{}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Sales", "Sales-2"}, openOutput(t, out).GetSheetList())
+
+	_, err = FillBytes(tmpl, multisheetData("Sales", "History"))
+	assert.ErrorIs(t, err, ErrInvalidSheetName)
+	assert.ErrorContains(t, err, `multisheet "names": sheet name for item 1`)
+}
+
+func TestFill_SheetData(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "Template")
+	f.SetCellValue("Template", "A1", "${d.Name}")
+	f.SetCellValue("Template", "B1", "${period}")
+	f.AddComment("Template", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="B1")` + "\n" + `jx:each(items="depts" var="d" multisheet="names" lastCell="B1")`})
+	f.NewSheet("Summary")
+	f.SetCellValue("Summary", "A1", "${period} ${title}")
+	f.AddComment("Summary", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "sheet_data.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := multisheetData("Jan", "Feb", "Mar")
+	data["period"], data["title"] = "Q1", "Report"
+	sheetData := WithSheetData(map[string]map[string]any{
+		"Jan":     {"period": "2026-01"},
+		"Feb":     {"period": "2026-02", "d": "shadowed by the loop variable"},
+		"Summary": {"title": "Summary"},
+	})
+	for _, opts := range [][]Option{{sheetData}, {sheetData, WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		for sheet, want := range map[string][]string{
+			"Jan": {"Jan", "2026-01"}, "Feb": {"Feb", "2026-02"}, "Mar": {"Mar", "Q1"},
+		} {
+			a, _ := res.GetCellValue(sheet, "A1")
+			b, _ := res.GetCellValue(sheet, "B1")
+			assert.Equal(t, want, []string{a, b}, sheet)
+		}
+		v, _ := res.GetCellValue("Summary", "A1")
+		assert.Equal(t, "Q1 Summary", v)
+	}
+}
+
+// quarter names its sheet with its String method.
+type quarter struct{ N int }
+
+func (q quarter) String() string { return fmt.Sprintf("Q%d", q.N) }
+
+func TestFill_MultisheetNameExpressions(t *testing.T) {
+	fillErr := func(each string, data map[string]any, opts ...Option) (*bytes.Buffer, error) {
+		f := excelize.NewFile()
+		defer f.Close()
+		f.SetSheetName("Sheet1", "Template")
+		f.SetCellValue("Template", "A1", "x")
+		f.AddComment("Template", excelize.Comment{Cell: "A1", Author: "xlfill",
+			Text: `jx:area(lastCell="A1")` + "\n" + each})
+		var tmpl, out bytes.Buffer
+		if err := f.Write(&tmpl); err != nil {
+			return nil, err
+		}
+		return &out, FillReader(&tmpl, &out, data, opts...)
+	}
+	fill := func(t *testing.T, each string, data map[string]any, opts ...Option) []string {
+		t.Helper()
+		out, err := fillErr(each, data, opts...)
+		require.NoError(t, err)
+		return openOutput(t, out.Bytes()).GetSheetList()
+	}
+	depts := []map[string]any{
+		{"Name": "Sales", "Year": 2025, "Region": "North"},
+		{"Name": "Ops", "Year": 2025, "Region": "North"},
+		{"Name": "Sales", "Year": 2026, "Region": "South/East"},
+	}
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		assert.Equal(t, []string{"Sales 2025", "Ops 2025", "Sales 2026"},
+			fill(t, `jx:each(items="depts" var="d" multisheet="d.Name + ' ' + string(d.Year)" lastCell="A1")`,
+				map[string]any{"depts": depts}, opts...))
+		assert.Equal(t, []string{"1-Sales", "2-Ops", "3-Sales"},
+			fill(t, `jx:each(items="depts" var="d" varIndex="i" multisheet="string(i+1) + '-' + d.Name" lastCell="A1")`,
+				map[string]any{"depts": depts}, opts...))
+	}
+
+	// @auto: Name, String method, plain values and groupBy keys, made valid and unique
+	assert.Equal(t, []string{"Sales", "Ops", "Sales (2)"},
+		fill(t, `jx:each(items="depts" var="d" multisheet="@auto" lastCell="A1")`, map[string]any{"depts": depts}))
+	assert.Equal(t, []string{"Q1", "Q2"},
+		fill(t, `jx:each(items="qs" var="q" multisheet="@auto" lastCell="A1")`,
+			map[string]any{"qs": []quarter{{1}, {2}}}))
+	assert.Equal(t, []string{"2025", "2026"},
+		fill(t, `jx:each(items="years" var="y" multisheet="@auto" lastCell="A1")`,
+			map[string]any{"years": []int{2025, 2026}}))
+	assert.Equal(t, []string{"North", "South_East"},
+		fill(t, `jx:each(items="depts" var="g" groupBy="Region" multisheet="@auto" lastCell="A1")`,
+			map[string]any{"depts": depts}))
+
+	// A list of names is still read from the context
+	assert.Equal(t, []string{"A", "B", "Template_3"},
+		fill(t, `jx:each(items="depts" var="d" multisheet="names" lastCell="A1")`,
+			map[string]any{"depts": depts, "names": []string{"A", "B"}}))
+
+	_, err := fillErr(`jx:each(items="lists" var="l" multisheet="@auto" lastCell="A1")`,
+		map[string]any{"lists": [][]int{{1}}})
+	assert.ErrorContains(t, err, "multisheet @auto: item 0: []int has no String method or Name")
+}
@@ -0,0 +1,98 @@
+package xlfill
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SheetProps are tab settings of an output sheet. Nil fields are left as they are.
+type SheetProps struct {
+	TabColor *string // RGB or ARGB hex color of the tab, e.g. "FF0000" or "#FF0000"
+	Hidden   *bool
+}
+
+// SheetPropsCommand implements jx:sheetProps. It sets the tab color and
+// visibility of the sheet it is written to from its attributes, which may
+// hold ${...} expressions, and renders its area. Inside a multisheet jx:each
+// each generated sheet gets the settings of its own item:
+//
+//	jx:sheetProps(tabColor="${dept.Color}" hidden="${dept.Internal}" lastCell="A1")
+//
+// An expression that evaluates to nil or "" leaves the setting unchanged.
+type SheetPropsCommand struct {
+	TabColor string // color, e.g. "${dept.Color}" or "#FF0000"
+	Hidden   string // boolean, e.g. "${dept.Internal}" or "true"
+	Area     *Area
+}
+
+func (c *SheetPropsCommand) Name() string { return "sheetProps" }
+func (c *SheetPropsCommand) Reset()       {}
+
+// newSheetPropsCommandFromAttrs creates a SheetPropsCommand from parsed attributes.
+func newSheetPropsCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &SheetPropsCommand{TabColor: attrs["tabColor"], Hidden: attrs["hidden"]}
+	if cmd.TabColor == "" && cmd.Hidden == "" {
+		return nil, fmt.Errorf("sheetProps command requires 'tabColor' or 'hidden' attribute")
+	}
+	return cmd, nil
+}
+
+// ApplyAt sets the properties of the target sheet and renders the area.
+func (c *SheetPropsCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	props, err := c.evaluate(ctx)
+	if err != nil {
+		return ZeroSize, err
+	}
+	if props.TabColor != nil || props.Hidden != nil {
+		if err := transformer.SetSheetProps(cellRef.Sheet, props); err != nil {
+			return ZeroSize, fmt.Errorf("set properties of sheet %q: %w", cellRef.Sheet, err)
+		}
+	}
+	if c.Area == nil {
+		return ZeroSize, nil
+	}
+	return c.Area.ApplyAt(cellRef, ctx)
+}
+
+// evaluate returns the properties the attributes give in ctx.
+func (c *SheetPropsCommand) evaluate(ctx *Context) (SheetProps, error) {
+	var props SheetProps
+	if c.TabColor != "" {
+		val, _, err := ctx.EvaluateCellValue(c.TabColor)
+		if err != nil {
+			return props, fmt.Errorf("evaluate tabColor %q: %w", c.TabColor, err)
+		}
+		if val != nil {
+			if color := fmt.Sprint(val); color != "" {
+				if _, ok := argbColor(color); !ok {
+					return props, fmt.Errorf("sheetProps command: invalid tabColor %q (expected a hex color such as FF0000)", color)
+				}
+				props.TabColor = &color
+			}
+		}
+	}
+	if c.Hidden != "" {
+		val, _, err := ctx.EvaluateCellValue(c.Hidden)
+		if err != nil {
+			return props, fmt.Errorf("evaluate hidden %q: %w", c.Hidden, err)
+		}
+		switch v := val.(type) {
+		case nil:
+		case bool:
+			props.Hidden = &v
+		case string:
+			if v == "" {
+				break
+			}
+			hidden, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return props, fmt.Errorf("sheetProps command: invalid hidden %q (expected true or false)", v)
+			}
+			props.Hidden = &hidden
+		default:
+			return props, fmt.Errorf("sheetProps command: invalid hidden %v (expected true or false)", v)
+		}
+	}
+	return props, nil
+}
@@ -0,0 +1,78 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestSheetPropsCommand_Multisheet(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "template")
+	f.SetCellValue("template", "A1", "${d.Name}")
+	f.AddComment("template", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B1")` + "\n" +
+		`jx:each(items="depts" var="d" multisheet="names" lastCell="B1")`})
+	f.AddComment("template", excelize.Comment{Cell: "B1", Author: "xlfill",
+		Text: `jx:sheetProps(tabColor="${d.Color}" hidden="${d.Internal}" lastCell="B1")`})
+	tmpl := filepath.Join(testdataDir(t), "sheetprops.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{
+		"names": []string{"Audit", "Sales", "Ops"},
+		"depts": []map[string]any{
+			{"Name": "Audit", "Color": "#C00000", "Internal": true},
+			{"Name": "Sales", "Color": "00B050", "Internal": false},
+			{"Name": "Ops"},
+		},
+	}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		b, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		out, err := excelize.OpenReader(bytes.NewReader(b))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Audit", "Sales", "Ops"}, out.GetSheetList())
+		for sheet, want := range map[string]string{"Audit": "FFC00000", "Sales": "FF00B050", "Ops": ""} {
+			props, err := out.GetSheetProps(sheet)
+			require.NoError(t, err)
+			var color string
+			if props.TabColorRGB != nil {
+				color = *props.TabColorRGB
+			}
+			assert.Equal(t, want, color, sheet)
+		}
+		visible, _ := out.GetSheetVisible("Audit")
+		assert.False(t, visible)
+		visible, _ = out.GetSheetVisible("Ops")
+		assert.True(t, visible)
+		// The output opens on the first visible generated sheet
+		assert.Equal(t, "Sales", out.GetSheetName(out.GetActiveSheetIndex()))
+		out.Close()
+	}
+
+	data["depts"] = []map[string]any{{"Name": "Audit", "Color": "red"}, {"Name": "Sales"}, {"Name": "Ops"}}
+	_, err := FillBytes(tmpl, data)
+	assert.ErrorContains(t, err, `invalid tabColor "red"`)
+	data["depts"] = []map[string]any{{"Name": "Audit", "Internal": "maybe"}, {"Name": "Sales"}, {"Name": "Ops"}}
+	_, err = FillBytes(tmpl, data)
+	assert.ErrorContains(t, err, `invalid hidden "maybe"`)
+}
+
+func TestSheetPropsCommand_Attributes(t *testing.T) {
+	_, err := newSheetPropsCommandFromAttrs(map[string]string{})
+	assert.ErrorContains(t, err, "requires 'tabColor' or 'hidden'")
+
+	cmd, err := newSheetPropsCommandFromAttrs(map[string]string{"tabColor": "FF0000", "hidden": "false"})
+	require.NoError(t, err)
+	props, err := cmd.(*SheetPropsCommand).evaluate(NewContext(nil))
+	require.NoError(t, err)
+	require.NotNil(t, props.TabColor)
+	require.NotNil(t, props.Hidden)
+	assert.Equal(t, "FF0000", *props.TabColor)
+	assert.False(t, *props.Hidden)
+}
@@ -0,0 +1,64 @@
+package xlfill
+
+import "fmt"
+
+// SheetDisposition controls what happens to a sheet after filling.
+type SheetDisposition string
+
+const (
+	SheetKeep   SheetDisposition = "keep"   // leave the sheet in the output
+	SheetHide   SheetDisposition = "hide"   // keep the sheet but hide it
+	SheetDelete SheetDisposition = "delete" // remove the sheet from the output
+)
+
+// parseSheetDisposition validates a templateSheet attribute value.
+func parseSheetDisposition(s string) (SheetDisposition, error) {
+	switch d := SheetDisposition(s); d {
+	case "", SheetKeep, SheetHide, SheetDelete:
+		return d, nil
+	default:
+		return "", fmt.Errorf("invalid templateSheet %q: expected keep, hide or delete", s)
+	}
+}
+
+// disposeSheet applies a disposition to a sheet.
+func disposeSheet(tx Transformer, sheet string, d SheetDisposition) error {
+	switch d {
+	case SheetDelete:
+		return tx.DeleteSheet(sheet)
+	case SheetHide:
+		return tx.SetHidden(sheet, true)
+	}
+	return nil
+}
+
+// sheetDispositions resolves per-sheet dispositions: WithTemplateSheets entries
+// override jx:area templateSheet attributes.
+func (f *Filler) sheetDispositions(areas []*Area) (map[string]SheetDisposition, error) {
+	dispositions := make(map[string]SheetDisposition)
+	for _, area := range areas {
+		if area.TemplateSheet != "" {
+			dispositions[area.StartCell.Sheet] = area.TemplateSheet
+		}
+	}
+	for sheet, d := range f.opts.templateSheets {
+		if _, err := parseSheetDisposition(string(d)); err != nil || d == "" {
+			return nil, fmt.Errorf("sheet %q: invalid disposition %q", sheet, d)
+		}
+		dispositions[sheet] = d
+	}
+	return dispositions, nil
+}
+
+// defaultTemplateDisposition is applied to multisheet template sheets without
+// an explicit disposition.
+func (f *Filler) defaultTemplateDisposition() SheetDisposition {
+	switch {
+	case f.opts.keepTemplateSheet:
+		return SheetKeep
+	case f.opts.hideTemplateSheet:
+		return SheetHide
+	default:
+		return SheetDelete
+	}
+}
@@ -0,0 +1,237 @@
+package xlfill
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createSheetLifecycleTemplate creates a workbook with a multisheet "template"
+// sheet and two static sheets, "Static" and "Lookup".
+func createSheetLifecycleTemplate(t *testing.T, name, lookupAreaAttrs string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", "template")
+	f.SetCellValue("template", "A1", "${e.Name}")
+	f.AddComment("template", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A1\")\njx:each(items=\"depts\" var=\"e\" multisheet=\"names\" lastCell=\"A1\")"})
+	for _, s := range []string{"Static", "Lookup"} {
+		_, err := f.NewSheet(s)
+		require.NoError(t, err)
+		f.SetCellValue(s, "A1", s)
+	}
+	f.AddComment("Lookup", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A1"` + lookupAreaAttrs + `)`})
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func fillSheetLifecycle(t *testing.T, tmpl string, opts ...Option) *excelize.File {
+	t.Helper()
+	out, err := FillBytes(tmpl, map[string]any{
+		"depts": []map[string]any{{"Name": "Sales"}, {"Name": "Ops"}},
+		"names": []string{"Sales", "Ops"},
+	}, opts...)
+	require.NoError(t, err)
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func isVisible(t *testing.T, f *excelize.File, sheet string) bool {
+	t.Helper()
+	visible, err := f.GetSheetVisible(sheet)
+	require.NoError(t, err)
+	return visible
+}
+
+func TestTemplateSheet_DefaultDeletesMultisheetTemplate(t *testing.T) {
+	f := fillSheetLifecycle(t, createSheetLifecycleTemplate(t, "lifecycle_default.xlsx", ""))
+	assert.Equal(t, []string{"Static", "Lookup", "Sales", "Ops"}, f.GetSheetList())
+}
+
+func TestSheetOrderAndActiveSheet(t *testing.T) {
+	tmpl := createSheetLifecycleTemplate(t, "lifecycle_order.xlsx", "")
+	activeSheet := func(f *excelize.File) string { return f.GetSheetName(f.GetActiveSheetIndex()) }
+
+	// The deleted multisheet template hands over to the first generated sheet
+	f := fillSheetLifecycle(t, tmpl)
+	assert.Equal(t, "Sales", activeSheet(f))
+
+	// A print area follows its sheet when the sheets are reordered
+	tf, err := excelize.OpenFile(tmpl)
+	require.NoError(t, err)
+	require.NoError(t, tf.SetDefinedName(&excelize.DefinedName{Name: "_xlnm.Print_Area", RefersTo: "Lookup!$A$1:$B$2", Scope: "Lookup"}))
+	require.NoError(t, tf.Save())
+	tf.Close()
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		f = fillSheetLifecycle(t, tmpl, append(opts, WithSheetOrder([]string{"Lookup", "Ops"}), WithActiveSheet("Static"))...)
+		assert.Equal(t, []string{"Lookup", "Ops", "Static", "Sales"}, f.GetSheetList())
+		assert.Equal(t, "Static", activeSheet(f))
+		assert.Equal(t, []excelize.DefinedName{{Name: "_xlnm.Print_Area", RefersTo: "Lookup!$A$1:$B$2", Scope: "Lookup"}}, f.GetDefinedName())
+	}
+
+	_, err = FillBytes(tmpl, map[string]any{"depts": []any{}, "names": []string{}}, WithSheetOrder([]string{"Summary"}))
+	assert.ErrorContains(t, err, `set sheet order: sheet "Summary" not found`)
+	_, err = FillBytes(tmpl, map[string]any{"depts": []any{}, "names": []string{}},
+		WithTemplateSheets(map[string]SheetDisposition{"Lookup": SheetHide}), WithActiveSheet("Lookup"))
+	assert.ErrorContains(t, err, `set active sheet: sheet "Lookup" is hidden`)
+}
+
+func TestTemplateSheet_GlobalKeepAndHide(t *testing.T) {
+	tmpl := createSheetLifecycleTemplate(t, "lifecycle_global.xlsx", "")
+
+	f := fillSheetLifecycle(t, tmpl, WithKeepTemplateSheet(true))
+	assert.Contains(t, f.GetSheetList(), "template")
+	assert.True(t, isVisible(t, f, "template"))
+
+	f = fillSheetLifecycle(t, tmpl, WithHideTemplateSheet(true))
+	assert.Contains(t, f.GetSheetList(), "template")
+	assert.False(t, isVisible(t, f, "template"))
+}
+
+func TestTemplateSheet_PerSheetOptionOverrides(t *testing.T) {
+	tmpl := createSheetLifecycleTemplate(t, "lifecycle_option.xlsx", "")
+	f := fillSheetLifecycle(t, tmpl,
+		WithKeepTemplateSheet(true),
+		WithTemplateSheets(map[string]SheetDisposition{"template": SheetHide, "Static": SheetDelete}),
+	)
+	assert.Equal(t, []string{"template", "Lookup", "Sales", "Ops"}, f.GetSheetList())
+	assert.False(t, isVisible(t, f, "template"))
+	assert.True(t, isVisible(t, f, "Lookup"))
+}
+
+func TestTemplateSheet_AreaAttribute(t *testing.T) {
+	tmpl := createSheetLifecycleTemplate(t, "lifecycle_attr.xlsx", ` templateSheet="hide"`)
+	f := fillSheetLifecycle(t, tmpl)
+	assert.False(t, isVisible(t, f, "Lookup"))
+	assert.True(t, isVisible(t, f, "Static"))
+
+	f = fillSheetLifecycle(t, tmpl, WithTemplateSheets(map[string]SheetDisposition{"Lookup": SheetDelete}))
+	assert.NotContains(t, f.GetSheetList(), "Lookup")
+}
+
+func TestTemplateSheet_InvalidDisposition(t *testing.T) {
+	tmpl := createSheetLifecycleTemplate(t, "lifecycle_invalid.xlsx", ` templateSheet="remove"`)
+	_, err := FillBytes(tmpl, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid templateSheet "remove"`)
+
+	tmpl = createSheetLifecycleTemplate(t, "lifecycle_invalid_opt.xlsx", "")
+	_, err = FillBytes(tmpl, nil, WithTemplateSheets(map[string]SheetDisposition{"Static": "archive"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid disposition "archive"`)
+}
+
+func TestMultisheet_SheetVariable(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "template")
+	f.SetCellValue("template", "A1", "${sheet.Name}: ${sheet.Index + 1} of ${sheet.Count}")
+	f.SetCellValue("template", "A2", "${e.Name} at row ${_row}")
+	f.AddComment("template", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A2\")\njx:each(items=\"depts\" var=\"e\" multisheet=\"names\" lastCell=\"A2\")"})
+	_, err := f.NewSheet("Cover")
+	require.NoError(t, err)
+	f.SetCellValue("Cover", "A1", "${sheet}")
+	f.AddComment("Cover", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "multisheet_sheet_var.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, map[string]any{
+			"depts": []map[string]any{{"Name": "Sales"}, {"Name": "Ops"}},
+			"names": []string{"Sales", "Ops"},
+			"sheet": "cover",
+		}, opts...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		for sheet, want := range map[string][]string{
+			"Sales": {"Sales: 1 of 2", "Sales at row 2"},
+			"Ops":   {"Ops: 2 of 2", "Ops at row 2"},
+			"Cover": {"cover"},
+		} {
+			for i, w := range want {
+				v, _ := res.GetCellValue(sheet, fmt.Sprintf("A%d", i+1))
+				assert.Equal(t, w, v, sheet)
+			}
+		}
+	}
+}
+
+func TestMultisheet_SheetAreas(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", "template")
+	f.SetCellValue("template", "A1", "${dept.Name}")
+	f.SetCellValue("template", "B1", "Page ${sheet.Index + 1} of ${sheet.Count}")
+	f.AddComment("template", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B1")`})
+	f.SetCellValue("template", "A3", "Name")
+	f.SetCellValue("template", "B3", "Salary")
+	f.AddComment("template", excelize.Comment{Cell: "A3", Author: "xlfill",
+		Text: `jx:area(lastCell="B4")` + "\n" + `jx:each(items="depts" var="dept" multisheet="names" lastCell="B4")`})
+	f.SetCellValue("template", "A4", "${e.Name}")
+	f.SetCellValue("template", "B4", "${e.Salary}")
+	f.AddComment("template", excelize.Comment{Cell: "A4", Author: "xlfill", Text: `jx:each(items="dept.Employees" var="e" lastCell="B4")`})
+	f.SetCellValue("template", "A6", "Total")
+	f.SetCellValue("template", "B6", `${sum(dept.Employees, "Salary")}`)
+	f.AddComment("template", excelize.Comment{Cell: "A6", Author: "xlfill", Text: `jx:area(lastCell="B6")`})
+	f.AddComment("template", excelize.Comment{Cell: "B6", Author: "xlfill", Text: "Before bonuses"})
+	tmpl := filepath.Join(testdataDir(t), "multisheet_sheet_areas.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{
+		"depts": []map[string]any{
+			{"Name": "Sales", "Employees": []map[string]any{{"Name": "Alice", "Salary": 5000}, {"Name": "Bob", "Salary": 4000}, {"Name": "Carol", "Salary": 3000}}},
+			{"Name": "Ops", "Employees": []map[string]any{{"Name": "Dan", "Salary": 2000}}},
+		},
+		"names": []string{"Sales", "Ops"},
+	}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, append(opts, WithStripMarkupComments(true))...)
+		require.NoError(t, err)
+		res := openOutput(t, out)
+		assert.Equal(t, []string{"Sales", "Ops"}, res.GetSheetList())
+
+		rows, err := res.GetRows("Sales")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Sales", "Page 1 of 2"},
+			nil,
+			{"Name", "Salary"},
+			{"Alice", "5000"},
+			{"Bob", "4000"},
+			{"Carol", "3000"},
+			nil,
+			{"Total", "12000"},
+		}, rows)
+		rows, err = res.GetRows("Ops")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Ops", "Page 2 of 2"},
+			nil,
+			{"Name", "Salary"},
+			{"Dan", "2000"},
+			nil,
+			{"Total", "2000"},
+		}, rows)
+
+		// Comments follow their cells onto every sheet; markup is stripped
+		for sheet, cell := range map[string]string{"Sales": "B8", "Ops": "B6"} {
+			comments, err := res.GetComments(sheet)
+			require.NoError(t, err)
+			require.Len(t, comments, 1, sheet)
+			assert.Equal(t, cell, comments[0].Cell, sheet)
+			assert.Equal(t, "Before bonuses", comments[0].Text, sheet)
+		}
+	}
+}
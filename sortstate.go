@@ -0,0 +1,83 @@
+package xlfill
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortStateCommand implements jx:sortState. It renders its area and adds an
+// AutoFilter over the output, headers in its first row, whose filter button on
+// Column shows the data rows as sorted. The rows keep the order they were
+// written in; sort them with the orderBy of the jx:each that writes them.
+type SortStateCommand struct {
+	Column     string // template column the data is sorted by (e.g., "B")
+	Descending bool   // order="descending"
+	Area       *Area
+}
+
+func (c *SortStateCommand) Name() string { return "sortState" }
+func (c *SortStateCommand) Reset()       {}
+
+// newSortStateCommandFromAttrs creates a SortStateCommand from parsed attributes.
+func newSortStateCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &SortStateCommand{Column: strings.ToUpper(attrs["column"])}
+	if cmd.Column == "" {
+		return nil, fmt.Errorf("sortState command requires 'column' attribute")
+	}
+	if _, err := NameToCol(cmd.Column); err != nil {
+		return nil, fmt.Errorf("sortState command: invalid column %q (expected a column letter such as B)", attrs["column"])
+	}
+	switch strings.ToLower(attrs["order"]) {
+	case "", "ascending":
+	case "descending":
+		cmd.Descending = true
+	default:
+		return nil, fmt.Errorf("sortState command: invalid order %q (expected ascending or descending)", attrs["order"])
+	}
+	return cmd, nil
+}
+
+// ApplyAt processes the area and then adds the AutoFilter over its output.
+func (c *SortStateCommand) ApplyAt(cellRef CellRef, ctx *Context, tx Transformer) (Size, error) {
+	if c.Area == nil {
+		return ZeroSize, nil
+	}
+
+	col, err := NameToCol(c.Column)
+	if err != nil {
+		return ZeroSize, fmt.Errorf("sortState command: %w", err)
+	}
+	offset := col - c.Area.StartCell.Col
+	if offset < 0 || offset >= c.Area.AreaSize.Width {
+		return ZeroSize, fmt.Errorf("sortState command: column %s is outside the command area", c.Column)
+	}
+
+	size, err := c.Area.ApplyAt(cellRef, ctx)
+	if err != nil {
+		return ZeroSize, err
+	}
+	if size.Width <= 0 || size.Height <= 0 {
+		return size, nil
+	}
+
+	last := CellRef{Sheet: cellRef.Sheet, Row: cellRef.Row + size.Height - 1, Col: cellRef.Col + size.Width - 1}
+	if err := setAutoFilter(tx, NewAreaRef(cellRef, last), cellRef.Col+offset, c.Descending); err != nil {
+		return ZeroSize, fmt.Errorf("set autofilter: %w", err)
+	}
+	return size, nil
+}
+
+// autoFilterer is implemented by transformers that can add AutoFilters.
+type autoFilterer interface {
+	// SetAutoFilter adds an AutoFilter over area, headers in its first row,
+	// showing its data rows as sorted by column sortCol.
+	SetAutoFilter(area AreaRef, sortCol int, descending bool) error
+}
+
+// setAutoFilter adds an AutoFilter over area showing column sortCol as sorted.
+func setAutoFilter(tx Transformer, area AreaRef, sortCol int, descending bool) error {
+	if _, ok := unwrapTransformer(tx).(autoFilterer); !ok {
+		return fmt.Errorf("transformer %T cannot add AutoFilters", unwrapTransformer(tx))
+	}
+	return tx.(autoFilterer).SetAutoFilter(area, sortCol, descending)
+}
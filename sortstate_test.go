@@ -0,0 +1,94 @@
+package xlfill
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetXML returns the worksheet part of Sheet1 of an xlsx package.
+func sheetXML(t *testing.T, out []byte) string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+	rc, err := zr.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSortStateCommand(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "B1", "Name")
+	f.SetCellValue("Sheet1", "C1", "Payment")
+	f.SetCellValue("Sheet1", "B2", "${e.Name}")
+	f.SetCellValue("Sheet1", "C2", "${e.Payment}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"C2\")\njx:sortState(column=\"c\" order=\"Descending\" lastCell=\"C2\")"})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "B2", Author: "xlfill",
+		Text: `jx:each(items="employees" var="e" orderBy="e.Payment DESC" lastCell="C2")`})
+	tab := "FF0000"
+	require.NoError(t, f.SetSheetProps("Sheet1", &excelize.SheetPropsOptions{TabColorRGB: &tab}))
+	tmpl := filepath.Join(testdataDir(t), "sort_state.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"employees": []map[string]any{
+		{"Name": "Alice", "Payment": 1500}, {"Name": "Bob", "Payment": 2300}, {"Name": "Carol", "Payment": 1800},
+	}}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		out, err := FillBytes(tmpl, data, opts...)
+		require.NoError(t, err)
+		got, err := openOutput(t, out).GetRows("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "Bob", "2300"}, got[1])
+		assert.Equal(t, []string{"", "Alice", "1500"}, got[3])
+
+		ws := sheetXML(t, out)
+		assert.Contains(t, ws, `<autoFilter ref="$B$1:$C$4">`)
+		assert.Contains(t, ws, `<sortState ref="B2:C4"><sortCondition descending="1" ref="C2:C4"/></sortState>`)
+		assert.NotContains(t, ws, `filterMode`, "an unfiltered sheet is not marked filtered")
+		props, err := openOutput(t, out).GetSheetProps("Sheet1")
+		require.NoError(t, err)
+		assert.Equal(t, "FF0000", *props.TabColorRGB, "other sheet properties are kept")
+	}
+
+	// Without data rows only the AutoFilter is added
+	out, err := FillBytes(tmpl, map[string]any{"employees": []any{}})
+	require.NoError(t, err)
+	ws := sheetXML(t, out)
+	assert.Contains(t, ws, `<autoFilter ref="$B$1:$C$1">`)
+	assert.NotContains(t, ws, `<sortState`)
+}
+
+func TestSortStateCommand_Attributes(t *testing.T) {
+	cmd, err := newSortStateCommandFromAttrs(map[string]string{"column": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, &SortStateCommand{Column: "B"}, cmd)
+	assert.Equal(t, "sortState", cmd.Name())
+
+	_, err = newSortStateCommandFromAttrs(map[string]string{})
+	assert.ErrorContains(t, err, "requires 'column'")
+	_, err = newSortStateCommandFromAttrs(map[string]string{"column": "B2"})
+	assert.ErrorContains(t, err, `invalid column "B2"`)
+	_, err = newSortStateCommandFromAttrs(map[string]string{"column": "B", "order": "up"})
+	assert.ErrorContains(t, err, `invalid order "up"`)
+
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Name")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"A2\")\njx:sortState(column=\"D\" lastCell=\"A2\")"})
+	tmpl := filepath.Join(testdataDir(t), "sort_state_outside.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+	_, err = FillBytes(tmpl, nil)
+	assert.ErrorContains(t, err, "column D is outside the command area")
+}
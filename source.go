@@ -0,0 +1,61 @@
+package xlfill
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// TemplateSource provides the template workbook, e.g. from object storage.
+// Set it with WithTemplateSource.
+type TemplateSource interface {
+	// OpenTemplate returns the template file's content. The caller closes it.
+	OpenTemplate(ctx context.Context) (io.ReadCloser, error)
+}
+
+// OutputSink receives the filled workbook, e.g. as an object in a bucket.
+// The output is written in the format of the template.
+type OutputSink interface {
+	// CreateOutput returns a writer for the output. The output is complete
+	// once Close returns nil. When writing fails and the writer has a
+	// CloseWithError(error) error method, as io.PipeWriter does, it is closed
+	// with that instead, so the sink can discard a partial output.
+	CreateOutput(ctx context.Context) (io.WriteCloser, error)
+}
+
+// WithTemplateSource reads the template from src.
+func WithTemplateSource(src TemplateSource) Option {
+	return func(o *Options) { o.templateSource = src }
+}
+
+// FillTo processes the template with data and writes the output to sink. The
+// output is only created once the template has been filled, and the fill stops
+// with ctx.Err() once ctx is done.
+func (f *Filler) FillTo(ctx context.Context, sink OutputSink, data map[string]any) error {
+	opts := *f.opts
+	opts.cancel = ctx
+	filler := *f
+	filler.opts = &opts
+	_, err := filler.fill(data, func() (io.WriteCloser, error) {
+		w, err := sink.CreateOutput(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("create output: %w", err)
+		}
+		return w, nil
+	}, "")
+	return err
+}
+
+// readTemplateSource reads the template from f's TemplateSource.
+func (f *Filler) readTemplateSource() ([]byte, error) {
+	ctx := f.opts.cancel
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r, err := f.opts.templateSource.OpenTemplate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,101 @@
+package xlfill
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memSource serves a template from memory.
+type memSource []byte
+
+func (s memSource) OpenTemplate(context.Context) (io.ReadCloser, error) {
+	if s == nil {
+		return nil, errors.New("no such object")
+	}
+	return io.NopCloser(bytes.NewReader(s)), nil
+}
+
+// memSink collects the output in memory and records how it was closed.
+type memSink struct {
+	buf      bytes.Buffer
+	writeErr error
+	created  bool
+	closed   bool
+	closeErr error
+}
+
+func (s *memSink) CreateOutput(context.Context) (io.WriteCloser, error) {
+	s.created = true
+	return s, nil
+}
+
+func (s *memSink) Write(p []byte) (int, error) {
+	if s.writeErr != nil {
+		return 0, s.writeErr
+	}
+	return s.buf.Write(p)
+}
+
+func (s *memSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func (s *memSink) CloseWithError(err error) error {
+	s.closeErr = err
+	return nil
+}
+
+func TestFiller_FillTo(t *testing.T) {
+	tmpl, err := os.ReadFile(createBasicTemplate(t))
+	require.NoError(t, err)
+	data := map[string]any{"employees": []any{map[string]any{"Name": "Alice", "Age": 30, "Salary": 5000.0}}}
+
+	sink := &memSink{}
+	filler := NewFiller(WithTemplateSource(memSource(tmpl)))
+	require.NoError(t, filler.FillTo(context.Background(), sink, data))
+	assert.True(t, sink.closed)
+	v, _ := openOutput(t, sink.buf.Bytes()).GetCellValue("Sheet1", "A2")
+	assert.Equal(t, "Alice", v)
+
+	// A failed fill creates no output
+	sink = &memSink{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = filler.FillTo(ctx, sink, data)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, sink.created)
+
+	// A failed write discards the partial output
+	sink = &memSink{writeErr: errors.New("disk full")}
+	err = filler.FillTo(context.Background(), sink, data)
+	assert.ErrorContains(t, err, "write output: disk full")
+	assert.False(t, sink.closed)
+	assert.ErrorContains(t, sink.closeErr, "disk full")
+
+	// The filler's own options are not changed by FillTo
+	_, err = filler.FillBytes(data)
+	require.NoError(t, err)
+
+	_, err = NewFiller(WithTemplateSource(memSource(nil))).FillBytes(data)
+	assert.ErrorContains(t, err, "open template source: no such object")
+}
+
+func TestFiller_FillCreatesOutputOnceFilled(t *testing.T) {
+	// A fill that fails keeps the previous output file as it was
+	out := filepath.Join(t.TempDir(), "report.xlsx")
+	require.NoError(t, os.WriteFile(out, []byte("last week"), 0o600))
+	err := Fill(filepath.Join(t.TempDir(), "missing.xlsx"), out, nil)
+	require.Error(t, err)
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "last week", string(content))
+}
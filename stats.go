@@ -0,0 +1,65 @@
+package xlfill
+
+import "time"
+
+// FillStats is the time a fill spent in each phase, for tracking performance
+// across releases and data sizes. Set it with WithStats.
+type FillStats struct {
+	Open     time.Duration // reading the template workbook
+	Parse    time.Duration // building the areas from the template's commands
+	Apply    time.Duration // applying the areas to the data
+	Formulas time.Duration // rewriting formulas for the expanded cells
+	Write    time.Duration // writing the output
+	Total    time.Duration // the whole fill, including steps not listed above
+
+	Areas int // root areas applied
+	Cells int // output cells written from template cells
+}
+
+// WithStats sets stats to the timings of each fill. A Filler used for several
+// fills at once overwrites the stats of one with another's, so measure with a
+// Filler of its own.
+func WithStats(stats *FillStats) Option {
+	return func(o *Options) { o.stats = stats }
+}
+
+// fillPhase names a phase of FillStats.
+type fillPhase int
+
+const (
+	phaseOpen fillPhase = iota
+	phaseParse
+	phaseApply
+	phaseFormulas
+	phaseWrite
+	phaseTotal
+)
+
+// record sets phase to the time since start. Nil stats record nothing.
+func (s *FillStats) record(phase fillPhase, start time.Time) {
+	if s == nil {
+		return
+	}
+	d := time.Since(start)
+	switch phase {
+	case phaseOpen:
+		s.Open = d
+	case phaseParse:
+		s.Parse = d
+	case phaseApply:
+		s.Apply = d
+	case phaseFormulas:
+		s.Formulas = d
+	case phaseWrite:
+		s.Write = d
+	case phaseTotal:
+		s.Total = d
+	}
+}
+
+// reset clears the stats of an earlier fill.
+func (s *FillStats) reset() {
+	if s != nil {
+		*s = FillStats{}
+	}
+}
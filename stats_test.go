@@ -0,0 +1,44 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWithStats(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${e.Name}")
+	f.SetCellValue("Sheet1", "B1", "${e.Qty}")
+	f.SetCellFormula("Sheet1", "B2", "SUM(B1)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B2")` + "\n" +
+		`jx:each(items="items" var="e" lastCell="B1")`})
+	tmpl := filepath.Join(testdataDir(t), "stats.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{"items": []map[string]any{{"Name": "a", "Qty": 1}, {"Name": "b", "Qty": 2}, {"Name": "c", "Qty": 3}}}
+	var stats FillStats
+	_, err := FillBytes(tmpl, data, WithStats(&stats))
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Areas)
+	assert.Equal(t, 8, stats.Cells, "three rows and the total row of two cells")
+	for name, d := range map[string]any{"Open": stats.Open, "Parse": stats.Parse, "Apply": stats.Apply,
+		"Formulas": stats.Formulas, "Write": stats.Write, "Total": stats.Total} {
+		assert.Positive(t, d, name)
+	}
+	assert.GreaterOrEqual(t, stats.Total, stats.Open+stats.Parse+stats.Apply+stats.Formulas+stats.Write)
+
+	// Each fill replaces the stats of the last; Apply writes nothing
+	file, err := excelize.OpenFile(tmpl)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = NewFiller(WithStats(&stats)).Apply(file, data)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Open)
+	assert.Zero(t, stats.Write)
+	assert.Positive(t, stats.Apply)
+}
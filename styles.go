@@ -0,0 +1,24 @@
+package xlfill
+
+import "fmt"
+
+// StyledValue is a cell value with a named style to lay over the cell's own
+// style. Template expressions create it with styled(value, "name").
+type StyledValue struct {
+	Value any
+	Style string // a style registered with WithStyles or WithStyleFromCell
+}
+
+// String returns the value as text, for styled values inside mixed content.
+func (s StyledValue) String() string {
+	if s.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(s.Value)
+}
+
+// Styled creates a StyledValue for use in template expressions.
+// Usage in template: ${styled(e.Balance, e.Balance < 0 ? "warn" : "ok")}
+func Styled(value any, style string) StyledValue {
+	return StyledValue{Value: value, Style: style}
+}
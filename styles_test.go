@@ -0,0 +1,114 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createStyledValueTemplate creates a balance list using styled() and a hidden
+// "Styles" sheet with a red "warn" cell.
+//
+//	Sheet1!A1: "${a.Name}"  [jx:area(lastCell="B1"), jx:each(items="accounts" var="a" lastCell="B1")]
+//	Sheet1!B1: "${styled(a.Balance, a.Balance < 0 ? "warn" : "ok")}" (number format 4)
+//	Styles!A1: red fill (hidden sheet)
+func createStyledValueTemplate(t *testing.T, name, b1 string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${a.Name}")
+	f.SetCellValue("Sheet1", "B1", b1)
+	num, err := f.NewStyle(&excelize.Style{NumFmt: 4})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Sheet1", "B1", "B1", num))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"B1\")\njx:each(items=\"accounts\" var=\"a\" lastCell=\"B1\")"})
+
+	_, err = f.NewSheet("Styles")
+	require.NoError(t, err)
+	red, err := f.NewStyle(&excelize.Style{Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Styles", "A1", "A1", red))
+	require.NoError(t, f.SetSheetVisible("Styles", false))
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+var accountsData = map[string]any{"accounts": []map[string]any{
+	{"Name": "Cash", "Balance": 120.5},
+	{"Name": "Loan", "Balance": -40},
+	{"Name": "Card", "Balance": -5},
+}}
+
+func openOutput(t *testing.T, out []byte) *excelize.File {
+	t.Helper()
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func styleOf(t *testing.T, f *excelize.File, sheet, cell string) (int, *excelize.Style) {
+	t.Helper()
+	id, err := f.GetCellStyle(sheet, cell)
+	require.NoError(t, err)
+	style, err := f.GetStyle(id)
+	require.NoError(t, err)
+	return id, style
+}
+
+func TestStyled_ExpressionWithStyleFromCell(t *testing.T) {
+	tmpl := createStyledValueTemplate(t, "styled_value.xlsx", `${styled(a.Balance, a.Balance < 0 ? "warn" : "ok")}`)
+	out, err := FillBytes(tmpl, accountsData,
+		WithStyleFromCell("warn", "Styles!A1"),
+		WithStyles(map[string]*excelize.Style{"ok": {Font: &excelize.Font{Color: "006100"}}}),
+	)
+	require.NoError(t, err)
+	f := openOutput(t, out)
+
+	v, _ := f.GetCellValue("Sheet1", "B2", excelize.Options{RawCellValue: true})
+	assert.Equal(t, "-40", v)
+
+	_, ok := styleOf(t, f, "Sheet1", "B1")
+	assert.Empty(t, ok.Fill.Color)
+	require.NotNil(t, ok.Font)
+	assert.Equal(t, "006100", ok.Font.Color)
+	assert.Equal(t, 4, ok.NumFmt)
+
+	// Both negative balances share one created style that keeps the number format
+	b2, warn := styleOf(t, f, "Sheet1", "B2")
+	b3, _ := styleOf(t, f, "Sheet1", "B3")
+	assert.Equal(t, b2, b3)
+	assert.Equal(t, []string{"FFC7CE"}, warn.Fill.Color)
+	assert.Equal(t, 4, warn.NumFmt)
+
+	_, name := styleOf(t, f, "Sheet1", "A2")
+	assert.Empty(t, name.Fill.Color)
+}
+
+func TestStyled_MixedContentWritesText(t *testing.T) {
+	tmpl := createStyledValueTemplate(t, "styled_mixed.xlsx", `Balance: ${styled(a.Balance, "warn")}`)
+	out, err := FillBytes(tmpl, accountsData, WithStyleFromCell("warn", "Styles!A1"))
+	require.NoError(t, err)
+	f := openOutput(t, out)
+
+	v, _ := f.GetCellValue("Sheet1", "B2")
+	assert.Equal(t, "Balance: -40", v)
+}
+
+func TestStyled_UnknownStyle(t *testing.T) {
+	tmpl := createStyledValueTemplate(t, "styled_unknown.xlsx", `${styled(a.Balance, "warn")}`)
+	_, err := FillBytes(tmpl, accountsData)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown style "warn"`)
+
+	_, err = FillBytes(tmpl, accountsData, WithStyleFromCell("warn", "A1"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `style "warn": "A1" is not a cell reference with a sheet name`)
+}
@@ -0,0 +1,95 @@
+package xlfill
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TocCommand implements jx:toc, which writes a table of contents: one row per
+// sheet, each a hyperlink to cell A1 of that sheet. It lists the sheets created
+// by multisheet jx:each in generation order, or every other sheet of the
+// workbook when no sheets were generated.
+//
+// Areas containing a jx:toc are processed after all other areas so the sheets
+// generated elsewhere in the workbook are known.
+type TocCommand struct {
+	IncludeHidden bool // list hidden sheets too (default: false)
+}
+
+func (c *TocCommand) Name() string { return "toc" }
+func (c *TocCommand) Reset()       {}
+
+// newTocCommandFromAttrs creates a TocCommand from parsed attributes.
+func newTocCommandFromAttrs(attrs map[string]string) (Command, error) {
+	cmd := &TocCommand{}
+	switch strings.ToLower(attrs["includeHidden"]) {
+	case "", "false":
+	case "true":
+		cmd.IncludeHidden = true
+	default:
+		return nil, fmt.Errorf("toc command: includeHidden must be \"true\" or \"false\", got %q", attrs["includeHidden"])
+	}
+	return cmd, nil
+}
+
+// ApplyAt writes the sheet links downward from cellRef.
+func (c *TocCommand) ApplyAt(cellRef CellRef, ctx *Context, transformer Transformer) (Size, error) {
+	sheets := ctx.GeneratedSheets()
+	if len(sheets) == 0 {
+		for _, name := range transformer.GetSheetNames() {
+			if name != cellRef.Sheet && name != configSheetName {
+				sheets = append(sheets, name)
+			}
+		}
+	}
+
+	row := 0
+	for _, name := range sheets {
+		if !c.IncludeHidden && isHidden(transformer, name) {
+			continue
+		}
+		ref := NewCellRef(cellRef.Sheet, cellRef.Row+row, cellRef.Col)
+		if err := transformer.SetCellHyperLink(ref, sheetLocation(name), name); err != nil {
+			return ZeroSize, fmt.Errorf("toc link to sheet %q: %w", name, err)
+		}
+		row++
+	}
+	if row == 0 {
+		return ZeroSize, nil
+	}
+	return Size{Width: 1, Height: row}, nil
+}
+
+// hiddenReader is implemented by transformers that know which sheets are hidden.
+type hiddenReader interface {
+	// IsHidden reports whether a sheet is hidden.
+	IsHidden(name string) bool
+}
+
+// isHidden reports whether a sheet is hidden; sheets of transformers without
+// IsHidden count as visible.
+func isHidden(tx Transformer, name string) bool {
+	hr, ok := unwrapTransformer(tx).(hiddenReader)
+	return ok && hr.IsHidden(name)
+}
+
+// sheetLocation returns an internal link target for cell A1 of a sheet, e.g. 'Q1 Sales'!A1.
+func sheetLocation(sheet string) string {
+	return "'" + strings.ReplaceAll(sheet, "'", "''") + "'!A1"
+}
+
+// areaContainsCommand reports whether an area or any nested command area holds
+// a command with the given name.
+func areaContainsCommand(area *Area, name string) bool {
+	for _, b := range area.Bindings {
+		if b.Command.Name() == name {
+			return true
+		}
+		for _, child := range childAreas(b.Command) {
+			if areaContainsCommand(child, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
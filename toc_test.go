@@ -0,0 +1,102 @@
+package xlfill
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTocTemplate creates an Index sheet with a jx:toc and, optionally,
+// a multisheet template sheet.
+//
+//	Index!A1: "Contents"  [jx:area(lastCell="A3")]
+//	Index!A2:             [jx:toc(lastCell="A2")]
+//	Index!A3: "End"
+func createTocTemplate(t *testing.T, name string, multisheet bool) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", "Index")
+	f.SetCellValue("Index", "A1", "Contents")
+	f.SetCellValue("Index", "A3", "End")
+	f.AddComment("Index", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A3")`})
+	f.AddComment("Index", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:toc(lastCell="A2")`})
+
+	if multisheet {
+		_, err := f.NewSheet("template")
+		require.NoError(t, err)
+		f.SetCellValue("template", "A1", "${e.Name}")
+		f.AddComment("template", excelize.Comment{Cell: "A1", Author: "xlfill",
+			Text: "jx:area(lastCell=\"A1\")\njx:each(items=\"depts\" var=\"e\" multisheet=\"names\" lastCell=\"A1\")"})
+	} else {
+		for _, s := range []string{"Summary", "Raw Data", "Lookups"} {
+			_, err := f.NewSheet(s)
+			require.NoError(t, err)
+		}
+		require.NoError(t, f.SetSheetVisible("Lookups", false))
+	}
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestToc_MultisheetGeneratedSheets(t *testing.T) {
+	tmpl := createTocTemplate(t, "toc_multisheet.xlsx", true)
+	data := map[string]any{
+		"depts": []map[string]any{{"Name": "Sales"}, {"Name": "R&D"}, {"Name": "Ops"}},
+		"names": []string{"Sales", "R&D", "O'Neil Ops"},
+	}
+	out, err := FillBytes(tmpl, data)
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, want := range map[string]string{
+		"A1": "Contents", "A2": "Sales", "A3": "R&D", "A4": "O'Neil Ops", "A5": "End",
+	} {
+		v, _ := f.GetCellValue("Index", cell)
+		assert.Equal(t, want, v, cell)
+	}
+
+	ok, link, err := f.GetCellHyperLink("Index", "A4")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "'O''Neil Ops'!A1", link)
+}
+
+func TestToc_AllSheetsSkipsHidden(t *testing.T) {
+	tmpl := createTocTemplate(t, "toc_static.xlsx", false)
+	out, err := FillBytes(tmpl, nil)
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(out))
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, want := range map[string]string{"A2": "Summary", "A3": "Raw Data", "A4": "End"} {
+		v, _ := f.GetCellValue("Index", cell)
+		assert.Equal(t, want, v, cell)
+	}
+}
+
+func TestToc_IncludeHidden(t *testing.T) {
+	tx, err := OpenTemplate(createTocTemplate(t, "toc_hidden.xlsx", false))
+	require.NoError(t, err)
+	defer tx.Close()
+
+	cmd, err := newTocCommandFromAttrs(map[string]string{"includeHidden": "true"})
+	require.NoError(t, err)
+	size, err := cmd.ApplyAt(NewCellRef("Index", 5, 0), NewContext(nil), tx)
+	require.NoError(t, err)
+	assert.Equal(t, Size{Width: 1, Height: 3}, size)
+
+	_, err = newTocCommandFromAttrs(map[string]string{"includeHidden": "yes"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package xlfill
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Debug events logged with WithLogger. Every event has a "depth" attribute, the
+// nesting of areas and commands it happened in.
+const (
+	traceAreaDiscovered = "area discovered" // area, name
+	traceCommandBound   = "command bound"   // command, area, parent
+	traceApply          = "apply"           // src, target
+	traceApplyDone      = "apply done"      // src, target, size
+	traceCommand        = "command"         // command, src, target
+	traceCommandDone    = "command done"    // command, src, target, size
+	traceTransform      = "transform"       // src, target
+	traceFormula        = "formula rewrite" // cell, before, after
+)
+
+// tracing reports whether processing events are logged.
+func (c *Context) tracing() bool {
+	return c.state != nil && c.state.logger != nil
+}
+
+// trace logs a processing event. Events are logged in the order of the writes
+// they describe, so they are queued with the writes of concurrent work. depth
+// is added to the nesting after the event is logged, or before when negative.
+func (c *Context) trace(msg string, depth int, attrs ...slog.Attr) {
+	state := c.state
+	c.run(func() error {
+		if depth < 0 {
+			state.depth += depth
+		}
+		logTrace(state.logger, msg, state.depth, attrs...)
+		if depth > 0 {
+			state.depth += depth
+		}
+		return nil
+	})
+}
+
+func logTrace(logger *slog.Logger, msg string, depth int, attrs ...slog.Attr) {
+	logger.LogAttrs(context.Background(), slog.LevelDebug, msg, append(attrs, slog.Int("depth", depth))...)
+}
+
+func sizeAttr(size Size) slog.Attr {
+	return slog.String("size", fmt.Sprintf("%dx%d", size.Width, size.Height))
+}
+
+// traceAreas logs the areas found in a template and the commands bound in them.
+func traceAreas(logger *slog.Logger, areas []*Area) {
+	var walk func(area *Area, depth int)
+	walk = func(area *Area, depth int) {
+		for _, b := range area.Bindings {
+			ref := NewAreaRef(b.StartRef, NewCellRef(b.StartRef.Sheet, b.StartRef.Row+b.Size.Height-1, b.StartRef.Col+b.Size.Width-1))
+			logTrace(logger, traceCommandBound, depth, slog.String("command", b.Command.Name()),
+				slog.String("area", ref.String()), slog.String("parent", area.SourceRef().String()))
+			for _, child := range childAreas(b.Command) {
+				walk(child, depth+1)
+			}
+		}
+	}
+	for _, area := range areas {
+		logTrace(logger, traceAreaDiscovered, 0, slog.String("area", area.SourceRef().String()), slog.String("name", area.Name))
+		walk(area, 1)
+	}
+}
+
+// Trace is a slog.Handler that records the debug events of a fill, so they can
+// be rendered as a processing tree with TraceString:
+//
+//	trace := xlfill.NewTrace()
+//	err := xlfill.Fill("template.xlsx", "out.xlsx", data, xlfill.WithLogger(slog.New(trace)))
+//	fmt.Print(trace.TraceString())
+type Trace struct {
+	mu      sync.Mutex
+	records []traceRecord
+}
+
+type traceRecord struct {
+	msg   string
+	depth int
+	attrs map[string]string
+}
+
+// NewTrace creates an empty Trace.
+func NewTrace() *Trace {
+	return &Trace{}
+}
+
+// Enabled reports whether records at level are recorded: debug and above.
+func (t *Trace) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelDebug
+}
+
+// Handle records an event.
+func (t *Trace) Handle(_ context.Context, r slog.Record) error {
+	rec := traceRecord{msg: r.Message, attrs: map[string]string{}}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "depth" {
+			rec.depth = int(a.Value.Int64())
+		} else {
+			rec.attrs[a.Key] = a.Value.String()
+		}
+		return true
+	})
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records = append(t.records, rec)
+	return nil
+}
+
+// WithAttrs returns t; attributes are not part of the trace.
+func (t *Trace) WithAttrs([]slog.Attr) slog.Handler { return t }
+
+// WithGroup returns t; groups are not part of the trace.
+func (t *Trace) WithGroup(string) slog.Handler { return t }
+
+// TraceString renders the recorded events as a tree, one event per line and
+// indented by nesting, e.g.
+//
+//	area Sheet1!A1:B3
+//	  each Sheet1!A2:B2
+//	apply Sheet1!A1:B3 → Sheet1!A1 (2x4)
+//	  transform Sheet1!A1 → Sheet1!A1
+//	  each Sheet1!A2:B2 → Sheet1!A2 (2x3)
+//	    apply Sheet1!A2:B2 → Sheet1!A2 (2x1)
+//
+// The size of an area or command is shown on the line that starts it.
+func (t *Trace) TraceString() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := make([]string, 0, len(t.records))
+	var open []int // lines of applies and commands not done yet
+	for _, r := range t.records {
+		a := r.attrs
+		var line string
+		switch r.msg {
+		case traceAreaDiscovered:
+			line = "area " + a["area"]
+			if a["name"] != "" {
+				line += fmt.Sprintf(" name=%q", a["name"])
+			}
+		case traceCommandBound:
+			line = a["command"] + " " + a["area"]
+		case traceApply:
+			line = "apply " + a["src"] + " → " + a["target"]
+		case traceCommand:
+			line = a["command"] + " " + a["src"] + " → " + a["target"]
+		case traceApplyDone, traceCommandDone:
+			if len(open) > 0 {
+				i := open[len(open)-1]
+				open = open[:len(open)-1]
+				lines[i] += " (" + a["size"] + ")"
+			}
+			continue
+		case traceTransform:
+			line = "transform " + a["src"] + " → " + a["target"]
+		case traceFormula:
+			line = fmt.Sprintf("formula %s: %s → %s", a["cell"], a["before"], a["after"])
+		default:
+			line = r.msg
+		}
+		if r.msg == traceApply || r.msg == traceCommand {
+			open = append(open, len(lines))
+		}
+		lines = append(lines, strings.Repeat("  ", max(r.depth, 0))+line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,102 @@
+package xlfill
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTraceTemplate saves a header, a jx:each row and a total.
+func createTraceTemplate(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Name")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "${e.Pay}")
+	f.SetCellFormula("Sheet1", "B3", "SUM(B2)")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3" name="pay")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="rows" var="e" lastCell="B2")`})
+	path := filepath.Join(testdataDir(t), "trace_template.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+var traceData = map[string]any{"rows": []any{
+	map[string]any{"Name": "Ann", "Pay": 10},
+	map[string]any{"Name": "Bob", "Pay": 20},
+}}
+
+func TestWithLogger_TraceString(t *testing.T) {
+	trace := NewTrace()
+	_, err := FillBytes(createTraceTemplate(t), traceData, WithLogger(slog.New(trace)))
+	require.NoError(t, err)
+
+	assert.Equal(t, strings.Join([]string{
+		`area Sheet1!A1:B3 name="pay"`,
+		`  each Sheet1!A2:B2`,
+		`apply Sheet1!A1:B3 → Sheet1!A1 (2x4)`,
+		`  transform Sheet1!A1 → Sheet1!A1`,
+		`  transform Sheet1!B1 → Sheet1!B1`,
+		`  each Sheet1!A2:B2 → Sheet1!A2 (2x2)`,
+		`    apply Sheet1!A2:B2 → Sheet1!A2 (2x1)`,
+		`      transform Sheet1!A2 → Sheet1!A2`,
+		`      transform Sheet1!B2 → Sheet1!B2`,
+		`    apply Sheet1!A2:B2 → Sheet1!A3 (2x1)`,
+		`      transform Sheet1!A2 → Sheet1!A3`,
+		`      transform Sheet1!B2 → Sheet1!B3`,
+		`  transform Sheet1!A3 → Sheet1!A4`,
+		`  transform Sheet1!B3 → Sheet1!B4`,
+		`formula Sheet1!B4: SUM(B2) → SUM(B2:B3)`,
+		``,
+	}, "\n"), trace.TraceString())
+}
+
+func TestWithLogger_StructuredEvents(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, err := FillBytes(createTraceTemplate(t), traceData, WithLogger(logger))
+	require.NoError(t, err)
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		assert.Equal(t, "DEBUG", e["level"])
+		events = append(events, e)
+	}
+	require.NotEmpty(t, events)
+	assert.Equal(t, "area discovered", events[0]["msg"])
+	assert.Equal(t, "pay", events[0]["name"])
+
+	var done map[string]any
+	for _, e := range events {
+		if e["msg"] == "command done" {
+			done = e
+		}
+	}
+	require.NotNil(t, done)
+	assert.Equal(t, map[string]any{"command": "each", "src": "Sheet1!A2:B2", "target": "Sheet1!A2",
+		"size": "2x2", "depth": 1.0}, map[string]any{"command": done["command"], "src": done["src"],
+		"target": done["target"], "size": done["size"], "depth": done["depth"]})
+}
+
+func TestWithLogger_ConcurrentTraceMatchesSerial(t *testing.T) {
+	tmpl := createConcurrencyTemplate(t, "trace_concurrency.xlsx")
+	traceOf := func(opts ...Option) string {
+		trace := NewTrace()
+		_, err := FillBytes(tmpl, concurrencyData(6), append(opts, WithLogger(slog.New(trace)))...)
+		require.NoError(t, err)
+		return trace.TraceString()
+	}
+	serial := traceOf()
+	assert.Contains(t, serial, "each template!A1:B3 → template!A1")
+	assert.Equal(t, serial, traceOf(WithConcurrency(4)))
+}
@@ -4,43 +4,88 @@ import "io"
 
 // Transformer abstracts Excel I/O operations. It reads template data into memory
 // and provides methods to transform cells from source to target positions.
+//
+// Commands, areas and the formula processor use only this interface, so custom
+// commands can be unit tested without a workbook against the in-memory
+// implementation in package xlfilltest. Rows and columns are 0-based.
 type Transformer interface {
-	// Cell data access
+	// Cell data access: template cells, read before any output is written
+
+	// GetCellData returns the template cell at ref, or nil when it is empty.
 	GetCellData(ref CellRef) *CellData
+	// GetCommentedCells returns the template cells that have comments.
 	GetCommentedCells() []*CellData
+	// GetFormulaCells returns the template cells that hold formulas.
 	GetFormulaCells() []*CellData
 
 	// Cell transformation
+
+	// Transform evaluates the template cell at src under ctx and writes it to
+	// target, recording the target for GetTargetCellRef. updateRowHeight copies
+	// the template row's height.
 	Transform(src, target CellRef, ctx *Context, updateRowHeight bool) error
+	// ClearCell removes the value of an output cell, keeping its style.
 	ClearCell(ref CellRef) error
+	// SetFormula writes a formula, without the leading "=", to an output cell.
 	SetFormula(ref CellRef, formula string) error
+	// SetCellValue writes a value to an output cell, keeping its style.
 	SetCellValue(ref CellRef, value any) error
 
 	// Target tracking for formula processing
+
+	// GetTargetCellRef returns the output cells written from a template cell.
 	GetTargetCellRef(src CellRef) []CellRef
+	// ResetTargetCellRefs forgets the output cells written so far.
 	ResetTargetCellRefs()
 
 	// Sheet data
+
+	// GetSheetNames returns the sheet names in workbook order.
 	GetSheetNames() []string
+	// GetColumnWidth returns a column's width in characters.
 	GetColumnWidth(sheet string, col int) float64
+	// GetRowHeight returns a row's height in points.
 	GetRowHeight(sheet string, row int) float64
+	// SetRowHeight sets a row's height in points.
 	SetRowHeight(sheet string, row int, height float64) error
 
 	// Sheet operations
+
+	// DeleteSheet removes a sheet.
 	DeleteSheet(name string) error
+	// SetHidden hides or shows a sheet.
 	SetHidden(name string, hidden bool) error
+	// CopySheet adds sheet dst as a copy of sheet src.
 	CopySheet(src, dst string) error
+	// MoveSheet moves a sheet directly after sheet after, or first when after is "".
+	MoveSheet(name, after string) error
+	// SetSheetOrder moves the named sheets first, in that order; the others
+	// follow in their current order.
+	SetSheetOrder(names []string) error
+	// SetActiveSheet selects the sheet shown when the output is opened.
+	SetActiveSheet(name string) error
+	// SetSheetProps sets the tab color and visibility of a sheet.
+	SetSheetProps(name string, props SheetProps) error
 
 	// Image/merge/hyperlink
+
+	// AddImage places a picture of type imgType ("png", "jpeg", ...) at cell, e.g. "B2".
 	AddImage(sheet string, cell string, imgBytes []byte, imgType string, scaleX, scaleY float64) error
+	// MergeCells merges the range from topLeft to bottomRight, e.g. "A1" and "C2".
 	MergeCells(sheet, topLeft, bottomRight string) error
+	// SetCellHyperLink writes display text linking to url to an output cell.
 	SetCellHyperLink(ref CellRef, url, display string) error
 
 	// Workbook properties
+
+	// SetRecalculateOnOpen sets whether formulas are recalculated when the output is opened.
 	SetRecalculateOnOpen(recalc bool) error
 
 	// I/O
+
+	// Write writes the output.
 	Write(w io.Writer) error
+	// Close releases the resources of the transformer.
 	Close() error
 }
 
@@ -49,6 +94,7 @@ type SheetData struct {
 	Name         string
 	ColumnWidths map[int]float64
 	Rows         map[int]*RowData
+	MergedCells  []AreaRef
 }
 
 // RowData holds in-memory data for a single row.
@@ -0,0 +1,152 @@
+package xlfill
+
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
+// UsageReport tells which data a fill read, for checking in CI that a
+// template and the data given to it still match. See WithUsageReport.
+type UsageReport struct {
+	Used       []string               // data keys read by an expression, sorted
+	Unused     []string               // data keys no expression read, sorted
+	Cells      []CellUsage            // template cells with expressions, by sheet, row and column
+	Unresolved []UnresolvedExpression // cell expressions that evaluated to nil, by cell
+}
+
+// CellUsage lists the variables the expressions of a template cell read,
+// data keys and loop variables alike.
+type CellUsage struct {
+	Cell      CellRef
+	Variables []string // sorted
+}
+
+// UnresolvedExpression is an expression of a template cell that evaluated to
+// nil, e.g. a misspelled field or a key missing from the data.
+type UnresolvedExpression struct {
+	Cell       CellRef // template cell
+	Expression string
+	Count      int     // output cells it was nil in
+	First      CellRef // first output cell it was nil in
+}
+
+// usageTracker records the expressions evaluated during a fill.
+type usageTracker struct {
+	vars       map[string][]string // variables of each expression seen
+	used       map[string]bool     // variables read outside the run variables
+	cells      map[CellRef]map[string]bool
+	unresolved map[unresolvedKey]*UnresolvedExpression
+}
+
+type unresolvedKey struct {
+	cell       CellRef
+	expression string
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{
+		vars:       map[string][]string{},
+		used:       map[string]bool{},
+		cells:      map[CellRef]map[string]bool{},
+		unresolved: map[unresolvedKey]*UnresolvedExpression{},
+	}
+}
+
+// noteEvaluation records the data read by expression. Variables bound by a
+// command, such as the var of a jx:each, are not data keys.
+func (c *Context) noteEvaluation(expression string) {
+	u := c.state.usage
+	c.state.mu.Lock()
+	vars, ok := u.vars[expression]
+	if !ok {
+		vars = ExpressionVariables(expression)
+		u.vars[expression] = vars
+	}
+	c.state.mu.Unlock()
+
+	var data []string
+	for _, name := range vars {
+		if _, ok := c.lookupRunVar(name); !ok {
+			data = append(data, name)
+		}
+	}
+
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	for _, name := range data {
+		u.used[name] = true
+	}
+	if c.source != nil {
+		cell := u.cells[*c.source]
+		if cell == nil {
+			cell = map[string]bool{}
+			u.cells[*c.source] = cell
+		}
+		for _, name := range vars {
+			cell[name] = true
+		}
+	}
+}
+
+// noteUnresolved records that a cell expression evaluated to nil.
+func (c *Context) noteUnresolved(expression string) {
+	if c.source == nil {
+		return
+	}
+	v, _ := c.lookupRunVar("_target")
+	target, _ := v.(CellRef)
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	key := unresolvedKey{cell: *c.source, expression: expression}
+	if r := c.state.usage.unresolved[key]; r != nil {
+		r.Count++
+		if cellBefore(target, r.First) {
+			r.First = target
+		}
+		return
+	}
+	c.state.usage.unresolved[key] = &UnresolvedExpression{Cell: *c.source, Expression: expression, Count: 1, First: target}
+}
+
+// usageReport returns the report of what the fill has read so far.
+func (c *Context) usageReport() UsageReport {
+	c.state.mu.Lock()
+	defer c.state.mu.Unlock()
+	u := c.state.usage
+
+	var r UsageReport
+	for _, key := range slices.Sorted(maps.Keys(c.data)) {
+		if u.used[key] {
+			r.Used = append(r.Used, key)
+		} else {
+			r.Unused = append(r.Unused, key)
+		}
+	}
+	for cell, vars := range u.cells {
+		r.Cells = append(r.Cells, CellUsage{Cell: cell, Variables: slices.Sorted(maps.Keys(vars))})
+	}
+	sort.Slice(r.Cells, func(i, j int) bool { return cellBefore(r.Cells[i].Cell, r.Cells[j].Cell) })
+	for _, e := range u.unresolved {
+		r.Unresolved = append(r.Unresolved, *e)
+	}
+	sort.Slice(r.Unresolved, func(i, j int) bool {
+		a, b := r.Unresolved[i], r.Unresolved[j]
+		if a.Cell != b.Cell {
+			return cellBefore(a.Cell, b.Cell)
+		}
+		return a.Expression < b.Expression
+	})
+	return r
+}
+
+// cellBefore orders cells by sheet, row and column.
+func cellBefore(a, b CellRef) bool {
+	if a.Sheet != b.Sheet {
+		return a.Sheet < b.Sheet
+	}
+	if a.Row != b.Row {
+		return a.Row < b.Row
+	}
+	return a.Col < b.Col
+}
@@ -0,0 +1,62 @@
+package xlfill
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestWithUsageReport(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${title}")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.SetCellValue("Sheet1", "B2", "Dept: ${e.Dept}")
+	f.SetCellValue("Sheet1", "C2", "${e.Salary * rate}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="C2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill",
+		Text: `jx:each(items="employees" var="e" select="e.Salary > minSalary" lastCell="C2")`})
+	tmpl := filepath.Join(testdataDir(t), "usage_report.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	data := map[string]any{
+		"title":     "Staff",
+		"rate":      2,
+		"minSalary": 0,
+		"currency":  "EUR",
+		"employees": []map[string]any{
+			{"Name": "Alice", "Salary": 1500},
+			{"Name": "Bob", "Dept": "Sales", "Salary": 2300},
+			{"Name": "Carol", "Salary": 1800},
+		},
+	}
+	for _, opts := range [][]Option{nil, {WithConcurrency(4)}} {
+		var report UsageReport
+		_, err := FillBytes(tmpl, data, append(opts, WithUsageReport(&report))...)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"employees", "minSalary", "rate", "title"}, report.Used)
+		assert.Equal(t, []string{"currency"}, report.Unused)
+		assert.Equal(t, []CellUsage{
+			{Cell: NewCellRef("Sheet1", 0, 0), Variables: []string{"title"}},
+			{Cell: NewCellRef("Sheet1", 1, 0), Variables: []string{"e"}},
+			{Cell: NewCellRef("Sheet1", 1, 1), Variables: []string{"e"}},
+			{Cell: NewCellRef("Sheet1", 1, 2), Variables: []string{"e", "rate"}},
+		}, report.Cells)
+		assert.Equal(t, []UnresolvedExpression{
+			{Cell: NewCellRef("Sheet1", 1, 1), Expression: "e.Dept", Count: 2, First: NewCellRef("Sheet1", 1, 1)},
+		}, report.Unresolved)
+	}
+
+	// A missing key is unresolved in every cell that reads it
+	var report UsageReport
+	_, err := FillBytes(tmpl, map[string]any{"employees": []any{}, "minSalary": 0}, WithUsageReport(&report))
+	require.NoError(t, err)
+	assert.Equal(t, []UnresolvedExpression{
+		{Cell: NewCellRef("Sheet1", 0, 0), Expression: "title", Count: 1, First: NewCellRef("Sheet1", 0, 0)},
+	}, report.Unresolved)
+	assert.Equal(t, []string{"minSalary"}, report.Unused, "the select of an empty jx:each is never evaluated")
+}
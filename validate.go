@@ -1,7 +1,10 @@
 package xlfill
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/expr-lang/expr"
@@ -15,6 +18,14 @@ const (
 	SeverityWarning                 // Template may produce unexpected results
 )
 
+// String returns "ERROR" or "WARN".
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "WARN"
+	}
+	return "ERROR"
+}
+
 // ValidationIssue represents a single problem found during template validation.
 type ValidationIssue struct {
 	Severity Severity
@@ -24,11 +35,17 @@ type ValidationIssue struct {
 
 // String formats the issue as "[ERROR] Sheet1!A2: message" or "[WARN] ...".
 func (v ValidationIssue) String() string {
-	sev := "ERROR"
-	if v.Severity == SeverityWarning {
-		sev = "WARN"
-	}
-	return fmt.Sprintf("[%s] %s: %s", sev, v.CellRef, v.Message)
+	return fmt.Sprintf("[%s] %s: %s", v.Severity, v.CellRef, v.Message)
+}
+
+// MarshalJSON encodes the issue as {"severity":"ERROR","cell":"Sheet1!A2","message":"..."},
+// a stable form for tooling such as the xlfill CLI.
+func (v ValidationIssue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Severity string `json:"severity"`
+		Cell     string `json:"cell"`
+		Message  string `json:"message"`
+	}{v.Severity.String(), v.CellRef.String(), v.Message})
 }
 
 // Validate checks a template for structural and expression errors without
@@ -42,7 +59,8 @@ func Validate(templatePath string, opts ...Option) ([]ValidationIssue, error) {
 
 // Validate opens the template and performs static validation checks.
 // Structural errors (missing jx:area, invalid cell refs) cause a non-nil error return.
-// Expression syntax errors and bounds violations are returned as issues.
+// Expression syntax errors, inverted lastCell ranges and bounds violations are
+// returned as issues.
 func (f *Filler) Validate() ([]ValidationIssue, error) {
 	tx, err := f.openTemplate()
 	if err != nil {
@@ -50,19 +68,49 @@ func (f *Filler) Validate() ([]ValidationIssue, error) {
 	}
 	defer tx.Close()
 
-	areas, err := f.BuildAreas(tx)
+	areas, problems, err := f.buildAreas(tx)
 	if err != nil {
 		return nil, fmt.Errorf("build areas: %w", err)
 	}
 
-	var issues []ValidationIssue
-	issues = append(issues, f.validateLastCellBounds(areas)...)
+	issues := problems
+	for _, area := range areas {
+		for _, b := range area.Bindings {
+			issues = append(issues, f.validateLastCellBounds(childAreas(b.Command))...)
+		}
+	}
 	issues = append(issues, f.validateExpressions(tx, areas)...)
 	issues = append(issues, f.validateCommandAttributes(areas)...)
+	issues = append(issues, validateAreaOverlaps(areas)...)
 	return issues, nil
 }
 
+// validateAreaOverlaps warns about root areas whose template ranges overlap;
+// their output would overwrite each other.
+func validateAreaOverlaps(areas []*Area) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, a := range areas {
+		ra := a.SourceRef()
+		for _, b := range areas[:i] {
+			rb := b.SourceRef()
+			if ra.First.Sheet != rb.First.Sheet {
+				continue
+			}
+			if spansOverlap(ra.First.Row, ra.Last.Row, rb.First.Row, rb.Last.Row) &&
+				spansOverlap(ra.First.Col, ra.Last.Col, rb.First.Col, rb.Last.Col) {
+				issues = append(issues, ValidationIssue{
+					Severity: SeverityWarning,
+					CellRef:  a.StartCell,
+					Message:  fmt.Sprintf("area %s overlaps area %s; the later area overwrites the earlier one's output", ra, rb),
+				})
+			}
+		}
+	}
+	return issues
+}
+
 // validateLastCellBounds checks that every command's area fits within its parent area.
+// Commands reaching outside their jx:area are reported while building the areas.
 func (f *Filler) validateLastCellBounds(areas []*Area) []ValidationIssue {
 	var issues []ValidationIssue
 	for _, area := range areas {
@@ -82,26 +130,26 @@ func (f *Filler) validateLastCellBounds(areas []*Area) []ValidationIssue {
 			}
 
 			// Recurse into child command areas
-			if childArea := getCommandArea(b.Command); childArea != nil {
-				issues = append(issues, f.validateLastCellBounds([]*Area{childArea})...)
-			}
+			issues = append(issues, f.validateLastCellBounds(childAreas(b.Command))...)
 		}
 	}
 	return issues
 }
 
-// validateExpressions checks expression syntax in all cells within areas.
+// validateExpressions checks expression syntax in all cells within areas,
+// except those of jx:raw commands, which are not evaluated.
 func (f *Filler) validateExpressions(tx Transformer, areas []*Area) []ValidationIssue {
 	var issues []ValidationIssue
 	notationBegin := f.opts.notationBegin
 	notationEnd := f.opts.notationEnd
+	raw := rawRanges(areas)
 
 	for _, area := range areas {
 		for row := 0; row < area.AreaSize.Height; row++ {
 			for col := 0; col < area.AreaSize.Width; col++ {
 				ref := NewCellRef(area.StartCell.Sheet, area.StartCell.Row+row, area.StartCell.Col+col)
 				cd := tx.GetCellData(ref)
-				if cd == nil {
+				if cd == nil || slices.ContainsFunc(raw, func(r AreaRef) bool { return r.Contains(ref) }) {
 					continue
 				}
 
@@ -128,8 +176,7 @@ func checkExpressionSyntax(ref CellRef, value, notationBegin, notationEnd string
 		if !seg.IsExpression {
 			continue
 		}
-		_, err := expr.Compile(seg.Text, expr.AllowUndefinedVariables())
-		if err != nil {
+		if err := compileSyntax(seg.Text); err != nil {
 			issues = append(issues, ValidationIssue{
 				Severity: SeverityError,
 				CellRef:  ref,
@@ -145,6 +192,10 @@ func (f *Filler) validateCommandAttributes(areas []*Area) []ValidationIssue {
 	var issues []ValidationIssue
 	for _, area := range areas {
 		for _, b := range area.Bindings {
+			if issue := compileCheck(b.StartRef, b.Command.Name(), "renderIf", b.RenderIf); issue != nil {
+				issues = append(issues, *issue)
+			}
+			issues = append(issues, unknownAttributes(b)...)
 			switch cmd := b.Command.(type) {
 			case *EachCommand:
 				if issue := compileCheck(b.StartRef, "each", "items", cmd.Items); issue != nil {
@@ -155,10 +206,51 @@ func (f *Filler) validateCommandAttributes(areas []*Area) []ValidationIssue {
 						issues = append(issues, *issue)
 					}
 				}
+				if issue := compileCheck(b.StartRef, "each", "distinct", cmd.Distinct); issue != nil {
+					issues = append(issues, *issue)
+				}
+				for _, let := range cmd.Let {
+					if issue := compileCheck(b.StartRef, "each", "let "+let.Name, let.Expression); issue != nil {
+						issues = append(issues, *issue)
+					}
+				}
+				if issue := compileCheck(b.StartRef, "each", "mergeBy", cmd.MergeBy); issue != nil {
+					issues = append(issues, *issue)
+				}
+				for _, style := range []string{cmd.OddStyle, cmd.EvenStyle} {
+					if issue := f.styleCheck(b.StartRef, "each", style); issue != nil {
+						issues = append(issues, *issue)
+					}
+				}
 			case *IfCommand:
 				if issue := compileCheck(b.StartRef, "if", "condition", cmd.Condition); issue != nil {
 					issues = append(issues, *issue)
 				}
+			case *PivotCommand:
+				for _, attr := range []struct{ name, expr string }{
+					{"items", cmd.Items}, {"rowKey", cmd.RowKey}, {"colKey", cmd.ColKey}, {"value", cmd.aggValue},
+				} {
+					if issue := compileCheck(b.StartRef, "pivot", attr.name, attr.expr); issue != nil {
+						issues = append(issues, *issue)
+					}
+				}
+			case *CallCommand:
+				for _, w := range cmd.With {
+					if issue := compileCheck(b.StartRef, "call", "with", w.Expression); issue != nil {
+						issues = append(issues, *issue)
+					}
+				}
+			case *DeleteRowIfCommand:
+				if issue := compileCheck(b.StartRef, "deleteRowIf", "condition", cmd.Condition); issue != nil {
+					issues = append(issues, *issue)
+				}
+			case *HighlightCommand:
+				if issue := compileCheck(b.StartRef, "highlight", "condition", cmd.Condition); issue != nil {
+					issues = append(issues, *issue)
+				}
+				if issue := f.styleCheck(b.StartRef, "highlight", cmd.Style); issue != nil {
+					issues = append(issues, *issue)
+				}
 			case *GridCommand:
 				if issue := compileCheck(b.StartRef, "grid", "headers", cmd.Headers); issue != nil {
 					issues = append(issues, *issue)
@@ -166,24 +258,117 @@ func (f *Filler) validateCommandAttributes(areas []*Area) []ValidationIssue {
 				if issue := compileCheck(b.StartRef, "grid", "data", cmd.Data); issue != nil {
 					issues = append(issues, *issue)
 				}
+				for _, style := range []string{cmd.HeaderStyle, cmd.DataStyle} {
+					if issue := f.styleCheck(b.StartRef, "grid", style); issue != nil {
+						issues = append(issues, *issue)
+					}
+				}
+				for _, prop := range splitProps(cmd.Props) {
+					if propertyPath.MatchString(prop) {
+						continue
+					}
+					if issue := compileCheck(b.StartRef, "grid", "props", prop); issue != nil {
+						issues = append(issues, *issue)
+					}
+				}
 			}
 
-			// Recurse into child areas
-			if childArea := getCommandArea(b.Command); childArea != nil {
-				issues = append(issues, f.validateCommandAttributes([]*Area{childArea})...)
+			// Recurse into child areas; commands inside jx:raw are not run
+			if _, raw := b.Command.(*RawCommand); raw {
+				continue
 			}
+			issues = append(issues, f.validateCommandAttributes(childAreas(b.Command))...)
+		}
+	}
+	return issues
+}
+
+// commandAttributes lists the attributes each built-in command reads, besides
+// lastCell and renderIf, which every command accepts.
+var commandAttributes = map[string][]string{
+	"each": {"items", "var", "varIndex", "varStatus", "rowIndex", "direction", "select", "distinct",
+		"groupBy", "groupOrder", "orderBy", "multisheet", "oddStyle", "evenStyle", "outline",
+		"summaryRow", "mergeBy", "let", "gap", "footerArea", "emptyAction", "oldSelectBehavior",
+		"groupVar", "itemVar"},
+	"if":            {"condition", "areas", "ifArea", "elseArea", "elseAction"},
+	"grid":          {"headers", "data", "props", "formatCells", "headerStyle", "dataStyle", "headerArea", "bodyArea", "direction"},
+	"image":         {"src", "imageType", "placeholder", "scaleX", "scaleY"},
+	"mergeCells":    {"cols", "rows", "minCols", "minRows"},
+	"updateCell":    {"updater"},
+	"autoRowHeight": {"lineHeight", "maxHeight"},
+	"sortState":     {"column", "order"},
+	"mask":          {"fields"},
+	"pivot":         {"items", "var", "rowKey", "colKey", "value", "corner", "rowOrder", "colOrder"},
+	"toc":           {"includeHidden"},
+	"highlight":     {"condition", "style", "applyTo"},
+	"anchor":        {"name"},
+	"raw":           {},
+	"sheetProps":    {"tabColor", "hidden"},
+	"deleteRowIf":   {"condition"},
+	"include":       {"template", "area"},
+	"define":        {"name"},
+	"call":          {"name", "with"},
+}
+
+// unknownAttributes warns about attributes a built-in command does not read,
+// which usually are misspelled. Custom commands are not checked.
+func unknownAttributes(b *CommandBinding) []ValidationIssue {
+	known, ok := commandAttributes[b.Command.Name()]
+	if !ok {
+		return nil
+	}
+	var issues []ValidationIssue
+	for _, name := range slices.Sorted(maps.Keys(b.Attrs)) {
+		if name == "lastCell" || name == "renderIf" || slices.Contains(known, name) {
+			continue
 		}
+		issues = append(issues, ValidationIssue{
+			Severity: SeverityWarning,
+			CellRef:  b.StartRef,
+			Message:  fmt.Sprintf("command %q has unknown attribute %q", b.Command.Name(), name),
+		})
 	}
 	return issues
 }
 
+// styleCheck returns an issue if a command refers to a style that is neither
+// registered with WithStyles or WithStyleFromCell nor a cell reference.
+func (f *Filler) styleCheck(ref CellRef, cmdName, style string) *ValidationIssue {
+	if style == "" {
+		return nil
+	}
+	if _, ok := f.opts.styles[style]; ok {
+		return nil
+	}
+	if _, ok := f.opts.styleCells[style]; ok {
+		return nil
+	}
+	if r, err := ParseCellRef(style); err == nil && (r.Sheet != "" || bareCellRef.MatchString(style)) {
+		return nil
+	}
+	return &ValidationIssue{
+		Severity: SeverityError,
+		CellRef:  ref,
+		Message:  fmt.Sprintf("%s command uses unregistered style %q", cmdName, style),
+	}
+}
+
+// compileSyntax compiles each alternative of an expression with fallbacks.
+func compileSyntax(expression string) error {
+	for _, alt := range fallbacks(expression) {
+		if _, err := expr.Compile(alt, compileOptions(nil)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // compileCheck compiles an expression for syntax checking and returns an issue if it fails.
 func compileCheck(ref CellRef, cmdName, attrName, expression string) *ValidationIssue {
 	if expression == "" {
 		return nil
 	}
-	_, err := expr.Compile(expression, expr.AllowUndefinedVariables())
-	if err != nil {
+	if err := compileSyntax(expression); err != nil {
 		return &ValidationIssue{
 			Severity: SeverityError,
 			CellRef:  ref,
@@ -1,6 +1,7 @@
 package xlfill
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -112,6 +113,31 @@ func TestValidate_LastCellOutOfBounds(t *testing.T) {
 	assert.True(t, found, "expected at least one error-level issue about bounds")
 }
 
+func TestValidate_LastCellOutOfElseAndFooterAreas(t *testing.T) {
+	// Areas built in code: the else area B2 and the footer area A5 are one
+	// cell wide, but the commands added to them reach column C
+	sheet := "Sheet1"
+	root := NewArea(NewCellRef(sheet, 0, 0), Size{Width: 3, Height: 5}, nil)
+	elseArea := NewArea(NewCellRef(sheet, 1, 1), Size{Width: 1, Height: 1}, nil)
+	elseArea.AddCommand(&UpdateCellCommand{}, NewCellRef(sheet, 1, 1), Size{Width: 2, Height: 1})
+	ifCmd := &IfCommand{
+		Condition: "ok",
+		IfArea:    NewArea(NewCellRef(sheet, 1, 0), Size{Width: 1, Height: 1}, nil),
+		ElseArea:  elseArea,
+	}
+	root.AddCommand(ifCmd, NewCellRef(sheet, 1, 0), Size{Width: 2, Height: 1})
+	footer := NewArea(NewCellRef(sheet, 4, 0), Size{Width: 1, Height: 1}, nil)
+	footer.AddCommand(&UpdateCellCommand{}, NewCellRef(sheet, 4, 0), Size{Width: 3, Height: 1})
+	each := &EachCommand{Items: "items", Var: "e", Area: NewArea(NewCellRef(sheet, 3, 0), Size{Width: 1, Height: 1}, nil), Footer: footer}
+	root.AddCommand(each, NewCellRef(sheet, 3, 0), Size{Width: 1, Height: 2})
+
+	var refs []string
+	for _, issue := range NewFiller().validateLastCellBounds([]*Area{root}) {
+		refs = append(refs, issue.CellRef.CellName())
+	}
+	assert.ElementsMatch(t, []string{"B2", "A5"}, refs)
+}
+
 func TestValidate_InvalidItemsExpression(t *testing.T) {
 	f := excelize.NewFile()
 	defer f.Close()
@@ -234,3 +260,53 @@ func TestValidate_IssueString(t *testing.T) {
 	}
 	assert.Equal(t, "[WARN] Data!C1: unused area", warnIssue.String())
 }
+
+func TestValidate_IssueJSON(t *testing.T) {
+	issue := ValidationIssue{
+		Severity: SeverityWarning,
+		CellRef:  NewCellRef("Sheet1", 1, 0),
+		Message:  "unused area",
+	}
+	b, err := json.Marshal(issue)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"severity":"WARN","cell":"Sheet1!A2","message":"unused area"}`, string(b))
+}
+
+func TestValidate_UnknownAttribute(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(itmes="employees" items="employees" var="e" lastcell="A1" lastCell="A1")`})
+	path := filepath.Join(testdataDir(t), "validate_unknown_attr.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	t.Cleanup(func() { os.Remove(path) })
+
+	issues, err := Validate(path)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, SeverityWarning, issues[0].Severity)
+	assert.Equal(t, `command "each" has unknown attribute "itmes"`, issues[0].Message)
+	assert.Equal(t, `command "each" has unknown attribute "lastcell"`, issues[1].Message)
+}
+
+func TestValidate_InvertedLastCell(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A3", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="B3")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A3", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="B2")`})
+	path := filepath.Join(testdataDir(t), "validate_inverted.xlsx")
+	require.NoError(t, f.SaveAs(path))
+	t.Cleanup(func() { os.Remove(path) })
+
+	issues, err := Validate(path)
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, SeverityError, issues[0].Severity)
+	assert.Equal(t, NewCellRef("Sheet1", 2, 0), issues[0].CellRef)
+	assert.Contains(t, issues[0].Message, `e.g. lastCell="B3"`)
+
+	_, err = FillBytes(path, map[string]any{"employees": []any{}})
+	assert.ErrorContains(t, err, `Sheet1!A3: jx:each lastCell "B2" is above or left of its cell A3`)
+}
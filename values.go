@@ -0,0 +1,182 @@
+package xlfill
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TextValue is a value written as a text cell with the Text ("@") number
+// format, so IDs such as "00123" keep their leading zeros and long numeric
+// codes are not rounded or shown in scientific notation.
+type TextValue struct {
+	Text string
+}
+
+// String returns the text.
+func (t TextValue) String() string {
+	return t.Text
+}
+
+// Text creates a TextValue for use in template expressions. Numbers are
+// formatted in full, without exponent.
+// Usage in template: ${text(e.ID)}
+func Text(value any) TextValue {
+	switch v := value.(type) {
+	case nil:
+		return TextValue{}
+	case string:
+		return TextValue{Text: v}
+	case float64:
+		return TextValue{Text: strconv.FormatFloat(v, 'f', -1, 64)}
+	case float32:
+		return TextValue{Text: strconv.FormatFloat(float64(v), 'f', -1, 32)}
+	case fmt.Stringer:
+		return TextValue{Text: v.String()}
+	default:
+		return TextValue{Text: fmt.Sprint(v)}
+	}
+}
+
+// numberFormatter is a numeric value written with its own number format.
+type numberFormatter interface {
+	number() float64
+	numberFormat() string
+}
+
+// DecimalValue is an exact decimal number, such as a monetary amount. It is
+// written as a number cell holding its digits as given, so no precision is lost
+// to float64, and shown with as many decimals as Text has unless the template
+// cell has a number format of its own. Values of shopspring/decimal and
+// *big.Rat are written as DecimalValues; a WithValueConverter function can
+// return one for other decimal types.
+type DecimalValue struct {
+	Text string // e.g. "-1234.50"
+}
+
+// String returns the digits, for mixed content.
+func (d DecimalValue) String() string {
+	return d.Text
+}
+
+// places returns the number of decimals of d, or false when Text is not a
+// plain decimal number.
+func (d DecimalValue) places() (int, bool) {
+	s := strings.TrimPrefix(d.Text, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" {
+		return 0, false
+	}
+	if strings.HasSuffix(s, ".") {
+		return 0, false
+	}
+	return len(frac), true
+}
+
+// fixedDecimal is the API of shopspring/decimal's Decimal used to write it.
+type fixedDecimal interface {
+	Exponent() int32
+	StringFixed(places int32) string
+}
+
+// exactNumber returns a decimal value of v as a DecimalValue. A *big.Rat
+// without an exact decimal form, such as 1/3, is returned as a float64.
+func exactNumber(v any) (any, bool) {
+	switch d := v.(type) {
+	case *big.Rat:
+		if d == nil {
+			return nil, false
+		}
+		if places, exact := d.FloatPrec(); exact {
+			return DecimalValue{Text: d.FloatString(places)}, true
+		}
+		f, _ := d.Float64()
+		return f, true
+	case fixedDecimal:
+		if rv := reflect.ValueOf(d); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil, false
+		}
+		return DecimalValue{Text: d.StringFixed(max(0, -d.Exponent()))}, true
+	}
+	return nil, false
+}
+
+// CurrencyValue is an amount written as a number with a currency format, so
+// the cell still works in sums and averages.
+type CurrencyValue struct {
+	Amount   float64
+	Currency string // ISO 4217 code, e.g. "EUR"
+}
+
+// currencyFormats holds the symbol and decimal places of common currencies.
+// Other codes are written after the amount with two decimals.
+var currencyFormats = map[string]struct {
+	symbol   string
+	decimals int
+}{
+	"USD": {"$", 2}, "EUR": {"€", 2}, "GBP": {"£", 2}, "JPY": {"¥", 0},
+	"CNY": {"CN¥", 2}, "INR": {"₹", 2}, "KRW": {"₩", 0}, "BRL": {"R$", 2},
+	"AUD": {"A$", 2}, "CAD": {"CA$", 2},
+}
+
+func (c CurrencyValue) number() float64 { return c.Amount }
+
+// numberFormat returns the Excel number format, e.g. "€"#,##0.00.
+func (c CurrencyValue) numberFormat() string {
+	if f, ok := currencyFormats[c.Currency]; ok {
+		return `"` + f.symbol + `"` + decimalFormat(f.decimals)
+	}
+	return decimalFormat(2) + ` "` + c.Currency + `"`
+}
+
+// String returns the amount as text, e.g. "€1234.50", for mixed content.
+func (c CurrencyValue) String() string {
+	if f, ok := currencyFormats[c.Currency]; ok {
+		return f.symbol + strconv.FormatFloat(c.Amount, 'f', f.decimals, 64)
+	}
+	return strconv.FormatFloat(c.Amount, 'f', 2, 64) + " " + c.Currency
+}
+
+// PercentValue is a fraction written as a number with a percent format:
+// 0.125 shows as 12.50%.
+type PercentValue struct {
+	Value float64
+}
+
+func (p PercentValue) number() float64      { return p.Value }
+func (p PercentValue) numberFormat() string { return "0.00%" }
+
+// String returns the percentage as text, e.g. "12.5%", for mixed content.
+func (p PercentValue) String() string {
+	return strconv.FormatFloat(p.Value*100, 'f', -1, 64) + "%"
+}
+
+// decimalFormat returns a thousands-separated number format with the given decimals.
+func decimalFormat(decimals int) string {
+	if decimals == 0 {
+		return "#,##0"
+	}
+	return "#,##0." + strings.Repeat("0", decimals)
+}
+
+// Currency creates a CurrencyValue for use in template expressions.
+// Usage in template: ${currency(e.Amount, "EUR")}
+func Currency(amount any, currency string) (CurrencyValue, error) {
+	f, ok := toFloat64(amount)
+	if !ok {
+		return CurrencyValue{}, fmt.Errorf("currency: amount %v (%T) is not a number", amount, amount)
+	}
+	return CurrencyValue{Amount: f, Currency: strings.ToUpper(currency)}, nil
+}
+
+// Percent creates a PercentValue for use in template expressions.
+// Usage in template: ${percent(e.Rate)}
+func Percent(value any) (PercentValue, error) {
+	f, ok := toFloat64(value)
+	if !ok {
+		return PercentValue{}, fmt.Errorf("percent: value %v (%T) is not a number", value, value)
+	}
+	return PercentValue{Value: f}, nil
+}
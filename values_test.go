@@ -0,0 +1,273 @@
+package xlfill
+
+import (
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// createValueTemplate creates a one-row list whose cells hold the given expressions.
+//
+//	A1: exprs[0]  [jx:area, jx:each(items="rows" var="r")]
+//	B1: exprs[1] ...
+func createValueTemplate(t *testing.T, name string, exprs ...string) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	last := ColToName(len(exprs)-1) + "1"
+	for i, e := range exprs {
+		f.SetCellValue("Sheet1", ColToName(i)+"1", e)
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Sheet1", "A1", "A1", bold))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: "jx:area(lastCell=\"" + last + "\")\njx:each(items=\"rows\" var=\"r\" lastCell=\"" + last + "\")"})
+
+	path := filepath.Join(testdataDir(t), name)
+	require.NoError(t, f.SaveAs(path))
+	return path
+}
+
+func TestText_Conversions(t *testing.T) {
+	assert.Equal(t, "00123", Text("00123").Text)
+	assert.Equal(t, "1234567890123456789", Text(int64(1234567890123456789)).Text)
+	assert.Equal(t, "12345678901234", Text(12345678901234.0).Text)
+	assert.Equal(t, "0.5", Text(0.5).Text)
+	assert.Equal(t, "", Text(nil).Text)
+	assert.Equal(t, "link", Text(Hyperlink("https://x", "link")).Text)
+}
+
+func TestText_WritesTextCells(t *testing.T) {
+	tmpl := createValueTemplate(t, "text_values.xlsx", "${text(r.ID)}", "${text(r.Code)}", "${r.Code}")
+	out, err := FillBytes(tmpl, map[string]any{"rows": []map[string]any{
+		{"ID": "00123", "Code": 4012888888881881.0},
+		{"ID": 7, "Code": 42},
+	}})
+	require.NoError(t, err)
+	f := openOutput(t, out)
+
+	for cell, want := range map[string]string{"A1": "00123", "B1": "4012888888881881", "A2": "7", "B2": "42"} {
+		v, err := f.GetCellValue("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, v, cell)
+
+		cellType, err := f.GetCellType("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, excelize.CellTypeSharedString, cellType, cell)
+
+		_, style := styleOf(t, f, "Sheet1", cell)
+		assert.Equal(t, 49, style.NumFmt, cell)
+	}
+
+	// The template style is kept under the text format
+	_, style := styleOf(t, f, "Sheet1", "A1")
+	require.NotNil(t, style.Font)
+	assert.True(t, style.Font.Bold)
+
+	// Without text() the code stays a number
+	cellType, err := f.GetCellType("Sheet1", "C1")
+	require.NoError(t, err)
+	assert.NotEqual(t, excelize.CellTypeSharedString, cellType)
+}
+
+func TestCurrencyAndPercent_FormattedNumbers(t *testing.T) {
+	tmpl := createValueTemplate(t, "currency_values.xlsx",
+		`${currency(r.Amount, r.Cur)}`, "${percent(r.Rate)}", `Total: ${currency(r.Amount, r.Cur)}`)
+	out, err := FillBytes(tmpl, map[string]any{"rows": []map[string]any{
+		{"Amount": 1234.5, "Cur": "eur", "Rate": 0.125},
+		{"Amount": 1500, "Cur": "JPY", "Rate": 1},
+		{"Amount": -20, "Cur": "CHF", "Rate": 0},
+	}})
+	require.NoError(t, err)
+	f := openOutput(t, out)
+
+	for cell, want := range map[string]string{
+		"A1": "1234.5", "B1": "0.125", "A2": "1500", "B2": "1", "A3": "-20",
+	} {
+		v, err := f.GetCellValue("Sheet1", cell, excelize.Options{RawCellValue: true})
+		require.NoError(t, err)
+		assert.Equal(t, want, v, cell)
+		cellType, err := f.GetCellType("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Contains(t, []excelize.CellType{excelize.CellTypeNumber, excelize.CellTypeUnset}, cellType, cell)
+	}
+
+	for cell, want := range map[string]string{
+		"A1": `"€"#,##0.00`, "B1": "0.00%", "A2": `"¥"#,##0`, "A3": `#,##0.00 "CHF"`,
+	} {
+		_, style := styleOf(t, f, "Sheet1", cell)
+		require.NotNil(t, style.CustomNumFmt, cell)
+		assert.Equal(t, want, *style.CustomNumFmt, cell)
+	}
+	_, style := styleOf(t, f, "Sheet1", "A1")
+	assert.True(t, style.Font.Bold)
+
+	// Inside text the value is formatted for reading
+	v, _ := f.GetCellValue("Sheet1", "C1")
+	assert.Equal(t, "Total: €1234.50", v)
+}
+
+func TestCurrencyAndPercent_RejectNonNumbers(t *testing.T) {
+	_, err := Currency("12", "USD")
+	assert.ErrorContains(t, err, "is not a number")
+	_, err = Percent(nil)
+	assert.ErrorContains(t, err, "is not a number")
+
+	tmpl := createValueTemplate(t, "currency_invalid.xlsx", `${currency(r.Amount, "USD")}`)
+	_, err = FillBytes(tmpl, map[string]any{"rows": []map[string]any{{"Amount": "n/a"}}})
+	assert.ErrorContains(t, err, "is not a number")
+}
+
+// testDecimal, testUUID and testNullInt stand in for application types such as
+// decimal.Decimal, uuid.UUID and null.Int.
+type testDecimal struct{ units, scale int64 }
+
+type testUUID [4]byte
+
+type testNullInt struct {
+	Int   int64
+	Valid bool
+}
+
+func convertTestValues(v any) (any, CellType, bool) {
+	switch v := v.(type) {
+	case testDecimal:
+		f := float64(v.units)
+		for range v.scale {
+			f /= 10
+		}
+		return f, CellNumber, true
+	case testUUID:
+		return fmt.Sprintf("%x", v[:]), CellString, true
+	case testNullInt:
+		if !v.Valid {
+			return nil, CellBlank, true
+		}
+		return v.Int, CellNumber, true
+	}
+	return nil, CellBlank, false
+}
+
+func TestWithValueConverter(t *testing.T) {
+	tmpl := createValueTemplate(t, "converted_values.xlsx",
+		"${r.Price}", "${r.ID}", "${r.Qty}", "ID ${r.ID}: ${r.Qty}", `${styled(r.Price, "money")}`, "${r.Name}")
+	rows := []map[string]any{
+		{"Price": testDecimal{12345, 2}, "ID": testUUID{0xde, 0xad, 0xbe, 0xef}, "Qty": testNullInt{3, true}, "Name": "Alice"},
+		{"Price": testDecimal{5, 0}, "ID": testUUID{}, "Qty": testNullInt{}, "Name": "Bob"},
+	}
+	out, err := FillBytes(tmpl, map[string]any{"rows": rows}, WithValueConverter(convertTestValues),
+		WithStyles(map[string]*excelize.Style{"money": {NumFmt: 2}}))
+	require.NoError(t, err)
+	f := openOutput(t, out)
+
+	for cell, want := range map[string]string{
+		"A1": "123.45", "B1": "deadbeef", "C1": "3", "D1": "ID deadbeef: 3", "E1": "123.45", "F1": "Alice",
+		"A2": "5", "B2": "00000000", "C2": "", "D2": "ID 00000000: ", "E2": "5.00", "F2": "Bob",
+	} {
+		v, err := f.GetCellValue("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, v, cell)
+	}
+	for _, cell := range []string{"A1", "C1", "E1"} {
+		cellType, err := f.GetCellType("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, excelize.CellTypeUnset, cellType, cell+" is a number")
+	}
+}
+
+// shopDecimal has the API of shopspring/decimal's Decimal used to write it:
+// value = coef * 10^exp.
+type shopDecimal struct {
+	coef int64
+	exp  int32
+}
+
+func (d shopDecimal) Exponent() int32 { return d.exp }
+
+func (d shopDecimal) StringFixed(places int32) string {
+	r := new(big.Rat).SetInt64(d.coef)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(d.exp, -d.exp))), nil))
+	if d.exp < 0 {
+		r.Quo(r, scale)
+	} else {
+		r.Mul(r, scale)
+	}
+	return r.FloatString(int(places))
+}
+
+func TestDecimalValues_ExactNumbers(t *testing.T) {
+	tmpl := createValueTemplate(t, "decimal_values.xlsx", "${r.Amount}", "${r.Rate}", "Due: ${r.Amount}")
+	tenths, _ := new(big.Rat).SetString("0.1")
+	sum := new(big.Rat).Add(tenths, big.NewRat(2, 10))
+	rows := []map[string]any{
+		{"Amount": shopDecimal{1234567890123456789, -2}, "Rate": sum},
+		{"Amount": shopDecimal{-12340, -3}, "Rate": big.NewRat(1, 3)},
+		{"Amount": shopDecimal{5, 2}, "Rate": big.NewRat(-7, 4)},
+	}
+	out, err := FillBytes(tmpl, map[string]any{"rows": rows})
+	require.NoError(t, err)
+	f := openOutput(t, out)
+
+	// The cells hold the exact digits, which float64 cannot represent
+	amount, a, b := 12345678901234567.89, 0.1, 0.2
+	assert.Equal(t, "12345678901234568", strconv.FormatFloat(amount, 'f', -1, 64))
+	assert.Equal(t, "0.30000000000000004", strconv.FormatFloat(a+b, 'f', -1, 64))
+	for cell, want := range map[string]string{
+		"A1": "12345678901234567.89", "B1": "0.3", "C1": "Due: 12345678901234567.89",
+		"A2": "-12.340", "B2": "0.3333333333333333", "C2": "Due: -12.340",
+		"A3": "500", "B3": "-1.75", "C3": "Due: 500",
+	} {
+		v, err := f.GetCellValue("Sheet1", cell, excelize.Options{RawCellValue: true})
+		require.NoError(t, err)
+		assert.Equal(t, want, v, cell)
+	}
+	for cell, want := range map[string]string{"A1": "#,##0.00", "B1": "#,##0.0", "A2": "#,##0.000", "A3": "#,##0", "B3": "#,##0.00"} {
+		cellType, err := f.GetCellType("Sheet1", cell)
+		require.NoError(t, err)
+		assert.Equal(t, excelize.CellTypeUnset, cellType, cell+" is a number")
+		_, style := styleOf(t, f, "Sheet1", cell)
+		require.NotNil(t, style.CustomNumFmt, cell)
+		assert.Equal(t, want, *style.CustomNumFmt, cell)
+		assert.Equal(t, cell == "A1" || cell == "A2" || cell == "A3", style.Font != nil && style.Font.Bold, cell+" keeps its style")
+	}
+	_, style := styleOf(t, f, "Sheet1", "B2")
+	assert.Nil(t, style.CustomNumFmt, "1/3 has no exact decimal form")
+}
+
+func TestDecimalValues_TemplateFormat(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${r.Amount}")
+	format := `"€"#,##0.0000`
+	euro, err := f.NewStyle(&excelize.Style{CustomNumFmt: &format})
+	require.NoError(t, err)
+	require.NoError(t, f.SetCellStyle("Sheet1", "A1", "A1", euro))
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(items="rows" var="r" lastCell="A1")`})
+	tmpl := filepath.Join(testdataDir(t), "decimal_format.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+
+	out, err := FillBytes(tmpl, map[string]any{"rows": []any{map[string]any{"Amount": shopDecimal{995, -2}}}})
+	require.NoError(t, err)
+	res := openOutput(t, out)
+	v, err := res.GetCellValue("Sheet1", "A1")
+	require.NoError(t, err)
+	assert.Equal(t, "€9.9500", v, "the template's number format is kept")
+
+	f = excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "${r.Bad}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill",
+		Text: `jx:area(lastCell="A1")` + "\n" + `jx:each(items="rows" var="r" lastCell="A1")`})
+	tmpl = filepath.Join(testdataDir(t), "decimal_invalid.xlsx")
+	require.NoError(t, f.SaveAs(tmpl))
+	f.Close()
+	_, err = FillBytes(tmpl, map[string]any{"rows": []any{map[string]any{"Bad": DecimalValue{Text: "1e5"}}}})
+	assert.ErrorContains(t, err, `invalid decimal "1e5"`)
+}
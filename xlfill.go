@@ -4,8 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/javajack/xlfill/odsconv"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -23,6 +31,18 @@ func FillBytes(templatePath string, data map[string]any, opts ...Option) ([]byte
 	return filler.FillBytes(data)
 }
 
+// FillFile fills the template workbook file with data in place and returns it,
+// without writing it. See Filler.Apply.
+func FillFile(file *excelize.File, data map[string]any, opts ...Option) (*excelize.File, error) {
+	if _, err := NewFiller(opts...).Apply(file, data); err != nil {
+		if isExpressionErrors(err) {
+			return file, err
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
 // FillReader processes a template from an io.Reader and writes to an io.Writer.
 func FillReader(template io.Reader, output io.Writer, data map[string]any, opts ...Option) error {
 	allOpts := append([]Option{WithTemplateReader(template)}, opts...)
@@ -30,16 +50,31 @@ func FillReader(template io.Reader, output io.Writer, data map[string]any, opts
 	return filler.FillWriter(data, output)
 }
 
-// Fill processes the template with data and writes to outputPath.
+// FillFS processes a template read from a file system, such as an embed.FS,
+// and writes to an io.Writer.
+func FillFS(fsys fs.FS, name string, output io.Writer, data map[string]any, opts ...Option) error {
+	allOpts := append([]Option{WithTemplateFS(fsys, name)}, opts...)
+	filler := NewFiller(allOpts...)
+	return filler.FillWriter(data, output)
+}
+
+// Fill processes the template with data and writes to outputPath. The file
+// is only created once the template has been filled, and removed when
+// writing it fails.
 func (f *Filler) Fill(data map[string]any, outputPath string) error {
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("create output file %q: %w", outputPath, err)
+	created := false
+	create := func() (io.WriteCloser, error) {
+		out, err := os.Create(outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("create output file %q: %w", outputPath, err)
+		}
+		created = true
+		return out, nil
 	}
-	defer out.Close()
-
-	if err := f.FillWriter(data, out); err != nil {
-		os.Remove(outputPath)
+	if _, err := f.fill(data, create, outputPath); err != nil {
+		if created && !isExpressionErrors(err) {
+			os.Remove(outputPath)
+		}
 		return err
 	}
 	return nil
@@ -48,84 +83,603 @@ func (f *Filler) Fill(data map[string]any, outputPath string) error {
 // FillBytes processes the template with data and returns the output as bytes.
 func (f *Filler) FillBytes(data map[string]any) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := f.FillWriter(data, &buf); err != nil {
+	err := f.FillWriter(data, &buf)
+	if err != nil && !isExpressionErrors(err) {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	return buf.Bytes(), err
 }
 
 // FillWriter processes the template with data and writes to w.
 func (f *Filler) FillWriter(data map[string]any, w io.Writer) error {
+	_, err := f.fill(data, writerOutput(w), "")
+	return err
+}
+
+// writerOutput returns an output for fill that writes to w and leaves it open.
+func writerOutput(w io.Writer) func() (io.WriteCloser, error) {
+	return func() (io.WriteCloser, error) { return nopWriteCloser{w}, nil }
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// fill processes the template with data, writes the output and reports where
+// each area and template cell ended up. The output is written in the format
+// of outputPath's extension, or of the template when it has none; a template
+// saved as an Excel template (.xltx) produces a workbook (.xlsx).
+//
+// The writer is created by create once the template has been filled, so a
+// failed fill creates no output, and closed once the output is written. When
+// writing fails and the writer has a CloseWithError(error) error method, as
+// io.PipeWriter does, it is closed with that instead.
+func (f *Filler) fill(data map[string]any, create func() (io.WriteCloser, error), outputPath string) (*FillResult, error) {
+	start := time.Now()
+	f.opts.stats.reset()
+	defer f.opts.stats.record(phaseTotal, start)
+
 	// Open template
 	tx, err := f.openTemplate()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Close()
+	f.opts.stats.record(phaseOpen, start)
+	format := outputFormat(workbookFormat(tx.file), outputPath)
+	tx.setFormat(format)
 
-	// Create context
-	ctxOpts := []ContextOption{}
-	if f.opts.notationBegin != "${" || f.opts.notationEnd != "}" {
-		ctxOpts = append(ctxOpts, WithNotation(f.opts.notationBegin, f.opts.notationEnd))
+	result, err := f.process(tx, data)
+	if err != nil && !isExpressionErrors(err) {
+		return nil, err
 	}
-	ctx := NewContext(data, ctxOpts...)
+
+	w, createErr := create()
+	if createErr != nil {
+		return nil, createErr
+	}
+	var out io.Writer = w
+	var written *bytes.Buffer
+	if len(f.opts.outputFilters) > 0 {
+		written = &bytes.Buffer{}
+		out = io.MultiWriter(w, written)
+	}
+	writeStart := time.Now()
+	if writeErr := tx.Write(out); writeErr != nil {
+		if cw, ok := w.(interface{ CloseWithError(error) error }); ok {
+			cw.CloseWithError(writeErr)
+		} else {
+			w.Close()
+		}
+		return nil, fmt.Errorf("write output: %w", writeErr)
+	}
+	if closeErr := w.Close(); closeErr != nil {
+		return nil, fmt.Errorf("close output: %w", closeErr)
+	}
+	f.opts.stats.record(phaseWrite, writeStart)
+	if written != nil {
+		if err := f.filterOutput(written.Bytes(), format); err != nil {
+			return nil, err
+		}
+	}
+	return result, err
+}
+
+// Apply fills the template workbook file with data in place, without writing
+// it, so the caller can keep working on the result, e.g. add charts, before
+// saving it. WithTemplate and WithTemplateReader are not used; file is not
+// closed.
+func (f *Filler) Apply(file *excelize.File, data map[string]any) (*FillResult, error) {
+	start := time.Now()
+	f.opts.stats.reset()
+	defer f.opts.stats.record(phaseTotal, start)
+	tx, err := NewExcelizeTransformer(file)
+	if err != nil {
+		return nil, err
+	}
+	return f.process(tx, data)
+}
+
+// process fills the workbook of tx with data. Under CollectAndContinue the
+// result comes with the ExpressionErrors of the cells that failed.
+func (f *Filler) process(tx *ExcelizeTransformer, data map[string]any) (*FillResult, error) {
+	tx.styles, tx.styleRefs = f.opts.styles, f.opts.styleCells
+	tx.setCellWriters(f.opts.cellWriters)
+
+	ctx, err := f.newContext(data, tx)
+	if err != nil {
+		return nil, err
+	}
+	defer ctx.state.includes.close()
 
 	// Build areas from template comments
+	parseStart := time.Now()
 	areas, err := f.BuildAreas(tx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	f.opts.stats.record(phaseParse, parseStart)
+	dispositions, err := f.sheetDispositions(areas)
+	if err != nil {
+		return nil, err
+	}
+	ctx.setSheetDispositions(dispositions, f.defaultTemplateDisposition())
+	for _, area := range areas {
+		if err := tx.unmergeTemplate(area.SourceRef()); err != nil {
+			return nil, err
+		}
 	}
 
-	// Process each area
+	// Process each area top to bottom, shifting areas below or to the right of
+	// an area that grew; areas with a table of contents go last so that every
+	// generated sheet is known
+	sort.SliceStable(areas, func(i, j int) bool {
+		return !areaContainsCommand(areas[i], "toc") && areaContainsCommand(areas[j], "toc")
+	})
+	sheetAreas := bindSheetAreas(areas)
+	layouts := map[string]*areaLayout{} // by sheet
 	for _, area := range areas {
-		if _, err := area.ApplyAt(area.StartCell, ctx); err != nil {
+		if layouts[area.StartCell.Sheet] == nil {
+			layouts[area.StartCell.Sheet] = newAreaLayout()
+		}
+	}
+	results := make([]AreaResult, len(areas))
+	applyStart := time.Now()
+	applyArea := func(ctx *Context, i int) error {
+		area := areas[i]
+		if sheetAreas[area] {
+			// Filled on the sheets of a multisheet jx:each instead
+			results[i] = AreaResult{Name: area.Name, Source: area.SourceRef(), Target: area.StartCell}
+			return nil
+		}
+		layout := layouts[area.StartCell.Sheet]
+		target := layout.target(area)
+		areaCtx := ctx.forSheet(target.Sheet)
+		if f.opts.shiftOutside {
+			// Hold the area's writes until the rows it needs are inserted
+			areaCtx = areaCtx.withWriteLog()
+		}
+		size, err := area.ApplyAt(target, areaCtx)
+		if err != nil {
 			return fmt.Errorf("process area at %s: %w", area.StartCell, err)
 		}
+		if f.opts.shiftOutside {
+			if err := layout.insertRows(ctx, tx, area, target, size); err != nil {
+				return err
+			}
+			for _, op := range areaCtx.deferred.ops {
+				if err := ctx.run(op); err != nil {
+					return err
+				}
+			}
+		}
+		if err := layout.place(ctx, area, target, size); err != nil && f.opts.areaCollisionCheck {
+			return err
+		}
+		results[i] = AreaResult{Name: area.Name, Source: area.SourceRef(), Target: target, Size: size}
+
+		return area.clearTemplateCells(ctx, target, size)
+	}
+
+	// Listeners may not be safe for concurrent use
+	workers := f.opts.concurrency
+	if len(f.opts.areaListeners) > 0 {
+		workers = 1
+	}
+	ctx.setConcurrency(workers)
+	if workers > 1 {
+		// Areas on different sheets are independent; areas on one sheet depend on
+		// each other's layout and run in order within one job
+		var groups [][]int
+		var tocAreas []int
+		groupOf := map[string]int{}
+		for i, area := range areas {
+			if areaContainsCommand(area, "toc") {
+				tocAreas = append(tocAreas, i)
+				continue
+			}
+			g, ok := groupOf[area.StartCell.Sheet]
+			if !ok {
+				g = len(groups)
+				groupOf[area.StartCell.Sheet] = g
+				groups = append(groups, nil)
+			}
+			groups[g] = append(groups[g], i)
+		}
+		jobs := make([]func(*Context) error, len(groups))
+		for g, group := range groups {
+			jobs[g] = func(jobCtx *Context) error {
+				for _, i := range group {
+					if err := applyArea(jobCtx, i); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+		if err := runJobs(ctx, workers, jobs); err != nil {
+			return nil, err
+		}
+		for _, i := range tocAreas {
+			if err := applyArea(ctx, i); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		for i := range areas {
+			if err := applyArea(ctx, i); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	f.opts.stats.record(phaseApply, applyStart)
+
+	if f.opts.shiftOutside {
+		tx.moved = func(ref CellRef) CellRef {
+			if layout, ok := layouts[ref.Sheet]; ok {
+				return layout.moved(ref)
+			}
+			return ref
+		}
+	}
+	if err := tx.placeComments(f.opts.stripMarkup, f.opts.markup()); err != nil {
+		return nil, err
+	}
+
+	result := &FillResult{Areas: results}
+	named := map[string]AreaRef{}
+	for _, r := range results {
+		if ref, ok := r.Ref(); ok && r.Name != "" {
+			named[r.Name] = ref
+		}
+	}
+
+	// Apply per-sheet dispositions not already handled by multisheet
+	sheets := make([]string, 0, len(dispositions))
+	for sheet := range dispositions {
+		sheets = append(sheets, sheet)
+	}
+	sort.Strings(sheets)
+	for _, sheet := range sheets {
+		if ctx.isDisposed(sheet) || !slices.Contains(tx.GetSheetNames(), sheet) {
+			continue
+		}
+		if err := disposeSheet(tx, sheet, dispositions[sheet]); err != nil {
+			return nil, fmt.Errorf("dispose sheet %q: %w", sheet, err)
+		}
+	}
+
+	// Remove the named-range command sheet from the output
+	if f.opts.namedRangeAreas && slices.Contains(tx.GetSheetNames(), configSheetName) {
+		if err := tx.DeleteSheet(configSheetName); err != nil {
+			return nil, fmt.Errorf("delete %s sheet: %w", configSheetName, err)
+		}
+	}
+
+	// Arrange the sheets of the output
+	if len(f.opts.sheetOrder) > 0 {
+		if err := tx.SetSheetOrder(f.opts.sheetOrder); err != nil {
+			return nil, fmt.Errorf("set sheet order: %w", err)
+		}
+	}
+	if f.opts.activeSheet != "" {
+		if err := tx.SetActiveSheet(f.opts.activeSheet); err != nil {
+			return nil, fmt.Errorf("set active sheet: %w", err)
+		}
+	}
 
-		// Clear template cells if configured
-		if f.opts.clearTemplateCells {
-			area.clearTemplateCells(ctx)
+	// Save the output range of named areas for FillArea, in name order so the
+	// output is the same on every run
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ref := named[name]
+		if !slices.Contains(tx.GetSheetNames(), ref.First.Sheet) {
+			continue
+		}
+		if err := tx.setAreaName(name, ref); err != nil {
+			return nil, err
 		}
 	}
 
+	// Name the anchor cells, so formulas on any sheet can refer to them
+	anchors := ctx.anchors()
+	names = names[:0]
+	for name, ref := range anchors {
+		if !slices.Contains(tx.GetSheetNames(), ref.Sheet) {
+			delete(anchors, name)
+			continue
+		}
+		if _, ok := named[name]; ok {
+			return nil, fmt.Errorf("anchor %q has the name of an area", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := tx.setCellName(name, anchors[name]); err != nil {
+			return nil, err
+		}
+	}
+	tx.anchors = anchors
+	result.anchors = anchors
+
+	// Update formula references to the expanded target cells
+	formulaStart := time.Now()
+	fp := NewFormulaProcessor()
+	fp.logger = f.opts.logger
+	fp.moved = tx.moved
+	for name, fn := range f.opts.formulaStrategies {
+		fp.RegisterStrategy(name, fn)
+	}
+	for _, area := range areas {
+		fp.ProcessAreaFormulas(tx, area)
+	}
+	ctx.state.includes.processFormulas(fp)
+	if f.opts.outsideFormulas {
+		var outputs []AreaRef
+		for _, layout := range layouts {
+			outputs = append(outputs, layout.outputs()...)
+		}
+		fp.processOutsideFormulas(tx, areas, outputs)
+	}
+	f.opts.stats.record(phaseFormulas, formulaStart)
+
+	// Drop the rows of jx:deleteRowIf whose condition holds, now that their
+	// formulas can be calculated
+	removed, err := ctx.deleteRows(tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range results {
+		results[i] = trimRemovedRows(r, removed[r.Target.Sheet])
+	}
+	for name, ref := range anchors {
+		anchors[name] = shiftRemovedRows(ref, removed[ref.Sheet])
+	}
+
+	result.targets = tx.targetRefs
+	if stats := f.opts.stats; stats != nil {
+		stats.Areas = len(areas)
+		for _, targets := range tx.targetRefs {
+			stats.Cells += len(targets)
+		}
+	}
+	if f.opts.usageReport != nil {
+		*f.opts.usageReport = ctx.usageReport()
+	}
+
 	// Recalculate formulas on open
 	if f.opts.recalculateOnOpen {
 		if err := tx.SetRecalculateOnOpen(true); err != nil {
-			return fmt.Errorf("set recalculate on open: %w", err)
+			return nil, fmt.Errorf("set recalculate on open: %w", err)
+		}
+	}
+	if f.opts.calcProps != nil {
+		if err := tx.SetCalcProps(*f.opts.calcProps); err != nil {
+			return nil, fmt.Errorf("set calculation properties: %w", err)
+		}
+	}
+	if f.opts.branding != nil {
+		if err := tx.ApplyBranding(*f.opts.branding); err != nil {
+			return nil, fmt.Errorf("apply branding: %w", err)
+		}
+	}
+
+	if f.opts.deterministic {
+		if err := tx.normalizeDocProps(); err != nil {
+			return nil, fmt.Errorf("normalize document properties: %w", err)
 		}
 	}
 
 	// Pre-write callback
 	if f.opts.preWrite != nil {
 		if err := f.opts.preWrite(tx); err != nil {
-			return fmt.Errorf("pre-write callback: %w", err)
+			return nil, fmt.Errorf("pre-write callback: %w", err)
 		}
 	}
+	return result, ctx.collectedErrors()
+}
 
-	// Write output
-	return tx.Write(w)
+// newContext creates the evaluation context for filling tx with data, merging the JSON data
+// source and applying the expression notation.
+func (f *Filler) newContext(data map[string]any, tx Transformer) (*Context, error) {
+	if f.opts.jsonData != nil {
+		var err error
+		data, err = mergeJSONData(f.opts.jsonData, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data = withDefaults(data, f.opts.defaults)
+
+	ctxOpts := []ContextOption{}
+	if f.opts.notationBegin != "${" || f.opts.notationEnd != "}" {
+		ctxOpts = append(ctxOpts, WithNotation(f.opts.notationBegin, f.opts.notationEnd))
+	}
+	if f.opts.sandbox != nil {
+		ctxOpts = append(ctxOpts, WithEvaluator(&exprEvaluator{sandbox: f.opts.sandbox}))
+	}
+	if !f.opts.clearTemplateCells {
+		ctxOpts = append(ctxOpts, WithClearCells(false))
+	}
+	ctx := NewContext(data, ctxOpts...)
+	ctx.state.logger = f.opts.logger
+	ctx.state.limits = f.opts.outputLimits
+	ctx.state.sheets = len(tx.GetSheetNames())
+	ctx.state.sheetNames = f.opts.sheetNameBuilder
+	ctx.state.sheetData = f.opts.sheetData
+	ctx.state.cancel = f.opts.cancel
+	ctx.state.convert = f.opts.valueConverter
+	if f.opts.usageReport != nil {
+		ctx.state.usage = newUsageTracker()
+	}
+	if f.opts.redaction != nil {
+		ctx.state.redaction = newRedaction(*f.opts.redaction)
+	}
+	ctx.state.selectAfterGroup = f.opts.selectAfterGroup
+	ctx.state.includes = &includes{filler: f, templates: map[string]*includedTemplate{}}
+	ctx.state.errorPolicy = f.opts.errorPolicy
+	ctx.state.errorPlaceholder = f.opts.errorPlaceholder
+	return ctx, nil
+}
+
+// bindSheetAreas hands the other root areas on the template sheet of a
+// multisheet jx:each to the command, which fills them on every generated sheet
+// within the item's scope. It returns the areas taken over.
+func bindSheetAreas(areas []*Area) map[*Area]bool {
+	taken := map[*Area]bool{}
+	for _, root := range areas {
+		each := multiSheetEach(root)
+		if each == nil || taken[root] {
+			continue
+		}
+		var sheetAreas []*Area
+		for _, area := range areas {
+			switch {
+			case area == root:
+				sheetAreas = append(sheetAreas, each.Area)
+			case area.StartCell.Sheet == root.StartCell.Sheet && !taken[area] &&
+				multiSheetEach(area) == nil && !areaContainsCommand(area, "toc"):
+				sheetAreas = append(sheetAreas, area)
+			}
+		}
+		if len(sheetAreas) < 2 {
+			continue
+		}
+		each.sheetAreas = sheetAreas
+		for _, area := range sheetAreas {
+			if area != each.Area {
+				taken[area] = true
+			}
+		}
+	}
+	return taken
+}
+
+// multiSheetEach returns the multisheet jx:each bound directly in area, if any.
+func multiSheetEach(area *Area) *EachCommand {
+	for _, b := range area.Bindings {
+		if each, ok := b.Command.(*EachCommand); ok && each.MultiSheet != "" {
+			return each
+		}
+	}
+	return nil
+}
+
+// withDefaults returns data with defaults set for missing or nil keys. data is
+// not modified.
+func withDefaults(data, defaults map[string]any) map[string]any {
+	if len(defaults) == 0 {
+		return data
+	}
+	merged := make(map[string]any, len(data)+len(defaults))
+	maps.Copy(merged, data)
+	for k, v := range defaults {
+		if merged[k] == nil {
+			merged[k] = v
+		}
+	}
+	return merged
 }
 
 // openTemplate opens the template from file path or reader.
 func (f *Filler) openTemplate() (*ExcelizeTransformer, error) {
+	file, err := f.openTemplateFile()
+	if err != nil {
+		return nil, err
+	}
+	return NewExcelizeTransformer(file)
+}
+
+// openTemplateFile opens the template workbook from reader, template source,
+// file system or file path. OpenDocument templates are converted to an excelize workbook.
+func (f *Filler) openTemplateFile() (*excelize.File, error) {
 	if f.opts.templateReader != nil {
-		file, err := excelize.OpenReader(f.opts.templateReader)
+		data, err := io.ReadAll(f.opts.templateReader)
 		if err != nil {
 			return nil, fmt.Errorf("open template reader: %w", err)
 		}
-		return NewExcelizeTransformer(file)
+		file, err := openTemplateData(data)
+		if err != nil {
+			return nil, fmt.Errorf("open template reader: %w", err)
+		}
+		return file, nil
+	}
+	if f.opts.templateSource != nil {
+		data, err := f.readTemplateSource()
+		if err == nil {
+			var file *excelize.File
+			if file, err = openTemplateData(data); err == nil {
+				return file, nil
+			}
+		}
+		return nil, fmt.Errorf("open template source: %w", err)
+	}
+	if f.opts.templateFS != nil {
+		data, err := fs.ReadFile(f.opts.templateFS, f.opts.templateName)
+		if err == nil {
+			var file *excelize.File
+			if file, err = openTemplateData(data); err == nil {
+				return file, nil
+			}
+		}
+		return nil, fmt.Errorf("open template %q: %w", f.opts.templateName, err)
 	}
 	if f.opts.templatePath != "" {
-		return OpenTemplate(f.opts.templatePath)
+		var file *excelize.File
+		var err error
+		if strings.EqualFold(filepath.Ext(f.opts.templatePath), odsFormat) {
+			var r *os.File
+			if r, err = os.Open(f.opts.templatePath); err == nil {
+				file, err = openODS(r)
+				r.Close()
+			}
+		} else {
+			file, err = excelize.OpenFile(f.opts.templatePath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open template %q: %w", f.opts.templatePath, err)
+		}
+		return file, nil
 	}
-	return nil, fmt.Errorf("no template specified: use WithTemplate or WithTemplateReader")
+	return nil, fmt.Errorf("no template specified: use WithTemplate, WithTemplateReader, WithTemplateFS or WithTemplateSource")
 }
 
-// clearTemplateCells clears cells that still contain unexpanded template expressions.
-func (a *Area) clearTemplateCells(ctx *Context) {
-	// We only clear the source area cells that weren't overwritten by command output.
-	// The area's ClearCells method handles this.
-	// For now, no-op — the Transform already wrote evaluated values to target cells.
-	// Template cells outside any processed area retain their expressions, which is
-	// handled by clearing the area source if the output target differs from source.
+// openTemplateData opens a template workbook read into memory, telling
+// OpenDocument from Excel files by their content.
+func openTemplateData(data []byte) (*excelize.File, error) {
+	if odsconv.IsODS(data) {
+		return openODS(bytes.NewReader(data))
+	}
+	return excelize.OpenReader(bytes.NewReader(data))
+}
+
+// clearTemplateCells clears the template content an area applied at target
+// leaves behind when its output of size is smaller than the template, e.g.
+// the rows below a jx:each over no items once the footer moved up.
+func (a *Area) clearTemplateCells(ctx *Context, target CellRef, size Size) error {
+	if !ctx.clearCells || (size.Height >= a.AreaSize.Height && size.Width >= a.AreaSize.Width) {
+		return nil
+	}
+	for row := 0; row < a.AreaSize.Height; row++ {
+		for col := 0; col < a.AreaSize.Width; col++ {
+			if row < size.Height && col < size.Width {
+				continue
+			}
+			at := NewCellRef(target.Sheet, target.Row+row, target.Col+col)
+			if a.Transformer.GetCellData(NewCellRef(a.StartCell.Sheet, a.StartCell.Row+row, a.StartCell.Col+col)) == nil &&
+				a.Transformer.GetCellData(NewCellRef(a.StartCell.Sheet, at.Row, at.Col)) == nil {
+				continue
+			}
+			if err := ctx.run(func() error { return a.Transformer.ClearCell(at) }); err != nil {
+				return fmt.Errorf("clear template cell %s: %w", at, err)
+			}
+		}
+	}
+	return nil
 }
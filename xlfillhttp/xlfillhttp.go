@@ -0,0 +1,119 @@
+// Package xlfillhttp serves filled workbooks as HTTP downloads.
+//
+//	http.Handle("/reports/sales", xlfillhttp.Handler("templates/sales.xlsx",
+//		func(r *http.Request) (map[string]any, error) {
+//			return loadSales(r.Context(), r.URL.Query().Get("month"))
+//		}))
+//
+// The workbook is streamed to the response rather than buffered as a whole,
+// and the fill stops when the client goes away.
+package xlfillhttp
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/javajack/xlfill"
+)
+
+// contentTypes maps the output file extensions to their media types.
+var contentTypes = map[string]string{
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".xlsm": "application/vnd.ms-excel.sheet.macroEnabled.12",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+}
+
+// templateOutputs maps Excel template extensions to the workbooks filled from them.
+var templateOutputs = map[string]string{
+	".xltx": ".xlsx",
+	".xltm": ".xlsm",
+}
+
+// Handler returns a handler that fills the template at templatePath with the
+// data dataFn returns for each request and sends the workbook as a download.
+// When dataFn or the fill fails, the handler replies 500 Internal Server Error,
+// or nothing when the request was cancelled. templatePath may be empty when
+// opts set the template, e.g. with xlfill.WithTemplateFS.
+func Handler(templatePath string, dataFn func(*http.Request) (map[string]any, error), opts ...xlfill.Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := dataFn(r)
+		if err == nil {
+			err = Fill(w, r, templatePath, data, opts...)
+		}
+		var exprErrs xlfill.ExpressionErrors
+		if err != nil && r.Context().Err() == nil && !errors.As(err, &exprErrs) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Fill fills the template at templatePath with data and writes the workbook
+// to w as a download named after the template, e.g. "sales.xlsx" for
+// "templates/sales.xltx". Content-Type and Content-Disposition headers already
+// set on w are kept, so a handler can pick its own file name. The fill stops
+// with the request's context. Nothing is written to w when the fill fails, so
+// the caller can still send an error response; ExpressionErrors of
+// xlfill.CollectAndContinue come after the workbook is written.
+func Fill(w http.ResponseWriter, r *http.Request, templatePath string, data map[string]any, opts ...xlfill.Option) error {
+	if err := r.Context().Err(); err != nil {
+		return err
+	}
+	allOpts := []xlfill.Option{xlfill.WithCancel(r.Context())}
+	if templatePath != "" {
+		allOpts = append(allOpts, xlfill.WithTemplate(templatePath))
+	}
+	allOpts = append(allOpts, opts...)
+
+	dw := &downloadWriter{w: w, name: downloadName(templatePath)}
+	if err := xlfill.NewFiller(allOpts...).FillWriter(data, dw); err != nil {
+		var exprErrs xlfill.ExpressionErrors
+		if dw.started && !errors.As(err, &exprErrs) {
+			return fmt.Errorf("write workbook: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// downloadName returns the file name of the workbook filled from templatePath.
+func downloadName(templatePath string) string {
+	if templatePath == "" {
+		return "report.xlsx"
+	}
+	base := filepath.Base(templatePath)
+	ext := strings.ToLower(filepath.Ext(base))
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	if out, ok := templateOutputs[ext]; ok {
+		return name + out
+	}
+	if _, ok := contentTypes[ext]; ok {
+		return name + ext
+	}
+	return name + ".xlsx"
+}
+
+// downloadWriter sets the download headers before the first byte of the
+// workbook, so a fill that fails early leaves the response untouched.
+type downloadWriter struct {
+	w       http.ResponseWriter
+	name    string
+	started bool
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	if !d.started {
+		d.started = true
+		h := d.w.Header()
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", contentTypes[strings.ToLower(filepath.Ext(d.name))])
+		}
+		if h.Get("Content-Disposition") == "" {
+			h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": d.name}))
+		}
+	}
+	return d.w.Write(p)
+}
@@ -0,0 +1,134 @@
+package xlfillhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/javajack/xlfill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTemplate creates a one-row each template reading ${e.Name}.
+func writeTemplate(t *testing.T, path string) {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "${title}")
+	f.SetCellValue("Sheet1", "A2", "${e.Name}")
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A1", Author: "xlfill", Text: `jx:area(lastCell="A2")`})
+	f.AddComment("Sheet1", excelize.Comment{Cell: "A2", Author: "xlfill", Text: `jx:each(items="employees" var="e" lastCell="A2")`})
+	require.NoError(t, f.SaveAs(path))
+}
+
+func salesData(r *http.Request) (map[string]any, error) {
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		return nil, errors.New("month is required")
+	}
+	return map[string]any{"title": month, "employees": []map[string]any{{"Name": "Alice"}, {"Name": "Bob"}}}, nil
+}
+
+func TestHandler(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "sales.xlsx")
+	writeTemplate(t, tmpl)
+	h := Handler(tmpl, salesData)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?month=March", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=sales.xlsx`, rec.Header().Get("Content-Disposition"))
+
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+	for cell, want := range map[string]string{"A1": "March", "A2": "Alice", "A3": "Bob"} {
+		v, _ := f.GetCellValue("Sheet1", cell)
+		assert.Equal(t, want, v, cell)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Disposition"))
+
+	rec = httptest.NewRecorder()
+	Handler(filepath.Join(t.TempDir(), "missing.xlsx"), salesData).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sales?month=March", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestFill(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "Sales Report.xltx")
+	writeTemplate(t, tmpl)
+	data := map[string]any{"title": "T", "employees": []any{}}
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, Fill(rec, httptest.NewRequest(http.MethodGet, "/", nil), tmpl, data))
+	assert.Equal(t, `attachment; filename="Sales Report.xlsx"`, rec.Header().Get("Content-Disposition"))
+
+	// Headers set by the caller are kept
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Content-Disposition", `attachment; filename="march.xlsx"`)
+	require.NoError(t, Fill(rec, httptest.NewRequest(http.MethodGet, "/", nil), tmpl, data))
+	assert.Equal(t, `attachment; filename="march.xlsx"`, rec.Header().Get("Content-Disposition"))
+
+	// A cancelled request stops the fill before anything is written
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	err := Fill(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx), tmpl, data)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, rec.Body.Len())
+}
+
+func TestFill_CancelledDuringFill(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "sales.xlsx")
+	writeTemplate(t, tmpl)
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	data := map[string]any{"title": "T", "employees": []map[string]any{{"Name": "Alice"}, {"Name": "Bob"}}}
+
+	// The client goes away after the second cell is written
+	l := &cancelListener{after: 2, cancel: cancel}
+	rec := httptest.NewRecorder()
+	err := Fill(rec, req, tmpl, data, xlfill.WithAreaListener(l))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, rec.Body.Len())
+	assert.Equal(t, 2, l.cells)
+}
+
+// cancelListener cancels the fill after a number of cells.
+type cancelListener struct {
+	after, cells int
+	cancel       context.CancelFunc
+}
+
+func (l *cancelListener) BeforeTransformCell(_, _ xlfill.CellRef, _ *xlfill.Context, _ xlfill.Transformer) bool {
+	return true
+}
+
+func (l *cancelListener) AfterTransformCell(_, _ xlfill.CellRef, _ *xlfill.Context, _ xlfill.Transformer) {
+	if l.cells++; l.cells == l.after {
+		l.cancel()
+	}
+}
+
+func TestDownloadName(t *testing.T) {
+	for path, want := range map[string]string{
+		"":                  "report.xlsx",
+		"t/sales.xlsx":      "sales.xlsx",
+		"t/macro.XLTM":      "macro.xlsm",
+		"t/sheet.ods":       "sheet.ods",
+		"t/report.template": "report.xlsx",
+	} {
+		assert.Equal(t, want, downloadName(path), path)
+	}
+}
@@ -0,0 +1,677 @@
+// Package xlfilltest provides an in-memory xlfill.Transformer for unit testing
+// custom commands without a workbook.
+//
+// A FakeTransformer holds template cells, set with SetTemplateCell,
+// SetTemplateFormula and SetTemplateComment, and records every write in Cells
+// and the other exported fields:
+//
+//	tx := xlfilltest.NewFakeTransformer()
+//	tx.SetTemplateCell("Sheet1!A1", "${e.Name}")
+//	area := xlfill.NewArea(xlfill.NewCellRef("Sheet1", 0, 0), xlfill.Size{Width: 1, Height: 1}, tx)
+//	cmd := &MyCommand{Area: area}
+//	size, err := cmd.ApplyAt(xlfill.NewCellRef("Sheet1", 0, 0), xlfill.NewContext(data), tx)
+//	// tx.Value("Sheet1!A1") is the evaluated ${e.Name}
+package xlfilltest
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/javajack/xlfill"
+)
+
+// Default sizes reported for columns and rows that were not sized.
+const (
+	DefaultColumnWidth = 9.140625
+	DefaultRowHeight   = 15.0
+)
+
+// Cell is the output of a cell written through a FakeTransformer.
+type Cell struct {
+	Value   any    // value written, nil for a formula or a cleared cell
+	Formula string // formula written, without the leading "="
+	Style   string // last style applied with ApplyStyle or styled()
+	Link    string // hyperlink URL
+}
+
+// Image is a picture added through a FakeTransformer.
+type Image struct {
+	Sheet  string
+	Cell   string
+	Data   []byte
+	Type   string
+	ScaleX float64
+	ScaleY float64
+}
+
+// AutoFilter is an AutoFilter added through a FakeTransformer.
+type AutoFilter struct {
+	Area       xlfill.AreaRef
+	SortCol    int
+	Descending bool
+}
+
+// RowRef identifies a row of a sheet (0-based).
+type RowRef struct {
+	Sheet string
+	Row   int
+}
+
+// ColRef identifies a column of a sheet (0-based).
+type ColRef struct {
+	Sheet string
+	Col   int
+}
+
+// FakeTransformer is an xlfill.Transformer that keeps the template and its
+// output in memory. Template cells are read by GetCellData and the other
+// getters; writes land in the exported fields, which tests inspect directly.
+// Transform evaluates ${...} expressions like the workbook transformer but
+// copies formulas as written. It is safe for concurrent use.
+type FakeTransformer struct {
+	mu       sync.Mutex
+	template map[xlfill.CellRef]*xlfill.CellData
+	merged   map[xlfill.CellRef]xlfill.AreaRef // template merges by top-left cell
+	targets  map[xlfill.CellRef][]xlfill.CellRef
+
+	Cells         map[xlfill.CellRef]*Cell // written cells
+	Merges        []xlfill.AreaRef         // merged ranges, in the order merged
+	Images        []Image                  // pictures, in the order added
+	ColumnWidths  map[ColRef]float64       // column widths set on the template or by CopySheet
+	RowHeights    map[RowRef]float64       // row heights set with SetRowHeight
+	RowFits       map[RowRef]xlfill.RowFit // rows fitted with FitRowHeight
+	OutlineLevels map[RowRef]uint8         // row outline levels
+	SummaryBelow  map[string]bool          // outline summary position by sheet
+	AutoFilters   []AutoFilter             // AutoFilters, in the order added
+	Sheets        []string                 // sheet names in order
+	Hidden        map[string]bool          // hidden sheets
+	Active        string                   // sheet set by SetActiveSheet
+	TabColors     map[string]string        // tab colors set with SetSheetProps, by sheet
+	DefinedNames  map[string]string        // workbook defined names returned by GetDefinedNames
+	Recalculate   bool                     // set by SetRecalculateOnOpen
+}
+
+var _ xlfill.Transformer = (*FakeTransformer)(nil)
+
+// NewFakeTransformer creates an empty FakeTransformer with the given sheets,
+// or a single "Sheet1" when none are given.
+func NewFakeTransformer(sheets ...string) *FakeTransformer {
+	if len(sheets) == 0 {
+		sheets = []string{"Sheet1"}
+	}
+	return &FakeTransformer{
+		template:      make(map[xlfill.CellRef]*xlfill.CellData),
+		merged:        make(map[xlfill.CellRef]xlfill.AreaRef),
+		targets:       make(map[xlfill.CellRef][]xlfill.CellRef),
+		Cells:         make(map[xlfill.CellRef]*Cell),
+		ColumnWidths:  make(map[ColRef]float64),
+		RowHeights:    make(map[RowRef]float64),
+		RowFits:       make(map[RowRef]xlfill.RowFit),
+		OutlineLevels: make(map[RowRef]uint8),
+		SummaryBelow:  make(map[string]bool),
+		Sheets:        slices.Clone(sheets),
+		Hidden:        make(map[string]bool),
+		TabColors:     make(map[string]string),
+		DefinedNames:  make(map[string]string),
+	}
+}
+
+// mustRef parses a reference such as "Sheet1!A1", panicking when it is invalid
+// or names no sheet.
+func mustRef(ref string) xlfill.CellRef {
+	r, err := xlfill.ParseCellRef(ref)
+	if err != nil {
+		panic(fmt.Sprintf("xlfilltest: %v", err))
+	}
+	if r.Sheet == "" {
+		panic(fmt.Sprintf("xlfilltest: cell reference %q has no sheet", ref))
+	}
+	return r
+}
+
+// templateCell returns the template cell at ref, creating it and its sheet.
+func (tx *FakeTransformer) templateCell(r xlfill.CellRef) *xlfill.CellData {
+	if !slices.Contains(tx.Sheets, r.Sheet) {
+		tx.Sheets = append(tx.Sheets, r.Sheet)
+	}
+	cd := tx.template[r]
+	if cd == nil {
+		cd = xlfill.NewCellData(r, nil, xlfill.CellBlank)
+		tx.template[r] = cd
+	}
+	return cd
+}
+
+// SetTemplateCell sets the value of a template cell such as "Sheet1!A1".
+// Strings may hold ${...} expressions.
+func (tx *FakeTransformer) SetTemplateCell(ref string, value any) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	cd := tx.templateCell(mustRef(ref))
+	cd.Value, cd.Type = value, cellType(value)
+}
+
+// SetTemplateFormula sets the formula, without the leading "=", of a template cell.
+func (tx *FakeTransformer) SetTemplateFormula(ref, formula string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	cd := tx.templateCell(mustRef(ref))
+	cd.Formula, cd.Type = formula, xlfill.CellFormula
+}
+
+// SetTemplateComment sets the comment of a template cell, e.g. jx: commands
+// for Filler.BuildAreas.
+func (tx *FakeTransformer) SetTemplateComment(ref, comment string) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.templateCell(mustRef(ref)).Comment = comment
+}
+
+// SetTemplateMerge merges a range of the template such as "Sheet1!A1:B2".
+func (tx *FakeTransformer) SetTemplateMerge(ref string) {
+	area, err := xlfill.ParseAreaRef(ref)
+	if err != nil || area.First.Sheet == "" {
+		panic(fmt.Sprintf("xlfilltest: invalid merged range %q", ref))
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.templateCell(area.First)
+	tx.merged[area.First] = area
+}
+
+func cellType(v any) xlfill.CellType {
+	switch v.(type) {
+	case nil:
+		return xlfill.CellBlank
+	case bool:
+		return xlfill.CellBoolean
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return xlfill.CellNumber
+	case time.Time:
+		return xlfill.CellDate
+	default:
+		return xlfill.CellString
+	}
+}
+
+// Cell returns the output cell at ref, such as "Sheet1!A1", or nil when
+// nothing was written there.
+func (tx *FakeTransformer) Cell(ref string) *Cell {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.Cells[mustRef(ref)]
+}
+
+// Value returns the value written at ref, such as "Sheet1!A1", or nil.
+func (tx *FakeTransformer) Value(ref string) any {
+	if c := tx.Cell(ref); c != nil {
+		return c.Value
+	}
+	return nil
+}
+
+// cell returns the output cell at ref, creating it.
+func (tx *FakeTransformer) cell(ref xlfill.CellRef) *Cell {
+	c := tx.Cells[ref]
+	if c == nil {
+		c = &Cell{}
+		tx.Cells[ref] = c
+	}
+	return c
+}
+
+// sortedCells returns the template cells matching keep, in sheet, row and column order.
+func (tx *FakeTransformer) sortedCells(keep func(*xlfill.CellData) bool) []*xlfill.CellData {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	var cells []*xlfill.CellData
+	for _, cd := range tx.template {
+		if keep(cd) {
+			cells = append(cells, cd)
+		}
+	}
+	slices.SortFunc(cells, func(a, b *xlfill.CellData) int { return compareRefs(a.Ref, b.Ref) })
+	return cells
+}
+
+func compareRefs(a, b xlfill.CellRef) int {
+	return cmp.Or(strings.Compare(a.Sheet, b.Sheet), cmp.Compare(a.Row, b.Row), cmp.Compare(a.Col, b.Col))
+}
+
+// GetCellData returns the template cell at ref, or nil.
+func (tx *FakeTransformer) GetCellData(ref xlfill.CellRef) *xlfill.CellData {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.template[ref]
+}
+
+// GetCommentedCells returns the template cells that have comments.
+func (tx *FakeTransformer) GetCommentedCells() []*xlfill.CellData {
+	return tx.sortedCells(func(cd *xlfill.CellData) bool { return cd.Comment != "" })
+}
+
+// GetCellsWithPrefix returns the template cells whose string value starts with prefix.
+func (tx *FakeTransformer) GetCellsWithPrefix(prefix string) []*xlfill.CellData {
+	return tx.sortedCells(func(cd *xlfill.CellData) bool {
+		s, ok := cd.Value.(string)
+		return ok && strings.HasPrefix(s, prefix)
+	})
+}
+
+// GetDefinedNames returns a copy of DefinedNames.
+func (tx *FakeTransformer) GetDefinedNames() map[string]string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	names := make(map[string]string, len(tx.DefinedNames))
+	for k, v := range tx.DefinedNames {
+		names[k] = v
+	}
+	return names
+}
+
+// GetFormulaCells returns the template cells that hold formulas.
+func (tx *FakeTransformer) GetFormulaCells() []*xlfill.CellData {
+	return tx.sortedCells((*xlfill.CellData).IsFormulaCell)
+}
+
+// GetMergedRange returns the template merge set with SetTemplateMerge whose
+// top-left cell is ref.
+func (tx *FakeTransformer) GetMergedRange(ref xlfill.CellRef) (xlfill.AreaRef, bool) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	area, ok := tx.merged[ref]
+	return area, ok
+}
+
+// Transform evaluates the template cell at src and writes it to target.
+func (tx *FakeTransformer) Transform(src, target xlfill.CellRef, ctx *xlfill.Context, updateRowHeight bool) error {
+	cd := tx.GetCellData(src)
+	if cd == nil {
+		return nil
+	}
+	if target.Sheet == "" {
+		target.Sheet = src.Sheet
+	}
+
+	var value any
+	var style, link string
+	if !cd.IsFormulaCell() {
+		value = cd.Value
+		if s, ok := cd.Value.(string); ok {
+			v, _, err := ctx.EvaluateCellValue(s)
+			if err != nil {
+				return fmt.Errorf("transform cell %s: %w", src, err)
+			}
+			switch v := v.(type) {
+			case xlfill.StyledValue:
+				value, style = v.Value, v.Style
+			case xlfill.HyperlinkValue:
+				value, link = v.String(), v.URL
+			case xlfill.TextValue:
+				value = v.Text
+			default:
+				value = v
+			}
+		}
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if updateRowHeight {
+		if h, ok := tx.RowHeights[RowRef{src.Sheet, src.Row}]; ok {
+			tx.RowHeights[RowRef{target.Sheet, target.Row}] = h
+		}
+	}
+	c := tx.cell(target)
+	if cd.IsFormulaCell() {
+		c.Value, c.Formula = nil, cd.Formula
+		cd.EvalFormulas = append(cd.EvalFormulas, cd.Formula)
+	} else {
+		c.Value, c.Formula = value, ""
+		cd.EvalResult, cd.TargetCellType = value, cellType(value)
+	}
+	if style != "" {
+		c.Style = style
+	}
+	if link != "" {
+		c.Link = link
+	}
+	cd.AddTargetPos(target)
+	tx.targets[src] = append(tx.targets[src], target)
+	return nil
+}
+
+// ClearCell clears the value and formula of an output cell, keeping its style.
+func (tx *FakeTransformer) ClearCell(ref xlfill.CellRef) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if c := tx.Cells[ref]; c != nil {
+		c.Value, c.Formula, c.Link = nil, "", ""
+		if c.Style == "" {
+			delete(tx.Cells, ref)
+		}
+	}
+	return nil
+}
+
+// SetFormula writes a formula to an output cell.
+func (tx *FakeTransformer) SetFormula(ref xlfill.CellRef, formula string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	c := tx.cell(ref)
+	c.Value, c.Formula = nil, formula
+	return nil
+}
+
+// SetCellValue writes a value to an output cell.
+func (tx *FakeTransformer) SetCellValue(ref xlfill.CellRef, value any) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	c := tx.cell(ref)
+	c.Value, c.Formula = value, ""
+	return nil
+}
+
+// ApplyStyle records name as the style of an output cell.
+func (tx *FakeTransformer) ApplyStyle(ref xlfill.CellRef, name string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.cell(ref).Style = name
+	return nil
+}
+
+// GetTargetCellRef returns where a template cell was written by Transform.
+func (tx *FakeTransformer) GetTargetCellRef(src xlfill.CellRef) []xlfill.CellRef {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return slices.Clone(tx.targets[src])
+}
+
+// ResetTargetCellRefs forgets where template cells were written.
+func (tx *FakeTransformer) ResetTargetCellRefs() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.targets = make(map[xlfill.CellRef][]xlfill.CellRef)
+}
+
+// GetSheetNames returns a copy of Sheets.
+func (tx *FakeTransformer) GetSheetNames() []string {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return slices.Clone(tx.Sheets)
+}
+
+// GetColumnWidth returns the width of a column, or DefaultColumnWidth.
+func (tx *FakeTransformer) GetColumnWidth(sheet string, col int) float64 {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if w, ok := tx.ColumnWidths[ColRef{sheet, col}]; ok {
+		return w
+	}
+	return DefaultColumnWidth
+}
+
+// GetRowHeight returns the height of a row, or DefaultRowHeight.
+func (tx *FakeTransformer) GetRowHeight(sheet string, row int) float64 {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if h, ok := tx.RowHeights[RowRef{sheet, row}]; ok {
+		return h
+	}
+	return DefaultRowHeight
+}
+
+// SetRowHeight records the height of a row.
+func (tx *FakeTransformer) SetRowHeight(sheet string, row int, height float64) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.RowHeights[RowRef{sheet, row}] = height
+	return nil
+}
+
+// FitRowHeight records the fit requested for a row in RowFits; it does not
+// measure text.
+func (tx *FakeTransformer) FitRowHeight(sheet string, row, firstCol, lastCol int, fit xlfill.RowFit) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.RowFits[RowRef{sheet, row}] = fit
+	return nil
+}
+
+// SetRowOutlineLevel records the outline level of a row.
+func (tx *FakeTransformer) SetRowOutlineLevel(sheet string, row int, level uint8) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.OutlineLevels[RowRef{sheet, row}] = level
+	return nil
+}
+
+// SetOutlineSummaryBelow records where the summary rows of a sheet's outline are.
+func (tx *FakeTransformer) SetOutlineSummaryBelow(sheet string, below bool) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.SummaryBelow[sheet] = below
+	return nil
+}
+
+// SetAutoFilter records an AutoFilter in AutoFilters.
+func (tx *FakeTransformer) SetAutoFilter(area xlfill.AreaRef, sortCol int, descending bool) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.AutoFilters = append(tx.AutoFilters, AutoFilter{area, sortCol, descending})
+	return nil
+}
+
+// DeleteSheet removes a sheet and its output cells.
+func (tx *FakeTransformer) DeleteSheet(name string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	i := slices.Index(tx.Sheets, name)
+	if i < 0 {
+		return fmt.Errorf("sheet %q does not exist", name)
+	}
+	tx.Sheets = slices.Delete(tx.Sheets, i, i+1)
+	for ref := range tx.Cells {
+		if ref.Sheet == name {
+			delete(tx.Cells, ref)
+		}
+	}
+	delete(tx.Hidden, name)
+	return nil
+}
+
+// SetHidden records whether a sheet is hidden.
+func (tx *FakeTransformer) SetHidden(name string, hidden bool) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if !slices.Contains(tx.Sheets, name) {
+		return fmt.Errorf("sheet %q does not exist", name)
+	}
+	tx.Hidden[name] = hidden
+	return nil
+}
+
+// IsHidden reports whether a sheet is hidden.
+func (tx *FakeTransformer) IsHidden(name string) bool {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.Hidden[name]
+}
+
+// CopySheet adds sheet dst with the template and output cells and the column
+// widths of src.
+func (tx *FakeTransformer) CopySheet(src, dst string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if !slices.Contains(tx.Sheets, src) {
+		return fmt.Errorf("sheet %q does not exist", src)
+	}
+	if !slices.Contains(tx.Sheets, dst) {
+		tx.Sheets = append(tx.Sheets, dst)
+	}
+	for ref, cd := range tx.template {
+		if ref.Sheet == src {
+			to := xlfill.NewCellRef(dst, ref.Row, ref.Col)
+			copied := xlfill.NewCellData(to, cd.Value, cd.Type)
+			copied.Formula, copied.Comment = cd.Formula, cd.Comment
+			tx.template[to] = copied
+		}
+	}
+	for ref, c := range tx.Cells {
+		if ref.Sheet == src {
+			copied := *c
+			tx.Cells[xlfill.NewCellRef(dst, ref.Row, ref.Col)] = &copied
+		}
+	}
+	for ref, w := range tx.ColumnWidths {
+		if ref.Sheet == src {
+			tx.ColumnWidths[ColRef{dst, ref.Col}] = w
+		}
+	}
+	return nil
+}
+
+// MoveSheet moves a sheet directly after sheet after, or first when after is "".
+func (tx *FakeTransformer) MoveSheet(name, after string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	i := slices.Index(tx.Sheets, name)
+	if i < 0 {
+		return fmt.Errorf("sheet %q does not exist", name)
+	}
+	sheets := slices.Delete(slices.Clone(tx.Sheets), i, i+1)
+	at := 0
+	if after != "" {
+		j := slices.Index(sheets, after)
+		if j < 0 {
+			return fmt.Errorf("sheet %q does not exist", after)
+		}
+		at = j + 1
+	}
+	tx.Sheets = slices.Insert(sheets, at, name)
+	return nil
+}
+
+// SetSheetOrder moves the named sheets first, in that order.
+func (tx *FakeTransformer) SetSheetOrder(names []string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	for _, name := range names {
+		if !slices.Contains(tx.Sheets, name) {
+			return fmt.Errorf("sheet %q does not exist", name)
+		}
+	}
+	rest := slices.DeleteFunc(slices.Clone(tx.Sheets), func(s string) bool { return slices.Contains(names, s) })
+	tx.Sheets = append(slices.Clone(names), rest...)
+	return nil
+}
+
+// SetActiveSheet records the active sheet in Active.
+func (tx *FakeTransformer) SetActiveSheet(name string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if !slices.Contains(tx.Sheets, name) {
+		return fmt.Errorf("sheet %q does not exist", name)
+	}
+	tx.Active = name
+	return nil
+}
+
+// SetSheetProps records the tab color in TabColors and the visibility in Hidden.
+func (tx *FakeTransformer) SetSheetProps(name string, props xlfill.SheetProps) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if !slices.Contains(tx.Sheets, name) {
+		return fmt.Errorf("sheet %q does not exist", name)
+	}
+	if props.TabColor != nil {
+		tx.TabColors[name] = *props.TabColor
+	}
+	if props.Hidden != nil {
+		tx.Hidden[name] = *props.Hidden
+	}
+	return nil
+}
+
+// AddImage records a picture in Images.
+func (tx *FakeTransformer) AddImage(sheet string, cell string, imgBytes []byte, imgType string, scaleX, scaleY float64) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.Images = append(tx.Images, Image{Sheet: sheet, Cell: cell, Data: imgBytes, Type: imgType, ScaleX: scaleX, ScaleY: scaleY})
+	return nil
+}
+
+// MergeCells records a merged range in Merges.
+func (tx *FakeTransformer) MergeCells(sheet, topLeft, bottomRight string) error {
+	first, err := xlfill.ParseCellRef(topLeft)
+	if err != nil {
+		return err
+	}
+	last, err := xlfill.ParseCellRef(bottomRight)
+	if err != nil {
+		return err
+	}
+	first.Sheet, last.Sheet = sheet, sheet
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.Merges = append(tx.Merges, xlfill.NewAreaRef(first, last))
+	return nil
+}
+
+// SetCellHyperLink writes a hyperlink to an output cell.
+func (tx *FakeTransformer) SetCellHyperLink(ref xlfill.CellRef, url, display string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	c := tx.cell(ref)
+	c.Value, c.Formula, c.Link = display, "", url
+	return nil
+}
+
+// SetRecalculateOnOpen records the setting in Recalculate.
+func (tx *FakeTransformer) SetRecalculateOnOpen(recalc bool) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.Recalculate = recalc
+	return nil
+}
+
+// Write writes the output cells as text, one "Sheet1!A1 = value" line per
+// cell in sheet order, for comparing against golden files.
+func (tx *FakeTransformer) Write(w io.Writer) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	refs := make([]xlfill.CellRef, 0, len(tx.Cells))
+	for ref := range tx.Cells {
+		refs = append(refs, ref)
+	}
+	slices.SortFunc(refs, func(a, b xlfill.CellRef) int {
+		return cmp.Or(cmp.Compare(slices.Index(tx.Sheets, a.Sheet), slices.Index(tx.Sheets, b.Sheet)), compareRefs(a, b))
+	})
+	for _, ref := range refs {
+		c := tx.Cells[ref]
+		line := fmt.Sprintf("%s = ", ref)
+		if c.Formula != "" {
+			line += "=" + c.Formula
+		} else if c.Value != nil {
+			line += fmt.Sprint(c.Value)
+		}
+		if c.Link != "" {
+			line += " <" + c.Link + ">"
+		}
+		if c.Style != "" {
+			line += " [" + c.Style + "]"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close does nothing.
+func (tx *FakeTransformer) Close() error { return nil }
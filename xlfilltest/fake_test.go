@@ -0,0 +1,170 @@
+package xlfilltest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/javajack/xlfill"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upperCommand is a custom command that writes its area and upper-cases the
+// string values it produced.
+type upperCommand struct {
+	Area *xlfill.Area
+}
+
+func (c *upperCommand) Name() string { return "upper" }
+func (c *upperCommand) Reset()       {}
+
+func (c *upperCommand) ApplyAt(cellRef xlfill.CellRef, ctx *xlfill.Context, tx xlfill.Transformer) (xlfill.Size, error) {
+	size, err := c.Area.ApplyAt(cellRef, ctx)
+	if err != nil {
+		return xlfill.ZeroSize, err
+	}
+	for row := 0; row < size.Height; row++ {
+		for col := 0; col < size.Width; col++ {
+			src := xlfill.NewCellRef(c.Area.StartCell.Sheet, c.Area.StartCell.Row+row, c.Area.StartCell.Col+col)
+			for _, target := range tx.GetTargetCellRef(src) {
+				if cd := tx.GetCellData(src); cd != nil {
+					if s, ok := cd.EvalResult.(string); ok {
+						if err := tx.SetCellValue(target, strings.ToUpper(s)); err != nil {
+							return xlfill.ZeroSize, err
+						}
+					}
+				}
+			}
+		}
+	}
+	return size, nil
+}
+
+func TestFakeTransformer_CustomCommand(t *testing.T) {
+	tx := NewFakeTransformer()
+	tx.SetTemplateCell("Sheet1!A1", "${name}")
+	tx.SetTemplateCell("Sheet1!B1", 42)
+
+	start := xlfill.NewCellRef("Sheet1", 0, 0)
+	cmd := &upperCommand{Area: xlfill.NewArea(start, xlfill.Size{Width: 2, Height: 1}, tx)}
+	size, err := cmd.ApplyAt(xlfill.NewCellRef("Sheet1", 2, 0), xlfill.NewContext(map[string]any{"name": "alice"}), tx)
+	require.NoError(t, err)
+
+	assert.Equal(t, xlfill.Size{Width: 2, Height: 1}, size)
+	assert.Equal(t, "ALICE", tx.Value("Sheet1!A3"))
+	assert.Equal(t, 42, tx.Value("Sheet1!B3"))
+	assert.Nil(t, tx.Cell("Sheet1!A1"), "the template cell is not written")
+}
+
+func TestFakeTransformer_BuiltInCommands(t *testing.T) {
+	tx := NewFakeTransformer()
+	tx.SetTemplateCell("Sheet1!A1", "Name")
+	tx.SetTemplateCell("Sheet1!A2", "${e.Name}")
+	tx.SetTemplateCell("Sheet1!B2", "${styled(e.Score, \"good\")}")
+	tx.SetTemplateFormula("Sheet1!B3", "SUM(B2)")
+	tx.SetTemplateComment("Sheet1!A1", `jx:area(lastCell="B3")`)
+	tx.SetTemplateComment("Sheet1!A2", `jx:each(items="rows" var="e" lastCell="B2")`+"\n"+
+		`jx:if(condition="e.Score > 80" lastCell="A2")`)
+
+	areas, err := xlfill.NewFiller().BuildAreas(tx)
+	require.NoError(t, err)
+	require.Len(t, areas, 1)
+	ctx := xlfill.NewContext(map[string]any{"rows": []any{
+		map[string]any{"Name": "Alice", "Score": 90},
+		map[string]any{"Name": "Bob", "Score": 75},
+	}})
+	_, err = areas[0].ApplyAt(areas[0].StartCell, ctx)
+	require.NoError(t, err)
+	xlfill.NewFormulaProcessor().ProcessAreaFormulas(tx, areas[0])
+
+	var out bytes.Buffer
+	require.NoError(t, tx.Write(&out))
+	assert.Equal(t, strings.Join([]string{
+		"Sheet1!A1 = Name",
+		"Sheet1!A2 = Alice",
+		"Sheet1!B2 = 90 [good]",
+		"Sheet1!B3 = 75 [good]",
+		"Sheet1!B4 = =SUM(B2:B3)",
+		"",
+	}, "\n"), out.String())
+	assert.Equal(t, []xlfill.CellRef{xlfill.NewCellRef("Sheet1", 1, 1), xlfill.NewCellRef("Sheet1", 2, 1)},
+		tx.GetTargetCellRef(xlfill.NewCellRef("Sheet1", 1, 1)))
+}
+
+func TestFakeTransformer_Sheets(t *testing.T) {
+	tx := NewFakeTransformer("Template")
+	tx.SetTemplateCell("Template!A1", "x")
+	tx.ColumnWidths[ColRef{"Template", 0}] = 20
+	require.NoError(t, tx.SetCellValue(xlfill.NewCellRef("Template", 0, 0), "x"))
+
+	require.NoError(t, tx.CopySheet("Template", "Copy"))
+	assert.Equal(t, []string{"Template", "Copy"}, tx.GetSheetNames())
+	assert.Equal(t, "x", tx.Value("Copy!A1"))
+	assert.Equal(t, "x", tx.GetCellData(xlfill.NewCellRef("Copy", 0, 0)).Value)
+	assert.Equal(t, 20.0, tx.GetColumnWidth("Copy", 0))
+	assert.Equal(t, DefaultColumnWidth, tx.GetColumnWidth("Copy", 1))
+
+	require.NoError(t, tx.SetHidden("Copy", true))
+	assert.True(t, tx.IsHidden("Copy"))
+	require.NoError(t, tx.DeleteSheet("Template"))
+	assert.Equal(t, []string{"Copy"}, tx.GetSheetNames())
+	assert.Nil(t, tx.Cell("Template!A1"))
+	assert.Error(t, tx.DeleteSheet("Missing"))
+	assert.Error(t, tx.CopySheet("Missing", "Other"))
+
+	require.NoError(t, tx.CopySheet("Copy", "Second"))
+	require.NoError(t, tx.CopySheet("Copy", "Third"))
+	require.NoError(t, tx.MoveSheet("Copy", "Third"))
+	assert.Equal(t, []string{"Second", "Third", "Copy"}, tx.GetSheetNames())
+	require.NoError(t, tx.MoveSheet("Third", ""))
+	assert.Equal(t, []string{"Third", "Second", "Copy"}, tx.GetSheetNames())
+	require.NoError(t, tx.SetSheetOrder([]string{"Copy", "Second"}))
+	assert.Equal(t, []string{"Copy", "Second", "Third"}, tx.GetSheetNames())
+	require.NoError(t, tx.SetActiveSheet("Second"))
+	assert.Equal(t, "Second", tx.Active)
+	assert.Error(t, tx.MoveSheet("Missing", ""))
+	assert.Error(t, tx.SetSheetOrder([]string{"Missing"}))
+	assert.Error(t, tx.SetActiveSheet("Missing"))
+}
+
+func TestFakeTransformer_ClearCellKeepsStyle(t *testing.T) {
+	tx := NewFakeTransformer()
+	ref := xlfill.NewCellRef("Sheet1", 0, 0)
+	require.NoError(t, tx.SetCellValue(ref, "x"))
+	require.NoError(t, tx.ApplyStyle(ref, "bold"))
+	require.NoError(t, tx.ClearCell(ref))
+	assert.Equal(t, &Cell{Style: "bold"}, tx.Cell("Sheet1!A1"))
+
+	require.NoError(t, tx.SetCellHyperLink(xlfill.NewCellRef("Sheet1", 1, 0), "https://example.com", "site"))
+	require.NoError(t, tx.ClearCell(xlfill.NewCellRef("Sheet1", 1, 0)))
+	assert.Nil(t, tx.Cell("Sheet1!A2"))
+	assert.Panics(t, func() { tx.Cell("A1") }, "references need a sheet")
+}
+
+func TestFakeTransformer_MergedTemplateCell(t *testing.T) {
+	// A transposed grid sizes its headers by the merged template cell
+	tx := NewFakeTransformer()
+	tx.SetTemplateCell("Sheet1!A1", "label")
+	tx.SetTemplateMerge("Sheet1!A1:B1")
+	tx.SetTemplateComment("Sheet1!A1", "jx:area(lastCell=\"B1\")\n"+
+		`jx:grid(headers="fields" data="rows" direction="right" lastCell="B1")`)
+
+	areas, err := xlfill.NewFiller().BuildAreas(tx)
+	require.NoError(t, err)
+	require.Len(t, areas, 1)
+	ctx := xlfill.NewContext(map[string]any{
+		"fields": []string{"Account", "Balance"},
+		"rows":   [][]any{{"Cash", 120}},
+	})
+	size, err := areas[0].ApplyAt(areas[0].StartCell, ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, xlfill.Size{Width: 3, Height: 2}, size)
+	assert.Equal(t, "Account", tx.Value("Sheet1!A1"))
+	assert.Equal(t, "Cash", tx.Value("Sheet1!C1"))
+	assert.Equal(t, 120, tx.Value("Sheet1!C2"))
+	area, ok := tx.GetMergedRange(xlfill.NewCellRef("Sheet1", 0, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "Sheet1!A1:B1", area.String())
+}
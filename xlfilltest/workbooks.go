@@ -0,0 +1,324 @@
+package xlfilltest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/javajack/xlfill"
+	"github.com/xuri/excelize/v2"
+)
+
+// UpdateGoldenEnv names the environment variable that makes AssertGolden
+// write golden files instead of comparing against them.
+const UpdateGoldenEnv = "XLFILL_UPDATE_GOLDEN"
+
+// maxDiffs limits the differences AssertEqualWorkbooks reports.
+const maxDiffs = 50
+
+// Ignore leaves part of the workbooks out of a comparison.
+type Ignore func(*comparison)
+
+type comparison struct {
+	styles bool
+	sheets map[string]bool
+	cells  []xlfill.AreaRef
+}
+
+// IgnoreStyles compares cell values, formulas and merges but not cell styles.
+func IgnoreStyles() Ignore {
+	return func(c *comparison) { c.styles = false }
+}
+
+// IgnoreSheets leaves the named sheets out of the comparison.
+func IgnoreSheets(names ...string) Ignore {
+	return func(c *comparison) {
+		for _, name := range names {
+			c.sheets[name] = true
+		}
+	}
+}
+
+// IgnoreCells leaves cells or ranges, such as "Sheet1!A1" or "Sheet1!B2:C9",
+// out of the comparison, e.g. cells holding the time of the fill. It panics
+// when a reference is invalid or names no sheet.
+func IgnoreCells(refs ...string) Ignore {
+	areas := make([]xlfill.AreaRef, len(refs))
+	for i, ref := range refs {
+		var err error
+		if strings.Contains(ref, ":") {
+			areas[i], err = xlfill.ParseAreaRef(ref)
+		} else {
+			var cell xlfill.CellRef
+			cell, err = xlfill.ParseCellRef(ref)
+			areas[i] = xlfill.NewAreaRef(cell, cell)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("xlfilltest: %v", err))
+		}
+		if areas[i].First.Sheet == "" {
+			panic(fmt.Sprintf("xlfilltest: cell reference %q has no sheet", ref))
+		}
+	}
+	return func(c *comparison) { c.cells = append(c.cells, areas...) }
+}
+
+func (c *comparison) ignored(ref xlfill.CellRef) bool {
+	return slices.ContainsFunc(c.cells, func(a xlfill.AreaRef) bool { return a.Contains(ref) })
+}
+
+// AssertEqualWorkbooks compares two xlsx files cell by cell: sheets, cell
+// values, formulas, merged cells and cell styles. It reports the differences
+// through t and returns whether there were none.
+func AssertEqualWorkbooks(t testing.TB, want, got []byte, ignore ...Ignore) bool {
+	t.Helper()
+	diffs, err := DiffWorkbooks(want, got, ignore...)
+	if err != nil {
+		t.Errorf("compare workbooks: %v", err)
+		return false
+	}
+	if len(diffs) == 0 {
+		return true
+	}
+	if len(diffs) > maxDiffs {
+		diffs = append(diffs[:maxDiffs], fmt.Sprintf("... and %d more", len(diffs)-maxDiffs))
+	}
+	t.Errorf("workbooks differ:\n  %s", strings.Join(diffs, "\n  "))
+	return false
+}
+
+// AssertGolden compares got with the workbook saved at path, like
+// AssertEqualWorkbooks. When the UpdateGoldenEnv environment variable is set,
+// got is saved at path instead.
+func AssertGolden(t testing.TB, path string, got []byte, ignore ...Ignore) bool {
+	t.Helper()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("update golden file: %v", err)
+			return false
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Errorf("update golden file: %v", err)
+			return false
+		}
+		return true
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("golden file %s does not exist; set %s=1 to create it", path, UpdateGoldenEnv)
+		return false
+	}
+	if err != nil {
+		t.Errorf("read golden file: %v", err)
+		return false
+	}
+	return AssertEqualWorkbooks(t, want, got, ignore...)
+}
+
+// DiffWorkbooks compares two xlsx files cell by cell and returns their
+// differences, one per line, such as
+//
+//	Sheet1!B2 value: want "10", got "12"
+//
+// Values are compared as stored, before number formats are applied.
+func DiffWorkbooks(want, got []byte, ignore ...Ignore) ([]string, error) {
+	c := &comparison{styles: true, sheets: map[string]bool{}}
+	for _, opt := range ignore {
+		opt(c)
+	}
+	wf, err := excelize.OpenReader(bytes.NewReader(want))
+	if err != nil {
+		return nil, fmt.Errorf("open want: %w", err)
+	}
+	defer wf.Close()
+	gf, err := excelize.OpenReader(bytes.NewReader(got))
+	if err != nil {
+		return nil, fmt.Errorf("open got: %w", err)
+	}
+	defer gf.Close()
+
+	var diffs []string
+	keep := func(name string) bool { return !c.sheets[name] }
+	wantSheets := slices.DeleteFunc(wf.GetSheetList(), func(s string) bool { return !keep(s) })
+	gotSheets := slices.DeleteFunc(gf.GetSheetList(), func(s string) bool { return !keep(s) })
+	if !slices.Equal(wantSheets, gotSheets) {
+		diffs = append(diffs, fmt.Sprintf("sheets: want %q, got %q", wantSheets, gotSheets))
+	}
+	for _, sheet := range wantSheets {
+		if !slices.Contains(gotSheets, sheet) {
+			continue
+		}
+		sheetDiffs, err := c.diffSheet(wf, gf, sheet)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet, err)
+		}
+		diffs = append(diffs, sheetDiffs...)
+	}
+	return diffs, nil
+}
+
+// cellState is what is compared of a cell.
+type cellState struct {
+	value, formula string
+	style          *excelize.Style
+}
+
+func (c *comparison) diffSheet(wf, gf *excelize.File, sheet string) ([]string, error) {
+	var diffs []string
+
+	rows, cols, err := usedRange(wf, sheet)
+	if err != nil {
+		return nil, err
+	}
+	gotRows, gotCols, err := usedRange(gf, sheet)
+	if err != nil {
+		return nil, err
+	}
+	rows, cols = max(rows, gotRows), max(cols, gotCols)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			ref := xlfill.NewCellRef(sheet, row, col)
+			if c.ignored(ref) {
+				continue
+			}
+			want, err := c.cellState(wf, ref)
+			if err != nil {
+				return nil, err
+			}
+			got, err := c.cellState(gf, ref)
+			if err != nil {
+				return nil, err
+			}
+			if want.value != got.value {
+				diffs = append(diffs, fmt.Sprintf("%s value: want %q, got %q", ref, want.value, got.value))
+			}
+			if want.formula != got.formula {
+				diffs = append(diffs, fmt.Sprintf("%s formula: want %q, got %q", ref, want.formula, got.formula))
+			}
+			diffs = append(diffs, styleDiffs(ref, want.style, got.style)...)
+		}
+	}
+
+	wantMerges, err := merges(wf, sheet)
+	if err != nil {
+		return nil, err
+	}
+	gotMerges, err := merges(gf, sheet)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(wantMerges, gotMerges) {
+		diffs = append(diffs, fmt.Sprintf("%s merged cells: want %v, got %v", sheet, wantMerges, gotMerges))
+	}
+	return diffs, nil
+}
+
+// usedRange returns the number of rows and columns of a sheet's cells.
+func usedRange(f *excelize.File, sheet string) (rows, cols int, err error) {
+	dim, err := f.GetSheetDimension(sheet)
+	if err != nil {
+		return 0, 0, err
+	}
+	if dim != "" {
+		last := dim[strings.LastIndex(dim, ":")+1:]
+		if c, r, err := excelize.CellNameToCoordinates(last); err == nil {
+			rows, cols = r, c
+		}
+	}
+	// The dimension is not always kept up to date; the cells are authoritative
+	all, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return 0, 0, err
+	}
+	rows = max(rows, len(all))
+	for _, row := range all {
+		cols = max(cols, len(row))
+	}
+	return rows, cols, nil
+}
+
+func (c *comparison) cellState(f *excelize.File, ref xlfill.CellRef) (cellState, error) {
+	cell := ref.CellName()
+	value, err := f.GetCellValue(ref.Sheet, cell, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return cellState{}, err
+	}
+	formula, err := f.GetCellFormula(ref.Sheet, cell)
+	if err != nil {
+		return cellState{}, err
+	}
+	state := cellState{value: value, formula: formula}
+	if c.styles {
+		id, err := f.GetCellStyle(ref.Sheet, cell)
+		if err != nil {
+			return cellState{}, err
+		}
+		if state.style, err = f.GetStyle(id); err != nil {
+			return cellState{}, err
+		}
+	}
+	return state, nil
+}
+
+// styleDiffs reports the style properties, such as Font or Fill, that differ.
+func styleDiffs(ref xlfill.CellRef, want, got *excelize.Style) []string {
+	if want == nil || got == nil || reflect.DeepEqual(want, got) {
+		return nil
+	}
+	var diffs []string
+	wv, gv := reflect.ValueOf(want).Elem(), reflect.ValueOf(got).Elem()
+	for i := 0; i < wv.NumField(); i++ {
+		w, g := wv.Field(i), gv.Field(i)
+		if !reflect.DeepEqual(w.Interface(), g.Interface()) {
+			diffs = append(diffs, fmt.Sprintf("%s style %s: want %s, got %s", ref, wv.Type().Field(i).Name, describe(w), describe(g)))
+		}
+	}
+	return diffs
+}
+
+// describe formats a style property, following pointers and leaving out
+// unset fields, e.g. {Bold:true Size:11}.
+func describe(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return "none"
+		}
+		return describe(v.Elem())
+	case reflect.Struct:
+		var fields []string
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Field(i).IsZero() {
+				fields = append(fields, v.Type().Field(i).Name+":"+describe(v.Field(i)))
+			}
+		}
+		return "{" + strings.Join(fields, " ") + "}"
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = describe(v.Index(i))
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func merges(f *excelize.File, sheet string) ([]string, error) {
+	mcs, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, len(mcs))
+	for i, mc := range mcs {
+		refs[i] = mc.GetStartAxis() + ":" + mc.GetEndAxis()
+	}
+	slices.Sort(refs)
+	return refs, nil
+}
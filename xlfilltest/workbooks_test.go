@@ -0,0 +1,112 @@
+package xlfilltest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// recorder captures the errors AssertEqualWorkbooks reports.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// workbook builds an xlsx file with a small table, changed by edit.
+func workbook(t *testing.T, edit func(f *excelize.File)) []byte {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetCellValue("Sheet1", "A1", "Name")
+	f.SetCellValue("Sheet1", "B1", "Pay")
+	f.SetCellValue("Sheet1", "A2", "Ann")
+	f.SetCellValue("Sheet1", "B2", 10)
+	f.SetCellFormula("Sheet1", "B3", "SUM(B2)")
+	if edit != nil {
+		edit(f)
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestDiffWorkbooks_Equal(t *testing.T) {
+	diffs, err := DiffWorkbooks(workbook(t, nil), workbook(t, nil))
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	rec := &recorder{}
+	assert.True(t, AssertEqualWorkbooks(rec, workbook(t, nil), workbook(t, nil)))
+	assert.Empty(t, rec.errors)
+}
+
+func TestDiffWorkbooks_Differences(t *testing.T) {
+	got := workbook(t, func(f *excelize.File) {
+		f.SetCellValue("Sheet1", "B2", 12)
+		f.SetCellFormula("Sheet1", "B3", "SUM(B2:B2)")
+		f.SetCellValue("Sheet1", "C4", "extra")
+		bold, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+		f.SetCellStyle("Sheet1", "A1", "A1", bold)
+		f.MergeCell("Sheet1", "A1", "B1")
+		f.NewSheet("Notes")
+	})
+	diffs, err := DiffWorkbooks(workbook(t, nil), got)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`sheets: want ["Sheet1"], got ["Sheet1" "Notes"]`,
+		`Sheet1!A1 style Font: want {Family:Calibri Size:11 ColorTheme:1}, got {Bold:true}`,
+		`Sheet1!B1 value: want "Pay", got "Name"`,
+		`Sheet1!B2 value: want "10", got "12"`,
+		`Sheet1!B3 formula: want "SUM(B2)", got "SUM(B2:B2)"`,
+		`Sheet1!C4 value: want "", got "extra"`,
+		`Sheet1 merged cells: want [], got [A1:B1]`,
+	}, diffs)
+
+	rec := &recorder{}
+	assert.False(t, AssertEqualWorkbooks(rec, workbook(t, nil), got))
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], "workbooks differ:\n  sheets:")
+}
+
+func TestDiffWorkbooks_Ignore(t *testing.T) {
+	got := workbook(t, func(f *excelize.File) {
+		f.SetCellValue("Sheet1", "B2", 12)
+		bold, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+		f.SetCellStyle("Sheet1", "A1", "A1", bold)
+		f.NewSheet("Notes")
+	})
+	diffs, err := DiffWorkbooks(workbook(t, nil), got, IgnoreStyles(), IgnoreCells("Sheet1!B2:B3"), IgnoreSheets("Notes"))
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	assert.Panics(t, func() { IgnoreCells("B2") }, "references need a sheet")
+}
+
+func TestAssertGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "report.xlsx")
+
+	rec := &recorder{}
+	assert.False(t, AssertGolden(rec, path, workbook(t, nil)))
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], UpdateGoldenEnv+"=1")
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	assert.True(t, AssertGolden(t, path, workbook(t, nil)))
+	t.Setenv(UpdateGoldenEnv, "")
+
+	assert.True(t, AssertGolden(t, path, workbook(t, nil)))
+	rec = &recorder{}
+	assert.False(t, AssertGolden(rec, path, workbook(t, func(f *excelize.File) { f.SetCellValue("Sheet1", "A2", "Bob") })))
+	require.Len(t, rec.errors, 1)
+	assert.Contains(t, rec.errors[0], `Sheet1!A2 value: want "Ann", got "Bob"`)
+}